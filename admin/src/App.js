@@ -8,11 +8,12 @@ import jsonServerProvider from 'ra-data-json-server';
 import simpleRestProvider from 'ra-data-simple-rest';
 import { createMuiTheme } from '@material-ui/core/styles';
 import UsersIcon from '@material-ui/icons/PeopleAlt';
-import AppsIcon from '@material-ui/icons/Tablet';
 
 import { SpaceIcon, SpaceList, SpaceShow, SpaceEdit, SpaceCreate } from './components/spaces.jsx'
-import { UserEdit } from './components/users.jsx'
+import { UserList, UserEdit } from './components/users.jsx'
 import { ProvidersIcon, ProvidersList, ProvidersShow, ProvidersEdit, ProvidersCreate } from './components/providers.jsx'
+import { AppsIcon, AppsList, AppsShow, AppsEdit, AppsCreate } from './components/apps.jsx'
+import authProvider from './authProvider'
 
 
 const theme = createMuiTheme({
@@ -25,8 +26,7 @@ const httpClient = (url, options = {}) => {
     if (!options.headers) {
         options.headers = new Headers({ Accept: 'application/json' });
     }
-    // const token = btoa('admin:password');
-    // options.headers.set('Authorization', `Basic ${token}`);
+    options.credentials = 'include';
     return fetchUtils.fetchJson(url, options);
 }
 
@@ -34,11 +34,13 @@ const httpClient = (url, options = {}) => {
 // const dataProvider = jsonServerProvider('http://localhost:6001/api', httpClient);
 const dataProvider = jsonServerProvider('/api', httpClient);
 const App = () => (
-    <Admin dataProvider={dataProvider} theme={theme}>
+    <Admin dataProvider={dataProvider} authProvider={authProvider} theme={theme}>
         <Resource name="spaces" icon={SpaceIcon} list={SpaceList} show={SpaceShow} edit={SpaceEdit} create={SpaceCreate} />
         <Resource name="identity_providers" icon={ProvidersIcon} list={ProvidersList} show={ProvidersShow} edit={ProvidersEdit} create={ProvidersCreate} />
-        <Resource name="users" icon={UsersIcon} list={ListGuesser} show={ShowGuesser} edit={UserEdit} />
-        <Resource name="apps" icon={AppsIcon} list={ListGuesser} show={ShowGuesser} edit={EditGuesser} />
+        <Resource name="users" icon={UsersIcon} list={UserList} show={ShowGuesser} edit={UserEdit} />
+        <Resource name="apps" icon={AppsIcon} list={AppsList} show={AppsShow} edit={AppsEdit} create={AppsCreate} />
+        <Resource name="webhook_deliveries" list={ListGuesser} show={ShowGuesser} />
+        <Resource name="audit" list={ListGuesser} />
     </Admin>
 );
 
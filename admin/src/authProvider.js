@@ -0,0 +1,24 @@
+// Operators sign in with the login & password or with the OIDC flow of the admin space,
+// the session is kept in the http-only cookie set by the server.
+const request = (url, method = 'GET', body) =>
+    fetch(url, {
+        method,
+        credentials: 'include',
+        headers: new Headers({ 'Content-Type': 'application/json' }),
+        body: body && JSON.stringify(body),
+    }).then(response => {
+        if (response.status < 200 || response.status >= 300) {
+            throw new Error(response.statusText);
+        }
+        return response;
+    });
+
+const authProvider = {
+    login: ({ username, password }) => request('/api/auth/login', 'POST', { login: username, password }),
+    logout: () => request('/api/auth/logout', 'POST').catch(() => {}),
+    checkAuth: () => request('/api/auth/me'),
+    checkError: error => (error.status === 401 ? Promise.reject() : Promise.resolve()),
+    getPermissions: () => Promise.resolve(),
+};
+
+export default authProvider;
@@ -0,0 +1,96 @@
+
+import React from 'react';
+import {
+ List, Datagrid, Filter,
+ SimpleForm, Create,
+ Show, SimpleShowLayout,
+ Edit, SimpleFormIterator,
+ BooleanField, DateField, TextField, ReferenceField,
+ BooleanInput, NullableBooleanInput, TextInput, ReferenceInput, SelectInput, ArrayInput,
+} from 'react-admin';
+import icon from '@material-ui/icons/Tablet';
+export const AppsIcon = icon
+
+
+const AppsFilter = props => (
+    <Filter {...props}>
+        <TextInput label="Search" source="q" alwaysOn />
+        <ReferenceInput source="space_id" reference="spaces"><SelectInput optionText="name" /></ReferenceInput>
+        <NullableBooleanInput source="is_active" />
+    </Filter>
+);
+
+export const AppsList = props => (
+    <List {...props} filters={<AppsFilter />}>
+        <Datagrid rowClick="show">
+            <TextField source="name" />
+            <ReferenceField source="space_id" reference="spaces" sortable={false}><TextField source="name" /></ReferenceField>
+            <BooleanField source="is_active" sortable={false} />
+            <DateField source="updated_at" />
+            <TextField source="id" />
+        </Datagrid>
+    </List>
+);
+
+const UrlsField = ({ record, source }) => (
+    <ul>
+        {(record[source] || []).map(item => (
+            <li key={item}>{item}</li>
+        ))}
+    </ul>
+)
+UrlsField.defaultProps = { addLabel: true };
+
+export const AppsShow = props => (
+    <Show {...props}>
+        <SimpleShowLayout>
+            <TextField source="id" />
+            <ReferenceField source="space_id" reference="spaces"><TextField source="name" /></ReferenceField>
+            <TextField source="name" />
+            <TextField source="description" />
+            <BooleanField source="is_active" />
+            <UrlsField source="auth_redirect_urls" />
+            <UrlsField source="post_logout_redirect_urls" />
+            <UrlsField source="web_hooks" />
+            <DateField source="created_at" showTime />
+            <DateField source="updated_at" showTime />
+        </SimpleShowLayout>
+    </Show>
+);
+
+const UrlsInput = props => (
+    <ArrayInput {...props}>
+        <SimpleFormIterator>
+            <TextInput type="url" />
+        </SimpleFormIterator>
+    </ArrayInput>
+);
+
+export const AppsEdit = props => (
+    <Edit {...props}>
+        <SimpleForm>
+            <TextInput source="id" disabled />
+            <ReferenceInput source="space_id" reference="spaces"><SelectInput optionText="name" disabled /></ReferenceInput>
+            <TextInput source="name" />
+            <TextInput source="description" multiline />
+            <BooleanInput source="is_active" />
+            <UrlsInput source="auth_redirect_urls" />
+            <UrlsInput source="post_logout_redirect_urls" />
+            <UrlsInput source="web_hooks" />
+        </SimpleForm>
+    </Edit>
+);
+
+export const AppsCreate = props => (
+    <Create {...props}>
+        <SimpleForm redirect="show">
+            <ReferenceInput source="space_id" reference="spaces"><SelectInput optionText="name" /></ReferenceInput>
+            <TextInput source="name" />
+            <TextInput source="description" multiline />
+            <BooleanInput source="is_active" defaultValue={true} />
+            <UrlsInput source="auth_redirect_urls" />
+            <UrlsInput source="post_logout_redirect_urls" />
+            <UrlsInput source="web_hooks" />
+        </SimpleForm>
+    </Create>
+);
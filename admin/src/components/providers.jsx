@@ -2,24 +2,57 @@
 import React from 'react';
 import {  
  Resource,
- List, Datagrid, 
+ List, Datagrid, Filter,
  SimpleForm,  Create,
  Show,  TabbedShowLayout, Tab, SimpleShowLayout,
  Edit, TabbedForm, FormTab, SimpleFormIterator,
  NumberField, BooleanField,  DateField, TextField, ReferenceField, ArrayField, SingleFieldList, UrlField,
- BooleanInput, DateInput, NumberInput, TextInput, ReferenceInput, SelectInput, ArrayInput,
+ BooleanInput, DateInput, NumberInput, TextInput, ReferenceInput, SelectInput, ArrayInput, FormDataConsumer,
 } from 'react-admin';
 import icon from '@material-ui/icons/SyncAlt';
 export const ProvidersIcon = icon
 
+const providerTypes = [
+    { id: 'password', name: 'Password' },
+    { id: 'social', name: 'Social' },
+    { id: 'oidc', name: 'OpenID Connect' },
+    { id: 'saml', name: 'SAML' },
+    { id: 'steam', name: 'Steam' },
+];
+
+const claims = ['external_id', 'email', 'email_verified', 'username', 'name', 'first_name', 'last_name', 'birthday', 'picture'];
+
+// ProviderTypeInputs are the inputs of the issuer, the SAML certificate and the Apple keys shown for the type of the provider.
+const ProviderTypeInputs = ({ formData, scopedFormData, getSource, ...rest }) => (
+    <>
+        {['oidc', 'saml', 'social'].includes(formData.type) &&
+            <TextInput source="issuer" type="url" {...rest} />}
+        {formData.type === 'saml' &&
+            <TextInput source="certificate" multiline {...rest} />}
+        {formData.type === 'social' && formData.name === 'apple' &&
+            <>
+                <TextInput source="team_id" {...rest} />
+                <TextInput source="key_id" {...rest} />
+            </>}
+    </>
+);
+
+
+const ProvidersFilter = props => (
+    <Filter {...props}>
+        <TextInput label="Search" source="q" alwaysOn />
+        <ReferenceInput source="space_id" reference="spaces"><SelectInput optionText="name" /></ReferenceInput>
+        <SelectInput source="type" choices={providerTypes} />
+    </Filter>
+);
 
 export const ProvidersList = props => (
-    <List {...props}>
+    <List {...props} filters={<ProvidersFilter />}>
         <Datagrid rowClick="show">
             <TextField source="display_name" />
             <TextField source="name" />
             <TextField source="type" />
-            <ReferenceField source="space_id" reference="spaces"><TextField source="name" /></ReferenceField>
+            <ReferenceField source="space_id" reference="spaces" sortable={false}><TextField source="name" /></ReferenceField>
             <TextField source="id" />
         </Datagrid>
     </List>
@@ -49,6 +82,13 @@ export const ProvidersShow = props => (
             <UrlField source="endpoint_auth_url" />
             <UrlField source="endpoint_token_url" />
             <UrlField source="endpoint_user_info_url" />
+            <TextField source="issuer" />
+            <TextField source="certificate" />
+            <TextField source="team_id" />
+            <TextField source="key_id" />
+            {claims.map(claim => (
+                <TextField key={claim} source={`claim_mapping.${claim}`} label={`Claim ${claim}`} />
+            ))}
         </SimpleShowLayout>
     </Show>
 );
@@ -59,10 +99,7 @@ export const ProvidersEdit = props => (
             <TextInput source="id" disabled />
             <ReferenceInput source="space_id" reference="spaces"><SelectInput optionText="name" disabled /></ReferenceInput>
             <TextInput source="name" />
-			<SelectInput source="type" choices={[
-    			{ id: 'password', name: 'Password' },
-    			{ id: 'social', name: 'Social' },
-			]} disabled />
+			<SelectInput source="type" choices={providerTypes} disabled />
             <TextInput source="display_name" />
             <TextInput source="client_id" />
             <TextInput source="client_secret" />
@@ -74,6 +111,12 @@ export const ProvidersEdit = props => (
             <TextInput source="endpoint_auth_url" type="url"/>
             <TextInput source="endpoint_token_url" type="url"/>
             <TextInput source="endpoint_user_info_url" type="url"/>
+            <FormDataConsumer>
+                {props => <ProviderTypeInputs {...props} />}
+            </FormDataConsumer>
+            {claims.map(claim => (
+                <TextInput key={claim} source={`claim_mapping.${claim}`} label={`Claim ${claim}`} />
+            ))}
         </SimpleForm>
     </Edit>
 );
@@ -84,10 +127,7 @@ export const ProvidersCreate = props => (
             {/*<TextInput source="id" disabled />*/}
             <ReferenceInput source="space_id" reference="spaces"><SelectInput optionText="name" /></ReferenceInput>
             <TextInput source="name" />
-			<SelectInput source="type" choices={[
-    			{ id: 'password', name: 'Password' },
-    			{ id: 'social', name: 'Social' },
-			]} />
+			<SelectInput source="type" choices={providerTypes} />
             <TextInput source="display_name" />
             <TextInput source="client_id" />
             <TextInput source="client_secret" />
@@ -99,6 +139,12 @@ export const ProvidersCreate = props => (
             <TextInput source="endpoint_auth_url" type="url"/>
             <TextInput source="endpoint_token_url" type="url"/>
             <TextInput source="endpoint_user_info_url" type="url"/>
+            <FormDataConsumer>
+                {props => <ProviderTypeInputs {...props} />}
+            </FormDataConsumer>
+            {claims.map(claim => (
+                <TextInput key={claim} source={`claim_mapping.${claim}`} label={`Claim ${claim}`} />
+            ))}
         </SimpleForm>
     </Create>
 );
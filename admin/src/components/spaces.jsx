@@ -2,16 +2,22 @@
 import React from 'react';
 import {  
  Resource,
- List, Datagrid, 
+ List, Datagrid, Filter,
  SimpleForm,  Create,
  Show,  TabbedShowLayout, Tab,
  Edit, TabbedForm, FormTab,
  NumberField, BooleanField,  DateField, TextField, ArrayField,
- BooleanInput, DateInput, NumberInput, TextInput, ArrayInput, SimpleFormIterator
+ BooleanInput, DateInput, NumberInput, TextInput, ArrayInput, SimpleFormIterator, SelectInput
 } from 'react-admin';
 import spaceIcon from '@material-ui/icons/Book';
 export const SpaceIcon = spaceIcon
 
+const passwordAlgorithms = [
+    { id: 'bcrypt', name: 'bcrypt' },
+    { id: 'argon2id', name: 'argon2id' },
+    { id: 'scrypt', name: 'scrypt' },
+];
+
 export const SpaceCreate = (props) => (
     <Create {...props}>
         <SimpleForm>
@@ -21,11 +27,17 @@ export const SpaceCreate = (props) => (
     </Create>
 );
 
+const SpaceFilter = props => (
+    <Filter {...props}>
+        <TextInput label="Search" source="q" alwaysOn />
+    </Filter>
+);
+
 export const SpaceList = props => (
-    <List {...props}>
+    <List {...props} filters={<SpaceFilter />}>
         <Datagrid rowClick="show">
             <TextField source="name" />
-            <TextField source="description" />
+            <TextField source="description" sortable={false} />
             <TextField source="id" />
             <TextField source="created_at" />
             <TextField source="updated_at" />
@@ -46,12 +58,21 @@ export const SpaceShow = props => (
 
                 <BooleanField source="unique_usernames" />
                 <BooleanField source="requires_captcha" />
+                <BooleanField source="requires_mfa" />
+                <BooleanField source="requires_verified_email" />
 
                 <DateField source="created_at" />
                 <DateField source="updated_at" />
             </Tab>
             <Tab label="Password Settings">
+                <TextField source="password_settings.algorithm" label="Algorithm" />
                 <NumberField source="password_settings.bcrypt_cost"  label="BCrypt Cost"/>
+                <NumberField source="password_settings.argon2_memory" label="Argon2 Memory (KiB)" />
+                <NumberField source="password_settings.argon2_time" label="Argon2 Time" />
+                <NumberField source="password_settings.argon2_threads" label="Argon2 Threads" />
+                <NumberField source="password_settings.scrypt_n" label="Scrypt N" />
+                <NumberField source="password_settings.scrypt_r" label="Scrypt R" />
+                <NumberField source="password_settings.scrypt_p" label="Scrypt P" />
                 <NumberField source="password_settings.min" label="Min" />
                 <NumberField source="password_settings.max" label="Max" />
                 <NumberField source="password_settings.token_length" label="Token Length" />
@@ -61,6 +82,14 @@ export const SpaceShow = props => (
                 <BooleanField source="password_settings.require_number" label="Require Number" />
                 <BooleanField source="password_settings.require_special" label="Require Special" />
             </Tab>
+            <Tab label="Lockout Settings">
+                <NumberField source="lockout_settings.max_attempts" label="Max Attempts" />
+                <NumberField source="lockout_settings.ip_max_attempts" label="IP Max Attempts" />
+                <NumberField source="lockout_settings.window" label="Window (s)" />
+                <NumberField source="lockout_settings.lock_duration" label="Lock Duration (s)" />
+                <NumberField source="lockout_settings.delay" label="Delay (s)" />
+                <NumberField source="lockout_settings.max_delay" label="Max Delay (s)" />
+            </Tab>
         </TabbedShowLayout>
     </Show>
 );
@@ -74,6 +103,8 @@ export const SpaceEdit = props => (
                 <TextInput source="description" />
                 <BooleanInput source="unique_usernames" />
                 <BooleanInput source="requires_captcha" />
+                <BooleanInput source="requires_mfa" />
+                <BooleanInput source="requires_verified_email" />
 
                 <ArrayInput source="roles">
                     <SimpleFormIterator>
@@ -86,7 +117,14 @@ export const SpaceEdit = props => (
                 <DateInput disabled source="updated_at" />
             </FormTab>
             <FormTab label="Password Settings">
+                <SelectInput source="password_settings.algorithm" label="Algorithm" choices={passwordAlgorithms} />
                 <NumberInput source="password_settings.bcrypt_cost"  label="BCrypt Cost"/>
+                <NumberInput source="password_settings.argon2_memory" label="Argon2 Memory (KiB)" />
+                <NumberInput source="password_settings.argon2_time" label="Argon2 Time" />
+                <NumberInput source="password_settings.argon2_threads" label="Argon2 Threads" />
+                <NumberInput source="password_settings.scrypt_n" label="Scrypt N" />
+                <NumberInput source="password_settings.scrypt_r" label="Scrypt R" />
+                <NumberInput source="password_settings.scrypt_p" label="Scrypt P" />
                 <NumberInput source="password_settings.min" label="Min" />
                 <NumberInput source="password_settings.max" label="Max" />
                 <NumberInput source="password_settings.token_length" label="Token Length" />
@@ -96,6 +134,14 @@ export const SpaceEdit = props => (
                 <BooleanInput source="password_settings.require_number" label="Require Number" />
                 <BooleanInput source="password_settings.require_special" label="Require Special" />
             </FormTab>
+            <FormTab label="Lockout Settings">
+                <NumberInput source="lockout_settings.max_attempts" label="Max Attempts" />
+                <NumberInput source="lockout_settings.ip_max_attempts" label="IP Max Attempts" />
+                <NumberInput source="lockout_settings.window" label="Window (s)" />
+                <NumberInput source="lockout_settings.lock_duration" label="Lock Duration (s)" />
+                <NumberInput source="lockout_settings.delay" label="Delay (s)" />
+                <NumberInput source="lockout_settings.max_delay" label="Max Delay (s)" />
+            </FormTab>
         </TabbedForm>
     </Edit>
 );
\ No newline at end of file
@@ -1,10 +1,37 @@
 
 import React from 'react';
 import {
-    SimpleForm,
-    Edit,  TextInput, ArrayInput, SimpleFormIterator
+    List, Datagrid, Filter,
+    TextField, BooleanField, DateField, ReferenceField,
+    SimpleForm, ReferenceInput, SelectInput,
+    Edit,  TextInput, ArrayInput, SimpleFormIterator, BooleanInput, NullableBooleanInput
 } from 'react-admin';
 
+const UserFilter = props => (
+    <Filter {...props}>
+        <TextInput label="Search" source="q" alwaysOn />
+        <ReferenceInput source="space_id" reference="spaces"><SelectInput optionText="name" /></ReferenceInput>
+        <TextInput source="email" />
+        <TextInput source="name" />
+        <NullableBooleanInput source="blocked" />
+        <NullableBooleanInput source="email_verified" />
+    </Filter>
+);
+
+export const UserList = props => (
+    <List {...props} filters={<UserFilter />}>
+        <Datagrid rowClick="edit">
+            <TextField source="email" />
+            <TextField source="name" />
+            <ReferenceField source="space_id" reference="spaces" sortable={false}><TextField source="name" /></ReferenceField>
+            <BooleanField source="email_verified" sortable={false} />
+            <BooleanField source="blocked" sortable={false} />
+            <DateField source="created_at" />
+            <TextField source="id" />
+        </Datagrid>
+    </List>
+);
+
 export const UserEdit = props => (
     <Edit {...props}>
         <SimpleForm>
@@ -12,6 +39,9 @@ export const UserEdit = props => (
             <TextInput disabled source="id" />
             <TextInput source="name" />
             <TextInput source="email" />
+            <BooleanInput source="blocked" />
+            <TextInput disabled source="blocked_reason" />
+            <TextInput disabled source="blocked_until" />
 
             <ArrayInput source="roles">
                 <SimpleFormIterator>
@@ -6,7 +6,13 @@ import (
 	"github.com/ProtocolONE/auth1.protocol.one/internal/admin"
 	"github.com/ProtocolONE/auth1.protocol.one/internal/app/container/env"
 	"github.com/ProtocolONE/auth1.protocol.one/internal/app/container/repository"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/app/container/service"
 	"github.com/ProtocolONE/auth1.protocol.one/pkg/config"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/persist"
+	rediswatcher "github.com/ProtocolONE/auth1.protocol.one/pkg/persist/redis"
+	appservice "github.com/ProtocolONE/auth1.protocol.one/pkg/service"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/webhooks"
+	"github.com/go-redis/redis"
 	"github.com/spf13/cobra"
 	"go.uber.org/fx"
 	"go.uber.org/zap"
@@ -27,16 +33,33 @@ func runAdminServer(cmd *cobra.Command, args []string) error {
 	db := createDatabase(&cfg.Database)
 	defer db.Close()
 
+	redisClient := redis.NewClient(&redis.Options{
+		Addr:     cfg.Redis.Addr,
+		Password: cfg.Redis.Password,
+	})
+	defer redisClient.Close()
+
+	hydraSDK := newHydraClient(&cfg.Hydra)
+
 	app := fx.New(
 		env.New(),
 		env.NewDB(db.DB(""))(),
+		env.NewRedis(redisClient)(),
 		repository.New(),
+		service.New(),
+		fx.Supply(&cfg.Webhooks, &cfg.Operators, &cfg.OIDC, &cfg.Hydra, &cfg.Proxy),
 		fx.Provide(
+			func() appservice.HydraAdminApi { return hydraSDK.Admin },
+			func() persist.Watcher { return rediswatcher.NewWatcher(redisClient) },
 			admin.NewServer,
+			admin.NewAuthHandler,
 			admin.NewSpaceHandler,
 			admin.NewProvidersHandler,
 			admin.NewUsersHandler,
 			admin.NewApplicationsHandler,
+			admin.NewWebhooksHandler,
+			admin.NewAuditHandler,
+			webhooks.NewWebhooks,
 		),
 		fx.Invoke(func(s *admin.Server) {
 			//
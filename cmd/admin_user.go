@@ -0,0 +1,209 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ProtocolONE/auth1.protocol.one/internal/app/container/env"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/app/container/repository"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/app/container/service"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
+	domain "github.com/ProtocolONE/auth1.protocol.one/internal/domain/service"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/service/operator"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/config"
+	"github.com/globalsign/mgo/bson"
+	"github.com/go-redis/redis"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+)
+
+var adminUserCmd = &cobra.Command{
+	Use:   "user",
+	Short: "Manage operator accounts of the administration server",
+}
+
+var adminUserCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Create operator account",
+	RunE:  runAdminUserCreate,
+}
+
+var adminUserGrantCmd = &cobra.Command{
+	Use:   "grant",
+	Short: "Replace roles of operator account",
+	RunE:  runAdminUserGrant,
+}
+
+var adminUserCreateFlags struct {
+	login         string
+	name          string
+	password      string
+	passwordStdin bool
+	subject       string
+	hash          string
+	roles         []string
+}
+
+var adminUserGrantFlags struct {
+	login string
+	roles []string
+}
+
+const roleUsage = "role of the operator: super_admin, space_admin:<space id> or support:<space id>, may be repeated"
+
+func init() {
+	f := adminUserCreateCmd.Flags()
+	f.StringVar(&adminUserCreateFlags.login, "login", "", "login of the operator, the verified email of the admin space user is linked to it on the OIDC sign in")
+	f.StringVar(&adminUserCreateFlags.name, "name", "", "display name of the operator")
+	f.StringVar(&adminUserCreateFlags.password, "password", "", "password of the operator, the operator may sign in only with OIDC without it")
+	f.BoolVar(&adminUserCreateFlags.passwordStdin, "password-stdin", false, "read the password from the standard input")
+	f.StringVar(&adminUserCreateFlags.subject, "subject", "", "id of the admin space user linked to the operator")
+	f.StringVar(&adminUserCreateFlags.hash, "hash", operator.AlgorithmBcrypt, "password hash algorithm: bcrypt, argon2id or scrypt")
+	f.StringArrayVar(&adminUserCreateFlags.roles, "role", nil, roleUsage)
+	adminUserCreateCmd.MarkFlagRequired("login")
+
+	f = adminUserGrantCmd.Flags()
+	f.StringVar(&adminUserGrantFlags.login, "login", "", "login of the operator")
+	f.StringArrayVar(&adminUserGrantFlags.roles, "role", nil, roleUsage)
+	adminUserGrantCmd.MarkFlagRequired("login")
+
+	adminUserCmd.AddCommand(adminUserCreateCmd)
+	adminUserCmd.AddCommand(adminUserGrantCmd)
+}
+
+func runAdminUserCreate(cmd *cobra.Command, args []string) error {
+	var cfg config.Admin
+	if err := config.Load(&cfg); err != nil {
+		logger.Fatal("Failed to load config", zap.Error(err))
+	}
+
+	password := adminUserCreateFlags.password
+	if adminUserCreateFlags.passwordStdin {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return err
+		}
+		password = strings.TrimRight(line, "\r\n")
+	}
+
+	grants, err := parseGrants(adminUserCreateFlags.roles)
+	if err != nil {
+		return err
+	}
+
+	operators, closer, err := newOperatorService(&cfg)
+	if err != nil {
+		return err
+	}
+	defer closer()
+
+	op, err := operators.Create(context.Background(), domain.CreateOperatorData{
+		Login:     adminUserCreateFlags.login,
+		Name:      adminUserCreateFlags.name,
+		Password:  password,
+		Subject:   adminUserCreateFlags.subject,
+		Grants:    grants,
+		Algorithm: adminUserCreateFlags.hash,
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Operator %s created with id %s\n", op.Login, op.ID)
+
+	return nil
+}
+
+func runAdminUserGrant(cmd *cobra.Command, args []string) error {
+	var cfg config.Admin
+	if err := config.Load(&cfg); err != nil {
+		logger.Fatal("Failed to load config", zap.Error(err))
+	}
+
+	grants, err := parseGrants(adminUserGrantFlags.roles)
+	if err != nil {
+		return err
+	}
+
+	operators, closer, err := newOperatorService(&cfg)
+	if err != nil {
+		return err
+	}
+	defer closer()
+
+	op, err := operators.Grant(context.Background(), adminUserGrantFlags.login, grants)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Operator %s granted %d role(s)\n", op.Login, len(op.Grants))
+
+	return nil
+}
+
+// newOperatorService connects to the storages and returns the operator service with the function closing them.
+func newOperatorService(cfg *config.Admin) (domain.OperatorService, func(), error) {
+	var operators domain.OperatorService
+	closer, err := populateServices(cfg, &operators)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return operators, closer, nil
+}
+
+// populateServices connects to the storages and fills the targets with the services, the returned function
+// closes the storages.
+func populateServices(cfg *config.Admin, targets ...interface{}) (func(), error) {
+	db := createDatabase(&cfg.Database)
+
+	redisClient := redis.NewClient(&redis.Options{
+		Addr:     cfg.Redis.Addr,
+		Password: cfg.Redis.Password,
+	})
+
+	closer := func() {
+		redisClient.Close()
+		db.Close()
+	}
+
+	app := fx.New(
+		fx.NopLogger,
+		env.New(),
+		env.NewDB(db.DB(""))(),
+		env.NewRedis(redisClient)(),
+		repository.New(),
+		service.New(),
+		fx.Supply(&cfg.Operators),
+		fx.Populate(targets...),
+	)
+	if err := app.Err(); err != nil {
+		closer()
+		return nil, err
+	}
+
+	return closer, nil
+}
+
+// parseGrants parses the roles in the form of "super_admin" or "<role>:<space id>".
+func parseGrants(roles []string) ([]entity.OperatorGrant, error) {
+	var grants []entity.OperatorGrant
+	for _, role := range roles {
+		parts := strings.SplitN(role, ":", 2)
+		grant := entity.OperatorGrant{Role: entity.OperatorRole(parts[0])}
+		if len(parts) == 2 {
+			grant.SpaceID = entity.SpaceID(parts[1])
+		}
+		if grant.Role != entity.OperatorSuperAdmin && !bson.IsObjectIdHex(string(grant.SpaceID)) {
+			return nil, errors.Errorf("invalid space of the role %q", role)
+		}
+		grants = append(grants, grant)
+	}
+
+	return grants, nil
+}
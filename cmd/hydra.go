@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"net/url"
+
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/config"
+	"github.com/go-openapi/runtime"
+	httptransport "github.com/go-openapi/runtime/client"
+	"github.com/go-openapi/strfmt"
+	"github.com/ory/hydra-client-go/client"
+	"go.uber.org/zap"
+)
+
+func newHydraClient(cfg *config.Hydra) *client.OryHydra {
+	u, err := url.Parse(cfg.AdminURL)
+	if err != nil {
+		zap.L().Fatal("Invalid of the Hydra admin url", zap.Error(err))
+	}
+
+	transport := httptransport.New(u.Host, "", []string{u.Scheme})
+	transport.DefaultAuthentication = runtime.ClientAuthInfoWriterFunc(func(req runtime.ClientRequest, _ strfmt.Registry) error {
+		req.SetHeaderParam("X-Forwarded-Proto", "https")
+		return nil
+	})
+
+	return client.New(transport, nil)
+}
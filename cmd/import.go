@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
+	domain "github.com/ProtocolONE/auth1.protocol.one/internal/domain/service"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/config"
+	"github.com/globalsign/mgo/bson"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var importCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Import data from other systems",
+}
+
+var importUsersCmd = &cobra.Command{
+	Use:   "users <file>",
+	Short: "Import users into the space from the jsonl or csv file, \"-\" reads the standard input",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runImportUsers,
+}
+
+var importUsersFlags struct {
+	space    string
+	app      string
+	format   string
+	dryRun   bool
+	firebase domain.FirebaseScryptOptions
+}
+
+func init() {
+	f := importUsersCmd.Flags()
+	f.StringVar(&importUsersFlags.space, "space", "", "id of the space the users are imported into")
+	f.StringVar(&importUsersFlags.app, "app", "", "id of the application the users are registered with, the first application of the space by default")
+	f.StringVar(&importUsersFlags.format, "format", domain.ImportFormatJSONL, "format of the file: jsonl or csv")
+	f.BoolVar(&importUsersFlags.dryRun, "dry-run", false, "check the records without creating the users")
+	f.StringVar(&importUsersFlags.firebase.SignerKey, "firebase-signer-key", "", "base64 signer key of the firebase project for the firebase-scrypt hashes")
+	f.StringVar(&importUsersFlags.firebase.SaltSeparator, "firebase-salt-separator", "", "base64 salt separator of the firebase project")
+	f.IntVar(&importUsersFlags.firebase.Rounds, "firebase-rounds", 8, "rounds of the firebase project")
+	f.IntVar(&importUsersFlags.firebase.MemCost, "firebase-mem-cost", 14, "memory cost of the firebase project")
+	importUsersCmd.MarkFlagRequired("space")
+
+	importCmd.AddCommand(importUsersCmd)
+}
+
+func runImportUsers(cmd *cobra.Command, args []string) error {
+	var cfg config.Admin
+	if err := config.Load(&cfg); err != nil {
+		logger.Fatal("Failed to load config", zap.Error(err))
+	}
+
+	if !bson.IsObjectIdHex(importUsersFlags.space) {
+		return errors.Errorf("invalid space id %q", importUsersFlags.space)
+	}
+
+	var r io.Reader = os.Stdin
+	if args[0] != "-" {
+		f, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var users domain.UserImportService
+	closer, err := populateServices(&cfg, &users)
+	if err != nil {
+		return err
+	}
+	defer closer()
+
+	result, err := users.Import(context.Background(), r, domain.ImportUsersOptions{
+		SpaceID:  entity.SpaceID(importUsersFlags.space),
+		AppID:    entity.AppID(importUsersFlags.app),
+		Format:   importUsersFlags.format,
+		Firebase: importUsersFlags.firebase,
+		DryRun:   importUsersFlags.dryRun,
+	})
+	if result != nil {
+		for _, e := range result.Errors {
+			fmt.Printf("Line %d %s: %s\n", e.Line, e.Email, e.Error)
+		}
+		fmt.Printf("Users created: %d, skipped: %d, failed: %d\n", result.Created, result.Skipped, len(result.Errors))
+	}
+
+	return err
+}
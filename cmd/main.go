@@ -22,6 +22,9 @@ func Execute() {
 	root.AddCommand(serverCmd)
 	// administration server
 	root.AddCommand(adminCmd)
+	adminCmd.AddCommand(adminUserCmd)
+	// import of the users from other systems
+	root.AddCommand(importCmd)
 
 	logger = appcore.InitLogger()
 	defer logger.Sync() // flushes buffer, if any
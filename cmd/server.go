@@ -3,29 +3,29 @@ package cmd
 import (
 	"log"
 	"net/http"
-	"net/url"
 	"os"
 	"os/signal"
 	"sync"
 	"syscall"
 
 	"github.com/ProtocolONE/auth1.protocol.one/internal/app"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
 	"github.com/ProtocolONE/auth1.protocol.one/pkg/api"
 	"github.com/ProtocolONE/auth1.protocol.one/pkg/config"
 	"github.com/ProtocolONE/auth1.protocol.one/pkg/database"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/passwords"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/saml"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/service"
 	"github.com/ProtocolONE/geoip-service/pkg"
 	geoproto "github.com/ProtocolONE/geoip-service/pkg/proto"
 	"github.com/ProtocolONE/mfa-service/pkg"
 	"github.com/ProtocolONE/mfa-service/pkg/proto"
 	"github.com/boj/redistore"
-	"github.com/go-openapi/runtime"
-	httptransport "github.com/go-openapi/runtime/client"
-	"github.com/go-openapi/strfmt"
+	"github.com/globalsign/mgo/bson"
 	"github.com/go-redis/redis"
 	"github.com/micro/go-micro"
 
 	"github.com/micro/go-plugins/client/selector/static"
-	"github.com/ory/hydra-client-go/client"
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
 )
@@ -75,30 +75,50 @@ func runServer(cmd *cobra.Command, args []string) {
 
 	zap.L().Info("Initialize micro service")
 
-	service := micro.NewService(options...)
-	service.Init()
+	microService := micro.NewService(options...)
+	microService.Init()
+
+	var ms service.MfaApiInterface
+	switch cfg.Mfa.Backend {
+	case service.MfaBackendNative:
+		ms = service.NewMfaTotpService(db)
+	case service.MfaBackendRemote:
+		ms = proto.NewMfaService(mfa.ServiceName, microService.Client())
+	default:
+		zap.L().Fatal("Unknown MFA backend", zap.String("backend", cfg.Mfa.Backend))
+	}
+	zap.L().Info("Use MFA backend", zap.String("backend", cfg.Mfa.Backend))
 
-	ms := proto.NewMfaService(mfa.ServiceName, service.Client())
+	geo := geoproto.NewGeoIpService(geoip.ServiceName, microService.Client())
 
-	geo := geoproto.NewGeoIpService(geoip.ServiceName, service.Client())
+	hydraSDK := newHydraClient(&cfg.Hydra)
 
-	u, err := url.Parse(cfg.Hydra.AdminURL)
-	if err != nil {
-		zap.L().Fatal("Invalid of the Hydra admin url", zap.Error(err))
+	var breached passwords.Corpus
+	if cfg.BreachedPasswords.File != "" {
+		corpus, err := passwords.LoadCorpus(cfg.BreachedPasswords.File)
+		if err != nil {
+			zap.L().Fatal("Unable to load breached passwords", zap.Error(err))
+		}
+		defer corpus.Close()
+		breached = corpus
+	} else {
+		warnBreachedPasswords(db)
 	}
 
-	transport := httptransport.New(u.Host, "", []string{u.Scheme})
-	transport.DefaultAuthentication = runtime.ClientAuthInfoWriterFunc(func(req runtime.ClientRequest, _ strfmt.Registry) error {
-		req.SetHeaderParam("X-Forwarded-Proto", "https")
-		return nil
-	})
-	hydraSDK := client.New(transport, nil)
-	if err != nil {
-		zap.L().Fatal("Hydra SDK creation failed", zap.Error(err))
+	var samlKey *saml.KeyPair
+	if cfg.SAML.KeyFile != "" {
+		key, err := saml.LoadKeyPair(cfg.SAML.KeyFile, cfg.SAML.CertificateFile)
+		if err != nil {
+			zap.L().Fatal("Unable to load SAML key pair", zap.Error(err))
+		}
+		samlKey = key
+	} else {
+		requireSAMLKey(db)
 	}
 
 	serverConfig := api.ServerConfig{
 		ApiConfig:     &cfg.Server,
+		Proxy:         &cfg.Proxy,
 		HydraConfig:   &cfg.Hydra,
 		SessionConfig: &cfg.Session,
 		GeoService:    geo,
@@ -108,9 +128,16 @@ func runServer(cmd *cobra.Command, args []string) {
 		RedisClient:   redisClient,
 		HydraAdminApi: hydraSDK.Admin,
 		Mailer:        &cfg.Mailer,
+		Sms:           &cfg.Sms,
+		WebAuthn:      &cfg.WebAuthn,
+		Webhooks:      &cfg.Webhooks,
+		Operators:     &cfg.Operators,
 		Recaptcha:     &cfg.Recaptcha,
 		MailTemplates: &cfg.MailTemplates,
 		Centrifugo:    &cfg.Centrifugo,
+
+		BreachedPasswords: breached,
+		SAMLKey:           samlKey,
 	}
 
 	app, server, err := app.New(db.DB(""), &serverConfig)
@@ -146,6 +173,34 @@ func runServer(cmd *cobra.Command, args []string) {
 	wg.Wait()
 }
 
+// warnBreachedPasswords warns about the spaces rejecting the breached passwords while the corpus isn't set,
+// the passwords of such spaces aren't checked against the breaches.
+func warnBreachedPasswords(db database.MgoSession) {
+	n, err := db.DB("").C(database.TableSpace).Find(bson.M{"password_settings.reject_breached": true}).Count()
+	if err != nil {
+		zap.L().Error("Unable to count spaces rejecting breached passwords", zap.Error(err))
+		return
+	}
+	if n > 0 {
+		zap.L().Warn(
+			"Breached passwords file isn't set, the passwords of the spaces with reject_breached aren't checked",
+			zap.Int("spaces", n),
+		)
+	}
+}
+
+// requireSAMLKey stops the server if any space has the SAML provider, its authentication requests can't be
+// signed without the key pair.
+func requireSAMLKey(db database.MgoSession) {
+	n, err := db.DB("").C(database.TableSpace).Find(bson.M{"identity_providers.type": string(entity.IDProviderTypeSAML)}).Count()
+	if err != nil {
+		zap.L().Fatal("Unable to count spaces with SAML providers", zap.Error(err))
+	}
+	if n > 0 {
+		zap.L().Fatal("SAML key file isn't set, the authentication requests of the SAML providers can't be signed", zap.Int("spaces", n))
+	}
+}
+
 func createDatabase(cfg *config.Database) database.MgoSession {
 	db, err := database.NewConnection(cfg)
 	if err != nil {
@@ -0,0 +1,25 @@
+package admin
+
+import (
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
+	"github.com/labstack/echo/v4"
+)
+
+// currentOperator returns the operator authenticated by the AuthHandler middleware.
+func currentOperator(ctx echo.Context) *entity.Operator {
+	return ctx.Get("operator").(*entity.Operator)
+}
+
+func canView(ctx echo.Context, space entity.SpaceID) error {
+	if !currentOperator(ctx).CanView(space) {
+		return echo.ErrForbidden
+	}
+	return nil
+}
+
+func canEdit(ctx echo.Context, space entity.SpaceID) error {
+	if !currentOperator(ctx).CanEdit(space) {
+		return echo.ErrForbidden
+	}
+	return nil
+}
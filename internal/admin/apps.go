@@ -1,33 +1,87 @@
 package admin
 
 import (
+	"context"
 	"net/http"
+	"net/url"
 	"strconv"
+	"time"
 
 	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
 	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/repository"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/service"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/appcore/log"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/helper"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/persist"
+	appservice "github.com/ProtocolONE/auth1.protocol.one/pkg/service"
+	"github.com/go-openapi/runtime"
 	"github.com/labstack/echo/v4"
+	"github.com/ory/hydra-client-go/client/admin"
+	hydra_models "github.com/ory/hydra-client-go/models"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
 )
 
 type ApplicationsHandler struct {
-	apps repository.ApplicationRepository
+	apps    repository.ApplicationRepository
+	spaces  repository.SpaceRepository
+	hydra   appservice.HydraAdminApi
+	watcher persist.Watcher
+	audit   service.AuditService
 }
 
-func NewApplicationsHandler(s repository.ApplicationRepository) *ApplicationsHandler {
-	return &ApplicationsHandler{s}
+func NewApplicationsHandler(
+	s repository.ApplicationRepository,
+	spaces repository.SpaceRepository,
+	hydra appservice.HydraAdminApi,
+	watcher persist.Watcher,
+	audit service.AuditService,
+) *ApplicationsHandler {
+	return &ApplicationsHandler{s, spaces, hydra, watcher, audit}
 }
 
 type appView struct {
-	ID                     entity.AppID `json:"id"`
-	Name                   string       `json:"name"`
-	Description            string       `json:"description"`
-	AuthRedirectUrls       []string     `json:"auth_redirect_urls"`
-	PostLogoutRedirectUrls []string     `json:"post_logout_redirect_urls"`
-	WebHooks               []string     `json:"web_hooks"`
+	ID                     entity.AppID   `json:"id"`
+	SpaceID                entity.SpaceID `json:"space_id"`
+	Name                   string         `json:"name"`
+	Description            string         `json:"description"`
+	IsActive               bool           `json:"is_active"`
+	AuthRedirectUrls       []string       `json:"auth_redirect_urls"`
+	PostLogoutRedirectUrls []string       `json:"post_logout_redirect_urls"`
+	WebHooks               []string       `json:"web_hooks"`
+	CreatedAt              time.Time      `json:"created_at"`
+	UpdatedAt              time.Time      `json:"updated_at"`
 }
 
+// appSecretView is returned only on the creation and the secret rotation, the secret can't be read later.
+type appSecretView struct {
+	appView
+	AuthSecret string `json:"auth_secret"`
+}
+
+var appSorts = map[string]string{
+	"id":         "id",
+	"name":       "name",
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+}
+
+// List returns the page of the applications, they may be filtered by space_id, the prefix of the name (q) and is_active.
 func (h *ApplicationsHandler) List(ctx echo.Context) error {
-	sx, err := h.apps.Find(ctx.Request().Context())
+	query := repository.ApplicationQuery{Search: ctx.QueryParam("q")}
+
+	var err error
+	if query.Page, err = listPage(ctx, appSorts); err != nil {
+		return err
+	}
+	if query.SpaceIDs, err = querySpaces(ctx, "space_id"); err != nil {
+		return err
+	}
+	if query.IsActive, err = queryBool(ctx, "is_active"); err != nil {
+		return err
+	}
+
+	sx, total, err := h.apps.Find(ctx.Request().Context(), query)
 	if err != nil {
 		return err
 	}
@@ -37,29 +91,322 @@ func (h *ApplicationsHandler) List(ctx echo.Context) error {
 		result = append(result, h.view(sx[i]))
 	}
 
-	ctx.Response().Header().Add("X-Total-Count", strconv.Itoa(len(sx)))
+	ctx.Response().Header().Add("X-Total-Count", strconv.Itoa(total))
 
 	return ctx.JSON(http.StatusOK, result)
 }
 
 func (h *ApplicationsHandler) Get(ctx echo.Context) error {
-	id := entity.AppID(ctx.Param("id"))
-
-	sx, err := h.apps.FindByID(ctx.Request().Context(), id)
+	sx, err := h.find(ctx)
 	if err != nil {
 		return err
 	}
+	if err := canView(ctx, sx.SpaceID); err != nil {
+		return err
+	}
 
 	return ctx.JSON(http.StatusOK, h.view(sx))
 }
 
+func (h *ApplicationsHandler) Create(ctx echo.Context) error {
+	var request appView
+	if err := ctx.Bind(&request); err != nil {
+		return err
+	}
+	if err := canEdit(ctx, request.SpaceID); err != nil {
+		return err
+	}
+	if err := validateApp(&request); err != nil {
+		return err
+	}
+
+	space, err := h.spaces.FindByID(ctx.Request().Context(), request.SpaceID)
+	if err != nil {
+		return err
+	}
+	if space == nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "space not found")
+	}
+
+	authSecret, err := helper.GetSecureRandString(64)
+	if err != nil {
+		return errors.Wrap(err, "unable to generate auth secret")
+	}
+	webHookSecret, err := helper.GetSecureRandString(64)
+	if err != nil {
+		return errors.Wrap(err, "unable to generate webhook secret")
+	}
+
+	app := &entity.Application{
+		SpaceID:                space.ID,
+		Name:                   request.Name,
+		Description:            request.Description,
+		IsActive:               request.IsActive,
+		CreatedAt:              time.Now(),
+		UpdatedAt:              time.Now(),
+		AuthSecret:             authSecret,
+		AuthRedirectUrls:       request.AuthRedirectUrls,
+		PostLogoutRedirectUrls: request.PostLogoutRedirectUrls,
+		OneTimeTokenSettings: &entity.OneTimeTokenSettings{
+			Length: 64,
+			TTL:    3600,
+		},
+		WebHooks:      request.WebHooks,
+		WebHookSecret: webHookSecret,
+	}
+	if err := h.apps.Create(ctx.Request().Context(), app); err != nil {
+		return err
+	}
+
+	if err := h.syncClient(ctx.Request().Context(), app); err != nil {
+		// INFO: The application without the client is useless, so it's removed to let the operator retry
+		if err := h.apps.Delete(ctx.Request().Context(), app.ID); err != nil {
+			return errors.Wrap(err, "unable to delete application")
+		}
+		return errors.Wrap(err, "unable to create hydra client")
+	}
+
+	record(ctx, h.audit, service.AuditEntry{
+		Action:     "application.create",
+		TargetType: "application",
+		TargetID:   string(app.ID),
+		SpaceID:    app.SpaceID,
+		After:      h.view(app),
+	})
+
+	return ctx.JSON(http.StatusOK, appSecretView{h.view(app), app.AuthSecret})
+}
+
+func (h *ApplicationsHandler) Update(ctx echo.Context) error {
+	var request appView
+	if err := ctx.Bind(&request); err != nil {
+		return err
+	}
+	if err := validateApp(&request); err != nil {
+		return err
+	}
+
+	app, err := h.find(ctx)
+	if err != nil {
+		return err
+	}
+	if err := canEdit(ctx, app.SpaceID); err != nil {
+		return err
+	}
+	before := h.view(app)
+	prev := *app
+
+	app.Name = request.Name
+	app.Description = request.Description
+	app.IsActive = request.IsActive
+	app.AuthRedirectUrls = request.AuthRedirectUrls
+	app.PostLogoutRedirectUrls = request.PostLogoutRedirectUrls
+	app.WebHooks = request.WebHooks
+	app.UpdatedAt = time.Now()
+
+	if err := h.save(ctx, app, &prev); err != nil {
+		return err
+	}
+
+	record(ctx, h.audit, service.AuditEntry{
+		Action:     "application.update",
+		TargetType: "application",
+		TargetID:   string(app.ID),
+		SpaceID:    app.SpaceID,
+		Before:     before,
+		After:      h.view(app),
+	})
+
+	return ctx.JSON(http.StatusOK, h.view(app))
+}
+
+func (h *ApplicationsHandler) Delete(ctx echo.Context) error {
+	app, err := h.find(ctx)
+	if err != nil {
+		return err
+	}
+	if err := canEdit(ctx, app.SpaceID); err != nil {
+		return err
+	}
+
+	if err := h.deleteClient(ctx.Request().Context(), app.ID); err != nil {
+		return errors.Wrap(err, "unable to delete hydra client")
+	}
+	if err := h.apps.Delete(ctx.Request().Context(), app.ID); err != nil {
+		return err
+	}
+	h.notify(ctx, app.ID)
+
+	record(ctx, h.audit, service.AuditEntry{
+		Action:     "application.delete",
+		TargetType: "application",
+		TargetID:   string(app.ID),
+		SpaceID:    app.SpaceID,
+		Before:     h.view(app),
+	})
+
+	return ctx.JSON(http.StatusOK, h.view(app))
+}
+
+// RotateSecret replaces the auth secret of the application, the previous secret stops working at once.
+func (h *ApplicationsHandler) RotateSecret(ctx echo.Context) error {
+	app, err := h.find(ctx)
+	if err != nil {
+		return err
+	}
+	if err := canEdit(ctx, app.SpaceID); err != nil {
+		return err
+	}
+
+	secret, err := helper.GetSecureRandString(64)
+	if err != nil {
+		return errors.Wrap(err, "unable to generate auth secret")
+	}
+
+	prev := *app
+	app.AuthSecret = secret
+	app.UpdatedAt = time.Now()
+
+	if err := h.save(ctx, app, &prev); err != nil {
+		return err
+	}
+
+	record(ctx, h.audit, service.AuditEntry{
+		Action:     "application.secret.rotate",
+		TargetType: "application",
+		TargetID:   string(app.ID),
+		SpaceID:    app.SpaceID,
+	})
+
+	return ctx.JSON(http.StatusOK, appSecretView{h.view(app), app.AuthSecret})
+}
+
+func (h *ApplicationsHandler) find(ctx echo.Context) (*entity.Application, error) {
+	app, err := h.apps.FindByID(ctx.Request().Context(), entity.AppID(ctx.Param("id")))
+	if err != nil {
+		return nil, err
+	}
+	if app == nil {
+		return nil, echo.ErrNotFound
+	}
+
+	return app, nil
+}
+
+// save syncs the hydra client first, so the stored application never allows more than the client. The client is
+// synced back to the previous state of the application if it can't be stored.
+func (h *ApplicationsHandler) save(ctx echo.Context, app, prev *entity.Application) error {
+	if err := h.syncClient(ctx.Request().Context(), app); err != nil {
+		return errors.Wrap(err, "unable to update hydra client")
+	}
+	if err := h.apps.Update(ctx.Request().Context(), app); err != nil {
+		if err := h.syncClient(ctx.Request().Context(), prev); err != nil {
+			log.Error(ctx.Request().Context(), "Unable to roll back hydra client", zap.String("app", string(app.ID)), zap.Error(err))
+		}
+		return err
+	}
+	h.notify(ctx, app.ID)
+
+	return nil
+}
+
+// notify drops the application from the cache of the auth servers.
+func (h *ApplicationsHandler) notify(ctx echo.Context, id entity.AppID) {
+	if err := h.watcher.Update(appservice.ApplicationWatcherChannel, string(id)); err != nil {
+		log.Error(ctx.Request().Context(), "Unable to notify about the application change", zap.String("app", string(id)), zap.Error(err))
+	}
+}
+
+// syncClient makes the hydra client match the application. The client exists only while the application
+// is active, so the inactive application can't start the authorization or exchange the tokens.
+func (h *ApplicationsHandler) syncClient(ctx context.Context, app *entity.Application) error {
+	if !app.IsActive {
+		return h.deleteClient(ctx, app.ID)
+	}
+
+	res, err := h.hydra.GetOAuth2Client(&admin.GetOAuth2ClientParams{Context: ctx, ID: string(app.ID)})
+	if hydraNotFound(err) {
+		_, err = h.hydra.CreateOAuth2Client(&admin.CreateOAuth2ClientParams{
+			Context: ctx,
+			Body: &hydra_models.OAuth2Client{
+				ClientID:               string(app.ID),
+				ClientName:             app.Name,
+				ClientSecret:           app.AuthSecret,
+				GrantTypes:             []string{"authorization_code", "refresh_token", "implicit"},
+				ResponseTypes:          []string{"code", "id_token", "token"},
+				RedirectUris:           app.AuthRedirectUrls,
+				PostLogoutRedirectUris: app.PostLogoutRedirectUrls,
+				Scope:                  "openid offline",
+			},
+		})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	client := res.Payload
+	client.ClientName = app.Name
+	client.ClientSecret = app.AuthSecret
+	client.RedirectUris = app.AuthRedirectUrls
+	client.PostLogoutRedirectUris = app.PostLogoutRedirectUrls
+
+	_, err = h.hydra.UpdateOAuth2Client(&admin.UpdateOAuth2ClientParams{Context: ctx, ID: string(app.ID), Body: client})
+	return err
+}
+
+func (h *ApplicationsHandler) deleteClient(ctx context.Context, id entity.AppID) error {
+	_, err := h.hydra.DeleteOAuth2Client(&admin.DeleteOAuth2ClientParams{Context: ctx, ID: string(id)})
+	if err != nil && !hydraNotFound(err) {
+		return err
+	}
+
+	return nil
+}
+
 func (h *ApplicationsHandler) view(s *entity.Application) appView {
 	return appView{
 		ID:                     s.ID,
+		SpaceID:                s.SpaceID,
 		Name:                   s.Name,
 		Description:            s.Description,
+		IsActive:               s.IsActive,
 		AuthRedirectUrls:       s.AuthRedirectUrls,
 		PostLogoutRedirectUrls: s.PostLogoutRedirectUrls,
 		WebHooks:               s.WebHooks,
+		CreatedAt:              s.CreatedAt,
+		UpdatedAt:              s.UpdatedAt,
+	}
+}
+
+func validateApp(request *appView) error {
+	if request.Name == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "name is required")
 	}
+	if len(request.AuthRedirectUrls) == 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "auth_redirect_urls is required")
+	}
+
+	for _, list := range [][]string{request.AuthRedirectUrls, request.PostLogoutRedirectUrls, request.WebHooks} {
+		for _, s := range list {
+			if u, err := url.Parse(s); err != nil || u.Scheme == "" || u.Host == "" {
+				return echo.NewHTTPError(http.StatusBadRequest, "invalid url "+s)
+			}
+		}
+	}
+
+	return nil
+}
+
+// hydraNotFound reports whether the hydra client doesn't exist, the unknown statuses are returned
+// by the sdk as the runtime.APIError.
+func hydraNotFound(err error) bool {
+	switch e := err.(type) {
+	case *admin.DeleteOAuth2ClientNotFound:
+		return true
+	case *runtime.APIError:
+		return e.Code == http.StatusNotFound
+	}
+
+	return false
 }
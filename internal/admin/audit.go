@@ -0,0 +1,115 @@
+package admin
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/repository"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/service"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/appcore/log"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+type AuditHandler struct {
+	records repository.AuditRepository
+}
+
+func NewAuditHandler(r repository.AuditRepository) *AuditHandler {
+	return &AuditHandler{r}
+}
+
+type auditRecordView struct {
+	ID         entity.AuditRecordID `json:"id"`
+	ActorID    entity.OperatorID    `json:"actor_id"`
+	ActorLogin string               `json:"actor_login"`
+	Action     string               `json:"action"`
+	TargetType string               `json:"target_type"`
+	TargetID   string               `json:"target_id"`
+	SpaceID    entity.SpaceID       `json:"space_id"`
+	Changes    []auditChangeView    `json:"changes"`
+	IP         string               `json:"ip"`
+	RequestID  string               `json:"request_id"`
+	CreatedAt  time.Time            `json:"created_at"`
+}
+
+type auditChangeView struct {
+	Field  string      `json:"field"`
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// List returns the page of the records set by the _start & _end params, the records may be filtered
+// by actor_id, action, target_type, target_id, space_id and the from & to time in RFC 3339.
+func (h *AuditHandler) List(ctx echo.Context) error {
+	query := repository.AuditQuery{
+		ActorID:    entity.OperatorID(ctx.QueryParam("actor_id")),
+		Action:     ctx.QueryParam("action"),
+		TargetType: ctx.QueryParam("target_type"),
+		TargetID:   ctx.QueryParam("target_id"),
+	}
+
+	var err error
+	if query.Offset, query.Limit, err = page(ctx); err != nil {
+		return err
+	}
+	if query.From, err = queryTime(ctx, "from"); err != nil {
+		return err
+	}
+	if query.To, err = queryTime(ctx, "to"); err != nil {
+		return err
+	}
+
+	if query.SpaceIDs, err = querySpaces(ctx, "space_id"); err != nil {
+		return err
+	}
+
+	sx, total, err := h.records.Find(ctx.Request().Context(), query)
+	if err != nil {
+		return err
+	}
+
+	result := make([]auditRecordView, 0, len(sx))
+	for i := range sx {
+		result = append(result, h.view(sx[i]))
+	}
+
+	ctx.Response().Header().Add("X-Total-Count", strconv.Itoa(total))
+
+	return ctx.JSON(http.StatusOK, result)
+}
+
+func (h *AuditHandler) view(r *entity.AuditRecord) auditRecordView {
+	changes := make([]auditChangeView, 0, len(r.Changes))
+	for _, c := range r.Changes {
+		changes = append(changes, auditChangeView(c))
+	}
+
+	return auditRecordView{
+		ID:         r.ID,
+		ActorID:    r.ActorID,
+		ActorLogin: r.ActorLogin,
+		Action:     r.Action,
+		TargetType: r.TargetType,
+		TargetID:   r.TargetID,
+		SpaceID:    r.SpaceID,
+		Changes:    changes,
+		IP:         r.IP,
+		RequestID:  r.RequestID,
+		CreatedAt:  r.CreatedAt,
+	}
+}
+
+// record appends the change made by the current operator to the audit trail. The failure is only logged
+// because the change has been already stored.
+func record(ctx echo.Context, audit service.AuditService, entry service.AuditEntry) {
+	entry.Actor = currentOperator(ctx)
+	entry.IP = ctx.RealIP()
+	entry.RequestID = ctx.Response().Header().Get(echo.HeaderXRequestID)
+
+	if err := audit.Record(ctx.Request().Context(), entry); err != nil {
+		log.Error(ctx.Request().Context(), "Unable to record audit", zap.String("action", entry.Action), zap.Error(err))
+	}
+}
@@ -0,0 +1,259 @@
+package admin
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/repository"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/service"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/service/operator"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/config"
+	appservice "github.com/ProtocolONE/auth1.protocol.one/pkg/service"
+	"github.com/globalsign/mgo/bson"
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+)
+
+const (
+	sessionCookie = "auth1_admin_session"
+	stateCookie   = "auth1_admin_state"
+)
+
+type AuthHandler struct {
+	operators service.OperatorService
+	users     repository.UserRepository
+	cfg       *config.AdminOIDC
+	hydra     *config.Hydra
+}
+
+func NewAuthHandler(o service.OperatorService, u repository.UserRepository, cfg *config.AdminOIDC, hydra *config.Hydra) *AuthHandler {
+	return &AuthHandler{o, u, cfg, hydra}
+}
+
+type operatorView struct {
+	ID          entity.OperatorID   `json:"id"`
+	Login       string              `json:"login"`
+	Name        string              `json:"name"`
+	Grants      []operatorGrantView `json:"grants"`
+	LastLoginAt time.Time           `json:"last_login_at"`
+}
+
+type operatorGrantView struct {
+	Role    entity.OperatorRole `json:"role"`
+	SpaceID entity.SpaceID      `json:"space_id,omitempty"`
+}
+
+type sessionView struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Middleware authenticates the operator by the bearer token or the session cookie of the panel.
+func (h *AuthHandler) Middleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(ctx echo.Context) error {
+		op, err := h.operators.Authenticate(ctx.Request().Context(), h.token(ctx))
+		if err == operator.ErrInvalidSession {
+			return echo.ErrUnauthorized
+		}
+		if err != nil {
+			return err
+		}
+
+		ctx.Set("operator", op)
+		return next(ctx)
+	}
+}
+
+func (h *AuthHandler) Login(ctx echo.Context) error {
+	var request struct {
+		Login    string `json:"login"`
+		Password string `json:"password"`
+	}
+	if err := ctx.Bind(&request); err != nil {
+		return err
+	}
+
+	session, err := h.operators.SignIn(ctx.Request().Context(), request.Login, request.Password, appservice.GetClientIP(ctx))
+	if err == operator.ErrInvalidCredentials {
+		return echo.NewHTTPError(http.StatusUnauthorized, err.Error())
+	}
+	if err == operator.ErrTooManyAttempts {
+		return echo.NewHTTPError(http.StatusTooManyRequests, err.Error())
+	}
+	if err != nil {
+		return err
+	}
+
+	h.setSession(ctx, session.Token, session.ExpiresAt)
+
+	return ctx.JSON(http.StatusOK, sessionView{Token: session.Token, ExpiresAt: session.ExpiresAt})
+}
+
+func (h *AuthHandler) Logout(ctx echo.Context) error {
+	if token := h.token(ctx); token != "" {
+		if err := h.operators.SignOut(ctx.Request().Context(), token); err != nil {
+			return err
+		}
+	}
+
+	h.setSession(ctx, "", time.Unix(0, 0))
+
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+func (h *AuthHandler) Me(ctx echo.Context) error {
+	op := currentOperator(ctx)
+
+	grants := make([]operatorGrantView, 0, len(op.Grants))
+	for _, g := range op.Grants {
+		grants = append(grants, operatorGrantView(g))
+	}
+
+	return ctx.JSON(http.StatusOK, operatorView{
+		ID:          op.ID,
+		Login:       op.Login,
+		Name:        op.Name,
+		Grants:      grants,
+		LastLoginAt: op.LastLoginAt,
+	})
+}
+
+// OIDCLogin redirects the operator to sign in with the user of the admin space.
+func (h *AuthHandler) OIDCLogin(ctx echo.Context) error {
+	if !h.oidcEnabled() {
+		return echo.ErrNotFound
+	}
+
+	state, err := randomString()
+	if err != nil {
+		return err
+	}
+	ctx.SetCookie(&http.Cookie{
+		Name:     stateCookie,
+		Value:    state,
+		Path:     "/api/auth/oidc",
+		MaxAge:   600,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	return ctx.Redirect(http.StatusFound, h.oauth2().AuthCodeURL(state))
+}
+
+// OIDCCallback exchanges the code, checks the user belongs to the admin space and opens the operator session.
+func (h *AuthHandler) OIDCCallback(ctx echo.Context) error {
+	if !h.oidcEnabled() {
+		return echo.ErrNotFound
+	}
+
+	state, err := ctx.Cookie(stateCookie)
+	if err != nil || state.Value == "" || state.Value != ctx.QueryParam("state") {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid state")
+	}
+	ctx.SetCookie(&http.Cookie{Name: stateCookie, Path: "/api/auth/oidc", MaxAge: -1})
+
+	if e := ctx.QueryParam("error"); e != "" {
+		return echo.NewHTTPError(http.StatusUnauthorized, e+": "+ctx.QueryParam("error_description"))
+	}
+
+	conf := h.oauth2()
+	token, err := conf.Exchange(ctx.Request().Context(), ctx.QueryParam("code"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, err.Error())
+	}
+
+	var claims struct {
+		Subject       string `json:"sub"`
+		Email         string `json:"email"`
+		EmailVerified bool   `json:"email_verified"`
+	}
+	resp, err := conf.Client(ctx.Request().Context(), token).Get(h.hydra.PublicURL + "/userinfo")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("userinfo request failed with status %d", resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&claims); err != nil {
+		return err
+	}
+
+	if !bson.IsObjectIdHex(claims.Subject) {
+		return echo.ErrForbidden
+	}
+	user, err := h.users.FindByID(ctx.Request().Context(), entity.UserID(claims.Subject))
+	if err != nil {
+		return err
+	}
+	if user == nil || user.IsBlocked(time.Now()) || string(user.SpaceID) != h.cfg.SpaceID {
+		return echo.ErrForbidden
+	}
+
+	session, err := h.operators.SignInSubject(ctx.Request().Context(), claims.Subject, claims.Email, claims.EmailVerified)
+	if err == operator.ErrOperatorNotLinked {
+		return echo.NewHTTPError(http.StatusForbidden, err.Error())
+	}
+	if err != nil {
+		return err
+	}
+
+	h.setSession(ctx, session.Token, session.ExpiresAt)
+
+	return ctx.Redirect(http.StatusFound, "/")
+}
+
+func (h *AuthHandler) oidcEnabled() bool {
+	return h.cfg.ClientID != "" && h.cfg.SpaceID != ""
+}
+
+func (h *AuthHandler) oauth2() *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     h.cfg.ClientID,
+		ClientSecret: h.cfg.ClientSecret,
+		RedirectURL:  h.cfg.RedirectURL,
+		Scopes:       []string{"openid", "email"},
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  h.hydra.PublicURL + "/oauth2/auth",
+			TokenURL: h.hydra.PublicURL + "/oauth2/token",
+		},
+	}
+}
+
+func (h *AuthHandler) token(ctx echo.Context) string {
+	if auth := ctx.Request().Header.Get(echo.HeaderAuthorization); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimPrefix(auth, "Bearer ")
+	}
+	if c, err := ctx.Cookie(sessionCookie); err == nil {
+		return c.Value
+	}
+
+	return ""
+}
+
+func (h *AuthHandler) setSession(ctx echo.Context, token string, expires time.Time) {
+	ctx.SetCookie(&http.Cookie{
+		Name:     sessionCookie,
+		Value:    token,
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   ctx.IsTLS(),
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func randomString() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
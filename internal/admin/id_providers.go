@@ -2,20 +2,28 @@ package admin
 
 import (
 	"net/http"
+	"net/url"
 	"strconv"
 
 	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
 	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/repository"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/service"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/apple"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/claims"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/models"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/oidc"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/saml"
 
 	"github.com/labstack/echo/v4"
 )
 
 type ProvidersHandler struct {
 	spaces repository.SpaceRepository
+	audit  service.AuditService
 }
 
-func NewProvidersHandler(s repository.SpaceRepository) *ProvidersHandler {
-	return &ProvidersHandler{s}
+func NewProvidersHandler(s repository.SpaceRepository, a service.AuditService) *ProvidersHandler {
+	return &ProvidersHandler{s, a}
 }
 
 type providerView struct {
@@ -30,22 +38,59 @@ type providerView struct {
 	EndpointAuthURL     string                    `json:"endpoint_auth_url"`
 	EndpointTokenURL    string                    `json:"endpoint_token_url"`
 	EndpointUserInfoURL string                    `json:"endpoint_user_info_url"`
+	Issuer              string                    `json:"issuer"`
+	Certificate         string                    `json:"certificate"`
+	TeamID              string                    `json:"team_id"`
+	KeyID               string                    `json:"key_id"`
+	ClaimMapping        claimMappingView          `json:"claim_mapping"`
 }
 
+type claimMappingView struct {
+	ExternalID    string `json:"external_id"`
+	Email         string `json:"email"`
+	EmailVerified string `json:"email_verified"`
+	Username      string `json:"username"`
+	Name          string `json:"name"`
+	FirstName     string `json:"first_name"`
+	LastName      string `json:"last_name"`
+	Birthday      string `json:"birthday"`
+	Picture       string `json:"picture"`
+}
+
+var providerSorts = map[string]string{
+	"id":           "id",
+	"name":         "name",
+	"display_name": "display_name",
+	"type":         "type",
+}
+
+// List returns the page of the providers, they may be filtered by space_id, the prefix of the name or
+// the display name (q) and type.
 func (h *ProvidersHandler) List(ctx echo.Context) error {
-	sx, err := h.spaces.Find(ctx.Request().Context())
+	query := repository.IdentityProviderQuery{
+		Search: ctx.QueryParam("q"),
+		Type:   entity.IDProviderType(ctx.QueryParam("type")),
+	}
+
+	var err error
+	if query.Page, err = listPage(ctx, providerSorts); err != nil {
+		return err
+	}
+	if query.SpaceIDs, err = querySpaces(ctx, "space_id"); err != nil {
+		return err
+	}
+
+	sx, total, err := h.spaces.FindProviders(ctx.Request().Context(), query)
 	if err != nil {
 		return err
 	}
 
 	result := make([]providerView, 0, len(sx))
 	for i := range sx {
-		for k := range sx[i].IdentityProviders {
-			result = append(result, h.view(sx[i], sx[i].IdentityProviders[k]))
-		}
+		result = append(result, h.view(sx[i].SpaceID, sx[i].Provider))
 	}
 
-	ctx.Response().Header().Add("X-Total-Count", strconv.Itoa(len(result)))
+	ctx.Response().Header().Add("X-Total-Count", strconv.Itoa(total))
 
 	return ctx.JSON(http.StatusOK, result)
 }
@@ -57,13 +102,16 @@ func (h *ProvidersHandler) Get(ctx echo.Context) error {
 	if err != nil {
 		return err
 	}
+	if err := canView(ctx, space.ID); err != nil {
+		return err
+	}
 
 	p, ok := space.IDProvider(id)
 	if !ok {
 		return ctx.NoContent(http.StatusNotFound)
 	}
 
-	return ctx.JSON(http.StatusOK, h.view(space, p))
+	return ctx.JSON(http.StatusOK, h.view(space.ID, p))
 }
 
 func (h *ProvidersHandler) Create(ctx echo.Context) error {
@@ -72,6 +120,10 @@ func (h *ProvidersHandler) Create(ctx echo.Context) error {
 		return err
 	}
 
+	if err := canEdit(ctx, request.SpaceID); err != nil {
+		return err
+	}
+
 	space, err := h.spaces.FindByID(ctx.Request().Context(), request.SpaceID)
 	if err != nil {
 		return err
@@ -87,6 +139,14 @@ func (h *ProvidersHandler) Create(ctx echo.Context) error {
 		EndpointAuthURL:     request.EndpointAuthURL,
 		EndpointTokenURL:    request.EndpointTokenURL,
 		EndpointUserInfoURL: request.EndpointUserInfoURL,
+		Issuer:              request.Issuer,
+		Certificate:         request.Certificate,
+		TeamID:              request.TeamID,
+		KeyID:               request.KeyID,
+		ClaimMapping:        entity.ClaimMapping(request.ClaimMapping),
+	}
+	if err := validateProvider(ctx, &p); err != nil {
+		return err
 	}
 	if err := space.AddIDProvider(p); err != nil {
 		return err
@@ -98,7 +158,15 @@ func (h *ProvidersHandler) Create(ctx echo.Context) error {
 
 	nv, _ := space.IDProviderName(p.Name)
 
-	return ctx.JSON(http.StatusOK, h.view(space, nv))
+	record(ctx, h.audit, service.AuditEntry{
+		Action:     "identity_provider.create",
+		TargetType: "identity_provider",
+		TargetID:   string(nv.ID),
+		SpaceID:    space.ID,
+		After:      h.view(space.ID, nv),
+	})
+
+	return ctx.JSON(http.StatusOK, h.view(space.ID, nv))
 }
 
 func (h *ProvidersHandler) Update(ctx echo.Context) error {
@@ -113,11 +181,15 @@ func (h *ProvidersHandler) Update(ctx echo.Context) error {
 	if err != nil {
 		return err
 	}
+	if err := canEdit(ctx, space.ID); err != nil {
+		return err
+	}
 
 	p, ok := space.IDProvider(id)
 	if !ok {
 		return ctx.NoContent(http.StatusNotFound)
 	}
+	before := h.view(space.ID, p)
 
 	p.Name = request.Name
 	p.DisplayName = request.DisplayName
@@ -127,7 +199,15 @@ func (h *ProvidersHandler) Update(ctx echo.Context) error {
 	p.EndpointAuthURL = request.EndpointAuthURL
 	p.EndpointTokenURL = request.EndpointTokenURL
 	p.EndpointUserInfoURL = request.EndpointUserInfoURL
+	p.Issuer = request.Issuer
+	p.Certificate = request.Certificate
+	p.TeamID = request.TeamID
+	p.KeyID = request.KeyID
+	p.ClaimMapping = entity.ClaimMapping(request.ClaimMapping)
 
+	if err := validateProvider(ctx, &p); err != nil {
+		return err
+	}
 	if err := space.UpdateIDProvider(p); err != nil {
 		return err
 	}
@@ -138,7 +218,35 @@ func (h *ProvidersHandler) Update(ctx echo.Context) error {
 
 	nv, _ := space.IDProvider(id)
 
-	return ctx.JSON(http.StatusOK, h.view(space, nv))
+	record(ctx, h.audit, service.AuditEntry{
+		Action:     "identity_provider.update",
+		TargetType: "identity_provider",
+		TargetID:   string(id),
+		SpaceID:    space.ID,
+		Before:     before,
+		After:      h.view(space.ID, nv),
+	})
+
+	return ctx.JSON(http.StatusOK, h.view(space.ID, nv))
+}
+
+// TestMapping runs the sample userinfo response or the claims of the provider through the claim mapping
+// and returns the profile selected, nothing is saved.
+func (h *ProvidersHandler) TestMapping(ctx echo.Context) error {
+	var request struct {
+		ClaimMapping claimMappingView `json:"claim_mapping"`
+		Payload      interface{}      `json:"payload"`
+	}
+	if err := ctx.Bind(&request); err != nil {
+		return err
+	}
+
+	profile, err := claims.Apply(entity.ClaimMapping(request.ClaimMapping), request.Payload)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	return ctx.JSON(http.StatusOK, profile)
 }
 
 func (h *ProvidersHandler) Delete(ctx echo.Context) error {
@@ -148,6 +256,15 @@ func (h *ProvidersHandler) Delete(ctx echo.Context) error {
 	if err != nil {
 		return err
 	}
+	if err := canEdit(ctx, space.ID); err != nil {
+		return err
+	}
+
+	p, ok := space.IDProvider(id)
+	if !ok {
+		return ctx.NoContent(http.StatusNotFound)
+	}
+	before := h.view(space.ID, p)
 
 	if err := space.RemoveIDProvider(id); err != nil {
 		return err
@@ -157,13 +274,21 @@ func (h *ProvidersHandler) Delete(ctx echo.Context) error {
 		return err
 	}
 
+	record(ctx, h.audit, service.AuditEntry{
+		Action:     "identity_provider.delete",
+		TargetType: "identity_provider",
+		TargetID:   string(id),
+		SpaceID:    space.ID,
+		Before:     before,
+	})
+
 	return ctx.NoContent(http.StatusNoContent)
 }
 
-func (h *ProvidersHandler) view(s *entity.Space, p entity.IdentityProvider) providerView {
+func (h *ProvidersHandler) view(space entity.SpaceID, p entity.IdentityProvider) providerView {
 	return providerView{
 		ID:                  p.ID,
-		SpaceID:             s.ID,
+		SpaceID:             space,
 		Name:                p.Name,
 		Type:                p.Type,
 		DisplayName:         p.DisplayName,
@@ -173,5 +298,89 @@ func (h *ProvidersHandler) view(s *entity.Space, p entity.IdentityProvider) prov
 		EndpointAuthURL:     p.EndpointAuthURL,
 		EndpointTokenURL:    p.EndpointTokenURL,
 		EndpointUserInfoURL: p.EndpointUserInfoURL,
+		Issuer:              p.Issuer,
+		Certificate:         p.Certificate,
+		TeamID:              p.TeamID,
+		KeyID:               p.KeyID,
+		ClaimMapping:        claimMappingView(p.ClaimMapping),
+	}
+}
+
+// validateProvider checks the expressions of the claim mapping, the OpenID Connect provider is discovered
+// by the issuer, the SAML provider has the valid certificate, the Steam provider has the Web API key and the Apple
+// provider has the key to sign the client secret, so the provider broken by the typo isn't offered to the users.
+func validateProvider(ctx echo.Context, p *entity.IdentityProvider) error {
+	if err := claims.Validate(p.ClaimMapping); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid claim_mapping: "+err.Error())
+	}
+	switch p.Type {
+	case entity.IDProviderTypeSAML:
+		return validateSAMLProvider(p)
+	case entity.IDProviderTypeSteam:
+		return validateSteamProvider(p)
+	case entity.IDProviderTypeSocial:
+		if p.Name == models.AppIdentityProviderNameApple {
+			return validateAppleProvider(p)
+		}
+	}
+	if p.Type != entity.IDProviderTypeOIDC {
+		return nil
+	}
+
+	if p.Issuer == "" || p.ClientID == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "issuer and client_id are required")
+	}
+	if !isHTTPURL(p.Issuer) {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid issuer "+p.Issuer)
+	}
+	if _, err := oidc.Discover(ctx.Request().Context(), p.Issuer); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	return nil
+}
+
+func validateSAMLProvider(p *entity.IdentityProvider) error {
+	if p.Issuer == "" || p.EndpointAuthURL == "" || p.Certificate == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "issuer, endpoint_auth_url and certificate are required")
+	}
+	if !isHTTPURL(p.EndpointAuthURL) {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid endpoint_auth_url "+p.EndpointAuthURL)
+	}
+	if _, err := saml.ParseCertificate(p.Certificate); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid certificate: "+err.Error())
+	}
+
+	return nil
+}
+
+// validateSteamProvider requires the Web API key, the endpoints of Steam are used unless they are set.
+func validateSteamProvider(p *entity.IdentityProvider) error {
+	if p.ClientSecret == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "client_secret is required, it is the steam web api key")
+	}
+	if p.EndpointAuthURL != "" && !isHTTPURL(p.EndpointAuthURL) {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid endpoint_auth_url "+p.EndpointAuthURL)
 	}
+	if p.EndpointUserInfoURL != "" && !isHTTPURL(p.EndpointUserInfoURL) {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid endpoint_userinfo_url "+p.EndpointUserInfoURL)
+	}
+
+	return nil
+}
+
+func validateAppleProvider(p *entity.IdentityProvider) error {
+	if p.ClientID == "" || p.TeamID == "" || p.KeyID == "" || p.Issuer == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "client_id, team_id, key_id and issuer are required")
+	}
+	if _, err := apple.ParseKey(p.ClientSecret); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "client_secret must be .p8 key: "+err.Error())
+	}
+
+	return nil
+}
+
+func isHTTPURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && u.Host != "" && (u.Scheme == "https" || u.Scheme == "http")
 }
@@ -0,0 +1,124 @@
+package admin
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/repository"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	listPageSize    = 25
+	listMaxPageSize = 1000
+)
+
+// page returns the offset and the limit from the _start & _end params of the list request.
+func page(ctx echo.Context) (int, int, error) {
+	start, end := 0, listPageSize
+	var err error
+	if v := ctx.QueryParam("_start"); v != "" {
+		if start, err = strconv.Atoi(v); err != nil || start < 0 {
+			return 0, 0, echo.NewHTTPError(http.StatusBadRequest, "invalid _start")
+		}
+		end = start + listPageSize
+	}
+	if v := ctx.QueryParam("_end"); v != "" {
+		if end, err = strconv.Atoi(v); err != nil || end <= start {
+			return 0, 0, echo.NewHTTPError(http.StatusBadRequest, "invalid _end")
+		}
+	}
+	if end-start > listMaxPageSize {
+		end = start + listMaxPageSize
+	}
+
+	return start, end - start, nil
+}
+
+// listPage returns the page set by the _start, _end, _sort & _order params of the list request.
+// The sorts maps the fields of the view allowed for sorting to the sort fields of the repository.
+func listPage(ctx echo.Context, sorts map[string]string) (repository.Page, error) {
+	var (
+		p   repository.Page
+		err error
+	)
+	if p.Offset, p.Limit, err = page(ctx); err != nil {
+		return p, err
+	}
+
+	if v := ctx.QueryParam("_sort"); v != "" {
+		sort, ok := sorts[v]
+		if !ok {
+			return p, echo.NewHTTPError(http.StatusBadRequest, "invalid _sort")
+		}
+		switch strings.ToUpper(ctx.QueryParam("_order")) {
+		case "", "ASC":
+			p.Sort = sort
+		case "DESC":
+			p.Sort = "-" + sort
+		default:
+			return p, echo.NewHTTPError(http.StatusBadRequest, "invalid _order")
+		}
+	}
+
+	return p, nil
+}
+
+func queryTime(ctx echo.Context, name string) (time.Time, error) {
+	v := ctx.QueryParam(name)
+	if v == "" {
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, echo.NewHTTPError(http.StatusBadRequest, "invalid "+name)
+	}
+
+	return t, nil
+}
+
+// queryBool returns nil if the param is missing, so the filter matches any value.
+func queryBool(ctx echo.Context, name string) (*bool, error) {
+	v := ctx.QueryParam(name)
+	if v == "" {
+		return nil, nil
+	}
+
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "invalid "+name)
+	}
+
+	return &b, nil
+}
+
+// querySpaces returns the spaces to filter the list by: the spaces of the param, it may be repeated,
+// or all spaces visible to the operator. The nil is returned for the super admin and matches all spaces.
+func querySpaces(ctx echo.Context, param string) ([]entity.SpaceID, error) {
+	if ids := ctx.QueryParams()[param]; len(ids) > 0 {
+		spaces := make([]entity.SpaceID, 0, len(ids))
+		for _, id := range ids {
+			if err := canView(ctx, entity.SpaceID(id)); err != nil {
+				return nil, err
+			}
+			spaces = append(spaces, entity.SpaceID(id))
+		}
+		return spaces, nil
+	}
+
+	op := currentOperator(ctx)
+	if op.IsSuperAdmin() {
+		return nil, nil
+	}
+
+	spaces := []entity.SpaceID{}
+	for _, g := range op.Grants {
+		spaces = append(spaces, g.SpaceID)
+	}
+
+	return spaces, nil
+}
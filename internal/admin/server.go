@@ -2,10 +2,10 @@ package admin
 
 import (
 	"context"
-	"crypto/sha256"
-	"encoding/hex"
 	"net/http"
 
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/config"
+	appservice "github.com/ProtocolONE/auth1.protocol.one/pkg/service"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"go.uber.org/fx"
@@ -15,10 +15,14 @@ type Params struct {
 	fx.In
 
 	fx.Lifecycle
+	Proxy     *config.Proxy
+	Auth      *AuthHandler
 	Spaces    *SpaceHandler
 	Providers *ProvidersHandler
 	Users     *UsersHandler
 	Apps      *ApplicationsHandler
+	Webhooks  *WebhooksHandler
+	Audit     *AuditHandler
 }
 
 type Server struct {
@@ -31,45 +35,56 @@ func NewServer(p Params) *Server {
 	engine.HideBanner = true
 	engine.Debug = true
 
+	engine.Use(middleware.RequestID())
+	engine.Use(appservice.ClientIP(p.Proxy.TrustedHops))
 	engine.Use(middleware.CORSWithConfig(middleware.CORSConfig{
-		ExposeHeaders: []string{"X-Total-Count"},
-		AllowHeaders:  []string{"Content-Type", "Authorization"},
-		AllowOrigins:  []string{"http://localhost:3000", "http://localhost:6001"},
-		AllowMethods:  []string{http.MethodGet, http.MethodHead, http.MethodPut, http.MethodPatch, http.MethodPost, http.MethodDelete, http.MethodOptions},
+		ExposeHeaders:    []string{"X-Total-Count"},
+		AllowHeaders:     []string{"Content-Type", "Authorization"},
+		AllowOrigins:     []string{"http://localhost:3000", "http://localhost:6001"},
+		AllowCredentials: true,
+		AllowMethods:     []string{http.MethodGet, http.MethodHead, http.MethodPut, http.MethodPatch, http.MethodPost, http.MethodDelete, http.MethodOptions},
 	}))
 
-	engine.Use(middleware.BasicAuthWithConfig(middleware.BasicAuthConfig{
-		Realm: "Auth1",
-		Validator: func(login, password string, ctx echo.Context) (bool, error) {
-			if login != "admin" {
-				return false, nil
-			}
-
-			hash := sha256.Sum256([]byte(password + "&()123#^^"))
-			if hex.EncodeToString(hash[:]) != "ef778317fa2c077d63c8d49cb3adaffa3279d2976c3ce22ee3ca65aeb849fd61" {
-				return false, nil
-			}
-			return true, nil
-		},
-	}))
-
-	engine.GET("/api/spaces", p.Spaces.List)
-	engine.POST("/api/spaces", p.Spaces.Create)
-	engine.GET("/api/spaces/:id", p.Spaces.Get)
-	engine.PUT("/api/spaces/:id", p.Spaces.Update)
-
-	engine.GET("/api/identity_providers", p.Providers.List)
-	engine.POST("/api/identity_providers", p.Providers.Create)
-	engine.GET("/api/identity_providers/:id", p.Providers.Get)
-	engine.PUT("/api/identity_providers/:id", p.Providers.Update)
-	engine.DELETE("/api/identity_providers/:id", p.Providers.Delete)
-
-	engine.GET("/api/users", p.Users.List)
-	engine.GET("/api/users/:id", p.Users.Get)
-	engine.PUT("/api/users/:id", p.Users.Update)
-
-	engine.GET("/api/apps", p.Apps.List)
-	engine.GET("/api/apps/:id", p.Apps.Get)
+	engine.POST("/api/auth/login", p.Auth.Login)
+	engine.POST("/api/auth/logout", p.Auth.Logout)
+	engine.GET("/api/auth/oidc/login", p.Auth.OIDCLogin)
+	engine.GET("/api/auth/oidc/callback", p.Auth.OIDCCallback)
+
+	api := engine.Group("/api", p.Auth.Middleware)
+
+	api.GET("/auth/me", p.Auth.Me)
+
+	api.GET("/spaces", p.Spaces.List)
+	api.POST("/spaces", p.Spaces.Create)
+	api.GET("/spaces/:id", p.Spaces.Get)
+	api.PUT("/spaces/:id", p.Spaces.Update)
+	api.POST("/spaces/:id/users/import", p.Users.Import)
+
+	api.GET("/identity_providers", p.Providers.List)
+	api.POST("/identity_providers", p.Providers.Create)
+	api.POST("/identity_providers/test_mapping", p.Providers.TestMapping)
+	api.GET("/identity_providers/:id", p.Providers.Get)
+	api.PUT("/identity_providers/:id", p.Providers.Update)
+	api.DELETE("/identity_providers/:id", p.Providers.Delete)
+
+	api.GET("/users", p.Users.List)
+	api.GET("/users/:id", p.Users.Get)
+	api.PUT("/users/:id", p.Users.Update)
+	api.POST("/users/:id/block", p.Users.Block)
+	api.POST("/users/:id/unblock", p.Users.Unblock)
+
+	api.GET("/apps", p.Apps.List)
+	api.POST("/apps", p.Apps.Create)
+	api.GET("/apps/:id", p.Apps.Get)
+	api.PUT("/apps/:id", p.Apps.Update)
+	api.DELETE("/apps/:id", p.Apps.Delete)
+	api.POST("/apps/:id/rotate_secret", p.Apps.RotateSecret)
+
+	api.GET("/webhook_deliveries", p.Webhooks.List)
+	api.GET("/webhook_deliveries/:id", p.Webhooks.Get)
+	api.POST("/webhook_deliveries/:id/replay", p.Webhooks.Replay)
+
+	api.GET("/audit", p.Audit.List)
 
 	engine.Static("/", "admin/build")
 
@@ -1,47 +1,72 @@
 package admin
 
 import (
+	"fmt"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
 	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/repository"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/service"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/hasher"
 
 	"github.com/labstack/echo/v4"
 )
 
 type SpaceHandler struct {
 	spaces repository.SpaceRepository
+	audit  service.AuditService
 }
 
-func NewSpaceHandler(s repository.SpaceRepository) *SpaceHandler {
-	return &SpaceHandler{s}
+func NewSpaceHandler(s repository.SpaceRepository, a service.AuditService) *SpaceHandler {
+	return &SpaceHandler{s, a}
 }
 
 type spaceView struct {
-	ID               entity.SpaceID       `json:"id"`
-	Name             string               `json:"name"`
-	Description      string               `json:"description"`
-	UniqueUsernames  bool                 `json:"unique_usernames"`
-	RequiresCaptcha  bool                 `json:"requires_captcha"`
-	PasswordSettings passwordSettingsView `json:"password_settings"`
-	Roles            []string             `json:"roles"`
-	DefaultRole      string               `json:"default_role"`
-	CreatedAt        time.Time            `json:"created_at"`
-	UpdatedAt        time.Time            `json:"updated_at"`
+	ID                    entity.SpaceID       `json:"id"`
+	Name                  string               `json:"name"`
+	Description           string               `json:"description"`
+	UniqueUsernames       bool                 `json:"unique_usernames"`
+	RequiresCaptcha       bool                 `json:"requires_captcha"`
+	RequiresMfa           bool                 `json:"requires_mfa"`
+	RequiresVerifiedEmail bool                 `json:"requires_verified_email"`
+	PasswordSettings      passwordSettingsView `json:"password_settings"`
+	LockoutSettings       lockoutSettingsView  `json:"lockout_settings"`
+	Roles                 []string             `json:"roles"`
+	DefaultRole           string               `json:"default_role"`
+	CreatedAt             time.Time            `json:"created_at"`
+	UpdatedAt             time.Time            `json:"updated_at"`
 }
 
 type passwordSettingsView struct {
-	BcryptCost     int  `json:"bcrypt_cost"`
-	Min            int  `json:"min"`
-	Max            int  `json:"max"`
-	RequireNumber  bool `json:"require_number"`
-	RequireUpper   bool `json:"require_upper"`
-	RequireSpecial bool `json:"require_special"`
-	RequireLetter  bool `json:"require_letter"`
-	TokenLength    int  `json:"token_length"`
-	TokenTTL       int  `json:"token_ttl"`
+	Algorithm      string `json:"algorithm"`
+	BcryptCost     int    `json:"bcrypt_cost"`
+	Argon2Memory   int    `json:"argon2_memory"`
+	Argon2Time     int    `json:"argon2_time"`
+	Argon2Threads  int    `json:"argon2_threads"`
+	ScryptN        int    `json:"scrypt_n"`
+	ScryptR        int    `json:"scrypt_r"`
+	ScryptP        int    `json:"scrypt_p"`
+	Min            int    `json:"min"`
+	Max            int    `json:"max"`
+	RequireNumber  bool   `json:"require_number"`
+	RequireUpper   bool   `json:"require_upper"`
+	RequireSpecial bool   `json:"require_special"`
+	RequireLetter  bool   `json:"require_letter"`
+	RejectBreached bool   `json:"reject_breached"`
+	HistorySize    int    `json:"history_size"`
+	TokenLength    int    `json:"token_length"`
+	TokenTTL       int    `json:"token_ttl"`
+}
+
+type lockoutSettingsView struct {
+	MaxAttempts   int `json:"max_attempts"`
+	IPMaxAttempts int `json:"ip_max_attempts"`
+	Window        int `json:"window"`
+	LockDuration  int `json:"lock_duration"`
+	Delay         int `json:"delay"`
+	MaxDelay      int `json:"max_delay"`
 }
 
 type spaceShortView struct {
@@ -52,8 +77,26 @@ type spaceShortView struct {
 	UpdatedAt   time.Time      `json:"updated_at"`
 }
 
+var spaceSorts = map[string]string{
+	"id":         "id",
+	"name":       "name",
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+}
+
+// List returns the page of the spaces visible to the operator, the spaces may be filtered by the prefix of the name (q).
 func (h *SpaceHandler) List(ctx echo.Context) error {
-	sx, err := h.spaces.Find(ctx.Request().Context())
+	query := repository.SpaceQuery{Search: ctx.QueryParam("q")}
+
+	var err error
+	if query.Page, err = listPage(ctx, spaceSorts); err != nil {
+		return err
+	}
+	if query.IDs, err = querySpaces(ctx, "id"); err != nil {
+		return err
+	}
+
+	sx, total, err := h.spaces.Find(ctx.Request().Context(), query)
 	if err != nil {
 		return err
 	}
@@ -63,13 +106,16 @@ func (h *SpaceHandler) List(ctx echo.Context) error {
 		result = append(result, h.shortView(sx[i]))
 	}
 
-	ctx.Response().Header().Add("X-Total-Count", strconv.Itoa(len(sx)))
+	ctx.Response().Header().Add("X-Total-Count", strconv.Itoa(total))
 
 	return ctx.JSON(http.StatusOK, result)
 }
 
 func (h *SpaceHandler) Get(ctx echo.Context) error {
 	id := entity.SpaceID(ctx.Param("id"))
+	if err := canView(ctx, id); err != nil {
+		return err
+	}
 
 	sx, err := h.spaces.FindByID(ctx.Request().Context(), id)
 	if err != nil {
@@ -80,6 +126,10 @@ func (h *SpaceHandler) Get(ctx echo.Context) error {
 }
 
 func (h *SpaceHandler) Create(ctx echo.Context) error {
+	if !currentOperator(ctx).IsSuperAdmin() {
+		return echo.ErrForbidden
+	}
+
 	space := entity.NewSpace()
 	var request = h.view(space)
 	if err := ctx.Bind(&request); err != nil {
@@ -96,27 +146,55 @@ func (h *SpaceHandler) Create(ctx echo.Context) error {
 		return err
 	}
 
+	record(ctx, h.audit, service.AuditEntry{
+		Action:     "space.create",
+		TargetType: "space",
+		TargetID:   string(space.ID),
+		SpaceID:    space.ID,
+		After:      h.view(space),
+	})
+
 	return ctx.JSON(http.StatusOK, h.view(space))
 }
 
 func (h *SpaceHandler) Update(ctx echo.Context) error {
 	id := entity.SpaceID(ctx.Param("id"))
+	if err := canEdit(ctx, id); err != nil {
+		return err
+	}
 
 	var request spaceView
 	if err := ctx.Bind(&request); err != nil {
 		return err
 	}
 
+	if _, err := hasher.New(entity.PasswordSettings(request.PasswordSettings)); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if request.PasswordSettings.HistorySize < 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "history_size can't be negative")
+	}
+	if request.PasswordSettings.HistorySize > entity.MaxPasswordHistorySize {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("history_size can't exceed %d", entity.MaxPasswordHistorySize))
+	}
+	if err := validateLockout(request.LockoutSettings); err != nil {
+		return err
+	}
+
 	space, err := h.spaces.FindByID(ctx.Request().Context(), id)
 	if err != nil {
 		return err
 	}
+	before := h.view(space)
 
 	space.Name = request.Name
 	space.Description = request.Description
 	space.PasswordSettings = entity.PasswordSettings(request.PasswordSettings)
+	space.LockoutSettings = entity.LockoutSettings(request.LockoutSettings)
 	space.UniqueUsernames = request.UniqueUsernames
 	space.RequiresCaptcha = request.RequiresCaptcha
+	space.RequiresMfa = request.RequiresMfa
+	space.RequiresVerifiedEmail = request.RequiresVerifiedEmail
 	space.Roles = request.Roles
 	space.DefaultRole = request.DefaultRole
 
@@ -124,6 +202,15 @@ func (h *SpaceHandler) Update(ctx echo.Context) error {
 		return err
 	}
 
+	record(ctx, h.audit, service.AuditEntry{
+		Action:     "space.update",
+		TargetType: "space",
+		TargetID:   string(space.ID),
+		SpaceID:    space.ID,
+		Before:     before,
+		After:      h.view(space),
+	})
+
 	return ctx.JSON(http.StatusOK, h.view(space))
 }
 
@@ -135,18 +222,33 @@ func (h *SpaceHandler) bindRequest(ctx echo.Context, request *spaceView) error {
 	return nil
 }
 
+// validateLockout refuses the negative settings and the locks which failures aren't counted within the window.
+func validateLockout(s lockoutSettingsView) error {
+	if s.MaxAttempts < 0 || s.IPMaxAttempts < 0 || s.Window < 0 || s.LockDuration < 0 || s.Delay < 0 || s.MaxDelay < 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "lockout settings can't be negative")
+	}
+	if (s.MaxAttempts > 0 || s.IPMaxAttempts > 0) && (s.Window == 0 || s.LockDuration == 0) {
+		return echo.NewHTTPError(http.StatusBadRequest, "window and lock_duration are required by max_attempts and ip_max_attempts")
+	}
+
+	return nil
+}
+
 func (h *SpaceHandler) view(s *entity.Space) spaceView {
 	return spaceView{
-		ID:               s.ID,
-		Name:             s.Name,
-		Description:      s.Description,
-		UniqueUsernames:  s.UniqueUsernames,
-		RequiresCaptcha:  s.RequiresCaptcha,
-		PasswordSettings: passwordSettingsView(s.PasswordSettings),
-		Roles:            s.Roles,
-		DefaultRole:      s.DefaultRole,
-		CreatedAt:        s.CreatedAt,
-		UpdatedAt:        s.UpdatedAt,
+		ID:                    s.ID,
+		Name:                  s.Name,
+		Description:           s.Description,
+		UniqueUsernames:       s.UniqueUsernames,
+		RequiresCaptcha:       s.RequiresCaptcha,
+		RequiresMfa:           s.RequiresMfa,
+		RequiresVerifiedEmail: s.RequiresVerifiedEmail,
+		PasswordSettings:      passwordSettingsView(s.PasswordSettings),
+		LockoutSettings:       lockoutSettingsView(s.LockoutSettings),
+		Roles:                 s.Roles,
+		DefaultRole:           s.DefaultRole,
+		CreatedAt:             s.CreatedAt,
+		UpdatedAt:             s.UpdatedAt,
 	}
 }
 
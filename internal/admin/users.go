@@ -1,32 +1,89 @@
 package admin
 
 import (
+	"context"
+	"io"
 	"net/http"
+	"net/mail"
 	"strconv"
+	"time"
 
 	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
 	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/repository"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/service"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/service/user"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/service/user_import"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/appcore/log"
+	appservice "github.com/ProtocolONE/auth1.protocol.one/pkg/service"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/webhooks"
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
 	"github.com/labstack/echo/v4"
+	"github.com/ory/hydra-client-go/client/admin"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
 )
 
 type UsersHandler struct {
-	users  repository.UserRepository
-	spaces repository.SpaceRepository
+	users       repository.UserRepository
+	spaces      repository.SpaceRepository
+	apps        repository.ApplicationRepository
+	userService service.UserService
+	hydra       appservice.HydraAdminApi
+	webhooks    *webhooks.WebHooks
+	audit       service.AuditService
+	importer    service.UserImportService
 }
 
-func NewUsersHandler(u repository.UserRepository, s repository.SpaceRepository) *UsersHandler {
-	return &UsersHandler{u, s}
+func NewUsersHandler(u repository.UserRepository, s repository.SpaceRepository, a repository.ApplicationRepository, us service.UserService, hydra appservice.HydraAdminApi, wh *webhooks.WebHooks, audit service.AuditService, importer service.UserImportService) *UsersHandler {
+	return &UsersHandler{u, s, a, us, hydra, wh, audit, importer}
 }
 
 type userView struct {
-	ID       entity.UserID `json:"id"`
-	Username string        `json:"name"`
-	Email    string        `json:"email"`
-	Roles    []string      `json:"roles"`
+	ID            entity.UserID  `json:"id"`
+	SpaceID       entity.SpaceID `json:"space_id"`
+	Username      string         `json:"name"`
+	Email         string         `json:"email"`
+	EmailVerified bool           `json:"email_verified"`
+	Roles         []string       `json:"roles"`
+	Blocked       bool           `json:"blocked"`
+	BlockedReason string         `json:"blocked_reason,omitempty"`
+	BlockedUntil  *time.Time     `json:"blocked_until,omitempty"`
+	BlockedAt     *time.Time     `json:"blocked_at,omitempty"`
+	CreatedAt     time.Time      `json:"created_at"`
 }
 
+var userSorts = map[string]string{
+	"id":         "id",
+	"name":       "username",
+	"email":      "email",
+	"created_at": "created_at",
+}
+
+// List returns the page of the users set by the _start, _end, _sort & _order params, the users may be filtered
+// by space_id, the prefix of the email or the name (or both with q), blocked and email_verified.
 func (h *UsersHandler) List(ctx echo.Context) error {
-	sx, err := h.users.Find(ctx.Request().Context())
+	query := repository.UserQuery{
+		Search:   ctx.QueryParam("q"),
+		Email:    ctx.QueryParam("email"),
+		Username: ctx.QueryParam("name"),
+	}
+
+	var err error
+	if query.Page, err = listPage(ctx, userSorts); err != nil {
+		return err
+	}
+	if query.SpaceIDs, err = querySpaces(ctx, "space_id"); err != nil {
+		return err
+	}
+	if query.Blocked, err = queryBool(ctx, "blocked"); err != nil {
+		return err
+	}
+	if query.EmailVerified, err = queryBool(ctx, "email_verified"); err != nil {
+		return err
+	}
+
+	sx, total, err := h.users.Find(ctx.Request().Context(), query)
 	if err != nil {
 		return err
 	}
@@ -36,34 +93,43 @@ func (h *UsersHandler) List(ctx echo.Context) error {
 		result = append(result, h.view(sx[i]))
 	}
 
-	ctx.Response().Header().Add("X-Total-Count", strconv.Itoa(len(sx)))
+	ctx.Response().Header().Add("X-Total-Count", strconv.Itoa(total))
 
 	return ctx.JSON(http.StatusOK, result)
 }
 
 func (h *UsersHandler) Get(ctx echo.Context) error {
-	id := entity.UserID(ctx.Param("id"))
-
-	sx, err := h.users.FindByID(ctx.Request().Context(), id)
+	sx, err := h.find(ctx)
 	if err != nil {
 		return err
 	}
+	if err := canView(ctx, sx.SpaceID); err != nil {
+		return err
+	}
 
 	return ctx.JSON(http.StatusOK, h.view(sx))
 }
 
 func (h *UsersHandler) Update(ctx echo.Context) error {
-	id := entity.UserID(ctx.Param("id"))
-
-	var request userView
+	var request struct {
+		Roles []string `json:"roles"`
+		// INFO: The status isn't changed if it's missing in the request
+		Blocked *bool `json:"blocked"`
+		// INFO: The email is changed at once without the confirmation and becomes unverified
+		Email *string `json:"email"`
+	}
 	if err := ctx.Bind(&request); err != nil {
 		return err
 	}
 
-	usr, err := h.users.FindByID(ctx.Request().Context(), id)
+	usr, err := h.find(ctx)
 	if err != nil {
 		return err
 	}
+	if err := canEdit(ctx, usr.SpaceID); err != nil {
+		return err
+	}
+	before := h.view(usr)
 
 	space, err := h.spaces.FindByID(ctx.Request().Context(), usr.SpaceID)
 	if err != nil {
@@ -79,6 +145,38 @@ func (h *UsersHandler) Update(ctx echo.Context) error {
 		}
 	}
 
+	var events []webhooks.Event
+	if request.Email != nil && *request.Email != usr.Email {
+		if _, err := mail.ParseAddress(*request.Email); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid email")
+		}
+
+		verified := false
+		err := h.userService.Update(ctx.Request().Context(), service.UpdateUserData{ID: usr.ID, Email: request.Email, EmailVerified: &verified})
+		if err == user.ErrEmailRegistered {
+			return echo.NewHTTPError(http.StatusConflict, err.Error())
+		}
+		if err != nil {
+			return err
+		}
+
+		events = append(events, webhooks.EmailChangedEvent{Email: *request.Email, PreviousEmail: usr.Email})
+		usr.Email = *request.Email
+		usr.EmailVerified = verified
+	}
+	if !sameRoles(usr.Roles, roles) {
+		events = append(events, webhooks.RolesChangedEvent{Roles: roles, PreviousRoles: usr.Roles})
+	}
+	blocked := false
+	if request.Blocked != nil && *request.Blocked != usr.Blocked {
+		if *request.Blocked {
+			block(usr, "", time.Time{})
+			blocked = true
+		} else {
+			unblock(usr)
+		}
+	}
+
 	usr.Roles = roles
 
 	err = h.users.Update(ctx.Request().Context(), usr)
@@ -86,14 +184,275 @@ func (h *UsersHandler) Update(ctx echo.Context) error {
 		return err
 	}
 
+	record(ctx, h.audit, service.AuditEntry{
+		Action:     "user.update",
+		TargetType: "user",
+		TargetID:   string(usr.ID),
+		SpaceID:    usr.SpaceID,
+		Before:     before,
+		After:      h.view(usr),
+	})
+
+	if blocked {
+		h.revoke(ctx.Request().Context(), usr)
+		events = append(events, webhooks.UserBlockedEvent{}, webhooks.UserLogoutEvent{})
+	}
+	if len(events) > 0 {
+		h.publish(ctx.Request().Context(), usr, events)
+	}
+
+	return ctx.JSON(http.StatusOK, h.view(usr))
+}
+
+// Block refuses all logins of the user until the time or permanently if it's missing, and signs the user out
+// of all sessions revoking the tokens issued to the applications.
+func (h *UsersHandler) Block(ctx echo.Context) error {
+	var request struct {
+		Reason string     `json:"reason"`
+		Until  *time.Time `json:"until"`
+	}
+	if err := ctx.Bind(&request); err != nil {
+		return err
+	}
+
+	var until time.Time
+	if request.Until != nil {
+		if !request.Until.After(time.Now()) {
+			return echo.NewHTTPError(http.StatusBadRequest, "until must be in the future")
+		}
+		until = *request.Until
+	}
+
+	usr, err := h.find(ctx)
+	if err != nil {
+		return err
+	}
+	if err := canEdit(ctx, usr.SpaceID); err != nil {
+		return err
+	}
+	before := h.view(usr)
+
+	block(usr, request.Reason, until)
+	if err := h.users.Update(ctx.Request().Context(), usr); err != nil {
+		return err
+	}
+
+	record(ctx, h.audit, service.AuditEntry{
+		Action:     "user.block",
+		TargetType: "user",
+		TargetID:   string(usr.ID),
+		SpaceID:    usr.SpaceID,
+		Before:     before,
+		After:      h.view(usr),
+	})
+
+	h.revoke(ctx.Request().Context(), usr)
+	h.publish(ctx.Request().Context(), usr, []webhooks.Event{webhooks.UserBlockedEvent{}, webhooks.UserLogoutEvent{}})
+
 	return ctx.JSON(http.StatusOK, h.view(usr))
 }
 
+// Unblock lets the user sign in again.
+func (h *UsersHandler) Unblock(ctx echo.Context) error {
+	usr, err := h.find(ctx)
+	if err != nil {
+		return err
+	}
+	if err := canEdit(ctx, usr.SpaceID); err != nil {
+		return err
+	}
+	before := h.view(usr)
+
+	unblock(usr)
+	if err := h.users.Update(ctx.Request().Context(), usr); err != nil {
+		return err
+	}
+
+	record(ctx, h.audit, service.AuditEntry{
+		Action:     "user.unblock",
+		TargetType: "user",
+		TargetID:   string(usr.ID),
+		SpaceID:    usr.SpaceID,
+		Before:     before,
+		After:      h.view(usr),
+	})
+
+	return ctx.JSON(http.StatusOK, h.view(usr))
+}
+
+type importErrorView struct {
+	Line  int    `json:"line"`
+	Email string `json:"email,omitempty"`
+	Error string `json:"error"`
+}
+
+type importView struct {
+	Created int               `json:"created"`
+	Skipped int               `json:"skipped"`
+	Errors  []importErrorView `json:"errors"`
+}
+
+// Import creates the users of the jsonl or csv file into the space, the file is the "file" field of the multipart
+// form or the whole body. The format, app_id, dry_run and the firebase_* params of the hashes are the query
+// or the form values.
+func (h *UsersHandler) Import(ctx echo.Context) error {
+	id := entity.SpaceID(ctx.Param("id"))
+	if err := canEdit(ctx, id); err != nil {
+		return err
+	}
+	if !bson.IsObjectIdHex(string(id)) {
+		return echo.ErrNotFound
+	}
+
+	options := service.ImportUsersOptions{
+		SpaceID: id,
+		AppID:   entity.AppID(ctx.FormValue("app_id")),
+		Format:  ctx.FormValue("format"),
+		Firebase: service.FirebaseScryptOptions{
+			SignerKey:     ctx.FormValue("firebase_signer_key"),
+			SaltSeparator: ctx.FormValue("firebase_salt_separator"),
+		},
+	}
+	var err error
+	for name, v := range map[string]*int{"firebase_rounds": &options.Firebase.Rounds, "firebase_mem_cost": &options.Firebase.MemCost} {
+		if p := ctx.FormValue(name); p != "" {
+			if *v, err = strconv.Atoi(p); err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, "invalid "+name)
+			}
+		}
+	}
+	if v := ctx.FormValue("dry_run"); v != "" {
+		if options.DryRun, err = strconv.ParseBool(v); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid dry_run")
+		}
+	}
+
+	var r io.Reader = ctx.Request().Body
+	if file, err := ctx.FormFile("file"); err == nil {
+		f, err := file.Open()
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	result, err := h.importer.Import(ctx.Request().Context(), r, options)
+	switch errors.Cause(err) {
+	case nil:
+	case user_import.ErrSpaceNotFound, mgo.ErrNotFound:
+		return echo.ErrNotFound
+	case user_import.ErrAppNotFound, user_import.ErrInvalidFirebaseOptions, user_import.ErrInvalidFile:
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	default:
+		return err
+	}
+
+	view := importView{Created: result.Created, Skipped: result.Skipped, Errors: []importErrorView{}}
+	for _, e := range result.Errors {
+		view.Errors = append(view.Errors, importErrorView{Line: e.Line, Email: e.Email, Error: e.Error})
+	}
+
+	if !options.DryRun {
+		record(ctx, h.audit, service.AuditEntry{
+			Action:     "users.import",
+			TargetType: "space",
+			TargetID:   string(id),
+			SpaceID:    id,
+			After:      view,
+		})
+	}
+
+	return ctx.JSON(http.StatusOK, view)
+}
+
+func (h *UsersHandler) find(ctx echo.Context) (*entity.User, error) {
+	usr, err := h.users.FindByID(ctx.Request().Context(), entity.UserID(ctx.Param("id")))
+	if err != nil {
+		return nil, err
+	}
+	if usr == nil {
+		return nil, echo.ErrNotFound
+	}
+
+	return usr, nil
+}
+
+// revoke signs the blocked user out of Hydra, the login sessions and the consents are removed together with
+// the tokens issued on them. The block is already saved and the logins are refused, so the failure is only logged.
+func (h *UsersHandler) revoke(ctx context.Context, usr *entity.User) {
+	_, err := h.hydra.RevokeAuthenticationSession(&admin.RevokeAuthenticationSessionParams{Subject: string(usr.ID), Context: ctx})
+	if err != nil {
+		log.Error(ctx, "Unable to revoke the authentication session of the blocked user", zap.String("user_id", string(usr.ID)), zap.Error(err))
+	}
+
+	_, err = h.hydra.RevokeConsentSessions(&admin.RevokeConsentSessionsParams{Subject: string(usr.ID), Context: ctx})
+	if err != nil {
+		log.Error(ctx, "Unable to revoke the consent sessions of the blocked user", zap.String("user_id", string(usr.ID)), zap.Error(err))
+	}
+}
+
+// publish sends the events to the webhooks of all applications of the user space.
+func (h *UsersHandler) publish(ctx context.Context, usr *entity.User, events []webhooks.Event) {
+	apps, _, err := h.apps.Find(ctx, repository.ApplicationQuery{SpaceIDs: []entity.SpaceID{usr.SpaceID}})
+	if err != nil {
+		log.Error(ctx, "Unable to publish webhooks, error on getting apps", zap.Error(err))
+		return
+	}
+
+	for _, app := range apps {
+		for _, event := range events {
+			if err := h.webhooks.Publish(ctx, string(app.ID), string(usr.ID), app.WebHooks, event); err != nil {
+				log.Error(ctx, "Unable to publish webhook", zap.String("action", event.Action()), zap.Error(err))
+			}
+		}
+	}
+}
+
+func block(usr *entity.User, reason string, until time.Time) {
+	usr.Blocked = true
+	usr.BlockedReason = reason
+	usr.BlockedUntil = until
+	usr.BlockedAt = time.Now()
+}
+
+func unblock(usr *entity.User) {
+	usr.Blocked = false
+	usr.BlockedReason = ""
+	usr.BlockedUntil = time.Time{}
+	usr.BlockedAt = time.Time{}
+}
+
+func sameRoles(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
 func (h *UsersHandler) view(s *entity.User) userView {
-	return userView{
-		ID:       s.ID,
-		Username: s.Username,
-		Email:    s.Email,
-		Roles:    s.Roles,
+	v := userView{
+		ID:            s.ID,
+		SpaceID:       s.SpaceID,
+		Username:      s.Username,
+		Email:         s.Email,
+		EmailVerified: s.EmailVerified,
+		Roles:         s.Roles,
+		Blocked:       s.Blocked,
+		BlockedReason: s.BlockedReason,
+		CreatedAt:     s.CreatedAt,
+	}
+	if !s.BlockedUntil.IsZero() {
+		v.BlockedUntil = &s.BlockedUntil
 	}
+	if !s.BlockedAt.IsZero() {
+		v.BlockedAt = &s.BlockedAt
+	}
+
+	return v
 }
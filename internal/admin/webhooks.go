@@ -0,0 +1,180 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/repository"
+	"github.com/labstack/echo/v4"
+)
+
+type WebhooksHandler struct {
+	deliveries repository.WebhookDeliveryRepository
+	apps       repository.ApplicationRepository
+}
+
+func NewWebhooksHandler(d repository.WebhookDeliveryRepository, a repository.ApplicationRepository) *WebhooksHandler {
+	return &WebhooksHandler{d, a}
+}
+
+type webhookDeliveryView struct {
+	ID             entity.WebhookDeliveryID     `json:"id"`
+	AppID          entity.AppID                 `json:"app_id"`
+	URL            string                       `json:"url"`
+	Event          string                       `json:"event"`
+	Payload        json.RawMessage              `json:"payload"`
+	Status         entity.WebhookDeliveryStatus `json:"status"`
+	Attempts       int                          `json:"attempts"`
+	NextAttemptAt  time.Time                    `json:"next_attempt_at"`
+	LastAttemptAt  time.Time                    `json:"last_attempt_at"`
+	LastStatusCode int                          `json:"last_status_code"`
+	LastError      string                       `json:"last_error"`
+	CreatedAt      time.Time                    `json:"created_at"`
+}
+
+// webhookDeliverySorts sorts by id as by created_at, the ids of the deliveries grow with the creation time.
+var webhookDeliverySorts = map[string]string{
+	"id":         "created_at",
+	"created_at": "created_at",
+}
+
+// List returns the page of the deliveries of the applications of the spaces visible to the operator, the newest
+// first unless it's sorted by created_at. The deliveries may be filtered by app_id, space_id and status.
+func (h *WebhooksHandler) List(ctx echo.Context) error {
+	query := repository.WebhookDeliveryQuery{
+		Status: entity.WebhookDeliveryStatus(ctx.QueryParam("status")),
+	}
+
+	var err error
+	if query.Page, err = listPage(ctx, webhookDeliverySorts); err != nil {
+		return err
+	}
+	if query.AppIDs, err = h.queryApps(ctx); err != nil {
+		return err
+	}
+
+	sx, total, err := h.deliveries.Find(ctx.Request().Context(), query)
+	if err != nil {
+		return err
+	}
+
+	result := make([]webhookDeliveryView, 0, len(sx))
+	for i := range sx {
+		result = append(result, h.view(sx[i]))
+	}
+
+	ctx.Response().Header().Add("X-Total-Count", strconv.Itoa(total))
+
+	return ctx.JSON(http.StatusOK, result)
+}
+
+func (h *WebhooksHandler) Get(ctx echo.Context) error {
+	d, err := h.find(ctx)
+	if err != nil {
+		return err
+	}
+	if err := h.check(ctx, d.AppID, canView); err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK, h.view(d))
+}
+
+// Replay puts the delivery back to the queue with a fresh number of attempts.
+func (h *WebhooksHandler) Replay(ctx echo.Context) error {
+	d, err := h.find(ctx)
+	if err != nil {
+		return err
+	}
+	if err := h.check(ctx, d.AppID, canEdit); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	d.Status = entity.WebhookDeliveryPending
+	d.Attempts = 0
+	d.NextAttemptAt = now
+	d.UpdatedAt = now
+
+	if err := h.deliveries.Update(ctx.Request().Context(), d); err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK, h.view(d))
+}
+
+func (h *WebhooksHandler) find(ctx echo.Context) (*entity.WebhookDelivery, error) {
+	id := entity.WebhookDeliveryID(ctx.Param("id"))
+
+	d, err := h.deliveries.FindByID(ctx.Request().Context(), id)
+	if err != nil {
+		return nil, err
+	}
+	if d == nil {
+		return nil, echo.ErrNotFound
+	}
+
+	return d, nil
+}
+
+// queryApps returns the applications to filter the deliveries by: the app_id param or the applications
+// of the spaces of querySpaces. The nil is returned for the super admin and matches all applications.
+func (h *WebhooksHandler) queryApps(ctx echo.Context) ([]entity.AppID, error) {
+	if id := entity.AppID(ctx.QueryParam("app_id")); id != "" {
+		if err := h.check(ctx, id, canView); err != nil {
+			return nil, err
+		}
+		return []entity.AppID{id}, nil
+	}
+
+	spaces, err := querySpaces(ctx, "space_id")
+	if err != nil || spaces == nil {
+		return nil, err
+	}
+
+	apps, _, err := h.apps.Find(ctx.Request().Context(), repository.ApplicationQuery{SpaceIDs: spaces})
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]entity.AppID, 0, len(apps))
+	for _, app := range apps {
+		ids = append(ids, app.ID)
+	}
+
+	return ids, nil
+}
+
+// check applies the access rule to the space of the application.
+func (h *WebhooksHandler) check(ctx echo.Context, id entity.AppID, rule func(echo.Context, entity.SpaceID) error) error {
+	app, err := h.apps.FindByID(ctx.Request().Context(), id)
+	if err != nil {
+		return err
+	}
+	if app == nil {
+		// INFO: The deliveries of the deleted application are left to the super admins
+		return rule(ctx, "")
+	}
+
+	return rule(ctx, app.SpaceID)
+}
+
+func (h *WebhooksHandler) view(d *entity.WebhookDelivery) webhookDeliveryView {
+	return webhookDeliveryView{
+		ID:             d.ID,
+		AppID:          d.AppID,
+		URL:            d.URL,
+		Event:          d.Event,
+		Payload:        json.RawMessage(d.Payload),
+		Status:         d.Status,
+		Attempts:       d.Attempts,
+		NextAttemptAt:  d.NextAttemptAt,
+		LastAttemptAt:  d.LastAttemptAt,
+		LastStatusCode: d.LastStatusCode,
+		LastError:      d.LastError,
+		CreatedAt:      d.CreatedAt,
+	}
+}
@@ -9,6 +9,9 @@ import (
 	"github.com/ProtocolONE/auth1.protocol.one/internal/app/container/service"
 	"github.com/ProtocolONE/auth1.protocol.one/internal/grpc"
 	"github.com/ProtocolONE/auth1.protocol.one/pkg/api"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/passwords"
+	appservice "github.com/ProtocolONE/auth1.protocol.one/pkg/service"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/webhooks"
 	"github.com/globalsign/mgo"
 	"go.uber.org/fx"
 )
@@ -28,12 +31,24 @@ func New(db *mgo.Database, srvConfig *api.ServerConfig) (*App, *api.Server, erro
 
 		env.New(),
 		env.NewDB(db)(),
+		env.NewRedis(srvConfig.RedisClient)(),
 		handler.New(),
 		repository.New(),
 		service.New(),
 
 		fx.Supply(srvConfig),
-		fx.Provide(api.NewServer),
+		fx.Supply(srvConfig.Webhooks),
+		fx.Supply(srvConfig.Operators),
+		fx.Supply(srvConfig.MailTemplates),
+		fx.Provide(
+			func() appservice.OneTimeTokenServiceInterface {
+				return appservice.NewOneTimeTokenService(srvConfig.RedisClient)
+			},
+			func() appservice.MailerInterface { return appservice.NewMailer(srvConfig.Mailer) },
+			func() passwords.Corpus { return srvConfig.BreachedPasswords },
+			webhooks.NewWebhooks,
+			api.NewServer,
+		),
 
 		fx.Populate(&app.grpc),
 		fx.Populate(&server),
@@ -3,6 +3,7 @@ package env
 import (
 	"github.com/ProtocolONE/auth1.protocol.one/internal/env"
 	"github.com/globalsign/mgo"
+	"github.com/go-redis/redis"
 	"go.uber.org/fx"
 )
 
@@ -22,3 +23,14 @@ func NewDB(db *mgo.Database) func() fx.Option {
 		)
 	}
 }
+
+// todo: it's temporary dependency fix
+func NewRedis(client *redis.Client) func() fx.Option {
+	return func() fx.Option {
+		return fx.Provide(
+			func() *redis.Client {
+				return client
+			},
+		)
+	}
+}
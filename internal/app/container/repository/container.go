@@ -3,9 +3,13 @@ package repository
 import (
 	"github.com/ProtocolONE/auth1.protocol.one/internal/repository"
 	"github.com/ProtocolONE/auth1.protocol.one/internal/repository/application"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/repository/audit"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/repository/operator"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/repository/operator_session"
 	"github.com/ProtocolONE/auth1.protocol.one/internal/repository/profile"
 	"github.com/ProtocolONE/auth1.protocol.one/internal/repository/user"
 	"github.com/ProtocolONE/auth1.protocol.one/internal/repository/user_identity"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/repository/webhook_delivery"
 	"go.uber.org/fx"
 )
 
@@ -16,5 +20,9 @@ func New() fx.Option {
 		application.New,
 		user_identity.New,
 		repository.MakeSpaceRepo,
+		webhook_delivery.New,
+		operator.New,
+		operator_session.New,
+		audit.New,
 	)
 }
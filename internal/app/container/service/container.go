@@ -2,10 +2,14 @@ package service
 
 import (
 	"github.com/ProtocolONE/auth1.protocol.one/internal/service/application"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/service/audit"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/service/email_change"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/service/operator"
 	"github.com/ProtocolONE/auth1.protocol.one/internal/service/password_manager"
 	"github.com/ProtocolONE/auth1.protocol.one/internal/service/profile"
 	"github.com/ProtocolONE/auth1.protocol.one/internal/service/user"
 	"github.com/ProtocolONE/auth1.protocol.one/internal/service/user_identity"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/service/user_import"
 	"go.uber.org/fx"
 )
 
@@ -16,5 +20,9 @@ func New() fx.Option {
 		user.New,
 		user_identity.New,
 		password_manager.New,
+		operator.New,
+		audit.New,
+		email_change.New,
+		user_import.New,
 	)
 }
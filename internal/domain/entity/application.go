@@ -39,6 +39,32 @@ type Application struct {
 	// PostLogoutRedirectUris is an array of allowed post logout redirect urls for the client.
 	PostLogoutRedirectUrls []string
 
+	// OneTimeTokenSettings contains settings for storing one-time application tokens.
+	OneTimeTokenSettings *OneTimeTokenSettings
+
 	// WebHook endpoint URLs
 	WebHooks []string
+
+	// WebHookSecret is a secret string with which the webhook requests are signed.
+	WebHookSecret string
+
+	// WebHookPreviousSecret is the secret replaced by the last rotation, the requests are signed
+	// with it too until WebHookPreviousSecretExpiresAt.
+	WebHookPreviousSecret string
+
+	// WebHookPreviousSecretExpiresAt is the end of the overlap window of the rotated secret.
+	WebHookPreviousSecretExpiresAt time.Time
+}
+
+// WebHookSecrets returns the secrets which the webhook requests are signed with at the moment.
+func (a *Application) WebHookSecrets(now time.Time) []string {
+	var secrets []string
+	if a.WebHookSecret != "" {
+		secrets = append(secrets, a.WebHookSecret)
+	}
+	if a.WebHookPreviousSecret != "" && now.Before(a.WebHookPreviousSecretExpiresAt) {
+		secrets = append(secrets, a.WebHookPreviousSecret)
+	}
+
+	return secrets
 }
@@ -0,0 +1,50 @@
+package entity
+
+import (
+	"time"
+)
+
+type AuditRecordID string
+
+// AuditRecord is the entry of the append-only audit trail of the changes made by the operators.
+type AuditRecord struct {
+	// ID is the id of the record.
+	ID AuditRecordID
+
+	// ActorID is the id of the operator who made the change.
+	ActorID OperatorID
+
+	// ActorLogin is the login of the operator at the time of the change.
+	ActorLogin string
+
+	// Action is the name of the change, e.g. space.update.
+	Action string
+
+	// TargetType is the kind of the changed object, e.g. space or application.
+	TargetType string
+
+	// TargetID is the id of the changed object.
+	TargetID string
+
+	// SpaceID is the space of the changed object, the record is visible to the operators of the space.
+	SpaceID SpaceID
+
+	// Changes are the changed fields with the values of the secret fields masked.
+	Changes []AuditChange
+
+	// IP is the address of the operator.
+	IP string
+
+	// RequestID is the id of the request made the change.
+	RequestID string
+
+	// CreatedAt returns the timestamp of the change.
+	CreatedAt time.Time
+}
+
+// AuditChange is the change of the field, the path of the nested field is joined with dots, e.g. password_settings.min.
+type AuditChange struct {
+	Field  string
+	Before interface{}
+	After  interface{}
+}
@@ -7,6 +7,9 @@ type IDProviderType string
 const (
 	IDProviderTypePassword IDProviderType = "password"
 	IDProviderTypeSocial   IDProviderType = "social"
+	IDProviderTypeOIDC     IDProviderType = "oidc"
+	IDProviderTypeSAML     IDProviderType = "saml"
+	IDProviderTypeSteam    IDProviderType = "steam"
 
 	IDProviderNameDefault = "initial"
 )
@@ -21,32 +24,95 @@ type IdentityProvider struct {
 	// Name is the service name used in authorization requests. It must not contain spaces and special characters.
 	Name string
 
-	// Type defines the type of provider, such as a password(password) or social authorization(social).
+	// Type defines the type of provider, such as a password(password), social authorization(social),
+	// OpenID Connect(oidc), SAML 2.0(saml) or the OpenID 2.0 login of Steam(steam).
 	Type IDProviderType
 
 	// ClientID is the client identifier on external network. For example, the application ID in Facebook.
 	ClientID string
 
-	// ClientSecret is the secret string of the client on external network.
+	// ClientSecret is the secret string of the client on external network, the Web API key of the Steam provider
+	// and the PEM encoded .p8 key of the Apple provider.
 	ClientSecret string
 
 	// ClientScopes is the scopes list for external network.
 	ClientScopes []string
 
-	// EndpointAuthURL is the authentication url on external network.
+	// EndpointAuthURL is the authentication url on external network, the single sign-on url of the SAML provider
+	// and the OpenID 2.0 endpoint of the Steam provider.
 	EndpointAuthURL string
 
 	// EndpointTokenURL is the endpoint url on external network for exchange authentication code to the tokens.
 	EndpointTokenURL string
 
-	// EndpointUserInfoURL is the endpoint on external network for to get user information.
+	// EndpointUserInfoURL is the endpoint on external network for to get user information, the base url of the Web API
+	// of the Steam provider.
 	EndpointUserInfoURL string
+
+	// Issuer is the issuer url of the OpenID Connect provider, the endpoints are discovered by it. It is the entity
+	// id of the SAML provider.
+	Issuer string
+
+	// Certificate is the PEM certificate the SAML provider signs the assertions with.
+	Certificate string
+
+	// TeamID is the team id of the Apple developer account, the client secret of the Apple provider is issued by it.
+	TeamID string
+
+	// KeyID is the id of the Sign in with Apple key.
+	KeyID string
+
+	// ClaimMapping selects the profile of the user in the userinfo response or the claims of the provider, the SAML
+	// attributes are the claims named by the attribute names.
+	ClaimMapping ClaimMapping
+}
+
+// ClaimMapping contains the JSONPath-like expressions of the profile fields, like "$.response[0].id" or
+// "picture.data.url". The empty expression leaves the field to the built-in parsing of the provider.
+type ClaimMapping struct {
+	// ExternalID is the id of the user in the external network.
+	ExternalID string
+
+	// Email is the email of the user.
+	Email string
+
+	// EmailVerified is the flag of the verified email, the email is ignored if it's false.
+	EmailVerified string
+
+	// Username is the nickname of the user.
+	Username string
+
+	// Name is the full name of the user.
+	Name string
+
+	// FirstName is the first name of the user.
+	FirstName string
+
+	// LastName is the last name of the user.
+	LastName string
+
+	// Birthday is the date of birth of the user.
+	Birthday string
+
+	// Picture is the url of the avatar of the user.
+	Picture string
+}
+
+// IsEmpty reports whether no expression is set.
+func (m ClaimMapping) IsEmpty() bool {
+	return m == ClaimMapping{}
 }
 
 func (p *IdentityProvider) IsDefault() bool {
 	return p.Type == IDProviderTypePassword && p.Name == IDProviderNameDefault
 }
 
+// IsSocial reports whether the user signs in with the external network, the OpenID Connect, SAML and Steam
+// providers included.
 func (p *IdentityProvider) IsSocial() bool {
-	return p.Type == IDProviderTypeSocial
+	switch p.Type {
+	case IDProviderTypeSocial, IDProviderTypeOIDC, IDProviderTypeSAML, IDProviderTypeSteam:
+		return true
+	}
+	return false
 }
@@ -0,0 +1,53 @@
+package entity
+
+import "time"
+
+// LockoutSettings determines the protection of the password logins against guessing of the password.
+// The failed attempts are counted per identity and per client ip address.
+type LockoutSettings struct {
+	// MaxAttempts is the number of failed logins to the identity after which it's locked, zero disables the lock.
+	MaxAttempts int
+
+	// IPMaxAttempts is the number of failed logins from the ip address after which the logins from it are locked,
+	// zero disables the lock.
+	IPMaxAttempts int
+
+	// Window is the time in seconds the failed attempts are counted within.
+	Window int
+
+	// LockDuration is the time in seconds the identity or the ip address is locked for.
+	LockDuration int
+
+	// Delay is the time in seconds the next login to the identity is delayed for after a failed one,
+	// it's doubled with every failure in a row up to MaxDelay. Zero disables the delays.
+	Delay int
+
+	// MaxDelay is the limit of the delay in seconds.
+	MaxDelay int
+}
+
+var DefaultLockoutSettings = LockoutSettings{
+	MaxAttempts:   10,
+	IPMaxAttempts: 100,
+	Window:        900,
+	LockDuration:  900,
+	Delay:         1,
+	MaxDelay:      30,
+}
+
+// DelayAfter returns the delay of the next login after the number of failed attempts in a row.
+func (s *LockoutSettings) DelayAfter(failures int) time.Duration {
+	if s.Delay <= 0 || failures <= 0 {
+		return 0
+	}
+
+	delay := s.Delay
+	for i := 1; i < failures && delay < s.MaxDelay; i++ {
+		delay *= 2
+	}
+	if s.MaxDelay > 0 && delay > s.MaxDelay {
+		delay = s.MaxDelay
+	}
+
+	return time.Duration(delay) * time.Second
+}
@@ -0,0 +1,25 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLockoutDelayIsDoubledUpToLimit(t *testing.T) {
+	s := &LockoutSettings{Delay: 1, MaxDelay: 5}
+
+	assert.Equal(t, time.Duration(0), s.DelayAfter(0))
+	assert.Equal(t, time.Second, s.DelayAfter(1))
+	assert.Equal(t, 2*time.Second, s.DelayAfter(2))
+	assert.Equal(t, 4*time.Second, s.DelayAfter(3))
+	assert.Equal(t, 5*time.Second, s.DelayAfter(4))
+	assert.Equal(t, 5*time.Second, s.DelayAfter(100))
+}
+
+func TestLockoutWithoutDelay(t *testing.T) {
+	s := &LockoutSettings{MaxDelay: 5}
+
+	assert.Equal(t, time.Duration(0), s.DelayAfter(3))
+}
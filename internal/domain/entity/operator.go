@@ -0,0 +1,104 @@
+package entity
+
+import (
+	"time"
+)
+
+type OperatorID string
+
+type OperatorRole string
+
+const (
+	// OperatorSuperAdmin manages all spaces and creates the new ones.
+	OperatorSuperAdmin OperatorRole = "super_admin"
+
+	// OperatorSpaceAdmin manages the space of the grant.
+	OperatorSpaceAdmin OperatorRole = "space_admin"
+
+	// OperatorSupport views the space of the grant without changing it.
+	OperatorSupport OperatorRole = "support"
+)
+
+// OperatorGrant binds the role to the space, the space is empty for the super admin.
+type OperatorGrant struct {
+	Role    OperatorRole
+	SpaceID SpaceID
+}
+
+// Operator is the account of the administration panel and the management api.
+type Operator struct {
+	// ID is the id of the operator.
+	ID OperatorID
+
+	// Login is the unique name used to sign in with the password.
+	Login string
+
+	// Name is the display name of the operator.
+	Name string
+
+	// PasswordHash is the bcrypt, argon2id or scrypt hash of the password, empty if the operator signs in only with OIDC.
+	PasswordHash string
+
+	// Subject is the id of the user of the admin space linked to the operator for the OIDC sign in.
+	Subject string
+
+	// Grants are the roles of the operator in the spaces.
+	Grants []OperatorGrant
+
+	// Disabled operator can't sign in and its sessions are rejected.
+	Disabled bool
+
+	// LastLoginAt is the time of the last sign in.
+	LastLoginAt time.Time
+
+	// CreatedAt returns the timestamp of the operator creation.
+	CreatedAt time.Time
+
+	// UpdatedAt returns the timestamp of the last update.
+	UpdatedAt time.Time
+}
+
+// IsSuperAdmin reports whether the operator manages all spaces.
+func (o *Operator) IsSuperAdmin() bool {
+	for _, g := range o.Grants {
+		if g.Role == OperatorSuperAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// CanView reports whether the operator may read the space and its providers, users and applications.
+func (o *Operator) CanView(space SpaceID) bool {
+	for _, g := range o.Grants {
+		if g.Role == OperatorSuperAdmin || g.SpaceID == space {
+			return true
+		}
+	}
+	return false
+}
+
+// CanEdit reports whether the operator may change the space and its providers, users and applications.
+func (o *Operator) CanEdit(space SpaceID) bool {
+	for _, g := range o.Grants {
+		if g.Role == OperatorSuperAdmin || (g.Role == OperatorSpaceAdmin && g.SpaceID == space) {
+			return true
+		}
+	}
+	return false
+}
+
+// OperatorSession is the signed in session of the operator.
+type OperatorSession struct {
+	// Token is the opaque bearer token of the session, it's stored only as a hash.
+	Token string
+
+	// OperatorID is the id of the session owner.
+	OperatorID OperatorID
+
+	// ExpiresAt is the time after which the session is rejected.
+	ExpiresAt time.Time
+
+	// CreatedAt returns the timestamp of the session creation.
+	CreatedAt time.Time
+}
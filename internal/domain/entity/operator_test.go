@@ -0,0 +1,29 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOperatorSuperAdminAccessesAllSpaces(t *testing.T) {
+	o := &Operator{Grants: []OperatorGrant{{Role: OperatorSuperAdmin}}}
+
+	assert.True(t, o.IsSuperAdmin())
+	assert.True(t, o.CanView("space"))
+	assert.True(t, o.CanEdit("space"))
+}
+
+func TestOperatorAccessIsScopedBySpace(t *testing.T) {
+	o := &Operator{Grants: []OperatorGrant{
+		{Role: OperatorSpaceAdmin, SpaceID: "managed"},
+		{Role: OperatorSupport, SpaceID: "supported"},
+	}}
+
+	assert.False(t, o.IsSuperAdmin())
+	assert.True(t, o.CanEdit("managed"))
+	assert.True(t, o.CanView("supported"))
+	assert.False(t, o.CanEdit("supported"))
+	assert.False(t, o.CanView("other"))
+	assert.False(t, o.CanView(""))
+}
@@ -3,10 +3,32 @@ package entity
 import "unicode"
 
 type PasswordSettings struct {
+	// Algorithm is the password hash algorithm: bcrypt (default), argon2id or scrypt.
+	// The hashes made by the other algorithm or parameters are upgraded on the next login.
+	Algorithm string
+
 	// BcryptCost determines the depth of password encryption for providers based on the database.
 	// CPU load and performance depend on the BCrypt cost.
 	BcryptCost int
 
+	// Argon2Memory is the memory in KiB used by the argon2id hash.
+	Argon2Memory int
+
+	// Argon2Time is the number of passes of the argon2id hash.
+	Argon2Time int
+
+	// Argon2Threads is the parallelism of the argon2id hash.
+	Argon2Threads int
+
+	// ScryptN is the CPU/memory cost of the scrypt hash, a power of two.
+	ScryptN int
+
+	// ScryptR is the block size of the scrypt hash.
+	ScryptR int
+
+	// ScryptP is the parallelism of the scrypt hash.
+	ScryptP int
+
 	// Min is the minimal length password.
 	Min int
 
@@ -25,6 +47,12 @@ type PasswordSettings struct {
 	// RequireLetter requires a letter in the password.
 	RequireLetter bool
 
+	// RejectBreached rejects the passwords found in the corpus of the breached passwords.
+	RejectBreached bool
+
+	// HistorySize is the number of the last passwords of the user which can't be reused, zero allows any.
+	HistorySize int
+
 	// TokenLength determines the length of the token in the password change letter.
 	TokenLength int
 
@@ -32,8 +60,36 @@ type PasswordSettings struct {
 	TokenTTL int
 }
 
+// The reasons the password is rejected by the settings.
+const (
+	PasswordTooShort        = "too_short"
+	PasswordTooLong         = "too_long"
+	PasswordNumberRequired  = "number_required"
+	PasswordUpperRequired   = "upper_required"
+	PasswordSpecialRequired = "special_required"
+	PasswordLetterRequired  = "letter_required"
+	PasswordBreached        = "breached"
+	PasswordReused          = "reused"
+)
+
+// MaxPasswordHistorySize is the upper bound of the history size, every kept hash is verified on the password change.
+const MaxPasswordHistorySize = 24
+
+const (
+	PasswordAlgorithmBcrypt   = "bcrypt"
+	PasswordAlgorithmArgon2id = "argon2id"
+	PasswordAlgorithmScrypt   = "scrypt"
+)
+
 var DefaultPasswordSettings = PasswordSettings{
+	Algorithm:      PasswordAlgorithmBcrypt,
 	BcryptCost:     8,
+	Argon2Memory:   64 * 1024,
+	Argon2Time:     3,
+	Argon2Threads:  2,
+	ScryptN:        32768,
+	ScryptR:        8,
+	ScryptP:        1,
 	Min:            7,
 	Max:            30,
 	RequireNumber:  true,
@@ -45,6 +101,12 @@ var DefaultPasswordSettings = PasswordSettings{
 }
 
 func (s *PasswordSettings) IsValid(password string) bool {
+	return s.Check(password) == ""
+}
+
+// Check returns the reason the password doesn't meet the requirements of the length and the characters,
+// the empty reason means the password is valid.
+func (s *PasswordSettings) Check(password string) string {
 	letters := 0
 	number := false
 	upper := false
@@ -64,17 +126,19 @@ func (s *PasswordSettings) IsValid(password string) bool {
 		}
 	}
 
-	if s.RequireNumber && !number {
-		return false
-	}
-	if s.RequireUpper && !upper {
-		return false
-	}
-	if s.RequireSpecial && !special {
-		return false
-	}
-	if s.RequireLetter && letters == 0 {
-		return false
+	switch {
+	case s.RequireNumber && !number:
+		return PasswordNumberRequired
+	case s.RequireUpper && !upper:
+		return PasswordUpperRequired
+	case s.RequireSpecial && !special:
+		return PasswordSpecialRequired
+	case s.RequireLetter && letters == 0:
+		return PasswordLetterRequired
+	case len(password) < s.Min:
+		return PasswordTooShort
+	case len(password) > s.Max:
+		return PasswordTooLong
 	}
-	return s.Min <= len(password) && len(password) <= s.Max
+	return ""
 }
@@ -23,9 +23,18 @@ type Space struct {
 	// RequiresCaptcha determines whether space users must have complete captcha verification
 	RequiresCaptcha bool
 
+	// RequiresMfa determines whether space users must pass multi-factor authentication on login
+	RequiresMfa bool
+
+	// RequiresVerifiedEmail determines whether space users must verify the email address before login
+	RequiresVerifiedEmail bool
+
 	// Password requirements
 	PasswordSettings PasswordSettings
 
+	// LockoutSettings determines the limits of the failed password logins
+	LockoutSettings LockoutSettings
+
 	// Roles available in the space
 	Roles []string
 
@@ -47,13 +56,16 @@ type Space struct {
 func NewSpace() *Space {
 	now := time.Now()
 	return &Space{
-		Name:              "",
-		Description:       "",
-		UniqueUsernames:   true,
-		RequiresCaptcha:   false,
-		PasswordSettings:  DefaultPasswordSettings,
-		IdentityProviders: NewIdentityProviders(),
-		CreatedAt:         now,
-		UpdatedAt:         now,
+		Name:                  "",
+		Description:           "",
+		UniqueUsernames:       true,
+		RequiresCaptcha:       false,
+		RequiresMfa:           false,
+		RequiresVerifiedEmail: false,
+		PasswordSettings:      DefaultPasswordSettings,
+		LockoutSettings:       DefaultLockoutSettings,
+		IdentityProviders:     NewIdentityProviders(),
+		CreatedAt:             now,
+		UpdatedAt:             now,
 	}
 }
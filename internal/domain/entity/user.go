@@ -23,6 +23,10 @@ type User struct {
 	// EmailVerified is status of verification user address.
 	EmailVerified bool
 
+	// EmailPending is set while the email change isn't applied to the identity of the default provider,
+	// the identity is reconciled from the email of the user.
+	EmailPending bool
+
 	// PhoneNumber is the phone number of the user.
 	PhoneNumber string
 
@@ -53,9 +57,23 @@ type User struct {
 	// Blocked is status of user blocked.
 	Blocked bool
 
+	// BlockedReason is the reason of the block given by the operator.
+	BlockedReason string
+
+	// BlockedUntil is the end time of the block, the zero value means the block is permanent.
+	BlockedUntil time.Time
+
+	// BlockedAt is timestamp of the block.
+	BlockedAt time.Time
+
 	// CreatedAt is timestamp of the user creation.
 	CreatedAt time.Time
 
 	// UpdatedAt is timestamp of the last update.
 	UpdatedAt time.Time
 }
+
+// IsBlocked reports whether the user can't sign in at the time because of the block.
+func (u *User) IsBlocked(now time.Time) bool {
+	return u.Blocked && (u.BlockedUntil.IsZero() || now.Before(u.BlockedUntil))
+}
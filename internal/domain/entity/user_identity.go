@@ -12,6 +12,9 @@ type UserIdentity struct {
 	// UserID is the id of the user.
 	UserID UserID
 
+	// AppID is the id of the application the identity is created with.
+	AppID AppID
+
 	// IdentityProviderID is the id of identity provider.
 	IdentityProviderID IdentityProviderID
 
@@ -21,6 +24,9 @@ type UserIdentity struct {
 	// Credential is the
 	Credential string
 
+	// PasswordHistory is the hashes of the previous passwords, the last one first.
+	PasswordHistory []string
+
 	// Email is the email address of the user.
 	Email string
 
@@ -0,0 +1,23 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserBlockExpiresAtUntilTime(t *testing.T) {
+	now := time.Now()
+	u := &User{Blocked: true, BlockedUntil: now.Add(time.Hour)}
+
+	assert.True(t, u.IsBlocked(now))
+	assert.False(t, u.IsBlocked(now.Add(time.Hour)))
+}
+
+func TestUserBlockWithoutUntilIsPermanent(t *testing.T) {
+	now := time.Now()
+
+	assert.True(t, (&User{Blocked: true}).IsBlocked(now.AddDate(10, 0, 0)))
+	assert.False(t, (&User{BlockedUntil: now.Add(time.Hour)}).IsBlocked(now))
+}
@@ -0,0 +1,62 @@
+package entity
+
+import (
+	"time"
+)
+
+type WebhookDeliveryID string
+
+type WebhookDeliveryStatus string
+
+const (
+	// WebhookDeliveryPending is the delivery waiting for the next attempt.
+	WebhookDeliveryPending WebhookDeliveryStatus = "pending"
+
+	// WebhookDeliveryDelivered is the delivery accepted by the endpoint with a 2xx status code.
+	WebhookDeliveryDelivered WebhookDeliveryStatus = "delivered"
+
+	// WebhookDeliveryDead is the delivery that has exhausted all attempts.
+	WebhookDeliveryDead WebhookDeliveryStatus = "dead"
+)
+
+// WebhookDelivery describes the webhook event to be sent to one application endpoint.
+type WebhookDelivery struct {
+	// ID is the id of the delivery.
+	ID WebhookDeliveryID
+
+	// AppID is the id of the application which owns the endpoint.
+	AppID AppID
+
+	// URL is the endpoint of the application.
+	URL string
+
+	// Event is the name of the event, e.g. user.logout.
+	Event string
+
+	// Payload is the JSON body of the request.
+	Payload []byte
+
+	// Status is the state of the delivery.
+	Status WebhookDeliveryStatus
+
+	// Attempts is the number of the failed attempts.
+	Attempts int
+
+	// NextAttemptAt is the time after which the pending delivery will be sent.
+	NextAttemptAt time.Time
+
+	// LastAttemptAt is the time of the last attempt.
+	LastAttemptAt time.Time
+
+	// LastStatusCode is the HTTP status code of the last attempt, zero if the request has failed.
+	LastStatusCode int
+
+	// LastError is the error of the last attempt.
+	LastError string
+
+	// CreatedAt returns the timestamp of the delivery creation.
+	CreatedAt time.Time
+
+	// UpdatedAt returns the timestamp of the last update.
+	UpdatedAt time.Time
+}
@@ -6,8 +6,26 @@ import (
 	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
 )
 
+// ApplicationQuery filters the applications, empty fields match any value.
+type ApplicationQuery struct {
+	Page
+
+	// SpaceIDs limits the applications to the spaces, nil matches all applications.
+	SpaceIDs []entity.SpaceID
+
+	// Search matches the prefix of the name.
+	Search string
+
+	IsActive *bool
+}
+
 //go:generate mockgen -destination=../mocks/application_repository.go -package=mocks github.com/ProtocolONE/auth1.protocol.one/internal/domain/repository ApplicationRepository
 type ApplicationRepository interface {
-	Find(ctx context.Context) ([]*entity.Application, error)
+	Create(ctx context.Context, app *entity.Application) error
+	Update(ctx context.Context, app *entity.Application) error
+	Delete(ctx context.Context, id entity.AppID) error
+	// Find returns the page of the applications matching the query, sorted by id, name, created_at or updated_at,
+	// and the number of all matching applications.
+	Find(ctx context.Context, query ApplicationQuery) ([]*entity.Application, int, error)
 	FindByID(ctx context.Context, id entity.AppID) (*entity.Application, error)
 }
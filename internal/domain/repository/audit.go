@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
+)
+
+// AuditQuery filters the audit records, empty fields match any value.
+type AuditQuery struct {
+	ActorID    entity.OperatorID
+	Action     string
+	TargetType string
+	TargetID   string
+	// SpaceIDs limits the records to the spaces, nil matches all records.
+	SpaceIDs []entity.SpaceID
+	From     time.Time
+	To       time.Time
+	Offset   int
+	Limit    int
+}
+
+type AuditRepository interface {
+	// Create appends the record, the records are never updated or removed.
+	Create(ctx context.Context, record *entity.AuditRecord) error
+
+	// Find returns the page of the records matching the query, the newest first, and the number of all matching records.
+	Find(ctx context.Context, query AuditQuery) ([]*entity.AuditRecord, int, error)
+}
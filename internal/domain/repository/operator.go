@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
+)
+
+type OperatorRepository interface {
+	Create(ctx context.Context, operator *entity.Operator) error
+	Update(ctx context.Context, operator *entity.Operator) error
+
+	// Find returns all operators ordered by login.
+	Find(ctx context.Context) ([]*entity.Operator, error)
+	// FindByID returns nil if the operator doesn't exist.
+	FindByID(ctx context.Context, id entity.OperatorID) (*entity.Operator, error)
+	// FindByLogin returns nil if the operator doesn't exist.
+	FindByLogin(ctx context.Context, login string) (*entity.Operator, error)
+	// FindBySubject returns nil if no operator is linked to the user.
+	FindBySubject(ctx context.Context, subject string) (*entity.Operator, error)
+}
+
+type OperatorSessionRepository interface {
+	// Create stores the session until it expires.
+	Create(ctx context.Context, session *entity.OperatorSession) error
+	// FindByToken returns nil if the session doesn't exist or has expired.
+	FindByToken(ctx context.Context, token string) (*entity.OperatorSession, error)
+	Delete(ctx context.Context, token string) error
+}
@@ -0,0 +1,13 @@
+package repository
+
+// Page selects the part of the sorted list.
+type Page struct {
+	Offset int
+
+	// Limit is the maximum number of the items, the zero limit selects all of them.
+	Limit int
+
+	// Sort is the name of the field to sort by, the "-" prefix sets the descending order.
+	// The unknown or empty field keeps the default order of the repository.
+	Sort string
+}
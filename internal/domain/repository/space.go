@@ -6,13 +6,49 @@ import (
 	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
 )
 
+// SpaceQuery filters the spaces, empty fields match any value.
+type SpaceQuery struct {
+	Page
+
+	// IDs limits the list to the spaces, nil matches all spaces.
+	IDs []entity.SpaceID
+
+	// Search matches the prefix of the name.
+	Search string
+}
+
+// IdentityProviderQuery filters the identity providers of the spaces, empty fields match any value.
+type IdentityProviderQuery struct {
+	Page
+
+	// SpaceIDs limits the providers to the spaces, nil matches the providers of all spaces.
+	SpaceIDs []entity.SpaceID
+
+	// Search matches the prefix of the name or the display name.
+	Search string
+
+	Type entity.IDProviderType
+}
+
+// SpaceIdentityProvider is the identity provider found with the space it belongs to.
+type SpaceIdentityProvider struct {
+	SpaceID  entity.SpaceID
+	Provider entity.IdentityProvider
+}
+
 type SpaceRepository interface {
 	Create(ctx context.Context, space *entity.Space) error
 	Update(ctx context.Context, space *entity.Space) error
 
-	Find(ctx context.Context) ([]*entity.Space, error)
+	// Find returns the page of the spaces matching the query, sorted by id, name, created_at or updated_at,
+	// and the number of all matching spaces.
+	Find(ctx context.Context, query SpaceQuery) ([]*entity.Space, int, error)
 	FindByID(ctx context.Context, id entity.SpaceID) (*entity.Space, error)
 	FindForProvider(ctx context.Context, id entity.IdentityProviderID) (*entity.Space, error)
+
+	// FindProviders returns the page of the identity providers matching the query, sorted by id, name,
+	// display_name or type, and the number of all matching providers.
+	FindProviders(ctx context.Context, query IdentityProviderQuery) ([]*SpaceIdentityProvider, int, error)
 }
 
 ////////////////////////////////////////////////////////////////////////////////////
@@ -27,10 +63,15 @@ type oneSpaceRepository struct {
 
 func (r *oneSpaceRepository) Create(ctx context.Context, space *entity.Space) error { return nil }
 func (r *oneSpaceRepository) Update(ctx context.Context, space *entity.Space) error { return nil }
-func (r *oneSpaceRepository) Find(ctx context.Context) ([]*entity.Space, error)     { return nil, nil }
+func (r *oneSpaceRepository) Find(ctx context.Context, query SpaceQuery) ([]*entity.Space, int, error) {
+	return nil, 0, nil
+}
 func (r *oneSpaceRepository) FindByID(ctx context.Context, id entity.SpaceID) (*entity.Space, error) {
 	return r.space, nil
 }
 func (r *oneSpaceRepository) FindForProvider(ctx context.Context, id entity.IdentityProviderID) (*entity.Space, error) {
 	return r.space, nil
 }
+func (r *oneSpaceRepository) FindProviders(ctx context.Context, query IdentityProviderQuery) ([]*SpaceIdentityProvider, int, error) {
+	return nil, 0, nil
+}
@@ -6,10 +6,31 @@ import (
 	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
 )
 
+// UserQuery filters the users, empty fields match any value.
+type UserQuery struct {
+	Page
+
+	// SpaceIDs limits the users to the spaces, nil matches all users.
+	SpaceIDs []entity.SpaceID
+
+	// Search matches the prefix of the email or the username.
+	Search string
+
+	// Email and Username match the prefix of the field.
+	Email    string
+	Username string
+
+	Blocked       *bool
+	EmailVerified *bool
+}
+
 //go:generate mockgen -destination=../mocks/user_repository.go -package=mocks github.com/ProtocolONE/auth1.protocol.one/internal/domain/repository UserRepository
 type UserRepository interface {
+	Create(ctx context.Context, user *entity.User) error
 	Update(ctx context.Context, user *entity.User) error
 
-	Find(ctx context.Context) ([]*entity.User, error)
+	// Find returns the page of the users matching the query, sorted by id, email, username or created_at,
+	// and the number of all matching users.
+	Find(ctx context.Context, query UserQuery) ([]*entity.User, int, error)
 	FindByID(ctx context.Context, id entity.UserID) (*entity.User, error)
 }
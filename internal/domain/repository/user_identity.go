@@ -13,9 +13,11 @@ const (
 
 //go:generate mockgen -destination=../mocks/user_identity_repository.go -package=mocks github.com/ProtocolONE/auth1.protocol.one/internal/domain/repository UserIdentityRepository
 type UserIdentityRepository interface {
+	Create(ctx context.Context, i *entity.UserIdentity) error
 	Update(ctx context.Context, i *entity.UserIdentity) error
 	//
 	FindByID(ctx context.Context, id entity.UserIdentityID) (*entity.UserIdentity, error)
 	FindByProviderAndUser(ctx context.Context, idProviderID entity.IdentityProviderID, userID entity.UserID) (*entity.UserIdentity, error)
+	FindByProviderAndExternalID(ctx context.Context, idProviderID entity.IdentityProviderID, externalID string) (*entity.UserIdentity, error)
 	FindForUser(ctx context.Context, userID entity.UserID) ([]*entity.UserIdentity, error)
 }
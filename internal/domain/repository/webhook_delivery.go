@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
+)
+
+// WebhookDeliveryQuery filters the deliveries, empty fields match any value.
+type WebhookDeliveryQuery struct {
+	Page
+
+	// AppIDs limits the deliveries to the applications, nil matches all deliveries.
+	AppIDs []entity.AppID
+
+	Status entity.WebhookDeliveryStatus
+}
+
+type WebhookDeliveryRepository interface {
+	Create(ctx context.Context, delivery *entity.WebhookDelivery) error
+	Update(ctx context.Context, delivery *entity.WebhookDelivery) error
+
+	// Find returns the page of the deliveries matching the query, the newest first unless it's sorted by created_at,
+	// and the number of all matching deliveries.
+	Find(ctx context.Context, query WebhookDeliveryQuery) ([]*entity.WebhookDelivery, int, error)
+	// FindByID returns nil if the delivery doesn't exist.
+	FindByID(ctx context.Context, id entity.WebhookDeliveryID) (*entity.WebhookDelivery, error)
+
+	// Claim returns up to limit pending deliveries due by now and postpones them for the lease,
+	// so the other dispatchers don't pick them until the lease expires.
+	Claim(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*entity.WebhookDelivery, error)
+
+	// Purge removes the delivered or the dead deliveries last updated before the time and returns their number.
+	Purge(ctx context.Context, status entity.WebhookDeliveryStatus, before time.Time) (int, error)
+}
@@ -0,0 +1,27 @@
+package service
+
+import (
+	"context"
+
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
+)
+
+type AuditService interface {
+	// Record appends the change to the audit trail. The changed fields are computed from the JSON
+	// of the Before and After states, the values of the secret fields are masked.
+	Record(ctx context.Context, entry AuditEntry) error
+}
+
+type AuditEntry struct {
+	Actor      *entity.Operator
+	Action     string
+	TargetType string
+	TargetID   string
+	SpaceID    entity.SpaceID
+	// Before is the state of the target before the change, nil on creation.
+	Before interface{}
+	// After is the state of the target after the change, nil on removal.
+	After     interface{}
+	IP        string
+	RequestID string
+}
@@ -0,0 +1,24 @@
+package service
+
+import (
+	"context"
+
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
+)
+
+type EmailChangeService interface {
+	// Start checks the current password of the user and sends the confirmation link to the new address
+	// and the notice with the revert link to the current one. The address isn't changed until it's confirmed.
+	Start(ctx context.Context, data StartEmailChangeData) error
+	// Confirm sets the new address of the user by the token of the confirmation link.
+	Confirm(ctx context.Context, token string) error
+	// Revert restores the previous address by the token of the revert link or cancels the change
+	// if it isn't confirmed yet.
+	Revert(ctx context.Context, token string) error
+}
+
+type StartEmailChangeData struct {
+	UserID   entity.UserID
+	Password string
+	Email    string
+}
@@ -0,0 +1,36 @@
+package service
+
+import (
+	"context"
+
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
+)
+
+type OperatorService interface {
+	Create(ctx context.Context, data CreateOperatorData) (*entity.Operator, error)
+	// Grant replaces the roles of the operator.
+	Grant(ctx context.Context, login string, grants []entity.OperatorGrant) (*entity.Operator, error)
+
+	// CheckPassword returns the enabled operator with the login & password. The failed checks are counted
+	// by the login and the ip address, which are locked for a while after too many of them.
+	CheckPassword(ctx context.Context, login, password, ip string) (*entity.Operator, error)
+	// SignIn checks the login & password and opens the session.
+	SignIn(ctx context.Context, login, password, ip string) (*entity.OperatorSession, error)
+	// SignInSubject opens the session for the operator linked to the user of the admin space.
+	// The operator with the login equal to the verified email is linked to the user on the first sign in.
+	SignInSubject(ctx context.Context, subject, email string, emailVerified bool) (*entity.OperatorSession, error)
+	SignOut(ctx context.Context, token string) error
+
+	// Authenticate returns the enabled operator of the session token.
+	Authenticate(ctx context.Context, token string) (*entity.Operator, error)
+}
+
+type CreateOperatorData struct {
+	Login    string
+	Name     string
+	Password string
+	Subject  string
+	Grants   []entity.OperatorGrant
+	// Algorithm is the password hash algorithm: "bcrypt" (default), "argon2id" or "scrypt".
+	Algorithm string
+}
@@ -8,4 +8,7 @@ import (
 
 type PasswordManager interface {
 	ChangePassword(ctx context.Context, userId entity.UserID, old, new string) error
+
+	// CheckPassword compares the password with the one of the default identity provider of the user space.
+	CheckPassword(ctx context.Context, userId entity.UserID, password string) error
 }
@@ -17,4 +17,8 @@ type UpdateUserData struct {
 	Phone         *string
 	PhoneVerified *bool
 	Role          *[]string
+	// Email is changed together with the identity of the default provider of the space,
+	// the address registered by another user of the space is refused.
+	Email         *string
+	EmailVerified *bool
 }
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+	"io"
+
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
+)
+
+const (
+	ImportFormatJSONL = "jsonl"
+	ImportFormatCSV   = "csv"
+)
+
+type UserImportService interface {
+	// Import creates the users of the records read in the format into the space. The records of the registered
+	// emails are skipped, the invalid ones are reported by the line and don't stop the import.
+	Import(ctx context.Context, r io.Reader, options ImportUsersOptions) (*ImportUsersResult, error)
+}
+
+type ImportUsersOptions struct {
+	SpaceID entity.SpaceID
+
+	// AppID is the application the users are registered with, the first application of the space if it's empty.
+	AppID entity.AppID
+
+	// Format is jsonl (default) or csv.
+	Format string
+
+	// Firebase contains the project parameters of the firebase-scrypt hashes encoded in base64.
+	Firebase FirebaseScryptOptions
+
+	// DryRun checks the records without creating the users.
+	DryRun bool
+}
+
+type FirebaseScryptOptions struct {
+	SignerKey     string
+	SaltSeparator string
+	Rounds        int
+	MemCost       int
+}
+
+type ImportUsersResult struct {
+	// Created is the number of the created users, or the ones to be created on the dry run.
+	Created int
+
+	// Skipped is the number of the records of the emails registered in the space already.
+	Skipped int
+
+	Errors []ImportUsersError
+}
+
+// ImportUsersError is the record which user isn't created. Line is the number of the record line in the file,
+// the csv header is the first line.
+type ImportUsersError struct {
+	Line  int
+	Email string
+	Error string
+}
@@ -1,13 +1,16 @@
 package env
 
-import "github.com/globalsign/mgo"
+import (
+	"github.com/globalsign/mgo"
+	"github.com/go-redis/redis"
+)
 
 type Env struct {
 	Store *Store
 }
 
-func New(db *mgo.Database) (*Env, error) {
-	storeEnv, err := newStore(db)
+func New(db *mgo.Database, redis *redis.Client) (*Env, error) {
+	storeEnv, err := newStore(db, redis)
 	if err != nil {
 		return nil, err
 	}
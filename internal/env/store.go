@@ -2,17 +2,23 @@ package env
 
 import (
 	"github.com/globalsign/mgo"
+	"github.com/go-redis/redis"
 )
 
 type Store struct {
 	Mongo *Mongo
+	Redis *Redis
 }
 
 type Mongo struct {
 	DB *mgo.Database
 }
 
-func newStore(db *mgo.Database) (*Store, error) {
+type Redis struct {
+	Client *redis.Client
+}
+
+func newStore(db *mgo.Database, client *redis.Client) (*Store, error) {
 	mgo, err := newMongo(db)
 	if err != nil {
 		return nil, err
@@ -20,6 +26,9 @@ func newStore(db *mgo.Database) (*Store, error) {
 
 	return &Store{
 		Mongo: mgo,
+		Redis: &Redis{
+			Client: client,
+		},
 	}, nil
 }
 
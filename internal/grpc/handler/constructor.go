@@ -13,6 +13,7 @@ type Params struct {
 	UserService         service.UserService
 	UserIdentityService service.UserIdentityService
 	PasswordManager     service.PasswordManager
+	EmailChange         service.EmailChangeService
 	// ApplicationService  service.ApplicationService
 	Users  repository.UserRepository
 	Spaces repository.SpaceRepository
@@ -26,6 +27,7 @@ func New(params Params) *Handler {
 		Spaces:              params.Spaces,
 		userIdentityService: params.UserIdentityService,
 		passwordManager:     params.PasswordManager,
+		emailChange:         params.EmailChange,
 		// app:             params.ApplicationService,
 	}
 }
@@ -18,6 +18,7 @@ type Handler struct {
 	Spaces              repository.SpaceRepository
 	userIdentityService service.UserIdentityService
 	passwordManager     service.PasswordManager
+	emailChange         service.EmailChangeService
 	// app             service.ApplicationService
 }
 
@@ -78,6 +79,17 @@ func (h *Handler) SetProfile(ctx context.Context, r *proto.SetProfileRequest) (*
 		})
 	}
 
+	// INFO: The new email is set after the user confirms it by the link
+	if r.Email != "" && r.Email != u.Email {
+		if err := h.emailChange.Start(ctx, service.StartEmailChangeData{
+			UserID:   entity.UserID(r.UserID),
+			Password: r.Password,
+			Email:    r.Email,
+		}); err != nil {
+			return nil, err
+		}
+	}
+
 	// update profile
 	if err == profile.ErrProfileNotFound {
 		p, err = h.ProfileService.Create(ctx, &service.CreateProfileData{
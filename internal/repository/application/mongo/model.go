@@ -1,6 +1,7 @@
 package mongo
 
 import (
+	"errors"
 	"time"
 
 	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
@@ -39,12 +40,24 @@ type model struct {
 	// PostLogoutRedirectUris is an array of allowed post logout redirect urls for the client.
 	PostLogoutRedirectUrls []string `bson:"post_logout_redirect_urls" json:"post_logout_redirect_urls"`
 
+	// OneTimeTokenSettings contains settings for storing one-time application tokens.
+	OneTimeTokenSettings *ottSettingsModel `bson:"ott_settings" json:"ott_settings"`
+
 	// WebHook endpoint URLs
 	WebHooks []string `bson:"webhooks" json:"webhooks"`
+
+	WebHookSecret                  string    `bson:"webhook_secret" json:"webhook_secret"`
+	WebHookPreviousSecret          string    `bson:"webhook_previous_secret" json:"-"`
+	WebHookPreviousSecretExpiresAt time.Time `bson:"webhook_previous_secret_expires_at" json:"-"`
+}
+
+type ottSettingsModel struct {
+	Length int `bson:"length" json:"length"`
+	TTL    int `bson:"ttl" json:"ttl"`
 }
 
 func (m model) Convert() *entity.Application {
-	return &entity.Application{
+	app := &entity.Application{
 		ID:                     entity.AppID(m.ID.Hex()),
 		SpaceID:                entity.SpaceID(m.SpaceID.Hex()),
 		Name:                   m.Name,
@@ -56,5 +69,52 @@ func (m model) Convert() *entity.Application {
 		AuthRedirectUrls:       m.AuthRedirectUrls,
 		PostLogoutRedirectUrls: m.PostLogoutRedirectUrls,
 		WebHooks:               m.WebHooks,
+
+		WebHookSecret:                  m.WebHookSecret,
+		WebHookPreviousSecret:          m.WebHookPreviousSecret,
+		WebHookPreviousSecretExpiresAt: m.WebHookPreviousSecretExpiresAt,
+	}
+	if m.OneTimeTokenSettings != nil {
+		app.OneTimeTokenSettings = &entity.OneTimeTokenSettings{
+			Length: m.OneTimeTokenSettings.Length,
+			TTL:    m.OneTimeTokenSettings.TTL,
+		}
+	}
+
+	return app
+}
+
+func newModel(i *entity.Application) (*model, error) {
+	if !bson.IsObjectIdHex(string(i.ID)) {
+		return nil, errors.New("Application.ID is invalid")
+	}
+	if !bson.IsObjectIdHex(string(i.SpaceID)) {
+		return nil, errors.New("Application.SpaceID is invalid")
+	}
+
+	m := &model{
+		ID:                     bson.ObjectIdHex(string(i.ID)),
+		SpaceID:                bson.ObjectIdHex(string(i.SpaceID)),
+		Name:                   i.Name,
+		Description:            i.Description,
+		IsActive:               i.IsActive,
+		CreatedAt:              i.CreatedAt,
+		UpdatedAt:              i.UpdatedAt,
+		AuthSecret:             i.AuthSecret,
+		AuthRedirectUrls:       i.AuthRedirectUrls,
+		PostLogoutRedirectUrls: i.PostLogoutRedirectUrls,
+		WebHooks:               i.WebHooks,
+
+		WebHookSecret:                  i.WebHookSecret,
+		WebHookPreviousSecret:          i.WebHookPreviousSecret,
+		WebHookPreviousSecretExpiresAt: i.WebHookPreviousSecretExpiresAt,
 	}
+	if i.OneTimeTokenSettings != nil {
+		m.OneTimeTokenSettings = &ottSettingsModel{
+			Length: i.OneTimeTokenSettings.Length,
+			TTL:    i.OneTimeTokenSettings.TTL,
+		}
+	}
+
+	return m, nil
 }
@@ -4,7 +4,9 @@ import (
 	"context"
 
 	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/repository"
 	"github.com/ProtocolONE/auth1.protocol.one/internal/env"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/database"
 	"github.com/globalsign/mgo"
 	"github.com/globalsign/mgo/bson"
 )
@@ -19,10 +21,74 @@ func New(env *env.Mongo) ApplicationRepository {
 	}
 }
 
-func (r ApplicationRepository) Find(ctx context.Context) ([]*entity.Application, error) {
+func (r ApplicationRepository) Create(ctx context.Context, app *entity.Application) error {
+	if app.ID == "" {
+		app.ID = entity.AppID(bson.NewObjectId().Hex())
+	}
+
+	m, err := newModel(app)
+	if err != nil {
+		return err
+	}
+	if err := r.col.Insert(m); err != nil {
+		return err
+	}
+
+	*app = *m.Convert()
+	return nil
+}
+
+func (r ApplicationRepository) Update(ctx context.Context, app *entity.Application) error {
+	m, err := newModel(app)
+	if err != nil {
+		return err
+	}
+	if err := r.col.UpdateId(m.ID, m); err != nil {
+		return err
+	}
+
+	*app = *m.Convert()
+	return nil
+}
+
+func (r ApplicationRepository) Delete(ctx context.Context, id entity.AppID) error {
+	return r.col.RemoveId(bson.ObjectIdHex(string(id)))
+}
+
+var sortFields = map[string]string{
+	"id":         "_id",
+	"name":       "name",
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+}
+
+func (r ApplicationRepository) Find(ctx context.Context, query repository.ApplicationQuery) ([]*entity.Application, int, error) {
+	filter := bson.M{}
+	if query.SpaceIDs != nil {
+		ids := make([]bson.ObjectId, 0, len(query.SpaceIDs))
+		for _, id := range query.SpaceIDs {
+			if bson.IsObjectIdHex(string(id)) {
+				ids = append(ids, bson.ObjectIdHex(string(id)))
+			}
+		}
+		filter["space_id"] = bson.M{"$in": ids}
+	}
+	if query.Search != "" {
+		filter["name"] = database.Prefix(query.Search)
+	}
+	if query.IsActive != nil {
+		filter["is_active"] = *query.IsActive
+	}
+
+	q := r.col.Find(filter)
+	total, err := q.Count()
+	if err != nil {
+		return nil, 0, err
+	}
+
 	var m []model
-	if err := r.col.Find(nil).All(&m); err != nil {
-		return nil, err
+	if err := q.Sort(database.Sort(query.Sort, sortFields)...).Skip(query.Offset).Limit(query.Limit).All(&m); err != nil {
+		return nil, 0, err
 	}
 
 	var result []*entity.Application
@@ -30,15 +96,19 @@ func (r ApplicationRepository) Find(ctx context.Context) ([]*entity.Application,
 		result = append(result, m[i].Convert())
 	}
 
-	return result, nil
+	return result, total, nil
 }
 
 func (r ApplicationRepository) FindByID(ctx context.Context, id entity.AppID) (*entity.Application, error) {
-	var (
-		p   model
-		oid = bson.ObjectIdHex(string(id))
-	)
-	if err := r.col.FindId(oid).One(&p); err != nil {
+	if !bson.IsObjectIdHex(string(id)) {
+		return nil, nil
+	}
+
+	var p model
+	if err := r.col.FindId(bson.ObjectIdHex(string(id))).One(&p); err != nil {
+		if err == mgo.ErrNotFound {
+			return nil, nil
+		}
 		return nil, err
 	}
 	return p.Convert(), nil
@@ -0,0 +1,11 @@
+package audit
+
+import (
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/repository"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/env"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/repository/audit/mongo"
+)
+
+func New(env *env.Env) repository.AuditRepository {
+	return mongo.New(env.Store.Mongo)
+}
@@ -0,0 +1,70 @@
+package mongo
+
+import (
+	"time"
+
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
+	"github.com/globalsign/mgo/bson"
+)
+
+type model struct {
+	ID         bson.ObjectId `bson:"_id"`
+	ActorID    string        `bson:"actor_id"`
+	ActorLogin string        `bson:"actor_login"`
+	Action     string        `bson:"action"`
+	TargetType string        `bson:"target_type"`
+	TargetID   string        `bson:"target_id"`
+	SpaceID    string        `bson:"space_id"`
+	Changes    []changeModel `bson:"changes"`
+	IP         string        `bson:"ip"`
+	RequestID  string        `bson:"request_id"`
+	CreatedAt  time.Time     `bson:"created_at"`
+}
+
+type changeModel struct {
+	Field  string      `bson:"field"`
+	Before interface{} `bson:"before"`
+	After  interface{} `bson:"after"`
+}
+
+func newModel(r *entity.AuditRecord) *model {
+	changes := make([]changeModel, 0, len(r.Changes))
+	for _, c := range r.Changes {
+		changes = append(changes, changeModel(c))
+	}
+
+	return &model{
+		ID:         bson.ObjectIdHex(string(r.ID)),
+		ActorID:    string(r.ActorID),
+		ActorLogin: r.ActorLogin,
+		Action:     r.Action,
+		TargetType: r.TargetType,
+		TargetID:   r.TargetID,
+		SpaceID:    string(r.SpaceID),
+		Changes:    changes,
+		IP:         r.IP,
+		RequestID:  r.RequestID,
+		CreatedAt:  r.CreatedAt,
+	}
+}
+
+func (m model) Convert() *entity.AuditRecord {
+	changes := make([]entity.AuditChange, 0, len(m.Changes))
+	for _, c := range m.Changes {
+		changes = append(changes, entity.AuditChange(c))
+	}
+
+	return &entity.AuditRecord{
+		ID:         entity.AuditRecordID(m.ID.Hex()),
+		ActorID:    entity.OperatorID(m.ActorID),
+		ActorLogin: m.ActorLogin,
+		Action:     m.Action,
+		TargetType: m.TargetType,
+		TargetID:   m.TargetID,
+		SpaceID:    entity.SpaceID(m.SpaceID),
+		Changes:    changes,
+		IP:         m.IP,
+		RequestID:  m.RequestID,
+		CreatedAt:  m.CreatedAt,
+	}
+}
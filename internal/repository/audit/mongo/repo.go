@@ -0,0 +1,80 @@
+package mongo
+
+import (
+	"context"
+
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/repository"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/env"
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+)
+
+type AuditRepository struct {
+	col *mgo.Collection
+}
+
+func New(env *env.Mongo) *AuditRepository {
+	return &AuditRepository{
+		col: env.DB.C("admin_audit"),
+	}
+}
+
+func (r *AuditRepository) Create(ctx context.Context, record *entity.AuditRecord) error {
+	if record.ID == "" {
+		record.ID = entity.AuditRecordID(bson.NewObjectId().Hex())
+	}
+
+	return r.col.Insert(newModel(record))
+}
+
+func (r *AuditRepository) Find(ctx context.Context, query repository.AuditQuery) ([]*entity.AuditRecord, int, error) {
+	filter := bson.M{}
+	if query.ActorID != "" {
+		filter["actor_id"] = string(query.ActorID)
+	}
+	if query.Action != "" {
+		filter["action"] = query.Action
+	}
+	if query.TargetType != "" {
+		filter["target_type"] = query.TargetType
+	}
+	if query.TargetID != "" {
+		filter["target_id"] = query.TargetID
+	}
+	if query.SpaceIDs != nil {
+		ids := make([]string, 0, len(query.SpaceIDs))
+		for _, id := range query.SpaceIDs {
+			ids = append(ids, string(id))
+		}
+		filter["space_id"] = bson.M{"$in": ids}
+	}
+	if !query.From.IsZero() || !query.To.IsZero() {
+		created := bson.M{}
+		if !query.From.IsZero() {
+			created["$gte"] = query.From
+		}
+		if !query.To.IsZero() {
+			created["$lt"] = query.To
+		}
+		filter["created_at"] = created
+	}
+
+	q := r.col.Find(filter)
+	total, err := q.Count()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var m []model
+	if err := q.Sort("-created_at").Skip(query.Offset).Limit(query.Limit).All(&m); err != nil {
+		return nil, 0, err
+	}
+
+	var result []*entity.AuditRecord
+	for i := range m {
+		result = append(result, m[i].Convert())
+	}
+
+	return result, total, nil
+}
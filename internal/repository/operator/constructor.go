@@ -0,0 +1,11 @@
+package operator
+
+import (
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/repository"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/env"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/repository/operator/mongo"
+)
+
+func New(env *env.Env) repository.OperatorRepository {
+	return mongo.New(env.Store.Mongo)
+}
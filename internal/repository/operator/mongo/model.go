@@ -0,0 +1,66 @@
+package mongo
+
+import (
+	"time"
+
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
+	"github.com/globalsign/mgo/bson"
+)
+
+type model struct {
+	ID           bson.ObjectId `bson:"_id"`
+	Login        string        `bson:"login"`
+	Name         string        `bson:"name"`
+	PasswordHash string        `bson:"password_hash"`
+	Subject      string        `bson:"subject"`
+	Grants       []grantModel  `bson:"grants"`
+	Disabled     bool          `bson:"disabled"`
+	LastLoginAt  time.Time     `bson:"last_login_at"`
+	CreatedAt    time.Time     `bson:"created_at"`
+	UpdatedAt    time.Time     `bson:"updated_at"`
+}
+
+type grantModel struct {
+	Role    string `bson:"role"`
+	SpaceID string `bson:"space_id"`
+}
+
+func newModel(o *entity.Operator) *model {
+	grants := make([]grantModel, 0, len(o.Grants))
+	for _, g := range o.Grants {
+		grants = append(grants, grantModel{Role: string(g.Role), SpaceID: string(g.SpaceID)})
+	}
+
+	return &model{
+		ID:           bson.ObjectIdHex(string(o.ID)),
+		Login:        o.Login,
+		Name:         o.Name,
+		PasswordHash: o.PasswordHash,
+		Subject:      o.Subject,
+		Grants:       grants,
+		Disabled:     o.Disabled,
+		LastLoginAt:  o.LastLoginAt,
+		CreatedAt:    o.CreatedAt,
+		UpdatedAt:    o.UpdatedAt,
+	}
+}
+
+func (m model) Convert() *entity.Operator {
+	var grants []entity.OperatorGrant
+	for _, g := range m.Grants {
+		grants = append(grants, entity.OperatorGrant{Role: entity.OperatorRole(g.Role), SpaceID: entity.SpaceID(g.SpaceID)})
+	}
+
+	return &entity.Operator{
+		ID:           entity.OperatorID(m.ID.Hex()),
+		Login:        m.Login,
+		Name:         m.Name,
+		PasswordHash: m.PasswordHash,
+		Subject:      m.Subject,
+		Grants:       grants,
+		Disabled:     m.Disabled,
+		LastLoginAt:  m.LastLoginAt,
+		CreatedAt:    m.CreatedAt,
+		UpdatedAt:    m.UpdatedAt,
+	}
+}
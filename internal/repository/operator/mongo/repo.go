@@ -0,0 +1,90 @@
+package mongo
+
+import (
+	"context"
+
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/env"
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+)
+
+type OperatorRepository struct {
+	col *mgo.Collection
+}
+
+func New(env *env.Mongo) *OperatorRepository {
+	return &OperatorRepository{
+		col: env.DB.C("operator"),
+	}
+}
+
+func (r *OperatorRepository) Create(ctx context.Context, operator *entity.Operator) error {
+	if operator.ID == "" {
+		operator.ID = entity.OperatorID(bson.NewObjectId().Hex())
+	}
+
+	m := newModel(operator)
+	if err := r.col.Insert(m); err != nil {
+		return err
+	}
+
+	*operator = *m.Convert()
+	return nil
+}
+
+func (r *OperatorRepository) Update(ctx context.Context, operator *entity.Operator) error {
+	m := newModel(operator)
+	if err := r.col.UpdateId(m.ID, m); err != nil {
+		return err
+	}
+
+	*operator = *m.Convert()
+	return nil
+}
+
+func (r *OperatorRepository) Find(ctx context.Context) ([]*entity.Operator, error) {
+	var m []model
+	if err := r.col.Find(nil).Sort("login").All(&m); err != nil {
+		return nil, err
+	}
+
+	var result []*entity.Operator
+	for i := range m {
+		result = append(result, m[i].Convert())
+	}
+
+	return result, nil
+}
+
+func (r *OperatorRepository) FindByID(ctx context.Context, id entity.OperatorID) (*entity.Operator, error) {
+	if !bson.IsObjectIdHex(string(id)) {
+		return nil, nil
+	}
+
+	return r.findOne(bson.M{"_id": bson.ObjectIdHex(string(id))})
+}
+
+func (r *OperatorRepository) FindByLogin(ctx context.Context, login string) (*entity.Operator, error) {
+	return r.findOne(bson.M{"login": login})
+}
+
+func (r *OperatorRepository) FindBySubject(ctx context.Context, subject string) (*entity.Operator, error) {
+	if subject == "" {
+		return nil, nil
+	}
+
+	return r.findOne(bson.M{"subject": subject})
+}
+
+func (r *OperatorRepository) findOne(filter bson.M) (*entity.Operator, error) {
+	m := &model{}
+	if err := r.col.Find(filter).One(m); err != nil {
+		if err == mgo.ErrNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return m.Convert(), nil
+}
@@ -0,0 +1,11 @@
+package operator_session
+
+import (
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/repository"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/env"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/repository/operator_session/redis"
+)
+
+func New(env *env.Env) repository.OperatorSessionRepository {
+	return redis.New(env.Store.Redis)
+}
@@ -0,0 +1,80 @@
+package redis
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/env"
+	"github.com/go-redis/redis"
+)
+
+const sessionKeyPattern = "operator_session_%s"
+
+type model struct {
+	OperatorID string    `json:"operator_id"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type OperatorSessionRepository struct {
+	client *redis.Client
+}
+
+func New(env *env.Redis) *OperatorSessionRepository {
+	return &OperatorSessionRepository{
+		client: env.Client,
+	}
+}
+
+func (r *OperatorSessionRepository) Create(ctx context.Context, session *entity.OperatorSession) error {
+	data, err := json.Marshal(model{
+		OperatorID: string(session.OperatorID),
+		ExpiresAt:  session.ExpiresAt,
+		CreatedAt:  session.CreatedAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	return r.client.Set(key(session.Token), data, time.Until(session.ExpiresAt)).Err()
+}
+
+func (r *OperatorSessionRepository) FindByToken(ctx context.Context, token string) (*entity.OperatorSession, error) {
+	data, err := r.client.Get(key(token)).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	m := model{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	if time.Now().After(m.ExpiresAt) {
+		return nil, nil
+	}
+
+	return &entity.OperatorSession{
+		Token:      token,
+		OperatorID: entity.OperatorID(m.OperatorID),
+		ExpiresAt:  m.ExpiresAt,
+		CreatedAt:  m.CreatedAt,
+	}, nil
+}
+
+func (r *OperatorSessionRepository) Delete(ctx context.Context, token string) error {
+	return r.client.Del(key(token)).Err()
+}
+
+// key hashes the token, so the tokens can't be read from the storage.
+func key(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return fmt.Sprintf(sessionKeyPattern, hex.EncodeToString(hash[:]))
+}
@@ -2,39 +2,62 @@ package repository
 
 import (
 	"context"
+	"strings"
 	"time"
 
 	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
 	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/repository"
 	"github.com/ProtocolONE/auth1.protocol.one/internal/env"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/database"
 	"github.com/globalsign/mgo"
 	"github.com/globalsign/mgo/bson"
 )
 
 type spaceModel struct {
-	ID                bson.ObjectId    `bson:"_id"`
-	Name              string           `bson:"name"`
-	Description       string           `bson:"description"`
-	UniqueUsernames   bool             `bson:"unique_usernames"`
-	RequiresCaptcha   bool             `bson:"requires_captcha"`
-	PasswordSettings  passwordSettings `bson:"password_settings"`
-	IdentityProviders []idProvider     `bson:"identity_providers"`
-	Roles             []string         `bson:"roles" json:"roles"`
-	DefaultRole       string           `bson:"default_role" json:"default_role"`
-	CreatedAt         time.Time        `bson:"created_at"`
-	UpdatedAt         time.Time        `bson:"updated_at"`
+	ID                    bson.ObjectId    `bson:"_id"`
+	Name                  string           `bson:"name"`
+	Description           string           `bson:"description"`
+	UniqueUsernames       bool             `bson:"unique_usernames"`
+	RequiresCaptcha       bool             `bson:"requires_captcha"`
+	RequiresMfa           bool             `bson:"requires_mfa"`
+	RequiresVerifiedEmail bool             `bson:"requires_verified_email"`
+	PasswordSettings      passwordSettings `bson:"password_settings"`
+	LockoutSettings       lockoutSettings  `bson:"lockout_settings"`
+	IdentityProviders     []idProvider     `bson:"identity_providers"`
+	Roles                 []string         `bson:"roles" json:"roles"`
+	DefaultRole           string           `bson:"default_role" json:"default_role"`
+	CreatedAt             time.Time        `bson:"created_at"`
+	UpdatedAt             time.Time        `bson:"updated_at"`
 }
 
 type passwordSettings struct {
-	BcryptCost     int  `bson:"bcrypt_cost"`
-	Min            int  `bson:"min"`
-	Max            int  `bson:"max"`
-	RequireNumber  bool `bson:"require_number"`
-	RequireUpper   bool `bson:"require_upper"`
-	RequireSpecial bool `bson:"require_special"`
-	RequireLetter  bool `bson:"require_letter"`
-	TokenLength    int  `bson:"token_length"`
-	TokenTTL       int  `bson:"token_ttl"`
+	Algorithm      string `bson:"algorithm"`
+	BcryptCost     int    `bson:"bcrypt_cost"`
+	Argon2Memory   int    `bson:"argon2_memory"`
+	Argon2Time     int    `bson:"argon2_time"`
+	Argon2Threads  int    `bson:"argon2_threads"`
+	ScryptN        int    `bson:"scrypt_n"`
+	ScryptR        int    `bson:"scrypt_r"`
+	ScryptP        int    `bson:"scrypt_p"`
+	Min            int    `bson:"min"`
+	Max            int    `bson:"max"`
+	RequireNumber  bool   `bson:"require_number"`
+	RequireUpper   bool   `bson:"require_upper"`
+	RequireSpecial bool   `bson:"require_special"`
+	RequireLetter  bool   `bson:"require_letter"`
+	RejectBreached bool   `bson:"reject_breached"`
+	HistorySize    int    `bson:"history_size"`
+	TokenLength    int    `bson:"token_length"`
+	TokenTTL       int    `bson:"token_ttl"`
+}
+
+type lockoutSettings struct {
+	MaxAttempts   int `bson:"max_attempts"`
+	IPMaxAttempts int `bson:"ip_max_attempts"`
+	Window        int `bson:"window"`
+	LockDuration  int `bson:"lock_duration"`
+	Delay         int `bson:"delay"`
+	MaxDelay      int `bson:"max_delay"`
 }
 
 type idProvider struct {
@@ -48,6 +71,23 @@ type idProvider struct {
 	EndpointAuthURL     string        `bson:"endpoint_auth_url"`
 	EndpointTokenURL    string        `bson:"endpoint_token_url"`
 	EndpointUserInfoURL string        `bson:"endpoint_userinfo_url"`
+	Issuer              string        `bson:"issuer,omitempty"`
+	Certificate         string        `bson:"certificate,omitempty"`
+	TeamID              string        `bson:"team_id,omitempty"`
+	KeyID               string        `bson:"key_id,omitempty"`
+	ClaimMapping        claimMapping  `bson:"claim_mapping"`
+}
+
+type claimMapping struct {
+	ExternalID    string `bson:"external_id,omitempty"`
+	Email         string `bson:"email,omitempty"`
+	EmailVerified string `bson:"email_verified,omitempty"`
+	Username      string `bson:"username,omitempty"`
+	Name          string `bson:"name,omitempty"`
+	FirstName     string `bson:"first_name,omitempty"`
+	LastName      string `bson:"last_name,omitempty"`
+	Birthday      string `bson:"birthday,omitempty"`
+	Picture       string `bson:"picture,omitempty"`
 }
 
 func newSpaceModel(s *entity.Space) *spaceModel {
@@ -64,21 +104,29 @@ func newSpaceModel(s *entity.Space) *spaceModel {
 			EndpointAuthURL:     provider.EndpointAuthURL,
 			EndpointTokenURL:    provider.EndpointTokenURL,
 			EndpointUserInfoURL: provider.EndpointUserInfoURL,
+			Issuer:              provider.Issuer,
+			Certificate:         provider.Certificate,
+			TeamID:              provider.TeamID,
+			KeyID:               provider.KeyID,
+			ClaimMapping:        claimMapping(provider.ClaimMapping),
 		})
 	}
 
 	return &spaceModel{
-		ID:                bson.ObjectIdHex(string(s.ID)),
-		Name:              s.Name,
-		Description:       s.Description,
-		UniqueUsernames:   s.UniqueUsernames,
-		RequiresCaptcha:   s.RequiresCaptcha,
-		PasswordSettings:  passwordSettings(s.PasswordSettings),
-		IdentityProviders: providers,
-		Roles:             s.Roles,
-		DefaultRole:       s.DefaultRole,
-		CreatedAt:         s.CreatedAt,
-		UpdatedAt:         s.UpdatedAt,
+		ID:                    bson.ObjectIdHex(string(s.ID)),
+		Name:                  s.Name,
+		Description:           s.Description,
+		UniqueUsernames:       s.UniqueUsernames,
+		RequiresCaptcha:       s.RequiresCaptcha,
+		RequiresMfa:           s.RequiresMfa,
+		RequiresVerifiedEmail: s.RequiresVerifiedEmail,
+		PasswordSettings:      passwordSettings(s.PasswordSettings),
+		LockoutSettings:       lockoutSettings(s.LockoutSettings),
+		IdentityProviders:     providers,
+		Roles:                 s.Roles,
+		DefaultRole:           s.DefaultRole,
+		CreatedAt:             s.CreatedAt,
+		UpdatedAt:             s.UpdatedAt,
 	}
 }
 
@@ -96,21 +144,29 @@ func (m *spaceModel) convert() *entity.Space {
 			EndpointAuthURL:     provider.EndpointAuthURL,
 			EndpointTokenURL:    provider.EndpointTokenURL,
 			EndpointUserInfoURL: provider.EndpointUserInfoURL,
+			Issuer:              provider.Issuer,
+			Certificate:         provider.Certificate,
+			TeamID:              provider.TeamID,
+			KeyID:               provider.KeyID,
+			ClaimMapping:        entity.ClaimMapping(provider.ClaimMapping),
 		})
 	}
 
 	return &entity.Space{
-		ID:                entity.SpaceID(m.ID.Hex()),
-		Name:              m.Name,
-		Description:       m.Description,
-		UniqueUsernames:   m.UniqueUsernames,
-		RequiresCaptcha:   m.RequiresCaptcha,
-		PasswordSettings:  entity.PasswordSettings(m.PasswordSettings),
-		IdentityProviders: providers,
-		Roles:             m.Roles,
-		DefaultRole:       m.DefaultRole,
-		CreatedAt:         m.CreatedAt,
-		UpdatedAt:         m.UpdatedAt,
+		ID:                    entity.SpaceID(m.ID.Hex()),
+		Name:                  m.Name,
+		Description:           m.Description,
+		UniqueUsernames:       m.UniqueUsernames,
+		RequiresCaptcha:       m.RequiresCaptcha,
+		RequiresMfa:           m.RequiresMfa,
+		RequiresVerifiedEmail: m.RequiresVerifiedEmail,
+		PasswordSettings:      entity.PasswordSettings(m.PasswordSettings),
+		LockoutSettings:       entity.LockoutSettings(m.LockoutSettings),
+		IdentityProviders:     providers,
+		Roles:                 m.Roles,
+		DefaultRole:           m.DefaultRole,
+		CreatedAt:             m.CreatedAt,
+		UpdatedAt:             m.UpdatedAt,
 	}
 }
 
@@ -128,10 +184,39 @@ func NewSpaceRepository(env *env.Mongo) *SpaceRepository {
 	}
 }
 
-func (r *SpaceRepository) Find(ctx context.Context) ([]*entity.Space, error) {
+var (
+	spaceSortFields = map[string]string{
+		"id":         "_id",
+		"name":       "name",
+		"created_at": "created_at",
+		"updated_at": "updated_at",
+	}
+	providerSortFields = map[string]string{
+		"id":           "identity_providers._id",
+		"name":         "identity_providers.name",
+		"display_name": "identity_providers.display_name",
+		"type":         "identity_providers.type",
+	}
+)
+
+func (r *SpaceRepository) Find(ctx context.Context, query repository.SpaceQuery) ([]*entity.Space, int, error) {
+	filter := bson.M{}
+	if query.IDs != nil {
+		filter["_id"] = bson.M{"$in": spaceObjectIds(query.IDs)}
+	}
+	if query.Search != "" {
+		filter["name"] = database.Prefix(query.Search)
+	}
+
+	q := r.col.Find(filter)
+	total, err := q.Count()
+	if err != nil {
+		return nil, 0, err
+	}
+
 	var m []spaceModel
-	if err := r.col.Find(nil).All(&m); err != nil {
-		return nil, err
+	if err := q.Sort(database.Sort(query.Sort, spaceSortFields)...).Skip(query.Offset).Limit(query.Limit).All(&m); err != nil {
+		return nil, 0, err
 	}
 
 	var result []*entity.Space
@@ -139,7 +224,86 @@ func (r *SpaceRepository) Find(ctx context.Context) ([]*entity.Space, error) {
 		result = append(result, m[i].convert())
 	}
 
-	return result, nil
+	return result, total, nil
+}
+
+func (r *SpaceRepository) FindProviders(ctx context.Context, query repository.IdentityProviderQuery) ([]*repository.SpaceIdentityProvider, int, error) {
+	var pipeline []bson.M
+	if query.SpaceIDs != nil {
+		pipeline = append(pipeline, bson.M{"$match": bson.M{"_id": bson.M{"$in": spaceObjectIds(query.SpaceIDs)}}})
+	}
+	pipeline = append(pipeline, bson.M{"$unwind": "$identity_providers"})
+
+	filter := bson.M{}
+	if query.Search != "" {
+		filter["$or"] = []bson.M{
+			{"identity_providers.name": database.Prefix(query.Search)},
+			{"identity_providers.display_name": database.Prefix(query.Search)},
+		}
+	}
+	if query.Type != "" {
+		filter["identity_providers.type"] = string(query.Type)
+	}
+	if len(filter) > 0 {
+		pipeline = append(pipeline, bson.M{"$match": filter})
+	}
+
+	var count []struct {
+		Total int `bson:"total"`
+	}
+	countPipeline := append(append([]bson.M{}, pipeline...), bson.M{"$group": bson.M{"_id": nil, "total": bson.M{"$sum": 1}}})
+	if err := r.col.Pipe(countPipeline).All(&count); err != nil {
+		return nil, 0, err
+	}
+	if len(count) == 0 {
+		return nil, 0, nil
+	}
+
+	// INFO: The providers of the space share its _id, so they are sorted by their own id by default
+	if _, ok := providerSortFields[strings.TrimPrefix(query.Sort, "-")]; !ok {
+		query.Sort = "id"
+	}
+	sort := bson.D{}
+	for _, f := range database.Sort(query.Sort, providerSortFields) {
+		if strings.HasPrefix(f, "-") {
+			sort = append(sort, bson.DocElem{Name: f[1:], Value: -1})
+		} else {
+			sort = append(sort, bson.DocElem{Name: f, Value: 1})
+		}
+	}
+	pipeline = append(pipeline, bson.M{"$sort": sort}, bson.M{"$skip": query.Offset})
+	if query.Limit > 0 {
+		pipeline = append(pipeline, bson.M{"$limit": query.Limit})
+	}
+
+	var m []struct {
+		SpaceID  bson.ObjectId `bson:"_id"`
+		Provider idProvider    `bson:"identity_providers"`
+	}
+	if err := r.col.Pipe(pipeline).All(&m); err != nil {
+		return nil, 0, err
+	}
+
+	var result []*repository.SpaceIdentityProvider
+	for i := range m {
+		space := spaceModel{ID: m[i].SpaceID, IdentityProviders: []idProvider{m[i].Provider}}
+		result = append(result, &repository.SpaceIdentityProvider{
+			SpaceID:  entity.SpaceID(m[i].SpaceID.Hex()),
+			Provider: space.convert().IdentityProviders[0],
+		})
+	}
+
+	return result, count[0].Total, nil
+}
+
+func spaceObjectIds(ids []entity.SpaceID) []bson.ObjectId {
+	result := make([]bson.ObjectId, 0, len(ids))
+	for _, id := range ids {
+		if bson.IsObjectIdHex(string(id)) {
+			result = append(result, bson.ObjectIdHex(string(id)))
+		}
+	}
+	return result
 }
 
 func (r *SpaceRepository) FindByID(ctx context.Context, id entity.SpaceID) (*entity.Space, error) {
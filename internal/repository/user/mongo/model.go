@@ -24,6 +24,9 @@ type model struct {
 	// EmailVerified is status of verification user address.
 	EmailVerified bool `bson:"email_verified" json:"email_verified"`
 
+	// EmailPending is set while the email change isn't applied to the identity of the default provider.
+	EmailPending bool `bson:"email_pending,omitempty" json:"-"`
+
 	// PhoneNumber is the phone number of the user.
 	PhoneNumber string `bson:"phone_number" json:"phone_number"`
 
@@ -54,6 +57,15 @@ type model struct {
 	// Blocked is status of user blocked.
 	Blocked bool `bson:"blocked" json:"blocked"`
 
+	// BlockedReason is the reason of the block given by the operator.
+	BlockedReason string `bson:"blocked_reason,omitempty" json:"blocked_reason,omitempty"`
+
+	// BlockedUntil is the end time of the block, the zero value means the block is permanent.
+	BlockedUntil time.Time `bson:"blocked_until,omitempty" json:"blocked_until,omitempty"`
+
+	// BlockedAt returns the timestamp of the block.
+	BlockedAt time.Time `bson:"blocked_at,omitempty" json:"blocked_at,omitempty"`
+
 	// DeviceID is unique user client identifier
 	// DeviceID []string `bson:"device_id" json:"device_id"`
 
@@ -74,12 +86,16 @@ func (m model) Convert() *entity.User {
 		AppID:         entity.AppID(m.AppID.Hex()),
 		Email:         m.Email,
 		EmailVerified: m.EmailVerified,
+		EmailPending:  m.EmailPending,
 		PhoneNumber:   m.PhoneNumber,
 		PhoneVerified: m.PhoneVerified,
 		Username:      m.Username,
 		Name:          m.Name,
 		Picture:       m.Picture,
 		Blocked:       m.Blocked,
+		BlockedReason: m.BlockedReason,
+		BlockedUntil:  m.BlockedUntil,
+		BlockedAt:     m.BlockedAt,
 		CreatedAt:     m.CreatedAt,
 		UpdatedAt:     m.UpdatedAt,
 		// LastIp:        m.LastIp,
@@ -103,15 +119,19 @@ func newModel(i *entity.User) (*model, error) {
 		AppID:         bson.ObjectIdHex(string(i.AppID)),
 		Email:         i.Email,
 		EmailVerified: i.EmailVerified,
+		EmailPending:  i.EmailPending,
 		PhoneNumber:   i.PhoneNumber,
 		PhoneVerified: i.PhoneVerified,
 		Username:      i.Username,
 		// UniqueUsername:  i.UniqueUsername, // TODO
-		Name:      i.Name,
-		Picture:   i.Picture,
-		Blocked:   i.Blocked,
-		CreatedAt: i.CreatedAt,
-		UpdatedAt: i.UpdatedAt,
+		Name:          i.Name,
+		Picture:       i.Picture,
+		Blocked:       i.Blocked,
+		BlockedReason: i.BlockedReason,
+		BlockedUntil:  i.BlockedUntil,
+		BlockedAt:     i.BlockedAt,
+		CreatedAt:     i.CreatedAt,
+		UpdatedAt:     i.UpdatedAt,
 		// LastIp:      i.LastIp,
 		// LastLogin:   i.LastLogin,
 		// LoginsCount: i.LoginsCount,
@@ -4,7 +4,9 @@ import (
 	"context"
 
 	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/repository"
 	"github.com/ProtocolONE/auth1.protocol.one/internal/env"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/database"
 	"github.com/globalsign/mgo"
 	"github.com/globalsign/mgo/bson"
 )
@@ -19,6 +21,18 @@ func New(env *env.Mongo) *UserRepository {
 	}
 }
 
+func (r *UserRepository) Create(ctx context.Context, user *entity.User) error {
+	if user.ID == "" {
+		user.ID = entity.UserID(bson.NewObjectId().Hex())
+	}
+	model, err := newModel(user)
+	if err != nil {
+		return err
+	}
+
+	return r.col.Insert(model)
+}
+
 func (r *UserRepository) Update(ctx context.Context, user *entity.User) error {
 	model, err := newModel(user)
 	if err != nil {
@@ -33,10 +47,52 @@ func (r *UserRepository) Update(ctx context.Context, user *entity.User) error {
 	return nil
 }
 
-func (r *UserRepository) Find(ctx context.Context) ([]*entity.User, error) {
+var sortFields = map[string]string{
+	"id":         "_id",
+	"email":      "email",
+	"username":   "username",
+	"created_at": "created_at",
+}
+
+func (r *UserRepository) Find(ctx context.Context, query repository.UserQuery) ([]*entity.User, int, error) {
+	filter := bson.M{}
+	if query.SpaceIDs != nil {
+		ids := make([]bson.ObjectId, 0, len(query.SpaceIDs))
+		for _, id := range query.SpaceIDs {
+			if bson.IsObjectIdHex(string(id)) {
+				ids = append(ids, bson.ObjectIdHex(string(id)))
+			}
+		}
+		filter["space_id"] = bson.M{"$in": ids}
+	}
+	if query.Search != "" {
+		filter["$or"] = []bson.M{
+			{"email": database.Prefix(query.Search)},
+			{"username": database.Prefix(query.Search)},
+		}
+	}
+	if query.Email != "" {
+		filter["email"] = database.Prefix(query.Email)
+	}
+	if query.Username != "" {
+		filter["username"] = database.Prefix(query.Username)
+	}
+	if query.Blocked != nil {
+		filter["blocked"] = *query.Blocked
+	}
+	if query.EmailVerified != nil {
+		filter["email_verified"] = *query.EmailVerified
+	}
+
+	q := r.col.Find(filter)
+	total, err := q.Count()
+	if err != nil {
+		return nil, 0, err
+	}
+
 	var m []model
-	if err := r.col.Find(nil).All(&m); err != nil {
-		return nil, err
+	if err := q.Sort(database.Sort(query.Sort, sortFields)...).Skip(query.Offset).Limit(query.Limit).All(&m); err != nil {
+		return nil, 0, err
 	}
 
 	var result []*entity.User
@@ -44,7 +100,7 @@ func (r *UserRepository) Find(ctx context.Context) ([]*entity.User, error) {
 		result = append(result, m[i].Convert())
 	}
 
-	return result, nil
+	return result, total, nil
 }
 
 func (r *UserRepository) FindByID(ctx context.Context, id entity.UserID) (*entity.User, error) {
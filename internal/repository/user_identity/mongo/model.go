@@ -11,9 +11,11 @@ import (
 type model struct {
 	ID                 bson.ObjectId `bson:"_id"`
 	UserID             bson.ObjectId `bson:"user_id"`
+	AppID              bson.ObjectId `bson:"app_id,omitempty"`
 	IdentityProviderID bson.ObjectId `bson:"identity_provider_id"`
 	ExternalID         string        `bson:"external_id"`
 	Credential         string        `bson:"credential"`
+	PasswordHistory    []string      `bson:"password_history,omitempty"`
 	Email              string        `bson:"email"`
 	Username           string        `bson:"username"`
 	Name               string        `bson:"name"`
@@ -27,14 +29,18 @@ func (m model) Convert() *entity.UserIdentity {
 	return &entity.UserIdentity{
 		ID:                 entity.UserIdentityID(m.ID.Hex()),
 		UserID:             entity.UserID(m.UserID.Hex()),
+		AppID:              entity.AppID(m.AppID.Hex()),
 		IdentityProviderID: entity.IdentityProviderID(m.IdentityProviderID.Hex()),
 		ExternalID:         m.ExternalID,
 		Credential:         m.Credential,
+		PasswordHistory:    m.PasswordHistory,
 		Email:              m.Email,
 		Username:           m.Username,
 		Name:               m.Name,
 		Picture:            m.Picture,
 		Friends:            m.Friends,
+		CreatedAt:          m.CreatedAt,
+		UpdatedAt:          m.UpdatedAt,
 	}
 }
 
@@ -48,16 +54,24 @@ func newModel(i *entity.UserIdentity) (*model, error) {
 	if i.IdentityProviderID == "" {
 		return nil, errors.New("UserIdentity.IdentityProviderID is empty")
 	}
+	var appID bson.ObjectId
+	if i.AppID != "" {
+		appID = bson.ObjectIdHex(string(i.AppID))
+	}
 	return &model{
 		ID:                 bson.ObjectIdHex(string(i.ID)),
 		UserID:             bson.ObjectIdHex(string(i.UserID)),
+		AppID:              appID,
 		IdentityProviderID: bson.ObjectIdHex(string(i.IdentityProviderID)),
 		ExternalID:         i.ExternalID,
 		Credential:         i.Credential,
+		PasswordHistory:    i.PasswordHistory,
 		Email:              i.Email,
 		Username:           i.Username,
 		Name:               i.Name,
 		Picture:            i.Picture,
 		Friends:            i.Friends,
+		CreatedAt:          i.CreatedAt,
+		UpdatedAt:          i.UpdatedAt,
 	}, nil
 }
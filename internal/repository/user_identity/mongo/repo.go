@@ -66,6 +66,33 @@ func (r UserIdentityRepository) FindByProviderAndUser(ctx context.Context, idPro
 	return ui.Convert(), nil
 }
 
+func (r UserIdentityRepository) FindByProviderAndExternalID(ctx context.Context, idProviderID entity.IdentityProviderID, externalID string) (*entity.UserIdentity, error) {
+	ui := &model{}
+	if err := r.col.Find(bson.M{
+		"identity_provider_id": bson.ObjectIdHex(string(idProviderID)),
+		"external_id":          externalID,
+	}).One(ui); err != nil {
+		if err == mgo.ErrNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return ui.Convert(), nil
+}
+
+func (r UserIdentityRepository) Create(ctx context.Context, i *entity.UserIdentity) error {
+	if i.ID == "" {
+		i.ID = entity.UserIdentityID(bson.NewObjectId().Hex())
+	}
+	model, err := newModel(i)
+	if err != nil {
+		return err
+	}
+
+	return r.col.Insert(model)
+}
+
 func (r UserIdentityRepository) Update(ctx context.Context, i *entity.UserIdentity) error {
 	model, err := newModel(i)
 	if err != nil {
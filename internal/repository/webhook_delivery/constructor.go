@@ -0,0 +1,30 @@
+package webhook_delivery
+
+import (
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/repository"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/env"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/repository/webhook_delivery/memory"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/repository/webhook_delivery/mongo"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/repository/webhook_delivery/redis"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/config"
+	"github.com/pkg/errors"
+)
+
+const (
+	BackendMongo  = "mongo"
+	BackendRedis  = "redis"
+	BackendMemory = "memory"
+)
+
+func New(env *env.Env, cfg *config.Webhooks) (repository.WebhookDeliveryRepository, error) {
+	switch cfg.Backend {
+	case BackendMongo:
+		return mongo.New(env.Store.Mongo), nil
+	case BackendRedis:
+		return redis.New(env.Store.Redis), nil
+	case BackendMemory:
+		return memory.New(), nil
+	}
+
+	return nil, errors.Errorf("unknown webhook delivery backend %q", cfg.Backend)
+}
@@ -0,0 +1,143 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/repository"
+	"github.com/globalsign/mgo/bson"
+	"github.com/pkg/errors"
+)
+
+var errNotFound = errors.New("webhook delivery not found")
+
+// WebhookDeliveryRepository keeps the deliveries in the process memory, it's intended for tests
+// and single instance development setups.
+type WebhookDeliveryRepository struct {
+	mx         sync.Mutex
+	deliveries map[entity.WebhookDeliveryID]entity.WebhookDelivery
+}
+
+func New() *WebhookDeliveryRepository {
+	return &WebhookDeliveryRepository{
+		deliveries: map[entity.WebhookDeliveryID]entity.WebhookDelivery{},
+	}
+}
+
+func (r *WebhookDeliveryRepository) Create(ctx context.Context, delivery *entity.WebhookDelivery) error {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	if delivery.ID == "" {
+		delivery.ID = entity.WebhookDeliveryID(bson.NewObjectId().Hex())
+	}
+	r.deliveries[delivery.ID] = *delivery
+
+	return nil
+}
+
+func (r *WebhookDeliveryRepository) Update(ctx context.Context, delivery *entity.WebhookDelivery) error {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	if _, ok := r.deliveries[delivery.ID]; !ok {
+		return errNotFound
+	}
+	r.deliveries[delivery.ID] = *delivery
+
+	return nil
+}
+
+func (r *WebhookDeliveryRepository) Find(ctx context.Context, query repository.WebhookDeliveryQuery) ([]*entity.WebhookDelivery, int, error) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	var result []*entity.WebhookDelivery
+	for _, d := range r.deliveries {
+		if (query.AppIDs == nil || hasApp(query.AppIDs, d.AppID)) && (query.Status == "" || d.Status == query.Status) {
+			d := d
+			result = append(result, &d)
+		}
+	}
+
+	ascending := query.Sort == "created_at"
+	sort.Slice(result, func(i, j int) bool {
+		if ascending {
+			return result[i].CreatedAt.Before(result[j].CreatedAt)
+		}
+		return result[i].CreatedAt.After(result[j].CreatedAt)
+	})
+
+	total := len(result)
+	if query.Offset >= total {
+		return nil, total, nil
+	}
+	result = result[query.Offset:]
+	if query.Limit > 0 && len(result) > query.Limit {
+		result = result[:query.Limit]
+	}
+
+	return result, total, nil
+}
+
+func hasApp(ids []entity.AppID, id entity.AppID) bool {
+	for i := range ids {
+		if ids[i] == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *WebhookDeliveryRepository) FindByID(ctx context.Context, id entity.WebhookDeliveryID) (*entity.WebhookDelivery, error) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	d, ok := r.deliveries[id]
+	if !ok {
+		return nil, nil
+	}
+
+	return &d, nil
+}
+
+func (r *WebhookDeliveryRepository) Claim(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*entity.WebhookDelivery, error) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	var due []entity.WebhookDelivery
+	for _, d := range r.deliveries {
+		if d.Status == entity.WebhookDeliveryPending && !d.NextAttemptAt.After(now) {
+			due = append(due, d)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].NextAttemptAt.Before(due[j].NextAttemptAt) })
+
+	var result []*entity.WebhookDelivery
+	for i := 0; i < len(due) && i < limit; i++ {
+		d := due[i]
+		d.NextAttemptAt = now.Add(lease)
+		r.deliveries[d.ID] = d
+		result = append(result, &d)
+	}
+
+	return result, nil
+}
+
+func (r *WebhookDeliveryRepository) Purge(ctx context.Context, status entity.WebhookDeliveryStatus, before time.Time) (int, error) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	n := 0
+	for id, d := range r.deliveries {
+		if status != entity.WebhookDeliveryPending && d.Status == status && d.UpdatedAt.Before(before) {
+			delete(r.deliveries, id)
+			n++
+		}
+	}
+
+	return n, nil
+}
@@ -0,0 +1,60 @@
+package mongo
+
+import (
+	"time"
+
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
+	"github.com/globalsign/mgo/bson"
+)
+
+type model struct {
+	ID             bson.ObjectId `bson:"_id"`
+	AppID          bson.ObjectId `bson:"app_id"`
+	URL            string        `bson:"url"`
+	Event          string        `bson:"event"`
+	Payload        []byte        `bson:"payload"`
+	Status         string        `bson:"status"`
+	Attempts       int           `bson:"attempts"`
+	NextAttemptAt  time.Time     `bson:"next_attempt_at"`
+	LastAttemptAt  time.Time     `bson:"last_attempt_at"`
+	LastStatusCode int           `bson:"last_status_code"`
+	LastError      string        `bson:"last_error"`
+	CreatedAt      time.Time     `bson:"created_at"`
+	UpdatedAt      time.Time     `bson:"updated_at"`
+}
+
+func newModel(d *entity.WebhookDelivery) *model {
+	return &model{
+		ID:             bson.ObjectIdHex(string(d.ID)),
+		AppID:          bson.ObjectIdHex(string(d.AppID)),
+		URL:            d.URL,
+		Event:          d.Event,
+		Payload:        d.Payload,
+		Status:         string(d.Status),
+		Attempts:       d.Attempts,
+		NextAttemptAt:  d.NextAttemptAt,
+		LastAttemptAt:  d.LastAttemptAt,
+		LastStatusCode: d.LastStatusCode,
+		LastError:      d.LastError,
+		CreatedAt:      d.CreatedAt,
+		UpdatedAt:      d.UpdatedAt,
+	}
+}
+
+func (m model) Convert() *entity.WebhookDelivery {
+	return &entity.WebhookDelivery{
+		ID:             entity.WebhookDeliveryID(m.ID.Hex()),
+		AppID:          entity.AppID(m.AppID.Hex()),
+		URL:            m.URL,
+		Event:          m.Event,
+		Payload:        m.Payload,
+		Status:         entity.WebhookDeliveryStatus(m.Status),
+		Attempts:       m.Attempts,
+		NextAttemptAt:  m.NextAttemptAt,
+		LastAttemptAt:  m.LastAttemptAt,
+		LastStatusCode: m.LastStatusCode,
+		LastError:      m.LastError,
+		CreatedAt:      m.CreatedAt,
+		UpdatedAt:      m.UpdatedAt,
+	}
+}
@@ -0,0 +1,143 @@
+package mongo
+
+import (
+	"context"
+	"time"
+
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/repository"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/env"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/database"
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+)
+
+type WebhookDeliveryRepository struct {
+	col *mgo.Collection
+}
+
+func New(env *env.Mongo) *WebhookDeliveryRepository {
+	return &WebhookDeliveryRepository{
+		col: env.DB.C(database.TableWebhookDelivery),
+	}
+}
+
+func (r *WebhookDeliveryRepository) Create(ctx context.Context, delivery *entity.WebhookDelivery) error {
+	if delivery.ID == "" {
+		delivery.ID = entity.WebhookDeliveryID(bson.NewObjectId().Hex())
+	}
+
+	m := newModel(delivery)
+	if err := r.col.Insert(m); err != nil {
+		return err
+	}
+
+	*delivery = *m.Convert()
+	return nil
+}
+
+func (r *WebhookDeliveryRepository) Update(ctx context.Context, delivery *entity.WebhookDelivery) error {
+	m := newModel(delivery)
+	if err := r.col.UpdateId(m.ID, m); err != nil {
+		return err
+	}
+
+	*delivery = *m.Convert()
+	return nil
+}
+
+var sortFields = map[string]string{
+	"created_at": "created_at",
+}
+
+func (r *WebhookDeliveryRepository) Find(ctx context.Context, query repository.WebhookDeliveryQuery) ([]*entity.WebhookDelivery, int, error) {
+	filter := bson.M{}
+	if query.AppIDs != nil {
+		ids := make([]bson.ObjectId, 0, len(query.AppIDs))
+		for _, id := range query.AppIDs {
+			if bson.IsObjectIdHex(string(id)) {
+				ids = append(ids, bson.ObjectIdHex(string(id)))
+			}
+		}
+		filter["app_id"] = bson.M{"$in": ids}
+	}
+	if query.Status != "" {
+		filter["status"] = string(query.Status)
+	}
+
+	q := r.col.Find(filter)
+	total, err := q.Count()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	sort := query.Sort
+	if sort == "" {
+		sort = "-created_at"
+	}
+
+	var m []model
+	if err := q.Sort(database.Sort(sort, sortFields)...).Skip(query.Offset).Limit(query.Limit).All(&m); err != nil {
+		return nil, 0, err
+	}
+
+	var result []*entity.WebhookDelivery
+	for i := range m {
+		result = append(result, m[i].Convert())
+	}
+
+	return result, total, nil
+}
+
+func (r *WebhookDeliveryRepository) FindByID(ctx context.Context, id entity.WebhookDeliveryID) (*entity.WebhookDelivery, error) {
+	if !bson.IsObjectIdHex(string(id)) {
+		return nil, nil
+	}
+
+	m := &model{}
+	if err := r.col.FindId(bson.ObjectIdHex(string(id))).One(m); err != nil {
+		if err == mgo.ErrNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return m.Convert(), nil
+}
+
+func (r *WebhookDeliveryRepository) Claim(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*entity.WebhookDelivery, error) {
+	var result []*entity.WebhookDelivery
+	for len(result) < limit {
+		// INFO: findAndModify is atomic, so the delivery is claimed by only one of the concurrent dispatchers
+		m := &model{}
+		_, err := r.col.Find(bson.M{"status": string(entity.WebhookDeliveryPending), "next_attempt_at": bson.M{"$lte": now}}).
+			Sort("next_attempt_at").
+			Apply(mgo.Change{Update: bson.M{"$set": bson.M{"next_attempt_at": now.Add(lease)}}, ReturnNew: true}, m)
+		if err == mgo.ErrNotFound {
+			break
+		}
+		if err != nil {
+			return result, err
+		}
+
+		result = append(result, m.Convert())
+	}
+
+	return result, nil
+}
+
+func (r *WebhookDeliveryRepository) Purge(ctx context.Context, status entity.WebhookDeliveryStatus, before time.Time) (int, error) {
+	if status == entity.WebhookDeliveryPending {
+		return 0, nil
+	}
+
+	info, err := r.col.RemoveAll(bson.M{
+		"status":     string(status),
+		"updated_at": bson.M{"$lt": before},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return info.Removed, nil
+}
@@ -0,0 +1,349 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/repository"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/env"
+	"github.com/globalsign/mgo/bson"
+	"github.com/go-redis/redis"
+	"github.com/pkg/errors"
+)
+
+const (
+	deliveryKeyPattern = "webhook_delivery_%s"
+	// scheduleKey is the sorted set of the pending deliveries scored by the next attempt time.
+	scheduleKey = "webhook_delivery_schedule"
+	// createdKey is the sorted set of all deliveries scored by the creation time.
+	createdKey = "webhook_delivery_created"
+	// appKeyPattern and statusKeyPattern are the sorted sets of the deliveries of the application and in the status
+	// scored by the creation time, so the search doesn't scan all deliveries.
+	appKeyPattern    = "webhook_delivery_app_%s"
+	statusKeyPattern = "webhook_delivery_status_%s"
+	// finishedKey is the sorted set of the delivered and dead deliveries scored by the last update time.
+	finishedKey = "webhook_delivery_finished"
+
+	findPageSize   = 100
+	purgeBatchSize = 100
+)
+
+var statuses = []entity.WebhookDeliveryStatus{
+	entity.WebhookDeliveryPending,
+	entity.WebhookDeliveryDelivered,
+	entity.WebhookDeliveryDead,
+}
+
+// claimScript atomically picks the due deliveries and moves them to the end of the lease.
+var claimScript = redis.NewScript(`
+local ids = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, ARGV[3])
+for _, id in ipairs(ids) do
+	redis.call('ZADD', KEYS[1], ARGV[2], id)
+end
+return ids
+`)
+
+type model struct {
+	ID             string    `json:"id"`
+	AppID          string    `json:"app_id"`
+	URL            string    `json:"url"`
+	Event          string    `json:"event"`
+	Payload        []byte    `json:"payload"`
+	Status         string    `json:"status"`
+	Attempts       int       `json:"attempts"`
+	NextAttemptAt  time.Time `json:"next_attempt_at"`
+	LastAttemptAt  time.Time `json:"last_attempt_at"`
+	LastStatusCode int       `json:"last_status_code"`
+	LastError      string    `json:"last_error"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+type WebhookDeliveryRepository struct {
+	client *redis.Client
+}
+
+func New(env *env.Redis) *WebhookDeliveryRepository {
+	return &WebhookDeliveryRepository{
+		client: env.Client,
+	}
+}
+
+func (r *WebhookDeliveryRepository) Create(ctx context.Context, delivery *entity.WebhookDelivery) error {
+	if delivery.ID == "" {
+		delivery.ID = entity.WebhookDeliveryID(bson.NewObjectId().Hex())
+	}
+
+	return r.save(delivery, true)
+}
+
+func (r *WebhookDeliveryRepository) Update(ctx context.Context, delivery *entity.WebhookDelivery) error {
+	return r.save(delivery, false)
+}
+
+func (r *WebhookDeliveryRepository) Find(ctx context.Context, query repository.WebhookDeliveryQuery) ([]*entity.WebhookDelivery, int, error) {
+	if query.AppIDs != nil && len(query.AppIDs) == 0 {
+		return nil, 0, nil
+	}
+
+	// INFO: The index matching the whole query is paged by itself, the others are scanned to filter and count the deliveries
+	key, filtered := createdKey, query.AppIDs != nil || query.Status != ""
+	switch {
+	case len(query.AppIDs) == 1:
+		key, filtered = fmt.Sprintf(appKeyPattern, query.AppIDs[0]), query.Status != ""
+	case query.Status != "":
+		key, filtered = fmt.Sprintf(statusKeyPattern, query.Status), query.AppIDs != nil
+	}
+	ascending := query.Sort == "created_at"
+
+	if !filtered {
+		total, err := r.client.ZCard(key).Result()
+		if err != nil {
+			return nil, 0, err
+		}
+		stop := int64(-1)
+		if query.Limit > 0 {
+			stop = int64(query.Offset + query.Limit - 1)
+		}
+		ids, err := r.ids(key, ascending, int64(query.Offset), stop)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		var result []*entity.WebhookDelivery
+		for _, id := range ids {
+			d, err := r.FindByID(ctx, entity.WebhookDeliveryID(id))
+			if err != nil {
+				return nil, 0, err
+			}
+			if d != nil {
+				result = append(result, d)
+			}
+		}
+
+		return result, int(total), nil
+	}
+
+	apps := make(map[entity.AppID]bool, len(query.AppIDs))
+	for _, id := range query.AppIDs {
+		apps[id] = true
+	}
+
+	var result []*entity.WebhookDelivery
+	total := 0
+	for start := int64(0); ; start += findPageSize {
+		ids, err := r.ids(key, ascending, start, start+findPageSize-1)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		for _, id := range ids {
+			d, err := r.FindByID(ctx, entity.WebhookDeliveryID(id))
+			if err != nil {
+				return nil, 0, err
+			}
+			if d == nil || (query.AppIDs != nil && !apps[d.AppID]) || (query.Status != "" && d.Status != query.Status) {
+				continue
+			}
+
+			if total >= query.Offset && (query.Limit == 0 || len(result) < query.Limit) {
+				result = append(result, d)
+			}
+			total++
+		}
+
+		if len(ids) < findPageSize {
+			return result, total, nil
+		}
+	}
+}
+
+// ids returns the range of the ids of the sorted set, the newest first unless it's ascending.
+func (r *WebhookDeliveryRepository) ids(key string, ascending bool, start, stop int64) ([]string, error) {
+	if ascending {
+		return r.client.ZRange(key, start, stop).Result()
+	}
+	return r.client.ZRevRange(key, start, stop).Result()
+}
+
+func (r *WebhookDeliveryRepository) FindByID(ctx context.Context, id entity.WebhookDeliveryID) (*entity.WebhookDelivery, error) {
+	data, err := r.client.Get(fmt.Sprintf(deliveryKeyPattern, id)).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	m := &model{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, errors.Wrap(err, "unable to decode webhook delivery")
+	}
+
+	return m.convert(), nil
+}
+
+func (r *WebhookDeliveryRepository) Claim(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*entity.WebhookDelivery, error) {
+	res, err := claimScript.Run(r.client, []string{scheduleKey}, score(now), score(now.Add(lease)), limit).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	ids, _ := res.([]interface{})
+	var result []*entity.WebhookDelivery
+	for _, id := range ids {
+		s, _ := id.(string)
+		d, err := r.FindByID(ctx, entity.WebhookDeliveryID(s))
+		if err != nil {
+			return result, err
+		}
+		if d == nil {
+			r.client.ZRem(scheduleKey, s)
+			continue
+		}
+
+		d.NextAttemptAt = now.Add(lease)
+		result = append(result, d)
+	}
+
+	return result, nil
+}
+
+func (r *WebhookDeliveryRepository) Purge(ctx context.Context, status entity.WebhookDeliveryStatus, before time.Time) (int, error) {
+	if status == entity.WebhookDeliveryPending {
+		return 0, nil
+	}
+
+	// INFO: The finished deliveries of the other status are kept in the range, so they're skipped by the offset
+	n, skipped := 0, int64(0)
+	for {
+		ids, err := r.client.ZRangeByScore(finishedKey, redis.ZRangeBy{
+			Min:    "-inf",
+			Max:    fmt.Sprintf("(%d", int64(score(before))),
+			Offset: skipped,
+			Count:  purgeBatchSize,
+		}).Result()
+		if err != nil {
+			return n, err
+		}
+		if len(ids) == 0 {
+			return n, nil
+		}
+
+		for _, id := range ids {
+			d, err := r.FindByID(ctx, entity.WebhookDeliveryID(id))
+			if err != nil {
+				return n, err
+			}
+			// INFO: The delivery retried after it has been read by the range stays, only its stale score is removed
+			if d != nil && (d.Status == entity.WebhookDeliveryPending || !d.UpdatedAt.Before(before)) {
+				r.client.ZRem(finishedKey, id)
+				continue
+			}
+			if d != nil && d.Status != status {
+				skipped++
+				continue
+			}
+
+			if err := r.remove(id, d); err != nil {
+				return n, err
+			}
+			n++
+		}
+	}
+}
+
+// remove removes the delivery and its indexes, the delivery is nil if only the indexes are left.
+func (r *WebhookDeliveryRepository) remove(id string, d *entity.WebhookDelivery) error {
+	_, err := r.client.TxPipelined(func(pipe redis.Pipeliner) error {
+		pipe.Del(fmt.Sprintf(deliveryKeyPattern, id))
+		pipe.ZRem(createdKey, id)
+		pipe.ZRem(scheduleKey, id)
+		pipe.ZRem(finishedKey, id)
+		for _, s := range statuses {
+			pipe.ZRem(fmt.Sprintf(statusKeyPattern, s), id)
+		}
+		if d != nil {
+			pipe.ZRem(fmt.Sprintf(appKeyPattern, d.AppID), id)
+		}
+		return nil
+	})
+
+	return err
+}
+
+func (r *WebhookDeliveryRepository) save(d *entity.WebhookDelivery, create bool) error {
+	data, err := json.Marshal(newModel(d))
+	if err != nil {
+		return err
+	}
+
+	id := string(d.ID)
+	created := redis.Z{Score: score(d.CreatedAt), Member: id}
+	_, err = r.client.TxPipelined(func(pipe redis.Pipeliner) error {
+		pipe.Set(fmt.Sprintf(deliveryKeyPattern, d.ID), data, 0)
+		if create {
+			pipe.ZAdd(createdKey, created)
+			pipe.ZAdd(fmt.Sprintf(appKeyPattern, d.AppID), created)
+		}
+		for _, s := range statuses {
+			if s != d.Status {
+				pipe.ZRem(fmt.Sprintf(statusKeyPattern, s), id)
+			}
+		}
+		pipe.ZAdd(fmt.Sprintf(statusKeyPattern, d.Status), created)
+		if d.Status == entity.WebhookDeliveryPending {
+			pipe.ZAdd(scheduleKey, redis.Z{Score: score(d.NextAttemptAt), Member: id})
+			pipe.ZRem(finishedKey, id)
+		} else {
+			pipe.ZRem(scheduleKey, id)
+			pipe.ZAdd(finishedKey, redis.Z{Score: score(d.UpdatedAt), Member: id})
+		}
+		return nil
+	})
+
+	return err
+}
+
+func score(t time.Time) float64 {
+	return float64(t.UnixNano() / int64(time.Millisecond))
+}
+
+func newModel(d *entity.WebhookDelivery) *model {
+	return &model{
+		ID:             string(d.ID),
+		AppID:          string(d.AppID),
+		URL:            d.URL,
+		Event:          d.Event,
+		Payload:        d.Payload,
+		Status:         string(d.Status),
+		Attempts:       d.Attempts,
+		NextAttemptAt:  d.NextAttemptAt,
+		LastAttemptAt:  d.LastAttemptAt,
+		LastStatusCode: d.LastStatusCode,
+		LastError:      d.LastError,
+		CreatedAt:      d.CreatedAt,
+		UpdatedAt:      d.UpdatedAt,
+	}
+}
+
+func (m *model) convert() *entity.WebhookDelivery {
+	return &entity.WebhookDelivery{
+		ID:             entity.WebhookDeliveryID(m.ID),
+		AppID:          entity.AppID(m.AppID),
+		URL:            m.URL,
+		Event:          m.Event,
+		Payload:        m.Payload,
+		Status:         entity.WebhookDeliveryStatus(m.Status),
+		Attempts:       m.Attempts,
+		NextAttemptAt:  m.NextAttemptAt,
+		LastAttemptAt:  m.LastAttemptAt,
+		LastStatusCode: m.LastStatusCode,
+		LastError:      m.LastError,
+		CreatedAt:      m.CreatedAt,
+		UpdatedAt:      m.UpdatedAt,
+	}
+}
@@ -0,0 +1,37 @@
+package audit
+
+import (
+	"context"
+	"time"
+
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/service"
+)
+
+type Service struct {
+	ServiceParams
+}
+
+func (s *Service) Record(ctx context.Context, entry service.AuditEntry) error {
+	changes, err := diff(entry.Before, entry.After)
+	if err != nil {
+		return err
+	}
+
+	record := &entity.AuditRecord{
+		Action:     entry.Action,
+		TargetType: entry.TargetType,
+		TargetID:   entry.TargetID,
+		SpaceID:    entry.SpaceID,
+		Changes:    changes,
+		IP:         entry.IP,
+		RequestID:  entry.RequestID,
+		CreatedAt:  time.Now(),
+	}
+	if entry.Actor != nil {
+		record.ActorID = entry.Actor.ID
+		record.ActorLogin = entry.Actor.Login
+	}
+
+	return s.Records.Create(ctx, record)
+}
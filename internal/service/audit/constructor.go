@@ -0,0 +1,19 @@
+package audit
+
+import (
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/repository"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/service"
+	"go.uber.org/fx"
+)
+
+type ServiceParams struct {
+	fx.In
+
+	Records repository.AuditRepository
+}
+
+func New(params ServiceParams) service.AuditService {
+	return &Service{
+		params,
+	}
+}
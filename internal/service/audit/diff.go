@@ -0,0 +1,128 @@
+package audit
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
+)
+
+const masked = "***"
+
+// secretFields are the substrings of the field names whose values never get to the audit trail.
+var secretFields = []string{"secret", "password", "private_key"}
+
+// diff returns the changed fields of the JSON representations of the states. The nested objects
+// are compared field by field, the arrays are compared as a whole.
+func diff(before, after interface{}) ([]entity.AuditChange, error) {
+	b, err := flatten(before)
+	if err != nil {
+		return nil, err
+	}
+	a, err := flatten(after)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make([]string, 0, len(a))
+	for field := range a {
+		fields = append(fields, field)
+	}
+	for field := range b {
+		if _, ok := a[field]; !ok {
+			fields = append(fields, field)
+		}
+	}
+	sort.Strings(fields)
+
+	changes := []entity.AuditChange{}
+	for _, field := range fields {
+		if reflect.DeepEqual(b[field], a[field]) {
+			continue
+		}
+		changes = append(changes, entity.AuditChange{
+			Field:  field,
+			Before: mask(field, b[field]),
+			After:  mask(field, a[field]),
+		})
+	}
+
+	return changes, nil
+}
+
+func flatten(v interface{}) (map[string]interface{}, error) {
+	result := map[string]interface{}{}
+	if v == nil || (reflect.ValueOf(v).Kind() == reflect.Ptr && reflect.ValueOf(v).IsNil()) {
+		return result, nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var decoded interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil, err
+	}
+
+	walk("", decoded, result)
+	return result, nil
+}
+
+func walk(prefix string, v interface{}, result map[string]interface{}) {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		result[prefix] = v
+		return
+	}
+
+	for k, value := range m {
+		field := k
+		if prefix != "" {
+			field = prefix + "." + k
+		}
+		walk(field, value, result)
+	}
+}
+
+// mask hides the non-empty value of the secret field, so the record still shows the secret has been changed.
+// The arrays are kept as a whole, so the secret fields of their objects are hidden the same way.
+func mask(field string, v interface{}) interface{} {
+	if v == nil || v == "" {
+		return v
+	}
+
+	if isSecret(field[strings.LastIndex(field, ".")+1:]) {
+		return masked
+	}
+
+	switch value := v.(type) {
+	case []interface{}:
+		result := make([]interface{}, len(value))
+		for i, item := range value {
+			result[i] = mask("", item)
+		}
+		return result
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(value))
+		for k, item := range value {
+			result[k] = mask(k, item)
+		}
+		return result
+	}
+
+	return v
+}
+
+func isSecret(name string) bool {
+	name = strings.ToLower(name)
+	for _, s := range secretFields {
+		if strings.Contains(name, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,74 @@
+package audit
+
+import (
+	"testing"
+
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+type settings struct {
+	Min int `json:"min"`
+	Max int `json:"max"`
+}
+
+type provider struct {
+	Name         string `json:"name"`
+	ClientSecret string `json:"client_secret"`
+}
+
+type target struct {
+	Name         string     `json:"name"`
+	ClientSecret string     `json:"client_secret"`
+	Roles        []string   `json:"roles"`
+	Settings     settings   `json:"settings"`
+	Providers    []provider `json:"providers"`
+}
+
+func TestDiffReturnsChangedNestedFields(t *testing.T) {
+	before := &target{Name: "app", Roles: []string{"user"}, Settings: settings{Min: 4, Max: 10}}
+	after := &target{Name: "app", Roles: []string{"user", "admin"}, Settings: settings{Min: 8, Max: 10}}
+
+	changes, err := diff(before, after)
+	assert.Nil(t, err)
+	assert.Equal(t, []entity.AuditChange{
+		{Field: "roles", Before: []interface{}{"user"}, After: []interface{}{"user", "admin"}},
+		{Field: "settings.min", Before: float64(4), After: float64(8)},
+	}, changes)
+}
+
+func TestDiffMasksSecrets(t *testing.T) {
+	changes, err := diff(&target{ClientSecret: "old"}, &target{ClientSecret: "new"})
+	assert.Nil(t, err)
+	assert.Equal(t, []entity.AuditChange{{Field: "client_secret", Before: masked, After: masked}}, changes)
+}
+
+func TestDiffMasksSecretsOfArrayItems(t *testing.T) {
+	before := &target{Providers: []provider{{Name: "google", ClientSecret: "old"}}}
+	after := &target{Providers: []provider{{Name: "google", ClientSecret: "new"}, {Name: "github"}}}
+
+	changes, err := diff(before, after)
+	assert.Nil(t, err)
+	assert.Equal(t, []entity.AuditChange{{
+		Field: "providers",
+		Before: []interface{}{
+			map[string]interface{}{"name": "google", "client_secret": masked},
+		},
+		After: []interface{}{
+			map[string]interface{}{"name": "google", "client_secret": masked},
+			map[string]interface{}{"name": "github", "client_secret": ""},
+		},
+	}}, changes)
+}
+
+func TestDiffOfCreationAndRemoval(t *testing.T) {
+	var none *target
+
+	changes, err := diff(none, &target{Name: "app"})
+	assert.Nil(t, err)
+	assert.Contains(t, changes, entity.AuditChange{Field: "name", Before: nil, After: "app"})
+
+	changes, err = diff(&target{Name: "app"}, nil)
+	assert.Nil(t, err)
+	assert.Contains(t, changes, entity.AuditChange{Field: "name", Before: "app", After: nil})
+}
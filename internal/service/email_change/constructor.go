@@ -0,0 +1,31 @@
+package email_change
+
+import (
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/repository"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/service"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/config"
+	appservice "github.com/ProtocolONE/auth1.protocol.one/pkg/service"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/webhooks"
+	"go.uber.org/fx"
+)
+
+type ServiceParams struct {
+	fx.In
+
+	Users           repository.UserRepository
+	UserIdentities  repository.UserIdentityRepository
+	Spaces          repository.SpaceRepository
+	Apps            repository.ApplicationRepository
+	UserService     service.UserService
+	PasswordManager service.PasswordManager
+	Tokens          appservice.OneTimeTokenServiceInterface
+	Mailer          appservice.MailerInterface
+	Templates       *config.MailTemplates
+	WebHooks        *webhooks.WebHooks
+}
+
+func New(params ServiceParams) service.EmailChangeService {
+	return &Service{
+		params,
+	}
+}
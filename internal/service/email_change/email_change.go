@@ -0,0 +1,225 @@
+package email_change
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/mail"
+	"text/template"
+
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/repository"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/service"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/service/user"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/appcore/log"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/models"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/webhooks"
+	"go.uber.org/zap"
+)
+
+// revertTokenTTL is the lifetime of the revert link in seconds, the owner of the previous address may not read
+// the mail at once.
+const revertTokenTTL = 7 * 24 * 60 * 60
+
+type Service struct {
+	ServiceParams
+}
+
+var ErrInvalidEmail = errors.New("invalid email address")
+var ErrInvalidToken = errors.New("invalid or expired token")
+
+// confirmToken is stored with the token of the confirmation link.
+type confirmToken struct {
+	UserID   string
+	OldEmail string
+	NewEmail string
+}
+
+// revertToken is stored with the token of the revert link.
+type revertToken struct {
+	UserID       string
+	OldEmail     string
+	NewEmail     string
+	ConfirmToken string
+}
+
+func (s *Service) Start(ctx context.Context, data service.StartEmailChangeData) error {
+	if _, err := mail.ParseAddress(data.Email); err != nil {
+		return ErrInvalidEmail
+	}
+
+	usr, err := s.UserService.GetByID(ctx, data.UserID)
+	if err != nil {
+		return err
+	}
+	if usr.Email == data.Email {
+		return nil
+	}
+
+	if err := s.PasswordManager.CheckPassword(ctx, usr.ID, data.Password); err != nil {
+		return err
+	}
+
+	space, err := s.Spaces.FindByID(ctx, usr.SpaceID)
+	if err != nil {
+		return err
+	}
+
+	other, err := s.UserIdentities.FindByProviderAndExternalID(ctx, space.DefaultIDProvider().ID, data.Email)
+	if err != nil {
+		return err
+	}
+	if other != nil {
+		return user.ErrEmailRegistered
+	}
+
+	confirm, err := s.Tokens.Create(&confirmToken{
+		UserID:   string(usr.ID),
+		OldEmail: usr.Email,
+		NewEmail: data.Email,
+	}, &models.OneTimeTokenSettings{
+		Length: space.PasswordSettings.TokenLength,
+		TTL:    space.PasswordSettings.TokenTTL,
+	})
+	if err != nil {
+		return err
+	}
+	revert, err := s.Tokens.Create(&revertToken{
+		UserID:       string(usr.ID),
+		OldEmail:     usr.Email,
+		NewEmail:     data.Email,
+		ConfirmToken: confirm.Token,
+	}, &models.OneTimeTokenSettings{
+		Length: space.PasswordSettings.TokenLength,
+		TTL:    revertTokenTTL,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = s.send(data.Email, "Confirm email address", s.Templates.ChangeEmailTpl, usr, data.Email, map[string]string{
+		"ConfirmLink": fmt.Sprintf("%s/change-email/confirm?token=%s", s.Templates.PlatformUrl, confirm.Token),
+	})
+	if err != nil {
+		return err
+	}
+
+	if usr.Email == "" {
+		return nil
+	}
+	return s.send(usr.Email, "Email address change", s.Templates.ChangeEmailNoticeTpl, usr, data.Email, map[string]string{
+		"RevertLink": fmt.Sprintf("%s/change-email/revert?token=%s", s.Templates.PlatformUrl, revert.Token),
+	})
+}
+
+func (s *Service) Confirm(ctx context.Context, token string) error {
+	ts := &confirmToken{}
+	if err := s.Tokens.Use(token, ts); err != nil {
+		return ErrInvalidToken
+	}
+
+	usr, err := s.Users.FindByID(ctx, entity.UserID(ts.UserID))
+	if err != nil {
+		return err
+	}
+	if usr == nil || usr.Email != ts.OldEmail {
+		// INFO: The address has been changed in another way after the link was sent
+		return ErrInvalidToken
+	}
+
+	if err := s.setEmail(ctx, usr, ts.NewEmail); err != nil {
+		return err
+	}
+	s.publish(ctx, usr, webhooks.EmailChangedEvent{Email: ts.NewEmail, PreviousEmail: ts.OldEmail})
+
+	return nil
+}
+
+func (s *Service) Revert(ctx context.Context, token string) error {
+	ts := &revertToken{}
+	if err := s.Tokens.Use(token, ts); err != nil {
+		return ErrInvalidToken
+	}
+
+	usr, err := s.Users.FindByID(ctx, entity.UserID(ts.UserID))
+	if err != nil {
+		return err
+	}
+	if usr == nil {
+		return ErrInvalidToken
+	}
+
+	switch usr.Email {
+	case ts.OldEmail:
+		// INFO: The change isn't confirmed yet, the confirmation link is dropped
+		if err := s.Tokens.Use(ts.ConfirmToken, &confirmToken{}); err != nil {
+			log.Info(ctx, "Confirmation token of the reverted email change is already used", zap.Error(err))
+		}
+		return nil
+	case ts.NewEmail:
+		if err := s.setEmail(ctx, usr, ts.OldEmail); err != nil {
+			return err
+		}
+		s.publish(ctx, usr, webhooks.EmailChangedEvent{Email: ts.OldEmail, PreviousEmail: ts.NewEmail})
+		return nil
+	}
+
+	return ErrInvalidToken
+}
+
+// setEmail changes the address of the user, it's verified as the link was sent to it.
+func (s *Service) setEmail(ctx context.Context, usr *entity.User, email string) error {
+	verified := true
+	if err := s.UserService.Update(ctx, service.UpdateUserData{ID: usr.ID, Email: &email, EmailVerified: &verified}); err != nil {
+		return err
+	}
+
+	usr.Email = email
+	usr.EmailVerified = verified
+	return nil
+}
+
+func (s *Service) send(to, subject, tpl string, usr *entity.User, email string, links map[string]string) error {
+	b, err := ioutil.ReadFile(tpl)
+	if err != nil {
+		return err
+	}
+	tmpl, err := template.New("mail").Parse(string(b))
+	if err != nil {
+		return err
+	}
+
+	params := map[string]string{
+		"UserName":         usr.Username,
+		"Email":            email,
+		"PlatformName":     s.Templates.PlatformName,
+		"SupportPortalUrl": s.Templates.SupportPortalUrl,
+	}
+	for k, v := range links {
+		params[k] = v
+	}
+
+	w := bytes.Buffer{}
+	if err := tmpl.Execute(&w, params); err != nil {
+		return err
+	}
+
+	return s.Mailer.Send(to, subject, w.String())
+}
+
+// publish sends the event to the webhooks of all applications of the user space.
+func (s *Service) publish(ctx context.Context, usr *entity.User, event webhooks.Event) {
+	apps, _, err := s.Apps.Find(ctx, repository.ApplicationQuery{SpaceIDs: []entity.SpaceID{usr.SpaceID}})
+	if err != nil {
+		log.Error(ctx, "Unable to publish webhook, error on getting apps", zap.Error(err))
+		return
+	}
+
+	for _, app := range apps {
+		if err := s.WebHooks.Publish(ctx, string(app.ID), string(usr.ID), app.WebHooks, event); err != nil {
+			log.Error(ctx, "Unable to publish webhook", zap.String("action", event.Action()), zap.Error(err))
+		}
+	}
+}
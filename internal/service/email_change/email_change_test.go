@@ -0,0 +1,288 @@
+package email_change
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/repository"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/service"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/repository/webhook_delivery/memory"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/service/password_manager"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/service/user"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/config"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/models"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/webhooks"
+	"github.com/globalsign/mgo"
+	"github.com/stretchr/testify/assert"
+)
+
+type users map[entity.UserID]entity.User
+
+func (r users) Create(ctx context.Context, u *entity.User) error { r[u.ID] = *u; return nil }
+func (r users) Update(ctx context.Context, u *entity.User) error { r[u.ID] = *u; return nil }
+func (r users) Find(ctx context.Context, query repository.UserQuery) ([]*entity.User, int, error) {
+	return nil, 0, nil
+}
+func (r users) FindByID(ctx context.Context, id entity.UserID) (*entity.User, error) {
+	if u, ok := r[id]; ok {
+		return &u, nil
+	}
+	return nil, nil
+}
+
+type identities map[entity.UserIdentityID]entity.UserIdentity
+
+func (r identities) Create(ctx context.Context, i *entity.UserIdentity) error {
+	r[i.ID] = *i
+	return nil
+}
+func (r identities) Update(ctx context.Context, i *entity.UserIdentity) error {
+	r[i.ID] = *i
+	return nil
+}
+func (r identities) FindByID(ctx context.Context, id entity.UserIdentityID) (*entity.UserIdentity, error) {
+	return nil, nil
+}
+func (r identities) FindByProviderAndUser(ctx context.Context, pid entity.IdentityProviderID, uid entity.UserID) (*entity.UserIdentity, error) {
+	return r.find(func(i entity.UserIdentity) bool { return i.IdentityProviderID == pid && i.UserID == uid })
+}
+func (r identities) FindByProviderAndExternalID(ctx context.Context, pid entity.IdentityProviderID, externalID string) (*entity.UserIdentity, error) {
+	return r.find(func(i entity.UserIdentity) bool { return i.IdentityProviderID == pid && i.ExternalID == externalID })
+}
+func (r identities) FindForUser(ctx context.Context, uid entity.UserID) ([]*entity.UserIdentity, error) {
+	return nil, nil
+}
+func (r identities) find(match func(i entity.UserIdentity) bool) (*entity.UserIdentity, error) {
+	for _, i := range r {
+		if match(i) {
+			return &i, nil
+		}
+	}
+	return nil, nil
+}
+
+type apps struct {
+	repository.ApplicationRepository
+}
+
+func (apps) Find(ctx context.Context, query repository.ApplicationQuery) ([]*entity.Application, int, error) {
+	return []*entity.Application{{ID: "app", WebHooks: []string{"http://localhost/hook"}}}, 1, nil
+}
+
+type passwords struct {
+	service.PasswordManager
+}
+
+func (passwords) CheckPassword(ctx context.Context, userId entity.UserID, password string) error {
+	if password != "password" {
+		return password_manager.ErrPasswordMismatch
+	}
+	return nil
+}
+
+// tokens keeps the one-time tokens named by their order.
+type tokens map[string][]byte
+
+func (t tokens) Create(obj interface{}, settings *models.OneTimeTokenSettings) (*models.OneTimeToken, error) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	token := fmt.Sprintf("token%d", len(t)+1)
+	t[token] = data
+	return &models.OneTimeToken{Token: token}, nil
+}
+func (t tokens) Get(token string, obj interface{}) error {
+	data, ok := t[token]
+	if !ok {
+		return errors.New("token not found")
+	}
+	return json.Unmarshal(data, obj)
+}
+func (t tokens) Use(token string, obj interface{}) error {
+	if err := t.Get(token, obj); err != nil {
+		return err
+	}
+	delete(t, token)
+	return nil
+}
+func (t tokens) Attempt(token string) (int, error) { return 0, nil }
+
+// mailer keeps the recipients of the sent mails.
+type mailer struct {
+	to []string
+}
+
+func (m *mailer) Send(to, subject, body string) error {
+	m.to = append(m.to, to)
+	return nil
+}
+
+type emailChangeTest struct {
+	users      users
+	identities identities
+	tokens     tokens
+	mailer     *mailer
+	deliveries *memory.WebhookDeliveryRepository
+	s          service.EmailChangeService
+}
+
+func newEmailChangeTest() *emailChangeTest {
+	space := &entity.Space{
+		ID:               "space",
+		PasswordSettings: entity.PasswordSettings{TokenLength: 16, TokenTTL: 60},
+		IdentityProviders: entity.IdentityProviders{{
+			ID:   "provider",
+			Type: entity.IDProviderTypePassword,
+			Name: entity.IDProviderNameDefault,
+		}},
+	}
+	test := &emailChangeTest{
+		users: users{
+			"user":  {ID: "user", SpaceID: space.ID, Email: "old@example.com", EmailVerified: true},
+			"other": {ID: "other", SpaceID: space.ID, Email: "other@example.com"},
+		},
+		identities: identities{
+			"identity": {ID: "identity", UserID: "user", IdentityProviderID: "provider", ExternalID: "old@example.com", Email: "old@example.com"},
+			"other":    {ID: "other", UserID: "other", IdentityProviderID: "provider", ExternalID: "other@example.com", Email: "other@example.com"},
+		},
+		tokens:     tokens{},
+		mailer:     &mailer{},
+		deliveries: memory.New(),
+	}
+
+	spaces := repository.OneSpaceRepo(space)
+	test.s = New(ServiceParams{
+		Users:           test.users,
+		UserIdentities:  test.identities,
+		Spaces:          spaces,
+		Apps:            apps{},
+		UserService:     user.New(user.ServiceParams{UserRepo: test.users, SpaceRepo: spaces, UserIdentityRepo: test.identities}),
+		PasswordManager: passwords{},
+		Tokens:          test.tokens,
+		Mailer:          test.mailer,
+		Templates: &config.MailTemplates{
+			ChangeEmailTpl:       "../../../public/templates/email/change_email.html",
+			ChangeEmailNoticeTpl: "../../../public/templates/email/change_email_notice.html",
+		},
+		WebHooks: webhooks.NewWebhooks(test.deliveries),
+	})
+	return test
+}
+
+func (test *emailChangeTest) start(password, email string) error {
+	return test.s.Start(context.Background(), service.StartEmailChangeData{UserID: "user", Password: password, Email: email})
+}
+
+func (test *emailChangeTest) published(t *testing.T) []string {
+	list, _, err := test.deliveries.Find(context.Background(), repository.WebhookDeliveryQuery{})
+	assert.Nil(t, err)
+
+	var actions []string
+	for _, d := range list {
+		actions = append(actions, d.Event)
+	}
+	return actions
+}
+
+func TestStartSendsLinksToBothAddresses(t *testing.T) {
+	test := newEmailChangeTest()
+
+	assert.Nil(t, test.start("password", "new@example.com"))
+	assert.Equal(t, []string{"new@example.com", "old@example.com"}, test.mailer.to)
+	assert.Equal(t, "old@example.com", test.users["user"].Email)
+}
+
+func TestStartReturnErrorWithIncorrectPassword(t *testing.T) {
+	test := newEmailChangeTest()
+
+	assert.Equal(t, password_manager.ErrPasswordMismatch, test.start("incorrect", "new@example.com"))
+	assert.Empty(t, test.mailer.to)
+}
+
+func TestStartReturnErrorWithRegisteredEmail(t *testing.T) {
+	test := newEmailChangeTest()
+
+	assert.Equal(t, user.ErrEmailRegistered, test.start("password", "other@example.com"))
+	assert.Empty(t, test.mailer.to)
+}
+
+func TestConfirmChangesUserAndIdentity(t *testing.T) {
+	test := newEmailChangeTest()
+	assert.Nil(t, test.start("password", "new@example.com"))
+
+	assert.Nil(t, test.s.Confirm(context.Background(), "token1"))
+	assert.Equal(t, "new@example.com", test.users["user"].Email)
+	assert.True(t, test.users["user"].EmailVerified)
+	assert.Equal(t, "new@example.com", test.identities["identity"].Email)
+	assert.Equal(t, "new@example.com", test.identities["identity"].ExternalID)
+	assert.Equal(t, []string{webhooks.EmailChangedAction}, test.published(t))
+
+	assert.Equal(t, ErrInvalidToken, test.s.Confirm(context.Background(), "token1"))
+}
+
+func TestRevertRestoresPreviousEmail(t *testing.T) {
+	test := newEmailChangeTest()
+	assert.Nil(t, test.start("password", "new@example.com"))
+	assert.Nil(t, test.s.Confirm(context.Background(), "token1"))
+
+	assert.Nil(t, test.s.Revert(context.Background(), "token2"))
+	assert.Equal(t, "old@example.com", test.users["user"].Email)
+	assert.Equal(t, "old@example.com", test.identities["identity"].ExternalID)
+	assert.Equal(t, []string{webhooks.EmailChangedAction, webhooks.EmailChangedAction}, test.published(t))
+}
+
+func TestRevertCancelsUnconfirmedChange(t *testing.T) {
+	test := newEmailChangeTest()
+	assert.Nil(t, test.start("password", "new@example.com"))
+
+	assert.Nil(t, test.s.Revert(context.Background(), "token2"))
+	assert.Equal(t, ErrInvalidToken, test.s.Confirm(context.Background(), "token1"))
+	assert.Equal(t, "old@example.com", test.users["user"].Email)
+	assert.Empty(t, test.published(t))
+}
+
+// dupIdentities refuses to update the identities as the unique index does for the taken address.
+type dupIdentities struct {
+	identities
+}
+
+func (r dupIdentities) Update(ctx context.Context, i *entity.UserIdentity) error {
+	return &mgo.LastError{Code: 11000}
+}
+
+func (test *emailChangeTest) userService(identities repository.UserIdentityRepository) service.UserService {
+	spaces := repository.OneSpaceRepo(&entity.Space{
+		ID:                "space",
+		IdentityProviders: entity.IdentityProviders{{ID: "provider", Type: entity.IDProviderTypePassword, Name: entity.IDProviderNameDefault}},
+	})
+	return user.New(user.ServiceParams{UserRepo: test.users, SpaceRepo: spaces, UserIdentityRepo: identities})
+}
+
+func TestUpdateRestoresUserIfIdentityAddressIsTaken(t *testing.T) {
+	test := newEmailChangeTest()
+
+	email := "new@example.com"
+	err := test.userService(dupIdentities{test.identities}).Update(context.Background(), service.UpdateUserData{ID: "user", Email: &email})
+	assert.Equal(t, user.ErrEmailRegistered, err)
+	assert.Equal(t, "old@example.com", test.users["user"].Email)
+	assert.False(t, test.users["user"].EmailPending)
+	assert.Equal(t, "old@example.com", test.identities["identity"].ExternalID)
+}
+
+func TestUpdateReconcilesPendingEmail(t *testing.T) {
+	test := newEmailChangeTest()
+	u := test.users["user"]
+	u.Email = "new@example.com"
+	u.EmailPending = true
+	test.users["user"] = u
+
+	verified := true
+	assert.Nil(t, test.userService(test.identities).Update(context.Background(), service.UpdateUserData{ID: "user", EmailVerified: &verified}))
+	assert.Equal(t, "new@example.com", test.identities["identity"].ExternalID)
+	assert.False(t, test.users["user"].EmailPending)
+}
@@ -0,0 +1,26 @@
+package operator
+
+import (
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/repository"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/service"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/config"
+	appservice "github.com/ProtocolONE/auth1.protocol.one/pkg/service"
+	"github.com/go-redis/redis"
+	"go.uber.org/fx"
+)
+
+type ServiceParams struct {
+	fx.In
+
+	Config    *config.Operators
+	Operators repository.OperatorRepository
+	Sessions  repository.OperatorSessionRepository
+	Redis     *redis.Client
+}
+
+func New(params ServiceParams) service.OperatorService {
+	return &Service{
+		ServiceParams: params,
+		attempts:      appservice.NewLoginAttempts(params.Redis),
+	}
+}
@@ -0,0 +1,81 @@
+package operator
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"sync"
+	"time"
+)
+
+// The limits of the failed password checks, the operator logins aren't scoped by spaces, so they don't follow
+// the lockout settings of any space.
+const (
+	loginMaxAttempts = 5
+	ipMaxAttempts    = 20
+	loginWindow      = 15 * time.Minute
+	loginLock        = 15 * time.Minute
+)
+
+var (
+	dummyOnce sync.Once
+	dummy     string
+)
+
+// dummyHash returns the hash compared for the unknown logins, so they take as long as the known ones.
+func dummyHash() string {
+	dummyOnce.Do(func() {
+		b := make([]byte, 24)
+		if _, err := rand.Read(b); err != nil {
+			return
+		}
+		dummy, _ = hashPassword(base64.RawURLEncoding.EncodeToString(b), AlgorithmBcrypt)
+	})
+	return dummy
+}
+
+func loginLockKey(login string) string {
+	return "operator_" + login
+}
+
+func ipLockKey(ip string) string {
+	return "operator_ip_" + ip
+}
+
+// checkLockout returns ErrTooManyAttempts if the login or the ip address is locked.
+func (s *Service) checkLockout(login, ip string) error {
+	for _, key := range []string{loginLockKey(login), ipLockKey(ip)} {
+		lock, _, err := s.attempts.Blocked(key)
+		if err != nil {
+			return err
+		}
+		if lock > 0 {
+			return ErrTooManyAttempts
+		}
+	}
+
+	return nil
+}
+
+// failLogin counts the failed password check and locks the login or the ip address on reaching the limits.
+func (s *Service) failLogin(login, ip string) error {
+	limits := []struct {
+		key string
+		max int
+	}{
+		{loginLockKey(login), loginMaxAttempts},
+		{ipLockKey(ip), ipMaxAttempts},
+	}
+	for _, l := range limits {
+		n, err := s.attempts.Fail(l.key, loginWindow)
+		if err != nil {
+			return err
+		}
+		if n >= l.max {
+			if err := s.attempts.Lock(l.key, loginLock); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,238 @@
+package operator
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"time"
+
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/service"
+	appservice "github.com/ProtocolONE/auth1.protocol.one/pkg/service"
+)
+
+type Service struct {
+	ServiceParams
+	attempts appservice.LoginAttemptsInterface
+}
+
+var (
+	ErrLoginRequired      = errors.New("operator login is required")
+	ErrOperatorExists     = errors.New("operator with the login already exists")
+	ErrOperatorNotFound   = errors.New("operator not found")
+	ErrInvalidGrant       = errors.New("invalid operator role or space")
+	ErrInvalidCredentials = errors.New("invalid login or password")
+	ErrOperatorNotLinked  = errors.New("user isn't linked to an operator")
+	ErrInvalidSession     = errors.New("operator session is invalid or expired")
+	ErrTooManyAttempts    = errors.New("too many failed logins, try again later")
+)
+
+func (s *Service) Create(ctx context.Context, data service.CreateOperatorData) (*entity.Operator, error) {
+	if data.Login == "" {
+		return nil, ErrLoginRequired
+	}
+	if err := validateGrants(data.Grants); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.Operators.FindByLogin(ctx, data.Login)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, ErrOperatorExists
+	}
+
+	var hash string
+	if data.Password != "" {
+		if hash, err = hashPassword(data.Password, data.Algorithm); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	operator := &entity.Operator{
+		Login:        data.Login,
+		Name:         data.Name,
+		PasswordHash: hash,
+		Subject:      data.Subject,
+		Grants:       data.Grants,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	if err := s.Operators.Create(ctx, operator); err != nil {
+		return nil, err
+	}
+
+	return operator, nil
+}
+
+func (s *Service) Grant(ctx context.Context, login string, grants []entity.OperatorGrant) (*entity.Operator, error) {
+	if err := validateGrants(grants); err != nil {
+		return nil, err
+	}
+
+	operator, err := s.Operators.FindByLogin(ctx, login)
+	if err != nil {
+		return nil, err
+	}
+	if operator == nil {
+		return nil, ErrOperatorNotFound
+	}
+
+	operator.Grants = grants
+	operator.UpdatedAt = time.Now()
+	if err := s.Operators.Update(ctx, operator); err != nil {
+		return nil, err
+	}
+
+	return operator, nil
+}
+
+func (s *Service) CheckPassword(ctx context.Context, login, password, ip string) (*entity.Operator, error) {
+	if err := s.checkLockout(login, ip); err != nil {
+		return nil, err
+	}
+
+	operator, err := s.Operators.FindByLogin(ctx, login)
+	if err != nil {
+		return nil, err
+	}
+
+	// INFO: The hash is compared for the unknown login as well, so it can't be told by the response time
+	hash := dummyHash()
+	if operator != nil && operator.PasswordHash != "" {
+		hash = operator.PasswordHash
+	}
+	if !comparePassword(hash, password) || operator == nil || operator.PasswordHash == "" || operator.Disabled {
+		if err := s.failLogin(login, ip); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidCredentials
+	}
+
+	if err := s.attempts.Reset(loginLockKey(login)); err != nil {
+		return nil, err
+	}
+
+	return operator, nil
+}
+
+func (s *Service) SignIn(ctx context.Context, login, password, ip string) (*entity.OperatorSession, error) {
+	operator, err := s.CheckPassword(ctx, login, password, ip)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.open(ctx, operator)
+}
+
+func (s *Service) SignInSubject(ctx context.Context, subject, email string, emailVerified bool) (*entity.OperatorSession, error) {
+	operator, err := s.Operators.FindBySubject(ctx, subject)
+	if err != nil {
+		return nil, err
+	}
+
+	if operator == nil && emailVerified && email != "" {
+		operator, err = s.Operators.FindByLogin(ctx, email)
+		if err != nil {
+			return nil, err
+		}
+		// INFO: the operator linked to another user is never relinked by email
+		if operator != nil && operator.Subject != "" {
+			operator = nil
+		}
+		if operator != nil {
+			operator.Subject = subject
+		}
+	}
+
+	if operator == nil || operator.Disabled {
+		return nil, ErrOperatorNotLinked
+	}
+
+	return s.open(ctx, operator)
+}
+
+func (s *Service) SignOut(ctx context.Context, token string) error {
+	return s.Sessions.Delete(ctx, token)
+}
+
+func (s *Service) Authenticate(ctx context.Context, token string) (*entity.Operator, error) {
+	if token == "" {
+		return nil, ErrInvalidSession
+	}
+
+	session, err := s.Sessions.FindByToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil {
+		return nil, ErrInvalidSession
+	}
+
+	operator, err := s.Operators.FindByID(ctx, session.OperatorID)
+	if err != nil {
+		return nil, err
+	}
+	if operator == nil || operator.Disabled {
+		return nil, ErrInvalidSession
+	}
+
+	return operator, nil
+}
+
+func (s *Service) open(ctx context.Context, operator *entity.Operator) (*entity.OperatorSession, error) {
+	now := time.Now()
+	operator.LastLoginAt = now
+	operator.UpdatedAt = now
+	if err := s.Operators.Update(ctx, operator); err != nil {
+		return nil, err
+	}
+
+	token, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+
+	session := &entity.OperatorSession{
+		Token:      token,
+		OperatorID: operator.ID,
+		ExpiresAt:  now.Add(s.Config.SessionTTL),
+		CreatedAt:  now,
+	}
+	if err := s.Sessions.Create(ctx, session); err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
+func validateGrants(grants []entity.OperatorGrant) error {
+	for _, g := range grants {
+		switch g.Role {
+		case entity.OperatorSuperAdmin:
+			if g.SpaceID != "" {
+				return ErrInvalidGrant
+			}
+		case entity.OperatorSpaceAdmin, entity.OperatorSupport:
+			if g.SpaceID == "" {
+				return ErrInvalidGrant
+			}
+		default:
+			return ErrInvalidGrant
+		}
+	}
+
+	return nil
+}
+
+func newToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,96 @@
+package operator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/repository"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type memoryOperators map[string]*entity.Operator
+
+func (m memoryOperators) Create(ctx context.Context, operator *entity.Operator) error {
+	m[operator.Login] = operator
+	return nil
+}
+
+func (m memoryOperators) Update(ctx context.Context, operator *entity.Operator) error {
+	m[operator.Login] = operator
+	return nil
+}
+
+func (m memoryOperators) Find(ctx context.Context) ([]*entity.Operator, error) {
+	return nil, nil
+}
+
+func (m memoryOperators) FindByID(ctx context.Context, id entity.OperatorID) (*entity.Operator, error) {
+	return nil, nil
+}
+
+func (m memoryOperators) FindByLogin(ctx context.Context, login string) (*entity.Operator, error) {
+	return m[login], nil
+}
+
+func (m memoryOperators) FindBySubject(ctx context.Context, subject string) (*entity.Operator, error) {
+	return nil, nil
+}
+
+var _ repository.OperatorRepository = memoryOperators{}
+
+func newPasswordService(t *testing.T) (*Service, *mocks.LoginAttemptsInterface) {
+	hash, err := hashPassword("secret", AlgorithmBcrypt)
+	assert.Nil(t, err)
+
+	attempts := &mocks.LoginAttemptsInterface{}
+	s := &Service{
+		ServiceParams: ServiceParams{Operators: memoryOperators{"admin": {Login: "admin", PasswordHash: hash}}},
+		attempts:      attempts,
+	}
+	return s, attempts
+}
+
+func TestCheckPasswordResetsFailuresOnSuccess(t *testing.T) {
+	s, attempts := newPasswordService(t)
+	attempts.On("Blocked", mock.Anything).Return(time.Duration(0), time.Duration(0), nil)
+	attempts.On("Reset", "operator_admin").Return(nil)
+
+	op, err := s.CheckPassword(context.Background(), "admin", "secret", "127.0.0.1")
+	assert.Nil(t, err)
+	assert.Equal(t, "admin", op.Login)
+	attempts.AssertNotCalled(t, "Fail", mock.Anything, mock.Anything)
+}
+
+func TestCheckPasswordLocksLoginAfterMaxAttempts(t *testing.T) {
+	s, attempts := newPasswordService(t)
+	attempts.On("Blocked", mock.Anything).Return(time.Duration(0), time.Duration(0), nil)
+	attempts.On("Fail", "operator_admin", loginWindow).Return(loginMaxAttempts, nil)
+	attempts.On("Fail", "operator_ip_127.0.0.1", loginWindow).Return(1, nil)
+	attempts.On("Lock", "operator_admin", loginLock).Return(nil)
+
+	_, err := s.CheckPassword(context.Background(), "admin", "other", "127.0.0.1")
+	assert.Equal(t, ErrInvalidCredentials, err)
+	attempts.AssertCalled(t, "Lock", "operator_admin", loginLock)
+}
+
+func TestCheckPasswordCountsUnknownLogin(t *testing.T) {
+	s, attempts := newPasswordService(t)
+	attempts.On("Blocked", mock.Anything).Return(time.Duration(0), time.Duration(0), nil)
+	attempts.On("Fail", mock.Anything, loginWindow).Return(1, nil)
+
+	_, err := s.CheckPassword(context.Background(), "unknown", "secret", "127.0.0.1")
+	assert.Equal(t, ErrInvalidCredentials, err)
+	attempts.AssertCalled(t, "Fail", "operator_unknown", loginWindow)
+}
+
+func TestCheckPasswordReturnErrorIfLocked(t *testing.T) {
+	s, attempts := newPasswordService(t)
+	attempts.On("Blocked", "operator_admin").Return(time.Minute, time.Duration(0), nil)
+
+	_, err := s.CheckPassword(context.Background(), "admin", "secret", "127.0.0.1")
+	assert.Equal(t, ErrTooManyAttempts, err)
+}
@@ -0,0 +1,27 @@
+package operator
+
+import (
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/hasher"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	AlgorithmBcrypt   = entity.PasswordAlgorithmBcrypt
+	AlgorithmArgon2id = entity.PasswordAlgorithmArgon2id
+)
+
+// hashPassword returns the bcrypt hash or the argon2id hash in the PHC string format.
+func hashPassword(password, algorithm string) (string, error) {
+	h, err := hasher.New(entity.PasswordSettings{Algorithm: algorithm, BcryptCost: bcrypt.DefaultCost})
+	if err != nil {
+		return "", err
+	}
+
+	return h.Hash(password)
+}
+
+// comparePassword reports whether the password matches the hash made by hashPassword.
+func comparePassword(hash, password string) bool {
+	return hasher.Verify(hash, password) == nil
+}
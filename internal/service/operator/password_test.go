@@ -0,0 +1,33 @@
+package operator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComparePasswordWithEachAlgorithm(t *testing.T) {
+	for _, algorithm := range []string{AlgorithmBcrypt, AlgorithmArgon2id} {
+		hash, err := hashPassword("secret", algorithm)
+		assert.Nil(t, err, algorithm)
+
+		assert.True(t, comparePassword(hash, "secret"), algorithm)
+		assert.False(t, comparePassword(hash, "other"), algorithm)
+	}
+}
+
+func TestHashPasswordArgon2idFormat(t *testing.T) {
+	hash, err := hashPassword("secret", AlgorithmArgon2id)
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=65536,t=3,p=2$"))
+}
+
+func TestComparePasswordRejectsMalformedHash(t *testing.T) {
+	assert.False(t, comparePassword("", ""))
+	assert.False(t, comparePassword("$argon2id$v=19$m=65536,t=3,p=2$broken", "secret"))
+	assert.False(t, comparePassword("plain", "plain"))
+
+	_, err := hashPassword("secret", "md5")
+	assert.NotNil(t, err)
+}
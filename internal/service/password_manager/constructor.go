@@ -3,6 +3,7 @@ package password_manager
 import (
 	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/repository"
 	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/service"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/passwords"
 	"go.uber.org/fx"
 )
 
@@ -12,6 +13,7 @@ type ServiceParams struct {
 	Users          repository.UserRepository
 	Spaces         repository.SpaceRepository
 	UserIdentities repository.UserIdentityRepository
+	Breached       passwords.Corpus `optional:"true"`
 }
 
 func New(params ServiceParams) service.PasswordManager {
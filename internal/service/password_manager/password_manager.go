@@ -5,7 +5,8 @@ import (
 	"errors"
 
 	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/hasher"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/passwords"
 )
 
 type Service struct {
@@ -15,13 +16,51 @@ type Service struct {
 var ErrPasswordTooWeak = errors.New("password doesn't meet requirements")
 var ErrPasswordMismatch = errors.New("password doesn't match")
 
+// WeakPasswordError is ErrPasswordTooWeak with the reason the password is rejected by the space settings.
+type WeakPasswordError struct {
+	Reason string
+}
+
+func (e *WeakPasswordError) Error() string {
+	return ErrPasswordTooWeak.Error() + ": " + e.Reason
+}
+
+func (e *WeakPasswordError) Is(target error) bool {
+	return target == ErrPasswordTooWeak
+}
+
 func (s *Service) Compare(hashedPassword string, password string) error {
-	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+	return hasher.Verify(hashedPassword, password)
+}
+
+func (s *Service) Digest(password string, settings entity.PasswordSettings) (string, error) {
+	h, err := hasher.New(settings)
+	if err != nil {
+		return "", err
+	}
+	return h.Hash(password)
 }
 
-func (s *Service) Digest(password string, cost int) (string, error) {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), cost)
-	return string(hashedPassword), err
+func (s *Service) CheckPassword(ctx context.Context, userId entity.UserID, password string) error {
+	user, err := s.Users.FindByID(ctx, userId)
+	if err != nil {
+		return err
+	}
+
+	space, err := s.Spaces.FindByID(ctx, user.SpaceID)
+	if err != nil {
+		return err
+	}
+
+	identity, err := s.UserIdentities.FindByProviderAndUser(ctx, space.DefaultIDProvider().ID, userId)
+	if err != nil {
+		return err
+	}
+	if identity == nil || s.Compare(identity.Credential, password) != nil {
+		return ErrPasswordMismatch
+	}
+
+	return nil
 }
 
 func (s *Service) ChangePassword(ctx context.Context, userId entity.UserID, old, new string) error {
@@ -35,8 +74,8 @@ func (s *Service) ChangePassword(ctx context.Context, userId entity.UserID, old,
 		return err
 	}
 
-	if !space.PasswordSettings.IsValid(new) {
-		return ErrPasswordTooWeak
+	if reason := space.PasswordSettings.Check(new); reason != "" {
+		return &WeakPasswordError{Reason: reason}
 	}
 
 	provider := space.DefaultIDProvider()
@@ -50,11 +89,22 @@ func (s *Service) ChangePassword(ctx context.Context, userId entity.UserID, old,
 		return ErrPasswordMismatch
 	}
 
-	hash, err := s.Digest(new, space.PasswordSettings.BcryptCost)
+	// INFO: The history is checked only after the old password, so it can't be guessed by the reuse errors
+	history := append([]string{identity.Credential}, identity.PasswordHistory...)
+	reason, err := passwords.Check(space.PasswordSettings, s.Breached, new, history)
+	if err != nil {
+		return err
+	}
+	if reason != "" {
+		return &WeakPasswordError{Reason: reason}
+	}
+
+	hash, err := s.Digest(new, space.PasswordSettings)
 	if err != nil {
 		return err
 	}
 
+	identity.PasswordHistory = passwords.History(space.PasswordSettings, identity.Credential, identity.PasswordHistory)
 	identity.Credential = hash
 
 	err = s.UserIdentities.Update(ctx, identity)
@@ -12,7 +12,7 @@ type ServiceParams struct {
 	ApplicationService service.ApplicationService
 	UserRepo           repository.UserRepository
 	SpaceRepo          repository.SpaceRepository
-	//userIdentityRepo repository.UserIdentityRepository
+	UserIdentityRepo   repository.UserIdentityRepository
 }
 
 func New(params ServiceParams) service.UserService {
@@ -2,10 +2,12 @@ package user
 
 import (
 	"context"
-	"errors"
+	"time"
 
 	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
 	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/service"
+	"github.com/globalsign/mgo"
+	"github.com/pkg/errors"
 )
 
 type Service struct {
@@ -13,6 +15,7 @@ type Service struct {
 }
 
 var ErrUserNotFound = errors.New("user not found")
+var ErrEmailRegistered = errors.New("email is registered by another user")
 
 func (s Service) Update(ctx context.Context, data service.UpdateUserData) error {
 	user, err := s.GetByID(ctx, data.ID)
@@ -29,6 +32,13 @@ func (s Service) Update(ctx context.Context, data service.UpdateUserData) error
 		return ErrUserNotFound
 	}
 
+	// INFO: The new address replaces the pending one in the identity as well
+	if user.EmailPending && (data.Email == nil || *data.Email == user.Email) {
+		if err := s.reconcileEmail(ctx, space, user); err != nil {
+			return err
+		}
+	}
+
 	if data.Phone != nil {
 		user.PhoneNumber = *data.Phone
 	}
@@ -49,9 +59,89 @@ func (s Service) Update(ctx context.Context, data service.UpdateUserData) error
 		}
 	}
 
+	if data.EmailVerified != nil {
+		user.EmailVerified = *data.EmailVerified
+	}
+	if data.Email != nil && *data.Email != user.Email {
+		return s.changeEmail(ctx, space, user, *data.Email)
+	}
+
+	return s.UserRepo.Update(ctx, user)
+}
+
+// changeEmail saves the user with the new address and updates the identity of the default provider used to sign in
+// with the password. The user is saved first with the pending mark, so the identity is reconciled from it if the
+// change is interrupted, the user is restored to the previous address if the identity can't be updated.
+func (s Service) changeEmail(ctx context.Context, space *entity.Space, user *entity.User, email string) error {
+	provider := space.DefaultIDProvider()
+
+	other, err := s.UserIdentityRepo.FindByProviderAndExternalID(ctx, provider.ID, email)
+	if err != nil {
+		return err
+	}
+	if other != nil && other.UserID != user.ID {
+		return ErrEmailRegistered
+	}
+
+	prev := *user
+	user.Email = email
+	user.EmailPending = true
+	user.UpdatedAt = time.Now()
+	if err := s.UserRepo.Update(ctx, user); err != nil {
+		if mgo.IsDup(err) {
+			return ErrEmailRegistered
+		}
+		return err
+	}
+
+	if err := s.updateIdentityEmail(ctx, provider.ID, user); err != nil {
+		prev.UpdatedAt = time.Now()
+		if rerr := s.UserRepo.Update(ctx, &prev); rerr != nil {
+			// INFO: The user keeps the pending mark, the identity is reconciled from it by the next update
+			return errors.Wrap(rerr, "unable to restore user")
+		}
+		return err
+	}
+
+	user.EmailPending = false
+	return s.UserRepo.Update(ctx, user)
+}
+
+// reconcileEmail applies the interrupted email change of the user to the identity of the default provider.
+func (s Service) reconcileEmail(ctx context.Context, space *entity.Space, user *entity.User) error {
+	if err := s.updateIdentityEmail(ctx, space.DefaultIDProvider().ID, user); err != nil {
+		return err
+	}
+
+	user.EmailPending = false
 	return s.UserRepo.Update(ctx, user)
 }
 
+// updateIdentityEmail sets the address of the user to its identity of the provider, ErrEmailRegistered is returned
+// if the address is taken by another identity.
+func (s Service) updateIdentityEmail(ctx context.Context, providerID entity.IdentityProviderID, user *entity.User) error {
+	identity, err := s.UserIdentityRepo.FindByProviderAndUser(ctx, providerID, user.ID)
+	if err != nil {
+		return err
+	}
+	if identity == nil || identity.ExternalID == user.Email {
+		// INFO: The user signed up with the social provider only or the identity is already updated
+		return nil
+	}
+
+	identity.ExternalID = user.Email
+	identity.Email = user.Email
+	identity.UpdatedAt = user.UpdatedAt
+	if err := s.UserIdentityRepo.Update(ctx, identity); err != nil {
+		if mgo.IsDup(err) {
+			return ErrEmailRegistered
+		}
+		return err
+	}
+
+	return nil
+}
+
 func (s Service) GetByID(ctx context.Context, id entity.UserID) (*entity.User, error) {
 	user, err := s.UserRepo.FindByID(ctx, id)
 	if err != nil {
@@ -0,0 +1,22 @@
+package user_import
+
+import (
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/repository"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/service"
+	"go.uber.org/fx"
+)
+
+type ServiceParams struct {
+	fx.In
+
+	Users          repository.UserRepository
+	UserIdentities repository.UserIdentityRepository
+	Spaces         repository.SpaceRepository
+	Apps           repository.ApplicationRepository
+}
+
+func New(params ServiceParams) service.UserImportService {
+	return &Service{
+		params,
+	}
+}
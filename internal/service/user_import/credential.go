@@ -0,0 +1,98 @@
+package user_import
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"strconv"
+	"strings"
+
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/hasher"
+	"github.com/pkg/errors"
+)
+
+// credential returns the hash of the password in the format verified by the hasher. The hashes of bcrypt,
+// argon2id, scrypt and phpass are kept as is, the others are encoded from their parts:
+//   - md5, sha1, sha256, sha512: the hex digest of the salt and the password, the salt is plain text;
+//   - pbkdf2-sha1, pbkdf2-sha256, pbkdf2-sha512: the base64 key, the plain salt and the iterations, or the
+//     whole hash of django like "pbkdf2_sha256$260000$salt$key";
+//   - firebase-scrypt: the base64 hash and salt, the project parameters are taken from the options.
+//
+// The hash which cost parameters exceed the bounds of the hasher is refused, it would never be verified.
+func credential(p *password, firebase *hasher.FirebaseScryptParams) (string, error) {
+	hash, err := encode(p, firebase)
+	if err != nil {
+		return "", err
+	}
+
+	switch hasher.Check(hash) {
+	case nil:
+		return hash, nil
+	case hasher.ErrCostExceeded:
+		return "", errors.Errorf("cost of the %s hash exceeds the bounds", p.Algorithm)
+	default:
+		return "", errors.Errorf("malformed %s hash", p.Algorithm)
+	}
+}
+
+// encode returns the hash of the password in the format of the hasher without checking its parameters.
+func encode(p *password, firebase *hasher.FirebaseScryptParams) (string, error) {
+	switch p.Algorithm {
+	case entity.PasswordAlgorithmBcrypt, entity.PasswordAlgorithmArgon2id, entity.PasswordAlgorithmScrypt, hasher.AlgorithmPhpass:
+		return p.Hash, nil
+
+	case hasher.AlgorithmMD5, hasher.AlgorithmSHA1, hasher.AlgorithmSHA256, hasher.AlgorithmSHA512:
+		digest, err := hex.DecodeString(p.Hash)
+		if err != nil || len(digest) == 0 {
+			return "", errors.Errorf("malformed %s hash, hex is expected", p.Algorithm)
+		}
+		return hasher.EncodeSaltedDigest(p.Algorithm, p.SaltFirst, []byte(p.Salt), digest), nil
+
+	case hasher.AlgorithmPBKDF2SHA1, hasher.AlgorithmPBKDF2SHA256, hasher.AlgorithmPBKDF2SHA512:
+		if strings.HasPrefix(p.Hash, "pbkdf2_") {
+			return djangoPBKDF2(p)
+		}
+		key, err := base64.StdEncoding.DecodeString(p.Hash)
+		if err != nil || len(key) == 0 {
+			return "", errors.Errorf("malformed %s hash, base64 is expected", p.Algorithm)
+		}
+		if p.Iterations <= 0 {
+			return "", errors.New("iterations of the pbkdf2 hash are required")
+		}
+		return hasher.EncodePBKDF2(p.Algorithm, p.Iterations, []byte(p.Salt), key), nil
+
+	case hasher.AlgorithmFirebaseScrypt:
+		if firebase == nil {
+			return "", errors.New("firebase signer key isn't set")
+		}
+		key, err := base64.StdEncoding.DecodeString(p.Hash)
+		if err != nil || len(key) == 0 {
+			return "", errors.New("malformed firebase-scrypt hash, base64 is expected")
+		}
+		salt, err := base64.StdEncoding.DecodeString(p.Salt)
+		if err != nil {
+			return "", errors.New("malformed firebase-scrypt salt, base64 is expected")
+		}
+		return hasher.EncodeFirebaseScrypt(*firebase, salt, key), nil
+	}
+
+	return "", errors.Errorf("unknown password algorithm %q", p.Algorithm)
+}
+
+// djangoPBKDF2 converts the hash of django, the digest of the hash must be the one of the algorithm.
+func djangoPBKDF2(p *password) (string, error) {
+	parts := strings.Split(p.Hash, "$")
+	if len(parts) != 4 || strings.Replace(parts[0], "_", "-", 1) != p.Algorithm {
+		return "", errors.Errorf("malformed %s hash", p.Algorithm)
+	}
+	iterations, err := strconv.Atoi(parts[1])
+	if err != nil || iterations <= 0 {
+		return "", errors.Errorf("malformed %s hash", p.Algorithm)
+	}
+	key, err := base64.StdEncoding.DecodeString(parts[3])
+	if err != nil || len(key) == 0 {
+		return "", errors.Errorf("malformed %s hash", p.Algorithm)
+	}
+
+	return hasher.EncodePBKDF2(p.Algorithm, iterations, []byte(parts[2]), key), nil
+}
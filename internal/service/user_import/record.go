@@ -0,0 +1,184 @@
+package user_import
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/service"
+	"github.com/pkg/errors"
+)
+
+// record is the user exported from the other system.
+type record struct {
+	Email         string     `json:"email"`
+	EmailVerified bool       `json:"email_verified"`
+	Username      string     `json:"username"`
+	Name          string     `json:"name"`
+	Picture       string     `json:"picture"`
+	PhoneNumber   string     `json:"phone_number"`
+	Roles         []string   `json:"roles"`
+	Password      *password  `json:"password"`
+	Identities    []identity `json:"identities"`
+}
+
+// password is the hash of the other system, the encoding of the hash and the salt depends on the algorithm.
+type password struct {
+	Algorithm  string `json:"algorithm"`
+	Hash       string `json:"hash"`
+	Salt       string `json:"salt"`
+	SaltFirst  bool   `json:"salt_first"`
+	Iterations int    `json:"iterations"`
+}
+
+// identity is the account of the social network linked to the user.
+type identity struct {
+	Provider   string `json:"provider"`
+	ExternalID string `json:"external_id"`
+	Email      string `json:"email"`
+	Username   string `json:"username"`
+	Name       string `json:"name"`
+	Picture    string `json:"picture"`
+}
+
+// reader returns the records one by one with the number of the line, io.EOF is returned after the last one.
+type reader interface {
+	Read() (*record, int, error)
+}
+
+func newReader(r io.Reader, format string) (reader, error) {
+	switch format {
+	case "", service.ImportFormatJSONL:
+		s := bufio.NewScanner(r)
+		s.Buffer(make([]byte, 64*1024), 1024*1024)
+		return &jsonlReader{s: s}, nil
+	case service.ImportFormatCSV:
+		c := csv.NewReader(r)
+		c.FieldsPerRecord = -1
+		header, err := c.Read()
+		if err != nil {
+			return nil, errors.Wrapf(ErrInvalidFile, "unable to read csv header: %v", err)
+		}
+		columns := map[string]int{}
+		for i, name := range header {
+			columns[strings.TrimSpace(name)] = i
+		}
+		return &csvReader{c: c, columns: columns, line: 1}, nil
+	}
+
+	return nil, errors.Wrapf(ErrInvalidFile, "unknown format %q", format)
+}
+
+type jsonlReader struct {
+	s    *bufio.Scanner
+	line int
+}
+
+func (r *jsonlReader) Read() (*record, int, error) {
+	for r.s.Scan() {
+		r.line++
+		if strings.TrimSpace(r.s.Text()) == "" {
+			continue
+		}
+
+		rec := &record{}
+		if err := json.Unmarshal(r.s.Bytes(), rec); err != nil {
+			return nil, r.line, &recordError{errors.Wrap(err, "invalid json")}
+		}
+		return rec, r.line, nil
+	}
+	if err := r.s.Err(); err != nil {
+		return nil, r.line + 1, errors.Wrapf(ErrInvalidFile, "line %d: %v", r.line+1, err)
+	}
+
+	return nil, r.line, io.EOF
+}
+
+// csvReader reads the records with the columns named by the header: email, email_verified, username, name,
+// picture, phone_number, roles, password_algorithm, password_hash, password_salt, password_salt_first,
+// password_iterations and identities. The roles are separated by ";", the identities are the list of
+// "provider:external_id" separated by ";".
+type csvReader struct {
+	c       *csv.Reader
+	columns map[string]int
+	line    int
+}
+
+func (r *csvReader) Read() (*record, int, error) {
+	row, err := r.c.Read()
+	r.line++
+	if err != nil {
+		if _, ok := err.(*csv.ParseError); ok {
+			err = &recordError{errors.Wrap(err, "invalid csv")}
+		}
+		return nil, r.line, err
+	}
+
+	get := func(name string) string {
+		if i, ok := r.columns[name]; ok && i < len(row) {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+	flag := func(name string) (bool, error) {
+		if v := get(name); v != "" {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return false, invalid("invalid %s", name)
+			}
+			return b, nil
+		}
+		return false, nil
+	}
+
+	rec := &record{
+		Email:       get("email"),
+		Username:    get("username"),
+		Name:        get("name"),
+		Picture:     get("picture"),
+		PhoneNumber: get("phone_number"),
+		Roles:       list(get("roles")),
+	}
+	if rec.EmailVerified, err = flag("email_verified"); err != nil {
+		return nil, r.line, err
+	}
+
+	if algorithm := get("password_algorithm"); algorithm != "" {
+		rec.Password = &password{
+			Algorithm: algorithm,
+			Hash:      get("password_hash"),
+			Salt:      get("password_salt"),
+		}
+		if rec.Password.SaltFirst, err = flag("password_salt_first"); err != nil {
+			return nil, r.line, err
+		}
+		if v := get("password_iterations"); v != "" {
+			if rec.Password.Iterations, err = strconv.Atoi(v); err != nil {
+				return nil, r.line, invalid("invalid password_iterations")
+			}
+		}
+	}
+
+	for _, v := range list(get("identities")) {
+		parts := strings.SplitN(v, ":", 2)
+		if len(parts) != 2 {
+			return nil, r.line, invalid("invalid identity %q", v)
+		}
+		rec.Identities = append(rec.Identities, identity{Provider: parts[0], ExternalID: parts[1]})
+	}
+
+	return rec, r.line, nil
+}
+
+func list(v string) []string {
+	var result []string
+	for _, item := range strings.Split(v, ";") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
@@ -0,0 +1,381 @@
+package user_import
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/repository"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/service"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/hasher"
+	"github.com/pkg/errors"
+)
+
+type Service struct {
+	ServiceParams
+}
+
+var (
+	ErrSpaceNotFound          = errors.New("space not found")
+	ErrAppNotFound            = errors.New("application of the space not found")
+	ErrInvalidFirebaseOptions = errors.New("invalid firebase scrypt options")
+	ErrInvalidFile            = errors.New("invalid import file")
+)
+
+// errSkipped is returned for the record of the email registered already.
+var errSkipped = errors.New("email is registered")
+
+// Import reads the records till the end, the result of the records handled before is returned together with
+// the error of the reader or the repositories.
+func (s *Service) Import(ctx context.Context, r io.Reader, options service.ImportUsersOptions) (*service.ImportUsersResult, error) {
+	space, err := s.Spaces.FindByID(ctx, options.SpaceID)
+	if err != nil {
+		return nil, err
+	}
+	if space == nil {
+		return nil, ErrSpaceNotFound
+	}
+
+	app, err := s.app(ctx, space, options.AppID)
+	if err != nil {
+		return nil, err
+	}
+
+	firebase, err := firebaseParams(options.Firebase)
+	if err != nil {
+		return nil, err
+	}
+
+	rd, err := newReader(r, options.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	im := &importer{
+		Service:     s,
+		space:       space,
+		app:         app,
+		firebase:    firebase,
+		dryRun:      options.DryRun,
+		emails:      map[string]bool{},
+		usernames:   map[string]bool{},
+		externalIDs: map[string]bool{},
+	}
+	result := &service.ImportUsersResult{}
+	for {
+		rec, line, err := rd.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if _, ok := err.(*recordError); !ok {
+				return result, err
+			}
+			result.Errors = append(result.Errors, service.ImportUsersError{Line: line, Error: err.Error()})
+			continue
+		}
+
+		err = im.add(ctx, rec)
+		switch e := err.(type) {
+		case nil:
+			result.Created++
+		case *recordError:
+			result.Errors = append(result.Errors, service.ImportUsersError{Line: line, Email: rec.Email, Error: e.Error()})
+		default:
+			if err == errSkipped {
+				result.Skipped++
+				continue
+			}
+			return result, err
+		}
+	}
+
+	return result, nil
+}
+
+// app returns the application of the space the users are registered with.
+func (s *Service) app(ctx context.Context, space *entity.Space, id entity.AppID) (*entity.Application, error) {
+	if id == "" {
+		apps, _, err := s.Apps.Find(ctx, repository.ApplicationQuery{
+			SpaceIDs: []entity.SpaceID{space.ID},
+			Page:     repository.Page{Limit: 1, Sort: "created_at"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(apps) == 0 {
+			return nil, ErrAppNotFound
+		}
+		return apps[0], nil
+	}
+
+	app, err := s.Apps.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if app == nil || app.SpaceID != space.ID {
+		return nil, ErrAppNotFound
+	}
+
+	return app, nil
+}
+
+// firebaseParams decodes the project parameters, nil is returned if the signer key isn't set.
+func firebaseParams(o service.FirebaseScryptOptions) (*hasher.FirebaseScryptParams, error) {
+	if o.SignerKey == "" {
+		return nil, nil
+	}
+
+	key, err := base64.StdEncoding.DecodeString(o.SignerKey)
+	if err != nil {
+		return nil, errors.Wrap(ErrInvalidFirebaseOptions, "signer key isn't base64")
+	}
+	separator, err := base64.StdEncoding.DecodeString(o.SaltSeparator)
+	if err != nil {
+		return nil, errors.Wrap(ErrInvalidFirebaseOptions, "salt separator isn't base64")
+	}
+
+	p := &hasher.FirebaseScryptParams{SignerKey: key, SaltSeparator: separator, Rounds: o.Rounds, MemCost: o.MemCost}
+	switch p.Validate() {
+	case hasher.ErrMalformedHash:
+		return nil, errors.Wrap(ErrInvalidFirebaseOptions, "rounds and mem cost are required")
+	case hasher.ErrCostExceeded:
+		return nil, errors.Wrapf(
+			ErrInvalidFirebaseOptions,
+			"rounds and mem cost can't exceed %d and %d",
+			hasher.MaxFirebaseRounds,
+			hasher.MaxFirebaseMemCost,
+		)
+	}
+
+	return p, nil
+}
+
+// recordError is the problem of the record, it's reported and the import goes on.
+type recordError struct {
+	error
+}
+
+func invalid(format string, args ...interface{}) error {
+	return &recordError{errors.Errorf(format, args...)}
+}
+
+// importer keeps the emails, the usernames and the external ids of the file to find the duplicates.
+type importer struct {
+	*Service
+	space       *entity.Space
+	app         *entity.Application
+	firebase    *hasher.FirebaseScryptParams
+	dryRun      bool
+	emails      map[string]bool
+	usernames   map[string]bool
+	externalIDs map[string]bool
+}
+
+// add checks the record and creates the user with the identities, nothing is created if the record is invalid.
+func (im *importer) add(ctx context.Context, rec *record) error {
+	rec.Email = strings.TrimSpace(rec.Email)
+	if rec.Email == "" && len(rec.Identities) == 0 {
+		return invalid("email or identities are required")
+	}
+	if rec.Email == "" && rec.Password != nil {
+		return invalid("password requires email")
+	}
+
+	provider := im.space.DefaultIDProvider()
+	if rec.Email != "" {
+		if addr, err := mail.ParseAddress(rec.Email); err != nil || addr.Address != rec.Email {
+			return invalid("invalid email")
+		}
+		if im.emails[strings.ToLower(rec.Email)] {
+			return invalid("duplicate email in the file")
+		}
+
+		other, err := im.UserIdentities.FindByProviderAndExternalID(ctx, provider.ID, rec.Email)
+		if err != nil {
+			return err
+		}
+		if other != nil {
+			return errSkipped
+		}
+	}
+
+	if rec.Username != "" && im.space.UniqueUsernames {
+		if im.usernames[rec.Username] {
+			return invalid("duplicate username in the file")
+		}
+		free, err := im.usernameFree(ctx, rec.Username)
+		if err != nil {
+			return err
+		}
+		if !free {
+			return invalid("username is taken")
+		}
+	}
+
+	roles, err := im.roles(rec.Roles)
+	if err != nil {
+		return err
+	}
+
+	var credential string
+	if rec.Password != nil {
+		if credential, err = im.credential(rec.Password); err != nil {
+			return err
+		}
+	}
+
+	identities, err := im.identities(ctx, rec.Identities)
+	if err != nil {
+		return err
+	}
+
+	if rec.Email != "" {
+		im.emails[strings.ToLower(rec.Email)] = true
+	}
+	if rec.Username != "" {
+		im.usernames[rec.Username] = true
+	}
+	for _, i := range identities {
+		im.externalIDs[string(i.IdentityProviderID)+":"+i.ExternalID] = true
+	}
+
+	if im.dryRun {
+		return nil
+	}
+
+	return im.create(ctx, rec, roles, credential, identities)
+}
+
+func (im *importer) create(ctx context.Context, rec *record, roles []string, credential string, identities []*entity.UserIdentity) error {
+	now := time.Now()
+	user := &entity.User{
+		SpaceID:       im.space.ID,
+		AppID:         im.app.ID,
+		Roles:         roles,
+		Email:         rec.Email,
+		EmailVerified: rec.EmailVerified,
+		PhoneNumber:   rec.PhoneNumber,
+		Username:      rec.Username,
+		Name:          rec.Name,
+		Picture:       rec.Picture,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	if err := im.Users.Create(ctx, user); err != nil {
+		return errors.Wrap(err, "unable to create user")
+	}
+
+	// INFO: The identity of the email is created without the password too, so the password may be set by the reset
+	if rec.Email != "" {
+		identities = append([]*entity.UserIdentity{{
+			IdentityProviderID: im.space.DefaultIDProvider().ID,
+			ExternalID:         rec.Email,
+			Credential:         credential,
+			Email:              rec.Email,
+			Username:           rec.Username,
+			Name:               rec.Name,
+			Picture:            rec.Picture,
+		}}, identities...)
+	}
+
+	for _, i := range identities {
+		i.UserID = user.ID
+		i.AppID = im.app.ID
+		i.CreatedAt = now
+		i.UpdatedAt = now
+		if err := im.UserIdentities.Create(ctx, i); err != nil {
+			return errors.Wrap(err, "unable to create user identity")
+		}
+	}
+
+	return nil
+}
+
+// usernameFree reports whether no user of the space has the username, the exact username is the first one
+// of the users sorted by the username among the ones with the prefix.
+func (im *importer) usernameFree(ctx context.Context, username string) (bool, error) {
+	users, _, err := im.Users.Find(ctx, repository.UserQuery{
+		Page:     repository.Page{Limit: 1, Sort: "username"},
+		SpaceIDs: []entity.SpaceID{im.space.ID},
+		Username: username,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return len(users) == 0 || users[0].Username != username, nil
+}
+
+// roles returns the roles of the record, the default role of the space is given to the user without roles.
+func (im *importer) roles(roles []string) ([]string, error) {
+	if len(roles) == 0 {
+		return []string{im.space.DefaultRole}, nil
+	}
+
+	for _, role := range roles {
+		known := false
+		for i := range im.space.Roles {
+			known = known || im.space.Roles[i] == role
+		}
+		if !known {
+			return nil, invalid("unknown role %q", role)
+		}
+	}
+
+	return roles, nil
+}
+
+func (im *importer) credential(p *password) (string, error) {
+	c, err := credential(p, im.firebase)
+	if err != nil {
+		return "", &recordError{err}
+	}
+	return c, nil
+}
+
+// identities returns the identities of the social providers of the space, the account can't be linked
+// to the other user.
+func (im *importer) identities(ctx context.Context, list []identity) ([]*entity.UserIdentity, error) {
+	var result []*entity.UserIdentity
+	providers := map[string]bool{}
+	for _, i := range list {
+		provider, ok := im.space.IDProviderName(i.Provider)
+		if !ok || !provider.IsSocial() {
+			return nil, invalid("unknown social provider %q", i.Provider)
+		}
+		if providers[i.Provider] {
+			return nil, invalid("only one %s identity may be linked", i.Provider)
+		}
+		providers[i.Provider] = true
+		if i.ExternalID == "" {
+			return nil, invalid("external id of the %s identity is required", i.Provider)
+		}
+		if im.externalIDs[string(provider.ID)+":"+i.ExternalID] {
+			return nil, invalid("duplicate %s identity %s in the file", i.Provider, i.ExternalID)
+		}
+
+		other, err := im.UserIdentities.FindByProviderAndExternalID(ctx, provider.ID, i.ExternalID)
+		if err != nil {
+			return nil, err
+		}
+		if other != nil {
+			return nil, invalid("%s identity %s is linked to other user", i.Provider, i.ExternalID)
+		}
+
+		result = append(result, &entity.UserIdentity{
+			IdentityProviderID: provider.ID,
+			ExternalID:         i.ExternalID,
+			Email:              i.Email,
+			Username:           i.Username,
+			Name:               i.Name,
+			Picture:            i.Picture,
+		})
+	}
+
+	return result, nil
+}
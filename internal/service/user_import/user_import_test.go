@@ -0,0 +1,268 @@
+package user_import
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/repository"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/service"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/hasher"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type users map[entity.UserID]entity.User
+
+func (r users) Create(ctx context.Context, u *entity.User) error {
+	u.ID = entity.UserID("user" + string(rune('a'+len(r))))
+	r[u.ID] = *u
+	return nil
+}
+func (r users) Update(ctx context.Context, u *entity.User) error { r[u.ID] = *u; return nil }
+func (r users) Find(ctx context.Context, query repository.UserQuery) ([]*entity.User, int, error) {
+	var result []*entity.User
+	for _, u := range r {
+		if strings.HasPrefix(u.Username, query.Username) {
+			u := u
+			result = append(result, &u)
+		}
+	}
+	return result, len(result), nil
+}
+func (r users) FindByID(ctx context.Context, id entity.UserID) (*entity.User, error) {
+	if u, ok := r[id]; ok {
+		return &u, nil
+	}
+	return nil, nil
+}
+
+type identities map[entity.UserIdentityID]entity.UserIdentity
+
+func (r identities) Create(ctx context.Context, i *entity.UserIdentity) error {
+	i.ID = entity.UserIdentityID("identity" + string(rune('a'+len(r))))
+	r[i.ID] = *i
+	return nil
+}
+func (r identities) Update(ctx context.Context, i *entity.UserIdentity) error {
+	r[i.ID] = *i
+	return nil
+}
+func (r identities) FindByID(ctx context.Context, id entity.UserIdentityID) (*entity.UserIdentity, error) {
+	return nil, nil
+}
+func (r identities) FindByProviderAndUser(ctx context.Context, pid entity.IdentityProviderID, uid entity.UserID) (*entity.UserIdentity, error) {
+	return r.find(func(i entity.UserIdentity) bool { return i.IdentityProviderID == pid && i.UserID == uid })
+}
+func (r identities) FindByProviderAndExternalID(ctx context.Context, pid entity.IdentityProviderID, externalID string) (*entity.UserIdentity, error) {
+	return r.find(func(i entity.UserIdentity) bool { return i.IdentityProviderID == pid && i.ExternalID == externalID })
+}
+func (r identities) FindForUser(ctx context.Context, uid entity.UserID) ([]*entity.UserIdentity, error) {
+	return nil, nil
+}
+func (r identities) find(match func(i entity.UserIdentity) bool) (*entity.UserIdentity, error) {
+	for _, i := range r {
+		if match(i) {
+			return &i, nil
+		}
+	}
+	return nil, nil
+}
+
+type apps struct {
+	repository.ApplicationRepository
+}
+
+func (apps) Find(ctx context.Context, query repository.ApplicationQuery) ([]*entity.Application, int, error) {
+	return []*entity.Application{{ID: "app", SpaceID: "space"}}, 1, nil
+}
+
+type importTest struct {
+	users      users
+	identities identities
+	s          service.UserImportService
+}
+
+func newImportTest() *importTest {
+	space := &entity.Space{
+		ID:              "space",
+		UniqueUsernames: true,
+		Roles:           []string{"user", "admin"},
+		DefaultRole:     "user",
+		IdentityProviders: entity.IdentityProviders{
+			{ID: "initial", Type: entity.IDProviderTypePassword, Name: entity.IDProviderNameDefault},
+			{ID: "github", Type: entity.IDProviderTypeSocial, Name: "github"},
+		},
+	}
+	test := &importTest{
+		users: users{
+			"taken": {ID: "taken", SpaceID: space.ID, Email: "taken@example.com", Username: "taken"},
+		},
+		identities: identities{
+			"taken":  {ID: "taken", UserID: "taken", IdentityProviderID: "initial", ExternalID: "taken@example.com"},
+			"linked": {ID: "linked", UserID: "taken", IdentityProviderID: "github", ExternalID: "100"},
+		},
+	}
+	test.s = New(ServiceParams{
+		Users:          test.users,
+		UserIdentities: test.identities,
+		Spaces:         repository.OneSpaceRepo(space),
+		Apps:           apps{},
+	})
+	return test
+}
+
+func (test *importTest) run(t *testing.T, format, data string, dryRun bool) *service.ImportUsersResult {
+	result, err := test.s.Import(context.Background(), strings.NewReader(data), service.ImportUsersOptions{
+		SpaceID: "space",
+		Format:  format,
+		DryRun:  dryRun,
+	})
+	assert.Nil(t, err)
+	return result
+}
+
+func (test *importTest) identity(externalID string) *entity.UserIdentity {
+	for _, i := range test.identities {
+		if i.ExternalID == externalID {
+			return &i
+		}
+	}
+	return nil
+}
+
+func TestImportJSONLCreatesUsersWithIdentities(t *testing.T) {
+	test := newImportTest()
+	digest := md5.Sum([]byte("saltpassword"))
+
+	result := test.run(t, service.ImportFormatJSONL, `
+{"email": "a@example.com", "username": "alice", "email_verified": true, "password": {"algorithm": "md5", "hash": "`+hex.EncodeToString(digest[:])+`", "salt": "salt", "salt_first": true}, "identities": [{"provider": "github", "external_id": "200"}]}
+{"identities": [{"provider": "github", "external_id": "300"}]}
+`, false)
+
+	assert.Equal(t, &service.ImportUsersResult{Created: 2}, result)
+	assert.Len(t, test.users, 3)
+
+	i := test.identity("a@example.com")
+	if assert.NotNil(t, i) {
+		assert.Equal(t, entity.IdentityProviderID("initial"), i.IdentityProviderID)
+		assert.Equal(t, entity.AppID("app"), i.AppID)
+		assert.Nil(t, hasher.Verify(i.Credential, "password"))
+
+		usr := test.users[i.UserID]
+		assert.Equal(t, "alice", usr.Username)
+		assert.True(t, usr.EmailVerified)
+		assert.Equal(t, []string{"user"}, usr.Roles)
+
+		social := test.identity("200")
+		if assert.NotNil(t, social) {
+			assert.Equal(t, i.UserID, social.UserID)
+			assert.Equal(t, entity.IdentityProviderID("github"), social.IdentityProviderID)
+		}
+	}
+	assert.NotNil(t, test.identity("300"))
+}
+
+func TestImportCSV(t *testing.T) {
+	test := newImportTest()
+
+	result := test.run(t, service.ImportFormatCSV, "email,roles,password_algorithm,password_hash,password_salt,password_iterations,identities\n"+
+		"a@example.com,admin;user,pbkdf2-sha256,YywoEuRtRgQQK6dhjp1tfS+BKPYma0oDJk0qBGC33LM=,salt,1000,github:200\n"+
+		"b@example.com,,,,,,\n", false)
+
+	assert.Equal(t, &service.ImportUsersResult{Created: 2}, result)
+
+	i := test.identity("a@example.com")
+	if assert.NotNil(t, i) {
+		assert.Equal(t, []string{"admin", "user"}, test.users[i.UserID].Roles)
+		assert.True(t, strings.HasPrefix(i.Credential, "$pbkdf2-sha256$i=1000$"))
+	}
+	assert.NotNil(t, test.identity("200"))
+	assert.Equal(t, "", test.identity("b@example.com").Credential)
+}
+
+func TestImportSkipsRegisteredEmails(t *testing.T) {
+	test := newImportTest()
+
+	result := test.run(t, service.ImportFormatJSONL, `{"email": "taken@example.com"}`, false)
+
+	assert.Equal(t, &service.ImportUsersResult{Skipped: 1}, result)
+	assert.Len(t, test.users, 1)
+}
+
+func TestImportReportsInvalidRecords(t *testing.T) {
+	test := newImportTest()
+
+	result := test.run(t, service.ImportFormatJSONL, `{"email": "a@example.com"}
+{"email": "a@example.com"}
+{"email": "invalid"}
+{"email": "b@example.com", "username": "taken"}
+{"email": "c@example.com", "roles": ["root"]}
+{"email": "d@example.com", "password": {"algorithm": "rot13", "hash": "x"}}
+{"email": "e@example.com", "identities": [{"provider": "github", "external_id": "100"}]}
+{"email": "f@example.com", "identities": [{"provider": "initial", "external_id": "1"}]}
+{"password": {"algorithm": "bcrypt", "hash": "x"}, "identities": [{"provider": "github", "external_id": "1"}]}
+{}
+not json
+`, false)
+
+	assert.Equal(t, 1, result.Created)
+	var lines []int
+	for _, e := range result.Errors {
+		lines = append(lines, e.Line)
+	}
+	assert.Equal(t, []int{2, 3, 4, 5, 6, 7, 8, 9, 10, 11}, lines)
+	assert.Len(t, test.users, 2)
+}
+
+func TestImportDryRunDoesNotCreateUsers(t *testing.T) {
+	test := newImportTest()
+
+	result := test.run(t, service.ImportFormatJSONL, `{"email": "a@example.com"}
+{"email": "a@example.com"}
+`, true)
+
+	assert.Equal(t, 1, result.Created)
+	assert.Len(t, result.Errors, 1)
+	assert.Len(t, test.users, 1)
+	assert.Len(t, test.identities, 2)
+}
+
+func TestImportFirebaseRequiresSignerKey(t *testing.T) {
+	test := newImportTest()
+
+	result := test.run(t, service.ImportFormatJSONL, `{"email": "a@example.com", "password": {"algorithm": "firebase-scrypt", "hash": "aGFzaA==", "salt": "c2FsdA=="}}`, false)
+
+	assert.Equal(t, 0, result.Created)
+	assert.Len(t, result.Errors, 1)
+}
+
+func TestImportConvertsDjangoPBKDF2(t *testing.T) {
+	c, err := credential(&password{
+		Algorithm: hasher.AlgorithmPBKDF2SHA256,
+		Hash:      "pbkdf2_sha256$1000$salt$YywoEuRtRgQQK6dhjp1tfS+BKPYma0oDJk0qBGC33LM=",
+	}, nil)
+
+	assert.Nil(t, err)
+	assert.Nil(t, hasher.Verify(c, "password"))
+}
+
+func TestImportRefusesCostlyHashes(t *testing.T) {
+	test := newImportTest()
+
+	result := test.run(t, service.ImportFormatJSONL, `{"email": "a@example.com", "password": {"algorithm": "pbkdf2-sha256", "hash": "pbkdf2_sha256$100000000$salt$YywoEuRtRgQQK6dhjp1tfS+BKPYma0oDJk0qBGC33LM="}}
+{"email": "b@example.com", "password": {"algorithm": "argon2id", "hash": "$argon2id$v=19$m=4194304,t=1,p=1$c2FsdA$a2V5"}}
+{"email": "c@example.com", "password": {"algorithm": "phpass", "hash": "$P$SIQRaTwmfeRo7ud9Fh4E2PdI0S3r.L0"}}
+`, false)
+
+	assert.Equal(t, 0, result.Created)
+	if assert.Len(t, result.Errors, 3) {
+		assert.Equal(t, "cost of the pbkdf2-sha256 hash exceeds the bounds", result.Errors[0].Error)
+	}
+
+	_, err := firebaseParams(service.FirebaseScryptOptions{SignerKey: "a2V5", Rounds: 8, MemCost: 30})
+	assert.Equal(t, ErrInvalidFirebaseOptions, errors.Cause(err))
+}
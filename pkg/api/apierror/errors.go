@@ -11,26 +11,36 @@ const (
 )
 
 var (
-	unknown            = New(1000, "unknown", http.StatusInternalServerError)
-	invalidRequest     = New(1001, "invalid_request", http.StatusBadRequest)
-	invalidParameters  = New(1002, "invalid_parameters", http.StatusBadRequest)
-	InvalidChallenge   = New(1003, "invalid_challenge", http.StatusBadRequest).WithParam("challenge")
-	InvalidToken       = New(1004, "invalid_token", http.StatusBadRequest).WithParam("token")
-	InvalidClient      = New(1005, "invalid_client", http.StatusBadRequest)
-	EmailNotFound      = New(1006, "email_not_found", http.StatusBadRequest).WithParam("email")
-	InvalidCredentials = New(1007, "invalid_credentials", http.StatusBadRequest)
-	UsernameTaken      = New(1008, "username_already_exists", http.StatusBadRequest).WithParam("username")
-	WeakPassword       = New(1009, "password_does_not_meet_policy", http.StatusBadRequest).WithParam("password")
-	EmailRegistered    = New(1010, "email_already_registered", http.StatusBadRequest).WithParam("email")
-	MissingCSRFToken   = New(1011, "missing_csrf_token", http.StatusBadRequest).WithParam("x-xsrf-token")
-	InvalidCSRFToken   = New(1012, "invalid_csrf_token", http.StatusForbidden).WithParam("x-xsrf-token")
-	MethodNotAllowed   = New(1013, "method_not_allowed", http.StatusMethodNotAllowed)
-	NotFound           = New(1014, "not_found", http.StatusNotFound)
-	CaptchaRequired    = New(1015, "captcha_required", http.StatusForbidden)
-	UnknownCaptchaType = New(1016, "unknown_captcha_type", http.StatusBadRequest)
-	TokenOutdated      = New(1017, "token_outdated", http.StatusForbidden)
-	AlreadyLinked      = New(1018, "already_linked", http.StatusConflict)
-	Unauthorized       = New(1019, "unauthorized", http.StatusUnauthorized)
+	unknown                   = New(1000, "unknown", http.StatusInternalServerError)
+	invalidRequest            = New(1001, "invalid_request", http.StatusBadRequest)
+	invalidParameters         = New(1002, "invalid_parameters", http.StatusBadRequest)
+	InvalidChallenge          = New(1003, "invalid_challenge", http.StatusBadRequest).WithParam("challenge")
+	InvalidToken              = New(1004, "invalid_token", http.StatusBadRequest).WithParam("token")
+	InvalidClient             = New(1005, "invalid_client", http.StatusBadRequest)
+	EmailNotFound             = New(1006, "email_not_found", http.StatusBadRequest).WithParam("email")
+	InvalidCredentials        = New(1007, "invalid_credentials", http.StatusBadRequest)
+	UsernameTaken             = New(1008, "username_already_exists", http.StatusBadRequest).WithParam("username")
+	WeakPassword              = New(1009, "password_does_not_meet_policy", http.StatusBadRequest).WithParam("password")
+	EmailRegistered           = New(1010, "email_already_registered", http.StatusBadRequest).WithParam("email")
+	MissingCSRFToken          = New(1011, "missing_csrf_token", http.StatusBadRequest).WithParam("x-xsrf-token")
+	InvalidCSRFToken          = New(1012, "invalid_csrf_token", http.StatusForbidden).WithParam("x-xsrf-token")
+	MethodNotAllowed          = New(1013, "method_not_allowed", http.StatusMethodNotAllowed)
+	NotFound                  = New(1014, "not_found", http.StatusNotFound)
+	CaptchaRequired           = New(1015, "captcha_required", http.StatusForbidden)
+	UnknownCaptchaType        = New(1016, "unknown_captcha_type", http.StatusBadRequest)
+	TokenOutdated             = New(1017, "token_outdated", http.StatusForbidden)
+	AlreadyLinked             = New(1018, "already_linked", http.StatusConflict)
+	Unauthorized              = New(1019, "unauthorized", http.StatusUnauthorized)
+	InvalidCode               = New(1020, "invalid_verification_code", http.StatusBadRequest).WithParam("verification_code")
+	InvalidConnection         = New(1021, "invalid_connection", http.StatusBadRequest).WithParam("connection")
+	MfaRequired               = New(1022, "mfa_required", http.StatusForbidden)
+	InvalidWebAuthnCredential = New(1023, "invalid_webauthn_credential", http.StatusBadRequest).WithParam("credential")
+	Forbidden                 = New(1024, "forbidden", http.StatusForbidden)
+	UserBlocked               = New(1025, "user_blocked", http.StatusForbidden)
+	EmailNotVerified          = New(1026, "email_not_verified", http.StatusForbidden)
+	VerificationThrottled     = New(1027, "verification_email_throttled", http.StatusTooManyRequests)
+	AccountLocked             = New(1028, "account_locked", http.StatusForbidden)
+	TooManyLoginAttempts      = New(1029, "too_many_login_attempts", http.StatusTooManyRequests)
 )
 
 func New(code int, message string, status int) *APIError {
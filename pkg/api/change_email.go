@@ -0,0 +1,117 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
+	domain "github.com/ProtocolONE/auth1.protocol.one/internal/domain/service"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/service/email_change"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/service/password_manager"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/service/user"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/api/apierror"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/service"
+	"github.com/labstack/echo/v4"
+)
+
+func InitChangeEmail(cfg *Server) error {
+	ce := &ChangeEmail{EmailChange: cfg.EmailChange, Hydra: cfg.Registry.HydraAdminApi()}
+
+	g := cfg.Echo.Group("/api/email/change")
+	g.POST("", ce.Start)
+	g.POST("/confirm", ce.Confirm)
+	g.POST("/revert", ce.Revert)
+
+	return nil
+}
+
+type ChangeEmail struct {
+	EmailChange domain.EmailChangeService
+	Hydra       service.HydraAdminApi
+}
+
+// Start sends the confirmation link to the new address of the user identified by the bearer token.
+func (ce *ChangeEmail) Start(ctx echo.Context) error {
+	var form struct {
+		Password string `json:"password" form:"password" validate:"required"`
+		Email    string `json:"email" form:"email" validate:"required,email"`
+	}
+
+	if err := ctx.Bind(&form); err != nil {
+		return apierror.InvalidRequest(err)
+	}
+	if err := ctx.Validate(form); err != nil {
+		return apierror.InvalidParameters(err)
+	}
+
+	c, err := service.AuthenticateBearer(ctx.Request().Context(), ce.Hydra, ctx.Request().Header)
+	if err != nil {
+		return apierror.Unauthorized
+	}
+
+	err = ce.EmailChange.Start(ctx.Request().Context(), domain.StartEmailChangeData{
+		UserID:   entity.UserID(c.UserId.Hex()),
+		Password: form.Password,
+		Email:    form.Email,
+	})
+	if err != nil {
+		return changeEmailError(err)
+	}
+
+	return ctx.JSON(http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (ce *ChangeEmail) Confirm(ctx echo.Context) error {
+	token, err := bindChangeEmailToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := ce.EmailChange.Confirm(ctx.Request().Context(), token); err != nil {
+		return changeEmailError(err)
+	}
+
+	return ctx.JSON(http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (ce *ChangeEmail) Revert(ctx echo.Context) error {
+	token, err := bindChangeEmailToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := ce.EmailChange.Revert(ctx.Request().Context(), token); err != nil {
+		return changeEmailError(err)
+	}
+
+	return ctx.JSON(http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func bindChangeEmailToken(ctx echo.Context) (string, error) {
+	var form struct {
+		Token string `json:"token" form:"token" validate:"required"`
+	}
+
+	if err := ctx.Bind(&form); err != nil {
+		return "", apierror.InvalidRequest(err)
+	}
+	if err := ctx.Validate(form); err != nil {
+		return "", apierror.InvalidParameters(err)
+	}
+
+	return form.Token, nil
+}
+
+func changeEmailError(err error) error {
+	switch err {
+	case password_manager.ErrPasswordMismatch:
+		return apierror.InvalidCredentials
+	case user.ErrEmailRegistered:
+		return apierror.EmailRegistered
+	case email_change.ErrInvalidEmail:
+		return apierror.InvalidParameters(err)
+	case email_change.ErrInvalidToken:
+		return apierror.InvalidToken
+	}
+
+	return err
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	domain "github.com/ProtocolONE/auth1.protocol.one/internal/domain/service"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/api/apierror"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/mocks"
+	"github.com/globalsign/mgo/bson"
+	"github.com/labstack/echo/v4"
+	"github.com/ory/hydra-client-go/client/admin"
+	models2 "github.com/ory/hydra-client-go/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type emailChangeService struct {
+	started []domain.StartEmailChangeData
+}
+
+func (s *emailChangeService) Start(ctx context.Context, data domain.StartEmailChangeData) error {
+	s.started = append(s.started, data)
+	return nil
+}
+
+func (s *emailChangeService) Confirm(ctx context.Context, token string) error {
+	return nil
+}
+
+func (s *emailChangeService) Revert(ctx context.Context, token string) error {
+	return nil
+}
+
+func startEmailChange(active bool) (*httptest.ResponseRecorder, *emailChangeService, string) {
+	userID := bson.NewObjectId().Hex()
+	h := &mocks.HydraAdminApi{}
+	h.On("IntrospectOAuth2Token", mock.MatchedBy(func(p *admin.IntrospectOAuth2TokenParams) bool {
+		return p.Token == "access_token"
+	}), mock.Anything).Return(&admin.IntrospectOAuth2TokenOK{Payload: &models2.OAuth2TokenIntrospection{
+		Active:    &active,
+		Sub:       userID,
+		TokenType: "access_token",
+	}}, nil)
+
+	ec := &emailChangeService{}
+	ce := &ChangeEmail{EmailChange: ec, Hydra: h}
+
+	e, rec := echo.New(), httptest.NewRecorder()
+	e.HTTPErrorHandler = apierror.Handler
+	registerCustomValidator(e)
+	e.POST("/api/email/change", ce.Start)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/email/change", strings.NewReader(`{"password":"password","email":"new@example.com"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set("Authorization", "Bearer access_token")
+	e.ServeHTTP(rec, req)
+
+	return rec, ec, userID
+}
+
+func TestChangeEmailStartAuthenticatesBearerToken(t *testing.T) {
+	rec, ec, userID := startEmailChange(true)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	if assert.Len(t, ec.started, 1) {
+		assert.Equal(t, userID, string(ec.started[0].UserID))
+		assert.Equal(t, "new@example.com", ec.started[0].Email)
+		assert.Equal(t, "password", ec.started[0].Password)
+	}
+}
+
+func TestChangeEmailStartReturnErrorWithInactiveBearerToken(t *testing.T) {
+	rec, ec, _ := startEmailChange(false)
+
+	assert.Equal(t, apierror.Unauthorized.Status, rec.Code)
+	assert.Empty(t, ec.started)
+}
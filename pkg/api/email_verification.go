@@ -0,0 +1,63 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/api/apierror"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/database"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/manager"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/models"
+	"github.com/labstack/echo/v4"
+)
+
+func InitEmailVerification(cfg *Server) error {
+	g := cfg.Echo.Group("/api/email", func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			db := c.Get("database").(database.MgoSession)
+			c.Set("email_verification_manager", manager.NewEmailVerificationManager(db, cfg.Registry, cfg.MailTemplates))
+
+			return next(c)
+		}
+	})
+
+	g.POST("/verify/start", emailVerificationStart)
+	g.POST("/verify", emailVerificationVerify)
+
+	return nil
+}
+
+func emailVerificationStart(ctx echo.Context) error {
+	form := new(models.EmailVerificationStartForm)
+	m := ctx.Get("email_verification_manager").(manager.EmailVerificationManagerInterface)
+
+	if err := ctx.Bind(form); err != nil {
+		return apierror.InvalidRequest(err)
+	}
+	if err := ctx.Validate(form); err != nil {
+		return apierror.InvalidParameters(err)
+	}
+
+	if err := m.EmailVerificationStart(ctx, form); err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func emailVerificationVerify(ctx echo.Context) error {
+	form := new(models.EmailVerificationForm)
+	m := ctx.Get("email_verification_manager").(manager.EmailVerificationManagerInterface)
+
+	if err := ctx.Bind(form); err != nil {
+		return apierror.InvalidRequest(err)
+	}
+	if err := ctx.Validate(form); err != nil {
+		return apierror.InvalidParameters(err)
+	}
+
+	if err := m.EmailVerificationVerify(form); err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK, map[string]string{"status": "ok"})
+}
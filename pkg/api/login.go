@@ -37,7 +37,7 @@ func (ctl *Login) check(ctx echo.Context) error {
 	}
 
 	db := ctx.Get("database").(database.MgoSession)
-	m := manager.NewOauthManager(db, ctl.cfg.Registry, ctl.cfg.SessionConfig, ctl.cfg.HydraConfig, ctl.cfg.ServerConfig, ctl.cfg.Recaptcha)
+	m := manager.NewOauthManager(db, ctl.cfg.Registry, ctl.cfg.SessionConfig, ctl.cfg.HydraConfig, ctl.cfg.ServerConfig, ctl.cfg.Recaptcha, ctl.cfg.MailTemplates)
 
 	url, err := m.CheckAuth(ctx, form)
 	if err != nil {
@@ -62,7 +62,7 @@ func (ctl *Login) login(ctx echo.Context) error {
 	}
 
 	db := ctx.Get("database").(database.MgoSession)
-	m := manager.NewOauthManager(db, ctl.cfg.Registry, ctl.cfg.SessionConfig, ctl.cfg.HydraConfig, ctl.cfg.ServerConfig, ctl.cfg.Recaptcha)
+	m := manager.NewOauthManager(db, ctl.cfg.Registry, ctl.cfg.SessionConfig, ctl.cfg.HydraConfig, ctl.cfg.ServerConfig, ctl.cfg.Recaptcha, ctl.cfg.MailTemplates)
 
 	url, err := m.Auth(ctx, form)
 	if err != nil {
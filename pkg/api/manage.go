@@ -3,15 +3,20 @@ package api
 import (
 	"fmt"
 	"net/http"
+	"strings"
 
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
+	domain "github.com/ProtocolONE/auth1.protocol.one/internal/domain/service"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/service/operator"
 	"github.com/ProtocolONE/auth1.protocol.one/pkg/api/apierror"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/appcore/log"
 	"github.com/ProtocolONE/auth1.protocol.one/pkg/database"
 	"github.com/ProtocolONE/auth1.protocol.one/pkg/helper"
 	"github.com/ProtocolONE/auth1.protocol.one/pkg/manager"
 	"github.com/ProtocolONE/auth1.protocol.one/pkg/models"
 	"github.com/ProtocolONE/auth1.protocol.one/pkg/service"
 	"github.com/labstack/echo/v4"
-	"github.com/labstack/echo/v4/middleware"
+	"go.uber.org/zap"
 )
 
 func InitManage(cfg *Server) error {
@@ -19,23 +24,71 @@ func InitManage(cfg *Server) error {
 		return func(c echo.Context) error {
 			db := c.Get("database").(database.MgoSession)
 			c.Set("manage_manager", manager.NewManageManager(db, cfg.Registry))
+			c.Set("audit", cfg.Audit)
 			return next(c)
 		}
-	}, middleware.BasicAuth(func(u, p string, ctx echo.Context) (bool, error) {
-		return u == "admin" && p == cfg.ServerConfig.ManageSecret, nil
-	}))
+	}, operatorAuth(cfg.Operators))
 
 	g.POST("/app", createApplication)
 	g.PUT("/app/:id", updateApplication)
 	g.GET("/app/:id", getApplication)
 	g.GET("/identity/templates", getIdentityProviderTemplates)
 	g.POST("/app/:id/ott", setOneTimeTokenSettings)
+	g.POST("/app/:id/webhook_secret", rotateWebHookSecret)
 	g.POST("/mfa", addMFA)
 	g.GET("/authlog", authlog)
 
 	return nil
 }
 
+// operatorAuth authenticates the operator by the session token in the bearer authorization
+// or by the login & password in the basic authorization, e.g. for scripts.
+// The management api isn't scoped by spaces, so it's available to the super admins only.
+func operatorAuth(operators domain.OperatorService) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			r := ctx.Request()
+
+			var err error
+			var op *entity.Operator
+			if login, password, ok := r.BasicAuth(); ok {
+				op, err = operators.CheckPassword(r.Context(), login, password, service.GetClientIP(ctx))
+			} else {
+				op, err = operators.Authenticate(r.Context(), strings.TrimPrefix(r.Header.Get(echo.HeaderAuthorization), "Bearer "))
+			}
+			if err == operator.ErrInvalidCredentials || err == operator.ErrInvalidSession {
+				ctx.Response().Header().Set(echo.HeaderWWWAuthenticate, `Bearer realm="Auth1"`)
+				return apierror.Unauthorized
+			}
+			if err == operator.ErrTooManyAttempts {
+				return apierror.TooManyLoginAttempts
+			}
+			if err != nil {
+				return err
+			}
+			if !op.IsSuperAdmin() {
+				return apierror.Forbidden
+			}
+
+			ctx.Set("operator", op)
+			return next(ctx)
+		}
+	}
+}
+
+// recordAudit appends the change made by the operator to the audit trail. The failure is only logged
+// because the change has been already stored.
+func recordAudit(ctx echo.Context, entry domain.AuditEntry) {
+	entry.Actor = ctx.Get("operator").(*entity.Operator)
+	entry.IP = ctx.RealIP()
+	entry.RequestID = ctx.Response().Header().Get(echo.HeaderXRequestID)
+
+	audit := ctx.Get("audit").(domain.AuditService)
+	if err := audit.Record(ctx.Request().Context(), entry); err != nil {
+		log.Error(ctx.Request().Context(), "Unable to record audit", zap.String("action", entry.Action), zap.Error(err))
+	}
+}
+
 // Manage
 func authlog(ctx echo.Context) error {
 	var req struct {
@@ -103,6 +156,14 @@ func createApplication(ctx echo.Context) error {
 		return ctx.HTML(http.StatusBadRequest, "Unable to create the application")
 	}
 
+	recordAudit(ctx, domain.AuditEntry{
+		Action:     "application.create",
+		TargetType: "application",
+		TargetID:   app.ID.Hex(),
+		SpaceID:    entity.SpaceID(app.SpaceId.Hex()),
+		After:      app,
+	})
+
 	return ctx.JSON(http.StatusOK, app)
 }
 
@@ -143,12 +204,29 @@ func updateApplication(ctx echo.Context) error {
 		return helper.JsonError(ctx, e)
 	}
 
+	// INFO: the application is cached by the service, so the state is copied before the update
+	before, err := m.GetApplication(ctx, id)
+	if err != nil {
+		ctx.Error(err.Err)
+		return ctx.HTML(http.StatusBadRequest, "Application not exists")
+	}
+	prev := *before
+
 	app, err := m.UpdateApplication(ctx, id, applicationForm)
 	if err != nil {
 		ctx.Error(err.Err)
 		return ctx.HTML(http.StatusBadRequest, "Unable to update the application")
 	}
 
+	recordAudit(ctx, domain.AuditEntry{
+		Action:     "application.update",
+		TargetType: "application",
+		TargetID:   id,
+		SpaceID:    entity.SpaceID(app.SpaceId.Hex()),
+		Before:     &prev,
+		After:      app,
+	})
+
 	return ctx.JSON(http.StatusOK, app)
 }
 
@@ -180,6 +258,17 @@ func addMFA(ctx echo.Context) error {
 		return ctx.HTML(http.StatusBadRequest, "Unable to create the application")
 	}
 
+	entry := domain.AuditEntry{
+		Action:     "application.mfa.add",
+		TargetType: "application",
+		TargetID:   app.AppID.Hex(),
+		After:      app,
+	}
+	if a, err := m.GetApplication(ctx, app.AppID.Hex()); err == nil {
+		entry.SpaceID = entity.SpaceID(a.SpaceId.Hex())
+	}
+	recordAudit(ctx, entry)
+
 	return ctx.JSON(http.StatusOK, app)
 }
 
@@ -211,10 +300,67 @@ func setOneTimeTokenSettings(ctx echo.Context) error {
 		return helper.JsonError(ctx, e)
 	}
 
+	app, err := m.GetApplication(ctx, id)
+	if err != nil {
+		ctx.Error(err.Err)
+		return ctx.HTML(http.StatusBadRequest, "Application not exists")
+	}
+	before := app.OneTimeTokenSettings
+
 	if err := m.SetOneTimeTokenSettings(ctx, id, form); err != nil {
 		ctx.Error(err.Err)
 		return ctx.HTML(http.StatusBadRequest, "Unable to set OneTimeToken settings for the application")
 	}
 
+	recordAudit(ctx, domain.AuditEntry{
+		Action:     "application.ott_settings.update",
+		TargetType: "application",
+		TargetID:   id,
+		SpaceID:    entity.SpaceID(app.SpaceId.Hex()),
+		Before:     before,
+		After:      form,
+	})
+
 	return ctx.HTML(http.StatusOK, "")
 }
+
+func rotateWebHookSecret(ctx echo.Context) error {
+	id := ctx.Param("id")
+	form := &models.WebHookSecretForm{Overlap: 86400} // default
+	m := ctx.Get("manage_manager").(*manager.ManageManager)
+
+	if err := ctx.Bind(form); err != nil {
+		e := &models.GeneralError{
+			Code:    BadRequiredCodeCommon,
+			Message: models.ErrorInvalidRequestParameters,
+		}
+		ctx.Error(err)
+		return helper.JsonError(ctx, e)
+	}
+
+	if err := ctx.Validate(form); err != nil {
+		e := &models.GeneralError{
+			Code:    fmt.Sprintf(BadRequiredCodeField, helper.GetSingleError(err).Field()),
+			Message: models.ErrorRequiredField,
+		}
+		ctx.Error(err)
+		return helper.JsonError(ctx, e)
+	}
+
+	app, err := m.RotateWebHookSecret(ctx, id, form)
+	if err != nil {
+		ctx.Error(err.Err)
+		return ctx.HTML(http.StatusBadRequest, "Unable to rotate the webhook secret")
+	}
+
+	recordAudit(ctx, domain.AuditEntry{
+		Action:     "application.webhook_secret.rotate",
+		TargetType: "application",
+		TargetID:   id,
+		SpaceID:    entity.SpaceID(app.SpaceId.Hex()),
+		Before:     map[string]string{"webhook_secret": app.WebHookPreviousSecret},
+		After:      map[string]string{"webhook_secret": app.WebHookSecret},
+	})
+
+	return ctx.JSON(http.StatusOK, app)
+}
@@ -15,7 +15,7 @@ func InitMFA(cfg *Server) error {
 	g := cfg.Echo.Group("/mfa", func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
 			db := c.Get("database").(database.MgoSession)
-			c.Set("mfa_manager", manager.NewMFAManager(db, cfg.Registry))
+			c.Set("mfa_manager", manager.NewMFAManager(db, cfg.Registry, cfg.MailTemplates))
 
 			return next(c)
 		}
@@ -97,22 +97,30 @@ func mfaChallenge(ctx echo.Context) error {
 	m := ctx.Get("mfa_manager").(*manager.MFAManager)
 
 	if err := ctx.Bind(form); err != nil {
+		e := &models.GeneralError{
+			Code:    BadRequiredCodeCommon,
+			Message: models.ErrorInvalidRequestParameters,
+		}
 		ctx.Error(err)
-		return ctx.HTML(http.StatusBadRequest, models.ErrorInvalidRequestParameters)
+		return ctx.JSON(http.StatusBadRequest, e)
 	}
 
 	if err := ctx.Validate(form); err != nil {
+		e := &models.GeneralError{
+			Code:    fmt.Sprintf(BadRequiredCodeField, helper.GetSingleError(err).Field()),
+			Message: models.ErrorRequiredField,
+		}
 		ctx.Error(err)
-		return ctx.HTML(http.StatusBadRequest, models.ErrorRequiredField)
+		return ctx.JSON(http.StatusBadRequest, e)
 	}
 
-	err := m.MFAChallenge(form)
+	challenge, err := m.MFAChallenge(ctx, form)
 	if err != nil {
 		ctx.Error(err.Err)
-		return ctx.HTML(http.StatusBadRequest, err.Message)
+		return ctx.JSON(http.StatusBadRequest, err)
 	}
 
-	return ctx.HTML(http.StatusNoContent, "")
+	return ctx.JSON(http.StatusOK, challenge)
 }
 
 func mfaVerify(ctx echo.Context) error {
@@ -137,13 +145,13 @@ func mfaVerify(ctx echo.Context) error {
 		return ctx.JSON(http.StatusBadRequest, e)
 	}
 
-	err := m.MFAVerify(ctx, form)
+	url, err := m.MFAVerify(ctx, form)
 	if err != nil {
 		ctx.Error(err.Err)
 		return ctx.JSON(http.StatusBadRequest, err)
 	}
 
-	return ctx.JSON(http.StatusOK, "")
+	return ctx.JSON(http.StatusOK, map[string]interface{}{"url": url})
 }
 
 func mfaAdd(ctx echo.Context) error {
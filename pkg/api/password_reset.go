@@ -209,10 +209,8 @@ func (pr *PasswordReset) userLogoutWebHook(ctx context.Context, ts *models.Chang
 		log.Error(ctx, "Cannot execute user.logout WebHook, error on getting app by id", zap.Error(err))
 		return
 	}
-	go func() {
-		err := pr.WebHooks.UserLogout(ctx, ts.Subject, app.WebHooks)
-		if err != nil {
-			log.Error(ctx, "Error on user.logout WebHook", zap.Error(err))
-		}
-	}()
+	err = pr.WebHooks.Publish(ctx, app.ID.Hex(), ts.Subject, app.WebHooks, webhooks.UserLogoutEvent{})
+	if err != nil {
+		log.Error(ctx, "Error on user.logout WebHook", zap.Error(err))
+	}
 }
@@ -1,18 +1,20 @@
 package api
 
 import (
-	"fmt"
-	"github.com/ProtocolONE/auth1.protocol.one/pkg/helper"
+	"net/http"
+
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/api/apierror"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/database"
 	"github.com/ProtocolONE/auth1.protocol.one/pkg/manager"
 	"github.com/ProtocolONE/auth1.protocol.one/pkg/models"
 	"github.com/labstack/echo/v4"
-	"net/http"
 )
 
 func InitPasswordLess(cfg *Server) error {
 	g := cfg.Echo.Group("/passwordless", func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
-			c.Set("passwordless_manager", manager.NewPasswordLessManager())
+			db := c.Get("database").(database.MgoSession)
+			c.Set("passwordless_manager", manager.NewPasswordLessManager(db, cfg.Registry, cfg.SessionConfig, cfg.MailTemplates))
 
 			return next(c)
 		}
@@ -26,31 +28,18 @@ func InitPasswordLess(cfg *Server) error {
 
 func passwordLessStart(ctx echo.Context) error {
 	form := new(models.PasswordLessStartForm)
-	m := ctx.Get("passwordless_manager").(*manager.PasswordLessManager)
+	m := ctx.Get("passwordless_manager").(manager.PasswordLessManagerInterface)
 
 	if err := ctx.Bind(form); err != nil {
-		e := &models.GeneralError{
-			Code:    BadRequiredCodeCommon,
-			Message: models.ErrorInvalidRequestParameters,
-		}
-		ctx.Error(err)
-		return ctx.JSON(http.StatusBadRequest, e)
+		return apierror.InvalidRequest(err)
 	}
-
 	if err := ctx.Validate(form); err != nil {
-		e := &models.GeneralError{
-			Code:    fmt.Sprintf(BadRequiredCodeField, helper.GetSingleError(err).Field()),
-			Message: models.ErrorRequiredField,
-		}
-
-		ctx.Error(err)
-		return ctx.JSON(http.StatusBadRequest, e)
+		return apierror.InvalidParameters(err)
 	}
 
-	token, err := m.PasswordLessStart(form)
+	token, err := m.PasswordLessStart(ctx, form)
 	if err != nil {
-		ctx.Error(err.Err)
-		return ctx.JSON(http.StatusBadRequest, err)
+		return err
 	}
 
 	return ctx.JSON(http.StatusOK, token)
@@ -58,31 +47,19 @@ func passwordLessStart(ctx echo.Context) error {
 
 func passwordLessVerify(ctx echo.Context) error {
 	form := new(models.PasswordLessVerifyForm)
-	m := ctx.Get("passwordless_manager").(*manager.PasswordLessManager)
+	m := ctx.Get("passwordless_manager").(manager.PasswordLessManagerInterface)
 
 	if err := ctx.Bind(form); err != nil {
-		e := &models.GeneralError{
-			Code:    BadRequiredCodeCommon,
-			Message: models.ErrorInvalidRequestParameters,
-		}
-		ctx.Error(err)
-		return ctx.JSON(http.StatusBadRequest, e)
+		return apierror.InvalidRequest(err)
 	}
-
 	if err := ctx.Validate(form); err != nil {
-		e := &models.GeneralError{
-			Code:    fmt.Sprintf(BadRequiredCodeField, helper.GetSingleError(err).Field()),
-			Message: models.ErrorRequiredField,
-		}
-		ctx.Error(err)
-		return ctx.JSON(http.StatusBadRequest, e)
+		return apierror.InvalidParameters(err)
 	}
 
-	err := m.PasswordLessVerify(form)
+	url, err := m.PasswordLessVerify(ctx, form)
 	if err != nil {
-		ctx.Error(err.Err)
-		return ctx.JSON(http.StatusBadRequest, err)
+		return err
 	}
 
-	return ctx.JSON(http.StatusOK, "")
+	return ctx.JSON(http.StatusOK, map[string]interface{}{"url": url})
 }
@@ -12,17 +12,20 @@ import (
 	"time"
 
 	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/repository"
+	domain "github.com/ProtocolONE/auth1.protocol.one/internal/domain/service"
 	"github.com/ProtocolONE/auth1.protocol.one/pkg/api/apierror"
 	"github.com/ProtocolONE/auth1.protocol.one/pkg/appcore"
 	"github.com/ProtocolONE/auth1.protocol.one/pkg/captcha"
 	"github.com/ProtocolONE/auth1.protocol.one/pkg/config"
 	"github.com/ProtocolONE/auth1.protocol.one/pkg/database"
 	"github.com/ProtocolONE/auth1.protocol.one/pkg/models"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/passwords"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/saml"
 	"github.com/ProtocolONE/auth1.protocol.one/pkg/service"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/webauthn"
 	"github.com/ProtocolONE/auth1.protocol.one/pkg/webhooks"
 
 	geoproto "github.com/ProtocolONE/geoip-service/pkg/proto"
-	"github.com/ProtocolONE/mfa-service/pkg/proto"
 	"github.com/boj/redistore"
 	"github.com/go-redis/redis"
 	"github.com/labstack/echo-contrib/session"
@@ -38,6 +41,9 @@ type ServerConfig struct {
 	// ApiConfig is common http setting for the application like a port, timeouts & etc.
 	ApiConfig *config.Server
 
+	// Proxy contains settings of the reverse proxies in front of the server.
+	Proxy *config.Proxy
+
 	// HydraConfig contains settings for the public and admin url of the Hydra application.
 	HydraConfig *config.Hydra
 
@@ -49,8 +55,8 @@ type ServerConfig struct {
 
 	GeoService geoproto.GeoIpService
 
-	// MfaService describes the interface for working with MFA micro-service.
-	MfaService proto.MfaService
+	// MfaService describes the interface for working with MFA backend (built-in or micro-service).
+	MfaService service.MfaApiInterface
 
 	// MgoSession describes the interface for working with Mongo session.
 	MgoSession database.MgoSession
@@ -64,6 +70,18 @@ type ServerConfig struct {
 	// Mailer contains settings for the postman service
 	Mailer *config.Mailer
 
+	// Sms contains settings for the sms sender
+	Sms *config.Sms
+
+	// WebAuthn contains settings of the relying party for passkeys
+	WebAuthn *config.WebAuthn
+
+	// Webhooks contains settings for the webhook delivery queue
+	Webhooks *config.Webhooks
+
+	// Operators contains settings for the operator sessions
+	Operators *config.Operators
+
 	// Recaptcha contains settings for recaptcha integration
 	Recaptcha *config.Recaptcha
 
@@ -72,6 +90,12 @@ type ServerConfig struct {
 
 	// Centrifugo contains centrifugo settings
 	Centrifugo *config.Centrifugo
+
+	// BreachedPasswords is the corpus of the breached passwords, nil if it isn't configured
+	BreachedPasswords passwords.Corpus
+
+	// SAMLKey is the key pair of the SAML service provider, nil if it isn't configured
+	SAMLKey *saml.KeyPair
 }
 
 // Server is the instance of the application
@@ -100,11 +124,26 @@ type Server struct {
 	// WebHooks is the web-hooks service
 	WebHooks *webhooks.WebHooks
 
+	// WebHooksDispatcher sends the queued web-hooks
+	WebHooksDispatcher *webhooks.Dispatcher
+
 	// MailTemplates
 	MailTemplates *config.MailTemplates
 
 	// Centrifugo
 	Centrifugo *config.Centrifugo
+
+	// WebAuthn is the relying party for passkeys
+	WebAuthn *webauthn.RelyingParty
+
+	// Operators authenticates the operators of the management api
+	Operators domain.OperatorService
+
+	// Audit records the changes made with the management api
+	Audit domain.AuditService
+
+	// EmailChange changes the email addresses of the users
+	EmailChange domain.EmailChangeService
 }
 
 // Template is used to display HTML pages.
@@ -116,28 +155,48 @@ type Template struct {
 func NewServer(
 	c *ServerConfig,
 	spaces repository.SpaceRepository,
+	deliveries repository.WebhookDeliveryRepository,
+	apps repository.ApplicationRepository,
+	operators domain.OperatorService,
+	audit domain.AuditService,
+	emails domain.EmailChangeService,
 ) (*Server, error) {
+	sms, err := service.NewSmsSender(c.Sms)
+	if err != nil {
+		return nil, err
+	}
+
+	wh := webhooks.NewWebhooks(deliveries)
 	registryConfig := &service.RegistryConfig{
 		MgoSession:        c.MgoSession,
 		HydraAdminApi:     c.HydraAdminApi,
 		MfaService:        c.MfaService,
 		RedisClient:       c.RedisClient,
 		Mailer:            service.NewMailer(c.Mailer),
+		SmsSender:         sms,
 		GeoIpService:      c.GeoService,
 		CentrifugoService: service.NewCentrifugoService(c.Centrifugo),
 		Spaces:            spaces,
+		WebHooks:          wh,
+		BreachedPasswords: c.BreachedPasswords,
+		SAMLKey:           c.SAMLKey,
 	}
 	server := &Server{
-		Echo:          echo.New(),
-		RedisHandler:  c.RedisClient,
-		ServerConfig:  c.ApiConfig,
-		SessionConfig: c.SessionConfig,
-		HydraConfig:   c.HydraConfig,
-		Registry:      service.NewRegistryBase(registryConfig),
-		Recaptcha:     captcha.NewRecaptcha(c.Recaptcha.Key, c.Recaptcha.Secret, c.Recaptcha.Hostname),
-		WebHooks:      webhooks.NewWebhooks(),
-		MailTemplates: c.MailTemplates,
-		Centrifugo:    c.Centrifugo,
+		Echo:               echo.New(),
+		RedisHandler:       c.RedisClient,
+		ServerConfig:       c.ApiConfig,
+		SessionConfig:      c.SessionConfig,
+		HydraConfig:        c.HydraConfig,
+		Registry:           service.NewRegistryBase(registryConfig),
+		Recaptcha:          captcha.NewRecaptcha(c.Recaptcha.Key, c.Recaptcha.Secret, c.Recaptcha.Hostname),
+		WebHooks:           wh,
+		WebHooksDispatcher: webhooks.NewDispatcher(deliveries, apps, c.Webhooks),
+		MailTemplates:      c.MailTemplates,
+		Centrifugo:         c.Centrifugo,
+		WebAuthn:           webauthn.NewRelyingParty(c.WebAuthn.RPID, c.WebAuthn.RPName, c.WebAuthn.Origins),
+		Operators:          operators,
+		Audit:              audit,
+		EmailChange:        emails,
 	}
 
 	t := &Template{
@@ -154,6 +213,7 @@ func NewServer(
 	// preprocessing middleware
 	s.Use(middleware.RequestID())
 	s.Use(service.DeviceID())
+	s.Use(service.ClientIP(c.Proxy.TrustedHops))
 	s.Use(contextMiddleware())
 
 	// TODO: Validate origins for each application by settings
@@ -207,6 +267,10 @@ func registerCustomValidator(e *echo.Echo) {
 }
 
 func (s *Server) Start(shutdown chan os.Signal) error {
+	dispatcherCtx, stopDispatcher := context.WithCancel(context.Background())
+	defer stopDispatcher()
+	go s.WebHooksDispatcher.Run(dispatcherCtx)
+
 	go func() {
 		err := s.Echo.Start(":" + strconv.Itoa(s.ServerConfig.Port))
 		if err != nil {
@@ -239,6 +303,9 @@ func (s *Server) setupRoutes() error {
 		InitLogin,
 		InitPasswordLess,
 		InitMFA,
+		InitWebAuthn,
+		InitEmailVerification,
+		InitChangeEmail,
 	}
 
 	for _, r := range routes {
@@ -4,7 +4,9 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
 	"github.com/ProtocolONE/auth1.protocol.one/pkg/api/apierror"
 	"github.com/ProtocolONE/auth1.protocol.one/pkg/captcha"
 	"github.com/ProtocolONE/auth1.protocol.one/pkg/config"
@@ -13,6 +15,7 @@ import (
 	"github.com/ProtocolONE/auth1.protocol.one/pkg/models"
 	"github.com/ProtocolONE/auth1.protocol.one/pkg/service"
 	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
 	"github.com/labstack/echo/v4"
 )
 
@@ -29,7 +32,9 @@ func InitSocial(cfg *Server) error {
 	// redirect based apis
 	cfg.Echo.GET("/api/providers/:name/forward", s.Forward, apierror.Redirect("/error"))
 	cfg.Echo.GET("/api/providers/:name/callback", s.Callback, apierror.Redirect("/error"))
+	cfg.Echo.POST("/api/providers/:name/callback", s.Callback, apierror.Redirect("/error"))
 	cfg.Echo.GET("/api/providers/:name/complete-auth", s.CompleteAuth, apierror.Redirect("/error"))
+	cfg.Echo.GET("/api/spaces/:id/saml/metadata", s.SAMLMetadata)
 
 	return nil
 }
@@ -41,6 +46,7 @@ type Social struct {
 	SessionConfig *config.Session
 	ServerConfig  *config.Server
 	Recaptcha     *captcha.Recaptcha
+	MailTemplates *config.MailTemplates
 }
 
 func NewSocial(cfg *Server) *Social {
@@ -49,6 +55,7 @@ func NewSocial(cfg *Server) *Social {
 		HydraConfig:   cfg.HydraConfig,
 		SessionConfig: cfg.SessionConfig,
 		ServerConfig:  cfg.ServerConfig,
+		MailTemplates: cfg.MailTemplates,
 	}
 }
 
@@ -61,7 +68,7 @@ func (s *Social) Signup(ctx echo.Context) error {
 	form := new(models.Oauth2SignUpForm)
 	var (
 		db = ctx.Get("database").(database.MgoSession)
-		m  = manager.NewOauthManager(db, s.registry, s.SessionConfig, s.HydraConfig, s.ServerConfig, s.Recaptcha)
+		m  = manager.NewOauthManager(db, s.registry, s.SessionConfig, s.HydraConfig, s.ServerConfig, s.Recaptcha, s.MailTemplates)
 	)
 
 	if err := ctx.Bind(form); err != nil {
@@ -79,7 +86,7 @@ func (s *Social) Signup(ctx echo.Context) error {
 func (s *Social) Link(ctx echo.Context) error {
 	var (
 		db = ctx.Get("database").(database.MgoSession)
-		m  = manager.NewOauthManager(db, s.registry, s.SessionConfig, s.HydraConfig, s.ServerConfig, s.Recaptcha)
+		m  = manager.NewOauthManager(db, s.registry, s.SessionConfig, s.HydraConfig, s.ServerConfig, s.Recaptcha, s.MailTemplates)
 	)
 
 	var form = new(models.Oauth2LoginSubmitForm)
@@ -162,12 +169,24 @@ func (s *Social) Callback(ctx echo.Context) error {
 	var (
 		name = ctx.Param("name")
 		req  struct {
-			Code  string `query:"code"`
-			State string `query:"state"`
-			Error string `query:"error"`
+			Code  string `query:"code" form:"code"`
+			State string `query:"state" form:"state"`
+			Error string `query:"error" form:"error"`
+			// User is posted by Apple with the name on the first login only
+			User string `form:"user"`
+			// SAMLResponse and RelayState are posted by the SAML providers
+			SAMLResponse string `form:"SAMLResponse"`
+			RelayState   string `form:"RelayState"`
+			// OpenIDMode is the mode of the OpenID 2.0 response of the Steam providers
+			OpenIDMode string `query:"openid.mode"`
 		}
 		domain = fmt.Sprintf("%s://%s", ctx.Scheme(), ctx.Request().Host)
+		// INFO: The response posted by Apple and the SAML providers isn't posted again to the next page
+		redirect = http.StatusTemporaryRedirect
 	)
+	if ctx.Request().Method == http.MethodPost {
+		redirect = http.StatusSeeOther
+	}
 
 	db := ctx.Get("database").(database.MgoSession)
 	m := manager.NewLoginManager(db, s.registry)
@@ -176,21 +195,35 @@ func (s *Social) Callback(ctx echo.Context) error {
 		return apierror.InvalidRequest(err)
 	}
 
-	if req.Error != "" {
+	if req.Error != "" || req.OpenIDMode == "cancel" {
 		s, err := manager.DecodeState(req.State)
 		if err != nil {
 			return err
 		}
-		return ctx.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("/sign-in?login_challenge=%s", s.Challenge))
+		return ctx.Redirect(redirect, fmt.Sprintf("/sign-in?login_challenge=%s", s.Challenge))
 	}
 
 	// if launcher token with login_challenge key exists, then return to launcher
-	state, err := manager.DecodeState(req.State)
+	var (
+		state *manager.State
+		err   error
+	)
+	if req.SAMLResponse != "" {
+		state, err = m.SAMLState(req.RelayState)
+		req.Code = req.SAMLResponse
+	} else {
+		state, err = manager.DecodeState(req.State)
+	}
 	if err != nil {
 		return err
 	}
+	if req.OpenIDMode != "" {
+		// INFO: The whole query is verified by Steam, not the only code
+		req.Code = ctx.QueryString()
+	}
+	state.AppleUser = req.User
 
-	ui, uis, err := m.GetUserIdentities(state.Challenge, name, domain, req.Code)
+	ui, uis, err := m.GetUserIdentities(state, name, domain, req.Code)
 	if err != nil && err != mgo.ErrNotFound {
 		return err
 	}
@@ -211,7 +244,7 @@ func (s *Social) Callback(ctx echo.Context) error {
 		if err != nil {
 			return err
 		}
-		return ctx.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("/social-sign-in-confirm?login_challenge=%s&name=%s", state.Challenge, name))
+		return ctx.Redirect(redirect, fmt.Sprintf("/social-sign-in-confirm?login_challenge=%s&name=%s", state.Challenge, name))
 	}
 
 	// For Web
@@ -229,10 +262,35 @@ func (s *Social) Callback(ctx echo.Context) error {
 	//	return err
 	//}
 	url, err := s.accept(ctx, m, ui, uis, name, domain, state.Challenge)
+	if err != nil {
+		return mfaRedirect(ctx, redirect, state.Challenge, err)
+	}
+	return ctx.Redirect(redirect, url)
+}
+
+// SAMLMetadata returns the metadata of the SAML service provider of the space, it is registered
+// in the SAML identity providers.
+func (s *Social) SAMLMetadata(ctx echo.Context) error {
+	var (
+		spaceID = ctx.Param("id")
+		domain  = fmt.Sprintf("%s://%s", ctx.Scheme(), ctx.Request().Host)
+	)
+
+	if !bson.IsObjectIdHex(spaceID) {
+		return ctx.NoContent(http.StatusNotFound)
+	}
+
+	ips := service.NewAppIdentityProviderService(s.registry.Spaces())
+	sp := service.SAMLServiceProvider(domain, entity.SpaceID(spaceID), s.registry.SAMLKey())
+	data, err := ips.GetSAMLMetadata(ctx.Request().Context(), domain, sp, entity.SpaceID(spaceID))
+	if err == mgo.ErrNotFound {
+		return ctx.NoContent(http.StatusNotFound)
+	}
 	if err != nil {
 		return err
 	}
-	return ctx.Redirect(http.StatusTemporaryRedirect, url)
+
+	return ctx.Blob(http.StatusOK, "application/samlmetadata+xml", data)
 }
 
 func (s *Social) Profile(ctx echo.Context) error {
@@ -388,12 +446,25 @@ func (s *Social) CompleteAuth(ctx echo.Context) error {
 
 	url, err := m.Accept(ctx, t.UserIdentity, t.Name, t.Challenge)
 	if err != nil {
-		return err
+		return mfaRedirect(ctx, http.StatusTemporaryRedirect, t.Challenge, err)
 	}
 
 	return ctx.Redirect(http.StatusTemporaryRedirect, url)
 }
 
+// mfaRedirect sends the user to the page of the second factor with the mfa token if the login requires mfa,
+// the other errors are returned as is.
+func mfaRedirect(ctx echo.Context, code int, challenge string, err error) error {
+	var e *apierror.APIError
+	if errors.As(err, &e) && e.Code == apierror.MfaRequired.Code {
+		if rsp, ok := e.Data.(*models.MfaRequiredResponse); ok {
+			v := url.Values{"login_challenge": {challenge}, "mfa_token": {rsp.Token}}
+			return ctx.Redirect(code, "/sign-in/mfa?"+v.Encode())
+		}
+	}
+	return err
+}
+
 func (s *Social) accept(ctx echo.Context, m manager.LoginManagerInterface, ui *models.UserIdentity, uis *models.UserIdentitySocial, name, domain, challenge string) (string, error) {
 	if ui != nil {
 		// accept login and redirect
@@ -0,0 +1,108 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/api/apierror"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/database"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/manager"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/models"
+	"github.com/labstack/echo/v4"
+)
+
+func InitWebAuthn(cfg *Server) error {
+	g := cfg.Echo.Group("/api/webauthn", func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			db := c.Get("database").(database.MgoSession)
+			c.Set("webauthn_manager", manager.NewWebAuthnManager(db, cfg.Registry, cfg.SessionConfig, cfg.WebAuthn))
+
+			return next(c)
+		}
+	})
+
+	g.POST("/register/begin", webAuthnRegisterBegin)
+	g.POST("/register/finish", webAuthnRegisterFinish)
+	g.POST("/login/begin", webAuthnLoginBegin)
+	g.POST("/login/finish", webAuthnLoginFinish)
+
+	return nil
+}
+
+func webAuthnRegisterBegin(ctx echo.Context) error {
+	form := new(models.WebAuthnRegisterBeginForm)
+	m := ctx.Get("webauthn_manager").(manager.WebAuthnManagerInterface)
+
+	if err := ctx.Bind(form); err != nil {
+		return apierror.InvalidRequest(err)
+	}
+	if err := ctx.Validate(form); err != nil {
+		return apierror.InvalidParameters(err)
+	}
+
+	resp, err := m.RegisterBegin(ctx, form)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+func webAuthnRegisterFinish(ctx echo.Context) error {
+	form := new(models.WebAuthnRegisterFinishForm)
+	m := ctx.Get("webauthn_manager").(manager.WebAuthnManagerInterface)
+
+	if err := ctx.Bind(form); err != nil {
+		return apierror.InvalidRequest(err)
+	}
+	if err := ctx.Validate(form); err != nil {
+		return apierror.InvalidParameters(err)
+	}
+
+	credential, err := m.RegisterFinish(ctx, form)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK, credential)
+}
+
+func webAuthnLoginBegin(ctx echo.Context) error {
+	form := new(models.WebAuthnLoginBeginForm)
+	m := ctx.Get("webauthn_manager").(manager.WebAuthnManagerInterface)
+
+	if err := ctx.Bind(form); err != nil {
+		return apierror.InvalidRequest(err)
+	}
+	if err := ctx.Validate(form); err != nil {
+		return apierror.InvalidParameters(err)
+	}
+	if form.Challenge == "" && form.Token == "" {
+		return apierror.InvalidChallenge
+	}
+
+	resp, err := m.LoginBegin(ctx, form)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK, resp)
+}
+
+func webAuthnLoginFinish(ctx echo.Context) error {
+	form := new(models.WebAuthnLoginFinishForm)
+	m := ctx.Get("webauthn_manager").(manager.WebAuthnManagerInterface)
+
+	if err := ctx.Bind(form); err != nil {
+		return apierror.InvalidRequest(err)
+	}
+	if err := ctx.Validate(form); err != nil {
+		return apierror.InvalidParameters(err)
+	}
+
+	url, err := m.LoginFinish(ctx, form)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK, map[string]interface{}{"url": url})
+}
@@ -0,0 +1,81 @@
+// Package apple contains the parts of Sign in with Apple the social provider of the "apple" template doesn't share
+// with the other networks: the client secret is the JWT signed by the .p8 key of the team, and the name of the user
+// is posted with the authorization code on the first login only.
+package apple
+
+import (
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/pkg/errors"
+)
+
+const (
+	// Issuer is the issuer of the id tokens of Apple and the audience of the client secrets.
+	Issuer = "https://appleid.apple.com"
+
+	// AuthURL is the authorization endpoint of Apple.
+	AuthURL = "https://appleid.apple.com/auth/authorize"
+
+	// TokenURL is the token endpoint of Apple.
+	TokenURL = "https://appleid.apple.com/auth/token"
+)
+
+// ClientSecretTTL is the time the client secret is valid for, Apple accepts up to 6 months.
+var ClientSecretTTL = 5 * time.Minute
+
+var now = time.Now
+
+// ParseKey parses the PEM encoded .p8 key downloaded from the Apple developer account.
+func ParseKey(p8 string) (*ecdsa.PrivateKey, error) {
+	b, _ := pem.Decode([]byte(strings.TrimSpace(p8)))
+	if b == nil {
+		return nil, errors.New("apple key must be PEM encoded")
+	}
+	k, err := x509.ParsePKCS8PrivateKey(b.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to parse apple key")
+	}
+	key, ok := k.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("apple key must be ecdsa key")
+	}
+	return key, nil
+}
+
+// ClientSecret returns the client secret of the services id signed by the key of the team.
+func ClientSecret(key *ecdsa.PrivateKey, teamID, keyID, clientID string) (string, error) {
+	t := now()
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.StandardClaims{
+		Issuer:    teamID,
+		IssuedAt:  t.Unix(),
+		ExpiresAt: t.Add(ClientSecretTTL).Unix(),
+		Audience:  Issuer,
+		Subject:   clientID,
+	})
+	token.Header["kid"] = keyID
+	return token.SignedString(key)
+}
+
+// User is the user Apple posts to the redirect uri on the first login, the name isn't in the id token.
+type User struct {
+	Name struct {
+		FirstName string `json:"firstName"`
+		LastName  string `json:"lastName"`
+	} `json:"name"`
+	Email string `json:"email"`
+}
+
+// ParseUser parses the user posted by Apple.
+func ParseUser(data string) (*User, error) {
+	u := &User{}
+	if err := json.Unmarshal([]byte(data), u); err != nil {
+		return nil, errors.Wrap(err, "invalid apple user")
+	}
+	return u, nil
+}
@@ -0,0 +1,75 @@
+package apple
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func encodeKey(t *testing.T, key interface{}) string {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+}
+
+func TestParseKey(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	parsed, err := ParseKey(encodeKey(t, key) + "\n")
+	if assert.NoError(t, err) {
+		assert.Equal(t, key.D, parsed.D)
+	}
+
+	_, err = ParseKey("key")
+	assert.Error(t, err)
+
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 1024)
+	_, err = ParseKey(encodeKey(t, rsaKey))
+	assert.Error(t, err)
+}
+
+func TestClientSecret(t *testing.T) {
+	defer func() { now = time.Now }()
+	issued := time.Now().Truncate(time.Second)
+	now = func() time.Time { return issued }
+
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	secret, err := ClientSecret(key, "TEAM", "KEY", "com.example.login")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	claims := jwt.MapClaims{}
+	token, err := new(jwt.Parser).ParseWithClaims(secret, claims, func(*jwt.Token) (interface{}, error) {
+		return &key.PublicKey, nil
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "ES256", token.Header["alg"])
+	assert.Equal(t, "KEY", token.Header["kid"])
+	assert.Equal(t, "TEAM", claims["iss"])
+	assert.Equal(t, "com.example.login", claims["sub"])
+	assert.Equal(t, Issuer, claims["aud"])
+	assert.Equal(t, float64(issued.Add(ClientSecretTTL).Unix()), claims["exp"])
+}
+
+func TestParseUser(t *testing.T) {
+	u, err := ParseUser(`{"name":{"firstName":"Robin","lastName":"Smith"},"email":"robin@privaterelay.appleid.com"}`)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "Robin", u.Name.FirstName)
+		assert.Equal(t, "Smith", u.Name.LastName)
+	}
+
+	_, err = ParseUser("name")
+	assert.Error(t, err)
+}
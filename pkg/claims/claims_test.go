@@ -0,0 +1,85 @@
+package claims
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func decode(t *testing.T, s string) interface{} {
+	var v interface{}
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestParse(t *testing.T) {
+	valid := map[string]Path{
+		"id":                       {{key: "id", index: -1}},
+		"$.id":                     {{key: "id", index: -1}},
+		"$.response[0].id":         {{key: "response", index: -1}, {index: 0}, {key: "id", index: -1}},
+		"picture.data.url":         {{key: "picture", index: -1}, {key: "data", index: -1}, {key: "url", index: -1}},
+		"['https://a.b/roles'][1]": {{key: "https://a.b/roles", index: -1}, {index: 1}},
+		`$["first name"]`:          {{key: "first name", index: -1}},
+	}
+	for expr, expected := range valid {
+		p, err := Parse(expr)
+		assert.Nil(t, err, expr)
+		assert.Equal(t, expected, p, expr)
+	}
+
+	for _, expr := range []string{"", "$", "a..b", "a[", "a[-1]", "a[x]", "$.", "a[0]b"} {
+		_, err := Parse(expr)
+		assert.NotNil(t, err, expr)
+	}
+}
+
+func TestApply(t *testing.T) {
+	data := decode(t, `{
+		"response": [{"id": 12345678901, "screen_name": "durov", "photo": {"url": "http://pic"}}],
+		"mail": "user@example.com",
+		"verified": true
+	}`)
+
+	p, err := Apply(entity.ClaimMapping{
+		ExternalID:    "$.response[0].id",
+		Email:         "mail",
+		EmailVerified: "verified",
+		Username:      "response[0].screen_name",
+		Picture:       "response[0].photo.url",
+		Name:          "response[0].missing",
+	}, data)
+
+	verified := true
+	assert.Nil(t, err)
+	assert.Equal(t, &Profile{
+		ExternalID:    "12345678901",
+		Email:         "user@example.com",
+		EmailVerified: &verified,
+		Username:      "durov",
+		Picture:       "http://pic",
+	}, p)
+}
+
+func TestApplyDropsUnverifiedEmail(t *testing.T) {
+	p, err := Apply(entity.ClaimMapping{Email: "email", EmailVerified: "email_verified"}, decode(t, `{"email": "user@example.com", "email_verified": "false"}`))
+
+	assert.Nil(t, err)
+	assert.Equal(t, "", p.Email)
+	if assert.NotNil(t, p.EmailVerified) {
+		assert.False(t, *p.EmailVerified)
+	}
+}
+
+func TestApplyRejectsObjects(t *testing.T) {
+	_, err := Apply(entity.ClaimMapping{Picture: "picture"}, decode(t, `{"picture": {"url": "http://pic"}}`))
+	assert.NotNil(t, err)
+}
+
+func TestValidate(t *testing.T) {
+	assert.Nil(t, Validate(entity.ClaimMapping{ExternalID: "$.id"}))
+	assert.NotNil(t, Validate(entity.ClaimMapping{Email: "a[x]"}))
+}
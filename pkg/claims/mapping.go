@@ -0,0 +1,101 @@
+package claims
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
+	"github.com/pkg/errors"
+)
+
+// Profile is the profile of the user selected by the mapping, the fields of the empty expressions are empty.
+type Profile struct {
+	ExternalID    string `json:"external_id,omitempty"`
+	Email         string `json:"email,omitempty"`
+	EmailVerified *bool  `json:"email_verified,omitempty"`
+	Username      string `json:"username,omitempty"`
+	Name          string `json:"name,omitempty"`
+	FirstName     string `json:"first_name,omitempty"`
+	LastName      string `json:"last_name,omitempty"`
+	Birthday      string `json:"birthday,omitempty"`
+	Picture       string `json:"picture,omitempty"`
+}
+
+// Validate checks all expressions of the mapping are valid.
+func Validate(m entity.ClaimMapping) error {
+	_, err := Apply(m, nil)
+	return err
+}
+
+// Apply selects the profile in the decoded JSON document. The missing values are left empty, but the path
+// selecting the object or the array is an error. The email is dropped if the email verified flag is false.
+func Apply(m entity.ClaimMapping, data interface{}) (*Profile, error) {
+	p := &Profile{}
+	fields := []struct {
+		name  string
+		expr  string
+		value *string
+	}{
+		{"external_id", m.ExternalID, &p.ExternalID},
+		{"email", m.Email, &p.Email},
+		{"username", m.Username, &p.Username},
+		{"name", m.Name, &p.Name},
+		{"first_name", m.FirstName, &p.FirstName},
+		{"last_name", m.LastName, &p.LastName},
+		{"birthday", m.Birthday, &p.Birthday},
+		{"picture", m.Picture, &p.Picture},
+	}
+	for _, f := range fields {
+		v, err := lookup(f.expr, data)
+		if err != nil {
+			return nil, errors.Wrap(err, f.name)
+		}
+		*f.value = v
+	}
+
+	verified, err := lookup(m.EmailVerified, data)
+	if err != nil {
+		return nil, errors.Wrap(err, "email_verified")
+	}
+	if verified == "true" || verified == "false" {
+		v := verified == "true"
+		p.EmailVerified = &v
+		if !v {
+			p.Email = ""
+		}
+	}
+
+	return p, nil
+}
+
+// lookup returns the scalar value of the expression as the string, the empty expression selects nothing.
+func lookup(expr string, data interface{}) (string, error) {
+	if expr == "" {
+		return "", nil
+	}
+
+	p, err := Parse(expr)
+	if err != nil {
+		return "", err
+	}
+
+	v, ok := p.Get(data)
+	if !ok {
+		return "", nil
+	}
+
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	}
+
+	return "", errors.Errorf("%q selects %T instead of the value", expr, v)
+}
@@ -0,0 +1,97 @@
+// Package claims selects the profile of the user in the userinfo responses and the id token claims of the identity
+// providers by the claim mapping of the provider.
+package claims
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var ErrInvalidPath = errors.New("invalid path")
+
+// Path is the compiled expression selecting the value in the decoded JSON document. The expression consists of
+// the optional root "$", the keys of the objects (".key" or "['key']") and the indexes of the arrays ("[0]"),
+// the leading dot may be omitted: "$.response[0].id", "picture.data.url", "['https://example.com/roles'][0]".
+type Path []segment
+
+type segment struct {
+	key   string
+	index int
+}
+
+// Parse compiles the expression.
+func Parse(expr string) (Path, error) {
+	s := strings.TrimPrefix(strings.TrimSpace(expr), "$")
+
+	var p Path
+	for s != "" {
+		switch {
+		case s[0] == '[':
+			end := strings.IndexByte(s, ']')
+			if end < 0 {
+				return nil, errors.Wrapf(ErrInvalidPath, "%q: unclosed bracket", expr)
+			}
+			inner := s[1:end]
+			s = s[end+1:]
+
+			if len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0] {
+				p = append(p, segment{key: inner[1 : len(inner)-1], index: -1})
+				continue
+			}
+			i, err := strconv.Atoi(inner)
+			if err != nil || i < 0 {
+				return nil, errors.Wrapf(ErrInvalidPath, "%q: invalid index %q", expr, inner)
+			}
+			p = append(p, segment{index: i})
+
+		case s[0] == '.' || len(p) == 0:
+			if s[0] == '.' {
+				s = s[1:]
+			}
+			n := strings.IndexAny(s, ".[")
+			if n < 0 {
+				n = len(s)
+			}
+			if n == 0 {
+				return nil, errors.Wrapf(ErrInvalidPath, "%q: empty key", expr)
+			}
+			p = append(p, segment{key: s[:n], index: -1})
+			s = s[n:]
+
+		default:
+			return nil, errors.Wrapf(ErrInvalidPath, "%q: unexpected %q", expr, s)
+		}
+	}
+
+	if len(p) == 0 {
+		return nil, errors.Wrapf(ErrInvalidPath, "%q: empty path", expr)
+	}
+
+	return p, nil
+}
+
+// Get returns the value of the path, ok is false if the document has no such value.
+func (p Path) Get(data interface{}) (value interface{}, ok bool) {
+	for _, s := range p {
+		if s.index >= 0 {
+			a, isArray := data.([]interface{})
+			if !isArray || s.index >= len(a) {
+				return nil, false
+			}
+			data = a[s.index]
+			continue
+		}
+
+		m, isObject := data.(map[string]interface{})
+		if !isObject {
+			return nil, false
+		}
+		if data, ok = m[s.key]; !ok {
+			return nil, false
+		}
+	}
+
+	return data, true
+}
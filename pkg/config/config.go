@@ -1,12 +1,32 @@
 package config
 
 import (
+	"time"
+
 	"github.com/kelseyhightower/envconfig"
 )
 
 type Admin struct {
 	// Database contains settings for connection to the database.
 	Database Database
+
+	// Redis contains settings for connection to the Redis.
+	Redis Redis
+
+	// Proxy contains settings of the reverse proxies in front of the server.
+	Proxy Proxy
+
+	// Webhooks contains settings for the webhook delivery queue.
+	Webhooks Webhooks
+
+	// Hydra contains settings for public and private urls of the Hydra api.
+	Hydra Hydra
+
+	// Operators contains settings for the operator sessions.
+	Operators Operators
+
+	// OIDC contains settings of the client used by the operators to sign in with Auth1.
+	OIDC AdminOIDC `envconfig:"ADMIN_OIDC"`
 }
 
 // Config is general configuration settings for the application.
@@ -14,6 +34,9 @@ type Config struct {
 	// Server contains settings for http application.
 	Server Server
 
+	// Proxy contains settings of the reverse proxies in front of the server.
+	Proxy Proxy
+
 	// Database contains settings for connection to the database.
 	Database Database
 
@@ -29,6 +52,9 @@ type Config struct {
 	// Mailer contains settings for the postman service.
 	Mailer Mailer
 
+	// Sms contains settings for the sms sender.
+	Sms Sms
+
 	// Recaptcha contains settings for recaptcha integration.
 	Recaptcha Recaptcha
 
@@ -37,6 +63,24 @@ type Config struct {
 	// Centrifugo settings to connect to centrifugo
 	Centrifugo Centrifugo
 
+	// Mfa contains settings for the multi-factor authentication.
+	Mfa Mfa
+
+	// WebAuthn contains settings for the passkey authentication.
+	WebAuthn WebAuthn
+
+	// Webhooks contains settings for the webhook delivery queue.
+	Webhooks Webhooks
+
+	// Operators contains settings for the operator sessions of the management api.
+	Operators Operators
+
+	// BreachedPasswords contains settings of the corpus of the breached passwords.
+	BreachedPasswords BreachedPasswords `envconfig:"BREACHED_PASSWORDS"`
+
+	// SAML contains settings of the service provider of the SAML identity providers.
+	SAML SAML
+
 	// MigrationDirect specifies direction for database migrations.
 	MigrationDirect string `envconfig:"MIGRATION_DIRECT" required:"false"`
 }
@@ -48,7 +92,13 @@ type Server struct {
 	AllowOrigins      []string `envconfig:"ALLOW_ORIGINS" required:"false" default:"*"`
 	AllowCredentials  bool     `envconfig:"ALLOW_CREDENTIALS" required:"false" default:"true"`
 	AuthWebFormSdkUrl string   `envconfig:"AUTH_WEB_FORM_SDK_URL" required:"false" default:"https://static.protocol.one/auth/form/dev/auth-web-form.js"`
-	ManageSecret      string   `required:"false" default:"password"`
+}
+
+// Proxy contains settings of the reverse proxies in front of the server.
+type Proxy struct {
+	// TrustedHops is the number of the proxies appending the client address to X-Forwarded-For, the header
+	// is set by the client, so the remote address of the connection is used without them.
+	TrustedHops int `envconfig:"TRUSTED_HOPS" required:"false" default:"0"`
 }
 
 // Database contains settings for connection to the database.
@@ -94,6 +144,20 @@ type Mailer struct {
 	InsecureSkipVerify bool   `envconfig:"SKIP_VERIFY" required:"false" default:"true"`
 }
 
+// Sms contains settings for the sms sender.
+type Sms struct {
+	// Backend is the sender implementation: "twilio" delivers messages by Twilio, "file" appends them to the File
+	// for tests and "none" disables the sms channel, the codes of the sms providers aren't delivered then.
+	// The application isn't started with the twilio backend without the credentials.
+	Backend string `envconfig:"BACKEND" required:"false" default:"none"`
+	File    string `envconfig:"FILE" required:"false" default:"./sms.log"`
+
+	TwilioURL        string `envconfig:"TWILIO_URL" required:"false" default:"https://api.twilio.com"`
+	TwilioAccountSID string `envconfig:"TWILIO_ACCOUNT_SID" required:"false"`
+	TwilioAuthToken  string `envconfig:"TWILIO_AUTH_TOKEN" required:"false"`
+	TwilioFrom       string `envconfig:"TWILIO_FROM" required:"false"`
+}
+
 type Recaptcha struct {
 	Key      string `envconfig:"KEY" required:"false" default:""`
 	Secret   string `envconfig:"SECRET" required:"false" default:""`
@@ -102,10 +166,15 @@ type Recaptcha struct {
 
 // Hydra contains settings for public and private urls of the Hydra api.
 type MailTemplates struct {
-	ChangePasswordTpl string `envconfig:"CHANGE_PASSWORD_TPL" required:"true" default:"./public/templates/email/change_password.html"`
-	PlatformUrl       string `envconfig:"PLATFORM_URL" required:"true" default:"http://localhost:7001"`
-	PlatformName      string `envconfig:"PLATFORM_NAME" required:"true" default:"Auth1"`
-	SupportPortalUrl  string `envconfig:"SUPPORT_PORTAL_URL" required:"true" default:"http://localhost:7001"`
+	ChangePasswordTpl    string `envconfig:"CHANGE_PASSWORD_TPL" required:"true" default:"./public/templates/email/change_password.html"`
+	PasswordLessTpl      string `envconfig:"PASSWORDLESS_TPL" required:"true" default:"./public/templates/email/passwordless.html"`
+	MfaCodeTpl           string `envconfig:"MFA_CODE_TPL" required:"true" default:"./public/templates/email/mfa_code.html"`
+	VerifyEmailTpl       string `envconfig:"VERIFY_EMAIL_TPL" required:"true" default:"./public/templates/email/verify_email.html"`
+	ChangeEmailTpl       string `envconfig:"CHANGE_EMAIL_TPL" required:"true" default:"./public/templates/email/change_email.html"`
+	ChangeEmailNoticeTpl string `envconfig:"CHANGE_EMAIL_NOTICE_TPL" required:"true" default:"./public/templates/email/change_email_notice.html"`
+	PlatformUrl          string `envconfig:"PLATFORM_URL" required:"true" default:"http://localhost:7001"`
+	PlatformName         string `envconfig:"PLATFORM_NAME" required:"true" default:"Auth1"`
+	SupportPortalUrl     string `envconfig:"SUPPORT_PORTAL_URL" required:"true" default:"http://localhost:7001"`
 }
 
 // Centrifugo settings
@@ -117,6 +186,69 @@ type Centrifugo struct {
 	LauncherChannel string `envconfig:"LAUNCHER_CHANNEL" required:"true" default:"launcher"`
 }
 
+// Mfa contains settings for the multi-factor authentication.
+type Mfa struct {
+	// Backend is the implementation of one-time codes: "native" for the built-in TOTP or "remote" for the mfa micro-service.
+	// The records enrolled by the remote backend have no secret, so its users must enroll again after the switch.
+	Backend string `envconfig:"BACKEND" required:"false" default:"remote"`
+}
+
+// WebAuthn contains settings of the relying party for the passkey authentication.
+type WebAuthn struct {
+	// RPID is the domain of the relying party, the credentials are bound to it and its subdomains.
+	RPID   string `envconfig:"RP_ID" required:"false" default:"localhost"`
+	RPName string `envconfig:"RP_NAME" required:"false" default:"Auth1"`
+	// Origins are the allowed origins of the pages that perform the ceremonies.
+	Origins []string `envconfig:"ORIGINS" required:"false" default:"http://localhost:8080"`
+}
+
+// Webhooks contains settings for the webhook delivery queue.
+type Webhooks struct {
+	// Backend is the storage of the queue: "mongo", "redis" or "memory" for a single instance without persistence.
+	Backend string `envconfig:"BACKEND" required:"false" default:"mongo"`
+	// MaxAttempts is the number of attempts after which the delivery is dead-lettered.
+	MaxAttempts int           `envconfig:"MAX_ATTEMPTS" required:"false" default:"10"`
+	BackoffBase time.Duration `envconfig:"BACKOFF_BASE" required:"false" default:"10s"`
+	BackoffMax  time.Duration `envconfig:"BACKOFF_MAX" required:"false" default:"1h"`
+	// Interval is the polling interval of the queue.
+	Interval  time.Duration `envconfig:"INTERVAL" required:"false" default:"5s"`
+	Timeout   time.Duration `envconfig:"TIMEOUT" required:"false" default:"30s"`
+	BatchSize int           `envconfig:"BATCH_SIZE" required:"false" default:"100"`
+	// Retention is the period the delivered deliveries are kept for, zero keeps them forever.
+	Retention time.Duration `envconfig:"RETENTION" required:"false" default:"168h"`
+	// DeadRetention is the period the dead-lettered deliveries are kept for to be replayed, zero keeps them forever.
+	DeadRetention time.Duration `envconfig:"DEAD_RETENTION" required:"false" default:"0"`
+}
+
+// Operators contains settings for the operator accounts of the administration panel and the management api.
+type Operators struct {
+	SessionTTL time.Duration `envconfig:"SESSION_TTL" required:"false" default:"12h"`
+}
+
+// BreachedPasswords contains settings of the corpus rejected by the spaces with the breached password check.
+type BreachedPasswords struct {
+	// File is the file of the SHA-1 hashes of the breached passwords sorted by hash, the check is disabled without it.
+	File string `envconfig:"FILE" required:"false"`
+}
+
+// SAML contains the key pair the authentication requests to the SAML identity providers are signed with and which
+// certificate is published in the metadata, the SAML providers can't be used without it.
+type SAML struct {
+	KeyFile         string `envconfig:"KEY_FILE" required:"false"`
+	CertificateFile string `envconfig:"CERTIFICATE_FILE" required:"false"`
+}
+
+// AdminOIDC contains settings of the OIDC client of the administration panel, the sign in with Auth1
+// is disabled without the SpaceID and the ClientID.
+type AdminOIDC struct {
+	// SpaceID is the dedicated admin space, only its users may sign in.
+	SpaceID      string `envconfig:"SPACE_ID" required:"false"`
+	ClientID     string `envconfig:"CLIENT_ID" required:"false"`
+	ClientSecret string `envconfig:"CLIENT_SECRET" required:"false"`
+	// RedirectURL is the callback of the panel, e.g. https://admin.example.com/api/auth/oidc/callback.
+	RedirectURL string `envconfig:"REDIRECT_URL" required:"false"`
+}
+
 func Load(v interface{}) error {
 	return envconfig.Process("AUTHONE", v)
 }
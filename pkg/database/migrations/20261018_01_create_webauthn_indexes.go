@@ -0,0 +1,49 @@
+package migrations
+
+import (
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/database"
+	"github.com/globalsign/mgo"
+	"github.com/pkg/errors"
+	"github.com/xakep666/mongo-migrate"
+)
+
+func init() {
+	err := migrate.Register(
+		func(db *mgo.Database) error {
+			err := db.C(database.TableUserWebAuthn).EnsureIndex(mgo.Index{
+				Name:       "Idx-CredentialId",
+				Key:        []string{"credential_id"},
+				Unique:     true,
+				Background: true,
+			})
+			if err != nil {
+				return errors.Wrapf(err, "Ensure user webauthn collection `Idx-CredentialId` index failed")
+			}
+
+			err = db.C(database.TableUserWebAuthn).EnsureIndex(mgo.Index{
+				Name:       "Idx-UserId",
+				Key:        []string{"user_id"},
+				Background: true,
+			})
+			if err != nil {
+				return errors.Wrapf(err, "Ensure user webauthn collection `Idx-UserId` index failed")
+			}
+
+			return nil
+		},
+		func(db *mgo.Database) error {
+			if err := db.C(database.TableUserWebAuthn).DropIndexName("Idx-CredentialId"); err != nil {
+				return errors.Wrapf(err, "Drop user webauthn collection `Idx-CredentialId` index failed")
+			}
+			if err := db.C(database.TableUserWebAuthn).DropIndexName("Idx-UserId"); err != nil {
+				return errors.Wrapf(err, "Drop user webauthn collection `Idx-UserId` index failed")
+			}
+
+			return nil
+		},
+	)
+
+	if err != nil {
+		return
+	}
+}
@@ -0,0 +1,48 @@
+package migrations
+
+import (
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/database"
+	"github.com/globalsign/mgo"
+	"github.com/pkg/errors"
+	"github.com/xakep666/mongo-migrate"
+)
+
+func init() {
+	err := migrate.Register(
+		func(db *mgo.Database) error {
+			err := db.C(database.TableWebhookDelivery).EnsureIndex(mgo.Index{
+				Name:       "Idx-Status-NextAttemptAt",
+				Key:        []string{"status", "next_attempt_at"},
+				Background: true,
+			})
+			if err != nil {
+				return errors.Wrapf(err, "Ensure webhook delivery collection `Idx-Status-NextAttemptAt` index failed")
+			}
+
+			err = db.C(database.TableWebhookDelivery).EnsureIndex(mgo.Index{
+				Name:       "Idx-AppId-CreatedAt",
+				Key:        []string{"app_id", "-created_at"},
+				Background: true,
+			})
+			if err != nil {
+				return errors.Wrapf(err, "Ensure webhook delivery collection `Idx-AppId-CreatedAt` index failed")
+			}
+
+			return nil
+		},
+		func(db *mgo.Database) error {
+			if err := db.C(database.TableWebhookDelivery).DropIndexName("Idx-Status-NextAttemptAt"); err != nil {
+				return errors.Wrapf(err, "Drop webhook delivery collection `Idx-Status-NextAttemptAt` index failed")
+			}
+			if err := db.C(database.TableWebhookDelivery).DropIndexName("Idx-AppId-CreatedAt"); err != nil {
+				return errors.Wrapf(err, "Drop webhook delivery collection `Idx-AppId-CreatedAt` index failed")
+			}
+
+			return nil
+		},
+	)
+
+	if err != nil {
+		return
+	}
+}
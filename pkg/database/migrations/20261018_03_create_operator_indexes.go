@@ -0,0 +1,49 @@
+package migrations
+
+import (
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/database"
+	"github.com/globalsign/mgo"
+	"github.com/pkg/errors"
+	"github.com/xakep666/mongo-migrate"
+)
+
+func init() {
+	err := migrate.Register(
+		func(db *mgo.Database) error {
+			err := db.C(database.TableOperator).EnsureIndex(mgo.Index{
+				Name:       "Idx-Login",
+				Key:        []string{"login"},
+				Unique:     true,
+				Background: true,
+			})
+			if err != nil {
+				return errors.Wrapf(err, "Ensure operator collection `Idx-Login` index failed")
+			}
+
+			err = db.C(database.TableOperator).EnsureIndex(mgo.Index{
+				Name:       "Idx-Subject",
+				Key:        []string{"subject"},
+				Background: true,
+			})
+			if err != nil {
+				return errors.Wrapf(err, "Ensure operator collection `Idx-Subject` index failed")
+			}
+
+			return nil
+		},
+		func(db *mgo.Database) error {
+			if err := db.C(database.TableOperator).DropIndexName("Idx-Login"); err != nil {
+				return errors.Wrapf(err, "Drop operator collection `Idx-Login` index failed")
+			}
+			if err := db.C(database.TableOperator).DropIndexName("Idx-Subject"); err != nil {
+				return errors.Wrapf(err, "Drop operator collection `Idx-Subject` index failed")
+			}
+
+			return nil
+		},
+	)
+
+	if err != nil {
+		return
+	}
+}
@@ -0,0 +1,48 @@
+package migrations
+
+import (
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/database"
+	"github.com/globalsign/mgo"
+	"github.com/pkg/errors"
+	"github.com/xakep666/mongo-migrate"
+)
+
+func init() {
+	err := migrate.Register(
+		func(db *mgo.Database) error {
+			err := db.C(database.TableAdminAudit).EnsureIndex(mgo.Index{
+				Name:       "Idx-SpaceId-CreatedAt",
+				Key:        []string{"space_id", "-created_at"},
+				Background: true,
+			})
+			if err != nil {
+				return errors.Wrapf(err, "Ensure admin audit collection `Idx-SpaceId-CreatedAt` index failed")
+			}
+
+			err = db.C(database.TableAdminAudit).EnsureIndex(mgo.Index{
+				Name:       "Idx-TargetId-CreatedAt",
+				Key:        []string{"target_id", "-created_at"},
+				Background: true,
+			})
+			if err != nil {
+				return errors.Wrapf(err, "Ensure admin audit collection `Idx-TargetId-CreatedAt` index failed")
+			}
+
+			return nil
+		},
+		func(db *mgo.Database) error {
+			if err := db.C(database.TableAdminAudit).DropIndexName("Idx-SpaceId-CreatedAt"); err != nil {
+				return errors.Wrapf(err, "Drop admin audit collection `Idx-SpaceId-CreatedAt` index failed")
+			}
+			if err := db.C(database.TableAdminAudit).DropIndexName("Idx-TargetId-CreatedAt"); err != nil {
+				return errors.Wrapf(err, "Drop admin audit collection `Idx-TargetId-CreatedAt` index failed")
+			}
+
+			return nil
+		},
+	)
+
+	if err != nil {
+		return
+	}
+}
@@ -0,0 +1,49 @@
+package migrations
+
+import (
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/database"
+	"github.com/globalsign/mgo"
+	"github.com/pkg/errors"
+	"github.com/xakep666/mongo-migrate"
+)
+
+// adminListIndexes serve the filters and the sorts of the lists of the administration server.
+var adminListIndexes = []struct {
+	table string
+	index mgo.Index
+}{
+	{database.TableUser, mgo.Index{Name: "Idx-SpaceId-Email", Key: []string{"space_id", "email"}, Background: true}},
+	{database.TableUser, mgo.Index{Name: "Idx-SpaceId-Username", Key: []string{"space_id", "username"}, Background: true}},
+	{database.TableUser, mgo.Index{Name: "Idx-SpaceId-CreatedAt", Key: []string{"space_id", "created_at"}, Background: true}},
+	{database.TableUser, mgo.Index{Name: "Idx-Email", Key: []string{"email"}, Background: true}},
+	{database.TableUser, mgo.Index{Name: "Idx-Username", Key: []string{"username"}, Background: true}},
+	{database.TableApplication, mgo.Index{Name: "Idx-SpaceId-Name", Key: []string{"space_id", "name"}, Background: true}},
+	{database.TableSpace, mgo.Index{Name: "Idx-Name", Key: []string{"name"}, Background: true}},
+}
+
+func init() {
+	err := migrate.Register(
+		func(db *mgo.Database) error {
+			for _, i := range adminListIndexes {
+				if err := db.C(i.table).EnsureIndex(i.index); err != nil {
+					return errors.Wrapf(err, "Ensure %s collection `%s` index failed", i.table, i.index.Name)
+				}
+			}
+
+			return nil
+		},
+		func(db *mgo.Database) error {
+			for _, i := range adminListIndexes {
+				if err := db.C(i.table).DropIndexName(i.index.Name); err != nil {
+					return errors.Wrapf(err, "Drop %s collection `%s` index failed", i.table, i.index.Name)
+				}
+			}
+
+			return nil
+		},
+	)
+
+	if err != nil {
+		return
+	}
+}
@@ -0,0 +1,46 @@
+package migrations
+
+import (
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/database"
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/pkg/errors"
+	"github.com/xakep666/mongo-migrate"
+)
+
+func init() {
+	err := migrate.Register(
+		func(db *mgo.Database) error {
+			s := entity.DefaultLockoutSettings
+			_, err := db.C(database.TableSpace).UpdateAll(
+				bson.M{"lockout_settings": bson.M{"$exists": false}},
+				bson.M{"$set": bson.M{"lockout_settings": bson.M{
+					"max_attempts":    s.MaxAttempts,
+					"ip_max_attempts": s.IPMaxAttempts,
+					"window":          s.Window,
+					"lock_duration":   s.LockDuration,
+					"delay":           s.Delay,
+					"max_delay":       s.MaxDelay,
+				}}},
+			)
+			if err != nil {
+				return errors.Wrap(err, "Unable to set default lockout settings of spaces")
+			}
+
+			return nil
+		},
+		func(db *mgo.Database) error {
+			_, err := db.C(database.TableSpace).UpdateAll(nil, bson.M{"$unset": bson.M{"lockout_settings": ""}})
+			if err != nil {
+				return errors.Wrap(err, "Unable to unset lockout settings of spaces")
+			}
+
+			return nil
+		},
+	)
+
+	if err != nil {
+		return
+	}
+}
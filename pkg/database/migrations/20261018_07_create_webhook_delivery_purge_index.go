@@ -0,0 +1,36 @@
+package migrations
+
+import (
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/database"
+	"github.com/globalsign/mgo"
+	"github.com/pkg/errors"
+	"github.com/xakep666/mongo-migrate"
+)
+
+func init() {
+	err := migrate.Register(
+		func(db *mgo.Database) error {
+			err := db.C(database.TableWebhookDelivery).EnsureIndex(mgo.Index{
+				Name:       "Idx-Status-UpdatedAt",
+				Key:        []string{"status", "updated_at"},
+				Background: true,
+			})
+			if err != nil {
+				return errors.Wrapf(err, "Ensure webhook delivery collection `Idx-Status-UpdatedAt` index failed")
+			}
+
+			return nil
+		},
+		func(db *mgo.Database) error {
+			if err := db.C(database.TableWebhookDelivery).DropIndexName("Idx-Status-UpdatedAt"); err != nil {
+				return errors.Wrapf(err, "Drop webhook delivery collection `Idx-Status-UpdatedAt` index failed")
+			}
+
+			return nil
+		},
+	)
+
+	if err != nil {
+		return
+	}
+}
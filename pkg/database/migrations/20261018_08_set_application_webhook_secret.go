@@ -0,0 +1,51 @@
+package migrations
+
+import (
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/database"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/helper"
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/pkg/errors"
+	"github.com/xakep666/mongo-migrate"
+)
+
+func init() {
+	err := migrate.Register(
+		func(db *mgo.Database) error {
+			// INFO: Each application gets its own secret, so the applications created before the webhooks are signed too
+			iter := db.C(database.TableApplication).
+				Find(bson.M{"$or": []bson.M{{"webhook_secret": bson.M{"$exists": false}}, {"webhook_secret": ""}}}).
+				Select(bson.M{"_id": 1}).
+				Iter()
+
+			var app struct {
+				ID bson.ObjectId `bson:"_id"`
+			}
+			for iter.Next(&app) {
+				secret, err := helper.GetSecureRandString(64)
+				if err != nil {
+					iter.Close()
+					return errors.Wrap(err, "Unable to generate webhook secret")
+				}
+				err = db.C(database.TableApplication).UpdateId(app.ID, bson.M{"$set": bson.M{"webhook_secret": secret}})
+				if err != nil {
+					iter.Close()
+					return errors.Wrapf(err, "Unable to set webhook secret of application %s", app.ID.Hex())
+				}
+			}
+			if err := iter.Close(); err != nil {
+				return errors.Wrap(err, "Unable to iterate applications without webhook secret")
+			}
+
+			return nil
+		},
+		func(db *mgo.Database) error {
+			// INFO: The secrets may be shared with the consumers of the webhooks already, so they're kept
+			return nil
+		},
+	)
+
+	if err != nil {
+		return
+	}
+}
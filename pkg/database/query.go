@@ -0,0 +1,31 @@
+package database
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/globalsign/mgo/bson"
+)
+
+// Prefix returns the filter matching the strings starting with the prefix. The filter is case sensitive,
+// so it's served by the index of the field.
+func Prefix(prefix string) bson.RegEx {
+	return bson.RegEx{Pattern: "^" + regexp.QuoteMeta(prefix)}
+}
+
+// Sort returns the sort fields of the query for the sort of the list, like "name" or "-name". The fields maps
+// the names of the list to the fields of the collection, the unknown name is sorted by _id. The _id is added
+// to the end to keep the order of the pages stable.
+func Sort(sort string, fields map[string]string) []string {
+	order := ""
+	if strings.HasPrefix(sort, "-") {
+		order, sort = "-", sort[1:]
+	}
+
+	field, ok := fields[sort]
+	if !ok || field == "_id" {
+		return []string{order + "_id"}
+	}
+
+	return []string{order + field, order + "_id"}
+}
@@ -0,0 +1,22 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/globalsign/mgo/bson"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrefixEscapesPattern(t *testing.T) {
+	assert.Equal(t, bson.RegEx{Pattern: `^john\.doe\+1`}, Prefix("john.doe+1"))
+}
+
+func TestSortAddsIdToKeepOrder(t *testing.T) {
+	fields := map[string]string{"id": "_id", "name": "name"}
+
+	assert.Equal(t, []string{"name", "_id"}, Sort("name", fields))
+	assert.Equal(t, []string{"-name", "-_id"}, Sort("-name", fields))
+	assert.Equal(t, []string{"-_id"}, Sort("-id", fields))
+	assert.Equal(t, []string{"_id"}, Sort("password", fields))
+	assert.Equal(t, []string{"_id"}, Sort("", fields))
+}
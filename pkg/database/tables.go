@@ -10,6 +10,10 @@ const (
 	TableAuthLog             = "auth_log"
 	TableApplicationMfa      = "application_mfa"
 	TableUserMfa             = "user_mfa"
+	TableUserWebAuthn        = "user_webauthn"
+	TableWebhookDelivery     = "webhook_delivery"
+	TableOperator            = "operator"
+	TableAdminAudit          = "admin_audit"
 
 	// removed (normalization in auth_log not needed)
 	TableUserAgent = "user_agent"
@@ -0,0 +1,148 @@
+package hasher
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	argon2KeyLen  = 32
+	argon2SaltLen = 16
+)
+
+type argon2Params struct {
+	memory  uint32
+	time    uint32
+	threads uint8
+}
+
+func (p argon2Params) validate() error {
+	if p.memory == 0 || p.time == 0 || p.threads == 0 {
+		return ErrMalformedHash
+	}
+	if p.memory > MaxArgon2Memory || p.time > MaxArgon2Time || p.threads > MaxArgon2Threads {
+		return ErrCostExceeded
+	}
+	return nil
+}
+
+type argon2idHasher struct {
+	params argon2Params
+	// err is the error of the settings found before they're narrowed to the parameters.
+	err error
+}
+
+func newArgon2id(s entity.PasswordSettings) Hasher {
+	d := entity.DefaultPasswordSettings
+	memory := orDefault(s.Argon2Memory, d.Argon2Memory)
+	time := orDefault(s.Argon2Time, d.Argon2Time)
+	threads := orDefault(s.Argon2Threads, d.Argon2Threads)
+
+	h := &argon2idHasher{params: argon2Params{
+		memory:  uint32(memory),
+		time:    uint32(time),
+		threads: uint8(threads),
+	}}
+	// INFO: The settings are checked as int, the narrowed ones could wrap around into the bounds
+	if s.Argon2Memory < 0 || s.Argon2Time < 0 || s.Argon2Threads < 0 {
+		h.err = ErrInvalidSettings
+	} else if memory > MaxArgon2Memory || time > MaxArgon2Time || threads > MaxArgon2Threads {
+		h.err = ErrCostExceeded
+	}
+
+	return h
+}
+
+func (h *argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, argon2SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	p := h.params
+	key := argon2.IDKey([]byte(password), salt, p.time, p.memory, p.threads, argon2KeyLen)
+
+	return fmt.Sprintf(
+		"$%s$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		entity.PasswordAlgorithmArgon2id,
+		argon2.Version,
+		p.memory,
+		p.time,
+		p.threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func (h *argon2idHasher) Verify(hash, password string) error {
+	p, salt, key, err := h.decode(hash)
+	if err != nil {
+		return err
+	}
+
+	actual := argon2.IDKey([]byte(password), salt, p.time, p.memory, p.threads, uint32(len(key)))
+	if subtle.ConstantTimeCompare(key, actual) != 1 {
+		return ErrMismatch
+	}
+	return nil
+}
+
+func (h *argon2idHasher) Match(hash string) bool {
+	return strings.HasPrefix(hash, "$"+entity.PasswordAlgorithmArgon2id+"$")
+}
+
+func (h *argon2idHasher) Outdated(hash string) bool {
+	p, _, _, err := h.decode(hash)
+	return err != nil || p != h.params
+}
+
+func (h *argon2idHasher) check(hash string) error {
+	_, _, _, err := h.decode(hash)
+	return err
+}
+
+func (h *argon2idHasher) validate() error {
+	if h.err != nil {
+		return h.err
+	}
+	return h.params.validate()
+}
+
+func (h *argon2idHasher) decode(hash string) (p argon2Params, salt, key []byte, err error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != entity.PasswordAlgorithmArgon2id {
+		return p, nil, nil, ErrMalformedHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return p, nil, nil, ErrMalformedHash
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.memory, &p.time, &p.threads); err != nil {
+		return p, nil, nil, ErrMalformedHash
+	}
+	if err := p.validate(); err != nil {
+		return p, nil, nil, err
+	}
+
+	if salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return p, nil, nil, ErrMalformedHash
+	}
+	if key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(key) == 0 {
+		return p, nil, nil, ErrMalformedHash
+	}
+
+	return p, salt, key, nil
+}
+
+func orDefault(v, d int) int {
+	if v <= 0 {
+		return d
+	}
+	return v
+}
@@ -0,0 +1,63 @@
+package hasher
+
+import (
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type bcryptHasher struct {
+	cost int
+}
+
+func newBcrypt(s entity.PasswordSettings) Hasher {
+	cost := s.BcryptCost
+	if cost == 0 {
+		cost = bcrypt.DefaultCost
+	}
+
+	return &bcryptHasher{cost: cost}
+}
+
+func (h *bcryptHasher) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), h.cost)
+	return string(hash), err
+}
+
+func (h *bcryptHasher) Verify(hash, password string) error {
+	if err := h.check(hash); err != nil {
+		return err
+	}
+
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if err == bcrypt.ErrMismatchedHashAndPassword {
+		return ErrMismatch
+	}
+	return err
+}
+
+func (h *bcryptHasher) Match(hash string) bool {
+	return len(hash) > 3 && hash[0] == '$' && hash[1] == '2' && (hash[2] == '$' || hash[3] == '$')
+}
+
+func (h *bcryptHasher) Outdated(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost != h.cost
+}
+
+func (h *bcryptHasher) check(hash string) error {
+	cost, err := bcrypt.Cost([]byte(hash))
+	if err != nil {
+		return ErrMalformedHash
+	}
+	if cost > MaxBcryptCost {
+		return ErrCostExceeded
+	}
+	return nil
+}
+
+func (h *bcryptHasher) validate() error {
+	if h.cost > MaxBcryptCost {
+		return ErrCostExceeded
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+// Package hasher contains the registry of the password hash algorithms. The hashes are stored in the PHC string
+// format ($<algorithm>$<params>$<salt>$<hash>), bcrypt keeps its own modular crypt format, so the algorithm and
+// the parameters of any stored hash are known without the space settings.
+package hasher
+
+import (
+	"sync"
+
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
+	"github.com/pkg/errors"
+)
+
+// Verifier describes of methods for checking the passwords against the hashes of the algorithm.
+type Verifier interface {
+	// Verify compares the password with the hash made by the algorithm with any parameters.
+	Verify(hash, password string) error
+
+	// Match reports whether the hash is made by the algorithm.
+	Match(hash string) bool
+}
+
+// Hasher describes of methods for the password hash algorithm.
+type Hasher interface {
+	Verifier
+
+	// Hash returns the hash of the password with the parameters of the hasher.
+	Hash(password string) (string, error)
+
+	// Outdated reports whether the hash is made with the other parameters than the ones of the hasher.
+	Outdated(hash string) bool
+}
+
+// Factory returns the hasher of the algorithm with the parameters of the space password settings.
+type Factory func(s entity.PasswordSettings) Hasher
+
+var (
+	ErrMismatch         = errors.New("password doesn't match")
+	ErrUnknownAlgorithm = errors.New("unknown password hash algorithm")
+	ErrMalformedHash    = errors.New("malformed password hash")
+	ErrCostExceeded     = errors.New("password hash cost exceeds the bounds")
+	ErrInvalidSettings  = errors.New("password hash parameters can't be negative")
+)
+
+// The upper bounds of the cost parameters. The parameters of the stored hash are used on every login of the user,
+// so the hash exceeding them is refused to be verified and the space settings exceeding them are refused as well.
+const (
+	MaxBcryptCost       = 16
+	MaxArgon2Memory     = 1 << 20 // KiB
+	MaxArgon2Time       = 10
+	MaxArgon2Threads    = 16
+	MaxScryptMemory     = 1 << 30 // bytes, 128 * r * N
+	MaxScryptP          = 16
+	MaxPBKDF2Iterations = 2000000
+	MaxPhpassCountLog2  = 20
+	MaxFirebaseMemCost  = 16
+	MaxFirebaseRounds   = 16
+)
+
+// checker is implemented by the algorithms which hashes keep the cost parameters.
+type checker interface {
+	// check returns ErrCostExceeded if the cost parameters of the hash are out of the bounds.
+	check(hash string) error
+}
+
+// validator is implemented by the hashers which parameters are taken from the space settings.
+type validator interface {
+	// validate returns ErrCostExceeded if the cost parameters of the hasher are out of the bounds and
+	// ErrInvalidSettings if they're negative.
+	validate() error
+}
+
+var (
+	mu        sync.RWMutex
+	factories = map[string]Factory{}
+	verifiers = map[string]Verifier{}
+)
+
+func init() {
+	Register(entity.PasswordAlgorithmBcrypt, newBcrypt)
+	Register(entity.PasswordAlgorithmArgon2id, newArgon2id)
+	Register(entity.PasswordAlgorithmScrypt, newScrypt)
+
+	for _, name := range []string{AlgorithmMD5, AlgorithmSHA1, AlgorithmSHA256, AlgorithmSHA512} {
+		RegisterVerifier(name, &saltedDigest{name: name})
+	}
+	for _, name := range []string{AlgorithmPBKDF2SHA1, AlgorithmPBKDF2SHA256, AlgorithmPBKDF2SHA512} {
+		RegisterVerifier(name, &pbkdf2Verifier{name: name})
+	}
+	RegisterVerifier(AlgorithmPhpass, &phpassVerifier{})
+	RegisterVerifier(AlgorithmFirebaseScrypt, &firebaseScryptVerifier{})
+}
+
+// Register adds the algorithm to the registry, the algorithm registered before is replaced.
+func Register(algorithm string, factory Factory) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	factories[algorithm] = factory
+}
+
+// RegisterVerifier adds the algorithm which hashes are only verified, such hashes are imported from the other
+// systems and replaced on the next login, so the algorithm can't be chosen by the space.
+func RegisterVerifier(algorithm string, verifier Verifier) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	verifiers[algorithm] = verifier
+}
+
+// New returns the hasher chosen by the space password settings, bcrypt is used if the algorithm isn't set.
+func New(s entity.PasswordSettings) (Hasher, error) {
+	algorithm := s.Algorithm
+	if algorithm == "" {
+		algorithm = entity.PasswordAlgorithmBcrypt
+	}
+
+	mu.RLock()
+	factory, ok := factories[algorithm]
+	mu.RUnlock()
+	if !ok {
+		return nil, errors.Wrap(ErrUnknownAlgorithm, algorithm)
+	}
+
+	h := factory(s)
+	if v, ok := h.(validator); ok {
+		if err := v.validate(); err != nil {
+			return nil, errors.Wrap(err, algorithm)
+		}
+	}
+
+	return h, nil
+}
+
+// Verify compares the password with the hash made by any registered algorithm.
+func Verify(hash, password string) error {
+	v := find(hash)
+	if v == nil {
+		return ErrUnknownAlgorithm
+	}
+
+	return v.Verify(hash, password)
+}
+
+// Known reports whether the hash is made by any registered algorithm.
+func Known(hash string) bool {
+	return find(hash) != nil
+}
+
+// Check returns ErrUnknownAlgorithm if the hash isn't made by any registered algorithm, ErrMalformedHash if its
+// parameters can't be parsed and ErrCostExceeded if they're out of the bounds, such hash is never verified.
+func Check(hash string) error {
+	v := find(hash)
+	if v == nil {
+		return ErrUnknownAlgorithm
+	}
+	if c, ok := v.(checker); ok {
+		return c.check(hash)
+	}
+
+	return nil
+}
+
+// find returns the algorithm which made the hash, nil is returned if there is no such one.
+func find(hash string) Verifier {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	for _, factory := range factories {
+		if h := factory(entity.PasswordSettings{}); h.Match(hash) {
+			return h
+		}
+	}
+	for _, v := range verifiers {
+		if v.Match(hash) {
+			return v
+		}
+	}
+
+	return nil
+}
+
+// NeedsRehash reports whether the hash should be replaced by the one made by the hasher.
+func NeedsRehash(h Hasher, hash string) bool {
+	return !h.Match(hash) || h.Outdated(hash)
+}
@@ -0,0 +1,109 @@
+package hasher
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// fast keeps the tests quick, the parameters of the hashes are checked by the rehash tests.
+var fast = entity.PasswordSettings{BcryptCost: 4, Argon2Memory: 1024, Argon2Time: 1, Argon2Threads: 1, ScryptN: 1024, ScryptR: 8, ScryptP: 1}
+
+func settings(algorithm string) entity.PasswordSettings {
+	s := fast
+	s.Algorithm = algorithm
+	return s
+}
+
+func TestVerifyWithEachAlgorithm(t *testing.T) {
+	for _, algorithm := range []string{entity.PasswordAlgorithmBcrypt, entity.PasswordAlgorithmArgon2id, entity.PasswordAlgorithmScrypt} {
+		h, err := New(settings(algorithm))
+		assert.Nil(t, err, algorithm)
+
+		hash, err := h.Hash("secret")
+		assert.Nil(t, err, algorithm)
+
+		assert.Nil(t, Verify(hash, "secret"), algorithm)
+		assert.Equal(t, ErrMismatch, Verify(hash, "other"), algorithm)
+		assert.False(t, NeedsRehash(h, hash), algorithm)
+	}
+}
+
+func TestHashFormat(t *testing.T) {
+	h, _ := New(settings(entity.PasswordAlgorithmArgon2id))
+	hash, _ := h.Hash("secret")
+	assert.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$"))
+
+	h, _ = New(settings(entity.PasswordAlgorithmScrypt))
+	hash, _ = h.Hash("secret")
+	assert.True(t, strings.HasPrefix(hash, "$scrypt$ln=10,r=8,p=1$"))
+
+	h, _ = New(settings(""))
+	hash, _ = h.Hash("secret")
+	assert.True(t, strings.HasPrefix(hash, "$2a$04$"))
+}
+
+func TestNeedsRehashOnOtherAlgorithmOrParams(t *testing.T) {
+	bcrypt, _ := New(settings(entity.PasswordAlgorithmBcrypt))
+	hash, _ := bcrypt.Hash("secret")
+
+	argon2id, _ := New(settings(entity.PasswordAlgorithmArgon2id))
+	assert.True(t, NeedsRehash(argon2id, hash))
+
+	s := settings(entity.PasswordAlgorithmBcrypt)
+	s.BcryptCost = 5
+	costly, _ := New(s)
+	assert.True(t, NeedsRehash(costly, hash))
+
+	hash, _ = argon2id.Hash("secret")
+	s = settings(entity.PasswordAlgorithmArgon2id)
+	s.Argon2Time = 2
+	costly, _ = New(s)
+	assert.True(t, NeedsRehash(costly, hash))
+}
+
+func TestUnknownAlgorithmAndMalformedHash(t *testing.T) {
+	_, err := New(settings("md5"))
+	assert.NotNil(t, err)
+
+	assert.Equal(t, ErrUnknownAlgorithm, Verify("plain", "plain"))
+	assert.Equal(t, ErrUnknownAlgorithm, Verify("", ""))
+	assert.Equal(t, ErrMalformedHash, Verify("$argon2id$v=19$m=1024,t=1,p=1$broken", "secret"))
+	assert.Equal(t, ErrMalformedHash, Verify("$scrypt$ln=99,r=8,p=1$c2FsdA$a2V5", "secret"))
+}
+
+func TestCostBounds(t *testing.T) {
+	bcrypt, _ := New(settings(entity.PasswordAlgorithmBcrypt))
+	hash, _ := bcrypt.Hash("secret")
+	assert.Nil(t, Check(hash))
+	assert.Equal(t, ErrCostExceeded, Verify(strings.Replace(hash, "$04$", "$31$", 1), "secret"))
+
+	assert.Equal(t, ErrUnknownAlgorithm, Check("plain"))
+	assert.Equal(t, ErrCostExceeded, Check("$argon2id$v=19$m=4194304,t=1,p=1$c2FsdA$a2V5"))
+	assert.Equal(t, ErrCostExceeded, Verify("$argon2id$v=19$m=1024,t=1000,p=1$c2FsdA$a2V5", "secret"))
+	assert.Equal(t, ErrCostExceeded, Verify("$scrypt$ln=22,r=8,p=1$c2FsdA$a2V5", "secret"))
+
+	s := settings(entity.PasswordAlgorithmArgon2id)
+	s.Argon2Memory = 2 * MaxArgon2Memory
+	_, err := New(s)
+	assert.Equal(t, ErrCostExceeded, errors.Cause(err))
+
+	// INFO: The settings wrapping around into the bounds as uint32 and uint8
+	s = settings(entity.PasswordAlgorithmArgon2id)
+	s.Argon2Memory = 1<<32 + 1024
+	_, err = New(s)
+	assert.Equal(t, ErrCostExceeded, errors.Cause(err))
+
+	s = settings(entity.PasswordAlgorithmArgon2id)
+	s.Argon2Threads = 257
+	_, err = New(s)
+	assert.Equal(t, ErrCostExceeded, errors.Cause(err))
+
+	s = settings(entity.PasswordAlgorithmArgon2id)
+	s.Argon2Time = -1
+	_, err = New(s)
+	assert.Equal(t, ErrInvalidSettings, errors.Cause(err))
+}
@@ -0,0 +1,333 @@
+package hasher
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+)
+
+// The algorithms of the hashes imported from the other systems.
+const (
+	AlgorithmMD5            = "md5"
+	AlgorithmSHA1           = "sha1"
+	AlgorithmSHA256         = "sha256"
+	AlgorithmSHA512         = "sha512"
+	AlgorithmPBKDF2SHA1     = "pbkdf2-sha1"
+	AlgorithmPBKDF2SHA256   = "pbkdf2-sha256"
+	AlgorithmPBKDF2SHA512   = "pbkdf2-sha512"
+	AlgorithmPhpass         = "phpass"
+	AlgorithmFirebaseScrypt = "firebase-scrypt"
+)
+
+var digests = map[string]func() hash.Hash{
+	AlgorithmMD5:          md5.New,
+	AlgorithmSHA1:         sha1.New,
+	AlgorithmSHA256:       sha256.New,
+	AlgorithmSHA512:       sha512.New,
+	AlgorithmPBKDF2SHA1:   sha1.New,
+	AlgorithmPBKDF2SHA256: sha256.New,
+	AlgorithmPBKDF2SHA512: sha512.New,
+}
+
+var b64 = base64.RawStdEncoding
+
+// EncodeSaltedDigest returns the hash of the digest of the salt and the password, the salt is either prepended
+// or appended to the password.
+func EncodeSaltedDigest(algorithm string, saltFirst bool, salt, digest []byte) string {
+	order := "ps"
+	if saltFirst {
+		order = "sp"
+	}
+	return fmt.Sprintf("$%s$o=%s$%s$%s", algorithm, order, b64.EncodeToString(salt), b64.EncodeToString(digest))
+}
+
+// EncodePBKDF2 returns the hash of the PBKDF2 key.
+func EncodePBKDF2(algorithm string, iterations int, salt, key []byte) string {
+	return fmt.Sprintf("$%s$i=%d$%s$%s", algorithm, iterations, b64.EncodeToString(salt), b64.EncodeToString(key))
+}
+
+// FirebaseScryptParams are the hash parameters of the Firebase project the users are exported from.
+type FirebaseScryptParams struct {
+	SignerKey     []byte
+	SaltSeparator []byte
+	Rounds        int
+	MemCost       int
+}
+
+// Validate returns ErrMalformedHash if the rounds or the mem cost aren't set and ErrCostExceeded if they're
+// out of the bounds.
+func (p FirebaseScryptParams) Validate() error {
+	if p.Rounds <= 0 || p.MemCost <= 0 {
+		return ErrMalformedHash
+	}
+	if p.Rounds > MaxFirebaseRounds || p.MemCost > MaxFirebaseMemCost {
+		return ErrCostExceeded
+	}
+	return nil
+}
+
+// EncodeFirebaseScrypt returns the hash of the Firebase modified scrypt, the project parameters are kept in
+// the hash till it's replaced on the next login.
+func EncodeFirebaseScrypt(p FirebaseScryptParams, salt, key []byte) string {
+	return fmt.Sprintf(
+		"$%s$r=%d,m=%d,s=%s,k=%s$%s$%s",
+		AlgorithmFirebaseScrypt,
+		p.Rounds,
+		p.MemCost,
+		b64.EncodeToString(p.SaltSeparator),
+		b64.EncodeToString(p.SignerKey),
+		b64.EncodeToString(salt),
+		b64.EncodeToString(key),
+	)
+}
+
+// split returns the params, the salt and the key of the hash of the algorithm.
+func split(algorithm, hash string) (params string, salt, key []byte, err error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 5 || parts[0] != "" || parts[1] != algorithm {
+		return "", nil, nil, ErrMalformedHash
+	}
+	if salt, err = b64.DecodeString(parts[3]); err != nil {
+		return "", nil, nil, ErrMalformedHash
+	}
+	if key, err = b64.DecodeString(parts[4]); err != nil || len(key) == 0 {
+		return "", nil, nil, ErrMalformedHash
+	}
+
+	return parts[2], salt, key, nil
+}
+
+func equal(expected, actual []byte) error {
+	if subtle.ConstantTimeCompare(expected, actual) != 1 {
+		return ErrMismatch
+	}
+	return nil
+}
+
+type saltedDigest struct {
+	name string
+}
+
+func (v *saltedDigest) Match(hash string) bool {
+	return strings.HasPrefix(hash, "$"+v.name+"$")
+}
+
+func (v *saltedDigest) Verify(hash, password string) error {
+	params, salt, key, err := split(v.name, hash)
+	if err != nil {
+		return err
+	}
+
+	h := digests[v.name]()
+	switch params {
+	case "o=sp":
+		h.Write(salt)
+		h.Write([]byte(password))
+	case "o=ps":
+		h.Write([]byte(password))
+		h.Write(salt)
+	default:
+		return ErrMalformedHash
+	}
+
+	return equal(key, h.Sum(nil))
+}
+
+type pbkdf2Verifier struct {
+	name string
+}
+
+func (v *pbkdf2Verifier) Match(hash string) bool {
+	return strings.HasPrefix(hash, "$"+v.name+"$")
+}
+
+func (v *pbkdf2Verifier) Verify(hash, password string) error {
+	iterations, salt, key, err := v.decode(hash)
+	if err != nil {
+		return err
+	}
+
+	return equal(key, pbkdf2.Key([]byte(password), salt, iterations, len(key), digests[v.name]))
+}
+
+func (v *pbkdf2Verifier) check(hash string) error {
+	_, _, _, err := v.decode(hash)
+	return err
+}
+
+func (v *pbkdf2Verifier) decode(hash string) (iterations int, salt, key []byte, err error) {
+	params, salt, key, err := split(v.name, hash)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+
+	if _, err := fmt.Sscanf(params, "i=%d", &iterations); err != nil || iterations <= 0 {
+		return 0, nil, nil, ErrMalformedHash
+	}
+	if iterations > MaxPBKDF2Iterations {
+		return 0, nil, nil, ErrCostExceeded
+	}
+
+	return iterations, salt, key, nil
+}
+
+const phpassItoa64 = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// phpassVerifier checks the portable hashes of phpass used by WordPress, phpBB and others, they're stored as is.
+type phpassVerifier struct{}
+
+func (v *phpassVerifier) Match(hash string) bool {
+	return len(hash) == 34 && (strings.HasPrefix(hash, "$P$") || strings.HasPrefix(hash, "$H$"))
+}
+
+func (v *phpassVerifier) Verify(hash, password string) error {
+	countLog2, err := v.decode(hash)
+	if err != nil {
+		return err
+	}
+	salt := hash[4:12]
+
+	sum := md5.Sum([]byte(salt + password))
+	for count := 1 << uint(countLog2); count > 0; count-- {
+		sum = md5.Sum(append(sum[:], password...))
+	}
+
+	return equal([]byte(hash[12:]), []byte(phpassEncode(sum[:])))
+}
+
+func (v *phpassVerifier) check(hash string) error {
+	_, err := v.decode(hash)
+	return err
+}
+
+// decode returns the binary logarithm of the iterations count of the hash.
+func (v *phpassVerifier) decode(hash string) (int, error) {
+	if !v.Match(hash) {
+		return 0, ErrMalformedHash
+	}
+
+	countLog2 := strings.IndexByte(phpassItoa64, hash[3])
+	if countLog2 < 7 || countLog2 > 30 {
+		return 0, ErrMalformedHash
+	}
+	if countLog2 > MaxPhpassCountLog2 {
+		return 0, ErrCostExceeded
+	}
+
+	return countLog2, nil
+}
+
+// phpassEncode is the base64 variant of phpass, it differs from the crypt one by the order of the bits.
+func phpassEncode(input []byte) string {
+	var out strings.Builder
+	for i := 0; i < len(input); {
+		value := int(input[i])
+		i++
+		out.WriteByte(phpassItoa64[value&0x3f])
+		if i < len(input) {
+			value |= int(input[i]) << 8
+		}
+		out.WriteByte(phpassItoa64[(value>>6)&0x3f])
+		if i >= len(input) {
+			break
+		}
+		i++
+		if i < len(input) {
+			value |= int(input[i]) << 16
+		}
+		out.WriteByte(phpassItoa64[(value>>12)&0x3f])
+		if i >= len(input) {
+			break
+		}
+		i++
+		out.WriteByte(phpassItoa64[(value>>18)&0x3f])
+	}
+	return out.String()
+}
+
+// firebaseScryptVerifier checks the hashes of the modified scrypt of Firebase: the scrypt key of the password
+// encrypts the signer key of the project with AES-256-CTR.
+type firebaseScryptVerifier struct{}
+
+func (v *firebaseScryptVerifier) Match(hash string) bool {
+	return strings.HasPrefix(hash, "$"+AlgorithmFirebaseScrypt+"$")
+}
+
+func (v *firebaseScryptVerifier) Verify(hash, password string) error {
+	p, salt, key, err := v.decode(hash)
+	if err != nil {
+		return err
+	}
+
+	derived, err := scrypt.Key([]byte(password), append(salt, p.SaltSeparator...), 1<<uint(p.MemCost), p.Rounds, 1, 32)
+	if err != nil {
+		return err
+	}
+	block, err := aes.NewCipher(derived)
+	if err != nil {
+		return err
+	}
+
+	actual := make([]byte, len(p.SignerKey))
+	cipher.NewCTR(block, make([]byte, aes.BlockSize)).XORKeyStream(actual, p.SignerKey)
+
+	return equal(key, actual)
+}
+
+func (v *firebaseScryptVerifier) check(hash string) error {
+	_, _, _, err := v.decode(hash)
+	return err
+}
+
+func (v *firebaseScryptVerifier) decode(hash string) (p FirebaseScryptParams, salt, key []byte, err error) {
+	params, salt, key, err := split(AlgorithmFirebaseScrypt, hash)
+	if err != nil {
+		return p, nil, nil, err
+	}
+	if p, err = parseFirebaseScryptParams(params); err != nil {
+		return p, nil, nil, err
+	}
+
+	return p, salt, key, nil
+}
+
+func parseFirebaseScryptParams(params string) (p FirebaseScryptParams, err error) {
+	for _, param := range strings.Split(params, ",") {
+		kv := strings.SplitN(param, "=", 2)
+		if len(kv) != 2 {
+			return p, ErrMalformedHash
+		}
+		switch kv[0] {
+		case "r":
+			p.Rounds, err = strconv.Atoi(kv[1])
+		case "m":
+			p.MemCost, err = strconv.Atoi(kv[1])
+		case "s":
+			p.SaltSeparator, err = b64.DecodeString(kv[1])
+		case "k":
+			p.SignerKey, err = b64.DecodeString(kv[1])
+		default:
+			err = ErrMalformedHash
+		}
+		if err != nil {
+			return p, ErrMalformedHash
+		}
+	}
+	if len(p.SignerKey) == 0 {
+		return p, ErrMalformedHash
+	}
+
+	return p, p.Validate()
+}
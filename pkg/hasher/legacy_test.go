@@ -0,0 +1,75 @@
+package hasher
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifySaltedDigest(t *testing.T) {
+	sum := md5.Sum([]byte("saltsecret"))
+	hash := EncodeSaltedDigest(AlgorithmMD5, true, []byte("salt"), sum[:])
+
+	assert.Nil(t, Verify(hash, "secret"))
+	assert.Equal(t, ErrMismatch, Verify(hash, "other"))
+
+	hash = EncodeSaltedDigest(AlgorithmMD5, false, []byte("salt"), sum[:])
+	assert.Equal(t, ErrMismatch, Verify(hash, "secret"))
+}
+
+func TestVerifyPBKDF2(t *testing.T) {
+	// RFC 6070
+	key, _ := hex.DecodeString("4b007901b765489abead49d926f721d065a429c1")
+	hash := EncodePBKDF2(AlgorithmPBKDF2SHA1, 4096, []byte("salt"), key)
+
+	assert.Nil(t, Verify(hash, "password"))
+	assert.Equal(t, ErrMismatch, Verify(hash, "other"))
+}
+
+func TestVerifyPhpass(t *testing.T) {
+	hash := "$P$9IQRaTwmfeRo7ud9Fh4E2PdI0S3r.L0"
+
+	assert.Nil(t, Verify(hash, "test12345"))
+	assert.Equal(t, ErrMismatch, Verify(hash, "test12346"))
+}
+
+func TestVerifyFirebaseScrypt(t *testing.T) {
+	decode := func(s string) []byte {
+		b, _ := base64.StdEncoding.DecodeString(s)
+		return b
+	}
+	params := FirebaseScryptParams{
+		SignerKey:     decode("jxspr8Ki0RYycVU8zykbdLGjFQ3McFUH0uiiTvC8pVMXAn210wjLNmdZJzxUECKbm0QsEmYUSDzZvpjeJ9WmXA=="),
+		SaltSeparator: decode("Bw=="),
+		Rounds:        8,
+		MemCost:       14,
+	}
+	hash := EncodeFirebaseScrypt(params, decode("42xEC+ixf3L2lw=="), decode("lSrfV15cpx95/sZS2W9c9Kp6i/LVgQNDNC/qzrCnh1SAyZvqmZqAjTdn3aoItz+VHjoZilo78198JAdRuid5lQ=="))
+
+	assert.Nil(t, Verify(hash, "user1password"))
+	assert.Equal(t, ErrMismatch, Verify(hash, "other"))
+}
+
+func TestLegacyHashIsRehashed(t *testing.T) {
+	sum := md5.Sum([]byte("secret"))
+	hash := EncodeSaltedDigest(AlgorithmMD5, true, nil, sum[:])
+
+	h, _ := New(fast)
+	assert.True(t, Known(hash))
+	assert.True(t, NeedsRehash(h, hash))
+
+	_, err := New(settings(AlgorithmMD5))
+	assert.NotNil(t, err)
+}
+
+func TestLegacyCostBounds(t *testing.T) {
+	assert.Equal(t, ErrCostExceeded, Verify(EncodePBKDF2(AlgorithmPBKDF2SHA256, 1<<30, []byte("salt"), []byte("key")), "password"))
+	assert.Equal(t, ErrCostExceeded, Verify("$P$SIQRaTwmfeRo7ud9Fh4E2PdI0S3r.L0", "test12345"))
+
+	params := FirebaseScryptParams{SignerKey: []byte("key"), Rounds: 8, MemCost: 30}
+	assert.Equal(t, ErrCostExceeded, params.Validate())
+	assert.Equal(t, ErrCostExceeded, Check(EncodeFirebaseScrypt(params, []byte("salt"), []byte("key"))))
+}
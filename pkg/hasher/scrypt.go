@@ -0,0 +1,128 @@
+package hasher
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"math/bits"
+	"strings"
+
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	scryptKeyLen  = 32
+	scryptSaltLen = 16
+)
+
+// scryptParams keeps the cost as the binary logarithm of N like the PHC string does.
+type scryptParams struct {
+	ln int
+	r  int
+	p  int
+}
+
+// validate checks the memory of the hash, it's 128 * r * N bytes.
+func (p scryptParams) validate() error {
+	if p.ln <= 0 || p.ln > 30 || p.r <= 0 || p.p <= 0 {
+		return ErrMalformedHash
+	}
+	if p.r > MaxScryptMemory/128 || uint64(128*p.r)<<uint(p.ln) > MaxScryptMemory || p.p > MaxScryptP {
+		return ErrCostExceeded
+	}
+	return nil
+}
+
+type scryptHasher struct {
+	params scryptParams
+}
+
+func newScrypt(s entity.PasswordSettings) Hasher {
+	d := entity.DefaultPasswordSettings
+	return &scryptHasher{params: scryptParams{
+		ln: bits.Len(uint(orDefault(s.ScryptN, d.ScryptN))) - 1,
+		r:  orDefault(s.ScryptR, d.ScryptR),
+		p:  orDefault(s.ScryptP, d.ScryptP),
+	}}
+}
+
+func (h *scryptHasher) Hash(password string) (string, error) {
+	salt := make([]byte, scryptSaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	p := h.params
+	key, err := scrypt.Key([]byte(password), salt, 1<<uint(p.ln), p.r, p.p, scryptKeyLen)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf(
+		"$%s$ln=%d,r=%d,p=%d$%s$%s",
+		entity.PasswordAlgorithmScrypt,
+		p.ln,
+		p.r,
+		p.p,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func (h *scryptHasher) Verify(hash, password string) error {
+	p, salt, key, err := h.decode(hash)
+	if err != nil {
+		return err
+	}
+
+	actual, err := scrypt.Key([]byte(password), salt, 1<<uint(p.ln), p.r, p.p, len(key))
+	if err != nil {
+		return err
+	}
+	if subtle.ConstantTimeCompare(key, actual) != 1 {
+		return ErrMismatch
+	}
+	return nil
+}
+
+func (h *scryptHasher) Match(hash string) bool {
+	return strings.HasPrefix(hash, "$"+entity.PasswordAlgorithmScrypt+"$")
+}
+
+func (h *scryptHasher) Outdated(hash string) bool {
+	p, _, _, err := h.decode(hash)
+	return err != nil || p != h.params
+}
+
+func (h *scryptHasher) check(hash string) error {
+	_, _, _, err := h.decode(hash)
+	return err
+}
+
+func (h *scryptHasher) validate() error {
+	return h.params.validate()
+}
+
+func (h *scryptHasher) decode(hash string) (p scryptParams, salt, key []byte, err error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 5 || parts[1] != entity.PasswordAlgorithmScrypt {
+		return p, nil, nil, ErrMalformedHash
+	}
+
+	if _, err := fmt.Sscanf(parts[2], "ln=%d,r=%d,p=%d", &p.ln, &p.r, &p.p); err != nil {
+		return p, nil, nil, ErrMalformedHash
+	}
+	if err := p.validate(); err != nil {
+		return p, nil, nil, err
+	}
+
+	if salt, err = base64.RawStdEncoding.DecodeString(parts[3]); err != nil {
+		return p, nil, nil, ErrMalformedHash
+	}
+	if key, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil || len(key) == 0 {
+		return p, nil, nil, ErrMalformedHash
+	}
+
+	return p, salt, key, nil
+}
@@ -1,6 +1,8 @@
 package helper
 
 import (
+	crand "crypto/rand"
+	"math/big"
 	"math/rand"
 	"time"
 )
@@ -30,3 +32,33 @@ func GetRandString(length int) string {
 
 	return string(b)
 }
+
+// GetSecureRandString create and return cryptographically secure random alphanumeric strings fixed length.
+func GetSecureRandString(length int) (string, error) {
+	b := make([]byte, length)
+	max := big.NewInt(int64(len(letterBytes)))
+	for i := range b {
+		n, err := crand.Int(crand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b[i] = letterBytes[n.Int64()]
+	}
+
+	return string(b), nil
+}
+
+// GetRandDigits create and return cryptographically secure random numeric strings fixed length.
+func GetRandDigits(length int) (string, error) {
+	b := make([]byte, length)
+	max := big.NewInt(10)
+	for i := range b {
+		n, err := crand.Int(crand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b[i] = byte('0' + n.Int64())
+	}
+
+	return string(b), nil
+}
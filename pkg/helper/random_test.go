@@ -16,3 +16,17 @@ func TestGetRandStringCheckCharacters(t *testing.T) {
 	str := GetRandString(length)
 	assert.Regexp(t, "^([A-z0-9]{1,})$", str, "The string must contain only letters and numbers.")
 }
+
+func TestGetSecureRandStringCheckCharacters(t *testing.T) {
+	length := 64
+	str, err := GetSecureRandString(length)
+	assert.NoError(t, err)
+	assert.Regexp(t, "^[A-Za-z0-9]{64}$", str, "The string must contain only letters and numbers.")
+}
+
+func TestGetRandDigitsCheckCharacters(t *testing.T) {
+	length := 6
+	str, err := GetRandDigits(length)
+	assert.NoError(t, err)
+	assert.Regexp(t, "^[0-9]{6}$", str, "The string must contain only digits.")
+}
@@ -8,10 +8,14 @@ import (
 	"text/template"
 
 	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/api/apierror"
 	"github.com/ProtocolONE/auth1.protocol.one/pkg/config"
 	"github.com/ProtocolONE/auth1.protocol.one/pkg/database"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/hasher"
 	"github.com/ProtocolONE/auth1.protocol.one/pkg/models"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/passwords"
 	"github.com/ProtocolONE/auth1.protocol.one/pkg/service"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/webhooks"
 	"github.com/globalsign/mgo/bson"
 	"github.com/pkg/errors"
 )
@@ -136,8 +140,8 @@ func (m *ChangePasswordManager) ChangePasswordVerify(form *models.ChangePassword
 		return &models.GeneralError{Code: "client_id", Message: models.ErrorUnknownError, Err: errors.New("Unable to get application space")}
 	}
 
-	if false == space.PasswordSettings.IsValid(form.Password) {
-		return &models.GeneralError{Code: "password", Message: models.ErrorPasswordIncorrect, Err: errors.New(models.ErrorPasswordIncorrect)}
+	if reason := space.PasswordSettings.Check(form.Password); reason != "" {
+		return &models.GeneralError{Code: "password", Message: models.ErrorPasswordIncorrect, Err: apierror.WeakPassword.WithData(map[string]string{"reason": reason})}
 	}
 
 	ipc := space.DefaultIDProvider()
@@ -150,8 +154,16 @@ func (m *ChangePasswordManager) ChangePasswordVerify(form *models.ChangePassword
 		return &models.GeneralError{Code: "common", Message: models.ErrorUnknownError, Err: errors.Wrap(err, "Unable to get user identity")}
 	}
 
-	be := models.NewBcryptEncryptor(&models.CryptConfig{Cost: space.PasswordSettings.BcryptCost})
-	ui.Credential, err = be.Digest(form.Password)
+	// INFO: The current password is the first one of the history
+	if err := checkPassword(m.r, space, form.Password, append([]string{ui.Credential}, ui.PasswordHistory...)); err != nil {
+		return &models.GeneralError{Code: "password", Message: models.ErrorPasswordIncorrect, Err: err}
+	}
+
+	ui.PasswordHistory = passwords.History(space.PasswordSettings, ui.Credential, ui.PasswordHistory)
+	h, err := hasher.New(space.PasswordSettings)
+	if err == nil {
+		ui.Credential, err = h.Hash(form.Password)
+	}
 	if err != nil {
 		return &models.GeneralError{Code: "password", Message: models.ErrorCryptPassword, Err: errors.Wrap(err, "Unable to crypt password")}
 	}
@@ -160,6 +172,10 @@ func (m *ChangePasswordManager) ChangePasswordVerify(form *models.ChangePassword
 		return &models.GeneralError{Code: "password", Message: models.ErrorUnableChangePassword, Err: errors.Wrap(err, "Unable to update password: "+err.Error())}
 	}
 
+	resetLockout(context.TODO(), m.r, space, ts.Email)
+	publish(context.TODO(), m.r, app, ui.UserID, webhooks.PasswordChangedEvent{})
+	publish(context.TODO(), m.r, app, ui.UserID, webhooks.PasswordResetEvent{Email: ts.Email})
+
 	return nil
 }
 
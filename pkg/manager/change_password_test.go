@@ -5,9 +5,13 @@ import (
 
 	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
 	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/repository"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/repository/webhook_delivery/memory"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/api/apierror"
 	"github.com/ProtocolONE/auth1.protocol.one/pkg/config"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/hasher"
 	"github.com/ProtocolONE/auth1.protocol.one/pkg/mocks"
 	"github.com/ProtocolONE/auth1.protocol.one/pkg/models"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/webhooks"
 	"github.com/globalsign/mgo/bson"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
@@ -19,9 +23,12 @@ type changePasswordTest struct {
 	app    *mocks.ApplicationServiceInterface
 	ott    *mocks.OneTimeTokenServiceInterface
 	mailer *mocks.MailerInterface
+	la     *mocks.LoginAttemptsInterface
 	r      *mocks.InternalRegistry
 	m      *ChangePasswordManager
 
+	deliveries *memory.WebhookDeliveryRepository
+
 	space *entity.Space
 }
 
@@ -31,7 +38,10 @@ func newChangePasswordTest() *changePasswordTest {
 		app:    &mocks.ApplicationServiceInterface{},
 		ott:    &mocks.OneTimeTokenServiceInterface{},
 		mailer: &mocks.MailerInterface{},
+		la:     &mocks.LoginAttemptsInterface{},
 		r:      &mocks.InternalRegistry{},
+
+		deliveries: memory.New(),
 		space: &entity.Space{
 			PasswordSettings: entity.PasswordSettings{Min: 1, Max: 8, BcryptCost: 4},
 			IdentityProviders: entity.IdentityProviders{{
@@ -46,19 +56,23 @@ func newChangePasswordTest() *changePasswordTest {
 }
 
 func (test *changePasswordTest) init() {
-	test.app.On("Get", mock.Anything).Return(&models.Application{}, nil)
+	test.app.On("Get", mock.Anything).Return(&models.Application{WebHooks: []string{"http://localhost/hook"}}, nil)
 	test.ott.On("Create", mock.Anything, mock.Anything).Return(&models.OneTimeToken{}, nil)
 	test.mailer.On("Send", mock.Anything, mock.Anything, mock.Anything).Return(nil)
 	test.ui.On("Get", mock.Anything, mock.Anything, mock.Anything).Return(&models.UserIdentity{ID: bson.NewObjectId()}, nil)
 	test.ui.On("Update", mock.Anything).Return(nil)
+	test.la.On("Reset", mock.Anything).Return(nil)
 	test.r.On("ApplicationService").Return(test.app)
 	test.r.On("OneTimeTokenService").Return(test.ott)
 	test.r.On("Mailer").Return(test.mailer)
 	test.r.On("Spaces").Return(repository.OneSpaceRepo(test.space))
+	test.r.On("WebHooks").Return(webhooks.NewWebhooks(test.deliveries))
+	test.r.On("LoginAttempts").Return(test.la)
 
 	test.ott.On("Use", mock.Anything, mock.MatchedBy(
 		func(ts *models.ChangePasswordTokenSource) bool {
 			ts.ClientID = bson.NewObjectId().Hex()
+			ts.Email = "email"
 			return true
 		})).Return(nil)
 
@@ -77,6 +91,7 @@ func TestChangePasswordStartReturnNilOnSuccessResult(t *testing.T) {
 
 	err := test.m.ChangePasswordStart(&models.ChangePasswordStartForm{ClientID: bson.NewObjectId().Hex()})
 	assert.Nil(t, err)
+	assert.Empty(t, publishedActions(t, test.deliveries))
 }
 
 func TestChangePasswordVerifyReturnNilOnSuccessResult(t *testing.T) {
@@ -85,6 +100,8 @@ func TestChangePasswordVerifyReturnNilOnSuccessResult(t *testing.T) {
 
 	err := test.m.ChangePasswordVerify(&models.ChangePasswordVerifyForm{Password: "1", PasswordRepeat: "1", ClientID: bson.NewObjectId().Hex()})
 	assert.Nil(t, err)
+	assert.Equal(t, []string{webhooks.PasswordChangedAction, webhooks.PasswordResetAction}, publishedActions(t, test.deliveries))
+	test.la.AssertCalled(t, "Reset", identityLockKey(test.space.ID, "email"))
 }
 
 ///////////////////////////////////////////////////////////////////////
@@ -184,6 +201,35 @@ func TestChangePasswordVerifyReturnErrorWithInvalidPassword(t *testing.T) {
 	}
 }
 
+func TestChangePasswordVerifyReturnErrorWithReusedPassword(t *testing.T) {
+	test := newChangePasswordTest()
+	test.space.PasswordSettings.HistorySize = 2
+	h, _ := hasher.New(test.space.PasswordSettings)
+	old, _ := h.Hash("1")
+	test.ui.On("Get", mock.Anything, mock.Anything, mock.Anything).Return(&models.UserIdentity{ID: bson.NewObjectId(), Credential: "current", PasswordHistory: []string{old}}, nil)
+	test.init()
+
+	err := test.m.ChangePasswordVerify(&models.ChangePasswordVerifyForm{Password: "1", PasswordRepeat: "1", ClientID: bson.NewObjectId().Hex()})
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "password", err.Code)
+		assert.Equal(t, apierror.WeakPassword.WithData(map[string]string{"reason": entity.PasswordReused}), err.Err)
+	}
+	test.ui.AssertNotCalled(t, "Update", mock.Anything)
+}
+
+func TestChangePasswordVerifyKeepsPasswordHistory(t *testing.T) {
+	test := newChangePasswordTest()
+	test.space.PasswordSettings.HistorySize = 2
+	ui := &models.UserIdentity{ID: bson.NewObjectId(), Credential: "current", PasswordHistory: []string{"previous"}}
+	test.ui.On("Get", mock.Anything, mock.Anything, mock.Anything).Return(ui, nil)
+	test.init()
+
+	err := test.m.ChangePasswordVerify(&models.ChangePasswordVerifyForm{Password: "1", PasswordRepeat: "1", ClientID: bson.NewObjectId().Hex()})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"current"}, ui.PasswordHistory)
+	assert.Nil(t, hasher.Verify(ui.Credential, "1"))
+}
+
 func TestChangePasswordVerifyReturnErrorWithUseToken(t *testing.T) {
 	test := newChangePasswordTest()
 	test.ott.On("Use", mock.Anything, mock.Anything).Return(errors.New(""))
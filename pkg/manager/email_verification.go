@@ -0,0 +1,191 @@
+package manager
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"text/template"
+	"time"
+
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/api/apierror"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/config"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/database"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/models"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/service"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/webhooks"
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/labstack/echo/v4"
+	"github.com/ory/hydra-client-go/client/admin"
+	"github.com/pkg/errors"
+)
+
+// emailVerificationResendInterval is the minimal interval between the verification emails of the user.
+const emailVerificationResendInterval = time.Minute
+
+// EmailVerificationManagerInterface describes of methods for the manager.
+type EmailVerificationManagerInterface interface {
+	// EmailVerificationStart sends the verification link to the user registered with the email in the default
+	// identity provider of the application space. Nothing is sent if the email is unknown or already verified.
+	EmailVerificationStart(echo.Context, *models.EmailVerificationStartForm) error
+
+	// EmailVerificationSend creates a one-time token and sends the link with it to the user's email.
+	// The emails are sent not more often than once a minute.
+	EmailVerificationSend(space *entity.Space, app *models.Application, user *models.User, challenge string) error
+
+	// EmailVerificationVerify marks the email address as verified by the one-time token from the link.
+	EmailVerificationVerify(*models.EmailVerificationForm) error
+}
+
+// EmailVerificationManager is the email verification manager.
+type EmailVerificationManager struct {
+	r                   service.InternalRegistry
+	userService         service.UserServiceInterface
+	userIdentityService service.UserIdentityServiceInterface
+	TplCfg              *config.MailTemplates
+}
+
+// NewEmailVerificationManager return new email verification manager.
+func NewEmailVerificationManager(db database.MgoSession, ir service.InternalRegistry, tplCfg *config.MailTemplates) EmailVerificationManagerInterface {
+	m := &EmailVerificationManager{
+		r:                   ir,
+		userService:         service.NewUserService(db),
+		userIdentityService: service.NewUserIdentityService(db),
+		TplCfg:              tplCfg,
+	}
+
+	return m
+}
+
+func (m *EmailVerificationManager) EmailVerificationStart(ctx echo.Context, form *models.EmailVerificationStartForm) error {
+	if !bson.IsObjectIdHex(form.ClientID) {
+		return apierror.InvalidClient
+	}
+
+	req, err := m.r.HydraAdminApi().GetLoginRequest(&admin.GetLoginRequestParams{Context: ctx.Request().Context(), LoginChallenge: form.Challenge})
+	if err != nil {
+		return apierror.InvalidChallenge
+	}
+	if req.Payload.Client.ClientID != form.ClientID {
+		return apierror.InvalidClient
+	}
+
+	app, err := m.r.ApplicationService().Get(bson.ObjectIdHex(form.ClientID))
+	if err != nil {
+		return apierror.InvalidClient
+	}
+
+	space, err := m.r.Spaces().FindByID(context.TODO(), entity.SpaceID(app.SpaceId.Hex()))
+	if err != nil {
+		return errors.Wrap(err, "unable to load space")
+	}
+
+	ui, err := m.userIdentityService.Get(models.OldIDProvider(space.DefaultIDProvider()), form.Email)
+	if err == mgo.ErrNotFound {
+		// INFO: Do not need to disclose the login
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, "unable to get user identity by email")
+	}
+
+	user, err := m.userService.Get(ui.UserID)
+	if err != nil {
+		return errors.Wrap(err, "unable to get user")
+	}
+	if user.EmailVerified || user.Email != form.Email {
+		return nil
+	}
+
+	err = m.EmailVerificationSend(space, app, user, form.Challenge)
+	if err == apierror.VerificationThrottled {
+		// INFO: Do not need to disclose the login by the throttled response
+		return nil
+	}
+	return err
+}
+
+func (m *EmailVerificationManager) EmailVerificationSend(space *entity.Space, app *models.Application, user *models.User, challenge string) error {
+	ok, err := m.r.RateLimiter().Allow("email_verification_"+user.ID.Hex(), emailVerificationResendInterval)
+	if err != nil {
+		return errors.Wrap(err, "unable to check the resend interval")
+	}
+	if !ok {
+		return apierror.VerificationThrottled
+	}
+
+	token, err := m.r.OneTimeTokenService().Create(&models.EmailVerificationTokenSource{
+		UserID:    user.ID.Hex(),
+		Email:     user.Email,
+		ClientID:  app.ID.Hex(),
+		Challenge: challenge,
+	}, &models.OneTimeTokenSettings{
+		Length: space.PasswordSettings.TokenLength,
+		TTL:    space.PasswordSettings.TokenTTL,
+	})
+	if err != nil {
+		return errors.Wrap(err, "unable to create one-time token")
+	}
+
+	b, err := ioutil.ReadFile(m.TplCfg.VerifyEmailTpl)
+	if err != nil {
+		return errors.Wrap(err, "unable to read verification mail template")
+	}
+	tmpl, err := template.New("mail").Parse(string(b))
+	if err != nil {
+		return errors.Wrap(err, "unable to parse verification mail template")
+	}
+	w := bytes.Buffer{}
+	err = tmpl.Execute(&w, struct {
+		UserName         string
+		PlatformName     string
+		VerifyLink       string
+		SupportPortalUrl string
+	}{
+		UserName:         user.Username,
+		PlatformName:     m.TplCfg.PlatformName,
+		VerifyLink:       fmt.Sprintf("%s/verify-email?login_challenge=%s&token=%s", m.TplCfg.PlatformUrl, url.QueryEscape(challenge), token.Token),
+		SupportPortalUrl: m.TplCfg.SupportPortalUrl,
+	})
+	if err != nil {
+		return errors.Wrap(err, "unable to build verification mail")
+	}
+
+	if err := m.r.Mailer().Send(user.Email, "Verify email address", w.String()); err != nil {
+		return errors.Wrap(err, "unable to send mail with verification link")
+	}
+
+	return nil
+}
+
+func (m *EmailVerificationManager) EmailVerificationVerify(form *models.EmailVerificationForm) error {
+	ts := &models.EmailVerificationTokenSource{}
+	if err := m.r.OneTimeTokenService().Use(form.Token, ts); err != nil {
+		return apierror.InvalidToken
+	}
+
+	user, err := m.userService.Get(bson.ObjectIdHex(ts.UserID))
+	if err != nil {
+		return errors.Wrap(err, "unable to get user")
+	}
+	if user.Email != ts.Email {
+		// INFO: The email has been changed after the link was sent
+		return apierror.InvalidToken
+	}
+	if user.EmailVerified {
+		return nil
+	}
+
+	user.EmailVerified = true
+	user.UpdatedAt = time.Now()
+	if err := m.userService.Update(user); err != nil {
+		return errors.Wrap(err, "unable to update user")
+	}
+
+	publishClient(context.TODO(), m.r, ts.ClientID, user.ID, webhooks.EmailVerifiedEvent{Email: user.Email})
+
+	return nil
+}
@@ -0,0 +1,181 @@
+package manager
+
+import (
+	"testing"
+
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/repository"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/repository/webhook_delivery/memory"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/api/apierror"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/config"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/mocks"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/models"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/webhooks"
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/ory/hydra-client-go/client/admin"
+	models2 "github.com/ory/hydra-client-go/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type emailVerificationTest struct {
+	app    *mocks.ApplicationServiceInterface
+	h      *mocks.HydraAdminApi
+	uis    *mocks.UserIdentityServiceInterface
+	us     *mocks.UserServiceInterface
+	ott    *mocks.OneTimeTokenServiceInterface
+	rl     *mocks.RateLimiterInterface
+	mailer *mocks.MailerInterface
+	r      *mocks.InternalRegistry
+	m      *EmailVerificationManager
+
+	deliveries *memory.WebhookDeliveryRepository
+
+	clientID string
+	user     *models.User
+	space    *entity.Space
+}
+
+func newEmailVerificationTest() *emailVerificationTest {
+	r, deliveries := mockIntRegistryWithWebHooks()
+	return &emailVerificationTest{
+		app:        &mocks.ApplicationServiceInterface{},
+		h:          &mocks.HydraAdminApi{},
+		uis:        &mocks.UserIdentityServiceInterface{},
+		us:         &mocks.UserServiceInterface{},
+		ott:        &mocks.OneTimeTokenServiceInterface{},
+		rl:         &mocks.RateLimiterInterface{},
+		mailer:     &mocks.MailerInterface{},
+		r:          r,
+		deliveries: deliveries,
+		clientID:   bson.NewObjectId().Hex(),
+		user:       &models.User{ID: bson.NewObjectId(), Email: "email"},
+		space: &entity.Space{
+			PasswordSettings: entity.PasswordSettings{TokenLength: 16, TokenTTL: 60},
+			IdentityProviders: entity.IdentityProviders{{
+				ID:          entity.IdentityProviderID(bson.NewObjectId().Hex()),
+				Type:        entity.IDProviderTypePassword,
+				Name:        entity.IDProviderNameDefault,
+				DisplayName: "Initial connection",
+			}},
+		},
+	}
+}
+
+func (test *emailVerificationTest) init() {
+	test.app.On("Get", mock.Anything).Return(&models.Application{ID: bson.ObjectIdHex(test.clientID), WebHooks: []string{"http://localhost/hook"}}, nil)
+
+	test.h.On("GetLoginRequest", mock.Anything).Return(&admin.GetLoginRequestOK{Payload: &models2.LoginRequest{
+		Client: &models2.OAuth2Client{ClientID: test.clientID},
+	}}, nil)
+
+	test.uis.On("Get", mock.Anything, "email").Return(&models.UserIdentity{ID: bson.NewObjectId(), UserID: test.user.ID}, nil)
+	test.us.On("Get", mock.Anything).Return(test.user, nil)
+	test.us.On("Update", mock.Anything).Return(nil)
+
+	test.ott.On("Create", mock.Anything, mock.Anything).Return(&models.OneTimeToken{Token: "token"}, nil)
+	test.ott.On("Use", "token", mock.MatchedBy(
+		func(ts *models.EmailVerificationTokenSource) bool {
+			ts.UserID = test.user.ID.Hex()
+			ts.Email = "email"
+			ts.ClientID = test.clientID
+			ts.Challenge = "login_challenge"
+			return true
+		})).Return(nil)
+	test.ott.On("Use", mock.Anything, mock.Anything).Return(mgo.ErrNotFound)
+
+	test.rl.On("Allow", mock.Anything, emailVerificationResendInterval).Return(true, nil)
+	test.mailer.On("Send", "email", mock.Anything, mock.Anything).Return(nil)
+
+	test.r.On("ApplicationService").Return(test.app)
+	test.r.On("HydraAdminApi").Return(test.h)
+	test.r.On("OneTimeTokenService").Return(test.ott)
+	test.r.On("RateLimiter").Return(test.rl)
+	test.r.On("Mailer").Return(test.mailer)
+	test.r.On("Spaces").Return(repository.OneSpaceRepo(test.space))
+
+	test.m = &EmailVerificationManager{
+		r:                   test.r,
+		userService:         test.us,
+		userIdentityService: test.uis,
+		TplCfg: &config.MailTemplates{
+			VerifyEmailTpl: "../../public/templates/email/verify_email.html",
+		},
+	}
+}
+
+func (test *emailVerificationTest) startForm() *models.EmailVerificationStartForm {
+	return &models.EmailVerificationStartForm{ClientID: test.clientID, Challenge: "login_challenge", Email: "email"}
+}
+
+func TestEmailVerificationStartSendsMail(t *testing.T) {
+	test := newEmailVerificationTest()
+	test.init()
+
+	assert.Nil(t, test.m.EmailVerificationStart(getContext(), test.startForm()))
+	test.mailer.AssertNumberOfCalls(t, "Send", 1)
+}
+
+func TestEmailVerificationStartDoesNotSendMailIfUserNotFound(t *testing.T) {
+	test := newEmailVerificationTest()
+	test.uis.On("Get", mock.Anything, "email").Return(nil, mgo.ErrNotFound)
+	test.init()
+
+	assert.Nil(t, test.m.EmailVerificationStart(getContext(), test.startForm()))
+	test.mailer.AssertNotCalled(t, "Send", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestEmailVerificationStartDoesNotSendMailIfEmailVerified(t *testing.T) {
+	test := newEmailVerificationTest()
+	test.user.EmailVerified = true
+	test.init()
+
+	assert.Nil(t, test.m.EmailVerificationStart(getContext(), test.startForm()))
+	test.mailer.AssertNotCalled(t, "Send", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestEmailVerificationStartReturnErrorWithForeignChallenge(t *testing.T) {
+	test := newEmailVerificationTest()
+	test.init()
+
+	form := test.startForm()
+	form.ClientID = bson.NewObjectId().Hex()
+	assert.Equal(t, apierror.InvalidClient, test.m.EmailVerificationStart(getContext(), form))
+}
+
+func TestEmailVerificationStartDoesNotDiscloseThrottling(t *testing.T) {
+	test := newEmailVerificationTest()
+	test.rl.On("Allow", mock.Anything, mock.Anything).Return(false, nil)
+	test.init()
+
+	assert.Nil(t, test.m.EmailVerificationStart(getContext(), test.startForm()))
+	test.mailer.AssertNotCalled(t, "Send", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestEmailVerificationVerifyMarksEmailVerified(t *testing.T) {
+	test := newEmailVerificationTest()
+	test.init()
+
+	assert.Nil(t, test.m.EmailVerificationVerify(&models.EmailVerificationForm{Token: "token"}))
+	assert.True(t, test.user.EmailVerified)
+	test.us.AssertNumberOfCalls(t, "Update", 1)
+	assert.Equal(t, []string{webhooks.EmailVerifiedAction}, publishedActions(t, test.deliveries))
+}
+
+func TestEmailVerificationVerifyReturnErrorIfEmailChanged(t *testing.T) {
+	test := newEmailVerificationTest()
+	test.user.Email = "changed"
+	test.init()
+
+	assert.Equal(t, apierror.InvalidToken, test.m.EmailVerificationVerify(&models.EmailVerificationForm{Token: "token"}))
+	assert.False(t, test.user.EmailVerified)
+	test.us.AssertNotCalled(t, "Update", mock.Anything)
+}
+
+func TestEmailVerificationVerifyReturnErrorWithUnknownToken(t *testing.T) {
+	test := newEmailVerificationTest()
+	test.init()
+
+	assert.Equal(t, apierror.InvalidToken, test.m.EmailVerificationVerify(&models.EmailVerificationForm{Token: "unknown"}))
+}
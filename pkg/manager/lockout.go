@@ -0,0 +1,147 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/api/apierror"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/appcore/log"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/models"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/service"
+	"github.com/globalsign/mgo/bson"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// The limits of the failed mfa codes of the user, every password login issues the new mfa token, so the attempts
+// of the token alone don't limit the guesses of the one who knows the password.
+const (
+	mfaMaxAttempts = 10
+	mfaWindow      = 15 * time.Minute
+	mfaLock        = 15 * time.Minute
+)
+
+// identityLockKey is the key of the failed logins to the identity, the unknown logins are counted too,
+// so the errors don't disclose the registered ones.
+func identityLockKey(spaceID entity.SpaceID, login string) string {
+	return fmt.Sprintf("identity_%s_%s", spaceID, strings.ToLower(login))
+}
+
+func ipLockKey(ip string) string {
+	return "ip_" + ip
+}
+
+// checkLockout returns the error if the password logins to the identity or from the ip address are blocked.
+func checkLockout(r service.InternalRegistry, space *entity.Space, login, ip string) error {
+	s := space.LockoutSettings
+	if s.MaxAttempts <= 0 && s.IPMaxAttempts <= 0 && s.Delay <= 0 {
+		return nil
+	}
+
+	lock, delay, err := r.LoginAttempts().Blocked(identityLockKey(space.ID, login))
+	if err != nil {
+		return errors.Wrap(err, "unable to check the identity lockout")
+	}
+	if lock > 0 {
+		return apierror.AccountLocked.WithData(retryAfter(lock))
+	}
+	if delay > 0 {
+		return apierror.TooManyLoginAttempts.WithData(retryAfter(delay))
+	}
+
+	if s.IPMaxAttempts > 0 {
+		lock, _, err := r.LoginAttempts().Blocked(ipLockKey(ip))
+		if err != nil {
+			return errors.Wrap(err, "unable to check the ip address lockout")
+		}
+		if lock > 0 {
+			return apierror.TooManyLoginAttempts.WithData(retryAfter(lock))
+		}
+	}
+
+	return nil
+}
+
+// failLogin counts the failed password login and blocks the identity or the ip address on reaching the limits.
+func failLogin(ctx context.Context, r service.InternalRegistry, space *entity.Space, login, ip string) {
+	s := space.LockoutSettings
+	window := time.Duration(s.Window) * time.Second
+	lockDuration := time.Duration(s.LockDuration) * time.Second
+
+	if s.MaxAttempts > 0 || s.Delay > 0 {
+		key := identityLockKey(space.ID, login)
+		n, err := r.LoginAttempts().Fail(key, window)
+		if err == nil {
+			if s.MaxAttempts > 0 && n >= s.MaxAttempts {
+				err = r.LoginAttempts().Lock(key, lockDuration)
+			} else if d := s.DelayAfter(n); d > 0 {
+				err = r.LoginAttempts().Delay(key, d)
+			}
+		}
+		if err != nil {
+			log.Error(ctx, "Unable to block the identity logins", zap.Error(err))
+		}
+	}
+
+	if s.IPMaxAttempts > 0 {
+		key := ipLockKey(ip)
+		n, err := r.LoginAttempts().Fail(key, window)
+		if err == nil && n >= s.IPMaxAttempts {
+			err = r.LoginAttempts().Lock(key, lockDuration)
+		}
+		if err != nil {
+			log.Error(ctx, "Unable to block the ip address logins", zap.Error(err))
+		}
+	}
+}
+
+// resetLockout clears the failed logins of the identity after the successful login or the password change.
+func resetLockout(ctx context.Context, r service.InternalRegistry, space *entity.Space, login string) {
+	if err := r.LoginAttempts().Reset(identityLockKey(space.ID, login)); err != nil {
+		log.Error(ctx, "Unable to reset the identity lockout", zap.Error(err))
+	}
+}
+
+func mfaLockKey(userID bson.ObjectId) string {
+	return "mfa_" + userID.Hex()
+}
+
+// checkMfaLockout returns the error if the mfa verification of the user is blocked.
+func checkMfaLockout(r service.InternalRegistry, userID bson.ObjectId) *models.GeneralError {
+	lock, _, err := r.LoginAttempts().Blocked(mfaLockKey(userID))
+	if err != nil {
+		return &models.GeneralError{Code: "common", Message: models.ErrorUnknownError, Err: errors.Wrap(err, "Unable to check the mfa lockout")}
+	}
+	if lock > 0 {
+		return &models.GeneralError{Code: "common", Message: models.ErrorAuthTemporaryLocked, Err: errors.New("Too many failed MFA codes")}
+	}
+
+	return nil
+}
+
+// failMfa counts the failed mfa code of the user and blocks the verification on reaching the limit.
+func failMfa(ctx context.Context, r service.InternalRegistry, userID bson.ObjectId) {
+	key := mfaLockKey(userID)
+	n, err := r.LoginAttempts().Fail(key, mfaWindow)
+	if err == nil && n >= mfaMaxAttempts {
+		err = r.LoginAttempts().Lock(key, mfaLock)
+	}
+	if err != nil {
+		log.Error(ctx, "Unable to block the mfa verification", zap.Error(err))
+	}
+}
+
+// resetMfaLockout clears the failed mfa codes of the user after the successful verification.
+func resetMfaLockout(ctx context.Context, r service.InternalRegistry, userID bson.ObjectId) {
+	if err := r.LoginAttempts().Reset(mfaLockKey(userID)); err != nil {
+		log.Error(ctx, "Unable to reset the mfa lockout", zap.Error(err))
+	}
+}
+
+func retryAfter(d time.Duration) map[string]int {
+	return map[string]int{"retry_after": int(math.Ceil(d.Seconds()))}
+}
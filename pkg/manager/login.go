@@ -8,9 +8,13 @@ import (
 	"time"
 
 	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/api/apierror"
 	"github.com/ProtocolONE/auth1.protocol.one/pkg/database"
 	"github.com/ProtocolONE/auth1.protocol.one/pkg/models"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/oidc"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/saml"
 	"github.com/ProtocolONE/auth1.protocol.one/pkg/service"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/webhooks"
 	"github.com/globalsign/mgo"
 	"github.com/globalsign/mgo/bson"
 	"github.com/labstack/echo/v4"
@@ -33,8 +37,12 @@ type LoginManagerInterface interface {
 	// ForwardUrl returns url for forwarding user to id provider
 	ForwardUrl(challenge, provider, domain, launcher string) (string, error)
 
-	// Get user's identity and social identity
-	GetUserIdentities(challenge, provider, domain, code string) (UserIdentity *models.UserIdentity, UserIdentitySocial *models.UserIdentitySocial, err error)
+	// SAMLState returns the state of the SAML request kept by the relay state
+	SAMLState(relayState string) (*State, error)
+
+	// Get user's identity and social identity, the code is the SAML response for the SAML providers and the query
+	// of the OpenID 2.0 response for the Steam providers
+	GetUserIdentities(state *State, provider, domain, code string) (UserIdentity *models.UserIdentity, UserIdentitySocial *models.UserIdentitySocial, err error)
 
 	// Accept accepts login request
 	Accept(ctx echo.Context, ui *models.UserIdentity, provider, challenge string) (string, error)
@@ -60,6 +68,7 @@ type LoginManager struct {
 	userService             service.UserServiceInterface
 	userIdentityService     service.UserIdentityServiceInterface
 	mfaService              service.MfaServiceInterface
+	webAuthnService         service.WebAuthnServiceInterface
 	authLogService          service.AuthLogServiceInterface
 	identityProviderService service.AppIdentityProviderServiceInterface
 	r                       service.InternalRegistry
@@ -72,6 +81,7 @@ func NewLoginManager(h database.MgoSession, r service.InternalRegistry) LoginMan
 		userService:             service.NewUserService(h),
 		userIdentityService:     service.NewUserIdentityService(h),
 		mfaService:              service.NewMfaService(h),
+		webAuthnService:         service.NewWebAuthnService(h),
 		authLogService:          service.NewAuthLogService(h, r.GeoIpService()),
 		identityProviderService: service.NewAppIdentityProviderService(r.Spaces()),
 	}
@@ -82,6 +92,20 @@ func NewLoginManager(h database.MgoSession, r service.InternalRegistry) LoginMan
 type State struct {
 	Challenge string `json:"challenge`
 	Launcher  string `json:"launcher"`
+	// OIDC is the one-time token of the nonce and the code verifier of the OpenID Connect request.
+	OIDC string `json:"oidc,omitempty"`
+	// SAML is the id of the SAML request, it is restored from the relay state only.
+	SAML string `json:"-"`
+	// Steam is the one-time token of the login challenge of the Steam request, the response is accepted once.
+	Steam string `json:"steam,omitempty"`
+	// AppleUser is the user Apple posts with the name on the first login only.
+	AppleUser string `json:"-"`
+}
+
+// samlRequest is kept by the one-time token sent as the relay state of the SAML request.
+type samlRequest struct {
+	State     *State `json:"state"`
+	RequestID string `json:"request_id"`
 }
 
 func DecodeState(state string) (*State, error) {
@@ -97,6 +121,19 @@ func DecodeState(state string) (*State, error) {
 	return &s, nil
 }
 
+func (m *LoginManager) SAMLState(relayState string) (*State, error) {
+	var r samlRequest
+	if err := m.r.OneTimeTokenService().Use(relayState, &r); err != nil {
+		return nil, errors.Wrap(err, "unable to use saml relay state")
+	}
+	if r.State == nil || r.RequestID == "" {
+		return nil, errors.New("invalid saml relay state")
+	}
+
+	r.State.SAML = r.RequestID
+	return r.State, nil
+}
+
 type SocialToken struct {
 	UserIdentityID string                     `json:"user_ident"`
 	Profile        *models.UserIdentitySocial `json:"profile"`
@@ -128,8 +165,8 @@ func (m *LoginManager) Providers(challenge string) ([]entity.IdentityProvider, e
 	return space.SocialProviders(), nil
 }
 
-func (m *LoginManager) GetUserIdentities(challenge, provider, domain, code string) (UserIdentity *models.UserIdentity, UserIdentitySocial *models.UserIdentitySocial, err error) {
-	req, err := m.r.HydraAdminApi().GetLoginRequest(&admin.GetLoginRequestParams{LoginChallenge: challenge, Context: context.TODO()})
+func (m *LoginManager) GetUserIdentities(state *State, provider, domain, code string) (UserIdentity *models.UserIdentity, UserIdentitySocial *models.UserIdentitySocial, err error) {
+	req, err := m.r.HydraAdminApi().GetLoginRequest(&admin.GetLoginRequestParams{LoginChallenge: state.Challenge, Context: context.TODO()})
 	if err != nil {
 		return nil, nil, errors.Wrap(err, "can't get challenge data")
 	}
@@ -144,7 +181,36 @@ func (m *LoginManager) GetUserIdentities(challenge, provider, domain, code strin
 		return nil, nil, errors.New("identity provider not found")
 	}
 
-	clientProfile, err := m.identityProviderService.GetSocialProfile(context.TODO(), domain, code, ip)
+	var clientProfile *models.UserIdentitySocial
+	switch ip.Type {
+	case models.AppIdentityProviderTypeOIDC:
+		authReq := &oidc.AuthRequest{}
+		if err := m.r.OneTimeTokenService().Use(state.OIDC, authReq); err != nil {
+			return nil, nil, errors.Wrap(err, "unable to use openid connect request token")
+		}
+		clientProfile, err = m.identityProviderService.GetOIDCProfile(context.TODO(), domain, code, ip, authReq)
+	case models.AppIdentityProviderTypeSAML:
+		// INFO: The unsolicited responses aren't accepted, the response must answer the request of the relay state
+		if state.SAML == "" {
+			return nil, nil, errors.New("saml request is missing")
+		}
+		sp := service.SAMLServiceProvider(domain, entity.SpaceID(app.SpaceId.Hex()), m.r.SAMLKey())
+		clientProfile, err = m.identityProviderService.GetSAMLProfile(domain, sp, code, ip, state.SAML)
+	case models.AppIdentityProviderTypeSteam:
+		var challenge string
+		if err := m.r.OneTimeTokenService().Use(state.Steam, &challenge); err != nil {
+			return nil, nil, errors.Wrap(err, "unable to use steam request token")
+		}
+		if challenge != state.Challenge {
+			return nil, nil, errors.New("steam request doesn't match login challenge")
+		}
+		clientProfile, err = m.identityProviderService.GetSteamProfile(context.TODO(), domain, code, ip)
+	default:
+		clientProfile, err = m.identityProviderService.GetSocialProfile(context.TODO(), domain, code, ip)
+		if err == nil && state.AppleUser != "" && ip.Name == models.AppIdentityProviderNameApple {
+			err = service.ParseAppleUser(state.AppleUser, clientProfile)
+		}
+	}
 	if err != nil || clientProfile == nil || clientProfile.ID == "" {
 		if err == nil {
 			err = errors.New("unable to load identity profile data")
@@ -176,10 +242,33 @@ func (m *LoginManager) Accept(ctx echo.Context, ui *models.UserIdentity, provide
 		return "", errors.New("identity provider not found")
 	}
 
+	user, err := m.userService.Get(ui.UserID)
+	if err != nil {
+		return "", errors.Wrap(err, "unable to get user")
+	}
+	if user.IsBlocked(time.Now()) {
+		return "", apierror.UserBlocked
+	}
+	if space.RequiresVerifiedEmail && !user.EmailVerified {
+		return "", apierror.EmailNotVerified
+	}
+
 	if err := m.authLogService.Add(ctx, service.ActionAuth, ui, app, &ip); err != nil {
 		return "", errors.Wrap(err, "unable to add auth log")
 	}
 
+	// INFO: The login challenge is accepted by MFAVerify if the user has to pass the second factor
+	err = requireMfa(m.r, m.mfaService, m.webAuthnService, space, user, &models.UserMfaToken{
+		UserIdentity: ui,
+		Challenge:    challenge,
+		Remember:     true,
+		ClientID:     app.ID.Hex(),
+		Provider:     provider,
+	})
+	if err != nil {
+		return "", err
+	}
+
 	id := ui.UserID.Hex()
 	reqACL, err := m.r.HydraAdminApi().AcceptLoginRequest(&admin.AcceptLoginRequestParams{
 		Context:        context.TODO(),
@@ -190,6 +279,8 @@ func (m *LoginManager) Accept(ctx echo.Context, ui *models.UserIdentity, provide
 		return "", errors.Wrap(err, "unable to accept login challenge")
 	}
 
+	publish(ctx.Request().Context(), m.r, app, ui.UserID, webhooks.UserLoginEvent{Provider: provider})
+
 	return reqACL.Payload.RedirectTo, nil
 }
 
@@ -261,7 +352,56 @@ func (m *LoginManager) ForwardUrl(challenge, provider, domain, launcher string)
 		return "", errors.New("identity provider not found")
 	}
 
-	return m.identityProviderService.GetAuthUrl(domain, ip, &State{Challenge: challenge, Launcher: launcher})
+	state := &State{Challenge: challenge, Launcher: launcher}
+	if ip.Type == models.AppIdentityProviderTypeSAML {
+		return m.samlForwardUrl(app, ip, domain, state)
+	}
+	if ip.Type == models.AppIdentityProviderTypeSteam {
+		return m.steamForwardUrl(app, ip, domain, state)
+	}
+	if ip.Type != models.AppIdentityProviderTypeOIDC {
+		return m.identityProviderService.GetAuthUrl(domain, ip, state)
+	}
+
+	authReq, err := oidc.NewAuthRequest()
+	if err != nil {
+		return "", err
+	}
+	ott, err := m.r.OneTimeTokenService().Create(authReq, app.OneTimeTokenSettings)
+	if err != nil {
+		return "", errors.Wrap(err, "unable to create openid connect request token")
+	}
+	state.OIDC = ott.Token
+
+	return m.identityProviderService.GetOIDCAuthUrl(context.TODO(), domain, ip, authReq, state)
+}
+
+// samlForwardUrl keeps the state in the one-time token as the relay state of the SAML request is limited
+// to 80 bytes.
+func (m *LoginManager) samlForwardUrl(app *models.Application, ip *models.AppIdentityProvider, domain string, state *State) (string, error) {
+	id, err := saml.NewRequestID()
+	if err != nil {
+		return "", err
+	}
+	ott, err := m.r.OneTimeTokenService().Create(&samlRequest{State: state, RequestID: id}, app.OneTimeTokenSettings)
+	if err != nil {
+		return "", errors.Wrap(err, "unable to create saml request token")
+	}
+
+	sp := service.SAMLServiceProvider(domain, entity.SpaceID(app.SpaceId.Hex()), m.r.SAMLKey())
+	return m.identityProviderService.GetSAMLAuthUrl(domain, sp, ip, id, ott.Token)
+}
+
+// steamForwardUrl keeps the login challenge in the one-time token, OpenID 2.0 has no single-use code,
+// so the token makes the response of Steam be accepted once.
+func (m *LoginManager) steamForwardUrl(app *models.Application, ip *models.AppIdentityProvider, domain string, state *State) (string, error) {
+	ott, err := m.r.OneTimeTokenService().Create(state.Challenge, app.OneTimeTokenSettings)
+	if err != nil {
+		return "", errors.Wrap(err, "unable to create steam request token")
+	}
+	state.Steam = ott.Token
+
+	return m.identityProviderService.GetSteamAuthUrl(domain, ip, state)
 }
 
 func (m *LoginManager) Check(token string) bool {
@@ -298,10 +438,17 @@ func (m *LoginManager) Link(token string, userID bson.ObjectId, app *models.Appl
 		Email:              t.Profile.Email,
 		ExternalID:         t.Profile.ID,
 		Name:               t.Profile.Name,
+		Username:           t.Profile.Username,
 		CreatedAt:          time.Now(),
 		UpdatedAt:          time.Now(),
 		Credential:         t.Profile.Token,
 	}
 
-	return m.userIdentityService.Create(userIdentity)
+	if err := m.userIdentityService.Create(userIdentity); err != nil {
+		return err
+	}
+
+	publish(context.TODO(), m.r, app, userID, webhooks.IdentityLinkedEvent{Provider: t.Provider, ExternalID: t.Profile.ID, Email: t.Profile.Email})
+
+	return nil
 }
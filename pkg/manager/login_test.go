@@ -6,9 +6,18 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/repository"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/api/apierror"
 	"github.com/ProtocolONE/auth1.protocol.one/pkg/mocks"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/models"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/oidc"
 	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
 	"github.com/labstack/echo/v4"
+	"github.com/ory/hydra-client-go/client/admin"
+	models2 "github.com/ory/hydra-client-go/models"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -24,6 +33,247 @@ func TestLoginManager(t *testing.T) {
 	assert.Implements(t, (*LoginManagerInterface)(nil), m)
 }
 
+func newProviderLoginManager(typ string, ott *mocks.OneTimeTokenServiceInterface, ips *mocks.AppIdentityProviderServiceInterface) *LoginManager {
+	h := &mocks.HydraAdminApi{}
+	h.On("GetLoginRequest", mock.Anything).Return(&admin.GetLoginRequestOK{Payload: &models2.LoginRequest{
+		Client: &models2.OAuth2Client{ClientID: bson.NewObjectId().Hex()},
+	}}, nil)
+	app := &mocks.ApplicationServiceInterface{}
+	app.On("Get", mock.Anything).Return(&models.Application{}, nil)
+	ips.On("FindByTypeAndName", mock.Anything, models.AppIdentityProviderTypeSocial, "corp").Return(&models.AppIdentityProvider{
+		Type: typ,
+		Name: "corp",
+	})
+
+	r := &mocks.InternalRegistry{}
+	r.On("HydraAdminApi").Return(h)
+	r.On("ApplicationService").Return(app)
+	r.On("OneTimeTokenService").Return(ott)
+	r.On("SAMLKey").Return(nil)
+
+	return &LoginManager{r: r, identityProviderService: ips}
+}
+
+func TestForwardUrlKeepsOIDCRequestInState(t *testing.T) {
+	ott := &mocks.OneTimeTokenServiceInterface{}
+	ott.On("Create", mock.AnythingOfType("*oidc.AuthRequest"), mock.Anything).Return(&models.OneTimeToken{Token: "token"}, nil)
+	ips := &mocks.AppIdentityProviderServiceInterface{}
+	ips.On("GetOIDCAuthUrl", mock.Anything, "domain", mock.Anything, mock.Anything, &State{Challenge: "challenge", OIDC: "token"}).Return("url", nil)
+	m := newProviderLoginManager(models.AppIdentityProviderTypeOIDC, ott, ips)
+
+	url, err := m.ForwardUrl("challenge", "corp", "domain", "")
+	assert.Nil(t, err)
+	assert.Equal(t, "url", url)
+
+	req := ips.Calls[1].Arguments.Get(3).(*oidc.AuthRequest)
+	assert.NotEmpty(t, req.Nonce)
+	assert.NotEmpty(t, req.CodeVerifier)
+}
+
+func TestGetUserIdentitiesUsesOIDCRequest(t *testing.T) {
+	ott := &mocks.OneTimeTokenServiceInterface{}
+	ott.On("Use", "token", mock.MatchedBy(func(req *oidc.AuthRequest) bool {
+		req.Nonce = "nonce"
+		return true
+	})).Return(nil)
+	ott.On("Use", mock.Anything, mock.Anything).Return(errors.New("token not found"))
+	ips := &mocks.AppIdentityProviderServiceInterface{}
+	ips.On("GetOIDCProfile", mock.Anything, "domain", "code", mock.Anything, &oidc.AuthRequest{Nonce: "nonce"}).Return(&models.UserIdentitySocial{ID: "1"}, nil)
+	uis := &mocks.UserIdentityServiceInterface{}
+	uis.On("Get", mock.Anything, "1").Return(nil, mgo.ErrNotFound)
+	m := newProviderLoginManager(models.AppIdentityProviderTypeOIDC, ott, ips)
+	m.userIdentityService = uis
+
+	_, profile, err := m.GetUserIdentities(&State{Challenge: "challenge", OIDC: "token"}, "corp", "domain", "code")
+	assert.Equal(t, mgo.ErrNotFound, err)
+	assert.Equal(t, "1", profile.ID)
+
+	_, _, err = m.GetUserIdentities(&State{Challenge: "challenge", OIDC: "other"}, "corp", "domain", "code")
+	assert.NotNil(t, err)
+}
+
+func TestForwardUrlKeepsSAMLRequestInRelayState(t *testing.T) {
+	ott := &mocks.OneTimeTokenServiceInterface{}
+	ott.On("Create", mock.AnythingOfType("*manager.samlRequest"), mock.Anything).Return(&models.OneTimeToken{Token: "token"}, nil)
+	ips := &mocks.AppIdentityProviderServiceInterface{}
+	ips.On("GetSAMLAuthUrl", "domain", mock.Anything, mock.Anything, mock.Anything, "token").Return("url", nil)
+	m := newProviderLoginManager(models.AppIdentityProviderTypeSAML, ott, ips)
+
+	url, err := m.ForwardUrl("challenge", "corp", "domain", "")
+	assert.Nil(t, err)
+	assert.Equal(t, "url", url)
+
+	req := ott.Calls[0].Arguments.Get(0).(*samlRequest)
+	assert.Equal(t, "challenge", req.State.Challenge)
+	assert.Equal(t, req.RequestID, ips.Calls[1].Arguments.Get(3))
+}
+
+func TestGetUserIdentitiesRequiresSAMLRequest(t *testing.T) {
+	ott := &mocks.OneTimeTokenServiceInterface{}
+	ott.On("Use", "token", mock.MatchedBy(func(req *samlRequest) bool {
+		req.State = &State{Challenge: "challenge"}
+		req.RequestID = "id-request"
+		return true
+	})).Return(nil)
+	ips := &mocks.AppIdentityProviderServiceInterface{}
+	ips.On("GetSAMLProfile", "domain", mock.Anything, "response", mock.Anything, "id-request").Return(&models.UserIdentitySocial{ID: "1"}, nil)
+	uis := &mocks.UserIdentityServiceInterface{}
+	uis.On("Get", mock.Anything, "1").Return(nil, mgo.ErrNotFound)
+	m := newProviderLoginManager(models.AppIdentityProviderTypeSAML, ott, ips)
+	m.userIdentityService = uis
+
+	state, err := m.SAMLState("token")
+	if !assert.Nil(t, err) {
+		return
+	}
+	_, profile, err := m.GetUserIdentities(state, "corp", "domain", "response")
+	assert.Equal(t, mgo.ErrNotFound, err)
+	assert.Equal(t, "1", profile.ID)
+
+	// INFO: The state decoded from the query of the callback doesn't carry the request
+	state, _ = DecodeState("eyJjaGFsbGVuZ2UiOiJjaGFsbGVuZ2UiLCJzYW1sIjoiaWQtcmVxdWVzdCJ9")
+	_, _, err = m.GetUserIdentities(state, "corp", "domain", "response")
+	assert.NotNil(t, err)
+}
+
+func TestForwardUrlKeepsSteamRequestInState(t *testing.T) {
+	ott := &mocks.OneTimeTokenServiceInterface{}
+	ott.On("Create", "challenge", mock.Anything).Return(&models.OneTimeToken{Token: "token"}, nil)
+	ips := &mocks.AppIdentityProviderServiceInterface{}
+	ips.On("GetSteamAuthUrl", "domain", mock.Anything, &State{Challenge: "challenge", Steam: "token"}).Return("url", nil)
+	m := newProviderLoginManager(models.AppIdentityProviderTypeSteam, ott, ips)
+
+	url, err := m.ForwardUrl("challenge", "corp", "domain", "")
+	assert.Nil(t, err)
+	assert.Equal(t, "url", url)
+}
+
+func TestGetUserIdentitiesRequiresSteamRequestOfChallenge(t *testing.T) {
+	ott := &mocks.OneTimeTokenServiceInterface{}
+	ott.On("Use", "token", mock.MatchedBy(func(challenge *string) bool {
+		*challenge = "challenge"
+		return true
+	})).Return(nil)
+	ott.On("Use", "other", mock.MatchedBy(func(challenge *string) bool {
+		*challenge = "other"
+		return true
+	})).Return(nil)
+	ips := &mocks.AppIdentityProviderServiceInterface{}
+	ips.On("GetSteamProfile", mock.Anything, "domain", "openid.mode=id_res", mock.Anything).Return(&models.UserIdentitySocial{ID: "1"}, nil)
+	uis := &mocks.UserIdentityServiceInterface{}
+	uis.On("Get", mock.Anything, "1").Return(nil, mgo.ErrNotFound)
+	m := newProviderLoginManager(models.AppIdentityProviderTypeSteam, ott, ips)
+	m.userIdentityService = uis
+
+	_, profile, err := m.GetUserIdentities(&State{Challenge: "challenge", Steam: "token"}, "corp", "domain", "openid.mode=id_res")
+	assert.Equal(t, mgo.ErrNotFound, err)
+	assert.Equal(t, "1", profile.ID)
+
+	_, _, err = m.GetUserIdentities(&State{Challenge: "challenge", Steam: "other"}, "corp", "domain", "openid.mode=id_res")
+	assert.NotNil(t, err)
+}
+
+type acceptTest struct {
+	h     *mocks.HydraAdminApi
+	ott   *mocks.OneTimeTokenServiceInterface
+	mfa   *mocks.MfaServiceInterface
+	wa    *mocks.WebAuthnServiceInterface
+	m     *LoginManager
+	space *entity.Space
+}
+
+func newAcceptTest() *acceptTest {
+	return &acceptTest{
+		h:   &mocks.HydraAdminApi{},
+		ott: &mocks.OneTimeTokenServiceInterface{},
+		mfa: &mocks.MfaServiceInterface{},
+		wa:  &mocks.WebAuthnServiceInterface{},
+		space: &entity.Space{
+			PasswordSettings: entity.PasswordSettings{TokenLength: 16, TokenTTL: 60},
+			IdentityProviders: entity.IdentityProviders{{
+				ID:   entity.IdentityProviderID(bson.NewObjectId().Hex()),
+				Type: entity.IDProviderTypeSocial,
+				Name: "corp",
+			}},
+		},
+	}
+}
+
+func (test *acceptTest) init() {
+	app := &mocks.ApplicationServiceInterface{}
+	app.On("Get", mock.Anything).Return(&models.Application{ID: bson.NewObjectId()}, nil)
+	us := &mocks.UserServiceInterface{}
+	us.On("Get", mock.Anything).Return(&models.User{ID: bson.NewObjectId()}, nil)
+	al := &mocks.AuthLogServiceInterface{}
+	al.On("Add", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	test.h.On("AcceptLoginRequest", mock.Anything).Return(&admin.AcceptLoginRequestOK{Payload: &models2.CompletedRequest{RedirectTo: "url"}}, nil)
+	test.ott.On("Create", mock.Anything, mock.Anything).Return(&models.OneTimeToken{Token: "mfa_token"}, nil)
+	test.mfa.On("GetUserProviders", mock.Anything).Return(nil, nil)
+	test.wa.On("GetUserCredentials", mock.Anything).Return(nil, nil)
+
+	r := mockIntRegistry()
+	r.On("ApplicationService").Return(app)
+	r.On("HydraAdminApi").Return(test.h)
+	r.On("OneTimeTokenService").Return(test.ott)
+	r.On("Spaces").Return(repository.OneSpaceRepo(test.space))
+
+	test.m = &LoginManager{
+		r:               r,
+		userService:     us,
+		mfaService:      test.mfa,
+		webAuthnService: test.wa,
+		authLogService:  al,
+	}
+}
+
+func TestAcceptReturnUrlToConsentRequest(t *testing.T) {
+	test := newAcceptTest()
+	test.init()
+
+	url, err := test.m.Accept(getContext(), &models.UserIdentity{UserID: bson.NewObjectId()}, "corp", "login_challenge")
+	assert.Nil(t, err)
+	assert.Equal(t, "url", url)
+}
+
+func TestAcceptReturnMfaRequiredIfUserHasProviders(t *testing.T) {
+	test := newAcceptTest()
+	test.mfa.On("GetUserProviders", mock.Anything).Return([]*models.MfaProvider{{ID: bson.NewObjectId()}}, nil)
+	test.init()
+
+	_, err := test.m.Accept(getContext(), &models.UserIdentity{UserID: bson.NewObjectId()}, "corp", "login_challenge")
+	if assert.IsType(t, &apierror.APIError{}, err) {
+		assert.Equal(t, apierror.MfaRequired.Code, err.(*apierror.APIError).Code)
+		assert.Equal(t, "mfa_token", err.(*apierror.APIError).Data.(*models.MfaRequiredResponse).Token)
+	}
+	test.ott.AssertCalled(t, "Create", mock.MatchedBy(func(t *models.UserMfaToken) bool {
+		return t.Challenge == "login_challenge" && t.Provider == "corp"
+	}), mock.Anything)
+	test.h.AssertNotCalled(t, "AcceptLoginRequest", mock.Anything)
+}
+
+func TestAcceptReturnMfaRequiredIfSpaceRequiresMfa(t *testing.T) {
+	test := newAcceptTest()
+	test.space.RequiresMfa = true
+	test.init()
+
+	_, err := test.m.Accept(getContext(), &models.UserIdentity{UserID: bson.NewObjectId()}, "corp", "login_challenge")
+	if assert.IsType(t, &apierror.APIError{}, err) {
+		assert.Equal(t, apierror.MfaRequired.Code, err.(*apierror.APIError).Code)
+	}
+	test.h.AssertNotCalled(t, "AcceptLoginRequest", mock.Anything)
+}
+
+func TestAcceptReturnErrorIfSpaceRequiresVerifiedEmail(t *testing.T) {
+	test := newAcceptTest()
+	test.space.RequiresVerifiedEmail = true
+	test.init()
+
+	_, err := test.m.Accept(getContext(), &models.UserIdentity{UserID: bson.NewObjectId()}, "corp", "login_challenge")
+	assert.Equal(t, apierror.EmailNotVerified, err)
+	test.h.AssertNotCalled(t, "AcceptLoginRequest", mock.Anything)
+}
+
 // func TestAuthorizeReturnErrorWithIncorrectClient(t *testing.T) {
 // 	app := &mocks.ApplicationServiceInterface{}
 // 	r := &mocks.InternalRegistry{}
@@ -39,6 +39,15 @@ func (m *ManageManager) CreateApplication(ctx echo.Context, form *models.Applica
 		return nil, &models.GeneralError{Message: "Unable to get space", Err: errors.Wrap(err, "Unable to get space")}
 	}
 
+	authSecret, err := helper.GetSecureRandString(64)
+	if err != nil {
+		return nil, &models.GeneralError{Message: "Unable to generate auth secret", Err: errors.Wrap(err, "Unable to generate auth secret")}
+	}
+	webHookSecret, err := helper.GetSecureRandString(64)
+	if err != nil {
+		return nil, &models.GeneralError{Message: "Unable to generate webhook secret", Err: errors.Wrap(err, "Unable to generate webhook secret")}
+	}
+
 	defaultRedirectUri := fmt.Sprintf("%s://%s/oauth2/callback", ctx.Scheme(), ctx.Request().Host)
 	form.Application.AuthRedirectUrls = append(form.Application.AuthRedirectUrls, defaultRedirectUri)
 
@@ -51,14 +60,15 @@ func (m *ManageManager) CreateApplication(ctx echo.Context, form *models.Applica
 		IsActive:               form.Application.IsActive,
 		CreatedAt:              time.Now(),
 		UpdatedAt:              time.Now(),
-		AuthSecret:             helper.GetRandString(64),
+		AuthSecret:             authSecret,
 		AuthRedirectUrls:       form.Application.AuthRedirectUrls,
 		PostLogoutRedirectUrls: form.Application.PostLogoutRedirectUrls,
 		OneTimeTokenSettings: &models.OneTimeTokenSettings{
 			Length: 64,
 			TTL:    3600,
 		},
-		WebHooks: form.Application.Webhooks,
+		WebHooks:      form.Application.Webhooks,
+		WebHookSecret: webHookSecret,
 	}
 
 	if err := m.r.ApplicationService().Create(app); err != nil {
@@ -111,6 +121,11 @@ func (m *ManageManager) UpdateApplication(ctx echo.Context, id string, form *mod
 	a.AuthRedirectUrls = form.Application.AuthRedirectUrls
 	a.PostLogoutRedirectUrls = form.Application.PostLogoutRedirectUrls
 	a.WebHooks = form.Application.Webhooks
+	if a.WebHookSecret == "" {
+		if a.WebHookSecret, err = helper.GetSecureRandString(64); err != nil {
+			return nil, &models.GeneralError{Message: "Unable to generate webhook secret", Err: errors.Wrap(err, "Unable to generate webhook secret")}
+		}
+	}
 
 	if err := m.r.ApplicationService().Update(a); err != nil {
 		return nil, &models.GeneralError{Message: "Unable to update application", Err: errors.Wrap(err, "Unable to update application")}
@@ -174,3 +189,27 @@ func (m *ManageManager) SetOneTimeTokenSettings(ctx echo.Context, appID string,
 
 	return nil
 }
+
+func (m *ManageManager) RotateWebHookSecret(ctx echo.Context, appID string, form *models.WebHookSecretForm) (*models.Application, *models.GeneralError) {
+	app, err := m.r.ApplicationService().Get(bson.ObjectIdHex(appID))
+	if err != nil {
+		return nil, &models.GeneralError{Message: "Unable to get application", Err: errors.Wrap(err, "Unable to get application")}
+	}
+
+	secret, err := helper.GetSecureRandString(64)
+	if err != nil {
+		return nil, &models.GeneralError{Message: "Unable to generate webhook secret", Err: errors.Wrap(err, "Unable to generate webhook secret")}
+	}
+
+	// INFO: The secret rotated twice within the window isn't kept, the receivers got only the last one anyway
+	app.WebHookPreviousSecret = app.WebHookSecret
+	app.WebHookPreviousSecretExpiresAt = time.Now().Add(time.Duration(form.Overlap) * time.Second)
+	app.WebHookSecret = secret
+	app.UpdatedAt = time.Now()
+
+	if err := m.r.ApplicationService().Update(app); err != nil {
+		return nil, &models.GeneralError{Message: "Unable to rotate application webhook secret", Err: errors.Wrap(err, "Unable to rotate application webhook secret")}
+	}
+
+	return app, nil
+}
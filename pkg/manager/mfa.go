@@ -1,30 +1,63 @@
 package manager
 
 import (
+	"bytes"
 	"context"
+	"crypto/subtle"
+	"fmt"
+	"io/ioutil"
+	"text/template"
+	"time"
 
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/api/apierror"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/config"
 	"github.com/ProtocolONE/auth1.protocol.one/pkg/database"
 	"github.com/ProtocolONE/auth1.protocol.one/pkg/helper"
 	"github.com/ProtocolONE/auth1.protocol.one/pkg/models"
 	"github.com/ProtocolONE/auth1.protocol.one/pkg/service"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/webhooks"
 	"github.com/ProtocolONE/mfa-service/pkg/proto"
 	"github.com/globalsign/mgo/bson"
 	"github.com/labstack/echo/v4"
+	"github.com/ory/hydra-client-go/client/admin"
+	models2 "github.com/ory/hydra-client-go/models"
 	"github.com/pkg/errors"
 )
 
+const (
+	mfaChallengeCodeLength  = 6
+	mfaChallengeMaxAttempts = 5
+
+	// mfaChallengeTokenInterval is the interval of the codes sent for the mfa token, mfaChallengeUserInterval
+	// is the one of the codes sent to the user for any token.
+	mfaChallengeTokenInterval = time.Minute
+	mfaChallengeUserInterval  = 20 * time.Second
+
+	mfaChallengeTypeOob    = "oob"
+	mfaBindingMethodPrompt = "prompt"
+)
+
 // MFAManagerInterface describes of methods for the manager.
 type MFAManagerInterface interface {
-	// MFAChallenge is temporary unused.
-	MFAChallenge(*models.MfaChallengeForm) *models.GeneralError
+	// MFAChallenge delivers a one-time code by the email or sms channel of the mfa provider.
+	//
+	// The returned oob code must be passed to MFAVerify together with the delivered code.
+	MFAChallenge(echo.Context, *models.MfaChallengeForm) (*models.MfaChallengeResponse, *models.GeneralError)
 
 	// MFAVerify verifies the one-time MFA token.
-	MFAVerify(echo.Context, *models.MfaVerifyForm) *models.GeneralError
+	//
+	// If the token was issued by the login process, the login challenge is accepted and the URL for
+	// the redirect will be returned to pass the agreement consent process.
+	MFAVerify(echo.Context, *models.MfaVerifyForm) (string, *models.GeneralError)
 
 	// MFAAdd adds mfa provider for the user.
 	//
 	// If successful, a secret key will be generated, a list of backup codes and a
 	// qr-code to add an authenticator to the program.
+	//
+	// The user is identified by the bearer token or, if the space requires MFA, by the mfa token
+	// issued by the login process for the user without providers.
 	MFAAdd(echo.Context, *models.MfaAddForm) (*models.MfaAuthenticator, *models.GeneralError)
 
 	// MFARemove removes mfa provider for user
@@ -36,30 +69,228 @@ type MFAManagerInterface interface {
 
 // MFAManager is the mfa manager.
 type MFAManager struct {
-	r              service.InternalRegistry
-	authLogService service.AuthLogServiceInterface
-	userService    service.UserServiceInterface
-	mfaService     service.MfaServiceInterface
+	r               service.InternalRegistry
+	authLogService  service.AuthLogServiceInterface
+	userService     service.UserServiceInterface
+	mfaService      service.MfaServiceInterface
+	webAuthnService service.WebAuthnServiceInterface
+	TplCfg          *config.MailTemplates
 }
 
 // NewMFAManager return new mfa manager.
-func NewMFAManager(h database.MgoSession, r service.InternalRegistry) MFAManagerInterface {
+func NewMFAManager(h database.MgoSession, r service.InternalRegistry, tplCfg *config.MailTemplates) MFAManagerInterface {
 	m := &MFAManager{
-		r:              r,
-		authLogService: service.NewAuthLogService(h, r.GeoIpService()),
-		mfaService:     service.NewMfaService(h),
-		userService:    service.NewUserService(h),
+		r:               r,
+		authLogService:  service.NewAuthLogService(h, r.GeoIpService()),
+		mfaService:      service.NewMfaService(h),
+		webAuthnService: service.NewWebAuthnService(h),
+		userService:     service.NewUserService(h),
+		TplCfg:          tplCfg,
 	}
 
 	return m
 }
 
-func (m *MFAManager) MFAChallenge(form *models.MfaChallengeForm) *models.GeneralError {
-	//TODO: For OTP over SMS/Email. Undone
+func (m *MFAManager) MFAChallenge(ctx echo.Context, form *models.MfaChallengeForm) (*models.MfaChallengeResponse, *models.GeneralError) {
+	if !bson.IsObjectIdHex(form.ClientId) {
+		return nil, &models.GeneralError{Code: "client_id", Message: models.ErrorClientIdIncorrect, Err: errors.New(models.ErrorClientIdIncorrect)}
+	}
+
+	app, err := m.r.ApplicationService().Get(bson.ObjectIdHex(form.ClientId))
+	if err != nil {
+		return nil, &models.GeneralError{Code: "client_id", Message: models.ErrorClientIdIncorrect, Err: errors.Wrap(err, "Unable to load application")}
+	}
+
+	space, err := m.r.Spaces().FindByID(context.TODO(), entity.SpaceID(app.SpaceId.Hex()))
+	if err != nil {
+		return nil, &models.GeneralError{Code: "common", Message: models.ErrorUnknownError, Err: errors.Wrap(err, "Unable to load space")}
+	}
+
+	mp := &models.UserMfaToken{}
+	if err := m.r.OneTimeTokenService().Get(form.Token, mp); err != nil {
+		return nil, &models.GeneralError{Code: "mfa_token", Message: models.ErrorCannotUseToken, Err: errors.Wrap(err, "Unable to use OneTimeToken")}
+	}
+	if mp.ClientID != form.ClientId {
+		return nil, &models.GeneralError{Code: "client_id", Message: models.ErrorClientIdIncorrect, Err: errors.New("Token is not issued for the application")}
+	}
+
+	provider, e := m.tokenProvider(mp, form.ProviderId)
+	if e != nil {
+		return nil, e
+	}
+	if provider.Channel != models.MfaChannelEmail && provider.Channel != models.MfaChannelSms {
+		return nil, &models.GeneralError{Code: "provider_id", Message: models.ErrorMfaChannelUnsupported, Err: errors.New(models.ErrorMfaChannelUnsupported)}
+	}
+
+	user, err := m.userService.Get(mp.UserIdentity.UserID)
+	if err != nil {
+		return nil, &models.GeneralError{Code: "common", Message: models.ErrorLoginIncorrect, Err: errors.Wrap(err, "Unable to get user")}
+	}
+	if provider.Channel == models.MfaChannelSms && user.PhoneNumber == "" {
+		return nil, &models.GeneralError{Code: "phone_number", Message: models.ErrorPhoneNumberRequired, Err: errors.New(models.ErrorPhoneNumberRequired)}
+	}
+
+	if e := m.allowChallenge(form.Token, user.ID); e != nil {
+		return nil, e
+	}
+
+	code, err := helper.GetRandDigits(mfaChallengeCodeLength)
+	if err != nil {
+		return nil, &models.GeneralError{Code: "common", Message: models.ErrorUnknownError, Err: errors.Wrap(err, "Unable to generate code")}
+	}
+
+	ott, err := m.r.OneTimeTokenService().Create(&models.MfaChallengeToken{
+		MfaToken:   form.Token,
+		ProviderID: provider.ID,
+		Code:       code,
+	}, &models.OneTimeTokenSettings{
+		Length: space.PasswordSettings.TokenLength,
+		TTL:    space.PasswordSettings.TokenTTL,
+	})
+	if err != nil {
+		return nil, &models.GeneralError{Code: "common", Message: models.ErrorCannotCreateToken, Err: errors.Wrap(err, "Unable to create OneTimeToken")}
+	}
+
+	if provider.Channel == models.MfaChannelSms {
+		err = m.r.SmsSender().Send(user.PhoneNumber, fmt.Sprintf("%s code: %s", m.TplCfg.PlatformName, code))
+	} else {
+		err = m.sendCodeMail(user, code)
+	}
+	if err != nil {
+		return nil, &models.GeneralError{Code: "common", Message: models.ErrorMfaCodeDelivery, Err: errors.Wrap(err, "Unable to deliver MFA code")}
+	}
+
+	return &models.MfaChallengeResponse{
+		ChallengeType: mfaChallengeTypeOob,
+		OobCode:       ott.Token,
+		BindingMethod: mfaBindingMethodPrompt,
+	}, nil
+}
+
+// allowChallenge limits the codes sent for the mfa token and to the user, so the user can't be flooded with messages.
+func (m *MFAManager) allowChallenge(token string, userID bson.ObjectId) *models.GeneralError {
+	limits := []struct {
+		key      string
+		interval time.Duration
+	}{
+		{"mfa_challenge_user_" + userID.Hex(), mfaChallengeUserInterval},
+		{"mfa_challenge_token_" + token, mfaChallengeTokenInterval},
+	}
+	for _, l := range limits {
+		ok, err := m.r.RateLimiter().Allow(l.key, l.interval)
+		if err != nil {
+			return &models.GeneralError{Code: "common", Message: models.ErrorUnknownError, Err: errors.Wrap(err, "Unable to check the resend interval")}
+		}
+		if !ok {
+			return &models.GeneralError{Code: "common", Message: models.ErrorMfaChallengeThrottled, Err: errors.New(models.ErrorMfaChallengeThrottled)}
+		}
+	}
+	return nil
+}
+
+func (m *MFAManager) sendCodeMail(user *models.User, code string) error {
+	b, err := ioutil.ReadFile(m.TplCfg.MfaCodeTpl)
+	if err != nil {
+		return errors.Wrap(err, "unable to read mfa code mail template")
+	}
+	tmpl, err := template.New("mail").Parse(string(b))
+	if err != nil {
+		return errors.Wrap(err, "unable to parse mfa code mail template")
+	}
+
+	w := bytes.Buffer{}
+	err = tmpl.Execute(&w, struct {
+		UserName         string
+		PlatformName     string
+		Code             string
+		SupportPortalUrl string
+	}{
+		UserName:         user.Username,
+		PlatformName:     m.TplCfg.PlatformName,
+		Code:             code,
+		SupportPortalUrl: m.TplCfg.SupportPortalUrl,
+	})
+	if err != nil {
+		return errors.Wrap(err, "unable to build mfa code mail")
+	}
+
+	return m.r.Mailer().Send(user.Email, "Verification code", w.String())
+}
+
+// tokenProvider returns the provider bound to the mfa token or, if the token is issued for any
+// of the user providers, the user provider with the specified id.
+func (m *MFAManager) tokenProvider(mp *models.UserMfaToken, providerID string) (*models.MfaProvider, *models.GeneralError) {
+	if mp.MfaProvider != nil {
+		return mp.MfaProvider, nil
+	}
+
+	providers, err := m.mfaService.GetUserProviders(&models.User{ID: mp.UserIdentity.UserID})
+	if err != nil {
+		return nil, &models.GeneralError{Code: "common", Message: models.ErrorUnknownError, Err: errors.Wrap(err, "Unable to get user providers")}
+	}
+	for _, p := range providers {
+		if p.ID.Hex() == providerID {
+			return p, nil
+		}
+	}
+
+	return nil, &models.GeneralError{Code: "provider_id", Message: models.ErrorProviderIdIncorrect, Err: errors.New(models.ErrorProviderIdIncorrect)}
+}
+
+// checkChallengeCode verifies the code delivered by MFAChallenge. The attempts are counted by the mfa token,
+// so the resent codes share them, and both tokens are burned after the failed last attempt.
+func (m *MFAManager) checkChallengeCode(ctx context.Context, form *models.MfaVerifyForm, mp *models.UserMfaToken, provider *models.MfaProvider, attempt int) *models.GeneralError {
+	ott := m.r.OneTimeTokenService()
+
+	ch := &models.MfaChallengeToken{}
+	if err := ott.Get(form.OobCode, ch); err != nil || ch.MfaToken != form.Token || ch.ProviderID != provider.ID {
+		if err == nil {
+			err = errors.New("Code is not issued for the token")
+		}
+		return &models.GeneralError{Code: "oob_code", Message: models.ErrorCannotUseToken, Err: errors.Wrap(err, "Unable to use OneTimeToken")}
+	}
+
+	if subtle.ConstantTimeCompare([]byte(ch.Code), []byte(form.Code)) != 1 {
+		if m.failMfaCode(ctx, mp, form.Token, attempt) {
+			_ = ott.Use(form.OobCode, &models.MfaChallengeToken{})
+		}
+		return &models.GeneralError{Code: "common", Message: models.ErrorMfaCodeInvalid, Err: errors.New(models.ErrorMfaCodeInvalid)}
+	}
+
+	if err := ott.Use(form.OobCode, &models.MfaChallengeToken{}); err != nil {
+		return &models.GeneralError{Code: "oob_code", Message: models.ErrorCannotUseToken, Err: errors.Wrap(err, "Unable to use OneTimeToken")}
+	}
 
 	return nil
 }
 
+// attemptMfaToken counts the verification of the mfa token before the code is compared, so the concurrent
+// requests can't compare more codes than allowed. The number of the attempt is returned, the token is burned
+// over the max attempts.
+func (m *MFAManager) attemptMfaToken(token string) (int, *models.GeneralError) {
+	ott := m.r.OneTimeTokenService()
+	n, err := ott.Attempt(token)
+	if err != nil {
+		return 0, &models.GeneralError{Code: "common", Message: models.ErrorUnknownError, Err: errors.Wrap(err, "Unable to count MFA attempt")}
+	}
+	if n > mfaChallengeMaxAttempts {
+		_ = ott.Use(token, &models.UserMfaToken{})
+		return 0, &models.GeneralError{Code: "mfa_token", Message: models.ErrorCannotUseToken, Err: errors.New("Too many MFA attempts")}
+	}
+	return n, nil
+}
+
+// failMfaCode counts the failed code of the user and burns the mfa token after the failed last attempt,
+// true is returned then.
+func (m *MFAManager) failMfaCode(ctx context.Context, mp *models.UserMfaToken, token string, attempt int) bool {
+	failMfa(ctx, m.r, mp.UserIdentity.UserID)
+	if attempt < mfaChallengeMaxAttempts {
+		return false
+	}
+	_ = m.r.OneTimeTokenService().Use(token, &models.UserMfaToken{})
+	return true
+}
+
 func (m *MFAManager) MFARemove(ctx echo.Context, form *models.MfaRemoveForm) *models.GeneralError {
 	app, err := m.r.ApplicationService().Get(bson.ObjectIdHex(form.ClientId))
 	if err != nil {
@@ -74,9 +305,9 @@ func (m *MFAManager) MFARemove(ctx echo.Context, form *models.MfaRemoveForm) *mo
 		return &models.GeneralError{Code: "provider_id", Message: models.ErrorProviderIdIncorrect, Err: errors.WithStack(err)}
 	}
 
-	c, err := helper.GetTokenFromAuthHeader(ctx.Request().Header)
-	if err != nil {
-		return &models.GeneralError{Code: "client_id", Message: models.ErrorClientIdIncorrect, Err: errors.Wrap(err, "Unable to validate bearer token")}
+	c, e := m.authenticateBearer(ctx, app)
+	if e != nil {
+		return e
 	}
 
 	err = m.mfaService.RemoveUserProvider(&models.MfaUserProvider{
@@ -88,6 +319,8 @@ func (m *MFAManager) MFARemove(ctx echo.Context, form *models.MfaRemoveForm) *mo
 		return &models.GeneralError{Code: "common", Message: models.ErrorMfaClientRemove, Err: errors.Wrap(err, "Unable to remove user provider")}
 	}
 
+	publish(ctx.Request().Context(), m.r, app, c.UserId, webhooks.MFARemovedEvent{ProviderID: p.ID.Hex()})
+
 	return nil
 }
 
@@ -100,31 +333,88 @@ func (m *MFAManager) MFAList(ctx echo.Context, form *models.MfaListForm) ([]*mod
 	return providers, nil
 }
 
-func (m *MFAManager) MFAVerify(ctx echo.Context, form *models.MfaVerifyForm) *models.GeneralError {
+func (m *MFAManager) MFAVerify(ctx echo.Context, form *models.MfaVerifyForm) (string, *models.GeneralError) {
 	mp := &models.UserMfaToken{}
 	if err := m.r.OneTimeTokenService().Get(form.Token, mp); err != nil {
-		return &models.GeneralError{Code: "mfa_token", Message: models.ErrorCannotUseToken, Err: errors.Wrap(err, "Unable to use OneTimeToken")}
+		return "", &models.GeneralError{Code: "mfa_token", Message: models.ErrorCannotUseToken, Err: errors.Wrap(err, "Unable to use OneTimeToken")}
+	}
+	if mp.ClientID != form.ClientId {
+		return "", &models.GeneralError{Code: "client_id", Message: models.ErrorClientIdIncorrect, Err: errors.New("Token is not issued for the application")}
 	}
 
-	rsp, err := m.r.MfaService().Check(context.TODO(), &proto.MfaCheckDataRequest{
-		ProviderID: mp.MfaProvider.ID.String(),
-		UserID:     mp.UserIdentity.UserID.String(),
-		Code:       form.Code,
-	})
+	provider, e := m.tokenProvider(mp, form.ProviderId)
+	if e != nil {
+		return "", e
+	}
+
+	if e := checkMfaLockout(m.r, mp.UserIdentity.UserID); e != nil {
+		return "", e
+	}
+
+	attempt, e := m.attemptMfaToken(form.Token)
+	if e != nil {
+		return "", e
+	}
+
+	if provider.Channel == models.MfaChannelEmail || provider.Channel == models.MfaChannelSms {
+		if e := m.checkChallengeCode(ctx.Request().Context(), form, mp, provider, attempt); e != nil {
+			return "", e
+		}
+	} else {
+		rsp, err := m.r.MfaService().Check(context.TODO(), &proto.MfaCheckDataRequest{
+			ProviderID: provider.ID.String(),
+			UserID:     mp.UserIdentity.UserID.String(),
+			Code:       form.Code,
+		})
+		if err != nil {
+			m.failMfaCode(ctx.Request().Context(), mp, form.Token, attempt)
+			return "", &models.GeneralError{Code: "common", Message: models.ErrorMfaCodeInvalid, Err: errors.Wrap(err, "Unable to verify MFA code")}
+		}
+
+		if rsp.Result != true {
+			m.failMfaCode(ctx.Request().Context(), mp, form.Token, attempt)
+			return "", &models.GeneralError{Code: "common", Message: models.ErrorMfaCodeInvalid, Err: errors.New(models.ErrorMfaCodeInvalid)}
+		}
+	}
+	resetMfaLockout(ctx.Request().Context(), m.r, mp.UserIdentity.UserID)
+
+	user, err := m.userService.Get(mp.UserIdentity.UserID)
 	if err != nil {
-		return &models.GeneralError{Code: "common", Message: models.ErrorMfaCodeInvalid, Err: errors.Wrap(err, "Unable to verify MFA code")}
+		return "", &models.GeneralError{Code: "email", Message: models.ErrorLoginIncorrect, Err: errors.Wrap(err, "Unable to get user")}
+	}
+	if user.IsBlocked(time.Now()) {
+		return "", &models.GeneralError{Code: "common", Message: models.ErrorUserBlocked, Err: apierror.UserBlocked}
 	}
 
-	if rsp.Result != true {
-		return &models.GeneralError{Code: "common", Message: models.ErrorMfaCodeInvalid, Err: errors.New(models.ErrorMfaCodeInvalid)}
+	if err := m.r.OneTimeTokenService().Use(form.Token, &models.UserMfaToken{}); err != nil {
+		return "", &models.GeneralError{Code: "mfa_token", Message: models.ErrorCannotUseToken, Err: errors.Wrap(err, "Unable to use OneTimeToken")}
 	}
 
-	_, err = m.userService.Get(mp.UserIdentity.UserID)
+	if mp.Challenge == "" {
+		return "", nil
+	}
+
+	amr := mp.Amr
+	if provider.Type != "" {
+		amr = append(amr, provider.Type)
+	}
+	amr = append(amr, amrMfa)
+
+	userId := user.ID.Hex()
+	reqACL, err := m.r.HydraAdminApi().AcceptLoginRequest(&admin.AcceptLoginRequestParams{
+		Context:        ctx.Request().Context(),
+		LoginChallenge: mp.Challenge,
+		Body:           &models2.AcceptLoginRequest{Subject: &userId, Remember: mp.Remember, RememberFor: RememberTime, Context: loginContext(amr)},
+	})
 	if err != nil {
-		return &models.GeneralError{Code: "email", Message: models.ErrorLoginIncorrect, Err: errors.Wrap(err, "Unable to get user")}
+		return "", &models.GeneralError{Code: "common", Message: models.ErrorLoginChallenge, Err: errors.Wrap(err, "Unable to accept login challenge")}
 	}
 
-	return nil
+	if mp.ClientID != "" {
+		publishClient(ctx.Request().Context(), m.r, mp.ClientID, user.ID, webhooks.UserLoginEvent{Provider: mp.Provider, Amr: amr})
+	}
+
+	return reqACL.Payload.RedirectTo, nil
 }
 
 func (m *MFAManager) MFAAdd(ctx echo.Context, form *models.MfaAddForm) (token *models.MfaAuthenticator, error *models.GeneralError) {
@@ -141,30 +431,81 @@ func (m *MFAManager) MFAAdd(ctx echo.Context, form *models.MfaAddForm) (token *m
 		return nil, &models.GeneralError{Code: "provider_id", Message: models.ErrorProviderIdIncorrect, Err: errors.WithStack(err)}
 	}
 
-	c, err := helper.GetTokenFromAuthHeader(ctx.Request().Header)
-	if err != nil {
-		return nil, &models.GeneralError{Code: "client_id", Message: models.ErrorClientIdIncorrect, Err: errors.Wrap(err, "Unable to validate bearer token")}
+	var userID bson.ObjectId
+	var email string
+	if form.Token != "" {
+		mp := &models.UserMfaToken{}
+		if err := m.r.OneTimeTokenService().Get(form.Token, mp); err != nil || mp.Challenge == "" {
+			if err == nil {
+				err = errors.New("Token is not issued by login")
+			}
+			return nil, &models.GeneralError{Code: "mfa_token", Message: models.ErrorCannotUseToken, Err: errors.Wrap(err, "Unable to use OneTimeToken")}
+		}
+		if mp.ClientID != form.ClientId {
+			return nil, &models.GeneralError{Code: "client_id", Message: models.ErrorClientIdIncorrect, Err: errors.New("Token is not issued for the application")}
+		}
+
+		// INFO: The login token can't be used to add a provider bypassing the existing ones
+		providers, err := m.mfaService.GetUserProviders(&models.User{ID: mp.UserIdentity.UserID})
+		if err != nil || len(providers) > 0 {
+			if err == nil {
+				err = errors.New("User already has mfa providers")
+			}
+			return nil, &models.GeneralError{Code: "mfa_token", Message: models.ErrorCannotUseToken, Err: errors.WithStack(err)}
+		}
+		credentials, err := m.webAuthnService.GetUserCredentials(mp.UserIdentity.UserID)
+		if err != nil || len(credentials) > 0 {
+			if err == nil {
+				err = errors.New("User already has passkeys")
+			}
+			return nil, &models.GeneralError{Code: "mfa_token", Message: models.ErrorCannotUseToken, Err: errors.WithStack(err)}
+		}
+
+		userID, email = mp.UserIdentity.UserID, mp.UserIdentity.Email
+	} else {
+		c, e := m.authenticateBearer(ctx, app)
+		if e != nil {
+			return nil, e
+		}
+		user, err := m.userService.Get(c.UserId)
+		if err != nil {
+			return nil, &models.GeneralError{Code: "common", Message: models.ErrorLoginIncorrect, Err: errors.Wrap(err, "Unable to get user")}
+		}
+
+		userID, email = user.ID, user.Email
 	}
 
-	rsp, err := m.r.MfaService().Create(context.TODO(), &proto.MfaCreateDataRequest{
-		ProviderID: p.ID.String(),
-		AppName:    app.Name,
-		UserID:     c.UserId.String(),
-		Email:      c.Email,
-		QrSize:     300,
-	})
-	if err != nil {
-		return nil, &models.GeneralError{Code: "common", Message: models.ErrorMfaClientAdd, Err: errors.Wrap(err, "Unable to add MFA")}
+	// INFO: The providers delivering codes by email or sms don't have a secret, the code is generated on challenge
+	rsp := &proto.MfaCreateDataResponse{}
+	switch p.Channel {
+	case models.MfaChannelEmail:
+	case models.MfaChannelSms:
+		if e := m.setPhoneNumber(userID, form.PhoneNumber); e != nil {
+			return nil, e
+		}
+	default:
+		rsp, err = m.r.MfaService().Create(context.TODO(), &proto.MfaCreateDataRequest{
+			ProviderID: p.ID.String(),
+			AppName:    app.Name,
+			UserID:     userID.String(),
+			Email:      email,
+			QrSize:     300,
+		})
+		if err != nil {
+			return nil, &models.GeneralError{Code: "common", Message: models.ErrorMfaClientAdd, Err: errors.Wrap(err, "Unable to add MFA")}
+		}
 	}
 
 	up := &models.MfaUserProvider{
-		UserID:     c.UserId,
+		UserID:     userID,
 		ProviderID: p.ID,
 	}
 	if err = m.mfaService.AddUserProvider(up); err != nil {
 		return nil, &models.GeneralError{Code: "common", Message: models.ErrorMfaClientAdd, Err: errors.Wrap(err, "Unable to add MFA to user")}
 	}
 
+	publish(ctx.Request().Context(), m.r, app, userID, webhooks.MFAAddedEvent{ProviderID: p.ID.Hex(), Type: p.Type, Channel: p.Channel})
+
 	return &models.MfaAuthenticator{
 		ID:            p.ID,
 		Type:          p.Type,
@@ -174,3 +515,71 @@ func (m *MFAManager) MFAAdd(ctx echo.Context, form *models.MfaAddForm) (token *m
 		RecoveryCodes: rsp.RecoveryCode,
 	}, nil
 }
+
+// authenticateBearer introspects the access token of the Authorization header, the token must be issued
+// to the application.
+func (m *MFAManager) authenticateBearer(ctx echo.Context, app *models.Application) (*models.JwtClaim, *models.GeneralError) {
+	c, err := service.AuthenticateBearer(ctx.Request().Context(), m.r.HydraAdminApi(), ctx.Request().Header)
+	if err == nil && c.AppId != app.ID {
+		err = errors.New("Token is issued to other application")
+	}
+	if err != nil {
+		return nil, &models.GeneralError{Code: "client_id", Message: models.ErrorClientIdIncorrect, Err: errors.Wrap(err, "Unable to validate bearer token")}
+	}
+
+	return c, nil
+}
+
+// setPhoneNumber updates the phone number of the user for the sms provider. If the number is not
+// specified, the user must already have one.
+func (m *MFAManager) setPhoneNumber(userID bson.ObjectId, phone string) *models.GeneralError {
+	user, err := m.userService.Get(userID)
+	if err != nil {
+		return &models.GeneralError{Code: "common", Message: models.ErrorLoginIncorrect, Err: errors.Wrap(err, "Unable to get user")}
+	}
+
+	if phone == "" || phone == user.PhoneNumber {
+		if user.PhoneNumber == "" {
+			return &models.GeneralError{Code: "phone_number", Message: models.ErrorPhoneNumberRequired, Err: errors.New(models.ErrorPhoneNumberRequired)}
+		}
+		return nil
+	}
+
+	user.PhoneNumber = phone
+	user.PhoneVerified = false
+	if err := m.userService.Update(user); err != nil {
+		return &models.GeneralError{Code: "common", Message: models.ErrorUpdateUser, Err: errors.Wrap(err, "Unable to update user")}
+	}
+
+	return nil
+}
+
+// requireMfa returns the mfa_required error with the mfa token of the login if the user has the second factor
+// or the space requires it, the login challenge of the token is accepted by MFAVerify then. Nil is returned
+// if the login challenge can be accepted right away.
+func requireMfa(r service.InternalRegistry, mfaService service.MfaServiceInterface, webAuthnService service.WebAuthnServiceInterface, space *entity.Space, user *models.User, token *models.UserMfaToken) error {
+	providers, err := mfaService.GetUserProviders(user)
+	if err != nil {
+		return errors.Wrap(err, "unable to get user mfa providers")
+	}
+	credentials, err := webAuthnService.GetUserCredentials(user.ID)
+	if err != nil {
+		return errors.Wrap(err, "unable to get user webauthn credentials")
+	}
+	if len(providers) == 0 && len(credentials) == 0 && !space.RequiresMfa {
+		return nil
+	}
+
+	ott, err := r.OneTimeTokenService().Create(token, &models.OneTimeTokenSettings{
+		Length: space.PasswordSettings.TokenLength,
+		TTL:    space.PasswordSettings.TokenTTL,
+	})
+	if err != nil {
+		return errors.Wrap(err, "unable to create mfa token")
+	}
+
+	if providers == nil {
+		providers = []*models.MfaProvider{}
+	}
+	return apierror.MfaRequired.WithData(&models.MfaRequiredResponse{Token: ott.Token, Providers: providers, WebAuthn: len(credentials) > 0})
+}
@@ -1,13 +1,24 @@
 package manager
 
 import (
+	"context"
+	"encoding/json"
+	"reflect"
 	"testing"
+	"time"
 
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/repository"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/repository/webhook_delivery/memory"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/config"
 	"github.com/ProtocolONE/auth1.protocol.one/pkg/mocks"
 	"github.com/ProtocolONE/auth1.protocol.one/pkg/models"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/webhooks"
 	"github.com/ProtocolONE/mfa-service/pkg/proto"
 	"github.com/globalsign/mgo"
 	"github.com/globalsign/mgo/bson"
+	"github.com/ory/hydra-client-go/client/admin"
+	models2 "github.com/ory/hydra-client-go/models"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -17,14 +28,56 @@ func TestMFAManager(t *testing.T) {
 	s := &mocks.MgoSession{}
 	s.On("DB", mock.Anything).Return(&mgo.Database{})
 	r := mockIntRegistry()
-	m := NewMFAManager(s, r)
+	m := NewMFAManager(s, r, &config.MailTemplates{})
 	assert.Implements(t, (*MFAManagerInterface)(nil), m)
 }
 
 func mockIntRegistry() *mocks.InternalRegistry {
+	r, _ := mockIntRegistryWithWebHooks()
+	return r
+}
+
+// mockIntRegistryWithWebHooks returns the registry which enqueues the webhooks to the returned queue.
+func mockIntRegistryWithWebHooks() (*mocks.InternalRegistry, *memory.WebhookDeliveryRepository) {
+	deliveries := memory.New()
 	r := &mocks.InternalRegistry{}
 	r.On("GeoIpService").Return(nil)
-	return r
+	r.On("WebHooks").Return(webhooks.NewWebhooks(deliveries))
+	return r, deliveries
+}
+
+// mockBearer makes the hydra of the registry introspect any token as the access token of the user issued to the application.
+func mockBearer(r *mocks.InternalRegistry, appID, userID bson.ObjectId) {
+	active := true
+	h := &mocks.HydraAdminApi{}
+	h.On("IntrospectOAuth2Token", mock.Anything, mock.Anything).Return(&admin.IntrospectOAuth2TokenOK{Payload: &models2.OAuth2TokenIntrospection{
+		Active:    &active,
+		Sub:       userID.Hex(),
+		ClientID:  appID.Hex(),
+		TokenType: "access_token",
+	}}, nil)
+	r.On("HydraAdminApi").Return(h)
+}
+
+// mockMfaLockout makes the registry count the failed mfa codes by the returned counters, the user isn't locked.
+func mockMfaLockout(r *mocks.InternalRegistry) *mocks.LoginAttemptsInterface {
+	la := &mocks.LoginAttemptsInterface{}
+	la.On("Blocked", mock.Anything).Return(time.Duration(0), time.Duration(0), nil)
+	la.On("Fail", mock.Anything, mfaWindow).Return(1, nil)
+	la.On("Reset", mock.Anything).Return(nil)
+	r.On("LoginAttempts").Return(la)
+	return la
+}
+
+func publishedActions(t *testing.T, deliveries *memory.WebhookDeliveryRepository) []string {
+	list, _, err := deliveries.Find(context.Background(), repository.WebhookDeliveryQuery{})
+	assert.Nil(t, err)
+
+	var actions []string
+	for _, d := range list {
+		actions = append(actions, d.Event)
+	}
+	return actions
 }
 
 func TestMFAVerifyReturnErrorWithUnableToGetToken(t *testing.T) {
@@ -35,7 +88,7 @@ func TestMFAVerifyReturnErrorWithUnableToGetToken(t *testing.T) {
 	r.On("OneTimeTokenService").Return(ott)
 
 	m := &MFAManager{r: r}
-	err := m.MFAVerify(getContext(), &models.MfaVerifyForm{})
+	_, err := m.MFAVerify(getContext(), &models.MfaVerifyForm{})
 	assert.NotNil(t, err)
 	assert.Equal(t, "mfa_token", err.Code)
 	assert.Equal(t, models.ErrorCannotUseToken, err.Message)
@@ -52,11 +105,13 @@ func TestMFAVerifyReturnErrorWithCheckCode(t *testing.T) {
 		arg.MfaProvider = &models.MfaProvider{ID: bson.NewObjectId()}
 	})
 	mfa.On("Check", mock.Anything, mock.Anything).Return(nil, errors.New(""))
+	ott.On("Attempt", "token").Return(1, nil)
+	mockMfaLockout(r)
 	r.On("OneTimeTokenService").Return(ott)
 	r.On("MfaService").Return(mfa)
 
 	m := &MFAManager{r: r}
-	err := m.MFAVerify(getContext(), &models.MfaVerifyForm{Token: "token"})
+	_, err := m.MFAVerify(getContext(), &models.MfaVerifyForm{Token: "token"})
 	assert.NotNil(t, err)
 	assert.Equal(t, "common", err.Code)
 	assert.Equal(t, models.ErrorMfaCodeInvalid, err.Message)
@@ -73,14 +128,118 @@ func TestMFAVerifyReturnErrorWithResultIsFalse(t *testing.T) {
 		arg.MfaProvider = &models.MfaProvider{ID: bson.NewObjectId()}
 	})
 	mfa.On("Check", mock.Anything, mock.Anything).Return(&proto.MfaCheckDataResponse{Result: false}, nil)
+	ott.On("Attempt", "token").Return(1, nil)
+	mockMfaLockout(r)
 	r.On("OneTimeTokenService").Return(ott)
 	r.On("MfaService").Return(mfa)
 
 	m := &MFAManager{r: r}
-	err := m.MFAVerify(getContext(), &models.MfaVerifyForm{Token: "token"})
+	_, err := m.MFAVerify(getContext(), &models.MfaVerifyForm{Token: "token"})
 	assert.NotNil(t, err)
 	assert.Equal(t, "common", err.Code)
 	assert.Equal(t, models.ErrorMfaCodeInvalid, err.Message)
+	ott.AssertNotCalled(t, "Use", mock.Anything, mock.Anything)
+}
+
+func TestMFAVerifyBurnsTokenAfterMaxAttempts(t *testing.T) {
+	ott := &mocks.OneTimeTokenServiceInterface{}
+	mfa := &mocks.MfaApiInterface{}
+	r := mockIntRegistry()
+
+	ott.On("Get", "token", &models.UserMfaToken{}).Return(nil).Run(func(args mock.Arguments) {
+		arg := args.Get(1).(*models.UserMfaToken)
+		arg.UserIdentity = &models.UserIdentity{UserID: bson.NewObjectId()}
+		arg.MfaProvider = &models.MfaProvider{ID: bson.NewObjectId()}
+	})
+	ott.On("Attempt", "token").Return(mfaChallengeMaxAttempts, nil)
+	ott.On("Use", "token", mock.Anything).Return(nil)
+	mfa.On("Check", mock.Anything, mock.Anything).Return(&proto.MfaCheckDataResponse{Result: false}, nil)
+	mockMfaLockout(r)
+	r.On("OneTimeTokenService").Return(ott)
+	r.On("MfaService").Return(mfa)
+
+	m := &MFAManager{r: r}
+	_, err := m.MFAVerify(getContext(), &models.MfaVerifyForm{Token: "token"})
+	assert.NotNil(t, err)
+	assert.Equal(t, models.ErrorMfaCodeInvalid, err.Message)
+	ott.AssertCalled(t, "Use", "token", mock.Anything)
+}
+
+func TestMFAVerifyRejectsAttemptOverMaxAttempts(t *testing.T) {
+	ott := &mocks.OneTimeTokenServiceInterface{}
+	mfa := &mocks.MfaApiInterface{}
+	r := mockIntRegistry()
+
+	ott.On("Get", "token", &models.UserMfaToken{}).Return(nil).Run(func(args mock.Arguments) {
+		arg := args.Get(1).(*models.UserMfaToken)
+		arg.UserIdentity = &models.UserIdentity{UserID: bson.NewObjectId()}
+		arg.MfaProvider = &models.MfaProvider{ID: bson.NewObjectId()}
+	})
+	ott.On("Attempt", "token").Return(mfaChallengeMaxAttempts+1, nil)
+	ott.On("Use", "token", mock.Anything).Return(nil)
+	mockMfaLockout(r)
+	r.On("OneTimeTokenService").Return(ott)
+	r.On("MfaService").Return(mfa)
+
+	m := &MFAManager{r: r}
+	_, err := m.MFAVerify(getContext(), &models.MfaVerifyForm{Token: "token"})
+	assert.NotNil(t, err)
+	assert.Equal(t, "mfa_token", err.Code)
+	mfa.AssertNotCalled(t, "Check", mock.Anything, mock.Anything)
+	ott.AssertCalled(t, "Use", "token", mock.Anything)
+}
+
+func TestMFAVerifyReturnErrorIfUserIsLocked(t *testing.T) {
+	ott := &mocks.OneTimeTokenServiceInterface{}
+	mfa := &mocks.MfaApiInterface{}
+	la := &mocks.LoginAttemptsInterface{}
+	r := mockIntRegistry()
+	userID := bson.NewObjectId()
+
+	ott.On("Get", "token", &models.UserMfaToken{}).Return(nil).Run(func(args mock.Arguments) {
+		arg := args.Get(1).(*models.UserMfaToken)
+		arg.UserIdentity = &models.UserIdentity{UserID: userID}
+		arg.MfaProvider = &models.MfaProvider{ID: bson.NewObjectId()}
+	})
+	la.On("Blocked", mfaLockKey(userID)).Return(time.Minute, time.Duration(0), nil)
+	r.On("LoginAttempts").Return(la)
+	r.On("OneTimeTokenService").Return(ott)
+	r.On("MfaService").Return(mfa)
+
+	m := &MFAManager{r: r}
+	_, err := m.MFAVerify(getContext(), &models.MfaVerifyForm{Token: "token"})
+	assert.NotNil(t, err)
+	assert.Equal(t, models.ErrorAuthTemporaryLocked, err.Message)
+	ott.AssertNotCalled(t, "Attempt", mock.Anything)
+	mfa.AssertNotCalled(t, "Check", mock.Anything, mock.Anything)
+}
+
+func TestMFAVerifyLocksUserAfterMaxFailedCodes(t *testing.T) {
+	ott := &mocks.OneTimeTokenServiceInterface{}
+	mfa := &mocks.MfaApiInterface{}
+	la := &mocks.LoginAttemptsInterface{}
+	r := mockIntRegistry()
+	userID := bson.NewObjectId()
+
+	ott.On("Get", "token", &models.UserMfaToken{}).Return(nil).Run(func(args mock.Arguments) {
+		arg := args.Get(1).(*models.UserMfaToken)
+		arg.UserIdentity = &models.UserIdentity{UserID: userID}
+		arg.MfaProvider = &models.MfaProvider{ID: bson.NewObjectId()}
+	})
+	ott.On("Attempt", "token").Return(1, nil)
+	la.On("Blocked", mfaLockKey(userID)).Return(time.Duration(0), time.Duration(0), nil)
+	la.On("Fail", mfaLockKey(userID), mfaWindow).Return(mfaMaxAttempts, nil)
+	la.On("Lock", mfaLockKey(userID), mfaLock).Return(nil)
+	mfa.On("Check", mock.Anything, mock.Anything).Return(&proto.MfaCheckDataResponse{Result: false}, nil)
+	r.On("LoginAttempts").Return(la)
+	r.On("OneTimeTokenService").Return(ott)
+	r.On("MfaService").Return(mfa)
+
+	m := &MFAManager{r: r}
+	_, err := m.MFAVerify(getContext(), &models.MfaVerifyForm{Token: "token"})
+	assert.NotNil(t, err)
+	assert.Equal(t, models.ErrorMfaCodeInvalid, err.Message)
+	la.AssertCalled(t, "Lock", mfaLockKey(userID), mfaLock)
 }
 
 func TestMFAVerifyReturnErrorWithUnableToGetUser(t *testing.T) {
@@ -96,6 +255,8 @@ func TestMFAVerifyReturnErrorWithUnableToGetUser(t *testing.T) {
 	})
 	mfa.On("Check", mock.Anything, mock.Anything).Return(&proto.MfaCheckDataResponse{Result: true}, nil)
 	us.On("Get", mock.Anything).Return(nil, errors.New(""))
+	ott.On("Attempt", "token").Return(1, nil)
+	mockMfaLockout(r)
 	r.On("OneTimeTokenService").Return(ott)
 	r.On("MfaService").Return(mfa)
 
@@ -103,7 +264,7 @@ func TestMFAVerifyReturnErrorWithUnableToGetUser(t *testing.T) {
 		r:           r,
 		userService: us,
 	}
-	err := m.MFAVerify(getContext(), &models.MfaVerifyForm{Token: "token"})
+	_, err := m.MFAVerify(getContext(), &models.MfaVerifyForm{Token: "token"})
 	assert.NotNil(t, err)
 	assert.Equal(t, "email", err.Code)
 	assert.Equal(t, models.ErrorLoginIncorrect, err.Message)
@@ -121,9 +282,12 @@ func TestMFAVerifySuccessResult(t *testing.T) {
 		arg.UserIdentity = &models.UserIdentity{UserID: bson.NewObjectId()}
 		arg.MfaProvider = &models.MfaProvider{ID: bson.NewObjectId()}
 	})
+	ott.On("Use", "token", mock.Anything).Return(nil)
 	mfa.On("Check", mock.Anything, mock.Anything).Return(&proto.MfaCheckDataResponse{Result: true}, nil)
 	us.On("Get", mock.Anything).Return(&models.User{}, nil)
 	a.On("Add", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	ott.On("Attempt", "token").Return(1, nil)
+	mockMfaLockout(r)
 	r.On("OneTimeTokenService").Return(ott)
 	r.On("MfaService").Return(mfa)
 
@@ -132,8 +296,111 @@ func TestMFAVerifySuccessResult(t *testing.T) {
 		userService:    us,
 		authLogService: a,
 	}
-	err := m.MFAVerify(getContext(), &models.MfaVerifyForm{Token: "token"})
+	_, err := m.MFAVerify(getContext(), &models.MfaVerifyForm{Token: "token"})
 	assert.Nil(t, err)
+	ott.AssertCalled(t, "Use", "token", mock.Anything)
+}
+
+func TestMFAVerifyAcceptsLoginChallenge(t *testing.T) {
+	ott := &mocks.OneTimeTokenServiceInterface{}
+	mfaApi := &mocks.MfaApiInterface{}
+	mfa := &mocks.MfaServiceInterface{}
+	us := &mocks.UserServiceInterface{}
+	h := &mocks.HydraAdminApi{}
+	r := mockIntRegistry()
+	providerID := bson.NewObjectId()
+
+	ott.On("Get", "token", &models.UserMfaToken{}).Return(nil).Run(func(args mock.Arguments) {
+		arg := args.Get(1).(*models.UserMfaToken)
+		arg.UserIdentity = &models.UserIdentity{UserID: bson.NewObjectId()}
+		arg.Challenge = "login_challenge"
+		arg.Amr = []string{"pwd"}
+	})
+	ott.On("Use", "token", mock.Anything).Return(nil)
+	mfa.On("GetUserProviders", mock.Anything).Return([]*models.MfaProvider{{ID: providerID, Type: "otp"}}, nil)
+	mfaApi.On("Check", mock.Anything, mock.Anything).Return(&proto.MfaCheckDataResponse{Result: true}, nil)
+	us.On("Get", mock.Anything).Return(&models.User{ID: bson.NewObjectId()}, nil)
+	h.On("AcceptLoginRequest", mock.MatchedBy(func(p *admin.AcceptLoginRequestParams) bool {
+		return p.LoginChallenge == "login_challenge" && reflect.DeepEqual(contextAmr(p.Body.Context), []string{"pwd", "otp", "mfa"})
+	})).Return(&admin.AcceptLoginRequestOK{Payload: &models2.CompletedRequest{RedirectTo: "url"}}, nil)
+	ott.On("Attempt", "token").Return(1, nil)
+	mockMfaLockout(r)
+	r.On("OneTimeTokenService").Return(ott)
+	r.On("MfaService").Return(mfaApi)
+	r.On("HydraAdminApi").Return(h)
+
+	m := &MFAManager{
+		r:           r,
+		userService: us,
+		mfaService:  mfa,
+	}
+	url, err := m.MFAVerify(getContext(), &models.MfaVerifyForm{Token: "token", ProviderId: providerID.Hex()})
+	assert.Nil(t, err)
+	assert.Equal(t, "url", url)
+}
+
+func TestMFAVerifyReturnErrorWithForeignProvider(t *testing.T) {
+	ott := &mocks.OneTimeTokenServiceInterface{}
+	mfa := &mocks.MfaServiceInterface{}
+	r := mockIntRegistry()
+
+	ott.On("Get", "token", &models.UserMfaToken{}).Return(nil).Run(func(args mock.Arguments) {
+		arg := args.Get(1).(*models.UserMfaToken)
+		arg.UserIdentity = &models.UserIdentity{UserID: bson.NewObjectId()}
+		arg.Challenge = "login_challenge"
+	})
+	mfa.On("GetUserProviders", mock.Anything).Return([]*models.MfaProvider{{ID: bson.NewObjectId()}}, nil)
+	r.On("OneTimeTokenService").Return(ott)
+
+	m := &MFAManager{
+		r:          r,
+		mfaService: mfa,
+	}
+	_, err := m.MFAVerify(getContext(), &models.MfaVerifyForm{Token: "token", ProviderId: bson.NewObjectId().Hex()})
+	assert.NotNil(t, err)
+	assert.Equal(t, "provider_id", err.Code)
+}
+
+// addWithLoginToken calls MFAAdd for the client with the login token issued to the token client.
+func addWithLoginToken(clientID, tokenClientID string) (*mocks.MfaServiceInterface, *models.GeneralError) {
+	app := &mocks.ApplicationServiceInterface{}
+	mfa := &mocks.MfaServiceInterface{}
+	ott := &mocks.OneTimeTokenServiceInterface{}
+	r := mockIntRegistry()
+
+	app.On("Get", mock.Anything).Return(&models.Application{}, nil)
+	mfa.On("Get", mock.Anything).Return(&models.MfaProvider{ID: bson.NewObjectId()}, nil)
+	mfa.On("GetUserProviders", mock.Anything).Return([]*models.MfaProvider{{ID: bson.NewObjectId()}}, nil)
+	ott.On("Get", "token", &models.UserMfaToken{}).Return(nil).Run(func(args mock.Arguments) {
+		arg := args.Get(1).(*models.UserMfaToken)
+		arg.UserIdentity = &models.UserIdentity{UserID: bson.NewObjectId()}
+		arg.Challenge = "login_challenge"
+		arg.ClientID = tokenClientID
+	})
+	r.On("ApplicationService").Return(app)
+	r.On("OneTimeTokenService").Return(ott)
+
+	m := &MFAManager{
+		r:          r,
+		mfaService: mfa,
+	}
+	_, err := m.MFAAdd(getContext(), &models.MfaAddForm{ClientId: clientID, ProviderId: bson.NewObjectId().Hex(), Token: "token"})
+	return mfa, err
+}
+
+func TestMFAAddReturnErrorWithLoginTokenIfUserHasProviders(t *testing.T) {
+	clientID := bson.NewObjectId().Hex()
+
+	_, err := addWithLoginToken(clientID, clientID)
+	assert.NotNil(t, err)
+	assert.Equal(t, "mfa_token", err.Code)
+}
+
+func TestMFAAddReturnErrorWithLoginTokenOfOtherClient(t *testing.T) {
+	mfa, err := addWithLoginToken(bson.NewObjectId().Hex(), bson.NewObjectId().Hex())
+	assert.NotNil(t, err)
+	assert.Equal(t, "client_id", err.Code)
+	mfa.AssertNotCalled(t, "GetUserProviders", mock.Anything)
 }
 
 func TestMFAAddReturnErrorWithUnableToGetApplication(t *testing.T) {
@@ -201,6 +468,7 @@ func TestMFAAddReturnErrorWithIncorrectAuthHeader(t *testing.T) {
 	mfaApi.On("Create", mock.Anything, mock.Anything).Return(nil, errors.New(""))
 	r.On("ApplicationService").Return(app)
 	r.On("MfaService").Return(mfaApi)
+	r.On("HydraAdminApi").Return(&mocks.HydraAdminApi{})
 
 	m := &MFAManager{
 		r:          r,
@@ -212,82 +480,341 @@ func TestMFAAddReturnErrorWithIncorrectAuthHeader(t *testing.T) {
 	assert.Equal(t, models.ErrorClientIdIncorrect, err.Message)
 }
 
-func TestMFAAddReturnErrorWithUnableToCreateMfa(t *testing.T) {
-	app := &mocks.ApplicationServiceInterface{}
-	mfa := &mocks.MfaServiceInterface{}
-	mfaApi := &mocks.MfaApiInterface{}
-	r := mockIntRegistry()
+// mfaAddTest adds the provider of the channel for the user authenticated by the bearer token.
+type mfaAddTest struct {
+	app    *models.Application
+	user   *models.User
+	mfa    *mocks.MfaServiceInterface
+	mfaApi *mocks.MfaApiInterface
+	us     *mocks.UserServiceInterface
+	r      *mocks.InternalRegistry
+	m      *MFAManager
+
+	deliveries *memory.WebhookDeliveryRepository
+	// clientID is the application the bearer token is issued to.
+	clientID bson.ObjectId
+}
 
-	app.On("Get", mock.Anything).Return(&models.Application{}, nil)
-	mfa.On("Get", mock.Anything).Return(&models.MfaProvider{ID: bson.NewObjectId()}, nil)
-	mfaApi.On("Create", mock.Anything, mock.Anything).Return(nil, errors.New(""))
+func newMFAAddTest(channel string) *mfaAddTest {
+	r, deliveries := mockIntRegistryWithWebHooks()
+	test := &mfaAddTest{
+		app:        &models.Application{ID: bson.NewObjectId(), WebHooks: []string{"http://localhost/hook"}},
+		user:       &models.User{ID: bson.NewObjectId(), Email: "test@example.com", PhoneNumber: "+15550000000"},
+		mfa:        &mocks.MfaServiceInterface{},
+		mfaApi:     &mocks.MfaApiInterface{},
+		us:         &mocks.UserServiceInterface{},
+		r:          r,
+		deliveries: deliveries,
+	}
+
+	app := &mocks.ApplicationServiceInterface{}
+	app.On("Get", mock.Anything).Return(test.app, nil)
+	test.mfa.On("Get", mock.Anything).Return(&models.MfaProvider{ID: bson.NewObjectId(), AppID: test.app.ID, Channel: channel}, nil)
+	test.us.On("Get", test.user.ID).Return(test.user, nil)
+	test.us.On("Update", mock.Anything).Return(nil)
 	r.On("ApplicationService").Return(app)
-	r.On("MfaService").Return(mfaApi)
+	r.On("MfaService").Return(test.mfaApi)
 
-	m := &MFAManager{
-		r:          r,
-		mfaService: mfa,
+	test.clientID = test.app.ID
+	test.m = &MFAManager{
+		r:           r,
+		mfaService:  test.mfa,
+		userService: test.us,
 	}
 
-	headers := map[string]interface{}{"Authorization": "Bearer 123", "X-CLIENT-ID": bson.NewObjectId().Hex()}
-	_, err := m.MFAAdd(getContext(map[string]interface{}{"headers": headers}), &models.MfaAddForm{ClientId: bson.NewObjectId().Hex(), ProviderId: bson.NewObjectId().Hex()})
+	return test
+}
+
+func (test *mfaAddTest) add(phone string) (*models.MfaAuthenticator, *models.GeneralError) {
+	mockBearer(test.r, test.clientID, test.user.ID)
+	headers := map[string]interface{}{"Authorization": "Bearer 123"}
+	return test.m.MFAAdd(getContext(map[string]interface{}{"headers": headers}), &models.MfaAddForm{ClientId: test.app.ID.Hex(), ProviderId: bson.NewObjectId().Hex(), PhoneNumber: phone})
+}
+
+func TestMFAAddReturnErrorWithUnableToCreateMfa(t *testing.T) {
+	test := newMFAAddTest("")
+	test.mfaApi.On("Create", mock.Anything, mock.Anything).Return(nil, errors.New(""))
+
+	_, err := test.add("")
 	assert.NotNil(t, err)
 	assert.Equal(t, "common", err.Code)
 	assert.Equal(t, models.ErrorMfaClientAdd, err.Message)
 }
 
 func TestMFAAddReturnErrorWithUnableToAddProvider(t *testing.T) {
-	app := &mocks.ApplicationServiceInterface{}
-	mfa := &mocks.MfaServiceInterface{}
-	mfaApi := &mocks.MfaApiInterface{}
-	r := mockIntRegistry()
-
-	app.On("Get", mock.Anything).Return(&models.Application{}, nil)
-	mfa.On("Get", mock.Anything).Return(&models.MfaProvider{ID: bson.NewObjectId()}, nil)
-	mfaApi.On("Create", mock.Anything, mock.Anything).Return(&proto.MfaCreateDataResponse{}, nil)
-	mfa.On("AddUserProvider", mock.Anything).Return(errors.New(""))
-	r.On("ApplicationService").Return(app)
-	r.On("MfaService").Return(mfaApi)
-
-	m := &MFAManager{
-		r:          r,
-		mfaService: mfa,
-	}
+	test := newMFAAddTest("")
+	test.mfaApi.On("Create", mock.Anything, mock.Anything).Return(&proto.MfaCreateDataResponse{}, nil)
+	test.mfa.On("AddUserProvider", mock.Anything).Return(errors.New(""))
 
-	headers := map[string]interface{}{"Authorization": "Bearer 123", "X-CLIENT-ID": bson.NewObjectId().Hex()}
-	_, err := m.MFAAdd(getContext(map[string]interface{}{"headers": headers}), &models.MfaAddForm{ClientId: bson.NewObjectId().Hex(), ProviderId: bson.NewObjectId().Hex()})
+	_, err := test.add("")
 	assert.NotNil(t, err)
 	assert.Equal(t, "common", err.Code)
 	assert.Equal(t, models.ErrorMfaClientAdd, err.Message)
 }
 
 func TestMFAAddReturnSuccess(t *testing.T) {
-	app := &mocks.ApplicationServiceInterface{}
-	mfa := &mocks.MfaServiceInterface{}
-	mfaApi := &mocks.MfaApiInterface{}
-	r := mockIntRegistry()
+	test := newMFAAddTest("")
+	test.mfaApi.On("Create", mock.Anything, mock.Anything).Return(&proto.MfaCreateDataResponse{}, nil)
+	test.mfa.On("AddUserProvider", mock.Anything).Return(nil)
 
-	app.On("Get", mock.Anything).Return(&models.Application{}, nil)
-	mfa.On("Get", mock.Anything).Return(&models.MfaProvider{ID: bson.NewObjectId()}, nil)
-	mfaApi.On("Create", mock.Anything, mock.Anything).Return(&proto.MfaCreateDataResponse{}, nil)
-	mfa.On("AddUserProvider", mock.Anything).Return(nil)
-	r.On("ApplicationService").Return(app)
-	r.On("MfaService").Return(mfaApi)
+	_, err := test.add("")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{webhooks.MFAAddedAction}, publishedActions(t, test.deliveries))
+	test.mfaApi.AssertCalled(t, "Create", mock.Anything, mock.MatchedBy(func(req *proto.MfaCreateDataRequest) bool {
+		return req.UserID == test.user.ID.String() && req.Email == test.user.Email
+	}))
+	test.mfa.AssertCalled(t, "AddUserProvider", mock.MatchedBy(func(up *models.MfaUserProvider) bool {
+		return up.UserID == test.user.ID
+	}))
+}
 
-	m := &MFAManager{
-		r:          r,
-		mfaService: mfa,
+func TestMFAAddReturnSuccessForEmailChannel(t *testing.T) {
+	test := newMFAAddTest(models.MfaChannelEmail)
+	test.mfa.On("AddUserProvider", mock.Anything).Return(nil)
+
+	_, err := test.add("")
+	assert.Nil(t, err)
+	test.mfaApi.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	test.mfa.AssertCalled(t, "AddUserProvider", mock.MatchedBy(func(up *models.MfaUserProvider) bool {
+		return up.UserID == test.user.ID
+	}))
+}
+
+func TestMFAAddReturnSuccessForSmsChannel(t *testing.T) {
+	test := newMFAAddTest(models.MfaChannelSms)
+	test.mfa.On("AddUserProvider", mock.Anything).Return(nil)
+
+	_, err := test.add("+15551111111")
+	assert.Nil(t, err)
+	test.us.AssertCalled(t, "Update", mock.MatchedBy(func(u *models.User) bool {
+		return u.ID == test.user.ID && u.PhoneNumber == "+15551111111" && !u.PhoneVerified
+	}))
+	test.mfa.AssertCalled(t, "AddUserProvider", mock.MatchedBy(func(up *models.MfaUserProvider) bool {
+		return up.UserID == test.user.ID
+	}))
+}
+
+func TestMFAAddReturnErrorWithBearerTokenOfOtherClient(t *testing.T) {
+	test := newMFAAddTest("")
+	test.clientID = bson.NewObjectId()
+
+	_, err := test.add("")
+	assert.NotNil(t, err)
+	assert.Equal(t, "client_id", err.Code)
+	test.mfa.AssertNotCalled(t, "AddUserProvider", mock.Anything)
+}
+
+type mfaChallengeTest struct {
+	app    *mocks.ApplicationServiceInterface
+	ott    *mocks.OneTimeTokenServiceInterface
+	us     *mocks.UserServiceInterface
+	mailer *mocks.MailerInterface
+	sms    *mocks.SmsSenderInterface
+	rl     *mocks.RateLimiterInterface
+	r      *mocks.InternalRegistry
+	m      *MFAManager
+
+	clientID string
+	provider *models.MfaProvider
+	user     *models.User
+}
+
+func newMfaChallengeTest(channel string) *mfaChallengeTest {
+	return &mfaChallengeTest{
+		app:      &mocks.ApplicationServiceInterface{},
+		ott:      &mocks.OneTimeTokenServiceInterface{},
+		us:       &mocks.UserServiceInterface{},
+		mailer:   &mocks.MailerInterface{},
+		sms:      &mocks.SmsSenderInterface{},
+		rl:       &mocks.RateLimiterInterface{},
+		r:        mockIntRegistry(),
+		clientID: bson.NewObjectId().Hex(),
+		provider: &models.MfaProvider{ID: bson.NewObjectId(), Type: "oob", Channel: channel},
+		user:     &models.User{ID: bson.NewObjectId(), Email: "email", PhoneNumber: "+10000000000"},
 	}
+}
 
-	headers := map[string]interface{}{"Authorization": "Bearer 123", "X-CLIENT-ID": bson.NewObjectId().Hex()}
-	_, err := m.MFAAdd(getContext(map[string]interface{}{"headers": headers}), &models.MfaAddForm{ClientId: bson.NewObjectId().Hex(), ProviderId: bson.NewObjectId().Hex()})
+func (test *mfaChallengeTest) init() {
+	test.app.On("Get", mock.Anything).Return(&models.Application{SpaceId: bson.NewObjectId()}, nil)
+	test.us.On("Get", mock.Anything).Return(test.user, nil)
+	test.ott.On("Get", "token", &models.UserMfaToken{}).Return(nil).Run(func(args mock.Arguments) {
+		arg := args.Get(1).(*models.UserMfaToken)
+		arg.UserIdentity = &models.UserIdentity{UserID: test.user.ID}
+		arg.MfaProvider = test.provider
+		arg.ClientID = test.clientID
+	})
+	test.ott.On("Get", "oob_code", &models.MfaChallengeToken{}).Return(nil).Run(func(args mock.Arguments) {
+		arg := args.Get(1).(*models.MfaChallengeToken)
+		arg.MfaToken = "token"
+		arg.ProviderID = test.provider.ID
+		arg.Code = "123456"
+	})
+	test.ott.On("Create", mock.Anything, mock.Anything).Return(&models.OneTimeToken{Token: "oob_code"}, nil)
+	test.ott.On("Use", "oob_code", mock.Anything).Return(nil)
+	test.ott.On("Use", "token", mock.Anything).Return(nil)
+	test.ott.On("Attempt", mock.Anything).Return(1, nil)
+	test.mailer.On("Send", "email", mock.Anything, mock.Anything).Return(nil)
+	test.sms.On("Send", "+10000000000", mock.Anything).Return(nil)
+	test.rl.On("Allow", mock.Anything, mock.Anything).Return(true, nil)
+
+	test.r.On("ApplicationService").Return(test.app)
+	test.r.On("OneTimeTokenService").Return(test.ott)
+	test.r.On("Mailer").Return(test.mailer)
+	test.r.On("SmsSender").Return(test.sms)
+	test.r.On("RateLimiter").Return(test.rl)
+	mockMfaLockout(test.r)
+	test.r.On("Spaces").Return(repository.OneSpaceRepo(&entity.Space{PasswordSettings: entity.PasswordSettings{TokenLength: 16, TokenTTL: 60}}))
+
+	test.m = &MFAManager{
+		r:           test.r,
+		userService: test.us,
+		TplCfg: &config.MailTemplates{
+			MfaCodeTpl:   "../../public/templates/email/mfa_code.html",
+			PlatformName: "Auth1",
+		},
+	}
+}
+
+func (test *mfaChallengeTest) challengeForm() *models.MfaChallengeForm {
+	return &models.MfaChallengeForm{ClientId: test.clientID, Token: "token"}
+}
+
+func TestMFAChallengeSendsCodeBySms(t *testing.T) {
+	test := newMfaChallengeTest(models.MfaChannelSms)
+	test.init()
+
+	ch, err := test.m.MFAChallenge(getContext(), test.challengeForm())
 	assert.Nil(t, err)
+	assert.Equal(t, "oob_code", ch.OobCode)
+	assert.Equal(t, "oob", ch.ChallengeType)
+	test.sms.AssertNumberOfCalls(t, "Send", 1)
+	test.ott.AssertCalled(t, "Create", mock.MatchedBy(func(ch *models.MfaChallengeToken) bool {
+		return ch.MfaToken == "token" && ch.ProviderID == test.provider.ID && len(ch.Code) == mfaChallengeCodeLength
+	}), mock.Anything)
 }
 
-func TestMFAChallengeReturnNil(t *testing.T) {
-	m := &MFAManager{}
-	err := m.MFAChallenge(&models.MfaChallengeForm{})
+func TestMFAChallengeReturnErrorWithForeignClient(t *testing.T) {
+	test := newMfaChallengeTest(models.MfaChannelSms)
+	test.init()
+
+	form := test.challengeForm()
+	form.ClientId = bson.NewObjectId().Hex()
+	_, err := test.m.MFAChallenge(getContext(), form)
+	assert.NotNil(t, err)
+	assert.Equal(t, "client_id", err.Code)
+	test.sms.AssertNotCalled(t, "Send", mock.Anything, mock.Anything)
+}
+
+func TestMFAChallengeReturnErrorWithResentCode(t *testing.T) {
+	test := newMfaChallengeTest(models.MfaChannelSms)
+	test.rl.On("Allow", "mfa_challenge_token_token", mfaChallengeTokenInterval).Return(false, nil)
+	test.init()
+
+	_, err := test.m.MFAChallenge(getContext(), test.challengeForm())
+	assert.NotNil(t, err)
+	assert.Equal(t, models.ErrorMfaChallengeThrottled, err.Message)
+	test.rl.AssertCalled(t, "Allow", "mfa_challenge_user_"+test.user.ID.Hex(), mfaChallengeUserInterval)
+	test.ott.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	test.sms.AssertNotCalled(t, "Send", mock.Anything, mock.Anything)
+}
+
+func TestMFAChallengeSendsCodeByEmail(t *testing.T) {
+	test := newMfaChallengeTest(models.MfaChannelEmail)
+	test.init()
+
+	_, err := test.m.MFAChallenge(getContext(), test.challengeForm())
 	assert.Nil(t, err)
+	test.mailer.AssertNumberOfCalls(t, "Send", 1)
+	test.sms.AssertNotCalled(t, "Send", mock.Anything, mock.Anything)
+}
+
+func TestMFAChallengeReturnErrorWithoutPhoneNumber(t *testing.T) {
+	test := newMfaChallengeTest(models.MfaChannelSms)
+	test.user.PhoneNumber = ""
+	test.init()
+
+	_, err := test.m.MFAChallenge(getContext(), test.challengeForm())
+	assert.NotNil(t, err)
+	assert.Equal(t, "phone_number", err.Code)
+	test.ott.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestMFAChallengeReturnErrorWithOtpProvider(t *testing.T) {
+	test := newMfaChallengeTest("")
+	test.init()
+
+	_, err := test.m.MFAChallenge(getContext(), test.challengeForm())
+	assert.NotNil(t, err)
+	assert.Equal(t, models.ErrorMfaChannelUnsupported, err.Message)
+}
+
+func TestMFAVerifyAcceptsChallengeCode(t *testing.T) {
+	test := newMfaChallengeTest(models.MfaChannelSms)
+	test.init()
+
+	_, err := test.m.MFAVerify(getContext(), &models.MfaVerifyForm{ClientId: test.clientID, Token: "token", OobCode: "oob_code", Code: "123456"})
+	assert.Nil(t, err)
+	test.ott.AssertCalled(t, "Use", "oob_code", mock.Anything)
+}
+
+func TestMFAVerifyReturnErrorWithForeignClient(t *testing.T) {
+	test := newMfaChallengeTest(models.MfaChannelSms)
+	test.init()
+
+	_, err := test.m.MFAVerify(getContext(), &models.MfaVerifyForm{ClientId: bson.NewObjectId().Hex(), Token: "token", OobCode: "oob_code", Code: "123456"})
+	assert.NotNil(t, err)
+	assert.Equal(t, "client_id", err.Code)
+	test.ott.AssertNotCalled(t, "Use", mock.Anything, mock.Anything)
+}
+
+func TestMFAVerifyBurnsChallengeCodeAfterMaxAttempts(t *testing.T) {
+	test := newMfaChallengeTest(models.MfaChannelSms)
+	test.ott.On("Attempt", "token").Return(mfaChallengeMaxAttempts, nil)
+	test.init()
+
+	_, err := test.m.MFAVerify(getContext(), &models.MfaVerifyForm{ClientId: test.clientID, Token: "token", OobCode: "oob_code", Code: "654321"})
+	assert.NotNil(t, err)
+	assert.Equal(t, models.ErrorMfaCodeInvalid, err.Message)
+	test.ott.AssertCalled(t, "Use", "oob_code", mock.Anything)
+	test.ott.AssertCalled(t, "Use", "token", mock.Anything)
+}
+
+func TestMFAVerifyRejectsChallengeCodeOverMaxAttempts(t *testing.T) {
+	test := newMfaChallengeTest(models.MfaChannelSms)
+	test.ott.On("Attempt", "token").Return(mfaChallengeMaxAttempts+1, nil)
+	test.init()
+
+	_, err := test.m.MFAVerify(getContext(), &models.MfaVerifyForm{ClientId: test.clientID, Token: "token", OobCode: "oob_code", Code: "123456"})
+	assert.NotNil(t, err)
+	assert.Equal(t, "mfa_token", err.Code)
+	test.ott.AssertNotCalled(t, "Get", "oob_code", mock.Anything)
+	test.ott.AssertCalled(t, "Use", "token", mock.Anything)
+}
+
+func TestMFAVerifyCountsChallengeCodeAttemptsByToken(t *testing.T) {
+	test := newMfaChallengeTest(models.MfaChannelSms)
+	test.ott.On("Attempt", "token").Return(1, nil)
+	test.init()
+
+	_, err := test.m.MFAVerify(getContext(), &models.MfaVerifyForm{ClientId: test.clientID, Token: "token", OobCode: "oob_code", Code: "654321"})
+	assert.NotNil(t, err)
+	test.ott.AssertNotCalled(t, "Attempt", "oob_code")
+	test.ott.AssertNotCalled(t, "Use", mock.Anything, mock.Anything)
+}
+
+func TestMFAVerifyReturnErrorWithForeignChallengeCode(t *testing.T) {
+	test := newMfaChallengeTest(models.MfaChannelSms)
+	test.ott.On("Get", "other_token", &models.UserMfaToken{}).Return(nil).Run(func(args mock.Arguments) {
+		arg := args.Get(1).(*models.UserMfaToken)
+		arg.UserIdentity = &models.UserIdentity{UserID: bson.NewObjectId()}
+		arg.MfaProvider = test.provider
+		arg.ClientID = test.clientID
+	})
+	test.init()
+
+	_, err := test.m.MFAVerify(getContext(), &models.MfaVerifyForm{ClientId: test.clientID, Token: "other_token", OobCode: "oob_code", Code: "123456"})
+	assert.NotNil(t, err)
+	assert.Equal(t, "oob_code", err.Code)
 }
 
 func TestMFAManagerError_MFAList(t *testing.T) {
@@ -382,6 +909,7 @@ func TestMFAManagerErrorWithoutHeaders_MFARemove(t *testing.T) {
 	mfa.On("RemoveUserProvider", mock.Anything).Return(nil)
 	r.On("ApplicationService").Return(app)
 	r.On("MfaService").Return(mfaApi)
+	r.On("HydraAdminApi").Return(&mocks.HydraAdminApi{})
 
 	m := &MFAManager{
 		r:          r,
@@ -406,6 +934,7 @@ func TestMFAManagerError_MFARemove(t *testing.T) {
 	mfa.On("RemoveUserProvider", mock.Anything).Return(errors.New("Some error"))
 	r.On("ApplicationService").Return(app)
 	r.On("MfaService").Return(mfaApi)
+	mockBearer(r, id, bson.NewObjectId())
 
 	m := &MFAManager{
 		r:          r,
@@ -423,14 +952,15 @@ func TestMFAManagerSuccess_MFARemove(t *testing.T) {
 	app := &mocks.ApplicationServiceInterface{}
 	mfa := &mocks.MfaServiceInterface{}
 	mfaApi := &mocks.MfaApiInterface{}
-	r := mockIntRegistry()
+	r, deliveries := mockIntRegistryWithWebHooks()
 
-	id := bson.NewObjectId()
-	app.On("Get", mock.Anything).Return(&models.Application{ID: id}, nil)
+	id, userID := bson.NewObjectId(), bson.NewObjectId()
+	app.On("Get", mock.Anything).Return(&models.Application{ID: id, WebHooks: []string{"http://localhost/hook"}}, nil)
 	mfa.On("Get", mock.Anything).Return(&models.MfaProvider{ID: bson.NewObjectId(), AppID: id}, nil)
 	mfa.On("RemoveUserProvider", mock.Anything).Return(nil)
 	r.On("ApplicationService").Return(app)
 	r.On("MfaService").Return(mfaApi)
+	mockBearer(r, id, userID)
 
 	m := &MFAManager{
 		r:          r,
@@ -440,4 +970,14 @@ func TestMFAManagerSuccess_MFARemove(t *testing.T) {
 	headers := map[string]interface{}{"Authorization": "Bearer 123", "X-CLIENT-ID": bson.NewObjectId().Hex()}
 	err := m.MFARemove(getContext(map[string]interface{}{"headers": headers}), &models.MfaRemoveForm{ClientId: bson.NewObjectId().Hex(), ProviderId: bson.NewObjectId().Hex()})
 	assert.Nil(t, err)
+	mfa.AssertCalled(t, "RemoveUserProvider", mock.MatchedBy(func(up *models.MfaUserProvider) bool {
+		return up.UserID == userID
+	}))
+	list, _, _ := deliveries.Find(context.Background(), repository.WebhookDeliveryQuery{})
+	if assert.Len(t, list, 1) {
+		hook := webhooks.Hook{}
+		assert.Nil(t, json.Unmarshal(list[0].Payload, &hook))
+		assert.Equal(t, webhooks.MFARemovedAction, hook.Action)
+		assert.Equal(t, userID.Hex(), hook.UserID)
+	}
 }
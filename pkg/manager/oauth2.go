@@ -2,16 +2,20 @@ package manager
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"time"
 
 	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
 	"github.com/ProtocolONE/auth1.protocol.one/pkg/api/apierror"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/appcore/log"
 	"github.com/ProtocolONE/auth1.protocol.one/pkg/captcha"
 	"github.com/ProtocolONE/auth1.protocol.one/pkg/config"
 	"github.com/ProtocolONE/auth1.protocol.one/pkg/database"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/hasher"
 	"github.com/ProtocolONE/auth1.protocol.one/pkg/models"
 	"github.com/ProtocolONE/auth1.protocol.one/pkg/service"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/webhooks"
 	"github.com/ProtocolONE/authone-jwt-verifier-golang"
 	"github.com/globalsign/mgo"
 	"github.com/globalsign/mgo/bson"
@@ -20,6 +24,7 @@ import (
 	"github.com/ory/hydra-client-go/client/admin"
 	models2 "github.com/ory/hydra-client-go/models"
 	"github.com/pkg/errors"
+	"go.uber.org/zap"
 	"gopkg.in/tomb.v2"
 )
 
@@ -27,6 +32,11 @@ const (
 	scopeOffline = "offline"
 	scopeOpenId  = "openid"
 	RememberTime = 30 * 24 * 60 * 60
+
+	// Authentication method references, see RFC 8176.
+	amrPassword = "pwd"
+	amrOtp      = "otp"
+	amrMfa      = "mfa"
 )
 
 var (
@@ -97,11 +107,14 @@ type OauthManager struct {
 	userService         service.UserServiceInterface
 	userIdentityService service.UserIdentityServiceInterface
 	authLogService      service.AuthLogServiceInterface
+	mfaService          service.MfaServiceInterface
+	webAuthnService     service.WebAuthnServiceInterface
 	r                   service.InternalRegistry
 	session             service.SessionService
 	ApiCfg              *config.Server
 	recaptcha           *captcha.Recaptcha
 	lm                  LoginManagerInterface
+	ev                  EmailVerificationManagerInterface
 }
 
 // NewOauthManager return new oauth manager.
@@ -111,7 +124,8 @@ func NewOauthManager(
 	s *config.Session,
 	h *config.Hydra,
 	apiCfg *config.Server,
-	recaptcha *captcha.Recaptcha) OauthManagerInterface {
+	recaptcha *captcha.Recaptcha,
+	tplCfg *config.MailTemplates) OauthManagerInterface {
 	m := &OauthManager{
 		ApiCfg:              apiCfg,
 		hydraConfig:         h,
@@ -119,9 +133,12 @@ func NewOauthManager(
 		userService:         service.NewUserService(db),
 		userIdentityService: service.NewUserIdentityService(db),
 		authLogService:      service.NewAuthLogService(db, r.GeoIpService()),
+		mfaService:          service.NewMfaService(db),
+		webAuthnService:     service.NewWebAuthnService(db),
 		session:             service.NewSessionService(s.Name),
 		recaptcha:           recaptcha,
 		lm:                  NewLoginManager(db, r),
+		ev:                  NewEmailVerificationManager(db, r, tplCfg),
 	}
 
 	return m
@@ -146,6 +163,26 @@ func (m *OauthManager) CheckAuth(ctx echo.Context, form *models.Oauth2LoginForm)
 	}
 
 	if req.Payload.Skip == true {
+		user, err := m.userService.Get(bson.ObjectIdHex(req.Payload.Subject))
+		if err != nil {
+			return "", &models.GeneralError{Code: "common", Message: models.ErrorUnknownError, Err: errors.Wrap(err, "Unable to get user")}
+		}
+		if user.IsBlocked(time.Now()) {
+			return "", &models.GeneralError{Code: "common", Message: models.ErrorUserBlocked, Err: apierror.UserBlocked}
+		}
+
+		app, err := m.r.ApplicationService().Get(bson.ObjectIdHex(req.Payload.Client.ClientID))
+		if err != nil {
+			return "", &models.GeneralError{Code: "client_id", Message: models.ErrorClientIdIncorrect, Err: errors.Wrap(err, "Unable to load application")}
+		}
+		space, err := m.r.Spaces().FindByID(ctx.Request().Context(), entity.SpaceID(app.SpaceId.Hex()))
+		if err != nil {
+			return "", &models.GeneralError{Code: "common", Message: models.ErrorUnknownError, Err: errors.Wrap(err, "Unable to load space")}
+		}
+		if space.RequiresVerifiedEmail && !user.EmailVerified {
+			return "", &models.GeneralError{Code: "common", Message: models.ErrorEmailNotVerified, Err: apierror.EmailNotVerified}
+		}
+
 		reqACL, err := m.r.HydraAdminApi().AcceptLoginRequest(&admin.AcceptLoginRequestParams{
 			Context:        ctx.Request().Context(),
 			LoginChallenge: form.Challenge,
@@ -192,8 +229,11 @@ func (m *OauthManager) Auth(ctx echo.Context, form *models.Oauth2LoginSubmitForm
 
 	userId := req.Payload.Subject
 	userIdentity := &models.UserIdentity{}
+	var amr []string
+	// INFO: The user is set if it is authenticated again, the remembered previous login doesn't require mfa
+	var loginUser *models.User
+	var ipc *entity.IdentityProvider
 	if req.Payload.Subject == "" || req.Payload.Subject != form.PreviousLogin {
-		var ipc *entity.IdentityProvider
 		if form.Token != "" {
 			if err := m.r.OneTimeTokenService().Use(form.Token, userIdentity); err != nil {
 				return "", apierror.InvalidToken
@@ -202,15 +242,24 @@ func (m *OauthManager) Auth(ctx echo.Context, form *models.Oauth2LoginSubmitForm
 			ip := space.DefaultIDProvider()
 			ipc = &ip
 
+			clientIP := service.GetClientIP(ctx)
+			if err := checkLockout(m.r, space, form.Email, clientIP); err != nil {
+				return "", err
+			}
+
 			userIdentity, err = m.userIdentityService.Get(models.OldIDProvider(ip), form.Email)
 			if err != nil {
+				failLogin(ctx.Request().Context(), m.r, space, form.Email, clientIP)
 				return "", apierror.InvalidCredentials
 			}
 
-			encryptor := models.NewBcryptEncryptor(&models.CryptConfig{Cost: space.PasswordSettings.BcryptCost})
-			if err := encryptor.Compare(userIdentity.Credential, form.Password); err != nil {
+			if err := hasher.Verify(userIdentity.Credential, form.Password); err != nil {
+				failLogin(ctx.Request().Context(), m.r, space, form.Email, clientIP)
 				return "", apierror.InvalidCredentials
 			}
+			resetLockout(ctx.Request().Context(), m.r, space, form.Email)
+			m.rehashPassword(ctx.Request().Context(), space, userIdentity, form.Password)
+			amr = append(amr, amrPassword)
 
 			if form.Social != "" {
 				if err := m.lm.Link(form.Social, userIdentity.UserID, app); err != nil {
@@ -226,6 +275,12 @@ func (m *OauthManager) Auth(ctx echo.Context, form *models.Oauth2LoginSubmitForm
 		if err != nil {
 			return "", errors.Wrap(err, "unable to get user")
 		}
+		if user.IsBlocked(time.Now()) {
+			return "", apierror.UserBlocked
+		}
+		if space.RequiresVerifiedEmail && !user.EmailVerified {
+			return "", apierror.EmailNotVerified
+		}
 
 		user.LoginsCount = user.LoginsCount + 1
 		user.AddDeviceID(service.GetDeviceID(ctx))
@@ -238,8 +293,20 @@ func (m *OauthManager) Auth(ctx echo.Context, form *models.Oauth2LoginSubmitForm
 			return "", errors.Wrap(err, "unable to add auth log")
 		}
 		userId = user.ID.Hex()
+		loginUser = user
 
 	} else {
+		user, err := m.userService.Get(bson.ObjectIdHex(userId))
+		if err != nil {
+			return "", errors.Wrap(err, "unable to get user")
+		}
+		if user.IsBlocked(time.Now()) {
+			return "", apierror.UserBlocked
+		}
+		if space.RequiresVerifiedEmail && !user.EmailVerified {
+			return "", apierror.EmailNotVerified
+		}
+
 		form.Remember = true
 	}
 
@@ -247,20 +314,66 @@ func (m *OauthManager) Auth(ctx echo.Context, form *models.Oauth2LoginSubmitForm
 		return "", errors.Wrap(err, "error saving session")
 	}
 
-	// TODO: Add MFA cases
+	if loginUser != nil {
+		token := &models.UserMfaToken{
+			UserIdentity: userIdentity,
+			Challenge:    form.Challenge,
+			Remember:     form.Remember,
+			Amr:          amr,
+			ClientID:     app.ID.Hex(),
+		}
+		if ipc != nil {
+			token.Provider = ipc.Name
+		}
+		if err := requireMfa(m.r, m.mfaService, m.webAuthnService, space, loginUser, token); err != nil {
+			return "", err
+		}
+	}
 
 	reqACL, err := m.r.HydraAdminApi().AcceptLoginRequest(&admin.AcceptLoginRequestParams{
 		Context:        ctx.Request().Context(),
 		LoginChallenge: form.Challenge,
-		Body:           &models2.AcceptLoginRequest{Subject: &userId, Remember: form.Remember, RememberFor: RememberTime},
+		Body:           &models2.AcceptLoginRequest{Subject: &userId, Remember: form.Remember, RememberFor: RememberTime, Context: loginContext(amr)},
 	})
 	if err != nil {
 		return "", errors.Wrap(err, "unable to accept login challenge")
 	}
 
+	// INFO: The remembered previous login isn't authenticated again
+	if userIdentity.UserID != "" {
+		event := webhooks.UserLoginEvent{Amr: amr}
+		if ipc != nil {
+			event.Provider = ipc.Name
+		}
+		publish(ctx.Request().Context(), m.r, app, userIdentity.UserID, event)
+	}
+
 	return reqACL.Payload.RedirectTo, nil
 }
 
+// rehashPassword replaces the hash made by the outdated algorithm or parameters with the one chosen by the space,
+// the login isn't failed if it's impossible.
+func (m *OauthManager) rehashPassword(ctx context.Context, space *entity.Space, ui *models.UserIdentity, password string) {
+	h, err := hasher.New(space.PasswordSettings)
+	if err != nil {
+		log.Error(ctx, "Unable to get password hasher of the space", zap.Error(err))
+		return
+	}
+	if !hasher.NeedsRehash(h, ui.Credential) {
+		return
+	}
+
+	hash, err := h.Hash(password)
+	if err == nil {
+		err = m.userIdentityService.UpdateCredential(ui.ID, hash)
+	}
+	if err != nil {
+		log.Error(ctx, "Unable to rehash password", zap.Error(err))
+		return
+	}
+	ui.Credential = hash
+}
+
 func (m *OauthManager) Consent(ctx echo.Context, form *models.Oauth2ConsentForm) ([]string, *models.GeneralError) {
 	reqGCR, err := m.r.HydraAdminApi().GetConsentRequest(&admin.GetConsentRequestParams{Context: ctx.Request().Context(), ConsentChallenge: form.Challenge})
 
@@ -308,6 +421,9 @@ func (m *OauthManager) ConsentSubmit(ctx echo.Context, form *models.Oauth2Consen
 		"picture":               user.Picture,
 		"username":              user.Username,
 	}
+	if amr := contextAmr(reqGCR.Payload.Context); len(amr) > 0 {
+		userInfo["amr"] = amr
+	}
 	req := models2.AcceptConsentRequest{
 		GrantScope:  form.Scope,
 		Remember:    true,
@@ -458,15 +574,18 @@ func (m *OauthManager) SignUp(ctx echo.Context, form *models.Oauth2SignUpForm) (
 		}
 	}
 
-	if false == space.PasswordSettings.IsValid(form.Password) {
-		return "", apierror.WeakPassword
+	if err := checkPassword(m.r, space, form.Password, nil); err != nil {
+		return "", err
 	}
 
 	encryptedPassword := ""
 	t, _ := tomb.WithContext(ctx.Request().Context())
 	t.Go(func() error {
-		encryptor := models.NewBcryptEncryptor(&models.CryptConfig{Cost: space.PasswordSettings.BcryptCost})
-		encryptedPassword, err = encryptor.Digest(form.Password)
+		h, err := hasher.New(space.PasswordSettings)
+		if err != nil {
+			return err
+		}
+		encryptedPassword, err = h.Hash(form.Password)
 		return err
 	})
 
@@ -531,6 +650,16 @@ func (m *OauthManager) SignUp(ctx echo.Context, form *models.Oauth2SignUpForm) (
 		return "", errors.Wrap(err, "unable to add auth log")
 	}
 
+	publish(ctx.Request().Context(), m.r, app, user.ID, webhooks.UserRegisteredEvent{Email: user.Email, Username: user.Username})
+
+	// INFO: The user is registered already, so the failed email is only logged and may be sent again on demand
+	if err := m.ev.EmailVerificationSend(space, app, user, form.Challenge); err != nil {
+		log.Error(ctx.Request().Context(), "Unable to send verification email", zap.Error(err))
+	}
+	if space.RequiresVerifiedEmail {
+		return "", apierror.EmailNotVerified
+	}
+
 	userId := user.ID.Hex()
 	reqACL, err := m.r.HydraAdminApi().AcceptLoginRequest(&admin.AcceptLoginRequestParams{Context: ctx.Request().Context(), LoginChallenge: form.Challenge, Body: &models2.AcceptLoginRequest{Subject: &userId}})
 	if err != nil {
@@ -639,3 +768,25 @@ func (m *OauthManager) loadRemoteScopes(scopes []string) error {
 	scopes = append(scopes, []string{"test1", "test2"}...)
 	return nil
 }
+
+// loginContext returns the context of the accepted login request, which Hydra passes to the consent request.
+func loginContext(amr []string) map[string]interface{} {
+	return map[string]interface{}{"amr": amr}
+}
+
+// contextAmr returns the authentication methods stored by loginContext.
+func contextAmr(v interface{}) []string {
+	var c struct {
+		Amr []string `json:"amr"`
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil
+	}
+
+	return c.Amr
+}
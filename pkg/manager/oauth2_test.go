@@ -1,12 +1,20 @@
 package manager
 
 import (
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
 	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/repository"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/repository/webhook_delivery/memory"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/api/apierror"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/config"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/hasher"
 	"github.com/ProtocolONE/auth1.protocol.one/pkg/mocks"
 	"github.com/ProtocolONE/auth1.protocol.one/pkg/models"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/passwords"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/webhooks"
 	"github.com/globalsign/mgo/bson"
 	"github.com/ory/hydra-client-go/client/admin"
 	models2 "github.com/ory/hydra-client-go/models"
@@ -15,6 +23,9 @@ import (
 	"github.com/stretchr/testify/mock"
 )
 
+// testSubject is the user remembered by the login request.
+var testSubject = bson.NewObjectId().Hex()
+
 type testOAuth2 struct {
 	app  *mocks.ApplicationServiceInterface
 	h    *mocks.HydraAdminApi
@@ -23,15 +34,22 @@ type testOAuth2 struct {
 	us   *mocks.UserServiceInterface
 	ott  *mocks.OneTimeTokenServiceInterface
 	al   *mocks.AuthLogServiceInterface
+	mfa  *mocks.MfaServiceInterface
+	wa   *mocks.WebAuthnServiceInterface
+	rl   *mocks.RateLimiterInterface
+	la   *mocks.LoginAttemptsInterface
+	mail *mocks.MailerInterface
 
-	r *mocks.InternalRegistry
-	m *OauthManager
+	r          *mocks.InternalRegistry
+	m          *OauthManager
+	deliveries *memory.WebhookDeliveryRepository
 
 	space        *entity.Space
 	loginRequest *admin.GetLoginRequestOK
 }
 
 func newTestOAuth2() *testOAuth2 {
+	r, deliveries := mockIntRegistryWithWebHooks()
 	return &testOAuth2{
 		app:  &mocks.ApplicationServiceInterface{},
 		h:    &mocks.HydraAdminApi{},
@@ -40,7 +58,14 @@ func newTestOAuth2() *testOAuth2 {
 		us:   &mocks.UserServiceInterface{},
 		ott:  &mocks.OneTimeTokenServiceInterface{},
 		al:   &mocks.AuthLogServiceInterface{},
-		r:    mockIntRegistry(),
+		mfa:  &mocks.MfaServiceInterface{},
+		wa:   &mocks.WebAuthnServiceInterface{},
+		rl:   &mocks.RateLimiterInterface{},
+		la:   &mocks.LoginAttemptsInterface{},
+		mail: &mocks.MailerInterface{},
+		r:    r,
+
+		deliveries: deliveries,
 
 		space: &entity.Space{
 			PasswordSettings: entity.PasswordSettings{Min: 1, Max: 8, BcryptCost: 4},
@@ -53,13 +78,13 @@ func newTestOAuth2() *testOAuth2 {
 		},
 		loginRequest: &admin.GetLoginRequestOK{Payload: &models2.LoginRequest{
 			Client:  &models2.OAuth2Client{ClientID: bson.NewObjectId().Hex()},
-			Subject: "subj",
+			Subject: testSubject,
 		}},
 	}
 }
 
 func (test *testOAuth2) init() {
-	test.app.On("Get", mock.Anything).Return(&models.Application{}, nil)
+	test.app.On("Get", mock.Anything).Return(&models.Application{WebHooks: []string{"http://localhost/hook"}}, nil)
 
 	test.h.On("GetLoginRequest", mock.Anything).Return(test.loginRequest, nil)
 	test.h.On("AcceptLoginRequest", mock.Anything).Return(&admin.AcceptLoginRequestOK{Payload: &models2.CompletedRequest{RedirectTo: "url"}}, nil)
@@ -72,19 +97,35 @@ func (test *testOAuth2) init() {
 	// passHash, _ := be.Digest("1234")
 	// test.uis.On("Get", mock.Anything, "email").Return(&models.UserIdentity{Credential: passHash}, nil)
 	test.uis.On("Create", mock.Anything).Return(nil)
+	test.uis.On("UpdateCredential", mock.Anything, mock.Anything).Return(nil)
 
 	test.us.On("Create", mock.Anything).Return(nil)
 	test.us.On("Get", mock.Anything).Return(&models.User{}, nil)
 	test.us.On("Update", mock.Anything).Return(nil)
 
 	test.ott.On("Use", "invalid_auth_token", mock.Anything).Return(nil)
+	test.ott.On("Create", mock.Anything, mock.Anything).Return(&models.OneTimeToken{Token: "token"}, nil)
 
 	test.al.On("Add", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 
+	test.mfa.On("GetUserProviders", mock.Anything).Return(nil, nil)
+	test.wa.On("GetUserCredentials", mock.Anything).Return(nil, nil)
+
+	test.rl.On("Allow", mock.Anything, mock.Anything).Return(true, nil)
+	test.la.On("Blocked", mock.Anything).Return(time.Duration(0), time.Duration(0), nil)
+	test.la.On("Fail", mock.Anything, mock.Anything).Return(1, nil)
+	test.la.On("Delay", mock.Anything, mock.Anything).Return(nil)
+	test.la.On("Lock", mock.Anything, mock.Anything).Return(nil)
+	test.la.On("Reset", mock.Anything).Return(nil)
+	test.mail.On("Send", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
 	test.r.On("OneTimeTokenService").Return(test.ott)
 	test.r.On("HydraAdminApi").Return(test.h)
 	test.r.On("ApplicationService").Return(test.app)
 	test.r.On("Spaces").Return(repository.OneSpaceRepo(test.space))
+	test.r.On("RateLimiter").Return(test.rl)
+	test.r.On("LoginAttempts").Return(test.la)
+	test.r.On("Mailer").Return(test.mail)
 
 	test.m = &OauthManager{
 		r:                   test.r,
@@ -92,6 +133,16 @@ func (test *testOAuth2) init() {
 		userService:         test.us,
 		userIdentityService: test.uis,
 		authLogService:      test.al,
+		mfaService:          test.mfa,
+		webAuthnService:     test.wa,
+		ev: &EmailVerificationManager{
+			r:                   test.r,
+			userService:         test.us,
+			userIdentityService: test.uis,
+			TplCfg: &config.MailTemplates{
+				VerifyEmailTpl: "../../public/templates/email/verify_email.html",
+			},
+		},
 	}
 }
 
@@ -102,6 +153,19 @@ func TestSignUpReturnUrlOnSuccessResponse(t *testing.T) {
 	url, err := test.m.SignUp(getContext(), &models.Oauth2SignUpForm{Remember: true, Password: "11", Challenge: "login_challenge", Email: "email"})
 	assert.Nil(t, err)
 	assert.Equal(t, "url", url)
+	assert.Equal(t, []string{webhooks.UserRegisteredAction}, publishedActions(t, test.deliveries))
+	test.mail.AssertNumberOfCalls(t, "Send", 1)
+}
+
+func TestSignUpReturnErrorIfSpaceRequiresVerifiedEmail(t *testing.T) {
+	test := newTestOAuth2()
+	test.space.RequiresVerifiedEmail = true
+	test.init()
+
+	_, err := test.m.SignUp(getContext(), &models.Oauth2SignUpForm{Remember: true, Password: "11", Challenge: "login_challenge", Email: "email"})
+	assert.Equal(t, apierror.EmailNotVerified, err)
+	test.mail.AssertNumberOfCalls(t, "Send", 1)
+	test.h.AssertNotCalled(t, "AcceptLoginRequest", mock.Anything)
 }
 
 func TestCheckAuthReturnEmptyWithoutSkip(t *testing.T) {
@@ -136,15 +200,122 @@ func TestCheckAuthReturnUrlForSkipStep(t *testing.T) {
 	assert.Equal(t, "url", url)
 }
 
+func TestCheckAuthReturnErrorForBlockedUser(t *testing.T) {
+	test := newTestOAuth2()
+	test.loginRequest.Payload.Skip = true
+	test.us.On("Get", mock.Anything).Return(&models.User{Blocked: true}, nil)
+	test.init()
+
+	url, err := test.m.CheckAuth(getContext(), &models.Oauth2LoginForm{Challenge: "login_challenge"})
+	if assert.NotNil(t, err) {
+		assert.Equal(t, models.ErrorUserBlocked, err.Message)
+		assert.Equal(t, apierror.UserBlocked, errors.Cause(err.Err))
+	}
+	assert.Equal(t, "", url)
+	test.h.AssertNotCalled(t, "AcceptLoginRequest", mock.Anything)
+}
+
+func TestCheckAuthReturnErrorIfSpaceRequiresVerifiedEmail(t *testing.T) {
+	test := newTestOAuth2()
+	test.loginRequest.Payload.Skip = true
+	test.space.RequiresVerifiedEmail = true
+	test.init()
+
+	url, err := test.m.CheckAuth(getContext(), &models.Oauth2LoginForm{Challenge: "login_challenge"})
+	if assert.NotNil(t, err) {
+		assert.Equal(t, models.ErrorEmailNotVerified, err.Message)
+		assert.Equal(t, apierror.EmailNotVerified, errors.Cause(err.Err))
+	}
+	assert.Equal(t, "", url)
+	test.h.AssertNotCalled(t, "AcceptLoginRequest", mock.Anything)
+}
+
+func TestAuthReturnErrorForBlockedUser(t *testing.T) {
+	test := newTestOAuth2()
+	test.us.On("Get", mock.Anything).Return(&models.User{Blocked: true, BlockedUntil: time.Now().Add(time.Hour)}, nil)
+	test.init()
+
+	_, err := test.m.Auth(getContext(), &models.Oauth2LoginSubmitForm{Challenge: "login_challenge", Remember: true, PreviousLogin: testSubject})
+	assert.Equal(t, apierror.UserBlocked, err)
+	test.h.AssertNotCalled(t, "AcceptLoginRequest", mock.Anything)
+}
+
+func TestAuthAcceptsUserWithExpiredBlock(t *testing.T) {
+	test := newTestOAuth2()
+	test.us.On("Get", mock.Anything).Return(&models.User{Blocked: true, BlockedUntil: time.Now().Add(-time.Hour)}, nil)
+	test.init()
+
+	url, err := test.m.Auth(getContext(), &models.Oauth2LoginSubmitForm{Challenge: "login_challenge", Remember: true, PreviousLogin: testSubject})
+	assert.Nil(t, err)
+	assert.Equal(t, "url", url)
+}
+
+func TestAuthReturnErrorForUnverifiedEmail(t *testing.T) {
+	test := newTestOAuth2()
+	test.space.RequiresVerifiedEmail = true
+	test.init()
+
+	_, err := test.m.Auth(getContext(), &models.Oauth2LoginSubmitForm{Challenge: "login_challenge", Remember: true, PreviousLogin: testSubject})
+	assert.Equal(t, apierror.EmailNotVerified, err)
+	test.h.AssertNotCalled(t, "AcceptLoginRequest", mock.Anything)
+}
+
 func TestAuthReturnUrlToConsentRequest(t *testing.T) {
 	test := newTestOAuth2()
 	test.init()
 
-	url, err := test.m.Auth(getContext(), &models.Oauth2LoginSubmitForm{Challenge: "login_challenge", Remember: true, PreviousLogin: "subj"})
+	url, err := test.m.Auth(getContext(), &models.Oauth2LoginSubmitForm{Challenge: "login_challenge", Remember: true, PreviousLogin: testSubject})
 	assert.Nil(t, err)
 	assert.Equal(t, "url", url)
 }
 
+func TestAuthReturnMfaRequiredIfUserHasProviders(t *testing.T) {
+	test := newTestOAuth2()
+	test.loginRequest.Payload.Subject = ""
+	test.mfa.On("GetUserProviders", mock.Anything).Return([]*models.MfaProvider{{ID: bson.NewObjectId()}}, nil)
+	test.ott.On("Create", mock.MatchedBy(func(t *models.UserMfaToken) bool { return t.Challenge == "login_challenge" }), mock.Anything).Return(&models.OneTimeToken{Token: "mfa_token"}, nil)
+	test.init()
+
+	_, err := test.m.Auth(getContext(), &models.Oauth2LoginSubmitForm{Challenge: "login_challenge", Token: "invalid_auth_token"})
+	if assert.IsType(t, &apierror.APIError{}, err) {
+		assert.Equal(t, apierror.MfaRequired.Code, err.(*apierror.APIError).Code)
+		assert.Equal(t, "mfa_token", err.(*apierror.APIError).Data.(*models.MfaRequiredResponse).Token)
+	}
+	test.h.AssertNotCalled(t, "AcceptLoginRequest", mock.Anything)
+}
+
+func TestAuthReturnMfaRequiredIfSpaceRequiresMfa(t *testing.T) {
+	test := newTestOAuth2()
+	test.loginRequest.Payload.Subject = ""
+	test.space.RequiresMfa = true
+	test.ott.On("Create", mock.Anything, mock.Anything).Return(&models.OneTimeToken{Token: "mfa_token"}, nil)
+	test.init()
+
+	_, err := test.m.Auth(getContext(), &models.Oauth2LoginSubmitForm{Challenge: "login_challenge", Token: "invalid_auth_token"})
+	if assert.IsType(t, &apierror.APIError{}, err) {
+		assert.Empty(t, err.(*apierror.APIError).Data.(*models.MfaRequiredResponse).Providers)
+	}
+}
+
+func TestAuthReturnMfaRequiredIfUserHasPasskeys(t *testing.T) {
+	test := newTestOAuth2()
+	test.loginRequest.Payload.Subject = ""
+	test.wa.On("GetUserCredentials", mock.Anything).Return([]*models.WebAuthnCredential{{ID: bson.NewObjectId()}}, nil)
+	test.ott.On("Create", mock.Anything, mock.Anything).Return(&models.OneTimeToken{Token: "mfa_token"}, nil)
+	test.init()
+
+	_, err := test.m.Auth(getContext(), &models.Oauth2LoginSubmitForm{Challenge: "login_challenge", Token: "invalid_auth_token"})
+	if assert.IsType(t, &apierror.APIError{}, err) {
+		assert.True(t, err.(*apierror.APIError).Data.(*models.MfaRequiredResponse).WebAuthn)
+	}
+	test.h.AssertNotCalled(t, "AcceptLoginRequest", mock.Anything)
+}
+
+func TestContextAmrReturnsMethodsFromLoginContext(t *testing.T) {
+	assert.Equal(t, []string{"pwd", "otp"}, contextAmr(loginContext([]string{"pwd", "otp"})))
+	assert.Nil(t, contextAmr(nil))
+}
+
 ///////////////////////////////////////////////////////////////////////
 // Negative cases
 
@@ -255,6 +426,117 @@ func TestAuthReturnErrorWithComparePassword(t *testing.T) {
 	// assert.Equal(t, models.ErrorPasswordIncorrect, err.Message)
 }
 
+func TestAuthReturnErrorForLockedIdentity(t *testing.T) {
+	test := newTestOAuth2()
+	test.space.ID = "space"
+	test.space.LockoutSettings = entity.DefaultLockoutSettings
+	test.la.On("Blocked", identityLockKey("space", "email")).Return(time.Minute, time.Duration(0), nil)
+	test.init()
+
+	_, err := test.m.Auth(getContext(), &models.Oauth2LoginSubmitForm{Challenge: "login_challenge", Email: "Email", Password: "1234"})
+	assert.Equal(t, apierror.AccountLocked.Code, err.(*apierror.APIError).Code)
+	assert.Equal(t, map[string]int{"retry_after": 60}, err.(*apierror.APIError).Data)
+	test.uis.AssertNotCalled(t, "Get", mock.Anything, mock.Anything)
+}
+
+func TestAuthReturnErrorForDelayedIdentity(t *testing.T) {
+	test := newTestOAuth2()
+	test.space.ID = "space"
+	test.space.LockoutSettings = entity.DefaultLockoutSettings
+	test.la.On("Blocked", identityLockKey("space", "email")).Return(time.Duration(0), 1500*time.Millisecond, nil)
+	test.init()
+
+	_, err := test.m.Auth(getContext(), &models.Oauth2LoginSubmitForm{Challenge: "login_challenge", Email: "email", Password: "1234"})
+	assert.Equal(t, apierror.TooManyLoginAttempts.Code, err.(*apierror.APIError).Code)
+	assert.Equal(t, map[string]int{"retry_after": 2}, err.(*apierror.APIError).Data)
+}
+
+func TestAuthReturnErrorForLockedIP(t *testing.T) {
+	test := newTestOAuth2()
+	test.space.ID = "space"
+	test.space.LockoutSettings = entity.DefaultLockoutSettings
+	test.la.On("Blocked", ipLockKey("192.0.2.1")).Return(time.Minute, time.Duration(0), nil)
+	test.init()
+
+	_, err := test.m.Auth(getContext(), &models.Oauth2LoginSubmitForm{Challenge: "login_challenge", Email: "email", Password: "1234"})
+	assert.Equal(t, apierror.TooManyLoginAttempts.Code, err.(*apierror.APIError).Code)
+}
+
+func TestAuthDelaysIdentityAfterFailedPassword(t *testing.T) {
+	test := newTestOAuth2()
+	test.space.ID = "space"
+	test.space.LockoutSettings = entity.LockoutSettings{MaxAttempts: 5, Window: 60, LockDuration: 60, Delay: 1, MaxDelay: 30}
+	test.uis.On("Get", mock.Anything, "email").Return(&models.UserIdentity{Credential: "1"}, nil)
+	test.la.On("Fail", identityLockKey("space", "email"), time.Minute).Return(3, nil)
+	test.init()
+
+	_, err := test.m.Auth(getContext(), &models.Oauth2LoginSubmitForm{Challenge: "login_challenge", Email: "email", Password: "1234"})
+	assert.Equal(t, apierror.InvalidCredentials, err)
+	test.la.AssertCalled(t, "Delay", identityLockKey("space", "email"), 4*time.Second)
+	test.la.AssertNotCalled(t, "Lock", mock.Anything, mock.Anything)
+}
+
+func TestAuthLocksIdentityOnMaxAttempts(t *testing.T) {
+	test := newTestOAuth2()
+	test.space.ID = "space"
+	test.space.LockoutSettings = entity.LockoutSettings{MaxAttempts: 5, IPMaxAttempts: 10, Window: 60, LockDuration: 300}
+	test.uis.On("Get", mock.Anything, "unknown").Return(nil, errors.New(""))
+	test.la.On("Fail", identityLockKey("space", "unknown"), time.Minute).Return(5, nil)
+	test.init()
+
+	_, err := test.m.Auth(getContext(), &models.Oauth2LoginSubmitForm{Challenge: "login_challenge", Email: "unknown", Password: "1234"})
+	assert.Equal(t, apierror.InvalidCredentials, err)
+	test.la.AssertCalled(t, "Lock", identityLockKey("space", "unknown"), 5*time.Minute)
+	test.la.AssertCalled(t, "Fail", ipLockKey("192.0.2.1"), time.Minute)
+	test.la.AssertNotCalled(t, "Lock", ipLockKey("192.0.2.1"), mock.Anything)
+}
+
+func TestAuthResetsLockoutOnSuccess(t *testing.T) {
+	test := newTestOAuth2()
+	test.space.ID = "space"
+	test.space.LockoutSettings = entity.DefaultLockoutSettings
+	be := models.NewBcryptEncryptor(&models.CryptConfig{Cost: 4})
+	hash, _ := be.Digest("1234")
+	test.uis.On("Get", mock.Anything, "email").Return(&models.UserIdentity{UserID: bson.NewObjectId(), Credential: hash}, nil)
+	test.init()
+
+	_, err := test.m.Auth(getContext(), &models.Oauth2LoginSubmitForm{Challenge: "login_challenge", Email: "email", Password: "1234"})
+	assert.Nil(t, err)
+	test.la.AssertCalled(t, "Reset", identityLockKey("space", "email"))
+	test.la.AssertNotCalled(t, "Fail", mock.Anything, mock.Anything)
+}
+
+func TestAuthRehashesPasswordWithOutdatedAlgorithm(t *testing.T) {
+	test := newTestOAuth2()
+	test.space.PasswordSettings.Algorithm = entity.PasswordAlgorithmArgon2id
+	test.space.PasswordSettings.Argon2Memory = 1024
+	test.space.PasswordSettings.Argon2Time = 1
+	test.space.PasswordSettings.Argon2Threads = 1
+	be := models.NewBcryptEncryptor(&models.CryptConfig{Cost: 4})
+	hash, _ := be.Digest("1234")
+	ui := &models.UserIdentity{ID: bson.NewObjectId(), UserID: bson.NewObjectId(), Credential: hash}
+	test.uis.On("Get", mock.Anything, "email").Return(ui, nil)
+	test.init()
+
+	_, err := test.m.Auth(getContext(), &models.Oauth2LoginSubmitForm{Challenge: "login_challenge", Email: "email", Password: "1234"})
+	assert.Nil(t, err)
+	test.uis.AssertCalled(t, "UpdateCredential", ui.ID, mock.MatchedBy(func(credential string) bool {
+		return hasher.Verify(credential, "1234") == nil && strings.HasPrefix(credential, "$argon2id$")
+	}))
+}
+
+func TestAuthKeepsPasswordWithActualAlgorithm(t *testing.T) {
+	test := newTestOAuth2()
+	be := models.NewBcryptEncryptor(&models.CryptConfig{Cost: 4})
+	hash, _ := be.Digest("1234")
+	test.uis.On("Get", mock.Anything, "email").Return(&models.UserIdentity{UserID: bson.NewObjectId(), Credential: hash}, nil)
+	test.init()
+
+	_, err := test.m.Auth(getContext(), &models.Oauth2LoginSubmitForm{Challenge: "login_challenge", Email: "email", Password: "1234"})
+	assert.Nil(t, err)
+	test.uis.AssertNotCalled(t, "UpdateCredential", mock.Anything, mock.Anything)
+}
+
 func TestAuthReturnErrorWithUnableToGetUser(t *testing.T) {
 	test := newTestOAuth2()
 	test.us.On("Get", mock.Anything).Return(nil, errors.New(""))
@@ -293,7 +575,7 @@ func TestAuthReturnErrorWithUnableToSetSessionRemember(t *testing.T) {
 	test.sess.On("Set", mock.Anything, loginRememberKey, true).Return(errors.New(""))
 	test.init()
 
-	_, err := test.m.Auth(getContext(), &models.Oauth2LoginSubmitForm{Challenge: "login_challenge", Remember: true, PreviousLogin: "subj"})
+	_, err := test.m.Auth(getContext(), &models.Oauth2LoginSubmitForm{Challenge: "login_challenge", Remember: true, PreviousLogin: testSubject})
 	assert.NotNil(t, err)
 	// assert.Equal(t, "common", err.Code)
 	// assert.Equal(t, models.ErrorUnknownError, err.Message)
@@ -304,7 +586,7 @@ func TestAuthReturnErrorWithUnableToAcceptLoginRequest(t *testing.T) {
 	test.h.On("AcceptLoginRequest", mock.Anything).Return(nil, errors.New(""))
 	test.init()
 
-	_, err := test.m.Auth(getContext(), &models.Oauth2LoginSubmitForm{Challenge: "login_challenge", Remember: true, PreviousLogin: "subj"})
+	_, err := test.m.Auth(getContext(), &models.Oauth2LoginSubmitForm{Challenge: "login_challenge", Remember: true, PreviousLogin: testSubject})
 	assert.NotNil(t, err)
 	// assert.Equal(t, "common", err.Code)
 	// assert.Equal(t, models.ErrorPasswordIncorrect, err.Message)
@@ -554,6 +836,18 @@ func TestSignUpReturnErrorWithInvalidPassword(t *testing.T) {
 	// assert.Equal(t, models.ErrorPasswordIncorrect, err.Message)
 }
 
+func TestSignUpReturnErrorWithBreachedPassword(t *testing.T) {
+	test := newTestOAuth2()
+	test.space.PasswordSettings.RejectBreached = true
+	// SHA-1 of "11"
+	corpus, _ := passwords.ReadCorpus(strings.NewReader("17BA0791499DB908433B80F37C5FBC89B870084B:10\n"))
+	test.r.On("BreachedPasswords").Return(corpus)
+	test.init()
+
+	_, err := test.m.SignUp(getContext(), &models.Oauth2SignUpForm{Remember: true, Password: "11", Challenge: "login_challenge", Email: "email"})
+	assert.Equal(t, apierror.WeakPassword.WithData(map[string]string{"reason": entity.PasswordBreached}), err)
+}
+
 func TestSignUpReturnErrorWithUnableToGetLoginChallenge(t *testing.T) {
 	test := newTestOAuth2()
 	test.h.On("GetLoginRequest", mock.Anything).Return(nil, errors.New(""))
@@ -0,0 +1,28 @@
+package manager
+
+import (
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/api/apierror"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/passwords"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/service"
+	"github.com/pkg/errors"
+)
+
+// checkPassword returns the WeakPassword error with the reason the new password is rejected by the space,
+// the history contains the hashes of the current and the previous passwords of the identity.
+func checkPassword(r service.InternalRegistry, space *entity.Space, password string, history []string) error {
+	var corpus passwords.Corpus
+	if space.PasswordSettings.RejectBreached {
+		corpus = r.BreachedPasswords()
+	}
+
+	reason, err := passwords.Check(space.PasswordSettings, corpus, password, history)
+	if err != nil {
+		return errors.Wrap(err, "unable to check password")
+	}
+	if reason != "" {
+		return apierror.WeakPassword.WithData(map[string]string{"reason": reason})
+	}
+
+	return nil
+}
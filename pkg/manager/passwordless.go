@@ -1,45 +1,289 @@
 package manager
 
 import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"io/ioutil"
+	"text/template"
+	"time"
+
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/api/apierror"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/config"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/database"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/helper"
 	"github.com/ProtocolONE/auth1.protocol.one/pkg/models"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/service"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/webhooks"
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/labstack/echo/v4"
+	"github.com/ory/hydra-client-go/client/admin"
+	models2 "github.com/ory/hydra-client-go/models"
 	"github.com/pkg/errors"
 )
 
+const (
+	passwordLessConnectionEmail = "email"
+	passwordLessCodeLength      = 6
+
+	// passwordLessEmailInterval is the minimal interval between the codes sent to the email, passwordLessIPInterval
+	// is the one of the codes requested from the IP address for any email.
+	passwordLessEmailInterval = time.Minute
+	passwordLessIPInterval    = 10 * time.Second
+)
+
+// PasswordLessManagerInterface describes of methods for the manager.
+type PasswordLessManagerInterface interface {
+	// PasswordLessStart creates a one-time token with a numeric verification code and sends the code
+	// and the magic link to the user's email.
+	//
+	// The returned token must be passed to PasswordLessVerify together with the code.
+	PasswordLessStart(echo.Context, *models.PasswordLessStartForm) (*models.OneTimeToken, error)
+
+	// PasswordLessVerify checks the verification code and accepts the login challenge.
+	//
+	// After successful verification, the URL for the redirect will be returned to pass the agreement consent process.
+	PasswordLessVerify(echo.Context, *models.PasswordLessVerifyForm) (string, error)
+}
+
+// PasswordLessManager is the passwordless login manager.
 type PasswordLessManager struct {
+	r                   service.InternalRegistry
+	userService         service.UserServiceInterface
+	userIdentityService service.UserIdentityServiceInterface
+	authLogService      service.AuthLogServiceInterface
+	mfaService          service.MfaServiceInterface
+	webAuthnService     service.WebAuthnServiceInterface
+	session             service.SessionService
+	TplCfg              *config.MailTemplates
 }
 
-func NewPasswordLessManager() *PasswordLessManager {
-	m := &PasswordLessManager{}
+// NewPasswordLessManager return new passwordless login manager.
+func NewPasswordLessManager(db database.MgoSession, r service.InternalRegistry, s *config.Session, tplCfg *config.MailTemplates) PasswordLessManagerInterface {
+	m := &PasswordLessManager{
+		r:                   r,
+		userService:         service.NewUserService(db),
+		userIdentityService: service.NewUserIdentityService(db),
+		authLogService:      service.NewAuthLogService(db, r.GeoIpService()),
+		mfaService:          service.NewMfaService(db),
+		webAuthnService:     service.NewWebAuthnService(db),
+		session:             service.NewSessionService(s.Name),
+		TplCfg:              tplCfg,
+	}
 
 	return m
 }
 
-func (m *PasswordLessManager) PasswordLessStart(form *models.PasswordLessStartForm) (ott *models.OneTimeToken, error *models.GeneralError) {
-	if form.ClientId == `incorrect` {
-		return nil, &models.GeneralError{Code: `client_id`, Message: models.ErrorClientIdIncorrect, Err: errors.New(models.ErrorClientIdIncorrect)}
+func (m *PasswordLessManager) PasswordLessStart(ctx echo.Context, form *models.PasswordLessStartForm) (*models.OneTimeToken, error) {
+	if form.Connection != passwordLessConnectionEmail {
+		return nil, apierror.InvalidConnection
+	}
+	if !bson.IsObjectIdHex(form.ClientId) {
+		return nil, apierror.InvalidClient
+	}
+
+	req, err := m.r.HydraAdminApi().GetLoginRequest(&admin.GetLoginRequestParams{Context: ctx.Request().Context(), LoginChallenge: form.Challenge})
+	if err != nil {
+		return nil, apierror.InvalidChallenge
+	}
+	if req.Payload.Client.ClientID != form.ClientId {
+		return nil, apierror.InvalidClient
+	}
+
+	app, err := m.r.ApplicationService().Get(bson.ObjectIdHex(form.ClientId))
+	if err != nil {
+		return nil, apierror.InvalidClient
+	}
+
+	space, err := m.r.Spaces().FindByID(context.TODO(), entity.SpaceID(app.SpaceId.Hex()))
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to load space")
+	}
+
+	ottSettings := &models.OneTimeTokenSettings{
+		Length: space.PasswordSettings.TokenLength,
+		TTL:    space.PasswordSettings.TokenTTL,
+	}
+
+	ok, err := m.allowStart(form.Email, service.GetClientIP(ctx))
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		// INFO: Do not need to disclose the throttling, the token is never stored so verification will fail
+		return &models.OneTimeToken{Token: helper.GetRandString(ottSettings.Length)}, nil
+	}
+
+	ui, err := m.userIdentityService.Get(models.OldIDProvider(space.DefaultIDProvider()), form.Email)
+	if err == mgo.ErrNotFound {
+		// INFO: Do not need to disclose the login, the token is never stored so verification will fail
+		return &models.OneTimeToken{Token: helper.GetRandString(ottSettings.Length)}, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to get user identity by email")
+	}
+
+	code, err := helper.GetRandDigits(passwordLessCodeLength)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to generate verification code")
+	}
+
+	token, err := m.r.OneTimeTokenService().Create(&models.PasswordLessTokenSource{
+		Email:     form.Email,
+		ClientID:  form.ClientId,
+		Challenge: form.Challenge,
+		Subject:   ui.UserID.Hex(),
+		Code:      code,
+	}, ottSettings)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to create one-time token")
+	}
+
+	b, err := ioutil.ReadFile(m.TplCfg.PasswordLessTpl)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read passwordless mail template")
+	}
+	tmpl, err := template.New("mail").Parse(string(b))
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to parse passwordless mail template")
 	}
-	if form.Connection == `incorrect` {
-		return nil, &models.GeneralError{Code: `connection`, Message: models.ErrorConnectionIncorrect, Err: errors.New(models.ErrorConnectionIncorrect)}
+	w := bytes.Buffer{}
+	err = tmpl.Execute(&w, struct {
+		UserName         string
+		PlatformName     string
+		Code             string
+		LoginLink        string
+		SupportPortalUrl string
+	}{
+		UserName:         ui.Username,
+		PlatformName:     m.TplCfg.PlatformName,
+		Code:             code,
+		LoginLink:        fmt.Sprintf("%s/sign-in/passwordless?login_challenge=%s&client_id=%s&token=%s&verification_code=%s", m.TplCfg.PlatformUrl, form.Challenge, form.ClientId, token.Token, code),
+		SupportPortalUrl: m.TplCfg.SupportPortalUrl,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to build passwordless mail")
 	}
 
-	return &models.OneTimeToken{
-		Token: `onetimetoken`,
-	}, nil
+	if err := m.r.Mailer().Send(form.Email, "Login code", w.String()); err != nil {
+		return nil, errors.Wrap(err, "unable to send mail with passwordless code")
+	}
+
+	return token, nil
+}
+
+// allowStart limits the codes sent to the email and requested from the IP address, so any mailbox can't be flooded
+// with messages.
+func (m *PasswordLessManager) allowStart(email, ip string) (bool, error) {
+	limits := []struct {
+		key      string
+		interval time.Duration
+	}{
+		{"passwordless_ip_" + ip, passwordLessIPInterval},
+		{"passwordless_email_" + email, passwordLessEmailInterval},
+	}
+	for _, l := range limits {
+		ok, err := m.r.RateLimiter().Allow(l.key, l.interval)
+		if err != nil {
+			return false, errors.Wrap(err, "unable to check the resend interval")
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+	return true, nil
 }
 
-func (m *PasswordLessManager) PasswordLessVerify(form *models.PasswordLessVerifyForm) (error *models.GeneralError) {
-	if form.ClientId == `incorrect` {
-		return &models.GeneralError{Code: `client_id`, Message: models.ErrorClientIdIncorrect, Err: errors.New(models.ErrorClientIdIncorrect)}
+func (m *PasswordLessManager) PasswordLessVerify(ctx echo.Context, form *models.PasswordLessVerifyForm) (string, error) {
+	if form.Connection != passwordLessConnectionEmail {
+		return "", apierror.InvalidConnection
+	}
+
+	// INFO: The token is removed atomically on any attempt and the code is compared only by the request that removed it,
+	// so the code can't be brute forced even by the concurrent requests
+	ts := &models.PasswordLessTokenSource{}
+	if err := m.r.OneTimeTokenService().Use(form.Token, ts); err != nil {
+		return "", apierror.InvalidToken
 	}
-	if form.Connection == `incorrect` {
-		return &models.GeneralError{Code: `connection`, Message: models.ErrorConnectionIncorrect, Err: errors.New(models.ErrorClientIdIncorrect)}
+
+	if ts.ClientID != form.ClientId {
+		return "", apierror.InvalidClient
+	}
+
+	if subtle.ConstantTimeCompare([]byte(ts.Code), []byte(form.Code)) != 1 {
+		return "", apierror.InvalidCode
+	}
+
+	app, err := m.r.ApplicationService().Get(bson.ObjectIdHex(ts.ClientID))
+	if err != nil {
+		return "", apierror.InvalidClient
 	}
-	if form.Code == `incorrect` {
-		return &models.GeneralError{Code: `verification_code`, Message: `Verification code is incorrect`, Err: errors.New("Verification code is incorrect")}
+
+	space, err := m.r.Spaces().FindByID(context.TODO(), entity.SpaceID(app.SpaceId.Hex()))
+	if err != nil {
+		return "", errors.Wrap(err, "unable to load space")
 	}
-	if form.Token == `incorrect` {
-		return &models.GeneralError{Code: `token`, Message: `Token is incorrect`, Err: errors.New("Token is incorrect")}
+
+	ipc := space.DefaultIDProvider()
+	userIdentity, err := m.userIdentityService.Get(models.OldIDProvider(ipc), ts.Email)
+	if err != nil {
+		return "", errors.Wrap(err, "unable to get user identity")
 	}
 
-	return nil
+	user, err := m.userService.Get(userIdentity.UserID)
+	if err != nil {
+		return "", errors.Wrap(err, "unable to get user")
+	}
+	if user.IsBlocked(time.Now()) {
+		return "", apierror.UserBlocked
+	}
+	if space.RequiresVerifiedEmail && !user.EmailVerified {
+		return "", apierror.EmailNotVerified
+	}
+
+	user.LoginsCount = user.LoginsCount + 1
+	user.AddDeviceID(service.GetDeviceID(ctx))
+
+	if err := m.userService.Update(user); err != nil {
+		return "", errors.Wrap(err, "unable to update user")
+	}
+
+	if err := m.authLogService.Add(ctx, service.ActionAuth, userIdentity, app, &ipc); err != nil {
+		return "", errors.Wrap(err, "unable to add auth log")
+	}
+
+	if err := m.session.Set(ctx, loginRememberKey, form.Remember); err != nil {
+		return "", errors.Wrap(err, "error saving session")
+	}
+
+	// INFO: The login challenge is accepted by MFAVerify if the user has to pass the second factor
+	amr := []string{amrOtp}
+	err = requireMfa(m.r, m.mfaService, m.webAuthnService, space, user, &models.UserMfaToken{
+		UserIdentity: userIdentity,
+		Challenge:    ts.Challenge,
+		Remember:     form.Remember,
+		Amr:          amr,
+		ClientID:     app.ID.Hex(),
+		Provider:     ipc.Name,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	userId := user.ID.Hex()
+	reqACL, err := m.r.HydraAdminApi().AcceptLoginRequest(&admin.AcceptLoginRequestParams{
+		Context:        ctx.Request().Context(),
+		LoginChallenge: ts.Challenge,
+		Body:           &models2.AcceptLoginRequest{Subject: &userId, Remember: form.Remember, RememberFor: RememberTime, Context: loginContext(amr)},
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "unable to accept login challenge")
+	}
+
+	publish(ctx.Request().Context(), m.r, app, user.ID, webhooks.UserLoginEvent{Provider: ipc.Name, Amr: amr})
+
+	return reqACL.Payload.RedirectTo, nil
 }
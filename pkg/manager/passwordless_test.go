@@ -0,0 +1,240 @@
+package manager
+
+import (
+	"testing"
+
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/repository"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/repository/webhook_delivery/memory"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/api/apierror"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/config"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/mocks"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/models"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/webhooks"
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/ory/hydra-client-go/client/admin"
+	models2 "github.com/ory/hydra-client-go/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type passwordLessTest struct {
+	app    *mocks.ApplicationServiceInterface
+	h      *mocks.HydraAdminApi
+	sess   *mocks.SessionService
+	uis    *mocks.UserIdentityServiceInterface
+	us     *mocks.UserServiceInterface
+	ott    *mocks.OneTimeTokenServiceInterface
+	al     *mocks.AuthLogServiceInterface
+	mfa    *mocks.MfaServiceInterface
+	wa     *mocks.WebAuthnServiceInterface
+	rl     *mocks.RateLimiterInterface
+	mailer *mocks.MailerInterface
+	r      *mocks.InternalRegistry
+	m      *PasswordLessManager
+
+	deliveries *memory.WebhookDeliveryRepository
+
+	clientID string
+	space    *entity.Space
+}
+
+func newPasswordLessTest() *passwordLessTest {
+	r, deliveries := mockIntRegistryWithWebHooks()
+	return &passwordLessTest{
+		app:    &mocks.ApplicationServiceInterface{},
+		h:      &mocks.HydraAdminApi{},
+		sess:   &mocks.SessionService{},
+		uis:    &mocks.UserIdentityServiceInterface{},
+		us:     &mocks.UserServiceInterface{},
+		ott:    &mocks.OneTimeTokenServiceInterface{},
+		al:     &mocks.AuthLogServiceInterface{},
+		mfa:    &mocks.MfaServiceInterface{},
+		wa:     &mocks.WebAuthnServiceInterface{},
+		rl:     &mocks.RateLimiterInterface{},
+		mailer: &mocks.MailerInterface{},
+		r:      r,
+
+		deliveries: deliveries,
+		clientID:   bson.NewObjectId().Hex(),
+		space: &entity.Space{
+			PasswordSettings: entity.PasswordSettings{TokenLength: 16, TokenTTL: 60},
+			IdentityProviders: entity.IdentityProviders{{
+				ID:          entity.IdentityProviderID(bson.NewObjectId().Hex()),
+				Type:        entity.IDProviderTypePassword,
+				Name:        entity.IDProviderNameDefault,
+				DisplayName: "Initial connection",
+			}},
+		},
+	}
+}
+
+func (test *passwordLessTest) init() {
+	test.app.On("Get", mock.Anything).Return(&models.Application{WebHooks: []string{"http://localhost/hook"}}, nil)
+
+	test.h.On("GetLoginRequest", mock.Anything).Return(&admin.GetLoginRequestOK{Payload: &models2.LoginRequest{
+		Client: &models2.OAuth2Client{ClientID: test.clientID},
+	}}, nil)
+	test.h.On("AcceptLoginRequest", mock.Anything).Return(&admin.AcceptLoginRequestOK{Payload: &models2.CompletedRequest{RedirectTo: "url"}}, nil)
+
+	test.sess.On("Set", mock.Anything, loginRememberKey, mock.Anything).Return(nil)
+
+	test.uis.On("Get", mock.Anything, "email").Return(&models.UserIdentity{ID: bson.NewObjectId(), UserID: bson.NewObjectId()}, nil)
+	test.us.On("Get", mock.Anything).Return(&models.User{ID: bson.NewObjectId()}, nil)
+	test.us.On("Update", mock.Anything).Return(nil)
+	test.al.On("Add", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	test.mfa.On("GetUserProviders", mock.Anything).Return(nil, nil)
+	test.wa.On("GetUserCredentials", mock.Anything).Return(nil, nil)
+
+	test.ott.On("Create", mock.Anything, mock.Anything).Return(&models.OneTimeToken{Token: "token"}, nil)
+	test.ott.On("Use", "token", mock.MatchedBy(
+		func(ts *models.PasswordLessTokenSource) bool {
+			ts.ClientID = test.clientID
+			ts.Email = "email"
+			ts.Challenge = "login_challenge"
+			ts.Code = "123456"
+			return true
+		})).Return(nil)
+	test.ott.On("Use", mock.Anything, mock.Anything).Return(mgo.ErrNotFound)
+
+	test.rl.On("Allow", mock.Anything, mock.Anything).Return(true, nil)
+	test.mailer.On("Send", "email", mock.Anything, mock.Anything).Return(nil)
+
+	test.r.On("ApplicationService").Return(test.app)
+	test.r.On("HydraAdminApi").Return(test.h)
+	test.r.On("OneTimeTokenService").Return(test.ott)
+	test.r.On("RateLimiter").Return(test.rl)
+	test.r.On("Mailer").Return(test.mailer)
+	test.r.On("Spaces").Return(repository.OneSpaceRepo(test.space))
+
+	test.m = &PasswordLessManager{
+		r:                   test.r,
+		session:             test.sess,
+		userService:         test.us,
+		userIdentityService: test.uis,
+		authLogService:      test.al,
+		mfaService:          test.mfa,
+		webAuthnService:     test.wa,
+		TplCfg: &config.MailTemplates{
+			PasswordLessTpl: "../../public/templates/email/passwordless.html",
+		},
+	}
+}
+
+func (test *passwordLessTest) startForm() *models.PasswordLessStartForm {
+	return &models.PasswordLessStartForm{ClientId: test.clientID, Connection: "email", Challenge: "login_challenge", Email: "email"}
+}
+
+func (test *passwordLessTest) verifyForm(code string) *models.PasswordLessVerifyForm {
+	return &models.PasswordLessVerifyForm{ClientId: test.clientID, Connection: "email", Token: "token", Code: code}
+}
+
+func TestPasswordLessStartReturnTokenOnSuccessResult(t *testing.T) {
+	test := newPasswordLessTest()
+	test.init()
+
+	token, err := test.m.PasswordLessStart(getContext(), test.startForm())
+	assert.Nil(t, err)
+	assert.Equal(t, "token", token.Token)
+	test.mailer.AssertNumberOfCalls(t, "Send", 1)
+}
+
+func TestPasswordLessStartDoesNotSendMailIfUserNotFound(t *testing.T) {
+	test := newPasswordLessTest()
+	test.uis.On("Get", mock.Anything, "email").Return(nil, mgo.ErrNotFound)
+	test.init()
+
+	token, err := test.m.PasswordLessStart(getContext(), test.startForm())
+	assert.Nil(t, err)
+	assert.Len(t, token.Token, test.space.PasswordSettings.TokenLength)
+	test.mailer.AssertNotCalled(t, "Send", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestPasswordLessStartDoesNotSendMailIfThrottled(t *testing.T) {
+	test := newPasswordLessTest()
+	test.rl.On("Allow", "passwordless_email_email", passwordLessEmailInterval).Return(false, nil)
+	test.init()
+
+	token, err := test.m.PasswordLessStart(getContext(), test.startForm())
+	assert.Nil(t, err)
+	assert.Len(t, token.Token, test.space.PasswordSettings.TokenLength)
+	test.ott.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	test.mailer.AssertNotCalled(t, "Send", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestPasswordLessStartReturnErrorWithIncorrectConnection(t *testing.T) {
+	test := newPasswordLessTest()
+	test.init()
+
+	form := test.startForm()
+	form.Connection = "sms"
+	_, err := test.m.PasswordLessStart(getContext(), form)
+	assert.Equal(t, apierror.InvalidConnection, err)
+}
+
+func TestPasswordLessStartReturnErrorWithForeignChallenge(t *testing.T) {
+	test := newPasswordLessTest()
+	test.init()
+
+	form := test.startForm()
+	form.ClientId = bson.NewObjectId().Hex()
+	_, err := test.m.PasswordLessStart(getContext(), form)
+	assert.Equal(t, apierror.InvalidClient, err)
+}
+
+func TestPasswordLessVerifyReturnUrlOnSuccessResult(t *testing.T) {
+	test := newPasswordLessTest()
+	test.init()
+
+	url, err := test.m.PasswordLessVerify(getContext(), test.verifyForm("123456"))
+	assert.Nil(t, err)
+	assert.Equal(t, "url", url)
+	assert.Equal(t, []string{webhooks.UserLoginAction}, publishedActions(t, test.deliveries))
+}
+
+func TestPasswordLessVerifyReturnMfaRequiredIfUserHasPasskeys(t *testing.T) {
+	test := newPasswordLessTest()
+	test.wa.On("GetUserCredentials", mock.Anything).Return([]*models.WebAuthnCredential{{ID: bson.NewObjectId()}}, nil)
+	test.init()
+
+	_, err := test.m.PasswordLessVerify(getContext(), test.verifyForm("123456"))
+	if assert.IsType(t, &apierror.APIError{}, err) {
+		assert.Equal(t, apierror.MfaRequired.Code, err.(*apierror.APIError).Code)
+		assert.True(t, err.(*apierror.APIError).Data.(*models.MfaRequiredResponse).WebAuthn)
+	}
+	test.ott.AssertCalled(t, "Create", mock.MatchedBy(func(t *models.UserMfaToken) bool {
+		return t.Challenge == "login_challenge" && len(t.Amr) == 1 && t.Amr[0] == amrOtp
+	}), mock.Anything)
+	test.h.AssertNotCalled(t, "AcceptLoginRequest", mock.Anything)
+	assert.Empty(t, publishedActions(t, test.deliveries))
+}
+
+func TestPasswordLessVerifyReturnErrorIfSpaceRequiresVerifiedEmail(t *testing.T) {
+	test := newPasswordLessTest()
+	test.space.RequiresVerifiedEmail = true
+	test.init()
+
+	_, err := test.m.PasswordLessVerify(getContext(), test.verifyForm("123456"))
+	assert.Equal(t, apierror.EmailNotVerified, err)
+	test.h.AssertNotCalled(t, "AcceptLoginRequest", mock.Anything)
+}
+
+func TestPasswordLessVerifyReturnErrorWithIncorrectCode(t *testing.T) {
+	test := newPasswordLessTest()
+	test.init()
+
+	_, err := test.m.PasswordLessVerify(getContext(), test.verifyForm("654321"))
+	assert.Equal(t, apierror.InvalidCode, err)
+	test.h.AssertNotCalled(t, "AcceptLoginRequest", mock.Anything)
+}
+
+func TestPasswordLessVerifyReturnErrorWithUnknownToken(t *testing.T) {
+	test := newPasswordLessTest()
+	test.init()
+
+	form := test.verifyForm("123456")
+	form.Token = "unknown"
+	_, err := test.m.PasswordLessVerify(getContext(), form)
+	assert.Equal(t, apierror.InvalidToken, err)
+}
@@ -0,0 +1,391 @@
+package manager
+
+import (
+	"context"
+	"time"
+
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/api/apierror"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/config"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/database"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/models"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/service"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/webauthn"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/webhooks"
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/labstack/echo/v4"
+	"github.com/ory/hydra-client-go/client/admin"
+	models2 "github.com/ory/hydra-client-go/models"
+	"github.com/pkg/errors"
+)
+
+// Authentication method reference of the proof-of-possession of a hardware-secured key, see RFC 8176.
+const amrHwk = "hwk"
+
+// WebAuthnManagerInterface describes of methods for the manager.
+type WebAuthnManagerInterface interface {
+	// RegisterBegin returns the options for navigator.credentials.create() and the ceremony token.
+	//
+	// The user is identified by the bearer token or, if the space requires MFA, by the mfa token
+	// issued by the login process for the user without second factors.
+	RegisterBegin(echo.Context, *models.WebAuthnRegisterBeginForm) (*models.WebAuthnBeginResponse, error)
+
+	// RegisterFinish verifies the created credential and saves it for the user.
+	RegisterFinish(echo.Context, *models.WebAuthnRegisterFinishForm) (*models.WebAuthnCredential, error)
+
+	// LoginBegin returns the options for navigator.credentials.get() and the ceremony token.
+	//
+	// With the login challenge the passkey is used as the first factor, any discoverable credential
+	// of the relying party is accepted. With the mfa token it is used as the second factor and only
+	// the credentials of the user are allowed.
+	LoginBegin(echo.Context, *models.WebAuthnLoginBeginForm) (*models.WebAuthnBeginResponse, error)
+
+	// LoginFinish verifies the assertion and accepts the login challenge.
+	//
+	// After successful verification, the URL for the redirect will be returned to pass the agreement consent process.
+	LoginFinish(echo.Context, *models.WebAuthnLoginFinishForm) (string, error)
+}
+
+// WebAuthnManager is the WebAuthn (passkey) manager.
+type WebAuthnManager struct {
+	r               service.InternalRegistry
+	rp              *webauthn.RelyingParty
+	userService     service.UserServiceInterface
+	mfaService      service.MfaServiceInterface
+	webAuthnService service.WebAuthnServiceInterface
+	authLogService  service.AuthLogServiceInterface
+	session         service.SessionService
+}
+
+// NewWebAuthnManager return new WebAuthn manager.
+func NewWebAuthnManager(db database.MgoSession, r service.InternalRegistry, s *config.Session, rp *webauthn.RelyingParty) WebAuthnManagerInterface {
+	m := &WebAuthnManager{
+		r:               r,
+		rp:              rp,
+		userService:     service.NewUserService(db),
+		mfaService:      service.NewMfaService(db),
+		webAuthnService: service.NewWebAuthnService(db),
+		authLogService:  service.NewAuthLogService(db, r.GeoIpService()),
+		session:         service.NewSessionService(s.Name),
+	}
+
+	return m
+}
+
+func (m *WebAuthnManager) RegisterBegin(ctx echo.Context, form *models.WebAuthnRegisterBeginForm) (*models.WebAuthnBeginResponse, error) {
+	app, space, err := m.loadApp(form.ClientId)
+	if err != nil {
+		return nil, err
+	}
+
+	var userID bson.ObjectId
+	if form.Token != "" {
+		mp := &models.UserMfaToken{}
+		if err := m.r.OneTimeTokenService().Get(form.Token, mp); err != nil || mp.Challenge == "" {
+			return nil, apierror.InvalidToken
+		}
+
+		// INFO: The login token can't be used to add a credential bypassing the existing second factors
+		hasFactors, err := m.hasSecondFactors(mp.UserIdentity.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if hasFactors {
+			return nil, apierror.InvalidToken
+		}
+
+		userID = mp.UserIdentity.UserID
+	} else {
+		c, err := service.AuthenticateBearer(ctx.Request().Context(), m.r.HydraAdminApi(), ctx.Request().Header)
+		if err != nil || c.AppId != app.ID {
+			return nil, apierror.Unauthorized
+		}
+
+		userID = c.UserId
+	}
+
+	user, err := m.userService.Get(userID)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to get user")
+	}
+
+	credentials, err := m.webAuthnService.GetUserCredentials(user.ID)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to get user credentials")
+	}
+	exclude := make([][]byte, len(credentials))
+	for i, c := range credentials {
+		exclude[i] = c.CredentialID
+	}
+
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := m.r.OneTimeTokenService().Create(&models.WebAuthnSession{
+		Challenge:    challenge,
+		Registration: true,
+		ClientID:     form.ClientId,
+		UserID:       user.ID,
+		MfaToken:     form.Token,
+	}, &models.OneTimeTokenSettings{
+		Length: space.PasswordSettings.TokenLength,
+		TTL:    space.PasswordSettings.TokenTTL,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to create one-time token")
+	}
+
+	displayName := user.Username
+	if displayName == "" {
+		displayName = user.Email
+	}
+
+	return &models.WebAuthnBeginResponse{
+		Token:     token.Token,
+		PublicKey: m.rp.CreationOptions(challenge, []byte(user.ID.Hex()), user.Email, displayName, exclude),
+	}, nil
+}
+
+func (m *WebAuthnManager) RegisterFinish(ctx echo.Context, form *models.WebAuthnRegisterFinishForm) (*models.WebAuthnCredential, error) {
+	ws := &models.WebAuthnSession{}
+	if err := m.r.OneTimeTokenService().Use(form.Token, ws); err != nil || !ws.Registration {
+		return nil, apierror.InvalidToken
+	}
+	if ws.ClientID != form.ClientId {
+		return nil, apierror.InvalidClient
+	}
+
+	cred, err := m.rp.VerifyAttestation(ws.Challenge, form.Credential)
+	if err != nil {
+		return nil, apierror.InvalidWebAuthnCredential
+	}
+
+	_, err = m.webAuthnService.GetByCredentialID(cred.ID)
+	if err == nil {
+		return nil, apierror.InvalidWebAuthnCredential
+	}
+	if err != mgo.ErrNotFound {
+		return nil, errors.Wrap(err, "unable to get credential")
+	}
+
+	now := time.Now()
+	c := &models.WebAuthnCredential{
+		ID:           bson.NewObjectId(),
+		UserID:       ws.UserID,
+		CredentialID: cred.ID,
+		PublicKey:    cred.PublicKey,
+		SignCount:    int64(cred.SignCount),
+		AAGUID:       cred.AAGUID,
+		Name:         form.Name,
+		CreatedAt:    now,
+		LastUsedAt:   now,
+	}
+	if err := m.webAuthnService.Add(c); err != nil {
+		return nil, errors.Wrap(err, "unable to add credential")
+	}
+
+	return c, nil
+}
+
+func (m *WebAuthnManager) LoginBegin(ctx echo.Context, form *models.WebAuthnLoginBeginForm) (*models.WebAuthnBeginResponse, error) {
+	_, space, err := m.loadApp(form.ClientId)
+	if err != nil {
+		return nil, err
+	}
+
+	ws := &models.WebAuthnSession{ClientID: form.ClientId}
+	userVerification := webauthn.UserVerificationRequired
+	var allow [][]byte
+
+	if form.Token != "" {
+		mp := &models.UserMfaToken{}
+		if err := m.r.OneTimeTokenService().Get(form.Token, mp); err != nil {
+			return nil, apierror.InvalidToken
+		}
+
+		credentials, err := m.webAuthnService.GetUserCredentials(mp.UserIdentity.UserID)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to get user credentials")
+		}
+		if len(credentials) == 0 {
+			return nil, apierror.InvalidWebAuthnCredential
+		}
+		for _, c := range credentials {
+			allow = append(allow, c.CredentialID)
+		}
+
+		ws.UserID = mp.UserIdentity.UserID
+		ws.MfaToken = form.Token
+		userVerification = webauthn.UserVerificationPreferred
+	} else {
+		req, err := m.r.HydraAdminApi().GetLoginRequest(&admin.GetLoginRequestParams{Context: ctx.Request().Context(), LoginChallenge: form.Challenge})
+		if err != nil {
+			return nil, apierror.InvalidChallenge
+		}
+		if req.Payload.Client.ClientID != form.ClientId {
+			return nil, apierror.InvalidClient
+		}
+
+		ws.LoginChallenge = form.Challenge
+	}
+
+	if ws.Challenge, err = webauthn.NewChallenge(); err != nil {
+		return nil, err
+	}
+
+	token, err := m.r.OneTimeTokenService().Create(ws, &models.OneTimeTokenSettings{
+		Length: space.PasswordSettings.TokenLength,
+		TTL:    space.PasswordSettings.TokenTTL,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to create one-time token")
+	}
+
+	return &models.WebAuthnBeginResponse{
+		Token:     token.Token,
+		PublicKey: m.rp.RequestOptions(ws.Challenge, allow, userVerification),
+	}, nil
+}
+
+func (m *WebAuthnManager) LoginFinish(ctx echo.Context, form *models.WebAuthnLoginFinishForm) (string, error) {
+	ws := &models.WebAuthnSession{}
+	if err := m.r.OneTimeTokenService().Use(form.Token, ws); err != nil || ws.Registration {
+		return "", apierror.InvalidToken
+	}
+	if ws.ClientID != form.ClientId {
+		return "", apierror.InvalidClient
+	}
+
+	credentialID, err := form.Credential.CredentialID()
+	if err != nil {
+		return "", apierror.InvalidWebAuthnCredential
+	}
+	stored, err := m.webAuthnService.GetByCredentialID(credentialID)
+	if err != nil {
+		return "", apierror.InvalidWebAuthnCredential
+	}
+	if ws.UserID != "" && stored.UserID != ws.UserID {
+		return "", apierror.InvalidWebAuthnCredential
+	}
+	if ws.UserID == "" {
+		// INFO: The discoverable credential returns the user id given on the registration
+		handle, err := form.Credential.UserHandle()
+		if err != nil || (len(handle) > 0 && string(handle) != stored.UserID.Hex()) {
+			return "", apierror.InvalidWebAuthnCredential
+		}
+	}
+
+	// INFO: The first factor must verify the user, so the passkey alone satisfies MFA
+	assertion, err := m.rp.VerifyAssertion(ws.Challenge, form.Credential, stored.Credential(), ws.MfaToken == "")
+	if err != nil {
+		return "", apierror.InvalidWebAuthnCredential
+	}
+
+	if err := m.webAuthnService.UpdateSignCount(stored.ID, int64(assertion.SignCount)); err != nil {
+		return "", errors.Wrap(err, "unable to update credential")
+	}
+
+	user, err := m.userService.Get(stored.UserID)
+	if err != nil {
+		return "", errors.Wrap(err, "unable to get user")
+	}
+	if user.IsBlocked(time.Now()) {
+		return "", apierror.UserBlocked
+	}
+
+	loginChallenge, remember := ws.LoginChallenge, form.Remember
+	var amr []string
+	var provider string
+
+	if ws.MfaToken != "" {
+		mp := &models.UserMfaToken{}
+		if err := m.r.OneTimeTokenService().Use(ws.MfaToken, mp); err != nil || mp.UserIdentity.UserID != user.ID {
+			return "", apierror.InvalidToken
+		}
+		if mp.Challenge == "" {
+			return "", nil
+		}
+
+		loginChallenge, remember, provider = mp.Challenge, mp.Remember, mp.Provider
+		amr = append(mp.Amr, amrHwk, amrMfa)
+	} else {
+		app, space, err := m.loadApp(ws.ClientID)
+		if err != nil {
+			return "", err
+		}
+		if user.SpaceID != "" && user.SpaceID != app.SpaceId {
+			return "", apierror.InvalidWebAuthnCredential
+		}
+		if space.RequiresVerifiedEmail && !user.EmailVerified {
+			return "", apierror.EmailNotVerified
+		}
+
+		user.LoginsCount = user.LoginsCount + 1
+		user.AddDeviceID(service.GetDeviceID(ctx))
+		if err := m.userService.Update(user); err != nil {
+			return "", errors.Wrap(err, "unable to update user")
+		}
+
+		if err := m.authLogService.Add(ctx, service.ActionAuth, &models.UserIdentity{UserID: user.ID}, app, nil); err != nil {
+			return "", errors.Wrap(err, "unable to add auth log")
+		}
+
+		if err := m.session.Set(ctx, loginRememberKey, form.Remember); err != nil {
+			return "", errors.Wrap(err, "error saving session")
+		}
+
+		amr = []string{amrHwk}
+		if assertion.UserVerified {
+			amr = append(amr, amrMfa)
+		}
+	}
+
+	userId := user.ID.Hex()
+	reqACL, err := m.r.HydraAdminApi().AcceptLoginRequest(&admin.AcceptLoginRequestParams{
+		Context:        ctx.Request().Context(),
+		LoginChallenge: loginChallenge,
+		Body:           &models2.AcceptLoginRequest{Subject: &userId, Remember: remember, RememberFor: RememberTime, Context: loginContext(amr)},
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "unable to accept login challenge")
+	}
+
+	publishClient(ctx.Request().Context(), m.r, ws.ClientID, user.ID, webhooks.UserLoginEvent{Provider: provider, Amr: amr})
+
+	return reqACL.Payload.RedirectTo, nil
+}
+
+func (m *WebAuthnManager) loadApp(clientID string) (*models.Application, *entity.Space, error) {
+	if !bson.IsObjectIdHex(clientID) {
+		return nil, nil, apierror.InvalidClient
+	}
+
+	app, err := m.r.ApplicationService().Get(bson.ObjectIdHex(clientID))
+	if err != nil {
+		return nil, nil, apierror.InvalidClient
+	}
+
+	space, err := m.r.Spaces().FindByID(context.TODO(), entity.SpaceID(app.SpaceId.Hex()))
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "unable to load space")
+	}
+
+	return app, space, nil
+}
+
+func (m *WebAuthnManager) hasSecondFactors(userID bson.ObjectId) (bool, error) {
+	providers, err := m.mfaService.GetUserProviders(&models.User{ID: userID})
+	if err != nil {
+		return false, errors.Wrap(err, "unable to get user mfa providers")
+	}
+
+	credentials, err := m.webAuthnService.GetUserCredentials(userID)
+	if err != nil {
+		return false, errors.Wrap(err, "unable to get user credentials")
+	}
+
+	return len(providers) > 0 || len(credentials) > 0, nil
+}
@@ -0,0 +1,215 @@
+package manager
+
+import (
+	"testing"
+
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/repository"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/api/apierror"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/mocks"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/models"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/webauthn"
+	"github.com/globalsign/mgo/bson"
+	"github.com/labstack/echo/v4"
+	"github.com/ory/hydra-client-go/client/admin"
+	models2 "github.com/ory/hydra-client-go/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type webAuthnTest struct {
+	ott *mocks.OneTimeTokenServiceInterface
+	us  *mocks.UserServiceInterface
+	mfa *mocks.MfaServiceInterface
+	wa  *mocks.WebAuthnServiceInterface
+	h   *mocks.HydraAdminApi
+	r   *mocks.InternalRegistry
+	m   *WebAuthnManager
+
+	clientID string
+	user     *models.User
+}
+
+func newWebAuthnTest() *webAuthnTest {
+	test := &webAuthnTest{
+		ott:      &mocks.OneTimeTokenServiceInterface{},
+		us:       &mocks.UserServiceInterface{},
+		mfa:      &mocks.MfaServiceInterface{},
+		wa:       &mocks.WebAuthnServiceInterface{},
+		h:        &mocks.HydraAdminApi{},
+		r:        mockIntRegistry(),
+		clientID: bson.NewObjectId().Hex(),
+		user:     &models.User{ID: bson.NewObjectId(), Email: "test@example.com"},
+	}
+
+	app := &mocks.ApplicationServiceInterface{}
+	app.On("Get", mock.Anything).Return(&models.Application{ID: bson.ObjectIdHex(test.clientID), SpaceId: bson.NewObjectId()}, nil)
+
+	test.us.On("Get", test.user.ID).Return(test.user, nil)
+	test.ott.On("Create", mock.Anything, mock.Anything).Return(&models.OneTimeToken{Token: "webauthn_token"}, nil)
+
+	test.r.On("OneTimeTokenService").Return(test.ott)
+	test.r.On("ApplicationService").Return(app)
+	test.r.On("HydraAdminApi").Return(test.h)
+	test.r.On("Spaces").Return(repository.OneSpaceRepo(&entity.Space{}))
+
+	test.m = &WebAuthnManager{
+		r:               test.r,
+		rp:              webauthn.NewRelyingParty("localhost", "Auth1", []string{"http://localhost"}),
+		userService:     test.us,
+		mfaService:      test.mfa,
+		webAuthnService: test.wa,
+	}
+
+	return test
+}
+
+func (test *webAuthnTest) mfaToken(challenge string) {
+	test.ott.On("Get", "mfa_token", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		arg := args.Get(1).(*models.UserMfaToken)
+		arg.UserIdentity = &models.UserIdentity{UserID: test.user.ID}
+		arg.Challenge = challenge
+	})
+}
+
+func (test *webAuthnTest) session(ws *models.WebAuthnSession) {
+	test.ott.On("Use", "webauthn_token", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		*args.Get(1).(*models.WebAuthnSession) = *ws
+	})
+}
+
+func TestWebAuthnRegisterBeginReturnCreationOptions(t *testing.T) {
+	test := newWebAuthnTest()
+	test.mfaToken("login_challenge")
+	test.mfa.On("GetUserProviders", mock.Anything).Return(nil, nil)
+	test.wa.On("GetUserCredentials", test.user.ID).Return(nil, nil)
+
+	resp, err := test.m.RegisterBegin(getContext(), &models.WebAuthnRegisterBeginForm{ClientId: test.clientID, Token: "mfa_token"})
+	assert.Nil(t, err)
+	if assert.NotNil(t, resp) {
+		assert.Equal(t, "webauthn_token", resp.Token)
+		options := resp.PublicKey.(*webauthn.CreationOptions)
+		assert.Equal(t, webauthn.EncodeBase64([]byte(test.user.ID.Hex())), options.User.ID)
+	}
+	test.ott.AssertCalled(t, "Create", mock.MatchedBy(func(ws *models.WebAuthnSession) bool {
+		return ws.Registration && ws.UserID == test.user.ID && len(ws.Challenge) > 0
+	}), mock.Anything)
+}
+
+func TestWebAuthnRegisterBeginReturnErrorWithLoginTokenIfUserHasPasskeys(t *testing.T) {
+	test := newWebAuthnTest()
+	test.mfaToken("login_challenge")
+	test.mfa.On("GetUserProviders", mock.Anything).Return(nil, nil)
+	test.wa.On("GetUserCredentials", test.user.ID).Return([]*models.WebAuthnCredential{{ID: bson.NewObjectId()}}, nil)
+
+	_, err := test.m.RegisterBegin(getContext(), &models.WebAuthnRegisterBeginForm{ClientId: test.clientID, Token: "mfa_token"})
+	assert.Equal(t, apierror.InvalidToken, err)
+	test.ott.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestWebAuthnRegisterBeginReturnErrorWithoutBearerToken(t *testing.T) {
+	test := newWebAuthnTest()
+
+	_, err := test.m.RegisterBegin(getContext(), &models.WebAuthnRegisterBeginForm{ClientId: test.clientID})
+	assert.Equal(t, apierror.Unauthorized, err)
+}
+
+func (test *webAuthnTest) bearer(active bool, clientID string) echo.Context {
+	test.h.On("IntrospectOAuth2Token", mock.MatchedBy(func(p *admin.IntrospectOAuth2TokenParams) bool {
+		return p.Token == "access_token"
+	}), mock.Anything).Return(&admin.IntrospectOAuth2TokenOK{Payload: &models2.OAuth2TokenIntrospection{
+		Active:    &active,
+		Sub:       test.user.ID.Hex(),
+		ClientID:  clientID,
+		TokenType: "access_token",
+	}}, nil)
+	return getContext(map[string]interface{}{"headers": map[string]interface{}{"Authorization": "Bearer access_token"}})
+}
+
+func TestWebAuthnRegisterBeginAuthenticatesBearerToken(t *testing.T) {
+	test := newWebAuthnTest()
+	test.wa.On("GetUserCredentials", test.user.ID).Return(nil, nil)
+
+	resp, err := test.m.RegisterBegin(test.bearer(true, test.clientID), &models.WebAuthnRegisterBeginForm{ClientId: test.clientID})
+	assert.Nil(t, err)
+	if assert.NotNil(t, resp) {
+		assert.Equal(t, "webauthn_token", resp.Token)
+	}
+}
+
+func TestWebAuthnRegisterBeginReturnErrorWithInactiveBearerToken(t *testing.T) {
+	test := newWebAuthnTest()
+
+	_, err := test.m.RegisterBegin(test.bearer(false, test.clientID), &models.WebAuthnRegisterBeginForm{ClientId: test.clientID})
+	assert.Equal(t, apierror.Unauthorized, err)
+}
+
+func TestWebAuthnRegisterBeginReturnErrorWithBearerTokenOfOtherClient(t *testing.T) {
+	test := newWebAuthnTest()
+
+	_, err := test.m.RegisterBegin(test.bearer(true, bson.NewObjectId().Hex()), &models.WebAuthnRegisterBeginForm{ClientId: test.clientID})
+	assert.Equal(t, apierror.Unauthorized, err)
+}
+
+func TestWebAuthnRegisterFinishReturnErrorWithLoginSession(t *testing.T) {
+	test := newWebAuthnTest()
+	test.session(&models.WebAuthnSession{ClientID: test.clientID, UserID: test.user.ID})
+
+	_, err := test.m.RegisterFinish(getContext(), &models.WebAuthnRegisterFinishForm{ClientId: test.clientID, Token: "webauthn_token", Credential: &webauthn.AttestationResponse{}})
+	assert.Equal(t, apierror.InvalidToken, err)
+}
+
+func TestWebAuthnRegisterFinishReturnErrorWithInvalidAttestation(t *testing.T) {
+	test := newWebAuthnTest()
+	test.session(&models.WebAuthnSession{Registration: true, ClientID: test.clientID, UserID: test.user.ID, Challenge: []byte("challenge")})
+
+	_, err := test.m.RegisterFinish(getContext(), &models.WebAuthnRegisterFinishForm{ClientId: test.clientID, Token: "webauthn_token", Credential: &webauthn.AttestationResponse{}})
+	assert.Equal(t, apierror.InvalidWebAuthnCredential, err)
+	test.wa.AssertNotCalled(t, "Add", mock.Anything)
+}
+
+func TestWebAuthnLoginBeginAllowsUserCredentialsForSecondFactor(t *testing.T) {
+	test := newWebAuthnTest()
+	test.mfaToken("login_challenge")
+	test.wa.On("GetUserCredentials", test.user.ID).Return([]*models.WebAuthnCredential{{CredentialID: []byte("credential")}}, nil)
+
+	resp, err := test.m.LoginBegin(getContext(), &models.WebAuthnLoginBeginForm{ClientId: test.clientID, Token: "mfa_token"})
+	assert.Nil(t, err)
+	if assert.NotNil(t, resp) {
+		options := resp.PublicKey.(*webauthn.RequestOptions)
+		assert.Len(t, options.AllowCredentials, 1)
+		assert.Equal(t, webauthn.UserVerificationPreferred, options.UserVerification)
+	}
+}
+
+func TestWebAuthnLoginBeginRequiresUserVerificationForFirstFactor(t *testing.T) {
+	test := newWebAuthnTest()
+	test.h.On("GetLoginRequest", mock.Anything).Return(&admin.GetLoginRequestOK{Payload: &models2.LoginRequest{Client: &models2.OAuth2Client{ClientID: test.clientID}}}, nil)
+
+	resp, err := test.m.LoginBegin(getContext(), &models.WebAuthnLoginBeginForm{ClientId: test.clientID, Challenge: "login_challenge"})
+	assert.Nil(t, err)
+	if assert.NotNil(t, resp) {
+		options := resp.PublicKey.(*webauthn.RequestOptions)
+		assert.Empty(t, options.AllowCredentials)
+		assert.Equal(t, webauthn.UserVerificationRequired, options.UserVerification)
+	}
+}
+
+func TestWebAuthnLoginBeginReturnErrorWithForeignClient(t *testing.T) {
+	test := newWebAuthnTest()
+	test.h.On("GetLoginRequest", mock.Anything).Return(&admin.GetLoginRequestOK{Payload: &models2.LoginRequest{Client: &models2.OAuth2Client{ClientID: bson.NewObjectId().Hex()}}}, nil)
+
+	_, err := test.m.LoginBegin(getContext(), &models.WebAuthnLoginBeginForm{ClientId: test.clientID, Challenge: "login_challenge"})
+	assert.Equal(t, apierror.InvalidClient, err)
+}
+
+func TestWebAuthnLoginFinishReturnErrorWithForeignCredential(t *testing.T) {
+	test := newWebAuthnTest()
+	test.session(&models.WebAuthnSession{ClientID: test.clientID, UserID: test.user.ID, MfaToken: "mfa_token"})
+	test.wa.On("GetByCredentialID", []byte("credential")).Return(&models.WebAuthnCredential{UserID: bson.NewObjectId()}, nil)
+
+	credential := &webauthn.AssertionResponse{RawID: webauthn.EncodeBase64([]byte("credential"))}
+	_, err := test.m.LoginFinish(getContext(), &models.WebAuthnLoginFinishForm{ClientId: test.clientID, Token: "webauthn_token", Credential: credential})
+	assert.Equal(t, apierror.InvalidWebAuthnCredential, err)
+	test.h.AssertNotCalled(t, "AcceptLoginRequest", mock.Anything)
+}
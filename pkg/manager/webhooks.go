@@ -0,0 +1,31 @@
+package manager
+
+import (
+	"context"
+
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/appcore/log"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/models"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/service"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/webhooks"
+	"github.com/globalsign/mgo/bson"
+	"go.uber.org/zap"
+)
+
+// publish sends the event of the user to the webhooks of the application. The operation has already been done
+// at this point, so the failure is only logged.
+func publish(ctx context.Context, r service.InternalRegistry, app *models.Application, userID bson.ObjectId, event webhooks.Event) {
+	if err := r.WebHooks().Publish(ctx, app.ID.Hex(), userID.Hex(), app.WebHooks, event); err != nil {
+		log.Error(ctx, "Unable to publish webhook", zap.String("action", event.Action()), zap.Error(err))
+	}
+}
+
+// publishClient is the same as publish for the application which isn't loaded yet.
+func publishClient(ctx context.Context, r service.InternalRegistry, clientID string, userID bson.ObjectId, event webhooks.Event) {
+	app, err := r.ApplicationService().Get(bson.ObjectIdHex(clientID))
+	if err != nil {
+		log.Error(ctx, "Unable to publish webhook, error on getting app by id", zap.String("action", event.Action()), zap.Error(err))
+		return
+	}
+
+	publish(ctx, r, app, userID, event)
+}
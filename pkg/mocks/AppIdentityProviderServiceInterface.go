@@ -5,8 +5,13 @@ package mocks
 import (
 	context "context"
 
+	entity "github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
+
 	models "github.com/ProtocolONE/auth1.protocol.one/pkg/models"
+	oidc "github.com/ProtocolONE/auth1.protocol.one/pkg/oidc"
 	mock "github.com/stretchr/testify/mock"
+
+	saml "github.com/ProtocolONE/auth1.protocol.one/pkg/saml"
 )
 
 // AppIdentityProviderServiceInterface is an autogenerated mock type for the AppIdentityProviderServiceInterface type
@@ -83,6 +88,117 @@ func (_m *AppIdentityProviderServiceInterface) GetAvailableTemplates() []string
 	return r0
 }
 
+// GetOIDCAuthUrl provides a mock function with given fields: _a0, _a1, _a2, _a3, _a4
+func (_m *AppIdentityProviderServiceInterface) GetOIDCAuthUrl(_a0 context.Context, _a1 string, _a2 *models.AppIdentityProvider, _a3 *oidc.AuthRequest, _a4 interface{}) (string, error) {
+	ret := _m.Called(_a0, _a1, _a2, _a3, _a4)
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func(context.Context, string, *models.AppIdentityProvider, *oidc.AuthRequest, interface{}) string); ok {
+		r0 = rf(_a0, _a1, _a2, _a3, _a4)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, *models.AppIdentityProvider, *oidc.AuthRequest, interface{}) error); ok {
+		r1 = rf(_a0, _a1, _a2, _a3, _a4)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetOIDCProfile provides a mock function with given fields: _a0, _a1, _a2, _a3, _a4
+func (_m *AppIdentityProviderServiceInterface) GetOIDCProfile(_a0 context.Context, _a1 string, _a2 string, _a3 *models.AppIdentityProvider, _a4 *oidc.AuthRequest) (*models.UserIdentitySocial, error) {
+	ret := _m.Called(_a0, _a1, _a2, _a3, _a4)
+
+	var r0 *models.UserIdentitySocial
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, *models.AppIdentityProvider, *oidc.AuthRequest) *models.UserIdentitySocial); ok {
+		r0 = rf(_a0, _a1, _a2, _a3, _a4)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.UserIdentitySocial)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, *models.AppIdentityProvider, *oidc.AuthRequest) error); ok {
+		r1 = rf(_a0, _a1, _a2, _a3, _a4)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetSAMLAuthUrl provides a mock function with given fields: _a0, _a1, _a2, _a3, _a4
+func (_m *AppIdentityProviderServiceInterface) GetSAMLAuthUrl(_a0 string, _a1 *saml.ServiceProvider, _a2 *models.AppIdentityProvider, _a3 string, _a4 string) (string, error) {
+	ret := _m.Called(_a0, _a1, _a2, _a3, _a4)
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func(string, *saml.ServiceProvider, *models.AppIdentityProvider, string, string) string); ok {
+		r0 = rf(_a0, _a1, _a2, _a3, _a4)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, *saml.ServiceProvider, *models.AppIdentityProvider, string, string) error); ok {
+		r1 = rf(_a0, _a1, _a2, _a3, _a4)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetSAMLMetadata provides a mock function with given fields: _a0, _a1, _a2, _a3
+func (_m *AppIdentityProviderServiceInterface) GetSAMLMetadata(_a0 context.Context, _a1 string, _a2 *saml.ServiceProvider, _a3 entity.SpaceID) ([]byte, error) {
+	ret := _m.Called(_a0, _a1, _a2, _a3)
+
+	var r0 []byte
+	if rf, ok := ret.Get(0).(func(context.Context, string, *saml.ServiceProvider, entity.SpaceID) []byte); ok {
+		r0 = rf(_a0, _a1, _a2, _a3)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]byte)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, *saml.ServiceProvider, entity.SpaceID) error); ok {
+		r1 = rf(_a0, _a1, _a2, _a3)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetSAMLProfile provides a mock function with given fields: _a0, _a1, _a2, _a3, _a4
+func (_m *AppIdentityProviderServiceInterface) GetSAMLProfile(_a0 string, _a1 *saml.ServiceProvider, _a2 string, _a3 *models.AppIdentityProvider, _a4 string) (*models.UserIdentitySocial, error) {
+	ret := _m.Called(_a0, _a1, _a2, _a3, _a4)
+
+	var r0 *models.UserIdentitySocial
+	if rf, ok := ret.Get(0).(func(string, *saml.ServiceProvider, string, *models.AppIdentityProvider, string) *models.UserIdentitySocial); ok {
+		r0 = rf(_a0, _a1, _a2, _a3, _a4)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.UserIdentitySocial)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, *saml.ServiceProvider, string, *models.AppIdentityProvider, string) error); ok {
+		r1 = rf(_a0, _a1, _a2, _a3, _a4)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetSocialProfile provides a mock function with given fields: _a0, _a1, _a2, _a3
 func (_m *AppIdentityProviderServiceInterface) GetSocialProfile(_a0 context.Context, _a1 string, _a2 string, _a3 *models.AppIdentityProvider) (*models.UserIdentitySocial, error) {
 	ret := _m.Called(_a0, _a1, _a2, _a3)
@@ -106,6 +222,50 @@ func (_m *AppIdentityProviderServiceInterface) GetSocialProfile(_a0 context.Cont
 	return r0, r1
 }
 
+// GetSteamAuthUrl provides a mock function with given fields: _a0, _a1, _a2
+func (_m *AppIdentityProviderServiceInterface) GetSteamAuthUrl(_a0 string, _a1 *models.AppIdentityProvider, _a2 interface{}) (string, error) {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func(string, *models.AppIdentityProvider, interface{}) string); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, *models.AppIdentityProvider, interface{}) error); ok {
+		r1 = rf(_a0, _a1, _a2)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetSteamProfile provides a mock function with given fields: _a0, _a1, _a2, _a3
+func (_m *AppIdentityProviderServiceInterface) GetSteamProfile(_a0 context.Context, _a1 string, _a2 string, _a3 *models.AppIdentityProvider) (*models.UserIdentitySocial, error) {
+	ret := _m.Called(_a0, _a1, _a2, _a3)
+
+	var r0 *models.UserIdentitySocial
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, *models.AppIdentityProvider) *models.UserIdentitySocial); ok {
+		r0 = rf(_a0, _a1, _a2, _a3)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.UserIdentitySocial)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, *models.AppIdentityProvider) error); ok {
+		r1 = rf(_a0, _a1, _a2, _a3)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetTemplate provides a mock function with given fields: _a0
 func (_m *AppIdentityProviderServiceInterface) GetTemplate(_a0 string) (*models.AppIdentityProvider, error) {
 	ret := _m.Called(_a0)
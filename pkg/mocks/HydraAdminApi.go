@@ -106,6 +106,29 @@ func (_m *HydraAdminApi) CreateOAuth2Client(_a0 *admin.CreateOAuth2ClientParams)
 	return r0, r1
 }
 
+// DeleteOAuth2Client provides a mock function with given fields: _a0
+func (_m *HydraAdminApi) DeleteOAuth2Client(_a0 *admin.DeleteOAuth2ClientParams) (*admin.DeleteOAuth2ClientNoContent, error) {
+	ret := _m.Called(_a0)
+
+	var r0 *admin.DeleteOAuth2ClientNoContent
+	if rf, ok := ret.Get(0).(func(*admin.DeleteOAuth2ClientParams) *admin.DeleteOAuth2ClientNoContent); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*admin.DeleteOAuth2ClientNoContent)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*admin.DeleteOAuth2ClientParams) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetConsentRequest provides a mock function with given fields: _a0
 func (_m *HydraAdminApi) GetConsentRequest(_a0 *admin.GetConsentRequestParams) (*admin.GetConsentRequestOK, error) {
 	ret := _m.Called(_a0)
@@ -6,11 +6,17 @@ import (
 	database "github.com/ProtocolONE/auth1.protocol.one/pkg/database"
 	mock "github.com/stretchr/testify/mock"
 
+	passwords "github.com/ProtocolONE/auth1.protocol.one/pkg/passwords"
+
 	persist "github.com/ProtocolONE/auth1.protocol.one/pkg/persist"
 
 	repository "github.com/ProtocolONE/auth1.protocol.one/internal/domain/repository"
 
+	saml "github.com/ProtocolONE/auth1.protocol.one/pkg/saml"
+
 	service "github.com/ProtocolONE/auth1.protocol.one/pkg/service"
+
+	webhooks "github.com/ProtocolONE/auth1.protocol.one/pkg/webhooks"
 )
 
 // InternalRegistry is an autogenerated mock type for the InternalRegistry type
@@ -34,6 +40,22 @@ func (_m *InternalRegistry) ApplicationService() service.ApplicationServiceInter
 	return r0
 }
 
+// BreachedPasswords provides a mock function with given fields:
+func (_m *InternalRegistry) BreachedPasswords() passwords.Corpus {
+	ret := _m.Called()
+
+	var r0 passwords.Corpus
+	if rf, ok := ret.Get(0).(func() passwords.Corpus); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(passwords.Corpus)
+		}
+	}
+
+	return r0
+}
+
 // CentrifugoService provides a mock function with given fields:
 func (_m *InternalRegistry) CentrifugoService() service.CentrifugoServiceInterface {
 	ret := _m.Called()
@@ -98,6 +120,22 @@ func (_m *InternalRegistry) LauncherTokenService() service.LauncherTokenServiceI
 	return r0
 }
 
+// LoginAttempts provides a mock function with given fields:
+func (_m *InternalRegistry) LoginAttempts() service.LoginAttemptsInterface {
+	ret := _m.Called()
+
+	var r0 service.LoginAttemptsInterface
+	if rf, ok := ret.Get(0).(func() service.LoginAttemptsInterface); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(service.LoginAttemptsInterface)
+		}
+	}
+
+	return r0
+}
+
 // Mailer provides a mock function with given fields:
 func (_m *InternalRegistry) Mailer() service.MailerInterface {
 	ret := _m.Called()
@@ -162,6 +200,54 @@ func (_m *InternalRegistry) OneTimeTokenService() service.OneTimeTokenServiceInt
 	return r0
 }
 
+// RateLimiter provides a mock function with given fields:
+func (_m *InternalRegistry) RateLimiter() service.RateLimiterInterface {
+	ret := _m.Called()
+
+	var r0 service.RateLimiterInterface
+	if rf, ok := ret.Get(0).(func() service.RateLimiterInterface); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(service.RateLimiterInterface)
+		}
+	}
+
+	return r0
+}
+
+// SAMLKey provides a mock function with given fields:
+func (_m *InternalRegistry) SAMLKey() *saml.KeyPair {
+	ret := _m.Called()
+
+	var r0 *saml.KeyPair
+	if rf, ok := ret.Get(0).(func() *saml.KeyPair); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*saml.KeyPair)
+		}
+	}
+
+	return r0
+}
+
+// SmsSender provides a mock function with given fields:
+func (_m *InternalRegistry) SmsSender() service.SmsSenderInterface {
+	ret := _m.Called()
+
+	var r0 service.SmsSenderInterface
+	if rf, ok := ret.Get(0).(func() service.SmsSenderInterface); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(service.SmsSenderInterface)
+		}
+	}
+
+	return r0
+}
+
 // Spaces provides a mock function with given fields:
 func (_m *InternalRegistry) Spaces() repository.SpaceRepository {
 	ret := _m.Called()
@@ -193,3 +279,19 @@ func (_m *InternalRegistry) Watcher() persist.Watcher {
 
 	return r0
 }
+
+// WebHooks provides a mock function with given fields:
+func (_m *InternalRegistry) WebHooks() *webhooks.WebHooks {
+	ret := _m.Called()
+
+	var r0 *webhooks.WebHooks
+	if rf, ok := ret.Get(0).(func() *webhooks.WebHooks); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*webhooks.WebHooks)
+		}
+	}
+
+	return r0
+}
@@ -0,0 +1,105 @@
+// Code generated by mockery v1.0.0. DO NOT EDIT.
+
+package mocks
+
+import (
+	mock "github.com/stretchr/testify/mock"
+
+	time "time"
+)
+
+// LoginAttemptsInterface is an autogenerated mock type for the LoginAttemptsInterface type
+type LoginAttemptsInterface struct {
+	mock.Mock
+}
+
+// Blocked provides a mock function with given fields: key
+func (_m *LoginAttemptsInterface) Blocked(key string) (time.Duration, time.Duration, error) {
+	ret := _m.Called(key)
+
+	var r0 time.Duration
+	if rf, ok := ret.Get(0).(func(string) time.Duration); ok {
+		r0 = rf(key)
+	} else {
+		r0 = ret.Get(0).(time.Duration)
+	}
+
+	var r1 time.Duration
+	if rf, ok := ret.Get(1).(func(string) time.Duration); ok {
+		r1 = rf(key)
+	} else {
+		r1 = ret.Get(1).(time.Duration)
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(string) error); ok {
+		r2 = rf(key)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// Delay provides a mock function with given fields: key, d
+func (_m *LoginAttemptsInterface) Delay(key string, d time.Duration) error {
+	ret := _m.Called(key, d)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, time.Duration) error); ok {
+		r0 = rf(key, d)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Fail provides a mock function with given fields: key, window
+func (_m *LoginAttemptsInterface) Fail(key string, window time.Duration) (int, error) {
+	ret := _m.Called(key, window)
+
+	var r0 int
+	if rf, ok := ret.Get(0).(func(string, time.Duration) int); ok {
+		r0 = rf(key, window)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, time.Duration) error); ok {
+		r1 = rf(key, window)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Lock provides a mock function with given fields: key, d
+func (_m *LoginAttemptsInterface) Lock(key string, d time.Duration) error {
+	ret := _m.Called(key, d)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, time.Duration) error); ok {
+		r0 = rf(key, d)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Reset provides a mock function with given fields: key
+func (_m *LoginAttemptsInterface) Reset(key string) error {
+	ret := _m.Called(key)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(key)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
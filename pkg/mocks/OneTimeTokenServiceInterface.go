@@ -12,6 +12,27 @@ type OneTimeTokenServiceInterface struct {
 	mock.Mock
 }
 
+// Attempt provides a mock function with given fields: token
+func (_m *OneTimeTokenServiceInterface) Attempt(token string) (int, error) {
+	ret := _m.Called(token)
+
+	var r0 int
+	if rf, ok := ret.Get(0).(func(string) int); ok {
+		r0 = rf(token)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(token)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Create provides a mock function with given fields: obj, settings
 func (_m *OneTimeTokenServiceInterface) Create(obj interface{}, settings *models.OneTimeTokenSettings) (*models.OneTimeToken, error) {
 	ret := _m.Called(obj, settings)
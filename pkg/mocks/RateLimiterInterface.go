@@ -0,0 +1,35 @@
+// Code generated by mockery v1.0.0. DO NOT EDIT.
+
+package mocks
+
+import (
+	mock "github.com/stretchr/testify/mock"
+
+	time "time"
+)
+
+// RateLimiterInterface is an autogenerated mock type for the RateLimiterInterface type
+type RateLimiterInterface struct {
+	mock.Mock
+}
+
+// Allow provides a mock function with given fields: key, interval
+func (_m *RateLimiterInterface) Allow(key string, interval time.Duration) (bool, error) {
+	ret := _m.Called(key, interval)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(string, time.Duration) bool); ok {
+		r0 = rf(key, interval)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, time.Duration) error); ok {
+		r1 = rf(key, interval)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
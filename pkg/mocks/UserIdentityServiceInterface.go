@@ -87,3 +87,17 @@ func (_m *UserIdentityServiceInterface) Update(userIdentity *models.UserIdentity
 
 	return r0
 }
+
+// UpdateCredential provides a mock function with given fields: id, credential
+func (_m *UserIdentityServiceInterface) UpdateCredential(id bson.ObjectId, credential string) error {
+	ret := _m.Called(id, credential)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(bson.ObjectId, string) error); ok {
+		r0 = rf(id, credential)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
@@ -0,0 +1,103 @@
+// Code generated by mockery v1.0.0. DO NOT EDIT.
+
+package mocks
+
+import (
+	bson "github.com/globalsign/mgo/bson"
+	mock "github.com/stretchr/testify/mock"
+
+	models "github.com/ProtocolONE/auth1.protocol.one/pkg/models"
+)
+
+// WebAuthnServiceInterface is an autogenerated mock type for the WebAuthnServiceInterface type
+type WebAuthnServiceInterface struct {
+	mock.Mock
+}
+
+// Add provides a mock function with given fields: _a0
+func (_m *WebAuthnServiceInterface) Add(_a0 *models.WebAuthnCredential) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*models.WebAuthnCredential) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetByCredentialID provides a mock function with given fields: _a0
+func (_m *WebAuthnServiceInterface) GetByCredentialID(_a0 []byte) (*models.WebAuthnCredential, error) {
+	ret := _m.Called(_a0)
+
+	var r0 *models.WebAuthnCredential
+	if rf, ok := ret.Get(0).(func([]byte) *models.WebAuthnCredential); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.WebAuthnCredential)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func([]byte) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetUserCredentials provides a mock function with given fields: _a0
+func (_m *WebAuthnServiceInterface) GetUserCredentials(_a0 bson.ObjectId) ([]*models.WebAuthnCredential, error) {
+	ret := _m.Called(_a0)
+
+	var r0 []*models.WebAuthnCredential
+	if rf, ok := ret.Get(0).(func(bson.ObjectId) []*models.WebAuthnCredential); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.WebAuthnCredential)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(bson.ObjectId) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Remove provides a mock function with given fields: userID, id
+func (_m *WebAuthnServiceInterface) Remove(userID bson.ObjectId, id bson.ObjectId) error {
+	ret := _m.Called(userID, id)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(bson.ObjectId, bson.ObjectId) error); ok {
+		r0 = rf(userID, id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpdateSignCount provides a mock function with given fields: id, signCount
+func (_m *WebAuthnServiceInterface) UpdateSignCount(id bson.ObjectId, signCount int64) error {
+	ret := _m.Called(id, signCount)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(bson.ObjectId, int64) error); ok {
+		r0 = rf(id, signCount)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
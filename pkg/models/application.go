@@ -10,18 +10,29 @@ import (
 var (
 	AppIdentityProviderTypePassword = "password"
 	AppIdentityProviderTypeSocial   = "social"
-
-	AppIdentityProviderNameDefault  = "initial"
-	AppIdentityProviderNameFacebook = "facebook"
-	AppIdentityProviderNameTwitch   = "twitch"
-	AppIdentityProviderNameGoogle   = "google"
-	AppIdentityProviderNameVk       = "vk"
-
-	AppIdentityProviderDisplayNameDefault  = "Initial connection"
-	AppIdentityProviderDisplayNameFacebook = "Facebook"
-	AppIdentityProviderDisplayNameTwitch   = "Twitch"
-	AppIdentityProviderDisplayNameGoogle   = "Google"
-	AppIdentityProviderDisplayNameVk       = "VKontakte"
+	AppIdentityProviderTypeOIDC     = "oidc"
+	AppIdentityProviderTypeSAML     = "saml"
+	AppIdentityProviderTypeSteam    = "steam"
+
+	AppIdentityProviderNameDefault   = "initial"
+	AppIdentityProviderNameFacebook  = "facebook"
+	AppIdentityProviderNameTwitch    = "twitch"
+	AppIdentityProviderNameGoogle    = "google"
+	AppIdentityProviderNameVk        = "vk"
+	AppIdentityProviderNameSteam     = "steam"
+	AppIdentityProviderNameApple     = "apple"
+	AppIdentityProviderNameDiscord   = "discord"
+	AppIdentityProviderNameMicrosoft = "microsoft"
+
+	AppIdentityProviderDisplayNameDefault   = "Initial connection"
+	AppIdentityProviderDisplayNameFacebook  = "Facebook"
+	AppIdentityProviderDisplayNameTwitch    = "Twitch"
+	AppIdentityProviderDisplayNameGoogle    = "Google"
+	AppIdentityProviderDisplayNameVk        = "VKontakte"
+	AppIdentityProviderDisplayNameSteam     = "Steam"
+	AppIdentityProviderDisplayNameApple     = "Apple"
+	AppIdentityProviderDisplayNameDiscord   = "Discord"
+	AppIdentityProviderDisplayNameMicrosoft = "Microsoft"
 )
 
 // Application describes a table for storing the basic properties and settings of the authorization application.
@@ -62,6 +73,16 @@ type Application struct {
 
 	// WebHook endpoint URLs
 	WebHooks []string `bson:"webhooks" json:"webhooks"`
+
+	// WebHookSecret is a secret string with which the webhook requests are signed.
+	WebHookSecret string `bson:"webhook_secret" json:"webhook_secret"`
+
+	// WebHookPreviousSecret is the secret replaced by the last rotation, the requests are signed
+	// with it too until WebHookPreviousSecretExpiresAt.
+	WebHookPreviousSecret string `bson:"webhook_previous_secret" json:"-"`
+
+	// WebHookPreviousSecretExpiresAt is the end of the overlap window of the rotated secret.
+	WebHookPreviousSecretExpiresAt time.Time `bson:"webhook_previous_secret_expires_at" json:"webhook_previous_secret_expires_at,omitempty"`
 }
 
 func (a *Application) MarshalLogObject(enc zapcore.ObjectEncoder) error {
@@ -0,0 +1,37 @@
+package models
+
+import "go.uber.org/zap/zapcore"
+
+// EmailVerificationStartForm contains form fields for requesting the email verification link.
+type EmailVerificationStartForm struct {
+	// ClientID is the id of the application.
+	ClientID string `json:"client_id" form:"client_id" validate:"required"`
+
+	// Challenge is the code of the oauth2 login challenge, the user returns to it after the verification.
+	Challenge string `json:"challenge" form:"challenge" validate:"required"`
+
+	// Email is the email address of user to verify.
+	Email string `json:"email" form:"email" validate:"required,email"`
+}
+
+// EmailVerificationForm contains form fields for confirming the email address.
+type EmailVerificationForm struct {
+	// Token is the one-time token from the verification link.
+	Token string `json:"token" form:"token" validate:"required"`
+}
+
+// EmailVerificationTokenSource contains the data stored with the email verification one-time token.
+type EmailVerificationTokenSource struct {
+	UserID    string
+	Email     string
+	ClientID  string
+	Challenge string
+}
+
+func (m *EmailVerificationStartForm) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("ClientID", m.ClientID)
+	enc.AddString("Challenge", m.Challenge)
+	enc.AddString("Email", m.Email)
+
+	return nil
+}
@@ -33,7 +33,13 @@ var (
 	ErrorLoginChallenge           = "Invalid login challenge"
 	ErrorAppIdIncorrect           = "Application ID is incorrect"
 	ErrorMfaClientRemove          = "Unable to remove MFA"
+	ErrorMfaChannelUnsupported    = "MFA provider does not deliver codes"
+	ErrorMfaCodeDelivery          = "Unable to deliver MFA code"
+	ErrorMfaChallengeThrottled    = "MFA code has been sent recently"
+	ErrorPhoneNumberRequired      = "Phone number required"
 	ErrorUsernameTaken            = "Username already taken"
+	ErrorUserBlocked              = "User is blocked"
+	ErrorEmailNotVerified         = "Email is not verified"
 )
 
 // ErrorInterface defines basic methods for application errors.
@@ -70,3 +76,7 @@ type GeneralError struct {
 func (e *GeneralError) Error() string {
 	return e.Message
 }
+
+func (e *GeneralError) Unwrap() error {
+	return e.Err
+}
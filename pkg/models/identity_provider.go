@@ -43,6 +43,21 @@ type AppIdentityProvider struct {
 
 	// EndpointUserInfoURL is the endpoint on external network for to get user information.
 	EndpointUserInfoURL string `bson:"endpoint_userinfo_url" json:"endpoint_userinfo_url"`
+
+	// Issuer is the issuer url of the OpenID Connect provider or the entity id of the SAML provider.
+	Issuer string `bson:"issuer" json:"issuer"`
+
+	// Certificate is the PEM certificate the SAML provider signs the assertions with.
+	Certificate string `bson:"certificate" json:"certificate"`
+
+	// TeamID is the team id of the Apple developer account.
+	TeamID string `bson:"team_id" json:"team_id"`
+
+	// KeyID is the id of the Sign in with Apple key.
+	KeyID string `bson:"key_id" json:"key_id"`
+
+	// ClaimMapping selects the profile of the user in the userinfo response or the claims of the provider.
+	ClaimMapping entity.ClaimMapping `bson:"-" json:"-"`
 }
 
 func (ipc *AppIdentityProvider) MarshalLogObject(enc zapcore.ObjectEncoder) error {
@@ -57,6 +72,7 @@ func (ipc *AppIdentityProvider) MarshalLogObject(enc zapcore.ObjectEncoder) erro
 	enc.AddString("EndpointAuthURL", ipc.EndpointAuthURL)
 	enc.AddString("EndpointTokenURL", ipc.EndpointTokenURL)
 	enc.AddString("EndpointUserInfoURL", ipc.EndpointUserInfoURL)
+	enc.AddString("Issuer", ipc.Issuer)
 
 	return nil
 }
@@ -73,5 +89,10 @@ func OldIDProvider(p entity.IdentityProvider) *AppIdentityProvider {
 		EndpointAuthURL:     p.EndpointAuthURL,
 		EndpointTokenURL:    p.EndpointTokenURL,
 		EndpointUserInfoURL: p.EndpointUserInfoURL,
+		Issuer:              p.Issuer,
+		Certificate:         p.Certificate,
+		TeamID:              p.TeamID,
+		KeyID:               p.KeyID,
+		ClaimMapping:        p.ClaimMapping,
 	}
 }
@@ -37,6 +37,12 @@ func (a *ApplicationFormApp) MarshalLogObject(enc zapcore.ObjectEncoder) error {
 	return nil
 }
 
+// WebHookSecretForm contains form fields for requesting to rotate the webhook secret of the application.
+type WebHookSecretForm struct {
+	// Overlap is the number of seconds during which the requests are signed with the previous secret too.
+	Overlap int `json:"overlap" validate:"min=0,max=604800"`
+}
+
 type ApplicationKeysForm struct {
 	ApplicationId string `json:"application_id" validate:"required"` // application id
 	Algorithm     string `json:"algorithm" validate:"required"`      // algorithm name (HS256, HS512, RS256, ECDSA)
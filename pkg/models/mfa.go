@@ -5,6 +5,14 @@ import (
 	"go.uber.org/zap/zapcore"
 )
 
+const (
+	// MfaChannelEmail is the channel delivering one-time codes to the user email.
+	MfaChannelEmail = "email"
+
+	// MfaChannelSms is the channel delivering one-time codes to the user phone number.
+	MfaChannelSms = "sms"
+)
+
 type MfaAuthenticator struct {
 	ID            bson.ObjectId `json:"id"`
 	Secret        string        `json:"secret"`
@@ -20,13 +28,41 @@ type MfaChallengeForm struct {
 	ClientId string `json:"client_id" form:"client_id" validate:"required"`
 
 	// Connection is the connection name of the application identity provider.
-	Connection string `json:"connection" form:"connection" validate:"required"`
+	Connection string `json:"connection" form:"connection"`
 
 	// Token is the one-time token for mfa connection.
 	Token string `json:"mfa_token" form:"mfa_token" validate:"required"`
 
 	// Type is the type of mfa challenge (otp, sms).
 	Type string `json:"challenge_type" form:"challenge_type"`
+
+	// ProviderId is the id of the mfa provider delivering the code.
+	// Required if the mfa token is not bound to a provider.
+	ProviderId string `json:"provider_id" form:"provider_id"`
+}
+
+// MfaChallengeResponse contains the result of the code delivery.
+type MfaChallengeResponse struct {
+	// ChallengeType is the type of challenge, always "oob" for the delivered codes.
+	ChallengeType string `json:"challenge_type"`
+
+	// OobCode is the token of the delivered code, it must be passed to the verification with the code.
+	OobCode string `json:"oob_code"`
+
+	// BindingMethod is the way the code is bound to the challenge, "prompt" means the user enters it manually.
+	BindingMethod string `json:"binding_method"`
+}
+
+// MfaChallengeToken contains the one-time code delivered by the email or sms channel.
+type MfaChallengeToken struct {
+	// MfaToken is the mfa token for which the code was requested.
+	MfaToken string `json:"mfa_token"`
+
+	// ProviderID is the id of the provider which delivered the code.
+	ProviderID bson.ObjectId `json:"provider_id"`
+
+	// Code is the delivered code.
+	Code string `json:"code"`
 }
 
 // MfaVerifyForm contains form fields for requesting to verify mfa challenge.
@@ -42,6 +78,9 @@ type MfaVerifyForm struct {
 
 	// Code is the string of one-time code.
 	Code string `json:"code" form:"code"`
+
+	// OobCode is the token returned by the challenge for the providers delivering codes by email or sms.
+	OobCode string `json:"oob_code" form:"oob_code"`
 }
 
 // MfaListForm contains form fields for requesting to list of mfa providers.
@@ -70,6 +109,9 @@ type MfaAddForm struct {
 	// Code is the string of one-time code.
 	Code string `json:"code" form:"code"`
 
+	// Token is the one-time token of mfa challenge, used to add the first provider during login.
+	Token string `json:"mfa_token" form:"mfa_token"`
+
 	// PhoneNumber is the phone number for which the provider will be associated.
 	PhoneNumber string `json:"phone_number" form:"phone_number"`
 }
@@ -128,7 +170,35 @@ type UserMfaToken struct {
 	UserIdentity *UserIdentity
 
 	// MfaProvider is the mfa provider.
+	// If empty, the user can verify the code with any of own providers.
 	MfaProvider *MfaProvider
+
+	// Challenge is the oauth2 login challenge which is accepted after successful verification.
+	Challenge string
+
+	// Remember is the option for the save user session in the cookie.
+	Remember bool
+
+	// Amr is the list of authentication methods used by the user before the second factor.
+	Amr []string
+
+	// ClientID is the application of the login challenge.
+	ClientID string
+
+	// Provider is the name of the identity provider of the first factor, it is reported by the login event.
+	Provider string
+}
+
+// MfaRequiredResponse contains the data to continue login with the second factor.
+type MfaRequiredResponse struct {
+	// Token is the one-time token for mfa verification bound to the login challenge.
+	Token string `json:"mfa_token"`
+
+	// Providers is the list of mfa providers of the user. If empty, the user must add a provider first.
+	Providers []*MfaProvider `json:"providers"`
+
+	// WebAuthn is true if the user has passkeys which can be used as the second factor.
+	WebAuthn bool `json:"webauthn"`
 }
 
 // MfaConnection contains property of mfa provider for showing to the user.
@@ -187,7 +257,8 @@ func (m *MfaChallengeForm) MarshalLogObject(enc zapcore.ObjectEncoder) error {
 	enc.AddString("ClientID", m.ClientId)
 	enc.AddString("Name", m.Connection)
 	enc.AddString("Type", m.Type)
-	enc.AddString("Token", m.Token)
+	enc.AddString("ProviderId", m.ProviderId)
+	enc.AddString("Token", "[HIDDEN]")
 
 	return nil
 }
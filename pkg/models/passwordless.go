@@ -2,21 +2,53 @@ package models
 
 import "go.uber.org/zap/zapcore"
 
+// PasswordLessStartForm contains form fields for requesting a passwordless login code.
 type PasswordLessStartForm struct {
-	ClientId   string `json:"client_id" form:"client_id" validate:"required"`
+	// ClientId is the id of the application.
+	ClientId string `json:"client_id" form:"client_id" validate:"required"`
+
+	// Connection is the name of the passwordless connection (only "email" is supported).
 	Connection string `json:"connection" form:"connection" validate:"required"`
+
+	// Challenge is the code of the oauth2 login challenge. This code to generates of the Hydra service.
+	Challenge string `json:"challenge" form:"challenge" validate:"required"`
+
+	// Email is the email address of user to send the login code.
+	Email string `json:"email" form:"email" validate:"required,email"`
 }
 
+// PasswordLessVerifyForm contains form fields for verifying a passwordless login code.
 type PasswordLessVerifyForm struct {
-	ClientId   string `json:"client_id" form:"client_id" validate:"required"`
+	// ClientId is the id of the application.
+	ClientId string `json:"client_id" form:"client_id" validate:"required"`
+
+	// Connection is the name of the passwordless connection (only "email" is supported).
 	Connection string `json:"connection" form:"connection" validate:"required"`
-	Code       string `json:"verification_code" form:"verification_code" validate:"required"`
-	Token      string `json:"token" form:"token" validate:"required"`
+
+	// Code is the numeric verification code sent to the user.
+	Code string `json:"verification_code" form:"verification_code" validate:"required"`
+
+	// Token is the one-time token returned by the start request.
+	Token string `json:"token" form:"token" validate:"required"`
+
+	// Remember is the option for the save user session in the cookie.
+	Remember bool `json:"remember" form:"remember"`
+}
+
+// PasswordLessTokenSource contains the data stored with the passwordless one-time token.
+type PasswordLessTokenSource struct {
+	Email     string
+	ClientID  string
+	Challenge string
+	Subject   string
+	Code      string
 }
 
 func (m *PasswordLessStartForm) MarshalLogObject(enc zapcore.ObjectEncoder) error {
 	enc.AddString("ClientID", m.ClientId)
 	enc.AddString("Name", m.Connection)
+	enc.AddString("Challenge", m.Challenge)
+	enc.AddString("Email", m.Email)
 
 	return nil
 }
@@ -24,7 +56,7 @@ func (m *PasswordLessStartForm) MarshalLogObject(enc zapcore.ObjectEncoder) erro
 func (m *PasswordLessVerifyForm) MarshalLogObject(enc zapcore.ObjectEncoder) error {
 	enc.AddString("ClientID", m.ClientId)
 	enc.AddString("Name", m.Connection)
-	enc.AddString("Code", m.Code)
+	enc.AddString("Code", "[HIDDEN]")
 	enc.AddString("Token", m.Token)
 
 	return nil
@@ -26,6 +26,9 @@ type User struct {
 	// EmailVerified is status of verification user address.
 	EmailVerified bool `bson:"email_verified" json:"email_verified"`
 
+	// EmailPending is set while the email change isn't applied to the identity of the default provider.
+	EmailPending bool `bson:"email_pending,omitempty" json:"-"`
+
 	// PhoneNumber is the phone number of the user.
 	PhoneNumber string `bson:"phone_number" json:"phone_number"`
 
@@ -56,6 +59,15 @@ type User struct {
 	// Blocked is status of user blocked.
 	Blocked bool `bson:"blocked" json:"blocked"`
 
+	// BlockedReason is the reason of the block given by the operator.
+	BlockedReason string `bson:"blocked_reason,omitempty" json:"-"`
+
+	// BlockedUntil is the end time of the block, the zero value means the block is permanent.
+	BlockedUntil time.Time `bson:"blocked_until,omitempty" json:"-"`
+
+	// BlockedAt returns the timestamp of the block.
+	BlockedAt time.Time `bson:"blocked_at,omitempty" json:"-"`
+
 	// DeviceID is unique user client identifier
 	DeviceID []string `bson:"device_id" json:"device_id"`
 
@@ -66,6 +78,11 @@ type User struct {
 	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
 }
 
+// IsBlocked reports whether the user can't sign in at the time because of the block.
+func (u *User) IsBlocked(now time.Time) bool {
+	return u.Blocked && (u.BlockedUntil.IsZero() || now.Before(u.BlockedUntil))
+}
+
 func (u *User) AddDeviceID(deviceID string) {
 	for i := range u.DeviceID {
 		if u.DeviceID[i] == deviceID {
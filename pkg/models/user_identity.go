@@ -27,6 +27,9 @@ type UserIdentity struct {
 	// Credential is the
 	Credential string `bson:"credential" json:"-" validate:"required"`
 
+	// PasswordHistory is the hashes of the previous passwords, the last one first.
+	PasswordHistory []string `bson:"password_history,omitempty" json:"-"`
+
 	// Email is the email address of the user.
 	Email string `bson:"email" json:"email" validate:"required,email"`
 
@@ -57,6 +60,9 @@ type UserIdentitySocial struct {
 	// Name is the nickname or username of the user.
 	Name string `json:"name,omitempty"`
 
+	// Username is the nickname of the user when the provider has it apart from the name.
+	Username string `json:"username,omitempty"`
+
 	// FirstName is the first name of the user.
 	FirstName string `json:"first_name,omitempty"`
 
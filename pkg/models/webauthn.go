@@ -0,0 +1,142 @@
+package models
+
+import (
+	"time"
+
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/webauthn"
+	"github.com/globalsign/mgo/bson"
+	"go.uber.org/zap/zapcore"
+)
+
+// WebAuthnCredential describes the public key credential (passkey) registered by the user.
+type WebAuthnCredential struct {
+	// ID is the id of the record.
+	ID bson.ObjectId `bson:"_id" json:"id"`
+
+	// UserID is the id of the user.
+	UserID bson.ObjectId `bson:"user_id" json:"user_id"`
+
+	// CredentialID is the id of the credential generated by the authenticator.
+	CredentialID []byte `bson:"credential_id" json:"-"`
+
+	// PublicKey is the COSE encoded public key of the credential.
+	PublicKey []byte `bson:"public_key" json:"-"`
+
+	// SignCount is the last signature counter reported by the authenticator.
+	SignCount int64 `bson:"sign_count" json:"-"`
+
+	// AAGUID is the model identifier of the authenticator.
+	AAGUID []byte `bson:"aaguid" json:"-"`
+
+	// Name is a human-readable name of the credential given by the user.
+	Name string `bson:"name" json:"name"`
+
+	// CreatedAt returns the timestamp of the credential registration.
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+
+	// LastUsedAt returns the timestamp of the last login with the credential.
+	LastUsedAt time.Time `bson:"last_used_at" json:"last_used_at"`
+}
+
+// Credential returns the credential for the assertion verification.
+func (c *WebAuthnCredential) Credential() *webauthn.Credential {
+	return &webauthn.Credential{ID: c.CredentialID, PublicKey: c.PublicKey, SignCount: uint32(c.SignCount), AAGUID: c.AAGUID}
+}
+
+// WebAuthnSession contains the state of the WebAuthn ceremony between the begin and finish requests.
+type WebAuthnSession struct {
+	// Challenge is the random challenge signed by the authenticator.
+	Challenge []byte `json:"challenge"`
+
+	// Registration is true for the registration ceremony and false for the login.
+	Registration bool `json:"registration"`
+
+	// ClientID is the application id.
+	ClientID string `json:"client_id"`
+
+	// UserID is the id of the user, empty for the login with a discoverable credential.
+	UserID bson.ObjectId `json:"user_id"`
+
+	// LoginChallenge is the oauth2 login challenge accepted by the passkey login.
+	LoginChallenge string `json:"login_challenge"`
+
+	// MfaToken is the mfa token of the login which is completed by the passkey as the second factor.
+	MfaToken string `json:"mfa_token"`
+}
+
+// WebAuthnRegisterBeginForm contains form fields for requesting the passkey registration options.
+type WebAuthnRegisterBeginForm struct {
+	// ClientID is the application id.
+	ClientId string `json:"client_id" form:"client_id" validate:"required"`
+
+	// Token is the mfa token of the login, used to register the first second factor when the space requires MFA.
+	// If empty, the user is identified by the bearer token.
+	Token string `json:"mfa_token" form:"mfa_token"`
+}
+
+// WebAuthnRegisterFinishForm contains form fields for completing the passkey registration.
+type WebAuthnRegisterFinishForm struct {
+	// ClientID is the application id.
+	ClientId string `json:"client_id" form:"client_id" validate:"required"`
+
+	// Token is the ceremony token returned by the begin request.
+	Token string `json:"webauthn_token" form:"webauthn_token" validate:"required"`
+
+	// Name is a human-readable name of the credential.
+	Name string `json:"name" form:"name"`
+
+	// Credential is the result of navigator.credentials.create().
+	Credential *webauthn.AttestationResponse `json:"credential" validate:"required"`
+}
+
+// WebAuthnLoginBeginForm contains form fields for requesting the passkey login options.
+type WebAuthnLoginBeginForm struct {
+	// ClientID is the application id.
+	ClientId string `json:"client_id" form:"client_id" validate:"required"`
+
+	// Challenge is the oauth2 login challenge, required for the passkey login as the first factor.
+	Challenge string `json:"challenge" form:"challenge"`
+
+	// Token is the mfa token of the login, required for the passkey login as the second factor.
+	Token string `json:"mfa_token" form:"mfa_token"`
+}
+
+// WebAuthnLoginFinishForm contains form fields for completing the passkey login.
+type WebAuthnLoginFinishForm struct {
+	// ClientID is the application id.
+	ClientId string `json:"client_id" form:"client_id" validate:"required"`
+
+	// Token is the ceremony token returned by the begin request.
+	Token string `json:"webauthn_token" form:"webauthn_token" validate:"required"`
+
+	// Remember is the option for the save user session in the cookie.
+	Remember bool `json:"remember" form:"remember"`
+
+	// Credential is the result of navigator.credentials.get().
+	Credential *webauthn.AssertionResponse `json:"credential" validate:"required"`
+}
+
+// WebAuthnBeginResponse contains the options for the WebAuthn browser api.
+type WebAuthnBeginResponse struct {
+	// Token is the ceremony token, it must be passed to the finish request.
+	Token string `json:"webauthn_token"`
+
+	// PublicKey is the options for navigator.credentials.create() or navigator.credentials.get().
+	PublicKey interface{} `json:"public_key"`
+}
+
+func (m *WebAuthnRegisterFinishForm) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("ClientID", m.ClientId)
+	enc.AddString("Token", "[HIDDEN]")
+	enc.AddString("Name", m.Name)
+
+	return nil
+}
+
+func (m *WebAuthnLoginFinishForm) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("ClientID", m.ClientId)
+	enc.AddString("Token", "[HIDDEN]")
+	enc.AddBool("Remember", m.Remember)
+
+	return nil
+}
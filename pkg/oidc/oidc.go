@@ -0,0 +1,122 @@
+// Package oidc contains the client side of OpenID Connect used by the identity providers of the "oidc" type:
+// the provider metadata is discovered by the issuer url, and the id tokens are verified against the keys
+// published by the provider.
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// DiscoveryTTL is the time the metadata of the provider is cached for.
+var DiscoveryTTL = time.Hour
+
+var ErrInvalidDiscovery = errors.New("invalid openid configuration")
+
+// Metadata is the part of the openid configuration of the provider used by the relying party.
+type Metadata struct {
+	Issuer                        string   `json:"issuer"`
+	AuthorizationEndpoint         string   `json:"authorization_endpoint"`
+	TokenEndpoint                 string   `json:"token_endpoint"`
+	UserInfoEndpoint              string   `json:"userinfo_endpoint"`
+	JWKSURI                       string   `json:"jwks_uri"`
+	CodeChallengeMethodsSupported []string `json:"code_challenge_methods_supported"`
+}
+
+// Provider is the discovered OpenID Connect provider.
+type Provider struct {
+	Metadata
+
+	client  *http.Client
+	keys    *keySet
+	expires time.Time
+}
+
+var cache = struct {
+	sync.Mutex
+	providers map[string]*Provider
+}{providers: map[string]*Provider{}}
+
+// Discover returns the provider of the issuer, the metadata is loaded from the well-known openid configuration
+// of the issuer and cached together with the keys for DiscoveryTTL.
+func Discover(ctx context.Context, issuer string) (*Provider, error) {
+	issuer = strings.TrimSuffix(issuer, "/")
+
+	cache.Lock()
+	p, ok := cache.providers[issuer]
+	cache.Unlock()
+	if ok && time.Now().Before(p.expires) {
+		return p, nil
+	}
+
+	p, err := discover(ctx, http.DefaultClient, issuer)
+	if err != nil {
+		return nil, err
+	}
+
+	cache.Lock()
+	cache.providers[issuer] = p
+	cache.Unlock()
+
+	return p, nil
+}
+
+func discover(ctx context.Context, client *http.Client, issuer string) (*Provider, error) {
+	p := &Provider{client: client, expires: time.Now().Add(DiscoveryTTL)}
+	if err := getJSON(ctx, client, issuer+"/.well-known/openid-configuration", &p.Metadata); err != nil {
+		return nil, errors.Wrap(err, "unable to load openid configuration")
+	}
+
+	// INFO: The issuer of the configuration must be the one it is loaded from, otherwise the tokens of the other
+	// provider would be accepted
+	if strings.TrimSuffix(p.Issuer, "/") != issuer {
+		return nil, errors.Wrapf(ErrInvalidDiscovery, "issuer %q doesn't match %q", p.Issuer, issuer)
+	}
+	if p.AuthorizationEndpoint == "" || p.TokenEndpoint == "" || p.JWKSURI == "" {
+		return nil, errors.Wrap(ErrInvalidDiscovery, "authorization, token and jwks endpoints are required")
+	}
+
+	p.keys = &keySet{url: p.JWKSURI, client: client}
+
+	return p, nil
+}
+
+// SupportsPKCE reports whether the provider accepts the S256 code challenge, the providers without
+// code_challenge_methods_supported are expected to ignore it.
+func (p *Provider) SupportsPKCE() bool {
+	if len(p.CodeChallengeMethodsSupported) == 0 {
+		return true
+	}
+	for _, m := range p.CodeChallengeMethodsSupported {
+		if m == "S256" {
+			return true
+		}
+	}
+	return false
+}
+
+func getJSON(ctx context.Context, client *http.Client, url string, v interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("request to %s failed with status %d", url, resp.StatusCode)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,158 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+)
+
+type testIssuer struct {
+	*httptest.Server
+	key *rsa.PrivateKey
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	i := &testIssuer{key: key}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(Metadata{
+			Issuer:                i.URL,
+			AuthorizationEndpoint: i.URL + "/auth",
+			TokenEndpoint:         i.URL + "/token",
+			UserInfoEndpoint:      i.URL + "/userinfo",
+			JWKSURI:               i.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"keys": []jsonWebKey{{
+			Kty: "RSA",
+			Kid: "key",
+			Use: "sig",
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer access" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"sub": "user", "email": "user@example.com"})
+	})
+	i.Server = httptest.NewServer(mux)
+
+	return i
+}
+
+func (i *testIssuer) token(t *testing.T, kid string, claims jwt.MapClaims) string {
+	base := jwt.MapClaims{
+		"iss":   i.URL,
+		"aud":   "client",
+		"sub":   "user",
+		"nonce": "nonce",
+		"exp":   time.Now().Add(time.Minute).Unix(),
+	}
+	for k, v := range claims {
+		if v == nil {
+			delete(base, k)
+			continue
+		}
+		base[k] = v
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, base)
+	token.Header["kid"] = kid
+	s, err := token.SignedString(i.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestVerifyAcceptsValidToken(t *testing.T) {
+	i := newTestIssuer(t)
+	defer i.Close()
+
+	p, err := Discover(context.Background(), i.URL+"/")
+	if assert.Nil(t, err) {
+		c, err := p.Verify(context.Background(), i.token(t, "key", jwt.MapClaims{"email": "user@example.com"}), "client", "nonce")
+		assert.Nil(t, err)
+		assert.Equal(t, "user@example.com", c.String("email"))
+	}
+}
+
+func TestVerifyRejectsInvalidTokens(t *testing.T) {
+	i := newTestIssuer(t)
+	defer i.Close()
+
+	p, err := Discover(context.Background(), i.URL)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	tokens := map[string]string{
+		"expired":         i.token(t, "key", jwt.MapClaims{"exp": time.Now().Add(-time.Minute).Unix()}),
+		"no expiration":   i.token(t, "key", jwt.MapClaims{"exp": nil}),
+		"other issuer":    i.token(t, "key", jwt.MapClaims{"iss": "https://example.com"}),
+		"other audience":  i.token(t, "key", jwt.MapClaims{"aud": []string{"other"}}),
+		"other party":     i.token(t, "key", jwt.MapClaims{"aud": []string{"client", "other"}, "azp": "other"}),
+		"other nonce":     i.token(t, "key", jwt.MapClaims{"nonce": "other"}),
+		"no subject":      i.token(t, "key", jwt.MapClaims{"sub": nil}),
+		"unknown key":     i.token(t, "other", nil),
+		"not a jwt token": "token",
+	}
+	for name, token := range tokens {
+		_, err := p.Verify(context.Background(), token, "client", "nonce")
+		assert.NotNil(t, err, name)
+	}
+
+	hs := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"iss": i.URL, "aud": "client", "sub": "user", "nonce": "nonce"})
+	s, _ := hs.SignedString([]byte("secret"))
+	_, err = p.Verify(context.Background(), s, "client", "nonce")
+	assert.NotNil(t, err)
+}
+
+func TestDiscoverRejectsOtherIssuer(t *testing.T) {
+	i := newTestIssuer(t)
+	defer i.Close()
+
+	_, err := discover(context.Background(), http.DefaultClient, i.URL+"/tenant")
+	assert.NotNil(t, err)
+}
+
+func TestUserInfoChecksSubject(t *testing.T) {
+	i := newTestIssuer(t)
+	defer i.Close()
+
+	p, err := Discover(context.Background(), i.URL)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	c, err := p.UserInfo(context.Background(), "access", "user")
+	assert.Nil(t, err)
+	assert.Equal(t, "user@example.com", c.String("email"))
+
+	_, err = p.UserInfo(context.Background(), "access", "other")
+	assert.NotNil(t, err)
+}
+
+func TestCodeChallenge(t *testing.T) {
+	r := &AuthRequest{CodeVerifier: "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"}
+	assert.Equal(t, "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM", r.CodeChallenge())
+}
@@ -0,0 +1,44 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+)
+
+// AuthRequest keeps the secrets of the authentication request till the callback of the provider.
+type AuthRequest struct {
+	// Nonce is sent with the request and must be returned in the id token.
+	Nonce string `json:"nonce"`
+
+	// CodeVerifier is the PKCE secret, only its S256 challenge is sent with the request.
+	CodeVerifier string `json:"code_verifier"`
+}
+
+// NewAuthRequest returns the request with the random nonce and code verifier.
+func NewAuthRequest() (*AuthRequest, error) {
+	nonce, err := randomString(16)
+	if err != nil {
+		return nil, err
+	}
+	verifier, err := randomString(32)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AuthRequest{Nonce: nonce, CodeVerifier: verifier}, nil
+}
+
+// CodeChallenge returns the S256 challenge of the code verifier.
+func (r *AuthRequest) CodeChallenge() string {
+	h := sha256.Sum256([]byte(r.CodeVerifier))
+	return base64.RawURLEncoding.EncodeToString(h[:])
+}
+
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
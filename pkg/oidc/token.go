@@ -0,0 +1,131 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/pkg/errors"
+)
+
+var ErrInvalidToken = errors.New("invalid id token")
+
+// signingMethods are the algorithms of the id tokens accepted, the symmetric ones are excluded as the client secret
+// isn't the key the provider signs with.
+var signingMethods = []string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}
+
+// Claims are the claims of the id token or the userinfo response.
+type Claims map[string]interface{}
+
+// String returns the claim of the string type, numbers are formatted, the other values are ignored.
+func (c Claims) String(name string) string {
+	switch v := c[name].(type) {
+	case string:
+		return v
+	case float64:
+		return fmt.Sprintf("%.0f", v)
+	}
+	return ""
+}
+
+// Bool returns the claim of the boolean type, the string "true" and "false" are accepted too as some providers
+// send them so, ok is false if the claim is missing.
+func (c Claims) Bool(name string) (value bool, ok bool) {
+	switch v := c[name].(type) {
+	case bool:
+		return v, true
+	case string:
+		return v == "true", v == "true" || v == "false"
+	}
+	return false, false
+}
+
+// Verify checks the signature of the id token by the keys of the provider, its expiration, the issuer, the audience
+// of the client and the nonce of the authentication request.
+func (p *Provider) Verify(ctx context.Context, raw, clientID, nonce string) (Claims, error) {
+	claims := jwt.MapClaims{}
+	parser := &jwt.Parser{ValidMethods: signingMethods}
+	_, err := parser.ParseWithClaims(raw, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return p.keys.key(ctx, kid)
+	})
+	if err != nil {
+		return nil, errors.Wrap(ErrInvalidToken, err.Error())
+	}
+
+	c := Claims(claims)
+	if _, ok := c["exp"].(float64); !ok {
+		return nil, errors.Wrap(ErrInvalidToken, "expiration is required")
+	}
+	if c.String("iss") != p.Issuer {
+		return nil, errors.Wrapf(ErrInvalidToken, "issuer %q isn't expected", c.String("iss"))
+	}
+	if !c.audience(clientID) {
+		return nil, errors.Wrap(ErrInvalidToken, "token isn't issued to the client")
+	}
+	if c.String("nonce") != nonce {
+		return nil, errors.Wrap(ErrInvalidToken, "nonce doesn't match")
+	}
+	if c.String("sub") == "" {
+		return nil, errors.Wrap(ErrInvalidToken, "subject is required")
+	}
+
+	return c, nil
+}
+
+// audience reports whether the token is issued to the client, the authorized party must be the client if the token
+// has several audiences.
+func (c Claims) audience(clientID string) bool {
+	switch aud := c["aud"].(type) {
+	case string:
+		return aud == clientID
+	case []interface{}:
+		found := false
+		for _, a := range aud {
+			found = found || a == clientID
+		}
+		if len(aud) > 1 {
+			if azp, ok := c["azp"]; ok && azp != clientID {
+				return false
+			}
+		}
+		return found
+	}
+	return false
+}
+
+// UserInfo returns the claims of the userinfo endpoint of the provider, the subject must be the one of the id token.
+func (p *Provider) UserInfo(ctx context.Context, accessToken, subject string) (Claims, error) {
+	if p.UserInfoEndpoint == "" {
+		return Claims{}, nil
+	}
+
+	req, err := http.NewRequest(http.MethodGet, p.UserInfoEndpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to load userinfo")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("userinfo request failed with status %d", resp.StatusCode)
+	}
+
+	var c Claims
+	if err := json.NewDecoder(resp.Body).Decode(&c); err != nil {
+		return nil, errors.Wrap(err, "unable to decode userinfo")
+	}
+	if c.String("sub") != subject {
+		return nil, errors.New("userinfo subject doesn't match the id token")
+	}
+
+	return c, nil
+}
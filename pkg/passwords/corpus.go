@@ -0,0 +1,214 @@
+// Package passwords checks the new passwords of the users against the password settings of the space.
+package passwords
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// maxLineLen is the length of the longest line of the corpus: the hash, ":" and the number of the breaches.
+const maxLineLen = 128
+
+// Corpus is the source of the breached passwords queried by the k-anonymity range, like the range api of
+// Have I Been Pwned: only the first 5 hex characters of the SHA-1 hash of the password are given to it.
+type Corpus interface {
+	// Range returns the uppercase suffixes (35 hex characters) of the SHA-1 hashes of the breached passwords
+	// starting with the uppercase prefix.
+	Range(prefix string) ([]string, error)
+}
+
+// FileCorpus is the corpus kept in the file of the hashes sorted in the ascending order, the range is found by
+// the binary search over the file, so the passwords are checked offline without loading the corpus into memory.
+type FileCorpus struct {
+	r      io.ReaderAt
+	size   int64
+	closer io.Closer
+}
+
+// LoadCorpus opens the file of the SHA-1 hashes of the breached passwords, one hash in hex per line sorted by
+// the hash like the download of Have I Been Pwned ordered by hash. The hash may be followed by ":" and the number
+// of the breaches. Only the first line is checked on the load, the others are checked when they're looked up.
+func LoadCorpus(path string) (*FileCorpus, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	c := &FileCorpus{r: f, size: info.Size(), closer: f}
+	_, line, err := c.line(0)
+	if err == nil && line != "" && !valid(hashOf(line)) {
+		err = errors.New("invalid SHA-1 hash on line 1")
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// ReadCorpus reads the corpus in the format of LoadCorpus into memory, the hashes don't have to be sorted.
+// It's meant for the small corpora, the large ones are opened by LoadCorpus.
+func ReadCorpus(r io.Reader) (*FileCorpus, error) {
+	var hashes []string
+
+	s := bufio.NewScanner(r)
+	line := 0
+	for s.Scan() {
+		line++
+		hash := hashOf(s.Text())
+		if hash == "" {
+			continue
+		}
+		if !valid(hash) {
+			return nil, errors.Errorf("invalid SHA-1 hash on line %d", line)
+		}
+		hashes = append(hashes, hash+"\n")
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Strings(hashes)
+	data := []byte(strings.Join(hashes, ""))
+
+	return &FileCorpus{r: bytes.NewReader(data), size: int64(len(data))}, nil
+}
+
+func (c *FileCorpus) Range(prefix string) ([]string, error) {
+	var err error
+	off := sort.Search(int(c.size), func(i int) bool {
+		if err != nil {
+			return true
+		}
+		var line string
+		if _, line, err = c.line(int64(i)); err != nil {
+			return true
+		}
+		hash := hashOf(line)
+		return hash == "" || hash >= prefix
+	})
+	if err != nil {
+		return nil, err
+	}
+	start, _, err := c.line(int64(off))
+	if err != nil {
+		return nil, err
+	}
+
+	var suffixes []string
+	s := bufio.NewScanner(io.NewSectionReader(c.r, start, c.size-start))
+	for s.Scan() {
+		hash := hashOf(s.Text())
+		if !strings.HasPrefix(hash, prefix) {
+			break
+		}
+		if !valid(hash) {
+			return nil, errors.Errorf("invalid SHA-1 hash %q", hash)
+		}
+		suffixes = append(suffixes, hash[len(prefix):])
+	}
+
+	return suffixes, s.Err()
+}
+
+// Close closes the file of the corpus opened by LoadCorpus.
+func (c *FileCorpus) Close() error {
+	if c.closer == nil {
+		return nil
+	}
+	return c.closer.Close()
+}
+
+// line returns the first line starting at the offset or after it, the empty line is returned at the end of the file.
+func (c *FileCorpus) line(off int64) (int64, string, error) {
+	start := off
+	if off > 0 {
+		i, err := c.index(off - 1)
+		if err != nil {
+			return 0, "", err
+		}
+		start = i + 1
+	}
+	if start >= c.size {
+		return c.size, "", nil
+	}
+
+	end, err := c.index(start)
+	if err != nil {
+		return 0, "", err
+	}
+	if end-start > maxLineLen {
+		return 0, "", errors.Errorf("line at %d is too long for the SHA-1 hash", start)
+	}
+	buf := make([]byte, end-start)
+	if _, err := c.r.ReadAt(buf, start); err != nil && err != io.EOF {
+		return 0, "", err
+	}
+
+	return start, string(buf), nil
+}
+
+// index returns the offset of the first newline at the offset or after it, the size is returned if there is none.
+func (c *FileCorpus) index(off int64) (int64, error) {
+	buf := make([]byte, maxLineLen)
+	for off < c.size {
+		n, err := c.r.ReadAt(buf, off)
+		if i := bytes.IndexByte(buf[:n], '\n'); i >= 0 {
+			return off + int64(i), nil
+		}
+		if err != nil && err != io.EOF {
+			return 0, err
+		}
+		if n == 0 {
+			break
+		}
+		off += int64(n)
+	}
+
+	return c.size, nil
+}
+
+// hashOf returns the uppercase hash of the line of the corpus without the number of the breaches.
+func hashOf(line string) string {
+	if i := strings.IndexByte(line, ':'); i >= 0 {
+		line = line[:i]
+	}
+	return strings.ToUpper(strings.TrimSpace(line))
+}
+
+func valid(hash string) bool {
+	_, err := hex.DecodeString(hash)
+	return err == nil && len(hash) == 2*sha1.Size
+}
+
+// Breached reports whether the password is found in the corpus.
+func Breached(c Corpus, password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	suffixes, err := c.Range(hash[:5])
+	if err != nil {
+		return false, err
+	}
+	for _, suffix := range suffixes {
+		if suffix == hash[5:] {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
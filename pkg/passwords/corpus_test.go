@@ -0,0 +1,87 @@
+package passwords
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const corpus = `5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8:3861493
+b1b3773a05c0ed0176787a4f1574ff0075f7521e
+
+`
+
+func TestBreachedFindsPasswordsOfCorpus(t *testing.T) {
+	c, err := ReadCorpus(strings.NewReader(corpus))
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	for password, breached := range map[string]bool{"password": true, "qwerty": true, "correct horse battery": false} {
+		found, err := Breached(c, password)
+		assert.Nil(t, err)
+		assert.Equal(t, breached, found, password)
+	}
+}
+
+func TestReadCorpusReturnErrorWithInvalidHash(t *testing.T) {
+	_, err := ReadCorpus(strings.NewReader("5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8\npassword\n"))
+	assert.EqualError(t, err, "invalid SHA-1 hash on line 2")
+}
+
+func TestLoadCorpusSearchesSortedFile(t *testing.T) {
+	f, err := ioutil.TempFile("", "corpus")
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(`000000005AD76BD555C1D6D771DE417A4B87E4B4:4
+5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD7:1
+5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8:3861493
+5BAA7000000000000000000000000000000000AA:2
+b1b3773a05c0ed0176787a4f1574ff0075f7521e:1
+FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF:1
+`)
+	f.Close()
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	c, err := LoadCorpus(f.Name())
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer c.Close()
+
+	suffixes, err := c.Range("5BAA6")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"1E4C9B93F3F0682250B6CF8331B7EE68FD7", "1E4C9B93F3F0682250B6CF8331B7EE68FD8"}, suffixes)
+
+	for prefix, n := range map[string]int{"00000": 1, "12345": 0, "B1B37": 1, "FFFFF": 1} {
+		suffixes, err := c.Range(prefix)
+		assert.Nil(t, err)
+		assert.Len(t, suffixes, n, prefix)
+	}
+
+	for password, breached := range map[string]bool{"password": true, "qwerty": true, "correct horse battery": false} {
+		found, err := Breached(c, password)
+		assert.Nil(t, err)
+		assert.Equal(t, breached, found, password)
+	}
+}
+
+func TestLoadCorpusReturnErrorWithInvalidFile(t *testing.T) {
+	f, err := ioutil.TempFile("", "corpus")
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("8846F7EAEE8FB117AD06BDD830B7586C:1\n")
+	f.Close()
+
+	_, err = LoadCorpus(f.Name())
+	assert.EqualError(t, err, "invalid SHA-1 hash on line 1")
+}
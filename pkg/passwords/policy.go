@@ -0,0 +1,57 @@
+package passwords
+
+import (
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/hasher"
+)
+
+// Check returns the reason the new password is rejected by the settings, the empty reason means the password
+// is accepted. The breached passwords are looked up in the corpus, nil corpus accepts any password. The history
+// contains the hashes of the current and the previous passwords of the identity, the current one first.
+func Check(s entity.PasswordSettings, corpus Corpus, password string, history []string) (string, error) {
+	if reason := s.Check(password); reason != "" {
+		return reason, nil
+	}
+
+	if s.RejectBreached && corpus != nil {
+		breached, err := Breached(corpus, password)
+		if err != nil {
+			return "", err
+		}
+		if breached {
+			return entity.PasswordBreached, nil
+		}
+	}
+
+	if s.HistorySize <= 0 {
+		history = nil
+	} else if len(history) > s.HistorySize {
+		history = history[:s.HistorySize]
+	}
+	for _, hash := range history {
+		if hash != "" && hasher.Verify(hash, password) == nil {
+			return entity.PasswordReused, nil
+		}
+	}
+
+	return "", nil
+}
+
+// History returns the hashes of the previous passwords kept with the identity after the password is changed:
+// the replaced hash is prepended to them, and only the ones checked with the current hash are kept.
+func History(s entity.PasswordSettings, replaced string, previous []string) []string {
+	if s.HistorySize <= 1 {
+		return nil
+	}
+
+	var result []string
+	if replaced != "" {
+		result = append(result, replaced)
+	}
+	result = append(result, previous...)
+	if len(result) > s.HistorySize-1 {
+		result = result[:s.HistorySize-1]
+	}
+
+	return result
+}
@@ -0,0 +1,72 @@
+package passwords
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/hasher"
+	"github.com/stretchr/testify/assert"
+)
+
+var settings = entity.PasswordSettings{Algorithm: entity.PasswordAlgorithmBcrypt, BcryptCost: 4, Min: 4, Max: 30, RequireNumber: true}
+
+func hash(t *testing.T, password string) string {
+	h, err := hasher.New(settings)
+	assert.Nil(t, err)
+	hash, err := h.Hash(password)
+	assert.Nil(t, err)
+	return hash
+}
+
+func TestCheckReturnReasonOfPolicy(t *testing.T) {
+	for password, reason := range map[string]string{"a1": entity.PasswordTooShort, "abcdef": entity.PasswordNumberRequired, "abcdef1": ""} {
+		r, err := Check(settings, nil, password, nil)
+		assert.Nil(t, err)
+		assert.Equal(t, reason, r, password)
+	}
+}
+
+func TestCheckRejectsBreachedPasswords(t *testing.T) {
+	c, err := ReadCorpus(strings.NewReader("6367C48DD193D56EA7B0BAAD25B19455E529F5EE\n")) // abc123
+	assert.Nil(t, err)
+
+	s := settings
+	reason, err := Check(s, c, "abc123", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "", reason)
+
+	s.RejectBreached = true
+	reason, err = Check(s, c, "abc123", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, entity.PasswordBreached, reason)
+}
+
+func TestCheckRejectsPasswordsOfHistory(t *testing.T) {
+	history := []string{hash(t, "current1"), hash(t, "previous1"), hash(t, "oldest1")}
+
+	s := settings
+	s.HistorySize = 2
+	for password, reason := range map[string]string{"current1": entity.PasswordReused, "previous1": entity.PasswordReused, "oldest1": ""} {
+		r, err := Check(s, nil, password, history)
+		assert.Nil(t, err)
+		assert.Equal(t, reason, r, password)
+	}
+
+	for _, size := range []int{0, -1} {
+		s.HistorySize = size
+		reason, err := Check(s, nil, "current1", history)
+		assert.Nil(t, err)
+		assert.Equal(t, "", reason)
+		assert.Nil(t, History(s, history[0], history[1:]))
+	}
+}
+
+func TestHistoryKeepsPreviousHashes(t *testing.T) {
+	s := settings
+	assert.Nil(t, History(s, "c", []string{"b", "a"}))
+
+	s.HistorySize = 3
+	assert.Equal(t, []string{"c", "b"}, History(s, "c", []string{"b", "a"}))
+	assert.Equal(t, []string{"b", "a"}, History(s, "", []string{"b", "a"}))
+}
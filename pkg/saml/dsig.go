@@ -0,0 +1,180 @@
+package saml
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	_ "crypto/sha1"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	nsDSig = "http://www.w3.org/2000/09/xmldsig#"
+
+	algExcC14N             = "http://www.w3.org/2001/10/xml-exc-c14n#"
+	algExcC14NWithComments = algExcC14N + "WithComments"
+	algEnvelopedSignature  = nsDSig + "enveloped-signature"
+	algRSASHA256           = "http://www.w3.org/2001/04/xmldsig-more#rsa-sha256"
+)
+
+var (
+	ErrNotSigned        = errors.New("element isn't signed")
+	ErrInvalidSignature = errors.New("invalid signature")
+)
+
+var signatureMethods = map[string]crypto.Hash{
+	nsDSig + "rsa-sha1": crypto.SHA1,
+	algRSASHA256:        crypto.SHA256,
+	"http://www.w3.org/2001/04/xmldsig-more#rsa-sha512": crypto.SHA512,
+}
+
+var digestMethods = map[string]crypto.Hash{
+	nsDSig + "sha1": crypto.SHA1,
+	"http://www.w3.org/2001/04/xmlenc#sha256": crypto.SHA256,
+	"http://www.w3.org/2001/04/xmlenc#sha512": crypto.SHA512,
+}
+
+// verifySignature checks the enveloped signature of the element is made by the certificate. The signature must be
+// the child of the element and reference the element by its ID, so the element checked is the element signed and
+// the signed content can't be moved elsewhere in the document. The key info of the signature is ignored, only the
+// configured certificate is trusted.
+func verifySignature(e *element, cert *x509.Certificate) error {
+	signatures := e.elements(nsDSig, "Signature")
+	if len(signatures) == 0 {
+		return ErrNotSigned
+	}
+	if len(signatures) > 1 {
+		return errors.Wrap(ErrInvalidSignature, "several signatures")
+	}
+	sig := signatures[0]
+
+	info := sig.element(nsDSig, "SignedInfo")
+	if info == nil {
+		return errors.Wrap(ErrInvalidSignature, "signed info is missing")
+	}
+	infoC14N, err := newCanonicalizer(info.element(nsDSig, "CanonicalizationMethod"))
+	if err != nil {
+		return err
+	}
+	method := info.element(nsDSig, "SignatureMethod")
+	if method == nil {
+		return errors.Wrap(ErrInvalidSignature, "signature method is missing")
+	}
+	hash, ok := signatureMethods[method.attr("Algorithm")]
+	if !ok {
+		return errors.Wrapf(ErrInvalidSignature, "signature method %q isn't supported", method.attr("Algorithm"))
+	}
+
+	refs := info.elements(nsDSig, "Reference")
+	if len(refs) != 1 {
+		return errors.Wrap(ErrInvalidSignature, "exactly one reference is expected")
+	}
+	ref := refs[0]
+	if id := e.attr("ID"); id == "" || ref.attr("URI") != "#"+id {
+		return errors.Wrap(ErrInvalidSignature, "reference doesn't point to the signed element")
+	}
+	refC14N, err := referenceCanonicalizer(ref)
+	if err != nil {
+		return err
+	}
+	refC14N.skip = sig
+
+	digestMethod := ref.element(nsDSig, "DigestMethod")
+	if digestMethod == nil {
+		return errors.Wrap(ErrInvalidSignature, "digest method is missing")
+	}
+	digestHash, ok := digestMethods[digestMethod.attr("Algorithm")]
+	if !ok {
+		return errors.Wrapf(ErrInvalidSignature, "digest method %q isn't supported", digestMethod.attr("Algorithm"))
+	}
+	digest, err := decodeBase64(ref.element(nsDSig, "DigestValue"))
+	if err != nil {
+		return err
+	}
+
+	content, err := refC14N.canonicalize(e)
+	if err != nil {
+		return errors.Wrap(ErrInvalidSignature, err.Error())
+	}
+	h := digestHash.New()
+	h.Write(content)
+	if !hmac.Equal(h.Sum(nil), digest) {
+		return errors.Wrap(ErrInvalidSignature, "digest doesn't match")
+	}
+
+	signature, err := decodeBase64(sig.element(nsDSig, "SignatureValue"))
+	if err != nil {
+		return err
+	}
+	signed, err := infoC14N.canonicalize(info)
+	if err != nil {
+		return errors.Wrap(ErrInvalidSignature, err.Error())
+	}
+	key, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return errors.Wrap(ErrInvalidSignature, "certificate key isn't rsa")
+	}
+	h = hash.New()
+	h.Write(signed)
+	if err := rsa.VerifyPKCS1v15(key, hash, h.Sum(nil), signature); err != nil {
+		return errors.Wrap(ErrInvalidSignature, err.Error())
+	}
+
+	return nil
+}
+
+// referenceCanonicalizer returns the canonicalizer of the transforms of the reference, only the enveloped signature
+// followed by the exclusive canonicalization is accepted.
+func referenceCanonicalizer(ref *element) (*canonicalizer, error) {
+	var transforms []*element
+	if t := ref.element(nsDSig, "Transforms"); t != nil {
+		transforms = t.elements(nsDSig, "Transform")
+	}
+	if len(transforms) != 2 || transforms[0].attr("Algorithm") != algEnvelopedSignature {
+		return nil, errors.Wrap(ErrInvalidSignature, "enveloped signature and canonicalization transforms are expected")
+	}
+	return newCanonicalizer(transforms[1])
+}
+
+func newCanonicalizer(method *element) (*canonicalizer, error) {
+	if method == nil {
+		return nil, errors.Wrap(ErrInvalidSignature, "canonicalization method is missing")
+	}
+
+	c := &canonicalizer{}
+	switch method.attr("Algorithm") {
+	case algExcC14N:
+	case algExcC14NWithComments:
+		c.comments = true
+	default:
+		return nil, errors.Wrapf(ErrInvalidSignature, "canonicalization %q isn't supported", method.attr("Algorithm"))
+	}
+
+	if ns := method.element(algExcC14N, "InclusiveNamespaces"); ns != nil {
+		for _, p := range strings.Fields(ns.attr("PrefixList")) {
+			if p == "#default" {
+				p = ""
+			}
+			c.inclusive = append(c.inclusive, p)
+		}
+	}
+
+	return c, nil
+}
+
+func decodeBase64(e *element) ([]byte, error) {
+	if e == nil {
+		return nil, errors.Wrap(ErrInvalidSignature, "value is missing")
+	}
+	b, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(e.text()), ""))
+	if err != nil {
+		return nil, errors.Wrap(ErrInvalidSignature, err.Error())
+	}
+	return b, nil
+}
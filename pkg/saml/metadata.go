@@ -0,0 +1,63 @@
+package saml
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+)
+
+type entityDescriptor struct {
+	XMLName         xml.Name        `xml:"urn:oasis:names:tc:SAML:2.0:metadata EntityDescriptor"`
+	EntityID        string          `xml:"entityID,attr"`
+	SPSSODescriptor spSSODescriptor `xml:"SPSSODescriptor"`
+}
+
+type spSSODescriptor struct {
+	AuthnRequestsSigned        bool                       `xml:"AuthnRequestsSigned,attr"`
+	WantAssertionsSigned       bool                       `xml:"WantAssertionsSigned,attr"`
+	ProtocolSupportEnumeration string                     `xml:"protocolSupportEnumeration,attr"`
+	KeyDescriptor              *keyDescriptor             `xml:"KeyDescriptor,omitempty"`
+	AssertionConsumerService   []assertionConsumerService `xml:"AssertionConsumerService"`
+}
+
+type keyDescriptor struct {
+	Use             string `xml:"use,attr"`
+	X509Certificate string `xml:"http://www.w3.org/2000/09/xmldsig# KeyInfo>X509Data>X509Certificate"`
+}
+
+type assertionConsumerService struct {
+	Binding  string `xml:"Binding,attr"`
+	Location string `xml:"Location,attr"`
+	Index    int    `xml:"index,attr"`
+}
+
+// Metadata returns the metadata of the service with the assertion consumer services of the identity providers,
+// the certificate of the key is published to verify the signatures of the authentication requests.
+func (sp *ServiceProvider) Metadata(acsURLs []string) ([]byte, error) {
+	d := &entityDescriptor{
+		EntityID: sp.EntityID,
+		SPSSODescriptor: spSSODescriptor{
+			AuthnRequestsSigned:        true,
+			WantAssertionsSigned:       true,
+			ProtocolSupportEnumeration: nsProtocol,
+		},
+	}
+	if sp.Key != nil {
+		d.SPSSODescriptor.KeyDescriptor = &keyDescriptor{
+			Use:             "signing",
+			X509Certificate: base64.StdEncoding.EncodeToString(sp.Key.Certificate.Raw),
+		}
+	}
+	for i, u := range acsURLs {
+		d.SPSSODescriptor.AssertionConsumerService = append(d.SPSSODescriptor.AssertionConsumerService, assertionConsumerService{
+			Binding:  bindingHTTPPost,
+			Location: u,
+			Index:    i,
+		})
+	}
+
+	data, err := xml.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
@@ -0,0 +1,86 @@
+package saml
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/xml"
+	"net/url"
+	"strings"
+	"time"
+)
+
+type authnRequest struct {
+	XMLName                     xml.Name     `xml:"urn:oasis:names:tc:SAML:2.0:protocol AuthnRequest"`
+	ID                          string       `xml:"ID,attr"`
+	Version                     string       `xml:"Version,attr"`
+	IssueInstant                string       `xml:"IssueInstant,attr"`
+	Destination                 string       `xml:"Destination,attr"`
+	AssertionConsumerServiceURL string       `xml:"AssertionConsumerServiceURL,attr"`
+	ProtocolBinding             string       `xml:"ProtocolBinding,attr"`
+	Issuer                      issuer       `xml:"urn:oasis:names:tc:SAML:2.0:assertion Issuer"`
+	NameIDPolicy                nameIDPolicy `xml:"NameIDPolicy"`
+}
+
+type issuer struct {
+	Value string `xml:",chardata"`
+}
+
+type nameIDPolicy struct {
+	AllowCreate bool `xml:"AllowCreate,attr"`
+}
+
+// AuthnRequestURL returns the url of the single sign-on service with the authentication request by
+// the HTTP-Redirect binding, the identity provider posts the response to the assertion consumer service url.
+// The request is signed by the key of the service, ErrKeyRequired is returned without it. The relay state is
+// returned with the response unchanged.
+func (sp *ServiceProvider) AuthnRequestURL(idp *IdentityProvider, acsURL, requestID, relayState string) (string, error) {
+	if sp.Key == nil {
+		return "", ErrKeyRequired
+	}
+
+	data, err := xml.Marshal(&authnRequest{
+		ID:                          requestID,
+		Version:                     "2.0",
+		IssueInstant:                now().UTC().Format(time.RFC3339),
+		Destination:                 idp.SSOURL,
+		AssertionConsumerServiceURL: acsURL,
+		ProtocolBinding:             bindingHTTPPost,
+		Issuer:                      issuer{Value: sp.EntityID},
+		NameIDPolicy:                nameIDPolicy{AllowCreate: true},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	w, _ := flate.NewWriter(&buf, flate.DefaultCompression)
+	if _, err := w.Write(data); err != nil {
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+
+	// INFO: The signature of the redirect binding covers the parameters in this order as they are encoded
+	query := "SAMLRequest=" + url.QueryEscape(base64.StdEncoding.EncodeToString(buf.Bytes()))
+	if relayState != "" {
+		query += "&RelayState=" + url.QueryEscape(relayState)
+	}
+	query += "&SigAlg=" + url.QueryEscape(algRSASHA256)
+	h := crypto.SHA256.New()
+	h.Write([]byte(query))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, sp.Key.Key, crypto.SHA256, h.Sum(nil))
+	if err != nil {
+		return "", err
+	}
+	query += "&Signature=" + url.QueryEscape(base64.StdEncoding.EncodeToString(sig))
+
+	if strings.Contains(idp.SSOURL, "?") {
+		return idp.SSOURL + "&" + query, nil
+	}
+	return idp.SSOURL + "?" + query, nil
+}
@@ -0,0 +1,212 @@
+package saml
+
+import (
+	"encoding/base64"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	statusSuccess = "urn:oasis:names:tc:SAML:2.0:status:Success"
+	methodBearer  = "urn:oasis:names:tc:SAML:2.0:cm:bearer"
+)
+
+// MaxClockSkew is the allowed difference between the clocks of the identity provider and the service.
+var MaxClockSkew = 3 * time.Minute
+
+var ErrInvalidResponse = errors.New("invalid saml response")
+
+var now = time.Now
+
+// Assertion is the verified assertion about the user.
+type Assertion struct {
+	NameID       string
+	NameIDFormat string
+	SessionIndex string
+	Attributes   map[string][]string
+}
+
+// Claims returns the name id and the attributes as the document the claim mapping selects the profile in.
+// The attribute having the only value is the string, the one having several values is the array.
+func (a *Assertion) Claims() map[string]interface{} {
+	c := map[string]interface{}{"name_id": a.NameID}
+	for name, values := range a.Attributes {
+		if len(values) == 1 {
+			c[name] = values[0]
+			continue
+		}
+		list := make([]interface{}, len(values))
+		for i := range values {
+			list[i] = values[i]
+		}
+		c[name] = list
+	}
+	return c
+}
+
+// Attribute returns the first value of the first attribute found by the names.
+func (a *Assertion) Attribute(names ...string) string {
+	for _, name := range names {
+		if values := a.Attributes[name]; len(values) > 0 {
+			return values[0]
+		}
+	}
+	return ""
+}
+
+// ParseResponse decodes the response of the HTTP-POST binding to the authentication request and verifies it:
+// either the response or the assertion must be signed by the identity provider, the response must be successful
+// and sent in response to the request, and the assertion must be issued by the identity provider for the service
+// and be valid now.
+func (sp *ServiceProvider) ParseResponse(idp *IdentityProvider, acsURL, encoded, requestID string) (*Assertion, error) {
+	data, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(encoded), ""))
+	if err != nil {
+		return nil, errors.Wrap(ErrInvalidResponse, err.Error())
+	}
+	root, err := parseXML(data)
+	if err != nil {
+		return nil, errors.Wrap(ErrInvalidResponse, err.Error())
+	}
+
+	if !root.is(nsProtocol, "Response") {
+		return nil, errors.Wrap(ErrInvalidResponse, "response is expected")
+	}
+	if d := root.attr("Destination"); d != "" && d != acsURL {
+		return nil, errors.Wrapf(ErrInvalidResponse, "destination %q isn't expected", d)
+	}
+	if root.attr("InResponseTo") != requestID {
+		return nil, errors.Wrap(ErrInvalidResponse, "response isn't sent in response to the request")
+	}
+	if i := root.element(nsAssertion, "Issuer"); i != nil && i.text() != idp.EntityID {
+		return nil, errors.Wrapf(ErrInvalidResponse, "issuer %q isn't expected", i.text())
+	}
+
+	responseSigned := true
+	if err := verifySignature(root, idp.Certificate); err == ErrNotSigned {
+		responseSigned = false
+	} else if err != nil {
+		return nil, err
+	}
+
+	status := root.element(nsProtocol, "Status")
+	if status == nil {
+		return nil, errors.Wrap(ErrInvalidResponse, "status is missing")
+	}
+	if code := status.element(nsProtocol, "StatusCode"); code == nil || code.attr("Value") != statusSuccess {
+		msg := ""
+		if m := status.element(nsProtocol, "StatusMessage"); m != nil {
+			msg = m.text()
+		}
+		return nil, errors.Wrapf(ErrInvalidResponse, "authentication failed: %s", msg)
+	}
+
+	if len(root.elements(nsAssertion, "EncryptedAssertion")) > 0 {
+		return nil, errors.Wrap(ErrInvalidResponse, "encrypted assertions aren't supported")
+	}
+	assertions := root.elements(nsAssertion, "Assertion")
+	if len(assertions) != 1 {
+		return nil, errors.Wrap(ErrInvalidResponse, "exactly one assertion is expected")
+	}
+	assertion := assertions[0]
+
+	if err := verifySignature(assertion, idp.Certificate); err == ErrNotSigned {
+		if !responseSigned {
+			return nil, errors.Wrap(ErrInvalidResponse, "neither response nor assertion is signed")
+		}
+	} else if err != nil {
+		return nil, err
+	}
+
+	return sp.checkAssertion(assertion, idp, acsURL, requestID)
+}
+
+func (sp *ServiceProvider) checkAssertion(e *element, idp *IdentityProvider, acsURL, requestID string) (*Assertion, error) {
+	t := now()
+
+	if i := e.element(nsAssertion, "Issuer"); i == nil || i.text() != idp.EntityID {
+		return nil, errors.Wrap(ErrInvalidResponse, "assertion isn't issued by the identity provider")
+	}
+
+	subject := e.element(nsAssertion, "Subject")
+	if subject == nil {
+		return nil, errors.Wrap(ErrInvalidResponse, "subject is missing")
+	}
+	nameID := subject.element(nsAssertion, "NameID")
+	if nameID == nil || nameID.text() == "" {
+		return nil, errors.Wrap(ErrInvalidResponse, "name id is missing")
+	}
+
+	confirmed := false
+	for _, c := range subject.elements(nsAssertion, "SubjectConfirmation") {
+		data := c.element(nsAssertion, "SubjectConfirmationData")
+		if c.attr("Method") != methodBearer || data == nil {
+			continue
+		}
+		if data.attr("Recipient") != acsURL || data.attr("InResponseTo") != requestID {
+			continue
+		}
+		if notOnOrAfter, err := parseTime(data.attr("NotOnOrAfter")); err != nil || !t.Before(notOnOrAfter.Add(MaxClockSkew)) {
+			continue
+		}
+		confirmed = true
+	}
+	if !confirmed {
+		return nil, errors.Wrap(ErrInvalidResponse, "subject isn't confirmed for the service")
+	}
+
+	conditions := e.element(nsAssertion, "Conditions")
+	if conditions == nil {
+		return nil, errors.Wrap(ErrInvalidResponse, "conditions are missing")
+	}
+	if v := conditions.attr("NotBefore"); v != "" {
+		notBefore, err := parseTime(v)
+		if err != nil || t.Add(MaxClockSkew).Before(notBefore) {
+			return nil, errors.Wrap(ErrInvalidResponse, "assertion isn't valid yet")
+		}
+	}
+	if v := conditions.attr("NotOnOrAfter"); v != "" {
+		notOnOrAfter, err := parseTime(v)
+		if err != nil || !t.Before(notOnOrAfter.Add(MaxClockSkew)) {
+			return nil, errors.Wrap(ErrInvalidResponse, "assertion is expired")
+		}
+	}
+	restrictions := conditions.elements(nsAssertion, "AudienceRestriction")
+	if len(restrictions) == 0 {
+		return nil, errors.Wrap(ErrInvalidResponse, "audience restriction is missing")
+	}
+	// INFO: Each restriction must be met, the restriction is met if the service is one of its audiences
+	for _, r := range restrictions {
+		met := false
+		for _, a := range r.elements(nsAssertion, "Audience") {
+			met = met || a.text() == sp.EntityID
+		}
+		if !met {
+			return nil, errors.Wrap(ErrInvalidResponse, "assertion isn't issued to the service")
+		}
+	}
+
+	a := &Assertion{
+		NameID:       nameID.text(),
+		NameIDFormat: nameID.attr("Format"),
+		Attributes:   map[string][]string{},
+	}
+	if s := e.element(nsAssertion, "AuthnStatement"); s != nil {
+		a.SessionIndex = s.attr("SessionIndex")
+	}
+	for _, s := range e.elements(nsAssertion, "AttributeStatement") {
+		for _, attr := range s.elements(nsAssertion, "Attribute") {
+			name := attr.attr("Name")
+			for _, v := range attr.elements(nsAssertion, "AttributeValue") {
+				a.Attributes[name] = append(a.Attributes[name], v.text())
+			}
+		}
+	}
+
+	return a, nil
+}
+
+func parseTime(s string) (time.Time, error) {
+	return time.Parse(time.RFC3339Nano, s)
+}
@@ -0,0 +1,98 @@
+// Package saml contains the service provider side of SAML 2.0 used by the identity providers of the "saml" type:
+// the authentication requests are sent by the HTTP-Redirect binding and signed by the key of the service, the
+// responses are received by the HTTP-POST binding and their assertions are verified by the certificate of the
+// identity provider.
+package saml
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	nsProtocol  = "urn:oasis:names:tc:SAML:2.0:protocol"
+	nsAssertion = "urn:oasis:names:tc:SAML:2.0:assertion"
+
+	bindingHTTPPost = "urn:oasis:names:tc:SAML:2.0:bindings:HTTP-POST"
+)
+
+// ErrKeyRequired is returned for the authentication request of the service without the key pair.
+var ErrKeyRequired = errors.New("saml key pair is required to sign the authentication requests")
+
+// KeyPair is the key the service signs the authentication requests with and its certificate published
+// in the metadata.
+type KeyPair struct {
+	Key         *rsa.PrivateKey
+	Certificate *x509.Certificate
+}
+
+// LoadKeyPair reads the PEM encoded rsa key and certificate.
+func LoadKeyPair(keyFile, certFile string) (*KeyPair, error) {
+	pair, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to load saml key pair")
+	}
+	key, ok := pair.PrivateKey.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("saml key must be rsa key")
+	}
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to parse saml certificate")
+	}
+
+	return &KeyPair{Key: key, Certificate: cert}, nil
+}
+
+// ParseCertificate parses the certificate either PEM encoded or as the base64 of DER as it is written
+// in the metadata of the identity provider.
+func ParseCertificate(s string) (*x509.Certificate, error) {
+	s = strings.TrimSpace(s)
+	if b, _ := pem.Decode([]byte(s)); b != nil {
+		return x509.ParseCertificate(b.Bytes)
+	}
+
+	der, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(s), ""))
+	if err != nil {
+		return nil, errors.Wrap(err, "certificate is neither PEM nor base64")
+	}
+	return x509.ParseCertificate(der)
+}
+
+// ServiceProvider is the service as the relying party of the identity providers of the space.
+type ServiceProvider struct {
+	// EntityID is the identifier of the service, the identity providers issue the assertions to it.
+	EntityID string
+
+	// Key signs the authentication requests, they are never sent without it.
+	Key *KeyPair
+}
+
+// IdentityProvider is the configuration of the identity provider.
+type IdentityProvider struct {
+	// EntityID is the identifier of the identity provider, the issuer of the responses and the assertions.
+	EntityID string
+
+	// SSOURL is the single sign-on service location of the HTTP-Redirect binding.
+	SSOURL string
+
+	// Certificate is the certificate the identity provider signs the responses or the assertions with.
+	Certificate *x509.Certificate
+}
+
+// NewRequestID returns the random identifier of the authentication request, it must not start with a digit.
+func NewRequestID() (string, error) {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "id-" + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,238 @@
+package saml
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"io/ioutil"
+	"math/big"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testACS      = "https://auth.example.com/api/providers/corp/callback"
+	testEntityID = "https://auth.example.com/api/spaces/space/saml/metadata"
+	testIssuer   = "https://idp.example.com"
+	testRequest  = "id-request"
+)
+
+func newKeyPair(t *testing.T) *KeyPair {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &KeyPair{Key: key, Certificate: cert}
+}
+
+// sign inserts the enveloped signature of the element with the ID after its first child.
+func sign(t *testing.T, doc, id string, key *rsa.PrivateKey) string {
+	root, err := parseXML([]byte(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var find func(e *element) *element
+	find = func(e *element) *element {
+		if e.attr("ID") == id {
+			return e
+		}
+		for _, c := range e.children {
+			if c, ok := c.(*element); ok {
+				if f := find(c); f != nil {
+					return f
+				}
+			}
+		}
+		return nil
+	}
+	e := find(root)
+	content, err := (&canonicalizer{inclusive: []string{"xs"}}).canonicalize(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := sha256.Sum256(content)
+
+	refs := `<ds:CanonicalizationMethod Algorithm="http://www.w3.org/2001/10/xml-exc-c14n#"/>` +
+		`<ds:SignatureMethod Algorithm="http://www.w3.org/2001/04/xmldsig-more#rsa-sha256"/>` +
+		`<ds:Reference URI="#` + id + `"><ds:Transforms>` +
+		`<ds:Transform Algorithm="http://www.w3.org/2000/09/xmldsig#enveloped-signature"/>` +
+		`<ds:Transform Algorithm="http://www.w3.org/2001/10/xml-exc-c14n#"><ec:InclusiveNamespaces xmlns:ec="http://www.w3.org/2001/10/xml-exc-c14n#" PrefixList="xs"/></ds:Transform>` +
+		`</ds:Transforms><ds:DigestMethod Algorithm="http://www.w3.org/2001/04/xmlenc#sha256"/>` +
+		`<ds:DigestValue>` + base64.StdEncoding.EncodeToString(digest[:]) + `</ds:DigestValue></ds:Reference>`
+	info, err := parseXML([]byte(`<ds:SignedInfo xmlns:ds="http://www.w3.org/2000/09/xmldsig#">` + refs + `</ds:SignedInfo>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed, err := (&canonicalizer{}).canonicalize(info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := sha256.Sum256(signed)
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, h[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature := `<ds:Signature xmlns:ds="http://www.w3.org/2000/09/xmldsig#"><ds:SignedInfo>` + refs + `</ds:SignedInfo>` +
+		`<ds:SignatureValue>` + base64.StdEncoding.EncodeToString(sig) + `</ds:SignatureValue></ds:Signature>`
+
+	// INFO: The signature follows the issuer, the first child of the response and the assertion
+	start := strings.Index(doc, `ID="`+id+`"`)
+	end := start + strings.Index(doc[start:], "</saml:Issuer>") + len("</saml:Issuer>")
+	return doc[:end] + signature + doc[end:]
+}
+
+func testAssertion(notOnOrAfter time.Time, audience string) string {
+	return `<saml:Assertion xmlns:saml="urn:oasis:names:tc:SAML:2.0:assertion" xmlns:xs="http://www.w3.org/2001/XMLSchema" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" ID="id-assertion" Version="2.0" IssueInstant="` + time.Now().UTC().Format(time.RFC3339) + `">` +
+		`<saml:Issuer>` + testIssuer + `</saml:Issuer>` +
+		`<saml:Subject><saml:NameID Format="urn:oasis:names:tc:SAML:1.1:nameid-format:emailAddress">user@example.com</saml:NameID>` +
+		`<saml:SubjectConfirmation Method="urn:oasis:names:tc:SAML:2.0:cm:bearer"><saml:SubjectConfirmationData InResponseTo="` + testRequest + `" NotOnOrAfter="` + notOnOrAfter.UTC().Format(time.RFC3339) + `" Recipient="` + testACS + `"/></saml:SubjectConfirmation></saml:Subject>` +
+		`<saml:Conditions NotBefore="` + time.Now().Add(-time.Minute).UTC().Format(time.RFC3339) + `" NotOnOrAfter="` + notOnOrAfter.UTC().Format(time.RFC3339) + `">` +
+		`<saml:AudienceRestriction><saml:Audience>` + audience + `</saml:Audience></saml:AudienceRestriction></saml:Conditions>` +
+		`<saml:AuthnStatement AuthnInstant="` + time.Now().UTC().Format(time.RFC3339) + `" SessionIndex="session"/>` +
+		`<saml:AttributeStatement>
+			<saml:Attribute Name="mail"><saml:AttributeValue xsi:type="xs:string">user@example.com</saml:AttributeValue></saml:Attribute>
+			<saml:Attribute Name="groups"><saml:AttributeValue xsi:type="xs:string">admins</saml:AttributeValue><saml:AttributeValue xsi:type="xs:string">users &amp; guests</saml:AttributeValue></saml:Attribute>
+		</saml:AttributeStatement>` +
+		`</saml:Assertion>`
+}
+
+func testResponse(assertion string) string {
+	return `<?xml version="1.0" encoding="UTF-8"?>` +
+		`<samlp:Response xmlns:samlp="urn:oasis:names:tc:SAML:2.0:protocol" xmlns:saml="urn:oasis:names:tc:SAML:2.0:assertion" ID="id-response" Version="2.0" IssueInstant="` + time.Now().UTC().Format(time.RFC3339) + `" Destination="` + testACS + `" InResponseTo="` + testRequest + `">` +
+		`<saml:Issuer>` + testIssuer + `</saml:Issuer>` +
+		`<samlp:Status><samlp:StatusCode Value="urn:oasis:names:tc:SAML:2.0:status:Success"/></samlp:Status>` +
+		assertion +
+		`</samlp:Response>`
+}
+
+func encode(s string) string {
+	return base64.StdEncoding.EncodeToString([]byte(s))
+}
+
+func TestParseResponseAcceptsSignedAssertion(t *testing.T) {
+	idpKey := newKeyPair(t)
+	sp := &ServiceProvider{EntityID: testEntityID}
+	idp := &IdentityProvider{EntityID: testIssuer, Certificate: idpKey.Certificate}
+
+	doc := testResponse(sign(t, testAssertion(time.Now().Add(5*time.Minute), testEntityID), "id-assertion", idpKey.Key))
+	a, err := sp.ParseResponse(idp, testACS, encode(doc), testRequest)
+	if assert.Nil(t, err) {
+		assert.Equal(t, "user@example.com", a.NameID)
+		assert.Equal(t, "session", a.SessionIndex)
+		assert.Equal(t, []string{"admins", "users & guests"}, a.Attributes["groups"])
+		assert.Equal(t, "user@example.com", a.Claims()["mail"])
+		assert.Equal(t, []interface{}{"admins", "users & guests"}, a.Claims()["groups"])
+	}
+}
+
+func TestParseResponseAcceptsSignedResponse(t *testing.T) {
+	idpKey := newKeyPair(t)
+	sp := &ServiceProvider{EntityID: testEntityID}
+	idp := &IdentityProvider{EntityID: testIssuer, Certificate: idpKey.Certificate}
+
+	doc := sign(t, testResponse(testAssertion(time.Now().Add(5*time.Minute), testEntityID)), "id-response", idpKey.Key)
+	_, err := sp.ParseResponse(idp, testACS, encode(doc), testRequest)
+	assert.Nil(t, err)
+}
+
+func TestParseResponseRejectsInvalidResponses(t *testing.T) {
+	idpKey := newKeyPair(t)
+	otherKey := newKeyPair(t)
+	sp := &ServiceProvider{EntityID: testEntityID}
+	idp := &IdentityProvider{EntityID: testIssuer, Certificate: idpKey.Certificate}
+
+	valid := testAssertion(time.Now().Add(5*time.Minute), testEntityID)
+	signed := sign(t, valid, "id-assertion", idpKey.Key)
+	responses := map[string]string{
+		"unsigned":        testResponse(valid),
+		"other key":       testResponse(sign(t, valid, "id-assertion", otherKey.Key)),
+		"tampered":        testResponse(strings.Replace(signed, "user@example.com</saml:NameID>", "admin@example.com</saml:NameID>", 1)),
+		"expired":         testResponse(sign(t, testAssertion(time.Now().Add(-5*time.Minute), testEntityID), "id-assertion", idpKey.Key)),
+		"other audience":  testResponse(sign(t, testAssertion(time.Now().Add(5*time.Minute), "https://other.example.com"), "id-assertion", idpKey.Key)),
+		"other request":   strings.Replace(testResponse(signed), `InResponseTo="`+testRequest+`"`, `InResponseTo="id-other"`, 1),
+		"other recipient": strings.Replace(testResponse(signed), `Destination="`+testACS+`"`, `Destination="https://other.example.com"`, 1),
+		"failed":          strings.Replace(testResponse(signed), "status:Success", "status:Requester", 1),
+		"doctype":         `<!DOCTYPE samlp:Response [<!ENTITY x "x">]>` + testResponse(signed),
+		// INFO: The signed assertion is moved into the extensions and the forged one takes its place
+		"wrapped": testResponse(strings.Replace(valid, "user@example.com</saml:NameID>", "admin@example.com</saml:NameID>", 1) +
+			`<samlp:Extensions>` + signed + `</samlp:Extensions>`),
+	}
+	for name, doc := range responses {
+		_, err := sp.ParseResponse(idp, testACS, encode(doc), testRequest)
+		assert.NotNil(t, err, name)
+	}
+}
+
+func TestAuthnRequestURL(t *testing.T) {
+	key := newKeyPair(t)
+	sp := &ServiceProvider{EntityID: testEntityID, Key: key}
+
+	u, err := sp.AuthnRequestURL(&IdentityProvider{SSOURL: "https://idp.example.com/sso?tenant=1"}, testACS, testRequest, "relay")
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	parsed, err := url.Parse(u)
+	if !assert.Nil(t, err) {
+		return
+	}
+	q := parsed.Query()
+	assert.Equal(t, "1", q.Get("tenant"))
+	assert.Equal(t, "relay", q.Get("RelayState"))
+
+	data, _ := base64.StdEncoding.DecodeString(q.Get("SAMLRequest"))
+	xml, err := ioutil.ReadAll(flate.NewReader(bytes.NewReader(data)))
+	if assert.Nil(t, err) {
+		assert.Contains(t, string(xml), `ID="`+testRequest+`"`)
+		assert.Contains(t, string(xml), testEntityID)
+	}
+
+	signed := parsed.RawQuery[strings.Index(parsed.RawQuery, "SAMLRequest="):strings.Index(parsed.RawQuery, "&Signature=")]
+	sig, _ := base64.StdEncoding.DecodeString(q.Get("Signature"))
+	h := sha256.Sum256([]byte(signed))
+	assert.Nil(t, rsa.VerifyPKCS1v15(&key.Key.PublicKey, crypto.SHA256, h[:], sig))
+}
+
+func TestAuthnRequestURLRequiresKey(t *testing.T) {
+	sp := &ServiceProvider{EntityID: testEntityID}
+
+	_, err := sp.AuthnRequestURL(&IdentityProvider{SSOURL: "https://idp.example.com/sso"}, testACS, testRequest, "relay")
+	assert.Equal(t, ErrKeyRequired, err)
+}
+
+func TestMetadata(t *testing.T) {
+	key := newKeyPair(t)
+	sp := &ServiceProvider{EntityID: testEntityID, Key: key}
+
+	data, err := sp.Metadata([]string{testACS})
+	if assert.Nil(t, err) {
+		assert.Contains(t, string(data), `entityID="`+testEntityID+`"`)
+		assert.Contains(t, string(data), `Location="`+testACS+`"`)
+		assert.Contains(t, string(data), `AuthnRequestsSigned="true"`)
+		assert.Contains(t, string(data), base64.StdEncoding.EncodeToString(key.Certificate.Raw))
+	}
+}
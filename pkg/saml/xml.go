@@ -0,0 +1,282 @@
+package saml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const nsXML = "http://www.w3.org/XML/1998/namespace"
+
+// element is the node of the parsed document keeping the prefixes and the attributes as they are written, which
+// the canonical form of the signed element is built from.
+type element struct {
+	parent   *element
+	prefix   string
+	local    string
+	attrs    []xml.Attr
+	children []interface{}
+}
+
+// parseXML parses the document, the document type declarations are rejected as the entities aren't expected
+// in the protocol messages.
+func parseXML(data []byte) (*element, error) {
+	d := xml.NewDecoder(bytes.NewReader(data))
+
+	var root, cur *element
+	for {
+		t, err := d.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := t.(type) {
+		case xml.StartElement:
+			e := &element{parent: cur, prefix: t.Name.Space, local: t.Name.Local, attrs: append([]xml.Attr(nil), t.Attr...)}
+			if cur != nil {
+				cur.children = append(cur.children, e)
+			} else if root == nil {
+				root = e
+			} else {
+				return nil, errors.New("document has several root elements")
+			}
+			cur = e
+		case xml.EndElement:
+			if cur == nil || t.Name.Space != cur.prefix || t.Name.Local != cur.local {
+				return nil, errors.Errorf("unexpected end element %q", t.Name.Local)
+			}
+			cur = cur.parent
+		case xml.CharData:
+			if cur != nil {
+				cur.children = append(cur.children, t.Copy())
+			}
+		case xml.Comment:
+			if cur != nil {
+				cur.children = append(cur.children, t.Copy())
+			}
+		case xml.ProcInst:
+			if cur != nil {
+				cur.children = append(cur.children, t.Copy())
+			}
+		case xml.Directive:
+			return nil, errors.New("document type declarations aren't allowed")
+		}
+	}
+
+	if root == nil || cur != nil {
+		return nil, errors.New("unexpected end of document")
+	}
+
+	return root, nil
+}
+
+// lookupNS returns the namespace the prefix is bound to in the scope of the element, the empty prefix is
+// the default namespace.
+func (e *element) lookupNS(prefix string) (string, bool) {
+	if prefix == "xml" {
+		return nsXML, true
+	}
+	for x := e; x != nil; x = x.parent {
+		for _, a := range x.attrs {
+			if prefix == "" && a.Name.Space == "" && a.Name.Local == "xmlns" || prefix != "" && a.Name.Space == "xmlns" && a.Name.Local == prefix {
+				return a.Value, true
+			}
+		}
+	}
+	return "", prefix == ""
+}
+
+// is reports whether the element has the name in the namespace.
+func (e *element) is(ns, local string) bool {
+	uri, _ := e.lookupNS(e.prefix)
+	return e.local == local && uri == ns
+}
+
+// attr returns the value of the attribute without the prefix.
+func (e *element) attr(name string) string {
+	for _, a := range e.attrs {
+		if a.Name.Space == "" && a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// elements returns the child elements with the name in the namespace.
+func (e *element) elements(ns, local string) []*element {
+	var list []*element
+	for _, c := range e.children {
+		if c, ok := c.(*element); ok && c.is(ns, local) {
+			list = append(list, c)
+		}
+	}
+	return list
+}
+
+// element returns the first child element with the name in the namespace or nil.
+func (e *element) element(ns, local string) *element {
+	if list := e.elements(ns, local); len(list) > 0 {
+		return list[0]
+	}
+	return nil
+}
+
+// text returns the text content of the element without the text of the child elements.
+func (e *element) text() string {
+	var b strings.Builder
+	for _, c := range e.children {
+		if c, ok := c.(xml.CharData); ok {
+			b.Write(c)
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+func isNamespaceDecl(a xml.Attr) bool {
+	return a.Name.Space == "xmlns" || a.Name.Space == "" && a.Name.Local == "xmlns"
+}
+
+// canonicalizer writes the exclusive canonical form (https://www.w3.org/TR/xml-exc-c14n/) of the element.
+type canonicalizer struct {
+	// inclusive are the prefixes of the InclusiveNamespaces list, the empty prefix is "#default".
+	inclusive []string
+	comments  bool
+	// skip is the excluded element, the enveloped signature.
+	skip *element
+}
+
+func (c *canonicalizer) canonicalize(e *element) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := c.write(&buf, e, map[string]string{"": ""}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+type canonicalAttr struct {
+	ns, local, name, value string
+}
+
+func (c *canonicalizer) write(buf *bytes.Buffer, e *element, rendered map[string]string) error {
+	utilized := map[string]bool{e.prefix: true}
+	var attrs []canonicalAttr
+	for _, a := range e.attrs {
+		if isNamespaceDecl(a) {
+			continue
+		}
+		ca := canonicalAttr{local: a.Name.Local, name: a.Name.Local, value: a.Value}
+		if a.Name.Space != "" {
+			ns, ok := e.lookupNS(a.Name.Space)
+			if !ok {
+				return errors.Errorf("prefix %q isn't bound", a.Name.Space)
+			}
+			ca.ns, ca.name = ns, a.Name.Space+":"+a.Name.Local
+			utilized[a.Name.Space] = true
+		}
+		attrs = append(attrs, ca)
+	}
+	for _, p := range c.inclusive {
+		if _, ok := e.lookupNS(p); ok {
+			utilized[p] = true
+		}
+	}
+
+	var prefixes []string
+	scope := map[string]string{}
+	for p := range rendered {
+		scope[p] = rendered[p]
+	}
+	for p := range utilized {
+		if p == "xml" {
+			continue
+		}
+		ns, ok := e.lookupNS(p)
+		if !ok {
+			return errors.Errorf("prefix %q isn't bound", p)
+		}
+		if prev, ok := rendered[p]; ok && prev == ns {
+			continue
+		}
+		if _, ok := rendered[p]; !ok && ns == "" {
+			continue
+		}
+		prefixes = append(prefixes, p)
+		scope[p] = ns
+	}
+	sort.Strings(prefixes)
+	sort.Slice(attrs, func(i, j int) bool {
+		if attrs[i].ns != attrs[j].ns {
+			return attrs[i].ns < attrs[j].ns
+		}
+		return attrs[i].local < attrs[j].local
+	})
+
+	name := e.local
+	if e.prefix != "" {
+		name = e.prefix + ":" + e.local
+	}
+	buf.WriteString("<" + name)
+	for _, p := range prefixes {
+		if p == "" {
+			buf.WriteString(` xmlns="`)
+		} else {
+			buf.WriteString(" xmlns:" + p + `="`)
+		}
+		escapeAttr(buf, scope[p])
+		buf.WriteByte('"')
+	}
+	for _, a := range attrs {
+		buf.WriteString(" " + a.name + `="`)
+		escapeAttr(buf, a.value)
+		buf.WriteByte('"')
+	}
+	buf.WriteByte('>')
+
+	for _, child := range e.children {
+		switch child := child.(type) {
+		case *element:
+			if child == c.skip {
+				continue
+			}
+			if err := c.write(buf, child, scope); err != nil {
+				return err
+			}
+		case xml.CharData:
+			escapeText(buf, string(child))
+		case xml.Comment:
+			if c.comments {
+				buf.WriteString("<!--" + string(child) + "-->")
+			}
+		case xml.ProcInst:
+			buf.WriteString("<?" + child.Target)
+			if len(child.Inst) > 0 {
+				buf.WriteString(" " + string(child.Inst))
+			}
+			buf.WriteString("?>")
+		}
+	}
+
+	buf.WriteString("</" + name + ">")
+
+	return nil
+}
+
+var (
+	textEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\r", "&#xD;")
+	attrEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", `"`, "&quot;", "\t", "&#x9;", "\n", "&#xA;", "\r", "&#xD;")
+)
+
+func escapeText(buf *bytes.Buffer, s string) {
+	_, _ = textEscaper.WriteString(buf, s)
+}
+
+func escapeAttr(buf *bytes.Buffer, s string) {
+	_, _ = attrEscaper.WriteString(buf, s)
+}
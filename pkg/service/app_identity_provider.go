@@ -15,11 +15,17 @@ import (
 
 	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
 	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/repository"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/apple"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/claims"
 	"github.com/ProtocolONE/auth1.protocol.one/pkg/models"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/oidc"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/saml"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/steam"
 	"github.com/pkg/errors"
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/facebook"
 	"golang.org/x/oauth2/google"
+	"golang.org/x/oauth2/microsoft"
 	"golang.org/x/oauth2/twitch"
 	"golang.org/x/oauth2/vk"
 )
@@ -55,6 +61,33 @@ type AppIdentityProviderServiceInterface interface {
 
 	// GetSocialProfile swaps the authorization code for an access token on a social network and gets a user profile in it.
 	GetSocialProfile(context.Context, string, string, *models.AppIdentityProvider) (*models.UserIdentitySocial, error)
+
+	// GetOIDCAuthUrl generates an authorization string for the OpenID Connect provider with the nonce and
+	// the code challenge of the request.
+	GetOIDCAuthUrl(context.Context, string, *models.AppIdentityProvider, *oidc.AuthRequest, interface{}) (string, error)
+
+	// GetOIDCProfile swaps the authorization code for the id token of the OpenID Connect provider, verifies it
+	// and maps its claims to the user profile.
+	GetOIDCProfile(context.Context, string, string, *models.AppIdentityProvider, *oidc.AuthRequest) (*models.UserIdentitySocial, error)
+
+	// GetSAMLAuthUrl generates the url of the single sign-on service of the SAML provider with the authentication
+	// request of the id, the relay state is posted back with the response.
+	GetSAMLAuthUrl(string, *saml.ServiceProvider, *models.AppIdentityProvider, string, string) (string, error)
+
+	// GetSAMLProfile verifies the SAML response to the authentication request of the id and maps the attributes
+	// of its assertion to the user profile.
+	GetSAMLProfile(string, *saml.ServiceProvider, string, *models.AppIdentityProvider, string) (*models.UserIdentitySocial, error)
+
+	// GetSAMLMetadata returns the metadata of the SAML service provider of the space with the assertion consumer
+	// services of its SAML providers.
+	GetSAMLMetadata(context.Context, string, *saml.ServiceProvider, entity.SpaceID) ([]byte, error)
+
+	// GetSteamAuthUrl generates the url of the OpenID 2.0 login of Steam, the state is passed in the return url.
+	GetSteamAuthUrl(string, *models.AppIdentityProvider, interface{}) (string, error)
+
+	// GetSteamProfile verifies the OpenID 2.0 response by the query of the return url and gets the summary
+	// of the player from the Steam Web API.
+	GetSteamProfile(context.Context, string, string, *models.AppIdentityProvider) (*models.UserIdentitySocial, error)
 }
 
 // AppIdentityProviderService is the AppIdentityProvider service.
@@ -79,7 +112,11 @@ func (s AppIdentityProviderService) FindByTypeAndName(app *models.Application, c
 		panic(err)
 	}
 	for _, p := range space.IdentityProviders {
-		if p.Name == name && string(p.Type) == connType {
+		if p.Name != name {
+			continue
+		}
+		// INFO: The OpenID Connect providers are used in the social login flow
+		if string(p.Type) == connType || connType == models.AppIdentityProviderTypeSocial && p.IsSocial() {
 			return models.OldIDProvider(p)
 		}
 	}
@@ -118,6 +155,10 @@ func (s *AppIdentityProviderService) GetAvailableTemplates() []string {
 		models.AppIdentityProviderNameTwitch,
 		models.AppIdentityProviderNameGoogle,
 		models.AppIdentityProviderNameVk,
+		models.AppIdentityProviderNameSteam,
+		models.AppIdentityProviderNameApple,
+		models.AppIdentityProviderNameDiscord,
+		models.AppIdentityProviderNameMicrosoft,
 	}
 }
 
@@ -140,6 +181,14 @@ func (s *AppIdentityProviderService) GetTemplate(name string) (*models.AppIdenti
 		return s.getGoogleTemplate(), nil
 	case models.AppIdentityProviderNameVk:
 		return s.getVkTemplate(), nil
+	case models.AppIdentityProviderNameSteam:
+		return s.getSteamTemplate(), nil
+	case models.AppIdentityProviderNameApple:
+		return s.getAppleTemplate(), nil
+	case models.AppIdentityProviderNameDiscord:
+		return s.getDiscordTemplate(), nil
+	case models.AppIdentityProviderNameMicrosoft:
+		return s.getMicrosoftTemplate(), nil
 	}
 	return nil, errors.Errorf(ErrorInvalidTemplate, name)
 }
@@ -192,6 +241,55 @@ func (s *AppIdentityProviderService) getVkTemplate() *models.AppIdentityProvider
 	}
 }
 
+func (s *AppIdentityProviderService) getSteamTemplate() *models.AppIdentityProvider {
+	return &models.AppIdentityProvider{
+		Type:                models.AppIdentityProviderTypeSteam,
+		EndpointAuthURL:     steam.OpenIDURL,
+		EndpointUserInfoURL: steam.APIURL,
+		Name:                models.AppIdentityProviderNameSteam,
+		DisplayName:         models.AppIdentityProviderDisplayNameSteam,
+	}
+}
+
+// getAppleTemplate returns Sign in with Apple, the client id is the services id and the client secret is
+// the .p8 key, the profile is taken from the id token as Apple has no userinfo endpoint.
+func (s *AppIdentityProviderService) getAppleTemplate() *models.AppIdentityProvider {
+	return &models.AppIdentityProvider{
+		Type:             models.AppIdentityProviderTypeSocial,
+		ClientScopes:     []string{"name", "email"},
+		EndpointAuthURL:  apple.AuthURL,
+		EndpointTokenURL: apple.TokenURL,
+		Issuer:           apple.Issuer,
+		Name:             models.AppIdentityProviderNameApple,
+		DisplayName:      models.AppIdentityProviderDisplayNameApple,
+	}
+}
+
+func (s *AppIdentityProviderService) getDiscordTemplate() *models.AppIdentityProvider {
+	return &models.AppIdentityProvider{
+		Type:                models.AppIdentityProviderTypeSocial,
+		ClientScopes:        []string{"identify", "email"},
+		EndpointAuthURL:     "https://discord.com/api/oauth2/authorize",
+		EndpointTokenURL:    "https://discord.com/api/oauth2/token",
+		EndpointUserInfoURL: "https://discord.com/api/users/@me",
+		Name:                models.AppIdentityProviderNameDiscord,
+		DisplayName:         models.AppIdentityProviderDisplayNameDiscord,
+	}
+}
+
+func (s *AppIdentityProviderService) getMicrosoftTemplate() *models.AppIdentityProvider {
+	endpoint := microsoft.AzureADEndpoint("common")
+	return &models.AppIdentityProvider{
+		Type:                models.AppIdentityProviderTypeSocial,
+		ClientScopes:        []string{"User.Read"},
+		EndpointAuthURL:     endpoint.AuthURL,
+		EndpointTokenURL:    endpoint.TokenURL,
+		EndpointUserInfoURL: "https://graph.microsoft.com/v1.0/me",
+		Name:                models.AppIdentityProviderNameMicrosoft,
+		DisplayName:         models.AppIdentityProviderDisplayNameMicrosoft,
+	}
+}
+
 func (s *AppIdentityProviderService) GetAuthUrl(domain string, ip *models.AppIdentityProvider, form interface{}) (string, error) {
 	var buf bytes.Buffer
 	buf.WriteString(ip.EndpointAuthURL)
@@ -203,6 +301,10 @@ func (s *AppIdentityProviderService) GetAuthUrl(domain string, ip *models.AppIde
 	if len(ip.ClientScopes) > 0 {
 		v.Set("scope", strings.Join(ip.ClientScopes, " "))
 	}
+	// INFO: Apple sends the name and the email scopes only with the response posted to the callback
+	if ip.Name == models.AppIdentityProviderNameApple {
+		v.Set("response_mode", "form_post")
+	}
 	state, err := json.Marshal(form)
 	if err != nil {
 		return "", err
@@ -234,16 +336,35 @@ func (s *AppIdentityProviderService) GetSocialProfile(ctx context.Context, domai
 		},
 	}
 
+	if ip.Name == models.AppIdentityProviderNameApple {
+		secret, err := appleClientSecret(ip)
+		if err != nil {
+			return nil, err
+		}
+		conf.ClientSecret = secret
+		conf.Endpoint.AuthStyle = oauth2.AuthStyleInParams
+	}
+
 	t, err := conf.Exchange(ctx, code)
 	if err != nil {
 		return nil, err
 	}
+	if ip.Name == models.AppIdentityProviderNameApple {
+		return appleProfile(ctx, ip, t)
+	}
 
-	resp, err := http.Get(fmt.Sprintf(ip.EndpointUserInfoURL, url.QueryEscape(t.AccessToken)))
+	req, err := userInfoRequest(ip.EndpointUserInfoURL, t.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("unable to get user info: %s", resp.Status)
+	}
 
 	b, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
@@ -259,6 +380,14 @@ func (s *AppIdentityProviderService) GetSocialProfile(ctx context.Context, domai
 		return nil, err
 	}
 
+	// INFO: The built-in parser of the network is replaced by the mapping, it may not match the response
+	if !ip.ClaimMapping.IsEmpty() {
+		if err := applyClaimMapping(ip.ClaimMapping, f, uis); err != nil {
+			return nil, err
+		}
+		return uis, nil
+	}
+
 	m := f.(map[string]interface{})
 	if _, err := parseResponse(ip.Name, m, uis); err != nil {
 		return nil, err
@@ -267,12 +396,362 @@ func (s *AppIdentityProviderService) GetSocialProfile(ctx context.Context, domai
 	return uis, nil
 }
 
+// userInfoRequest returns the request of the profile, the access token is put into the url if it has the verb
+// and is sent by the Authorization header otherwise, as Discord and Microsoft Graph accept it.
+func userInfoRequest(endpoint, token string) (*http.Request, error) {
+	if strings.Contains(endpoint, "%s") {
+		return http.NewRequest(http.MethodGet, fmt.Sprintf(endpoint, url.QueryEscape(token)), nil)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return req, nil
+}
+
+// appleClientSecret signs the client secret by the .p8 key of the provider for the token request.
+func appleClientSecret(ip *models.AppIdentityProvider) (string, error) {
+	key, err := apple.ParseKey(ip.ClientSecret)
+	if err != nil {
+		return "", err
+	}
+	return apple.ClientSecret(key, ip.TeamID, ip.KeyID, ip.ClientID)
+}
+
+// appleProfile verifies the id token of the token response by the keys of Apple and maps its claims to the profile.
+func appleProfile(ctx context.Context, ip *models.AppIdentityProvider, t *oauth2.Token) (*models.UserIdentitySocial, error) {
+	raw, _ := t.Extra("id_token").(string)
+	if raw == "" {
+		return nil, errors.New("id token is missing in the token response")
+	}
+	p, err := oidc.Discover(ctx, ip.Issuer)
+	if err != nil {
+		return nil, err
+	}
+	claims, err := p.Verify(ctx, raw, ip.ClientID, "")
+	if err != nil {
+		return nil, err
+	}
+
+	uis := &models.UserIdentitySocial{Token: t.AccessToken}
+	parseClaimsOIDC(claims, uis)
+	if err := applyClaimMapping(ip.ClaimMapping, map[string]interface{}(claims), uis); err != nil {
+		return nil, err
+	}
+
+	return uis, nil
+}
+
+// ParseAppleUser fills the name of the profile by the user Apple posts on the first login only, the name
+// the user has given to the provider is kept.
+func ParseAppleUser(data string, uis *models.UserIdentitySocial) error {
+	u, err := apple.ParseUser(data)
+	if err != nil {
+		return err
+	}
+	if uis.FirstName == "" {
+		uis.FirstName = u.Name.FirstName
+	}
+	if uis.LastName == "" {
+		uis.LastName = u.Name.LastName
+	}
+	if uis.Name == "" {
+		uis.Name = strings.TrimSpace(uis.FirstName + " " + uis.LastName)
+	}
+	return nil
+}
+
+func (s *AppIdentityProviderService) GetOIDCAuthUrl(ctx context.Context, domain string, ip *models.AppIdentityProvider, req *oidc.AuthRequest, form interface{}) (string, error) {
+	p, err := oidc.Discover(ctx, ip.Issuer)
+	if err != nil {
+		return "", err
+	}
+
+	state, err := json.Marshal(form)
+	if err != nil {
+		return "", err
+	}
+
+	opts := []oauth2.AuthCodeOption{oauth2.SetAuthURLParam("nonce", req.Nonce)}
+	if p.SupportsPKCE() {
+		opts = append(opts,
+			oauth2.SetAuthURLParam("code_challenge", req.CodeChallenge()),
+			oauth2.SetAuthURLParam("code_challenge_method", "S256"),
+		)
+	}
+
+	return s.oidcConfig(domain, ip, p).AuthCodeURL(base64.StdEncoding.EncodeToString(state), opts...), nil
+}
+
+func (s *AppIdentityProviderService) GetOIDCProfile(ctx context.Context, domain string, code string, ip *models.AppIdentityProvider, req *oidc.AuthRequest) (*models.UserIdentitySocial, error) {
+	p, err := oidc.Discover(ctx, ip.Issuer)
+	if err != nil {
+		return nil, err
+	}
+
+	var opts []oauth2.AuthCodeOption
+	if p.SupportsPKCE() {
+		opts = append(opts, oauth2.SetAuthURLParam("code_verifier", req.CodeVerifier))
+	}
+	t, err := s.oidcConfig(domain, ip, p).Exchange(ctx, code, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, _ := t.Extra("id_token").(string)
+	if raw == "" {
+		return nil, errors.New("id token is missing in the token response")
+	}
+	claims, err := p.Verify(ctx, raw, ip.ClientID, req.Nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := p.UserInfo(ctx, t.AccessToken, claims.String("sub"))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range info {
+		if _, ok := claims[k]; !ok {
+			claims[k] = v
+		}
+	}
+
+	uis := &models.UserIdentitySocial{Token: t.AccessToken}
+	parseClaimsOIDC(claims, uis)
+	if err := applyClaimMapping(ip.ClaimMapping, map[string]interface{}(claims), uis); err != nil {
+		return nil, err
+	}
+
+	return uis, nil
+}
+
+func (s *AppIdentityProviderService) oidcConfig(domain string, ip *models.AppIdentityProvider, p *oidc.Provider) *oauth2.Config {
+	scopes := ip.ClientScopes
+	if len(scopes) == 0 {
+		scopes = []string{"openid", "email", "profile"}
+	}
+	openid := false
+	for _, scope := range scopes {
+		openid = openid || scope == "openid"
+	}
+	if !openid {
+		scopes = append([]string{"openid"}, scopes...)
+	}
+
+	return &oauth2.Config{
+		ClientID:     ip.ClientID,
+		ClientSecret: ip.ClientSecret,
+		Scopes:       scopes,
+		RedirectURL:  s.callbackUrl(domain, ip.Name),
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  p.AuthorizationEndpoint,
+			TokenURL: p.TokenEndpoint,
+		},
+	}
+}
+
+// SAMLServiceProvider returns the SAML service provider of the space, its entity id is the url of its metadata.
+func SAMLServiceProvider(domain string, spaceID entity.SpaceID, key *saml.KeyPair) *saml.ServiceProvider {
+	return &saml.ServiceProvider{
+		EntityID: fmt.Sprintf("%s/api/spaces/%s/saml/metadata", domain, spaceID),
+		Key:      key,
+	}
+}
+
+func (s *AppIdentityProviderService) GetSAMLAuthUrl(domain string, sp *saml.ServiceProvider, ip *models.AppIdentityProvider, requestID, relayState string) (string, error) {
+	idp, err := samlIdentityProvider(ip)
+	if err != nil {
+		return "", err
+	}
+
+	return sp.AuthnRequestURL(idp, s.callbackUrl(domain, ip.Name), requestID, relayState)
+}
+
+func (s *AppIdentityProviderService) GetSAMLProfile(domain string, sp *saml.ServiceProvider, response string, ip *models.AppIdentityProvider, requestID string) (*models.UserIdentitySocial, error) {
+	idp, err := samlIdentityProvider(ip)
+	if err != nil {
+		return nil, err
+	}
+
+	a, err := sp.ParseResponse(idp, s.callbackUrl(domain, ip.Name), response, requestID)
+	if err != nil {
+		return nil, err
+	}
+
+	uis := &models.UserIdentitySocial{}
+	parseAssertionSAML(a, uis)
+	if err := applyClaimMapping(ip.ClaimMapping, a.Claims(), uis); err != nil {
+		return nil, err
+	}
+
+	return uis, nil
+}
+
+func (s *AppIdentityProviderService) GetSAMLMetadata(ctx context.Context, domain string, sp *saml.ServiceProvider, spaceID entity.SpaceID) ([]byte, error) {
+	space, err := s.spaces.FindByID(ctx, spaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	var acs []string
+	for _, p := range space.IdentityProviders {
+		if p.Type == entity.IDProviderTypeSAML {
+			acs = append(acs, s.callbackUrl(domain, p.Name))
+		}
+	}
+
+	return sp.Metadata(acs)
+}
+
+func (s *AppIdentityProviderService) GetSteamAuthUrl(domain string, ip *models.AppIdentityProvider, form interface{}) (string, error) {
+	state, err := json.Marshal(form)
+	if err != nil {
+		return "", err
+	}
+	returnTo := s.callbackUrl(domain, ip.Name) + "?" + url.Values{"state": {base64.StdEncoding.EncodeToString(state)}}.Encode()
+
+	return steamClient(ip).AuthURL(domain, returnTo), nil
+}
+
+func (s *AppIdentityProviderService) GetSteamProfile(ctx context.Context, domain string, query string, ip *models.AppIdentityProvider) (*models.UserIdentitySocial, error) {
+	q, err := url.ParseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	c := steamClient(ip)
+	id, err := c.Verify(ctx, s.callbackUrl(domain, ip.Name), q)
+	if err != nil {
+		return nil, err
+	}
+	p, err := c.Player(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	uis := &models.UserIdentitySocial{}
+	parsePlayerSteam(p, uis)
+	if err := applyClaimMapping(ip.ClaimMapping, map[string]interface{}(p), uis); err != nil {
+		return nil, err
+	}
+
+	return uis, nil
+}
+
+// steamClient returns the client of the provider, the Web API key is kept as the client secret.
+func steamClient(ip *models.AppIdentityProvider) *steam.Client {
+	return steam.NewClient(ip.EndpointAuthURL, ip.EndpointUserInfoURL, ip.ClientSecret)
+}
+
+// parsePlayerSteam maps the summary of the player to the profile, Steam doesn't share the email.
+func parsePlayerSteam(p steam.Player, uis *models.UserIdentitySocial) {
+	uis.ID = p.String("steamid")
+	uis.Username = p.String("personaname")
+	uis.Name = p.String("realname")
+	if uis.Name == "" {
+		uis.Name = uis.Username
+	}
+	uis.Picture = p.String("avatarfull")
+}
+
+func samlIdentityProvider(ip *models.AppIdentityProvider) (*saml.IdentityProvider, error) {
+	cert, err := saml.ParseCertificate(ip.Certificate)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid certificate of saml provider")
+	}
+
+	return &saml.IdentityProvider{
+		EntityID:    ip.Issuer,
+		SSOURL:      ip.EndpointAuthURL,
+		Certificate: cert,
+	}, nil
+}
+
+// samlAttributes are the names of the profile attributes as they are sent by the common providers: the short
+// names, the claims of ADFS and Azure AD and the LDAP OIDs.
+var samlAttributes = struct {
+	email, name, firstName, lastName []string
+}{
+	email:     []string{"email", "mail", "emailAddress", "http://schemas.xmlsoap.org/ws/2005/05/identity/claims/emailaddress", "urn:oid:0.9.2342.19200300.100.1.3"},
+	name:      []string{"displayName", "name", "http://schemas.microsoft.com/identity/claims/displayname", "http://schemas.xmlsoap.org/ws/2005/05/identity/claims/name", "urn:oid:2.16.840.1.113730.3.1.241"},
+	firstName: []string{"givenName", "firstName", "http://schemas.xmlsoap.org/ws/2005/05/identity/claims/givenname", "urn:oid:2.5.4.42"},
+	lastName:  []string{"sn", "surname", "lastName", "http://schemas.xmlsoap.org/ws/2005/05/identity/claims/surname", "urn:oid:2.5.4.4"},
+}
+
+// parseAssertionSAML maps the name id and the common attributes of the assertion to the profile.
+func parseAssertionSAML(a *saml.Assertion, uis *models.UserIdentitySocial) {
+	uis.ID = a.NameID
+	uis.Email = a.Attribute(samlAttributes.email...)
+	if uis.Email == "" && a.NameIDFormat == "urn:oasis:names:tc:SAML:1.1:nameid-format:emailAddress" {
+		uis.Email = a.NameID
+	}
+	uis.Name = a.Attribute(samlAttributes.name...)
+	uis.FirstName = a.Attribute(samlAttributes.firstName...)
+	uis.LastName = a.Attribute(samlAttributes.lastName...)
+}
+
+// parseClaimsOIDC maps the standard claims of OpenID Connect to the profile.
+func parseClaimsOIDC(claims oidc.Claims, uis *models.UserIdentitySocial) {
+	uis.ID = claims.String("sub")
+	uis.Name = claims.String("name")
+	if uis.Name == "" {
+		uis.Name = claims.String("preferred_username")
+	}
+	uis.FirstName = claims.String("given_name")
+	uis.LastName = claims.String("family_name")
+	uis.Birthday = claims.String("birthdate")
+	uis.Picture = claims.String("picture")
+
+	// INFO: The email is used to link the existing account, so the one the provider didn't verify is ignored
+	if verified, ok := claims.Bool("email_verified"); !ok || verified {
+		uis.Email = claims.String("email")
+	}
+}
+
+// applyClaimMapping sets the fields of the profile which have the expressions in the mapping.
+func applyClaimMapping(m entity.ClaimMapping, data interface{}, uis *models.UserIdentitySocial) error {
+	p, err := claims.Apply(m, data)
+	if err != nil {
+		return errors.Wrap(err, "unable to apply claim mapping")
+	}
+
+	fields := []struct {
+		expr  string
+		dst   *string
+		value string
+	}{
+		{m.ExternalID, &uis.ID, p.ExternalID},
+		{m.Email, &uis.Email, p.Email},
+		{m.Username, &uis.Username, p.Username},
+		{m.Name, &uis.Name, p.Name},
+		{m.FirstName, &uis.FirstName, p.FirstName},
+		{m.LastName, &uis.LastName, p.LastName},
+		{m.Birthday, &uis.Birthday, p.Birthday},
+		{m.Picture, &uis.Picture, p.Picture},
+	}
+	for _, f := range fields {
+		if f.expr != "" {
+			*f.dst = f.value
+		}
+	}
+	if p.EmailVerified != nil && !*p.EmailVerified {
+		uis.Email = ""
+	}
+
+	return nil
+}
+
 func parseResponse(name string, params ...interface{}) (result *models.UserIdentitySocial, err error) {
 	funcs := map[string]interface{}{
-		"facebook": parseResponseFacebook,
-		"twitch":   parseResponseTwitch,
-		"google":   parseResponseGoogle,
-		"vk":       parseResponseVk,
+		"facebook":  parseResponseFacebook,
+		"twitch":    parseResponseTwitch,
+		"google":    parseResponseGoogle,
+		"vk":        parseResponseVk,
+		"discord":   parseResponseDiscord,
+		"microsoft": parseResponseMicrosoft,
 	}
 	f := reflect.ValueOf(funcs[name])
 	if len(params) != f.Type().NumIn() {
@@ -367,3 +846,34 @@ func parseResponseVk(data map[string]interface{}, uis *models.UserIdentitySocial
 	}
 	return uis
 }
+
+func parseResponseDiscord(data map[string]interface{}, uis *models.UserIdentitySocial) interface{} {
+	c := oidc.Claims(data)
+	uis.ID = c.String("id")
+	uis.Username = c.String("username")
+	uis.Name = c.String("global_name")
+	if uis.Name == "" {
+		uis.Name = uis.Username
+	}
+	if avatar := c.String("avatar"); avatar != "" {
+		uis.Picture = fmt.Sprintf("https://cdn.discordapp.com/avatars/%s/%s.png", uis.ID, avatar)
+	}
+
+	// INFO: The email is used to link the existing account, so the one Discord didn't verify is ignored
+	uis.Email = ""
+	if verified, _ := c.Bool("verified"); verified {
+		uis.Email = c.String("email")
+	}
+	return uis
+}
+
+func parseResponseMicrosoft(data map[string]interface{}, uis *models.UserIdentitySocial) interface{} {
+	c := oidc.Claims(data)
+	uis.ID = c.String("id")
+	uis.Email = c.String("mail")
+	uis.Name = c.String("displayName")
+	uis.FirstName = c.String("givenName")
+	uis.LastName = c.String("surname")
+
+	return uis
+}
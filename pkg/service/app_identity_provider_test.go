@@ -1,13 +1,28 @@
 package service
 
 import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"regexp"
 	"testing"
 
 	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
 	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/repository"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/apple"
 	"github.com/ProtocolONE/auth1.protocol.one/pkg/models"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/oidc"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/saml"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/steam/steamtest"
+	"github.com/dgrijalva/jwt-go"
 	"github.com/globalsign/mgo/bson"
 	"github.com/stretchr/testify/assert"
 )
@@ -24,9 +39,21 @@ var (
 		Name: "name1",
 	}
 
+	idp3 = entity.IdentityProvider{
+		ID:   entity.IdentityProviderID(bson.NewObjectId().Hex()),
+		Type: entity.IDProviderTypeOIDC,
+		Name: "corp",
+	}
+
+	idp4 = entity.IdentityProvider{
+		ID:   entity.IdentityProviderID(bson.NewObjectId().Hex()),
+		Type: entity.IDProviderTypeSAML,
+		Name: "sso",
+	}
+
 	space = &entity.Space{
 		ID:                entity.SpaceID(bson.NewObjectId().Hex()),
-		IdentityProviders: entity.IdentityProviders{idp1, idp2},
+		IdentityProviders: entity.IdentityProviders{idp1, idp2, idp3, idp4},
 	}
 	app = &models.Application{SpaceId: bson.ObjectIdHex(string(space.ID))}
 )
@@ -45,6 +72,14 @@ func TestIdentityProvidersFindByTypeAndNameReturnEmptyProviders(t *testing.T) {
 	assert.Nil(t, ip.FindByTypeAndName(app, "password", "name2"), "Identity provider must be empty")
 }
 
+func TestIdentityProvidersFindByTypeAndNameReturnOIDCProviderAsSocial(t *testing.T) {
+	ip := NewAppIdentityProviderService(spacesNew)
+	p := ip.FindByTypeAndName(app, models.AppIdentityProviderTypeSocial, "corp")
+	if assert.NotNil(t, p) {
+		assert.Equal(t, models.AppIdentityProviderTypeOIDC, p.Type)
+	}
+}
+
 func TestIdentityProvidersGetTemplateReturnError(t *testing.T) {
 	ip := NewAppIdentityProviderService(spacesNew)
 	_, err := ip.GetTemplate("test")
@@ -57,7 +92,7 @@ func TestIdentityProvidersGetTemplateReturnError(t *testing.T) {
 
 func TestIdentityProvidersGetAvailableTemplates(t *testing.T) {
 	ip := NewAppIdentityProviderService(spacesNew)
-	assert.Len(t, ip.GetAvailableTemplates(), 4, "Invalid count available templates")
+	assert.Len(t, ip.GetAvailableTemplates(), 8, "Invalid count available templates")
 }
 
 func TestIdentityProvidersGetAllTemplates(t *testing.T) {
@@ -138,3 +173,239 @@ func TestIdentityProvidersGetAuthUrl(t *testing.T) {
 	expected := "http://localhost/?client_id=1&redirect_uri=http%3A%2F%2Flocalhost%2Fapi%2Fproviders%2Fgoogle%2Fcallback&response_type=code&state=IiI%3D"
 	assert.Equal(t, expected, url, "Invalid social auth url")
 }
+
+func TestIdentityProvidersGetAuthUrlPostsAppleResponse(t *testing.T) {
+	ip := NewAppIdentityProviderService(spacesNew)
+	ipc, _ := ip.GetTemplate(models.AppIdentityProviderNameApple)
+	u, _ := ip.GetAuthUrl("http://localhost", ipc, "")
+
+	parsed, _ := url.Parse(u)
+	assert.Equal(t, "form_post", parsed.Query().Get("response_mode"))
+	assert.Equal(t, "name email", parsed.Query().Get("scope"))
+}
+
+func TestIdentityProvidersGetSocialProfileSendsBearerToken(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/token" {
+			_, _ = w.Write([]byte(`{"access_token":"token","token_type":"bearer"}`))
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"id":"80351110224678912","username":"nelly","global_name":"Nelly","avatar":"8342729096ea3675442027381ff50dfe","email":"nelly@example.com","verified":false}`))
+	}))
+	defer srv.Close()
+
+	ip := NewAppIdentityProviderService(spacesNew)
+	ipc, _ := ip.GetTemplate(models.AppIdentityProviderNameDiscord)
+	ipc.EndpointTokenURL = srv.URL + "/token"
+	ipc.EndpointUserInfoURL = srv.URL + "/users/@me"
+
+	uis, err := ip.GetSocialProfile(context.Background(), "http://localhost", "code", ipc)
+	if assert.Nil(t, err) {
+		assert.Equal(t, &models.UserIdentitySocial{
+			ID:       "80351110224678912",
+			Username: "nelly",
+			Name:     "Nelly",
+			Picture:  "https://cdn.discordapp.com/avatars/80351110224678912/8342729096ea3675442027381ff50dfe.png",
+			Token:    "token",
+		}, uis)
+	}
+}
+
+func TestIdentityProvidersParseResponseMicrosoft(t *testing.T) {
+	uis, err := parseResponse("microsoft", map[string]interface{}{
+		"id":                "87d349ed-44d7-43e1-9a83-5f2406dee5bd",
+		"displayName":       "Megan Bowen",
+		"givenName":         "Megan",
+		"surname":           "Bowen",
+		"mail":              "MeganB@example.com",
+		"userPrincipalName": "MeganB@example.com",
+	}, &models.UserIdentitySocial{})
+	if assert.Nil(t, err) {
+		assert.Equal(t, &models.UserIdentitySocial{
+			ID:        "87d349ed-44d7-43e1-9a83-5f2406dee5bd",
+			Email:     "MeganB@example.com",
+			Name:      "Megan Bowen",
+			FirstName: "Megan",
+			LastName:  "Bowen",
+		}, uis)
+	}
+}
+
+func TestIdentityProvidersGetSocialProfileSignsAppleClientSecret(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	der, _ := x509.MarshalPKCS8PrivateKey(key)
+
+	var form url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		form = r.PostForm
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token":"token","token_type":"bearer"}`))
+	}))
+	defer srv.Close()
+
+	ip := NewAppIdentityProviderService(spacesNew)
+	ipc, _ := ip.GetTemplate(models.AppIdentityProviderNameApple)
+	ipc.ClientID = "com.example.login"
+	ipc.ClientSecret = string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	ipc.TeamID = "TEAM"
+	ipc.KeyID = "KEY"
+	ipc.EndpointTokenURL = srv.URL
+
+	_, err := ip.GetSocialProfile(context.Background(), "http://localhost", "code", ipc)
+	assert.EqualError(t, err, "id token is missing in the token response")
+
+	claims := jwt.MapClaims{}
+	_, err = new(jwt.Parser).ParseWithClaims(form.Get("client_secret"), claims, func(*jwt.Token) (interface{}, error) {
+		return &key.PublicKey, nil
+	})
+	if assert.Nil(t, err) {
+		assert.Equal(t, "TEAM", claims["iss"])
+		assert.Equal(t, "com.example.login", claims["sub"])
+		assert.Equal(t, apple.Issuer, claims["aud"])
+	}
+}
+
+func TestIdentityProvidersParseAppleUserKeepsGivenName(t *testing.T) {
+	uis := &models.UserIdentitySocial{ID: "1"}
+	assert.Nil(t, ParseAppleUser(`{"name":{"firstName":"Robin","lastName":"Smith"}}`, uis))
+	assert.Equal(t, &models.UserIdentitySocial{ID: "1", Name: "Robin Smith", FirstName: "Robin", LastName: "Smith"}, uis)
+
+	uis = &models.UserIdentitySocial{ID: "1", Name: "Robin", FirstName: "Robin"}
+	assert.Nil(t, ParseAppleUser(`{"name":{"firstName":"Bob","lastName":"Smith"}}`, uis))
+	assert.Equal(t, &models.UserIdentitySocial{ID: "1", Name: "Robin", FirstName: "Robin", LastName: "Smith"}, uis)
+
+	assert.NotNil(t, ParseAppleUser("user", uis))
+}
+
+func TestIdentityProvidersGetOIDCAuthUrl(t *testing.T) {
+	var issuer string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(oidc.Metadata{
+			Issuer:                issuer,
+			AuthorizationEndpoint: issuer + "/auth",
+			TokenEndpoint:         issuer + "/token",
+			JWKSURI:               issuer + "/jwks",
+		})
+	}))
+	defer srv.Close()
+	issuer = srv.URL
+
+	ip := NewAppIdentityProviderService(spacesNew)
+	ipc := &models.AppIdentityProvider{Issuer: issuer, ClientID: "1", Name: "corp", ClientScopes: []string{"email"}}
+	req := &oidc.AuthRequest{Nonce: "nonce", CodeVerifier: "verifier"}
+	u, err := ip.GetOIDCAuthUrl(context.Background(), "http://localhost", ipc, req, "")
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	parsed, _ := url.Parse(u)
+	assert.Equal(t, issuer+"/auth", parsed.Scheme+"://"+parsed.Host+parsed.Path)
+	assert.Equal(t, "openid email", parsed.Query().Get("scope"))
+	assert.Equal(t, "nonce", parsed.Query().Get("nonce"))
+	assert.Equal(t, req.CodeChallenge(), parsed.Query().Get("code_challenge"))
+	assert.Equal(t, "S256", parsed.Query().Get("code_challenge_method"))
+	assert.Equal(t, "http://localhost/api/providers/corp/callback", parsed.Query().Get("redirect_uri"))
+}
+
+func TestIdentityProvidersParseClaimsOIDCIgnoresUnverifiedEmail(t *testing.T) {
+	uis := &models.UserIdentitySocial{}
+	parseClaimsOIDC(oidc.Claims{"sub": "1", "email": "user@example.com", "email_verified": false, "preferred_username": "user"}, uis)
+	assert.Equal(t, &models.UserIdentitySocial{ID: "1", Name: "user"}, uis)
+
+	parseClaimsOIDC(oidc.Claims{"sub": "1", "email": "user@example.com", "email_verified": true, "given_name": "First"}, uis)
+	assert.Equal(t, "user@example.com", uis.Email)
+	assert.Equal(t, "First", uis.FirstName)
+}
+
+func TestIdentityProvidersApplyClaimMappingKeepsUnmappedFields(t *testing.T) {
+	uis := &models.UserIdentitySocial{ID: "1", Email: "user@example.com", Name: "User"}
+	data := map[string]interface{}{"login": "user", "verified": false}
+
+	err := applyClaimMapping(entity.ClaimMapping{Username: "login", EmailVerified: "verified"}, data, uis)
+	assert.Nil(t, err)
+	assert.Equal(t, &models.UserIdentitySocial{ID: "1", Name: "User", Username: "user"}, uis)
+
+	assert.NotNil(t, applyClaimMapping(entity.ClaimMapping{ExternalID: "id["}, data, uis))
+}
+
+func TestIdentityProvidersParseAssertionSAML(t *testing.T) {
+	uis := &models.UserIdentitySocial{}
+	parseAssertionSAML(&saml.Assertion{
+		NameID:       "user@example.com",
+		NameIDFormat: "urn:oasis:names:tc:SAML:1.1:nameid-format:emailAddress",
+		Attributes: map[string][]string{
+			"http://schemas.xmlsoap.org/ws/2005/05/identity/claims/givenname": {"First"},
+			"urn:oid:2.5.4.4": {"Last"},
+			"displayName":     {"First Last"},
+		},
+	}, uis)
+
+	assert.Equal(t, &models.UserIdentitySocial{
+		ID:        "user@example.com",
+		Email:     "user@example.com",
+		Name:      "First Last",
+		FirstName: "First",
+		LastName:  "Last",
+	}, uis)
+}
+
+func TestIdentityProvidersGetSAMLMetadata(t *testing.T) {
+	ip := NewAppIdentityProviderService(spacesNew)
+	sp := SAMLServiceProvider("http://localhost", space.ID, nil)
+
+	data, err := ip.GetSAMLMetadata(context.Background(), "http://localhost", sp, space.ID)
+	if assert.Nil(t, err) {
+		assert.Contains(t, string(data), fmt.Sprintf(`entityID="http://localhost/api/spaces/%s/saml/metadata"`, space.ID))
+		assert.Contains(t, string(data), `Location="http://localhost/api/providers/sso/callback"`)
+		assert.NotContains(t, string(data), "/api/providers/corp/callback")
+	}
+}
+
+func TestIdentityProvidersGetSteamProfile(t *testing.T) {
+	srv := steamtest.NewServer("key")
+	defer srv.Close()
+	srv.Players["76561197960435530"] = map[string]interface{}{
+		"steamid":     "76561197960435530",
+		"personaname": "robin",
+		"avatarfull":  "https://example.com/avatar.jpg",
+	}
+
+	ip := NewAppIdentityProviderService(spacesNew)
+	ipc := &models.AppIdentityProvider{
+		Type:                models.AppIdentityProviderTypeSteam,
+		Name:                "steam",
+		ClientSecret:        "key",
+		EndpointAuthURL:     srv.OpenIDURL(),
+		EndpointUserInfoURL: srv.APIURL(),
+	}
+	authUrl, err := ip.GetSteamAuthUrl("http://localhost", ipc, "")
+	if !assert.Nil(t, err) {
+		return
+	}
+	callback, err := srv.Login(authUrl, "76561197960435530")
+	if !assert.Nil(t, err) {
+		return
+	}
+	u, _ := url.Parse(callback)
+	assert.Equal(t, "http://localhost/api/providers/steam/callback", u.Scheme+"://"+u.Host+u.Path)
+	assert.Equal(t, "IiI=", u.Query().Get("state"))
+
+	uis, err := ip.GetSteamProfile(context.Background(), "http://localhost", u.RawQuery, ipc)
+	if assert.Nil(t, err) {
+		assert.Equal(t, &models.UserIdentitySocial{
+			ID:       "76561197960435530",
+			Username: "robin",
+			Name:     "robin",
+			Picture:  "https://example.com/avatar.jpg",
+		}, uis)
+	}
+
+	_, err = ip.GetSteamProfile(context.Background(), "http://localhost", u.RawQuery, ipc)
+	assert.NotNil(t, err, "Steam response must be accepted once")
+}
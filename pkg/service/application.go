@@ -54,11 +54,16 @@ func NewApplicationService(r InternalRegistry) *ApplicationService {
 		watcher: r.Watcher(),
 	}
 
+	// The application is changed or deleted by other instance, it's loaded again on the next Get.
 	a.watcher.SetUpdateCallback(ApplicationWatcherChannel, func(id string) {
+		if !bson.IsObjectIdHex(id) {
+			return
+		}
+
 		a.mx.Lock()
 		defer a.mx.Unlock()
 
-		_, _ = a.loadToCache(bson.ObjectIdHex(id))
+		delete(a.pool, bson.ObjectIdHex(id))
 	})
 
 	return a
@@ -73,7 +78,7 @@ func (s ApplicationService) Create(app *models.Application) error {
 	}
 
 	s.pool[app.ID] = app
-	return s.watcher.Update(ApplicationWatcherChannel, app.ID.String())
+	return s.watcher.Update(ApplicationWatcherChannel, app.ID.Hex())
 }
 
 func (s ApplicationService) Update(app *models.Application) error {
@@ -85,7 +90,7 @@ func (s ApplicationService) Update(app *models.Application) error {
 	}
 
 	s.pool[app.ID] = app
-	return s.watcher.Update(ApplicationWatcherChannel, app.ID.String())
+	return s.watcher.Update(ApplicationWatcherChannel, app.ID.Hex())
 }
 
 func (s ApplicationService) Get(id bson.ObjectId) (*models.Application, error) {
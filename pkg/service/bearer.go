@@ -0,0 +1,40 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/models"
+	"github.com/globalsign/mgo/bson"
+	"github.com/ory/hydra-client-go/client/admin"
+	"github.com/pkg/errors"
+)
+
+// ErrInvalidBearerToken is returned if the bearer token is missing, inactive or isn't the access token of the user.
+var ErrInvalidBearerToken = errors.New("invalid bearer token")
+
+// AuthenticateBearer introspects the access token of the Authorization header by Hydra and returns the claims
+// of the user it is issued for, the application of the claims is the client of the token.
+func AuthenticateBearer(ctx context.Context, hydra HydraAdminApi, header http.Header) (*models.JwtClaim, error) {
+	s := strings.SplitN(header.Get("Authorization"), " ", 2)
+	if len(s) != 2 || s[0] != "Bearer" || s[1] == "" {
+		return nil, ErrInvalidBearerToken
+	}
+
+	rsp, err := hydra.IntrospectOAuth2Token(&admin.IntrospectOAuth2TokenParams{Context: ctx, Token: s[1]}, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to introspect token")
+	}
+
+	t := rsp.Payload
+	if t == nil || t.Active == nil || !*t.Active || t.TokenType != "access_token" || !bson.IsObjectIdHex(t.Sub) {
+		return nil, ErrInvalidBearerToken
+	}
+
+	c := &models.JwtClaim{UserId: bson.ObjectIdHex(t.Sub)}
+	if bson.IsObjectIdHex(t.ClientID) {
+		c.AppId = bson.ObjectIdHex(t.ClientID)
+	}
+	return c, nil
+}
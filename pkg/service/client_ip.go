@@ -0,0 +1,52 @@
+package service
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+var clientIPKey = "client_ip"
+
+// ClientIP middleware resolves the address of the client for the limits keyed by it. The X-Forwarded-For header
+// is set by the client, so only the address appended by the outermost of the trusted proxies is taken from it,
+// without the trusted proxies the remote address of the connection is used.
+func ClientIP(trustedProxies int) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			ctx.Set(clientIPKey, clientIP(ctx.Request(), trustedProxies))
+			return next(ctx)
+		}
+	}
+}
+
+// GetClientIP returns the address resolved by the ClientIP middleware or the remote address without it.
+func GetClientIP(ctx echo.Context) string {
+	if value, ok := ctx.Get(clientIPKey).(string); ok {
+		return value
+	}
+	return clientIP(ctx.Request(), 0)
+}
+
+func clientIP(r *http.Request, trustedProxies int) string {
+	if trustedProxies > 0 {
+		var addrs []string
+		for _, header := range r.Header[echo.HeaderXForwardedFor] {
+			for _, addr := range strings.Split(header, ",") {
+				addrs = append(addrs, strings.TrimSpace(addr))
+			}
+		}
+		// INFO: Each proxy appends the address it's connected from, so only the last entries are trusted
+		if len(addrs) >= trustedProxies {
+			return addrs[len(addrs)-trustedProxies]
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
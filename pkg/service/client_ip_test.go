@@ -0,0 +1,39 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func resolveClientIP(trustedProxies int, forwardedFor ...string) string {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	for _, value := range forwardedFor {
+		req.Header.Add(echo.HeaderXForwardedFor, value)
+	}
+	ctx := echo.New().NewContext(req, httptest.NewRecorder())
+
+	var ip string
+	_ = ClientIP(trustedProxies)(func(ctx echo.Context) error {
+		ip = GetClientIP(ctx)
+		return nil
+	})(ctx)
+	return ip
+}
+
+func TestClientIPIgnoresForwardedForWithoutTrustedProxies(t *testing.T) {
+	assert.Equal(t, "10.0.0.1", resolveClientIP(0, "203.0.113.1"))
+}
+
+func TestClientIPTakesAddressAppendedByTrustedProxy(t *testing.T) {
+	assert.Equal(t, "203.0.113.1", resolveClientIP(1, "198.51.100.1, 203.0.113.1"))
+	assert.Equal(t, "203.0.113.1", resolveClientIP(2, "198.51.100.1", "203.0.113.1, 10.0.0.2"))
+}
+
+func TestClientIPUsesRemoteAddressWithoutForwardedFor(t *testing.T) {
+	assert.Equal(t, "10.0.0.1", resolveClientIP(1))
+}
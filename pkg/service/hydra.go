@@ -11,6 +11,9 @@ type HydraAdminApi interface {
 	// CreateOAuth2Client creates an o auth 2 0 client.
 	CreateOAuth2Client(*admin.CreateOAuth2ClientParams) (*admin.CreateOAuth2ClientCreated, error)
 
+	// DeleteOAuth2Client deletes an o auth 2 0 client.
+	DeleteOAuth2Client(*admin.DeleteOAuth2ClientParams) (*admin.DeleteOAuth2ClientNoContent, error)
+
 	// GetOAuth2Client gets an o auth 2 0 client.
 	GetOAuth2Client(*admin.GetOAuth2ClientParams) (*admin.GetOAuth2ClientOK, error)
 
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+const (
+	LoginAttemptsStoragePattern = "login_attempts_%s"
+	LoginDelayStoragePattern    = "login_delay_%s"
+	LoginLockStoragePattern     = "login_lock_%s"
+)
+
+// failScript counts the failure and starts the window with the first one in the same step, so the counter
+// can't be left without the expiration.
+var failScript = redis.NewScript(`
+local n = redis.call('INCR', KEYS[1])
+if n == 1 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+return n
+`)
+
+// LoginAttemptsInterface describes of methods for the counters of the failed logins.
+type LoginAttemptsInterface interface {
+	// Blocked returns the remaining time of the lock and of the delay of the logins by the key.
+	Blocked(key string) (lock time.Duration, delay time.Duration, err error)
+
+	// Fail counts the failed login by the key and returns the number of failures within the window,
+	// the window starts with the first failure.
+	Fail(key string, window time.Duration) (int, error)
+
+	// Delay blocks the logins by the key for the short time.
+	Delay(key string, d time.Duration) error
+
+	// Lock blocks the logins by the key for the time and resets the counter of the failures.
+	Lock(key string, d time.Duration) error
+
+	// Reset clears the failures, the delay and the lock of the key.
+	Reset(key string) error
+}
+
+// LoginAttempts keeps the counters in Redis, so the limits are shared by all instances.
+type LoginAttempts struct {
+	Redis *redis.Client
+}
+
+// NewLoginAttempts return new counters of the failed logins.
+func NewLoginAttempts(redis *redis.Client) *LoginAttempts {
+	return &LoginAttempts{Redis: redis}
+}
+
+func (s *LoginAttempts) Blocked(key string) (time.Duration, time.Duration, error) {
+	pipe := s.Redis.Pipeline()
+	lock := pipe.PTTL(fmt.Sprintf(LoginLockStoragePattern, key))
+	delay := pipe.PTTL(fmt.Sprintf(LoginDelayStoragePattern, key))
+	if _, err := pipe.Exec(); err != nil {
+		return 0, 0, err
+	}
+
+	return positive(lock.Val()), positive(delay.Val()), nil
+}
+
+func (s *LoginAttempts) Fail(key string, window time.Duration) (int, error) {
+	k := fmt.Sprintf(LoginAttemptsStoragePattern, key)
+
+	res, err := failScript.Run(s.Redis, []string{k}, int64(window/time.Millisecond)).Result()
+	if err != nil {
+		return 0, err
+	}
+	n, ok := res.(int64)
+	if !ok {
+		return 0, fmt.Errorf("unexpected failures counter %v", res)
+	}
+
+	return int(n), nil
+}
+
+func (s *LoginAttempts) Delay(key string, d time.Duration) error {
+	return s.Redis.Set(fmt.Sprintf(LoginDelayStoragePattern, key), 1, d).Err()
+}
+
+func (s *LoginAttempts) Lock(key string, d time.Duration) error {
+	pipe := s.Redis.TxPipeline()
+	pipe.Set(fmt.Sprintf(LoginLockStoragePattern, key), 1, d)
+	pipe.Del(fmt.Sprintf(LoginAttemptsStoragePattern, key), fmt.Sprintf(LoginDelayStoragePattern, key))
+	_, err := pipe.Exec()
+	return err
+}
+
+func (s *LoginAttempts) Reset(key string) error {
+	return s.Redis.Del(
+		fmt.Sprintf(LoginAttemptsStoragePattern, key),
+		fmt.Sprintf(LoginDelayStoragePattern, key),
+		fmt.Sprintf(LoginLockStoragePattern, key),
+	).Err()
+}
+
+// positive drops the negative ttl Redis returns for the missing keys.
+func positive(d time.Duration) time.Duration {
+	if d < 0 {
+		return 0
+	}
+	return d
+}
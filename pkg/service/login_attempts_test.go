@@ -0,0 +1,68 @@
+// +build integration
+
+package service
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/globalsign/mgo/bson"
+	"github.com/go-redis/redis"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoginAttemptsCountsFailuresWithinWindow(t *testing.T) {
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:6379"})
+	defer client.Close()
+
+	la := NewLoginAttempts(client)
+	key := bson.NewObjectId().Hex()
+
+	n, err := la.Fail(key, time.Second)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, n)
+	ttl := client.PTTL(fmt.Sprintf(LoginAttemptsStoragePattern, key)).Val()
+	assert.True(t, ttl > 0 && ttl <= time.Second)
+
+	n, err = la.Fail(key, time.Second)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, n)
+
+	time.Sleep(1100 * time.Millisecond)
+	n, err = la.Fail(key, time.Second)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, n)
+}
+
+func TestLoginAttemptsLockAndReset(t *testing.T) {
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:6379"})
+	defer client.Close()
+
+	la := NewLoginAttempts(client)
+	key := bson.NewObjectId().Hex()
+
+	lock, delay, err := la.Blocked(key)
+	assert.Nil(t, err)
+	assert.Zero(t, lock)
+	assert.Zero(t, delay)
+
+	_, err = la.Fail(key, time.Minute)
+	assert.Nil(t, err)
+	assert.Nil(t, la.Delay(key, time.Minute))
+	assert.Nil(t, la.Lock(key, time.Minute))
+
+	lock, delay, err = la.Blocked(key)
+	assert.Nil(t, err)
+	assert.True(t, lock > 0)
+	assert.Zero(t, delay)
+
+	n, err := la.Fail(key, time.Minute)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, n)
+
+	assert.Nil(t, la.Reset(key))
+	lock, _, err = la.Blocked(key)
+	assert.Nil(t, err)
+	assert.Zero(t, lock)
+}
@@ -78,7 +78,8 @@ func (s *MfaService) Get(id bson.ObjectId) (provider *models.MfaProvider, err er
 }
 
 func (s *MfaService) AddUserProvider(up *models.MfaUserProvider) error {
-	if err := s.db.C(database.TableUserMfa).Insert(up); err != nil {
+	// INFO: The record may already exist with the secret stored by the built-in TOTP backend
+	if _, err := s.db.C(database.TableUserMfa).Upsert(bson.M{"user_id": up.UserID, "provider_id": up.ProviderID}, bson.M{"$set": up}); err != nil {
 		return err
 	}
 
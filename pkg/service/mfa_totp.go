@@ -0,0 +1,285 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/database"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/models"
+	mfa "github.com/ProtocolONE/mfa-service/pkg/proto"
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/micro/go-micro/client"
+	"github.com/pkg/errors"
+)
+
+const (
+	// MfaBackendNative is the name of the built-in TOTP backend.
+	MfaBackendNative = "native"
+
+	// MfaBackendRemote is the name of the backend using the mfa micro-service.
+	MfaBackendRemote = "remote"
+
+	totpSecretSize     = 20
+	totpDigits         = 6
+	totpPeriod         = 30
+	totpSkew           = 1
+	recoveryCodesCount = 10
+	recoveryCodeSize   = 5
+	recoveryCodeCost   = 10
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// userMfaSecret is the part of the user mfa record that is managed by the native TOTP backend.
+type userMfaSecret struct {
+	// Secret is the base32 encoded TOTP shared secret.
+	Secret string `bson:"secret"`
+
+	// RecoveryCodes is the list of bcrypt hashes of the unused recovery codes.
+	RecoveryCodes []string `bson:"recovery_codes"`
+
+	// LastCounter is the time step of the last accepted code, used to prevent replays.
+	LastCounter uint64 `bson:"last_counter"`
+}
+
+// ErrMfaSecretMissing is returned for the mfa record without the secret, like the ones enrolled by the remote
+// backend, the user must enroll again.
+var ErrMfaSecretMissing = errors.New("mfa record has no secret")
+
+// userMfaStore keeps the secrets of the user mfa records, mgo.ErrNotFound is returned for the missing record.
+type userMfaStore interface {
+	Find(userID, providerID bson.ObjectId) (*userMfaSecret, error)
+	Save(userID, providerID bson.ObjectId, rec *userMfaSecret) error
+	// SetLastCounter returns mgo.ErrNotFound if the last counter has been changed since it was read.
+	SetLastCounter(userID, providerID bson.ObjectId, last, counter uint64) error
+	// PullRecoveryCode returns mgo.ErrNotFound if the recovery code has been already used.
+	PullRecoveryCode(userID, providerID bson.ObjectId, hash string) error
+}
+
+// MfaTotpService is the built-in implementation of the MfaApiInterface based on RFC 6238 time-based one-time passwords.
+// Secrets and hashed recovery codes are stored with the user mfa record.
+type MfaTotpService struct {
+	store userMfaStore
+	now   func() time.Time
+}
+
+// NewMfaTotpService return new built-in TOTP service.
+func NewMfaTotpService(dbHandler database.MgoSession) *MfaTotpService {
+	return &MfaTotpService{store: &mongoUserMfaStore{db: dbHandler.DB("")}, now: time.Now}
+}
+
+func (s *MfaTotpService) Create(ctx context.Context, in *mfa.MfaCreateDataRequest, opts ...client.CallOption) (*mfa.MfaCreateDataResponse, error) {
+	userID, providerID, err := parseMfaIDs(in.UserID, in.ProviderID)
+	if err != nil {
+		return nil, err
+	}
+
+	key := make([]byte, totpSecretSize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, errors.Wrap(err, "unable to generate secret")
+	}
+	secret := totpEncoding.EncodeToString(key)
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.store.Save(userID, providerID, &userMfaSecret{Secret: secret, RecoveryCodes: hashes}); err != nil {
+		return nil, errors.Wrap(err, "unable to save secret")
+	}
+
+	return &mfa.MfaCreateDataResponse{
+		SecretKey:    secret,
+		QrCodeURL:    totpProvisioningURI(in.AppName, in.Email, secret),
+		RecoveryCode: codes,
+	}, nil
+}
+
+func (s *MfaTotpService) Check(ctx context.Context, in *mfa.MfaCheckDataRequest, opts ...client.CallOption) (*mfa.MfaCheckDataResponse, error) {
+	userID, providerID, err := parseMfaIDs(in.UserID, in.ProviderID)
+	if err != nil {
+		return nil, err
+	}
+
+	rec, err := s.store.Find(userID, providerID)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to load secret")
+	}
+
+	// INFO: The empty secret is the empty HMAC key, the codes of it are predictable
+	if rec.Secret == "" {
+		return nil, ErrMfaSecretMissing
+	}
+	key, err := totpEncoding.DecodeString(rec.Secret)
+	if err != nil || len(key) == 0 {
+		return nil, errors.Wrap(ErrMfaSecretMissing, "invalid secret")
+	}
+
+	if len(in.Code) == totpDigits {
+		counter, ok := validateTotp(key, in.Code, s.now())
+		if !ok || counter <= rec.LastCounter {
+			return &mfa.MfaCheckDataResponse{Result: false}, nil
+		}
+
+		err := s.store.SetLastCounter(userID, providerID, rec.LastCounter, counter)
+		if err == mgo.ErrNotFound {
+			// INFO: The same code was accepted concurrently
+			return &mfa.MfaCheckDataResponse{Result: false}, nil
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to save last counter")
+		}
+
+		return &mfa.MfaCheckDataResponse{Result: true}, nil
+	}
+
+	be := models.NewBcryptEncryptor(&models.CryptConfig{Cost: recoveryCodeCost})
+	code := strings.ToUpper(in.Code)
+	for _, hash := range rec.RecoveryCodes {
+		if be.Compare(hash, code) != nil {
+			continue
+		}
+
+		err := s.store.PullRecoveryCode(userID, providerID, hash)
+		if err == mgo.ErrNotFound {
+			// INFO: The same recovery code was redeemed concurrently
+			return &mfa.MfaCheckDataResponse{Result: false}, nil
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to use recovery code")
+		}
+
+		return &mfa.MfaCheckDataResponse{Result: true}, nil
+	}
+
+	return &mfa.MfaCheckDataResponse{Result: false}, nil
+}
+
+type mongoUserMfaStore struct {
+	db *mgo.Database
+}
+
+func (m *mongoUserMfaStore) Find(userID, providerID bson.ObjectId) (*userMfaSecret, error) {
+	rec := &userMfaSecret{}
+	err := m.db.C(database.TableUserMfa).Find(bson.M{"user_id": userID, "provider_id": providerID}).One(rec)
+	return rec, err
+}
+
+func (m *mongoUserMfaStore) Save(userID, providerID bson.ObjectId, rec *userMfaSecret) error {
+	_, err := m.db.C(database.TableUserMfa).Upsert(bson.M{"user_id": userID, "provider_id": providerID}, bson.M{"$set": rec})
+	return err
+}
+
+func (m *mongoUserMfaStore) SetLastCounter(userID, providerID bson.ObjectId, last, counter uint64) error {
+	return m.db.C(database.TableUserMfa).Update(
+		bson.M{"user_id": userID, "provider_id": providerID, "last_counter": last},
+		bson.M{"$set": bson.M{"last_counter": counter}},
+	)
+}
+
+func (m *mongoUserMfaStore) PullRecoveryCode(userID, providerID bson.ObjectId, hash string) error {
+	return m.db.C(database.TableUserMfa).Update(
+		bson.M{"user_id": userID, "provider_id": providerID, "recovery_codes": hash},
+		bson.M{"$pull": bson.M{"recovery_codes": hash}},
+	)
+}
+
+// validateTotp checks the code against the time steps around t and returns the matched time step.
+func validateTotp(key []byte, code string, t time.Time) (uint64, bool) {
+	current := uint64(t.Unix()) / totpPeriod
+	for i := -totpSkew; i <= totpSkew; i++ {
+		counter := uint64(int64(current) + int64(i))
+		if hmac.Equal([]byte(totpCode(key, counter, totpDigits)), []byte(code)) {
+			return counter, true
+		}
+	}
+
+	return 0, false
+}
+
+// totpCode calculates the HOTP value (RFC 4226) for the time step counter.
+func totpCode(key []byte, counter uint64, digits int) string {
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, counter)
+
+	h := hmac.New(sha1.New, key)
+	h.Write(msg)
+	sum := h.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < digits; i++ {
+		mod *= 10
+	}
+
+	return fmt.Sprintf("%0*d", digits, value%mod)
+}
+
+// totpProvisioningURI returns the otpauth uri to be shown as a QR code for authenticator apps.
+func totpProvisioningURI(issuer, account, secret string) string {
+	q := url.Values{}
+	q.Set("secret", secret)
+	q.Set("issuer", issuer)
+	q.Set("algorithm", "SHA1")
+	q.Set("digits", fmt.Sprint(totpDigits))
+	q.Set("period", fmt.Sprint(totpPeriod))
+
+	u := url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + issuer + ":" + account,
+		RawQuery: q.Encode(),
+	}
+
+	return u.String()
+}
+
+// generateRecoveryCodes returns the plain recovery codes for the user and their hashes for storing.
+func generateRecoveryCodes() ([]string, []string, error) {
+	be := models.NewBcryptEncryptor(&models.CryptConfig{Cost: recoveryCodeCost})
+	codes := make([]string, recoveryCodesCount)
+	hashes := make([]string, recoveryCodesCount)
+
+	for i := range codes {
+		b := make([]byte, recoveryCodeSize)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, errors.Wrap(err, "unable to generate recovery code")
+		}
+		codes[i] = totpEncoding.EncodeToString(b)
+
+		hash, err := be.Digest(codes[i])
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "unable to hash recovery code")
+		}
+		hashes[i] = hash
+	}
+
+	return codes, hashes, nil
+}
+
+// parseMfaIDs parses the user and provider ids passed to the mfa api. The ids are accepted both
+// as plain hex strings and in the ObjectIdHex("...") form used by the mfa micro-service requests.
+func parseMfaIDs(userID, providerID string) (bson.ObjectId, bson.ObjectId, error) {
+	ids := make([]bson.ObjectId, 2)
+	for i, id := range []string{userID, providerID} {
+		id = strings.TrimSuffix(strings.TrimPrefix(id, `ObjectIdHex("`), `")`)
+		if !bson.IsObjectIdHex(id) {
+			return "", "", errors.Errorf("invalid object id %q", id)
+		}
+		ids[i] = bson.ObjectIdHex(id)
+	}
+
+	return ids[0], ids[1], nil
+}
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	mfa "github.com/ProtocolONE/mfa-service/pkg/proto"
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test vectors from RFC 6238, Appendix B (SHA1).
+var totpVectors = []struct {
+	time int64
+	code string
+}{
+	{59, "94287082"},
+	{1111111109, "07081804"},
+	{1111111111, "14050471"},
+	{1234567890, "89005924"},
+	{2000000000, "69279037"},
+	{20000000000, "65353130"},
+}
+
+func TestTotpCodeMatchesRFCVectors(t *testing.T) {
+	key := []byte("12345678901234567890")
+	for _, v := range totpVectors {
+		assert.Equal(t, v.code, totpCode(key, uint64(v.time)/totpPeriod, 8))
+	}
+}
+
+func TestValidateTotpAcceptsAdjacentTimeStep(t *testing.T) {
+	key := []byte("12345678901234567890")
+	now := time.Unix(1234567890, 0)
+	code := totpCode(key, uint64(now.Unix())/totpPeriod-1, totpDigits)
+
+	counter, ok := validateTotp(key, code, now)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(now.Unix())/totpPeriod-1, counter)
+
+	_, ok = validateTotp(key, code, now.Add(2*totpPeriod*time.Second))
+	assert.False(t, ok)
+}
+
+func TestTotpProvisioningURI(t *testing.T) {
+	u, err := url.Parse(totpProvisioningURI("Auth1", "user@example.com", "SECRET"))
+	assert.Nil(t, err)
+	assert.Equal(t, "otpauth", u.Scheme)
+	assert.Equal(t, "totp", u.Host)
+	assert.Equal(t, "/Auth1:user@example.com", u.Path)
+	assert.Equal(t, "SECRET", u.Query().Get("secret"))
+	assert.Equal(t, "Auth1", u.Query().Get("issuer"))
+}
+
+func TestGenerateRecoveryCodesReturnsHashes(t *testing.T) {
+	codes, hashes, err := generateRecoveryCodes()
+	assert.Nil(t, err)
+	assert.Len(t, codes, recoveryCodesCount)
+	assert.Len(t, hashes, recoveryCodesCount)
+	assert.NotEqual(t, codes[0], hashes[0])
+}
+
+func TestParseMfaIDsAcceptsBothForms(t *testing.T) {
+	id := bson.NewObjectId()
+
+	u, p, err := parseMfaIDs(id.String(), id.Hex())
+	assert.Nil(t, err)
+	assert.Equal(t, id, u)
+	assert.Equal(t, id, p)
+
+	_, _, err = parseMfaIDs("invalid", id.Hex())
+	assert.NotNil(t, err)
+}
+
+type memoryUserMfaStore map[string]*userMfaSecret
+
+func (m memoryUserMfaStore) Find(userID, providerID bson.ObjectId) (*userMfaSecret, error) {
+	rec, ok := m[userID.Hex()+providerID.Hex()]
+	if !ok {
+		return nil, mgo.ErrNotFound
+	}
+	c := *rec
+	return &c, nil
+}
+
+func (m memoryUserMfaStore) Save(userID, providerID bson.ObjectId, rec *userMfaSecret) error {
+	m[userID.Hex()+providerID.Hex()] = rec
+	return nil
+}
+
+func (m memoryUserMfaStore) SetLastCounter(userID, providerID bson.ObjectId, last, counter uint64) error {
+	rec, ok := m[userID.Hex()+providerID.Hex()]
+	if !ok || rec.LastCounter != last {
+		return mgo.ErrNotFound
+	}
+	rec.LastCounter = counter
+	return nil
+}
+
+func (m memoryUserMfaStore) PullRecoveryCode(userID, providerID bson.ObjectId, hash string) error {
+	rec, ok := m[userID.Hex()+providerID.Hex()]
+	if !ok {
+		return mgo.ErrNotFound
+	}
+	for i, h := range rec.RecoveryCodes {
+		if h == hash {
+			rec.RecoveryCodes = append(rec.RecoveryCodes[:i:i], rec.RecoveryCodes[i+1:]...)
+			return nil
+		}
+	}
+	return mgo.ErrNotFound
+}
+
+func TestMfaTotpCheckAcceptsValidCode(t *testing.T) {
+	userID, providerID := bson.NewObjectId(), bson.NewObjectId()
+	key := []byte("12345678901234567890")
+	now := time.Unix(1234567890, 0)
+	store := memoryUserMfaStore{}
+	_ = store.Save(userID, providerID, &userMfaSecret{Secret: totpEncoding.EncodeToString(key)})
+	s := &MfaTotpService{store: store, now: func() time.Time { return now }}
+
+	code := totpCode(key, uint64(now.Unix())/totpPeriod, totpDigits)
+	res, err := s.Check(context.Background(), &mfa.MfaCheckDataRequest{UserID: userID.Hex(), ProviderID: providerID.Hex(), Code: code})
+	assert.Nil(t, err)
+	assert.True(t, res.Result)
+
+	// INFO: The code is accepted once
+	res, err = s.Check(context.Background(), &mfa.MfaCheckDataRequest{UserID: userID.Hex(), ProviderID: providerID.Hex(), Code: code})
+	assert.Nil(t, err)
+	assert.False(t, res.Result)
+}
+
+func TestMfaTotpCheckRejectsEmptySecret(t *testing.T) {
+	userID, providerID := bson.NewObjectId(), bson.NewObjectId()
+	now := time.Unix(1234567890, 0)
+	store := memoryUserMfaStore{}
+	_ = store.Save(userID, providerID, &userMfaSecret{})
+	s := &MfaTotpService{store: store, now: func() time.Time { return now }}
+
+	code := totpCode(nil, uint64(now.Unix())/totpPeriod, totpDigits)
+	res, err := s.Check(context.Background(), &mfa.MfaCheckDataRequest{UserID: userID.Hex(), ProviderID: providerID.Hex(), Code: code})
+	assert.Equal(t, ErrMfaSecretMissing, err)
+	assert.Nil(t, res)
+}
+
+// staleUserMfaStore returns the record as it was read before a concurrent request changed it.
+type staleUserMfaStore struct {
+	memoryUserMfaStore
+	rec *userMfaSecret
+}
+
+func (m staleUserMfaStore) Find(userID, providerID bson.ObjectId) (*userMfaSecret, error) {
+	c := *m.rec
+	return &c, nil
+}
+
+func TestMfaTotpCheckRedeemsRecoveryCodeOnce(t *testing.T) {
+	userID, providerID := bson.NewObjectId(), bson.NewObjectId()
+	key := []byte("12345678901234567890")
+	codes, hashes, err := generateRecoveryCodes()
+	assert.Nil(t, err)
+
+	rec := &userMfaSecret{Secret: totpEncoding.EncodeToString(key), RecoveryCodes: hashes}
+	store := memoryUserMfaStore{}
+	_ = store.Save(userID, providerID, &userMfaSecret{Secret: rec.Secret, RecoveryCodes: append([]string{}, hashes...)})
+	s := &MfaTotpService{store: staleUserMfaStore{memoryUserMfaStore: store, rec: rec}, now: time.Now}
+
+	res, err := s.Check(context.Background(), &mfa.MfaCheckDataRequest{UserID: userID.Hex(), ProviderID: providerID.Hex(), Code: codes[0]})
+	assert.Nil(t, err)
+	assert.True(t, res.Result)
+
+	// INFO: The concurrent request has read the code before it was pulled
+	res, err = s.Check(context.Background(), &mfa.MfaCheckDataRequest{UserID: userID.Hex(), ProviderID: providerID.Hex(), Code: codes[0]})
+	assert.Nil(t, err)
+	assert.False(t, res.Result)
+}
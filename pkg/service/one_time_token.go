@@ -10,7 +10,34 @@ import (
 	"github.com/go-redis/redis"
 )
 
-const OneTimeTokenStoragePattern = "ott_data_%s"
+const (
+	OneTimeTokenStoragePattern  = "ott_data_%s"
+	OneTimeTokenAttemptsPattern = "ott_attempts_%s"
+)
+
+// attemptScript counts the failed attempt and expires the counter together with the token in the same step,
+// so the counter can't be left without the expiration. The counter of the expired token lives for a second.
+var attemptScript = redis.NewScript(`
+local n = redis.call('INCR', KEYS[1])
+if n == 1 then
+	local ttl = redis.call('PTTL', KEYS[2])
+	if ttl <= 0 then
+		ttl = 1000
+	end
+	redis.call('PEXPIRE', KEYS[1], ttl)
+end
+return n
+`)
+
+// useScript reads the token and deletes it with its attempts counter in the same step, so the concurrent requests
+// can't read the token before it's deleted and only one of them gets its contents.
+var useScript = redis.NewScript(`
+local data = redis.call('GET', KEYS[1])
+if data then
+	redis.call('DEL', KEYS[1], KEYS[2])
+end
+return data
+`)
 
 // OneTimeTokenServiceInterface describes of methods for the one-time token service.
 type OneTimeTokenServiceInterface interface {
@@ -21,8 +48,13 @@ type OneTimeTokenServiceInterface interface {
 	// Get returns the contents of a one-time token by its code.
 	Get(token string, obj interface{}) error
 
-	// Use returns the contents of a one-time token by its code and deletes it.
+	// Use returns the contents of a one-time token by its code and deletes it atomically,
+	// the token is returned to one caller only.
 	Use(token string, obj interface{}) error
+
+	// Attempt increments the counter of failed attempts to use the token and returns its new value.
+	// The counter expires together with the token.
+	Attempt(token string) (int, error)
 }
 
 // OneTimeTokenService is the one-time token service.
@@ -67,9 +99,29 @@ func (s *OneTimeTokenService) Get(token string, obj interface{}) error {
 }
 
 func (s *OneTimeTokenService) Use(token string, d interface{}) error {
-	if err := s.Get(token, &d); err != nil {
+	keys := []string{fmt.Sprintf(OneTimeTokenStoragePattern, token), fmt.Sprintf(OneTimeTokenAttemptsPattern, token)}
+	res, err := useScript.Run(s.Redis, keys).Result()
+	if err != nil {
 		return err
 	}
+	data, ok := res.(string)
+	if !ok {
+		return fmt.Errorf("unexpected token data %v", res)
+	}
+
+	return json.Unmarshal([]byte(data), &d)
+}
+
+func (s *OneTimeTokenService) Attempt(token string) (int, error) {
+	keys := []string{fmt.Sprintf(OneTimeTokenAttemptsPattern, token), fmt.Sprintf(OneTimeTokenStoragePattern, token)}
+	res, err := attemptScript.Run(s.Redis, keys).Result()
+	if err != nil {
+		return 0, err
+	}
+	n, ok := res.(int64)
+	if !ok {
+		return 0, fmt.Errorf("unexpected attempts counter %v", res)
+	}
 
-	return s.Redis.Del(fmt.Sprintf(OneTimeTokenStoragePattern, token)).Err()
+	return int(n), nil
 }
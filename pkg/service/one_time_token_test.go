@@ -3,7 +3,9 @@
 package service
 
 import (
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/ProtocolONE/auth1.protocol.one/pkg/models"
 	"github.com/go-redis/redis"
@@ -72,3 +74,50 @@ func TestOneTimeTokenAlreadyUse(t *testing.T) {
 
 	assert.NotNil(t, err)
 }
+
+func TestOneTimeTokenConcurrentUse(t *testing.T) {
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:6379"})
+	defer client.Close()
+
+	ott := NewOneTimeTokenService(client)
+	token, err := ott.Create("test", &models.OneTimeTokenSettings{Length: 6, TTL: 3})
+	assert.Nil(t, err)
+
+	used := make(chan bool, 10)
+	for i := 0; i < cap(used); i++ {
+		go func() {
+			actual := ""
+			used <- ott.Use(token.Token, &actual) == nil
+		}()
+	}
+
+	n := 0
+	for i := 0; i < cap(used); i++ {
+		if <-used {
+			n++
+		}
+	}
+	assert.Equal(t, 1, n)
+}
+
+func TestOneTimeTokenAttemptsCountsUntilUse(t *testing.T) {
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:6379"})
+	defer client.Close()
+
+	ott := NewOneTimeTokenService(client)
+	token, err := ott.Create("test", &models.OneTimeTokenSettings{Length: 6, TTL: 3})
+	assert.Nil(t, err)
+
+	n, err := ott.Attempt(token.Token)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, n)
+	ttl := client.PTTL(fmt.Sprintf(OneTimeTokenAttemptsPattern, token.Token)).Val()
+	assert.True(t, ttl > 0 && ttl <= 3*time.Second)
+	n, err = ott.Attempt(token.Token)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, n)
+
+	actual := ""
+	assert.Nil(t, ott.Use(token.Token, &actual))
+	assert.Equal(t, int64(0), client.Exists(fmt.Sprintf(OneTimeTokenAttemptsPattern, token.Token)).Val())
+}
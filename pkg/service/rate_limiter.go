@@ -0,0 +1,31 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+const RateLimiterStoragePattern = "rate_limit_%s"
+
+// RateLimiterInterface describes of methods for the rate limiter.
+type RateLimiterInterface interface {
+	// Allow reports whether the action with the key may be done now. The action is allowed once per interval,
+	// the allowed call starts the next interval.
+	Allow(key string, interval time.Duration) (bool, error)
+}
+
+// RateLimiter is the rate limiter keeping the intervals in Redis, so the limit is shared by all instances.
+type RateLimiter struct {
+	Redis *redis.Client
+}
+
+// NewRateLimiter return new rate limiter.
+func NewRateLimiter(redis *redis.Client) *RateLimiter {
+	return &RateLimiter{Redis: redis}
+}
+
+func (s *RateLimiter) Allow(key string, interval time.Duration) (bool, error) {
+	return s.Redis.SetNX(fmt.Sprintf(RateLimiterStoragePattern, key), 1, interval).Result()
+}
@@ -0,0 +1,33 @@
+// +build integration
+
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/globalsign/mgo/bson"
+	"github.com/go-redis/redis"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiterAllowsOncePerInterval(t *testing.T) {
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:6379"})
+	defer client.Close()
+
+	rl := NewRateLimiter(client)
+	key := bson.NewObjectId().Hex()
+
+	ok, err := rl.Allow(key, time.Second)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	ok, err = rl.Allow(key, time.Second)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	time.Sleep(1100 * time.Millisecond)
+	ok, err = rl.Allow(key, time.Second)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+}
@@ -3,7 +3,10 @@ package service
 import (
 	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/repository"
 	"github.com/ProtocolONE/auth1.protocol.one/pkg/database"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/passwords"
 	"github.com/ProtocolONE/auth1.protocol.one/pkg/persist"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/saml"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/webhooks"
 )
 
 // InternalRegistry describes of methods the registry service.
@@ -37,6 +40,24 @@ type InternalRegistry interface {
 	// LauncherTokenService returns instance of the launcher token service
 	LauncherTokenService() LauncherTokenServiceInterface
 
+	// RateLimiter return instance of the rate limiter.
+	RateLimiter() RateLimiterInterface
+
+	// LoginAttempts return instance of the counters of the failed logins.
+	LoginAttempts() LoginAttemptsInterface
+
+	// BreachedPasswords return the corpus of the breached passwords, nil if it isn't configured.
+	BreachedPasswords() passwords.Corpus
+
+	// SAMLKey return the key the SAML authentication requests are signed with, nil if it isn't configured.
+	SAMLKey() *saml.KeyPair
+
 	// Mailer return client of the postman service.
 	Mailer() MailerInterface
+
+	// SmsSender return client of the sms delivery.
+	SmsSender() SmsSenderInterface
+
+	// WebHooks return the service publishing the events to the application webhooks.
+	WebHooks() *webhooks.WebHooks
 }
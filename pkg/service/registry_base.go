@@ -3,25 +3,34 @@ package service
 import (
 	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/repository"
 	"github.com/ProtocolONE/auth1.protocol.one/pkg/database"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/passwords"
 	"github.com/ProtocolONE/auth1.protocol.one/pkg/persist"
 	"github.com/ProtocolONE/auth1.protocol.one/pkg/persist/redis"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/saml"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/webhooks"
 	"github.com/go-redis/redis"
 )
 
 // RegistryBase contains common services.
 type RegistryBase struct {
-	redis     *redis.Client
-	session   database.MgoSession
-	as        ApplicationServiceInterface
-	spaces    repository.SpaceRepository
-	ott       OneTimeTokenServiceInterface
-	lts       LauncherTokenServiceInterface
-	watcher   persist.Watcher
-	hydra     HydraAdminApi
-	mfa       MfaApiInterface
-	geo       GeoIp
-	mailer    MailerInterface
-	cent      CentrifugoServiceInterface
+	redis    *redis.Client
+	session  database.MgoSession
+	as       ApplicationServiceInterface
+	spaces   repository.SpaceRepository
+	ott      OneTimeTokenServiceInterface
+	lts      LauncherTokenServiceInterface
+	rl       RateLimiterInterface
+	la       LoginAttemptsInterface
+	breached passwords.Corpus
+	samlKey  *saml.KeyPair
+	watcher  persist.Watcher
+	hydra    HydraAdminApi
+	mfa      MfaApiInterface
+	geo      GeoIp
+	mailer   MailerInterface
+	sms      SmsSenderInterface
+	cent     CentrifugoServiceInterface
+	webhooks *webhooks.WebHooks
 }
 
 // RegistryConfig contains the configuration parameters of Registry
@@ -44,25 +53,43 @@ type RegistryConfig struct {
 	// Mailer is the interface for the postman.
 	Mailer MailerInterface
 
+	// SmsSender is the interface for the sms delivery.
+	SmsSender SmsSenderInterface
+
 	// CentrifugoService
 	CentrifugoService CentrifugoServiceInterface
 
 	Spaces repository.SpaceRepository
+
+	// WebHooks is the service publishing the events to the application webhooks.
+	WebHooks *webhooks.WebHooks
+
+	// BreachedPasswords is the corpus of the breached passwords rejected by the spaces.
+	BreachedPasswords passwords.Corpus
+
+	// SAMLKey is the key the service provider signs the SAML authentication requests with.
+	SAMLKey *saml.KeyPair
 }
 
 // NewRegistryBase creates new registry service.
 func NewRegistryBase(config *RegistryConfig) InternalRegistry {
 	r := &RegistryBase{
-		session:   config.MgoSession,
-		redis:     config.RedisClient,
-		hydra:     config.HydraAdminApi,
-		mfa:       config.MfaService,
-		mailer:    config.Mailer,
-		geo:       config.GeoIpService,
-		ott:       NewOneTimeTokenService(config.RedisClient),
-		lts:       NewLauncherTokenService(config.RedisClient),
-		cent:      config.CentrifugoService,
-		spaces:    config.Spaces,
+		session:  config.MgoSession,
+		redis:    config.RedisClient,
+		hydra:    config.HydraAdminApi,
+		mfa:      config.MfaService,
+		mailer:   config.Mailer,
+		sms:      config.SmsSender,
+		geo:      config.GeoIpService,
+		ott:      NewOneTimeTokenService(config.RedisClient),
+		lts:      NewLauncherTokenService(config.RedisClient),
+		rl:       NewRateLimiter(config.RedisClient),
+		la:       NewLoginAttempts(config.RedisClient),
+		cent:     config.CentrifugoService,
+		spaces:   config.Spaces,
+		webhooks: config.WebHooks,
+		breached: config.BreachedPasswords,
+		samlKey:  config.SAMLKey,
 	}
 	r.as = NewApplicationService(r)
 
@@ -97,6 +124,14 @@ func (r *RegistryBase) Mailer() MailerInterface {
 	return r.mailer
 }
 
+func (r *RegistryBase) SmsSender() SmsSenderInterface {
+	return r.sms
+}
+
+func (r *RegistryBase) WebHooks() *webhooks.WebHooks {
+	return r.webhooks
+}
+
 func (r *RegistryBase) ApplicationService() ApplicationServiceInterface {
 	return r.as
 }
@@ -116,3 +151,19 @@ func (r *RegistryBase) CentrifugoService() CentrifugoServiceInterface {
 func (r *RegistryBase) LauncherTokenService() LauncherTokenServiceInterface {
 	return r.lts
 }
+
+func (r *RegistryBase) RateLimiter() RateLimiterInterface {
+	return r.rl
+}
+
+func (r *RegistryBase) LoginAttempts() LoginAttemptsInterface {
+	return r.la
+}
+
+func (r *RegistryBase) BreachedPasswords() passwords.Corpus {
+	return r.breached
+}
+
+func (r *RegistryBase) SAMLKey() *saml.KeyPair {
+	return r.samlKey
+}
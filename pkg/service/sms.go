@@ -0,0 +1,143 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/config"
+	"github.com/pkg/errors"
+)
+
+const (
+	// SmsBackendTwilio is the name of the sender delivering messages by Twilio.
+	SmsBackendTwilio = "twilio"
+
+	// SmsBackendFile is the name of the sender appending messages to the file.
+	SmsBackendFile = "file"
+
+	// SmsBackendNone is the name of the sender refusing to send messages, the sms channel is disabled.
+	SmsBackendNone = "none"
+)
+
+// ErrSmsDisabled is returned by the sender of the "none" backend.
+var ErrSmsDisabled = errors.New("sms delivery is disabled")
+
+// SmsSenderInterface describes of methods for the sms sender.
+type SmsSenderInterface interface {
+	// Send sends the text message to the specified phone number.
+	Send(to, message string) error
+}
+
+// NewSmsSender return new sms sender for the configured backend.
+func NewSmsSender(cfg *config.Sms) (SmsSenderInterface, error) {
+	switch cfg.Backend {
+	case SmsBackendTwilio:
+		if cfg.TwilioAccountSID == "" || cfg.TwilioAuthToken == "" || cfg.TwilioFrom == "" {
+			return nil, errors.New("twilio sms backend requires account sid, auth token and sender number, use the \"none\" backend to disable sms")
+		}
+		return NewSmsTwilioSender(cfg.TwilioURL, cfg.TwilioAccountSID, cfg.TwilioAuthToken, cfg.TwilioFrom), nil
+	case SmsBackendFile:
+		return NewSmsFileSender(cfg.File), nil
+	case SmsBackendNone:
+		return &SmsNoneSender{}, nil
+	}
+
+	return nil, errors.Errorf("unknown sms backend %q", cfg.Backend)
+}
+
+// SmsNoneSender refuses to send messages, so the codes of the sms channel can't be delivered.
+type SmsNoneSender struct{}
+
+func (s *SmsNoneSender) Send(to, message string) error {
+	return ErrSmsDisabled
+}
+
+// SmsTwilioSender delivers messages by the Messages resource of the Twilio REST API.
+type SmsTwilioSender struct {
+	url        string
+	accountSID string
+	authToken  string
+	from       string
+	client     *http.Client
+}
+
+// NewSmsTwilioSender return new sms sender of the Twilio account, the messages are sent from the specified number.
+func NewSmsTwilioSender(apiURL, accountSID, authToken, from string) *SmsTwilioSender {
+	return &SmsTwilioSender{
+		url:        strings.TrimSuffix(apiURL, "/"),
+		accountSID: accountSID,
+		authToken:  authToken,
+		from:       from,
+		client:     &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (s *SmsTwilioSender) Send(to, message string) error {
+	v := url.Values{"To": {to}, "From": {s.from}, "Body": {message}}
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/2010-04-01/Accounts/%s/Messages.json", s.url, url.PathEscape(s.accountSID)), strings.NewReader(v.Encode()))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(s.accountSID, s.authToken)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "unable to send sms")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		// INFO: The error of Twilio doesn't contain the message, so it can be logged
+		var e struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&e)
+		return errors.Errorf("unable to send sms: %s, twilio error %d: %s", resp.Status, e.Code, e.Message)
+	}
+	return nil
+}
+
+// SmsFileMessage is the record written by the SmsFileSender.
+type SmsFileMessage struct {
+	To      string    `json:"to"`
+	Message string    `json:"message"`
+	SentAt  time.Time `json:"sent_at"`
+}
+
+// SmsFileSender appends messages to the file as json lines, so they can be read by tests.
+type SmsFileSender struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewSmsFileSender return new sms sender writing to the file.
+func NewSmsFileSender(path string) *SmsFileSender {
+	return &SmsFileSender{path: path}
+}
+
+func (s *SmsFileSender) Send(to, message string) error {
+	data, err := json.Marshal(&SmsFileMessage{To: to, Message: message, SentAt: time.Now()})
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return errors.Wrap(err, "unable to open sms file")
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSmsFileSenderAppendsMessages(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sms")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	s := NewSmsFileSender(filepath.Join(dir, "sms.log"))
+	assert.Nil(t, s.Send("+10000000001", "first"))
+	assert.Nil(t, s.Send("+10000000002", "second"))
+
+	f, err := os.Open(filepath.Join(dir, "sms.log"))
+	assert.Nil(t, err)
+	defer f.Close()
+
+	var messages []SmsFileMessage
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		m := SmsFileMessage{}
+		assert.Nil(t, json.Unmarshal(scanner.Bytes(), &m))
+		messages = append(messages, m)
+	}
+
+	if assert.Len(t, messages, 2) {
+		assert.Equal(t, "+10000000001", messages[0].To)
+		assert.Equal(t, "second", messages[1].Message)
+	}
+}
+
+func TestNewSmsSenderReturnErrorWithUnknownBackend(t *testing.T) {
+	_, err := NewSmsSender(&config.Sms{Backend: "unknown"})
+	assert.NotNil(t, err)
+
+	s, err := NewSmsSender(&config.Sms{Backend: SmsBackendNone})
+	assert.Nil(t, err)
+	assert.Equal(t, ErrSmsDisabled, s.Send("+10000000001", "code"))
+}
+
+func TestNewSmsSenderReturnErrorWithoutTwilioCredentials(t *testing.T) {
+	_, err := NewSmsSender(&config.Sms{Backend: SmsBackendTwilio, TwilioAccountSID: "AC1", TwilioFrom: "+10000000000"})
+	assert.NotNil(t, err)
+
+	s, err := NewSmsSender(&config.Sms{Backend: SmsBackendTwilio, TwilioAccountSID: "AC1", TwilioAuthToken: "token", TwilioFrom: "+10000000000"})
+	assert.Nil(t, err)
+	assert.IsType(t, &SmsTwilioSender{}, s)
+}
+
+func TestSmsTwilioSenderSendsMessage(t *testing.T) {
+	var form url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sid, token, _ := r.BasicAuth()
+		if r.URL.Path != "/2010-04-01/Accounts/AC1/Messages.json" || sid != "AC1" || token != "token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"code":20003,"message":"Authenticate"}`))
+			return
+		}
+		r.ParseForm()
+		form = r.PostForm
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+
+	s := NewSmsTwilioSender(srv.URL, "AC1", "token", "+10000000000")
+	assert.Nil(t, s.Send("+10000000001", "code"))
+	assert.Equal(t, "+10000000001", form.Get("To"))
+	assert.Equal(t, "+10000000000", form.Get("From"))
+	assert.Equal(t, "code", form.Get("Body"))
+
+	err := NewSmsTwilioSender(srv.URL, "AC1", "other", "+10000000000").Send("+10000000001", "code")
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "20003")
+	}
+}
@@ -15,6 +15,9 @@ type UserIdentityServiceInterface interface {
 	// Update updates user identity data.
 	Update(userIdentity *models.UserIdentity) error
 
+	// UpdateCredential replaces the password hash of the user identity.
+	UpdateCredential(id bson.ObjectId, credential string) error
+
 	// Get return the user identity by id.
 	Get(ip *models.AppIdentityProvider, externalID string) (*models.UserIdentity, error)
 
@@ -49,6 +52,10 @@ func (us UserIdentityService) Update(userIdentity *models.UserIdentity) error {
 	return nil
 }
 
+func (us UserIdentityService) UpdateCredential(id bson.ObjectId, credential string) error {
+	return us.db.C(database.TableUserIdentity).UpdateId(id, bson.M{"$set": bson.M{"credential": credential}})
+}
+
 func (us UserIdentityService) FindByUser(ip *models.AppIdentityProvider, userId bson.ObjectId) (*models.UserIdentity, error) {
 	ui := &models.UserIdentity{}
 	if err := us.db.C(database.TableUserIdentity).
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"time"
+
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/database"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/models"
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+)
+
+// WebAuthnServiceInterface describes of methods for the WebAuthn credentials service.
+type WebAuthnServiceInterface interface {
+	// Add adds the credential registered by the user.
+	Add(*models.WebAuthnCredential) error
+
+	// GetUserCredentials returns a list of the credentials registered by the user.
+	GetUserCredentials(bson.ObjectId) ([]*models.WebAuthnCredential, error)
+
+	// GetByCredentialID returns the credential by the id generated by the authenticator.
+	GetByCredentialID([]byte) (*models.WebAuthnCredential, error)
+
+	// UpdateSignCount saves the signature counter and the usage time after successful login.
+	UpdateSignCount(id bson.ObjectId, signCount int64) error
+
+	// Remove removes the credential by id for the user.
+	Remove(userID, id bson.ObjectId) error
+}
+
+// WebAuthnService is the WebAuthn credentials service.
+type WebAuthnService struct {
+	db *mgo.Database
+}
+
+// NewWebAuthnService return new WebAuthn credentials service.
+func NewWebAuthnService(dbHandler database.MgoSession) *WebAuthnService {
+	return &WebAuthnService{db: dbHandler.DB("")}
+}
+
+func (s *WebAuthnService) Add(c *models.WebAuthnCredential) error {
+	return s.db.C(database.TableUserWebAuthn).Insert(c)
+}
+
+func (s *WebAuthnService) GetUserCredentials(userID bson.ObjectId) (credentials []*models.WebAuthnCredential, err error) {
+	if err := s.db.C(database.TableUserWebAuthn).Find(bson.M{"user_id": userID}).All(&credentials); err != nil {
+		return nil, err
+	}
+
+	return credentials, nil
+}
+
+func (s *WebAuthnService) GetByCredentialID(id []byte) (*models.WebAuthnCredential, error) {
+	c := &models.WebAuthnCredential{}
+	if err := s.db.C(database.TableUserWebAuthn).Find(bson.M{"credential_id": id}).One(c); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (s *WebAuthnService) UpdateSignCount(id bson.ObjectId, signCount int64) error {
+	return s.db.C(database.TableUserWebAuthn).UpdateId(id, bson.M{"$set": bson.M{"sign_count": signCount, "last_used_at": time.Now()}})
+}
+
+func (s *WebAuthnService) Remove(userID, id bson.ObjectId) error {
+	return s.db.C(database.TableUserWebAuthn).Remove(bson.M{"_id": id, "user_id": userID})
+}
@@ -0,0 +1,259 @@
+// Package steam contains the relying party side of the OpenID 2.0 login of Steam used by the identity providers
+// of the "steam" type: the positive assertions are verified by the check_authentication request to Steam, and
+// the profile of the player is loaded from the Steam Web API with the key of the provider.
+package steam
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// OpenIDURL is the OpenID 2.0 endpoint of Steam.
+	OpenIDURL = "https://steamcommunity.com/openid/login"
+
+	// APIURL is the base url of the Steam Web API.
+	APIURL = "https://api.steampowered.com"
+
+	nsOpenID         = "http://specs.openid.net/auth/2.0"
+	identifierSelect = "http://specs.openid.net/auth/2.0/identifier_select"
+)
+
+// MaxNonceAge is the time the positive assertion is accepted for after it has been issued.
+var MaxNonceAge = 5 * time.Minute
+
+var ErrInvalidResponse = errors.New("invalid steam openid response")
+
+var now = time.Now
+
+// claimedID is the identifier Steam asserts, it ends with the 64-bit steam id of the player.
+var claimedID = regexp.MustCompile(`^https://steamcommunity\.com/openid/id/([0-9]+)$`)
+
+// signedFields must be covered by the signature of the positive assertion.
+var signedFields = []string{"op_endpoint", "claimed_id", "identity", "return_to", "response_nonce", "assoc_handle"}
+
+// Client is the relying party of Steam.
+type Client struct {
+	// OpenIDURL is the OpenID 2.0 endpoint the users are sent to and the assertions are verified by.
+	OpenIDURL string
+
+	// APIURL is the base url of the Steam Web API.
+	APIURL string
+
+	// Key is the Steam Web API key.
+	Key string
+
+	// HTTPClient sends the requests to Steam, http.DefaultClient is used if it is nil.
+	HTTPClient *http.Client
+}
+
+// NewClient returns the client of the endpoints, the ones of Steam are used for the empty urls.
+func NewClient(openIDURL, apiURL, key string) *Client {
+	if openIDURL == "" {
+		openIDURL = OpenIDURL
+	}
+	if apiURL == "" {
+		apiURL = APIURL
+	}
+	return &Client{OpenIDURL: openIDURL, APIURL: strings.TrimSuffix(apiURL, "/"), Key: key}
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+// AuthURL returns the url of the checkid_setup request, Steam redirects the user to the return url with
+// the assertion after the login. The realm is the url the user is asked to trust, the return url must be under it.
+func (c *Client) AuthURL(realm, returnTo string) string {
+	v := url.Values{
+		"openid.ns":         {nsOpenID},
+		"openid.mode":       {"checkid_setup"},
+		"openid.claimed_id": {identifierSelect},
+		"openid.identity":   {identifierSelect},
+		"openid.return_to":  {returnTo},
+		"openid.realm":      {realm},
+	}
+	if strings.Contains(c.OpenIDURL, "?") {
+		return c.OpenIDURL + "&" + v.Encode()
+	}
+	return c.OpenIDURL + "?" + v.Encode()
+}
+
+// Verify verifies the positive assertion the user is redirected to the return url with and returns the steam id
+// of the player: the assertion must be issued by the endpoint of the client for the return url and recently, and
+// Steam must confirm its signature. The query is the whole query of the request to the return url.
+func (c *Client) Verify(ctx context.Context, returnTo string, query url.Values) (string, error) {
+	if query.Get("openid.ns") != nsOpenID {
+		return "", errors.Wrap(ErrInvalidResponse, "openid 2.0 response is expected")
+	}
+	if mode := query.Get("openid.mode"); mode != "id_res" {
+		return "", errors.Wrapf(ErrInvalidResponse, "mode %q isn't positive assertion", mode)
+	}
+	if query.Get("openid.op_endpoint") != c.OpenIDURL {
+		return "", errors.Wrap(ErrInvalidResponse, "assertion isn't issued by steam")
+	}
+	if err := checkReturnTo(returnTo, query); err != nil {
+		return "", err
+	}
+
+	m := claimedID.FindStringSubmatch(query.Get("openid.claimed_id"))
+	if m == nil {
+		return "", errors.Wrapf(ErrInvalidResponse, "claimed id %q isn't steam id", query.Get("openid.claimed_id"))
+	}
+	if query.Get("openid.identity") != query.Get("openid.claimed_id") {
+		return "", errors.Wrap(ErrInvalidResponse, "identity doesn't match claimed id")
+	}
+
+	signed := map[string]bool{}
+	for _, f := range strings.Split(query.Get("openid.signed"), ",") {
+		signed[f] = true
+	}
+	for _, f := range signedFields {
+		if !signed[f] {
+			return "", errors.Wrapf(ErrInvalidResponse, "%s isn't signed", f)
+		}
+	}
+
+	if err := checkNonce(query.Get("openid.response_nonce")); err != nil {
+		return "", err
+	}
+
+	if err := c.checkAuthentication(ctx, query); err != nil {
+		return "", err
+	}
+
+	return m[1], nil
+}
+
+// checkReturnTo checks the assertion is sent to the return url and the parameters of the return url
+// match the ones of the request.
+func checkReturnTo(returnTo string, query url.Values) error {
+	u, err := url.Parse(query.Get("openid.return_to"))
+	if err != nil {
+		return errors.Wrap(ErrInvalidResponse, err.Error())
+	}
+	if u.Scheme+"://"+u.Host+u.Path != returnTo {
+		return errors.Wrapf(ErrInvalidResponse, "return url %q isn't expected", query.Get("openid.return_to"))
+	}
+	for k, v := range u.Query() {
+		if len(v) != 1 || query.Get(k) != v[0] {
+			return errors.Wrapf(ErrInvalidResponse, "parameter %s doesn't match return url", k)
+		}
+	}
+	return nil
+}
+
+// checkNonce checks the assertion has been issued recently, the nonce starts with the time it has been issued at.
+func checkNonce(nonce string) error {
+	if len(nonce) < len("2006-01-02T15:04:05Z") {
+		return errors.Wrap(ErrInvalidResponse, "response nonce is missing")
+	}
+	t, err := time.Parse(time.RFC3339, nonce[:len("2006-01-02T15:04:05Z")])
+	if err != nil {
+		return errors.Wrap(ErrInvalidResponse, "invalid response nonce")
+	}
+	if d := now().Sub(t); d > MaxNonceAge || d < -MaxNonceAge {
+		return errors.Wrap(ErrInvalidResponse, "assertion is expired")
+	}
+	return nil
+}
+
+// checkAuthentication asks Steam to verify the signature of the assertion, the nonce is invalidated by Steam
+// so the assertion can't be verified twice.
+func (c *Client) checkAuthentication(ctx context.Context, query url.Values) error {
+	v := url.Values{}
+	for k := range query {
+		if strings.HasPrefix(k, "openid.") {
+			v.Set(k, query.Get(k))
+		}
+	}
+	v.Set("openid.mode", "check_authentication")
+
+	req, err := http.NewRequest(http.MethodPost, c.OpenIDURL, strings.NewReader(v.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := c.httpClient().Do(req.WithContext(ctx))
+	if err != nil {
+		return errors.Wrap(err, "unable to verify steam assertion")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("unable to verify steam assertion: %s", resp.Status)
+	}
+
+	// INFO: The response is in the key-value form, the lines of "key:value"
+	valid := false
+	s := bufio.NewScanner(resp.Body)
+	for s.Scan() {
+		if kv := strings.SplitN(s.Text(), ":", 2); len(kv) == 2 && kv[0] == "is_valid" {
+			valid = kv[1] == "true"
+		}
+	}
+	if err := s.Err(); err != nil {
+		return errors.Wrap(err, "unable to read steam verification")
+	}
+	if !valid {
+		return errors.Wrap(ErrInvalidResponse, "steam hasn't confirmed assertion")
+	}
+	return nil
+}
+
+// Player is the summary of the player returned by the Steam Web API.
+type Player map[string]interface{}
+
+// String returns the value of the field if it is the string or the number.
+func (p Player) String(name string) string {
+	switch v := p[name].(type) {
+	case string:
+		return v
+	case float64:
+		return fmt.Sprintf("%.0f", v)
+	}
+	return ""
+}
+
+// Player returns the summary of the player by the steam id.
+func (c *Client) Player(ctx context.Context, steamID string) (Player, error) {
+	v := url.Values{"key": {c.Key}, "steamids": {steamID}}
+	req, err := http.NewRequest(http.MethodGet, c.APIURL+"/ISteamUser/GetPlayerSummaries/v2/?"+v.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.httpClient().Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to get steam player")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("unable to get steam player: %s", resp.Status)
+	}
+
+	var r struct {
+		Response struct {
+			Players []Player `json:"players"`
+		} `json:"response"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return nil, errors.Wrap(err, "invalid steam player summaries")
+	}
+	for _, p := range r.Response.Players {
+		if p.String("steamid") == steamID {
+			return p, nil
+		}
+	}
+	return nil, errors.Errorf("steam player %s not found", steamID)
+}
@@ -0,0 +1,138 @@
+package steam
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/steam/steamtest"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testSteamID  = "76561197960435530"
+	testReturnTo = "http://localhost/api/providers/steam/callback"
+)
+
+func newTestClient() (*Client, *steamtest.Server) {
+	srv := steamtest.NewServer("key")
+	srv.Players[testSteamID] = map[string]interface{}{
+		"steamid":     testSteamID,
+		"personaname": "robin",
+		"avatarfull":  "https://example.com/avatar.jpg",
+	}
+	return NewClient(srv.OpenIDURL(), srv.APIURL(), "key"), srv
+}
+
+// login signs in the player at the stand-in and returns the query of the request to the return url.
+func login(t *testing.T, c *Client, srv *steamtest.Server) url.Values {
+	callback, err := srv.Login(c.AuthURL("http://localhost", testReturnTo+"?state=abc"), testSteamID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(callback)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u.Query()
+}
+
+func TestAuthURL(t *testing.T) {
+	c := NewClient("", "", "key")
+	u, err := url.Parse(c.AuthURL("http://localhost", testReturnTo))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "steamcommunity.com", u.Host)
+	q := u.Query()
+	assert.Equal(t, "checkid_setup", q.Get("openid.mode"))
+	assert.Equal(t, identifierSelect, q.Get("openid.claimed_id"))
+	assert.Equal(t, testReturnTo, q.Get("openid.return_to"))
+	assert.Equal(t, "http://localhost", q.Get("openid.realm"))
+}
+
+func TestVerifyReturnsSteamID(t *testing.T) {
+	c, srv := newTestClient()
+	defer srv.Close()
+
+	id, err := c.Verify(context.Background(), testReturnTo, login(t, c, srv))
+	assert.NoError(t, err)
+	assert.Equal(t, testSteamID, id)
+}
+
+func TestVerifyRejectsInvalidAssertions(t *testing.T) {
+	c, srv := newTestClient()
+	defer srv.Close()
+
+	cases := map[string]func(q url.Values){
+		"cancel":         func(q url.Values) { q.Set("openid.mode", "cancel") },
+		"other endpoint": func(q url.Values) { q.Set("openid.op_endpoint", "https://example.com/openid") },
+		"other return":   func(q url.Values) { q.Set("openid.return_to", "http://localhost/other?state=abc") },
+		"other state":    func(q url.Values) { q.Set("state", "xyz") },
+		"other player": func(q url.Values) {
+			q.Set("openid.claimed_id", "https://steamcommunity.com/openid/id/1")
+			q.Set("openid.identity", "https://steamcommunity.com/openid/id/1")
+		},
+		"other identity": func(q url.Values) { q.Set("openid.identity", "https://steamcommunity.com/openid/id/1") },
+		"not steam id": func(q url.Values) {
+			q.Set("openid.claimed_id", "https://example.com/id/1")
+			q.Set("openid.identity", "https://example.com/id/1")
+		},
+		"unsigned field": func(q url.Values) { q.Set("openid.signed", "signed,op_endpoint,claimed_id,identity,return_to") },
+		"expired":        func(q url.Values) { q.Set("openid.response_nonce", "2019-01-01T00:00:00Zabc") },
+		"invalid sig":    func(q url.Values) { q.Set("openid.sig", "AAAA") },
+		"invalid nonce":  func(q url.Values) { q.Set("openid.response_nonce", "abc") },
+		"missing openid": func(q url.Values) { q.Del("openid.ns") },
+		"unsigned return": func(q url.Values) {
+			q.Set("openid.return_to", testReturnTo+"?state=xyz")
+			q.Set("state", "xyz")
+		},
+	}
+	for name, tamper := range cases {
+		q := login(t, c, srv)
+		tamper(q)
+		_, err := c.Verify(context.Background(), testReturnTo, q)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestVerifyRejectsReplayedAssertion(t *testing.T) {
+	c, srv := newTestClient()
+	defer srv.Close()
+
+	q := login(t, c, srv)
+	_, err := c.Verify(context.Background(), testReturnTo, q)
+	assert.NoError(t, err)
+	_, err = c.Verify(context.Background(), testReturnTo, q)
+	assert.Error(t, err)
+}
+
+func TestCheckNonceAllowsRecentAssertion(t *testing.T) {
+	defer func() { now = time.Now }()
+	now = func() time.Time { return time.Date(2019, 1, 1, 0, 4, 0, 0, time.UTC) }
+
+	assert.NoError(t, checkNonce("2019-01-01T00:00:00Zabc"))
+	assert.Error(t, checkNonce("2018-12-31T23:58:00Zabc"))
+}
+
+func TestPlayer(t *testing.T) {
+	c, srv := newTestClient()
+	defer srv.Close()
+
+	p, err := c.Player(context.Background(), testSteamID)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "robin", p.String("personaname"))
+	}
+
+	_, err = c.Player(context.Background(), "1")
+	assert.Error(t, err)
+
+	c.Key = "other"
+	_, err = c.Player(context.Background(), testSteamID)
+	if assert.Error(t, err) {
+		assert.True(t, strings.Contains(err.Error(), "403"))
+	}
+}
@@ -0,0 +1,145 @@
+// Package steamtest provides the local stand-in of Steam for tests: the OpenID 2.0 endpoint signs the assertions
+// of the players and verifies them once, and the Steam Web API returns the summaries of the players.
+package steamtest
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const nsOpenID = "http://specs.openid.net/auth/2.0"
+
+// Server is the stand-in of Steam.
+type Server struct {
+	*httptest.Server
+
+	// Key is the only Steam Web API key the server accepts.
+	Key string
+
+	// Players are the summaries of the players by the steam id.
+	Players map[string]map[string]interface{}
+
+	secret []byte
+
+	mu     sync.Mutex
+	nonces map[string]bool
+}
+
+// NewServer starts the server accepting the Steam Web API key, it must be closed by the caller.
+func NewServer(key string) *Server {
+	s := &Server{
+		Key:     key,
+		Players: map[string]map[string]interface{}{},
+		secret:  make([]byte, 32),
+		nonces:  map[string]bool{},
+	}
+	rand.Read(s.secret)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/openid/login", s.checkAuthentication)
+	mux.HandleFunc("/ISteamUser/GetPlayerSummaries/v2/", s.playerSummaries)
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// OpenIDURL returns the url of the OpenID 2.0 endpoint.
+func (s *Server) OpenIDURL() string {
+	return s.URL + "/openid/login"
+}
+
+// APIURL returns the base url of the Steam Web API.
+func (s *Server) APIURL() string {
+	return s.URL
+}
+
+// Login returns the url Steam redirects the user to after the player of the steam id has signed in
+// by the checkid_setup request of the auth url.
+func (s *Server) Login(authURL, steamID string) (string, error) {
+	u, err := url.Parse(authURL)
+	if err != nil {
+		return "", err
+	}
+	returnTo := u.Query().Get("openid.return_to")
+	if returnTo == "" {
+		return "", fmt.Errorf("return url is missing")
+	}
+
+	b := make([]byte, 8)
+	rand.Read(b)
+	id := "https://steamcommunity.com/openid/id/" + steamID
+	v := url.Values{
+		"openid.ns":             {nsOpenID},
+		"openid.mode":           {"id_res"},
+		"openid.op_endpoint":    {s.OpenIDURL()},
+		"openid.claimed_id":     {id},
+		"openid.identity":       {id},
+		"openid.return_to":      {returnTo},
+		"openid.response_nonce": {time.Now().UTC().Format("2006-01-02T15:04:05Z") + hex.EncodeToString(b)},
+		"openid.assoc_handle":   {"1234567890"},
+		"openid.signed":         {"signed,op_endpoint,claimed_id,identity,return_to,response_nonce,assoc_handle"},
+	}
+	v.Set("openid.sig", s.sign(v))
+
+	if strings.Contains(returnTo, "?") {
+		return returnTo + "&" + v.Encode(), nil
+	}
+	return returnTo + "?" + v.Encode(), nil
+}
+
+// sign returns the signature of the signed fields in the key-value form.
+func (s *Server) sign(v url.Values) string {
+	mac := hmac.New(sha256.New, s.secret)
+	for _, f := range strings.Split(v.Get("openid.signed"), ",") {
+		fmt.Fprintf(mac, "%s:%s\n", f, v.Get("openid."+f))
+	}
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (s *Server) checkAuthentication(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.PostFormValue("openid.mode") != "check_authentication" {
+		http.Error(w, "check_authentication is expected", http.StatusBadRequest)
+		return
+	}
+
+	v := r.PostForm
+	valid := hmac.Equal([]byte(v.Get("openid.sig")), []byte(s.sign(v)))
+	if valid {
+		s.mu.Lock()
+		nonce := v.Get("openid.response_nonce")
+		valid = !s.nonces[nonce]
+		s.nonces[nonce] = true
+		s.mu.Unlock()
+	}
+
+	fmt.Fprintf(w, "ns:%s\nis_valid:%t\n", nsOpenID, valid)
+}
+
+func (s *Server) playerSummaries(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("key") != s.Key {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	players := []map[string]interface{}{}
+	for _, id := range strings.Split(r.URL.Query().Get("steamids"), ",") {
+		if p, ok := s.Players[id]; ok {
+			players = append(players, p)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"response": map[string]interface{}{"players": players},
+	})
+}
@@ -0,0 +1,86 @@
+package webauthn
+
+import (
+	"encoding/binary"
+
+	"github.com/pkg/errors"
+)
+
+// Authenticator data flags.
+const (
+	flagUserPresent  = 0x01
+	flagUserVerified = 0x04
+	flagAttestedData = 0x40
+	flagExtensions   = 0x80
+
+	authDataMinLength = 37
+	aaguidLength      = 16
+)
+
+// authenticatorData is the parsed authenticator data structure, see WebAuthn Level 1, section 6.1.
+type authenticatorData struct {
+	RPIDHash  []byte
+	Flags     byte
+	SignCount uint32
+
+	// Attested credential data, present only in the registration response.
+	AAGUID       []byte
+	CredentialID []byte
+	PublicKey    []byte
+}
+
+func (d *authenticatorData) UserPresent() bool {
+	return d.Flags&flagUserPresent != 0
+}
+
+func (d *authenticatorData) UserVerified() bool {
+	return d.Flags&flagUserVerified != 0
+}
+
+func parseAuthenticatorData(b []byte) (*authenticatorData, error) {
+	if len(b) < authDataMinLength {
+		return nil, errors.New("webauthn: authenticator data is too short")
+	}
+
+	d := &authenticatorData{
+		RPIDHash:  b[:32],
+		Flags:     b[32],
+		SignCount: binary.BigEndian.Uint32(b[33:37]),
+	}
+	rest := b[authDataMinLength:]
+
+	if d.Flags&flagAttestedData != 0 {
+		if len(rest) < aaguidLength+2 {
+			return nil, errors.New("webauthn: attested credential data is too short")
+		}
+		d.AAGUID = rest[:aaguidLength]
+		l := int(binary.BigEndian.Uint16(rest[aaguidLength:]))
+		rest = rest[aaguidLength+2:]
+
+		if len(rest) < l {
+			return nil, errors.New("webauthn: credential id is too short")
+		}
+		d.CredentialID = rest[:l]
+		rest = rest[l:]
+
+		_, tail, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, errors.Wrap(err, "webauthn: invalid credential public key")
+		}
+		d.PublicKey = rest[:len(rest)-len(tail)]
+		rest = tail
+	}
+
+	if d.Flags&flagExtensions != 0 {
+		var err error
+		if _, rest, err = decodeCBOR(rest); err != nil {
+			return nil, errors.Wrap(err, "webauthn: invalid extensions")
+		}
+	}
+
+	if len(rest) != 0 {
+		return nil, errors.New("webauthn: unexpected data after authenticator data")
+	}
+
+	return d, nil
+}
@@ -0,0 +1,148 @@
+package webauthn
+
+import (
+	"encoding/binary"
+	"math"
+
+	"github.com/pkg/errors"
+)
+
+const cborMaxDepth = 16
+
+var errCBORUnexpectedEnd = errors.New("cbor: unexpected end of data")
+
+// decodeCBOR decodes the first CBOR data item (RFC 7049) and returns it with the rest of the input.
+//
+// Only the subset used by the WebAuthn structures is supported: integers (returned as int64), byte
+// strings, text strings, arrays, maps, tags (the tag number is dropped), booleans and null.
+// Indefinite-length items and floats are rejected.
+func decodeCBOR(b []byte) (interface{}, []byte, error) {
+	return decodeCBORItem(b, 0)
+}
+
+func decodeCBORItem(b []byte, depth int) (interface{}, []byte, error) {
+	if depth > cborMaxDepth {
+		return nil, nil, errors.New("cbor: nesting is too deep")
+	}
+	if len(b) == 0 {
+		return nil, nil, errCBORUnexpectedEnd
+	}
+
+	major, info := b[0]>>5, b[0]&0x1f
+	b = b[1:]
+
+	if major == 7 {
+		switch info {
+		case 20:
+			return false, b, nil
+		case 21:
+			return true, b, nil
+		case 22, 23:
+			return nil, b, nil
+		}
+		return nil, nil, errors.Errorf("cbor: unsupported simple value %d", info)
+	}
+
+	n, b, err := readCBORUint(info, b)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch major {
+	case 0:
+		if n > math.MaxInt64 {
+			return nil, nil, errors.New("cbor: integer overflow")
+		}
+		return int64(n), b, nil
+
+	case 1:
+		if n > math.MaxInt64 {
+			return nil, nil, errors.New("cbor: integer overflow")
+		}
+		return -1 - int64(n), b, nil
+
+	case 2, 3:
+		if uint64(len(b)) < n {
+			return nil, nil, errCBORUnexpectedEnd
+		}
+		if major == 3 {
+			return string(b[:n]), b[n:], nil
+		}
+		return b[:n], b[n:], nil
+
+	case 4:
+		// INFO: Each item takes at least one byte, so the length can't exceed the rest of the input
+		if uint64(len(b)) < n {
+			return nil, nil, errCBORUnexpectedEnd
+		}
+		items := make([]interface{}, 0, n)
+		for i := uint64(0); i < n; i++ {
+			var item interface{}
+			if item, b, err = decodeCBORItem(b, depth+1); err != nil {
+				return nil, nil, err
+			}
+			items = append(items, item)
+		}
+		return items, b, nil
+
+	case 5:
+		if uint64(len(b)) < n*2 {
+			return nil, nil, errCBORUnexpectedEnd
+		}
+		m := make(map[interface{}]interface{}, n)
+		for i := uint64(0); i < n; i++ {
+			var key, value interface{}
+			if key, b, err = decodeCBORItem(b, depth+1); err != nil {
+				return nil, nil, err
+			}
+			switch key.(type) {
+			case int64, string:
+			default:
+				return nil, nil, errors.New("cbor: unsupported map key type")
+			}
+			if value, b, err = decodeCBORItem(b, depth+1); err != nil {
+				return nil, nil, err
+			}
+			m[key] = value
+		}
+		return m, b, nil
+
+	case 6:
+		return decodeCBORItem(b, depth+1)
+	}
+
+	return nil, nil, errors.Errorf("cbor: unsupported major type %d", major)
+}
+
+func readCBORUint(info byte, b []byte) (uint64, []byte, error) {
+	var size int
+	switch {
+	case info < 24:
+		return uint64(info), b, nil
+	case info == 24:
+		size = 1
+	case info == 25:
+		size = 2
+	case info == 26:
+		size = 4
+	case info == 27:
+		size = 8
+	default:
+		return 0, nil, errors.New("cbor: indefinite length is not supported")
+	}
+
+	if len(b) < size {
+		return 0, nil, errCBORUnexpectedEnd
+	}
+
+	switch size {
+	case 1:
+		return uint64(b[0]), b[1:], nil
+	case 2:
+		return uint64(binary.BigEndian.Uint16(b)), b[2:], nil
+	case 4:
+		return uint64(binary.BigEndian.Uint32(b)), b[4:], nil
+	}
+
+	return binary.BigEndian.Uint64(b), b[8:], nil
+}
@@ -0,0 +1,126 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/asn1"
+	"math/big"
+
+	"github.com/pkg/errors"
+)
+
+// COSE key parameters and algorithms, see RFC 8152.
+const (
+	coseKeyType      = 1
+	coseKeyAlg       = 3
+	coseKeyCrv       = -1
+	coseKeyX         = -2
+	coseKeyY         = -3
+	coseKeyRSAModulo = -1
+	coseKeyRSAExp    = -2
+
+	coseKeyTypeOKP = 1
+	coseKeyTypeEC2 = 2
+	coseKeyTypeRSA = 3
+
+	coseCurveP256    = 1
+	coseCurveEd25519 = 6
+
+	// COSEAlgES256 is ECDSA with P-256 and SHA-256.
+	COSEAlgES256 = -7
+
+	// COSEAlgEdDSA is EdDSA with Ed25519.
+	COSEAlgEdDSA = -8
+
+	// COSEAlgRS256 is RSASSA-PKCS1-v1_5 with SHA-256.
+	COSEAlgRS256 = -257
+)
+
+// ErrInvalidSignature is returned if the assertion signature doesn't match the credential public key.
+var ErrInvalidSignature = errors.New("webauthn: invalid signature")
+
+// publicKey is the parsed credential public key.
+type publicKey struct {
+	key crypto.PublicKey
+}
+
+// parsePublicKey parses the COSE encoded public key of the supported algorithms.
+func parsePublicKey(coseKey []byte) (*publicKey, error) {
+	v, rest, err := decodeCBOR(coseKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "webauthn: invalid public key")
+	}
+	key, ok := v.(map[interface{}]interface{})
+	if !ok || len(rest) != 0 {
+		return nil, errors.New("webauthn: invalid public key")
+	}
+
+	kty, _ := key[int64(coseKeyType)].(int64)
+	alg, _ := key[int64(coseKeyAlg)].(int64)
+
+	switch {
+	case kty == coseKeyTypeEC2 && alg == COSEAlgES256:
+		crv, _ := key[int64(coseKeyCrv)].(int64)
+		x, _ := key[int64(coseKeyX)].([]byte)
+		y, _ := key[int64(coseKeyY)].([]byte)
+		if crv != coseCurveP256 || len(x) == 0 || len(y) == 0 {
+			return nil, errors.New("webauthn: invalid EC2 public key")
+		}
+
+		pub := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+			return nil, errors.New("webauthn: invalid EC2 public key")
+		}
+		return &publicKey{key: pub}, nil
+
+	case kty == coseKeyTypeOKP && alg == COSEAlgEdDSA:
+		crv, _ := key[int64(coseKeyCrv)].(int64)
+		x, _ := key[int64(coseKeyX)].([]byte)
+		if crv != coseCurveEd25519 || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("webauthn: invalid OKP public key")
+		}
+		return &publicKey{key: ed25519.PublicKey(x)}, nil
+
+	case kty == coseKeyTypeRSA && alg == COSEAlgRS256:
+		n, _ := key[int64(coseKeyRSAModulo)].([]byte)
+		e, _ := key[int64(coseKeyRSAExp)].([]byte)
+		if len(n) == 0 || len(e) == 0 || len(e) > 4 {
+			return nil, errors.New("webauthn: invalid RSA public key")
+		}
+		return &publicKey{key: &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}}, nil
+	}
+
+	return nil, errors.Errorf("webauthn: unsupported key type %d with algorithm %d", kty, alg)
+}
+
+// verifySignature checks the signature of the data with the COSE encoded public key.
+func verifySignature(coseKey, data, sig []byte) error {
+	pub, err := parsePublicKey(coseKey)
+	if err != nil {
+		return err
+	}
+
+	h := sha256.Sum256(data)
+	ok := false
+	switch k := pub.key.(type) {
+	case *ecdsa.PublicKey:
+		es := struct{ R, S *big.Int }{}
+		if rest, err := asn1.Unmarshal(sig, &es); err == nil && len(rest) == 0 {
+			ok = ecdsa.Verify(k, h[:], es.R, es.S)
+		}
+	case ed25519.PublicKey:
+		ok = ed25519.Verify(k, data, sig)
+	case *rsa.PublicKey:
+		ok = rsa.VerifyPKCS1v15(k, crypto.SHA256, h[:], sig) == nil
+	}
+
+	if !ok {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
@@ -0,0 +1,382 @@
+// Package webauthn implements the relying party part of the Web Authentication ceremonies
+// (https://www.w3.org/TR/webauthn/) used for the passkey registration and login.
+//
+// Attestation statements are not verified: the relying party requests the "none" attestation
+// conveyance and doesn't depend on the authenticator model.
+package webauthn
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	challengeSize = 32
+	timeout       = 60000
+
+	// CredentialTypePublicKey is the only credential type defined by WebAuthn.
+	CredentialTypePublicKey = "public-key"
+
+	// UserVerificationRequired requires the authenticator to verify the user by PIN or biometrics.
+	UserVerificationRequired = "required"
+
+	// UserVerificationPreferred asks the authenticator to verify the user if possible.
+	UserVerificationPreferred = "preferred"
+
+	clientDataTypeCreate = "webauthn.create"
+	clientDataTypeGet    = "webauthn.get"
+)
+
+var (
+	// ErrInvalidClientData is returned if the client data doesn't match the ceremony.
+	ErrInvalidClientData = errors.New("webauthn: invalid client data")
+
+	// ErrInvalidAuthenticatorData is returned if the authenticator data doesn't match the relying party.
+	ErrInvalidAuthenticatorData = errors.New("webauthn: invalid authenticator data")
+
+	// ErrUserNotVerified is returned if user verification is required but not performed by the authenticator.
+	ErrUserNotVerified = errors.New("webauthn: user is not verified")
+
+	// ErrCloned is returned if the signature counter is not increased, which means the authenticator may be cloned.
+	ErrCloned = errors.New("webauthn: signature counter is not increased")
+)
+
+// RelyingParty verifies the registration and authentication ceremonies for the relying party id.
+type RelyingParty struct {
+	id      string
+	name    string
+	origins []string
+}
+
+// NewRelyingParty return new relying party. The origins are the list of allowed origins
+// of the pages running the ceremonies, e.g. "https://auth1.example.com".
+func NewRelyingParty(id, name string, origins []string) *RelyingParty {
+	return &RelyingParty{id: id, name: name, origins: origins}
+}
+
+// RelyingPartyEntity describes the relying party for the authenticator.
+type RelyingPartyEntity struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// UserEntity describes the user account for the authenticator.
+type UserEntity struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+// CredentialParameter is the credential type and algorithm accepted by the relying party.
+type CredentialParameter struct {
+	Type string `json:"type"`
+	Alg  int    `json:"alg"`
+}
+
+// CredentialDescriptor identifies the credential to be excluded or allowed.
+type CredentialDescriptor struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+// AuthenticatorSelection contains the requirements for the authenticator.
+type AuthenticatorSelection struct {
+	ResidentKey        string `json:"residentKey"`
+	RequireResidentKey bool   `json:"requireResidentKey"`
+	UserVerification   string `json:"userVerification"`
+}
+
+// CreationOptions is the PublicKeyCredentialCreationOptions for navigator.credentials.create().
+// The binary fields are base64url encoded.
+type CreationOptions struct {
+	Challenge              string                 `json:"challenge"`
+	RP                     RelyingPartyEntity     `json:"rp"`
+	User                   UserEntity             `json:"user"`
+	PubKeyCredParams       []CredentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int                    `json:"timeout"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection AuthenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                 `json:"attestation"`
+}
+
+// RequestOptions is the PublicKeyCredentialRequestOptions for navigator.credentials.get().
+// The binary fields are base64url encoded.
+type RequestOptions struct {
+	Challenge        string                 `json:"challenge"`
+	Timeout          int                    `json:"timeout"`
+	RPID             string                 `json:"rpId"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                 `json:"userVerification"`
+}
+
+// AttestationResponse is the result of navigator.credentials.create() with base64url encoded binary fields.
+type AttestationResponse struct {
+	ID       string `json:"id"`
+	RawID    string `json:"rawId"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    string `json:"clientDataJSON"`
+		AttestationObject string `json:"attestationObject"`
+	} `json:"response"`
+}
+
+// AssertionResponse is the result of navigator.credentials.get() with base64url encoded binary fields.
+type AssertionResponse struct {
+	ID       string `json:"id"`
+	RawID    string `json:"rawId"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    string `json:"clientDataJSON"`
+		AuthenticatorData string `json:"authenticatorData"`
+		Signature         string `json:"signature"`
+		UserHandle        string `json:"userHandle"`
+	} `json:"response"`
+}
+
+// CredentialID returns the decoded id of the credential used for the assertion.
+func (r *AssertionResponse) CredentialID() ([]byte, error) {
+	return DecodeBase64(r.RawID)
+}
+
+// UserHandle returns the decoded user handle, empty for the non-discoverable credentials.
+func (r *AssertionResponse) UserHandle() ([]byte, error) {
+	return DecodeBase64(r.Response.UserHandle)
+}
+
+// Credential is the public key credential created by the registration ceremony.
+type Credential struct {
+	ID        []byte
+	PublicKey []byte
+	SignCount uint32
+	AAGUID    []byte
+}
+
+// Assertion is the result of the verified authentication ceremony.
+type Assertion struct {
+	SignCount    uint32
+	UserVerified bool
+}
+
+type clientData struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	Origin    string `json:"origin"`
+}
+
+// NewChallenge returns a new random challenge for the ceremony.
+func NewChallenge() ([]byte, error) {
+	c := make([]byte, challengeSize)
+	if _, err := rand.Read(c); err != nil {
+		return nil, errors.Wrap(err, "webauthn: unable to generate challenge")
+	}
+
+	return c, nil
+}
+
+// CreationOptions returns the options for the registration ceremony. The user id must not contain
+// personal information, it is returned by the authenticator as the user handle on login.
+func (rp *RelyingParty) CreationOptions(challenge, userID []byte, name, displayName string, exclude [][]byte) *CreationOptions {
+	return &CreationOptions{
+		Challenge: EncodeBase64(challenge),
+		RP:        RelyingPartyEntity{ID: rp.id, Name: rp.name},
+		User:      UserEntity{ID: EncodeBase64(userID), Name: name, DisplayName: displayName},
+		PubKeyCredParams: []CredentialParameter{
+			{Type: CredentialTypePublicKey, Alg: COSEAlgES256},
+			{Type: CredentialTypePublicKey, Alg: COSEAlgEdDSA},
+			{Type: CredentialTypePublicKey, Alg: COSEAlgRS256},
+		},
+		Timeout:            timeout,
+		ExcludeCredentials: descriptors(exclude),
+		AuthenticatorSelection: AuthenticatorSelection{
+			ResidentKey:      "preferred",
+			UserVerification: UserVerificationPreferred,
+		},
+		Attestation: "none",
+	}
+}
+
+// RequestOptions returns the options for the authentication ceremony. If the list of allowed credentials
+// is empty, the authenticator offers the discoverable credentials (passkeys) for the relying party.
+func (rp *RelyingParty) RequestOptions(challenge []byte, allow [][]byte, userVerification string) *RequestOptions {
+	return &RequestOptions{
+		Challenge:        EncodeBase64(challenge),
+		Timeout:          timeout,
+		RPID:             rp.id,
+		AllowCredentials: descriptors(allow),
+		UserVerification: userVerification,
+	}
+}
+
+// VerifyAttestation verifies the registration ceremony and returns the new credential.
+func (rp *RelyingParty) VerifyAttestation(challenge []byte, resp *AttestationResponse) (*Credential, error) {
+	if resp.Type != CredentialTypePublicKey {
+		return nil, errors.New("webauthn: invalid credential type")
+	}
+
+	if _, err := rp.verifyClientData(resp.Response.ClientDataJSON, clientDataTypeCreate, challenge); err != nil {
+		return nil, err
+	}
+
+	raw, err := DecodeBase64(resp.Response.AttestationObject)
+	if err != nil {
+		return nil, errors.Wrap(err, "webauthn: invalid attestation object")
+	}
+	v, rest, err := decodeCBOR(raw)
+	if err != nil {
+		return nil, errors.Wrap(err, "webauthn: invalid attestation object")
+	}
+	obj, ok := v.(map[interface{}]interface{})
+	if !ok || len(rest) != 0 {
+		return nil, errors.New("webauthn: invalid attestation object")
+	}
+	if _, ok := obj["fmt"].(string); !ok {
+		return nil, errors.New("webauthn: invalid attestation format")
+	}
+	rawAuthData, ok := obj["authData"].([]byte)
+	if !ok {
+		return nil, errors.New("webauthn: invalid attestation object")
+	}
+
+	authData, err := rp.verifyAuthenticatorData(rawAuthData, false)
+	if err != nil {
+		return nil, err
+	}
+	if authData.CredentialID == nil {
+		return nil, errors.New("webauthn: attested credential data is missing")
+	}
+
+	rawID, err := DecodeBase64(resp.RawID)
+	if err != nil || !bytes.Equal(rawID, authData.CredentialID) {
+		return nil, errors.New("webauthn: credential id mismatch")
+	}
+
+	// INFO: Check the key is supported, so the credential won't fail on every login
+	if _, err := parsePublicKey(authData.PublicKey); err != nil {
+		return nil, err
+	}
+
+	return &Credential{
+		ID:        authData.CredentialID,
+		PublicKey: authData.PublicKey,
+		SignCount: authData.SignCount,
+		AAGUID:    authData.AAGUID,
+	}, nil
+}
+
+// VerifyAssertion verifies the authentication ceremony with the stored credential.
+func (rp *RelyingParty) VerifyAssertion(challenge []byte, resp *AssertionResponse, cred *Credential, requireUserVerification bool) (*Assertion, error) {
+	if resp.Type != CredentialTypePublicKey {
+		return nil, errors.New("webauthn: invalid credential type")
+	}
+
+	rawID, err := resp.CredentialID()
+	if err != nil || !bytes.Equal(rawID, cred.ID) {
+		return nil, errors.New("webauthn: credential id mismatch")
+	}
+
+	cd, err := rp.verifyClientData(resp.Response.ClientDataJSON, clientDataTypeGet, challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	rawAuthData, err := DecodeBase64(resp.Response.AuthenticatorData)
+	if err != nil {
+		return nil, ErrInvalidAuthenticatorData
+	}
+	authData, err := rp.verifyAuthenticatorData(rawAuthData, requireUserVerification)
+	if err != nil {
+		return nil, err
+	}
+
+	sig, err := DecodeBase64(resp.Response.Signature)
+	if err != nil {
+		return nil, ErrInvalidSignature
+	}
+	cdHash := sha256.Sum256(cd)
+	signed := make([]byte, 0, len(rawAuthData)+len(cdHash))
+	signed = append(append(signed, rawAuthData...), cdHash[:]...)
+	if err := verifySignature(cred.PublicKey, signed, sig); err != nil {
+		return nil, err
+	}
+
+	if (authData.SignCount != 0 || cred.SignCount != 0) && authData.SignCount <= cred.SignCount {
+		return nil, ErrCloned
+	}
+
+	return &Assertion{SignCount: authData.SignCount, UserVerified: authData.UserVerified()}, nil
+}
+
+// verifyClientData checks the client data and returns its raw json, which is signed by the authenticator.
+func (rp *RelyingParty) verifyClientData(encoded, typ string, challenge []byte) ([]byte, error) {
+	raw, err := DecodeBase64(encoded)
+	if err != nil {
+		return nil, ErrInvalidClientData
+	}
+
+	cd := &clientData{}
+	if err := json.Unmarshal(raw, cd); err != nil {
+		return nil, ErrInvalidClientData
+	}
+	if cd.Type != typ {
+		return nil, errors.Wrap(ErrInvalidClientData, "type mismatch")
+	}
+
+	c, err := DecodeBase64(cd.Challenge)
+	if err != nil || subtle.ConstantTimeCompare(c, challenge) != 1 {
+		return nil, errors.Wrap(ErrInvalidClientData, "challenge mismatch")
+	}
+
+	for _, o := range rp.origins {
+		if cd.Origin == o {
+			return raw, nil
+		}
+	}
+
+	return nil, errors.Wrap(ErrInvalidClientData, "origin is not allowed")
+}
+
+func (rp *RelyingParty) verifyAuthenticatorData(raw []byte, requireUserVerification bool) (*authenticatorData, error) {
+	d, err := parseAuthenticatorData(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	h := sha256.Sum256([]byte(rp.id))
+	if !bytes.Equal(d.RPIDHash, h[:]) {
+		return nil, errors.Wrap(ErrInvalidAuthenticatorData, "relying party id mismatch")
+	}
+	if !d.UserPresent() {
+		return nil, errors.Wrap(ErrInvalidAuthenticatorData, "user is not present")
+	}
+	if requireUserVerification && !d.UserVerified() {
+		return nil, ErrUserNotVerified
+	}
+
+	return d, nil
+}
+
+func descriptors(ids [][]byte) []CredentialDescriptor {
+	list := make([]CredentialDescriptor, len(ids))
+	for i, id := range ids {
+		list[i] = CredentialDescriptor{Type: CredentialTypePublicKey, ID: EncodeBase64(id)}
+	}
+
+	return list
+}
+
+// EncodeBase64 encodes the binary data with the unpadded base64url encoding used by WebAuthn.
+func EncodeBase64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeBase64 decodes the base64url encoded data, the padding is optional.
+func DecodeBase64(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
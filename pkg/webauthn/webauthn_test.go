@@ -0,0 +1,249 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testRPID   = "auth1.example.com"
+	testOrigin = "https://auth1.example.com"
+)
+
+// encodeCBOR encodes the subset of values produced by the authenticators for the tests.
+func encodeCBOR(v interface{}) []byte {
+	head := func(major byte, n uint64) []byte {
+		switch {
+		case n < 24:
+			return []byte{major<<5 | byte(n)}
+		case n < 1<<8:
+			return []byte{major<<5 | 24, byte(n)}
+		case n < 1<<16:
+			b := []byte{major<<5 | 25, 0, 0}
+			binary.BigEndian.PutUint16(b[1:], uint16(n))
+			return b
+		}
+		b := []byte{major<<5 | 26, 0, 0, 0, 0}
+		binary.BigEndian.PutUint32(b[1:], uint32(n))
+		return b
+	}
+
+	switch v := v.(type) {
+	case int:
+		if v < 0 {
+			return head(1, uint64(-1-v))
+		}
+		return head(0, uint64(v))
+	case []byte:
+		return append(head(2, uint64(len(v))), v...)
+	case string:
+		return append(head(3, uint64(len(v))), v...)
+	case map[interface{}]interface{}:
+		keys := make([][]byte, 0, len(v))
+		values := map[string][]byte{}
+		for k, val := range v {
+			ek := encodeCBOR(k)
+			keys = append(keys, ek)
+			values[string(ek)] = encodeCBOR(val)
+		}
+		sort.Slice(keys, func(i, j int) bool { return string(keys[i]) < string(keys[j]) })
+		b := head(5, uint64(len(v)))
+		for _, k := range keys {
+			b = append(append(b, k...), values[string(k)]...)
+		}
+		return b
+	}
+	panic("unsupported type")
+}
+
+type testAuthenticator struct {
+	credentialID []byte
+	signer       crypto.Signer
+	publicKey    []byte
+	signCount    uint32
+	flags        byte
+}
+
+func newTestAuthenticator(t *testing.T, alg int) *testAuthenticator {
+	a := &testAuthenticator{credentialID: []byte("credential-id"), flags: flagUserPresent | flagUserVerified}
+
+	switch alg {
+	case COSEAlgES256:
+		k, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		assert.Nil(t, err)
+		a.signer = k
+		a.publicKey = encodeCBOR(map[interface{}]interface{}{
+			coseKeyType: coseKeyTypeEC2, coseKeyAlg: COSEAlgES256, coseKeyCrv: coseCurveP256,
+			coseKeyX: k.X.Bytes(), coseKeyY: k.Y.Bytes(),
+		})
+	case COSEAlgEdDSA:
+		pub, k, err := ed25519.GenerateKey(rand.Reader)
+		assert.Nil(t, err)
+		a.signer = k
+		a.publicKey = encodeCBOR(map[interface{}]interface{}{
+			coseKeyType: coseKeyTypeOKP, coseKeyAlg: COSEAlgEdDSA, coseKeyCrv: coseCurveEd25519, coseKeyX: []byte(pub),
+		})
+	}
+
+	return a
+}
+
+func (a *testAuthenticator) authData(attested bool) []byte {
+	h := sha256.Sum256([]byte(testRPID))
+	b := append([]byte{}, h[:]...)
+	flags := a.flags
+	if attested {
+		flags |= flagAttestedData
+	}
+	b = append(b, flags, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(b[33:], a.signCount)
+
+	if attested {
+		b = append(b, make([]byte, aaguidLength)...)
+		b = append(b, byte(len(a.credentialID)>>8), byte(len(a.credentialID)))
+		b = append(b, a.credentialID...)
+		b = append(b, a.publicKey...)
+	}
+
+	return b
+}
+
+func clientDataJSON(typ string, challenge []byte, origin string) []byte {
+	b, _ := json.Marshal(&clientData{Type: typ, Challenge: EncodeBase64(challenge), Origin: origin})
+	return b
+}
+
+func (a *testAuthenticator) create(challenge []byte, origin string) *AttestationResponse {
+	r := &AttestationResponse{ID: EncodeBase64(a.credentialID), RawID: EncodeBase64(a.credentialID), Type: CredentialTypePublicKey}
+	r.Response.ClientDataJSON = EncodeBase64(clientDataJSON(clientDataTypeCreate, challenge, origin))
+	r.Response.AttestationObject = EncodeBase64(encodeCBOR(map[interface{}]interface{}{
+		"fmt":      "none",
+		"attStmt":  map[interface{}]interface{}{},
+		"authData": a.authData(true),
+	}))
+
+	return r
+}
+
+func (a *testAuthenticator) get(t *testing.T, challenge []byte, origin string) *AssertionResponse {
+	a.signCount++
+	authData := a.authData(false)
+	cd := clientDataJSON(clientDataTypeGet, challenge, origin)
+	cdHash := sha256.Sum256(cd)
+	signed := append(append([]byte{}, authData...), cdHash[:]...)
+
+	var sig []byte
+	var err error
+	if _, ok := a.signer.(ed25519.PrivateKey); ok {
+		sig, err = a.signer.Sign(rand.Reader, signed, crypto.Hash(0))
+	} else {
+		h := sha256.Sum256(signed)
+		sig, err = a.signer.Sign(rand.Reader, h[:], crypto.SHA256)
+	}
+	assert.Nil(t, err)
+
+	r := &AssertionResponse{ID: EncodeBase64(a.credentialID), RawID: EncodeBase64(a.credentialID), Type: CredentialTypePublicKey}
+	r.Response.ClientDataJSON = EncodeBase64(cd)
+	r.Response.AuthenticatorData = EncodeBase64(authData)
+	r.Response.Signature = EncodeBase64(sig)
+
+	return r
+}
+
+func TestDecodeCBOR(t *testing.T) {
+	v, rest, err := decodeCBOR(append(encodeCBOR(map[interface{}]interface{}{1: -7, "a": []byte{1, 2}}), 0xff))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{0xff}, rest)
+	assert.Equal(t, map[interface{}]interface{}{int64(1): int64(-7), "a": []byte{1, 2}}, v)
+
+	_, _, err = decodeCBOR([]byte{0x5f})
+	assert.NotNil(t, err)
+
+	_, _, err = decodeCBOR([]byte{0x9b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff})
+	assert.NotNil(t, err)
+}
+
+func TestRegistrationAndAssertion(t *testing.T) {
+	rp := NewRelyingParty(testRPID, "Auth1", []string{testOrigin})
+
+	for _, alg := range []int{COSEAlgES256, COSEAlgEdDSA} {
+		a := newTestAuthenticator(t, alg)
+
+		challenge, err := NewChallenge()
+		assert.Nil(t, err)
+		cred, err := rp.VerifyAttestation(challenge, a.create(challenge, testOrigin))
+		if !assert.Nil(t, err) {
+			continue
+		}
+		assert.Equal(t, a.credentialID, cred.ID)
+
+		challenge, _ = NewChallenge()
+		assertion, err := rp.VerifyAssertion(challenge, a.get(t, challenge, testOrigin), cred, true)
+		assert.Nil(t, err)
+		assert.Equal(t, uint32(1), assertion.SignCount)
+		assert.True(t, assertion.UserVerified)
+	}
+}
+
+func TestVerifyAttestationReturnErrorWithForeignOrigin(t *testing.T) {
+	rp := NewRelyingParty(testRPID, "Auth1", []string{testOrigin})
+	a := newTestAuthenticator(t, COSEAlgES256)
+	challenge, _ := NewChallenge()
+
+	_, err := rp.VerifyAttestation(challenge, a.create(challenge, "https://evil.example.com"))
+	assert.NotNil(t, err)
+}
+
+func TestVerifyAttestationReturnErrorWithOtherChallenge(t *testing.T) {
+	rp := NewRelyingParty(testRPID, "Auth1", []string{testOrigin})
+	a := newTestAuthenticator(t, COSEAlgES256)
+	challenge, _ := NewChallenge()
+	other, _ := NewChallenge()
+
+	_, err := rp.VerifyAttestation(challenge, a.create(other, testOrigin))
+	assert.NotNil(t, err)
+}
+
+func TestVerifyAssertionReturnErrorWithInvalidSignature(t *testing.T) {
+	rp := NewRelyingParty(testRPID, "Auth1", []string{testOrigin})
+	a := newTestAuthenticator(t, COSEAlgES256)
+	cred := &Credential{ID: a.credentialID, PublicKey: newTestAuthenticator(t, COSEAlgES256).publicKey}
+	challenge, _ := NewChallenge()
+
+	_, err := rp.VerifyAssertion(challenge, a.get(t, challenge, testOrigin), cred, false)
+	assert.Equal(t, ErrInvalidSignature, err)
+}
+
+func TestVerifyAssertionReturnErrorWithoutUserVerification(t *testing.T) {
+	rp := NewRelyingParty(testRPID, "Auth1", []string{testOrigin})
+	a := newTestAuthenticator(t, COSEAlgES256)
+	a.flags = flagUserPresent
+	cred := &Credential{ID: a.credentialID, PublicKey: a.publicKey}
+	challenge, _ := NewChallenge()
+
+	_, err := rp.VerifyAssertion(challenge, a.get(t, challenge, testOrigin), cred, true)
+	assert.Equal(t, ErrUserNotVerified, err)
+
+	_, err = rp.VerifyAssertion(challenge, a.get(t, challenge, testOrigin), cred, false)
+	assert.Nil(t, err)
+}
+
+func TestVerifyAssertionReturnErrorWithClonedAuthenticator(t *testing.T) {
+	rp := NewRelyingParty(testRPID, "Auth1", []string{testOrigin})
+	a := newTestAuthenticator(t, COSEAlgES256)
+	cred := &Credential{ID: a.credentialID, PublicKey: a.publicKey, SignCount: 10}
+	challenge, _ := NewChallenge()
+
+	_, err := rp.VerifyAssertion(challenge, a.get(t, challenge, testOrigin), cred, false)
+	assert.Equal(t, ErrCloned, err)
+}
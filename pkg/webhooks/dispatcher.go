@@ -0,0 +1,200 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/repository"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/config"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/webhooks/signature"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// Dispatcher sends the queued webhook deliveries. Failed deliveries are retried with exponential backoff
+// and dead-lettered after the maximum number of attempts. The requests are signed with the secrets of the application.
+type Dispatcher struct {
+	deliveries repository.WebhookDeliveryRepository
+	apps       repository.ApplicationRepository
+	cfg        *config.Webhooks
+	client     *http.Client
+	now        func() time.Time
+}
+
+func NewDispatcher(deliveries repository.WebhookDeliveryRepository, apps repository.ApplicationRepository, cfg *config.Webhooks) *Dispatcher {
+	return &Dispatcher{
+		deliveries: deliveries,
+		apps:       apps,
+		cfg:        cfg,
+		client:     &http.Client{Timeout: cfg.Timeout},
+		now:        time.Now,
+	}
+}
+
+// purgeInterval is the interval between the removals of the finished deliveries.
+const purgeInterval = time.Hour
+
+// Run polls the queue until the context is cancelled.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.cfg.Interval)
+	defer ticker.Stop()
+
+	var purged time.Time
+	for {
+		if d.now().Sub(purged) >= purgeInterval {
+			purged = d.now()
+			if _, err := d.Purge(ctx); err != nil {
+				zap.L().Error("Unable to purge webhook deliveries", zap.Error(err))
+			}
+		}
+
+		for {
+			n, err := d.Process(ctx)
+			if err != nil {
+				zap.L().Error("Unable to process webhook deliveries", zap.Error(err))
+			}
+			// INFO: The full batch means there may be more due deliveries, so don't wait for the next tick
+			if err != nil || n == 0 || n < d.cfg.BatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Purge removes the delivered deliveries older than the retention period and the dead ones older than the dead
+// retention period, and returns their number.
+func (d *Dispatcher) Purge(ctx context.Context) (int, error) {
+	delivered, err := d.purge(ctx, entity.WebhookDeliveryDelivered, d.cfg.Retention)
+	if err != nil {
+		return delivered, err
+	}
+	dead, err := d.purge(ctx, entity.WebhookDeliveryDead, d.cfg.DeadRetention)
+
+	return delivered + dead, err
+}
+
+// purge removes the deliveries in the status older than the retention, zero retention keeps them forever.
+func (d *Dispatcher) purge(ctx context.Context, status entity.WebhookDeliveryStatus, retention time.Duration) (int, error) {
+	if retention <= 0 {
+		return 0, nil
+	}
+	return d.deliveries.Purge(ctx, status, d.now().Add(-retention))
+}
+
+// Process sends one batch of the due deliveries and returns its size.
+func (d *Dispatcher) Process(ctx context.Context) (int, error) {
+	// INFO: The lease outlives the request, so the other instances don't send the delivery at the same time
+	items, err := d.deliveries.Claim(ctx, d.now(), 2*d.cfg.Timeout, d.cfg.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	wg := sync.WaitGroup{}
+	wg.Add(len(items))
+	for _, item := range items {
+		go func(item *entity.WebhookDelivery) {
+			defer wg.Done()
+			d.deliver(ctx, item)
+		}(item)
+	}
+	wg.Wait()
+
+	return len(items), nil
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, item *entity.WebhookDelivery) {
+	code, err := d.send(ctx, item)
+
+	now := d.now()
+	item.LastAttemptAt = now
+	item.LastStatusCode = code
+	item.UpdatedAt = now
+
+	if err == nil {
+		item.Status = entity.WebhookDeliveryDelivered
+		item.LastError = ""
+	} else {
+		item.Attempts++
+		item.LastError = err.Error()
+		if item.Attempts >= d.cfg.MaxAttempts {
+			item.Status = entity.WebhookDeliveryDead
+		} else {
+			item.NextAttemptAt = now.Add(backoff(d.cfg.BackoffBase, d.cfg.BackoffMax, item.Attempts))
+		}
+
+		zap.L().Warn(
+			"Webhook delivery failed",
+			zap.String("delivery", string(item.ID)),
+			zap.String("url", item.URL),
+			zap.Int("attempts", item.Attempts),
+			zap.String("status", string(item.Status)),
+			zap.Error(err),
+		)
+	}
+
+	if err := d.deliveries.Update(ctx, item); err != nil {
+		zap.L().Error("Unable to update webhook delivery", zap.String("delivery", string(item.ID)), zap.Error(err))
+	}
+}
+
+func (d *Dispatcher) send(ctx context.Context, item *entity.WebhookDelivery) (int, error) {
+	// INFO: The secrets are read on each attempt, so the retries of the old deliveries are signed with the rotated secret
+	app, err := d.apps.FindByID(ctx, item.AppID)
+	if err != nil {
+		return 0, errors.Wrap(err, "unable to get application")
+	}
+	if app == nil {
+		return 0, errors.New("application is deleted")
+	}
+
+	req, err := http.NewRequest(http.MethodPost, item.URL, bytes.NewReader(item.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+
+	now := d.now()
+	if secrets := app.WebHookSecrets(now); len(secrets) > 0 {
+		signature.SetHeaders(req.Header, now, item.Payload, secrets...)
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// backoff returns the delay before the next attempt, it doubles with each failed attempt up to the max.
+func backoff(base, max time.Duration, attempts int) time.Duration {
+	delay := base
+	for i := 1; i < attempts && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+
+	return delay
+}
@@ -0,0 +1,258 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/repository"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/repository/webhook_delivery/memory"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/config"
+	"github.com/ProtocolONE/auth1.protocol.one/pkg/webhooks/signature"
+	"github.com/globalsign/mgo/bson"
+	"github.com/stretchr/testify/assert"
+)
+
+type appRepository map[entity.AppID]*entity.Application
+
+func (r appRepository) Create(ctx context.Context, app *entity.Application) error {
+	r[app.ID] = app
+	return nil
+}
+
+func (r appRepository) Update(ctx context.Context, app *entity.Application) error {
+	r[app.ID] = app
+	return nil
+}
+
+func (r appRepository) Delete(ctx context.Context, id entity.AppID) error {
+	delete(r, id)
+	return nil
+}
+
+func (r appRepository) Find(ctx context.Context, query repository.ApplicationQuery) ([]*entity.Application, int, error) {
+	var result []*entity.Application
+	for _, app := range r {
+		result = append(result, app)
+	}
+	return result, len(result), nil
+}
+
+func (r appRepository) FindByID(ctx context.Context, id entity.AppID) (*entity.Application, error) {
+	if app, ok := r[id]; ok {
+		return app, nil
+	}
+	return nil, errors.New("not found")
+}
+
+type dispatcherTest struct {
+	deliveries *memory.WebhookDeliveryRepository
+	dispatcher *Dispatcher
+	app        *entity.Application
+	now        time.Time
+	status     int
+	received   [][]byte
+	headers    []http.Header
+	server     *httptest.Server
+}
+
+func newDispatcherTest(t *testing.T, maxAttempts int) *dispatcherTest {
+	test := &dispatcherTest{
+		deliveries: memory.New(),
+		app:        &entity.Application{ID: entity.AppID(bson.NewObjectId().Hex()), WebHookSecret: "secret"},
+		status:     http.StatusOK,
+	}
+	test.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		test.received = append(test.received, body)
+		test.headers = append(test.headers, r.Header)
+		w.WriteHeader(test.status)
+	}))
+
+	test.dispatcher = NewDispatcher(test.deliveries, appRepository{test.app.ID: test.app}, &config.Webhooks{
+		MaxAttempts: maxAttempts,
+		BackoffBase: time.Second,
+		BackoffMax:  time.Minute,
+		Interval:    time.Second,
+		Timeout:     5 * time.Second,
+		BatchSize:   10,
+		Retention:   24 * time.Hour,
+	})
+	test.dispatcher.now = func() time.Time { return test.now }
+
+	err := NewWebhooks(test.deliveries).Publish(context.Background(), string(test.app.ID), "user_id", []string{test.server.URL}, UserLogoutEvent{})
+	assert.Nil(t, err)
+	test.now = time.Now()
+
+	return test
+}
+
+func (test *dispatcherTest) delivery(t *testing.T) *entity.WebhookDelivery {
+	list, _, err := test.deliveries.Find(context.Background(), repository.WebhookDeliveryQuery{})
+	assert.Nil(t, err)
+	assert.Len(t, list, 1)
+	return list[0]
+}
+
+func TestDispatcherDeliversEvent(t *testing.T) {
+	test := newDispatcherTest(t, 3)
+	defer test.server.Close()
+
+	n, err := test.dispatcher.Process(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 1, n)
+
+	if assert.Len(t, test.received, 1) {
+		hook := Hook{}
+		assert.Nil(t, json.Unmarshal(test.received[0], &hook))
+		assert.Equal(t, UserLogoutAction, hook.Action)
+		assert.Equal(t, "user_id", hook.UserID)
+		assert.Equal(t, &UserLogoutEvent{}, hook.Event)
+	}
+
+	d := test.delivery(t)
+	assert.Equal(t, entity.WebhookDeliveryDelivered, d.Status)
+	assert.Equal(t, http.StatusOK, d.LastStatusCode)
+}
+
+func TestDispatcherRetriesFailedDeliveryWithBackoff(t *testing.T) {
+	test := newDispatcherTest(t, 3)
+	defer test.server.Close()
+	test.status = http.StatusServiceUnavailable
+
+	_, err := test.dispatcher.Process(context.Background())
+	assert.Nil(t, err)
+
+	d := test.delivery(t)
+	assert.Equal(t, entity.WebhookDeliveryPending, d.Status)
+	assert.Equal(t, 1, d.Attempts)
+	assert.Equal(t, http.StatusServiceUnavailable, d.LastStatusCode)
+	assert.Equal(t, test.now.Add(time.Second), d.NextAttemptAt)
+
+	// INFO: The delivery isn't due until the backoff is passed
+	n, _ := test.dispatcher.Process(context.Background())
+	assert.Equal(t, 0, n)
+
+	test.now = test.now.Add(time.Second)
+	test.status = http.StatusNoContent
+	n, _ = test.dispatcher.Process(context.Background())
+	assert.Equal(t, 1, n)
+	assert.Equal(t, entity.WebhookDeliveryDelivered, test.delivery(t).Status)
+}
+
+func TestDispatcherDeadLettersAfterMaxAttempts(t *testing.T) {
+	test := newDispatcherTest(t, 2)
+	defer test.server.Close()
+	test.status = http.StatusInternalServerError
+
+	for i := 0; i < 3; i++ {
+		_, err := test.dispatcher.Process(context.Background())
+		assert.Nil(t, err)
+		test.now = test.now.Add(time.Hour)
+	}
+
+	d := test.delivery(t)
+	assert.Equal(t, entity.WebhookDeliveryDead, d.Status)
+	assert.Equal(t, 2, d.Attempts)
+	assert.Len(t, test.received, 2)
+}
+
+func TestDispatcherPurgesFinishedDeliveries(t *testing.T) {
+	test := newDispatcherTest(t, 3)
+	defer test.server.Close()
+
+	// INFO: The pending delivery is kept however old it is
+	test.now = test.now.Add(48 * time.Hour)
+	n, err := test.dispatcher.Purge(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 0, n)
+
+	_, err = test.dispatcher.Process(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, entity.WebhookDeliveryDelivered, test.delivery(t).Status)
+
+	test.now = test.now.Add(time.Hour)
+	n, err = test.dispatcher.Purge(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 0, n)
+
+	test.now = test.now.Add(24 * time.Hour)
+	n, err = test.dispatcher.Purge(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 1, n)
+
+	list, _, err := test.deliveries.Find(context.Background(), repository.WebhookDeliveryQuery{})
+	assert.Nil(t, err)
+	assert.Empty(t, list)
+}
+
+func TestDispatcherKeepsDeadDeliveriesForDeadRetention(t *testing.T) {
+	test := newDispatcherTest(t, 1)
+	defer test.server.Close()
+	test.status = http.StatusInternalServerError
+
+	_, err := test.dispatcher.Process(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, entity.WebhookDeliveryDead, test.delivery(t).Status)
+
+	// INFO: The dead delivery is kept for the replay unless the dead retention is set
+	test.now = test.now.Add(30 * 24 * time.Hour)
+	n, err := test.dispatcher.Purge(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 0, n)
+
+	test.dispatcher.cfg.DeadRetention = 48 * time.Hour
+	n, err = test.dispatcher.Purge(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 1, n)
+}
+
+func TestDispatcherSignsRequestWithApplicationSecrets(t *testing.T) {
+	test := newDispatcherTest(t, 3)
+	defer test.server.Close()
+	test.app.WebHookSecret = "new_secret"
+	test.app.WebHookPreviousSecret = "secret"
+	test.app.WebHookPreviousSecretExpiresAt = test.now.Add(time.Hour)
+
+	_, err := test.dispatcher.Process(context.Background())
+	assert.Nil(t, err)
+
+	if assert.Len(t, test.headers, 1) {
+		h := test.headers[0]
+		ts := h.Get(signature.TimestampHeader)
+		assert.Equal(t, strconv.FormatInt(test.now.Unix(), 10), ts)
+		for _, secret := range []string{"new_secret", "secret"} {
+			assert.Nil(t, signature.Verify(h.Get(signature.SignatureHeader), ts, test.received[0], test.now, signature.DefaultTolerance, secret))
+		}
+	}
+}
+
+func TestDispatcherDropsExpiredPreviousSecret(t *testing.T) {
+	test := newDispatcherTest(t, 3)
+	defer test.server.Close()
+	test.app.WebHookSecret = "new_secret"
+	test.app.WebHookPreviousSecret = "secret"
+	test.app.WebHookPreviousSecretExpiresAt = test.now.Add(-time.Second)
+
+	_, err := test.dispatcher.Process(context.Background())
+	assert.Nil(t, err)
+
+	if assert.Len(t, test.headers, 1) {
+		h := test.headers[0]
+		err := signature.Verify(h.Get(signature.SignatureHeader), h.Get(signature.TimestampHeader), test.received[0], test.now, signature.DefaultTolerance, "secret")
+		assert.Equal(t, signature.ErrSignatureMismatch, err)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, 10*time.Second, backoff(10*time.Second, time.Minute, 1))
+	assert.Equal(t, 40*time.Second, backoff(10*time.Second, time.Minute, 3))
+	assert.Equal(t, time.Minute, backoff(10*time.Second, time.Minute, 10))
+}
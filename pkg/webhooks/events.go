@@ -0,0 +1,128 @@
+package webhooks
+
+// SchemaVersion is the version of the hook schema. It's increased on the incompatible changes of the hook
+// or the event payloads, the new fields may be added to the payloads without increasing it.
+const SchemaVersion = 1
+
+const (
+	UserRegisteredAction   = "user.registered"
+	UserLoginAction        = "user.login"
+	UserLogoutAction       = "user.logout"
+	UserBlockedAction      = "user.blocked"
+	PasswordChangedAction  = "password.changed"
+	PasswordResetAction    = "password.reset"
+	EmailVerifiedAction    = "email.verified"
+	EmailChangedAction     = "email.changed"
+	IdentityLinkedAction   = "identity.linked"
+	IdentityUnlinkedAction = "identity.unlinked"
+	MFAAddedAction         = "mfa.added"
+	MFARemovedAction       = "mfa.removed"
+	RolesChangedAction     = "roles.changed"
+)
+
+// Event is the payload of the hook, each action has its own type.
+type Event interface {
+	Action() string
+}
+
+// events creates an empty payload for the action to decode the hook.
+var events = map[string]func() Event{
+	UserRegisteredAction:   func() Event { return &UserRegisteredEvent{} },
+	UserLoginAction:        func() Event { return &UserLoginEvent{} },
+	UserLogoutAction:       func() Event { return &UserLogoutEvent{} },
+	UserBlockedAction:      func() Event { return &UserBlockedEvent{} },
+	PasswordChangedAction:  func() Event { return &PasswordChangedEvent{} },
+	PasswordResetAction:    func() Event { return &PasswordResetEvent{} },
+	EmailVerifiedAction:    func() Event { return &EmailVerifiedEvent{} },
+	EmailChangedAction:     func() Event { return &EmailChangedEvent{} },
+	IdentityLinkedAction:   func() Event { return &IdentityLinkedEvent{} },
+	IdentityUnlinkedAction: func() Event { return &IdentityUnlinkedEvent{} },
+	MFAAddedAction:         func() Event { return &MFAAddedEvent{} },
+	MFARemovedAction:       func() Event { return &MFARemovedEvent{} },
+	RolesChangedAction:     func() Event { return &RolesChangedEvent{} },
+}
+
+// UserRegisteredEvent is sent when the user signs up with the password.
+type UserRegisteredEvent struct {
+	Email    string `json:"email"`
+	Username string `json:"username"`
+}
+
+// UserLoginEvent is sent when the user is authenticated and the login request is accepted.
+type UserLoginEvent struct {
+	// Provider is the name of the identity provider used for the first factor.
+	Provider string `json:"provider,omitempty"`
+	// Amr is the list of the authentication method references, see RFC 8176.
+	Amr []string `json:"amr,omitempty"`
+}
+
+// UserLogoutEvent is sent when all sessions of the user are revoked.
+type UserLogoutEvent struct{}
+
+// UserBlockedEvent is sent when the user is blocked by the operator.
+type UserBlockedEvent struct{}
+
+// PasswordChangedEvent is sent when the user sets the new password.
+type PasswordChangedEvent struct{}
+
+// PasswordResetEvent is sent after PasswordChangedEvent when the new password is set by the reset link.
+type PasswordResetEvent struct {
+	Email string `json:"email"`
+}
+
+// EmailVerifiedEvent is sent when the user confirms the email address.
+type EmailVerifiedEvent struct {
+	Email string `json:"email"`
+}
+
+// EmailChangedEvent is sent when the email address of the user is changed or the change is reverted.
+type EmailChangedEvent struct {
+	Email         string `json:"email"`
+	PreviousEmail string `json:"previous_email"`
+}
+
+// IdentityLinkedEvent is sent when the social account is linked to the user.
+type IdentityLinkedEvent struct {
+	Provider   string `json:"provider"`
+	ExternalID string `json:"external_id"`
+	Email      string `json:"email,omitempty"`
+}
+
+// IdentityUnlinkedEvent is sent when the social account is unlinked from the user. The accounts can't be unlinked
+// yet, the event is declared for the consumers to handle it once they can.
+type IdentityUnlinkedEvent struct {
+	Provider   string `json:"provider"`
+	ExternalID string `json:"external_id"`
+}
+
+// MFAAddedEvent is sent when the user adds the mfa provider.
+type MFAAddedEvent struct {
+	ProviderID string `json:"provider_id"`
+	Type       string `json:"type"`
+	Channel    string `json:"channel,omitempty"`
+}
+
+// MFARemovedEvent is sent when the user removes the mfa provider.
+type MFARemovedEvent struct {
+	ProviderID string `json:"provider_id"`
+}
+
+// RolesChangedEvent is sent when the operator changes the roles of the user.
+type RolesChangedEvent struct {
+	Roles         []string `json:"roles"`
+	PreviousRoles []string `json:"previous_roles"`
+}
+
+func (UserRegisteredEvent) Action() string   { return UserRegisteredAction }
+func (UserLoginEvent) Action() string        { return UserLoginAction }
+func (UserLogoutEvent) Action() string       { return UserLogoutAction }
+func (UserBlockedEvent) Action() string      { return UserBlockedAction }
+func (PasswordChangedEvent) Action() string  { return PasswordChangedAction }
+func (PasswordResetEvent) Action() string    { return PasswordResetAction }
+func (EmailVerifiedEvent) Action() string    { return EmailVerifiedAction }
+func (EmailChangedEvent) Action() string     { return EmailChangedAction }
+func (IdentityLinkedEvent) Action() string   { return IdentityLinkedAction }
+func (IdentityUnlinkedEvent) Action() string { return IdentityUnlinkedAction }
+func (MFAAddedEvent) Action() string         { return MFAAddedAction }
+func (MFARemovedEvent) Action() string       { return MFARemovedAction }
+func (RolesChangedEvent) Action() string     { return RolesChangedAction }
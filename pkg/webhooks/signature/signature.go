@@ -0,0 +1,116 @@
+// Package signature signs the webhook requests sent by Auth1 and verifies them on the receiving side.
+//
+// Each request carries the unix timestamp of the sending in the TimestampHeader and one or more
+// HMAC-SHA256 signatures of "<timestamp>.<body>" in the SignatureHeader, e.g. "v1=5257a8...,v1=9f1c07...".
+// There are several signatures while the application secret is being rotated: one for the new secret
+// and one for the previous secret, so the receiver may be updated at any point of the overlap window.
+package signature
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	SignatureHeader = "X-Auth1-Signature"
+	TimestampHeader = "X-Auth1-Timestamp"
+
+	// DefaultTolerance is the maximum age of the request accepted by VerifyRequest.
+	DefaultTolerance = 5 * time.Minute
+
+	scheme = "v1"
+)
+
+var (
+	ErrNoSignature       = errors.New("webhook signature is missing")
+	ErrInvalidTimestamp  = errors.New("webhook timestamp is invalid")
+	ErrTimestampExpired  = errors.New("webhook timestamp is out of the tolerance")
+	ErrSignatureMismatch = errors.New("webhook signature mismatch")
+)
+
+// Sign returns the hex encoded signature of the body.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Header returns the value of the SignatureHeader with a signature for each secret.
+func Header(timestamp int64, body []byte, secrets ...string) string {
+	signatures := make([]string, 0, len(secrets))
+	for _, secret := range secrets {
+		signatures = append(signatures, scheme+"="+Sign(secret, timestamp, body))
+	}
+
+	return strings.Join(signatures, ",")
+}
+
+// SetHeaders signs the request body and sets the signature headers.
+func SetHeaders(h http.Header, now time.Time, body []byte, secrets ...string) {
+	ts := now.Unix()
+	h.Set(TimestampHeader, strconv.FormatInt(ts, 10))
+	h.Set(SignatureHeader, Header(ts, body, secrets...))
+}
+
+// Verify checks that any signature of the header is made with any of the secrets and the timestamp
+// isn't older than the tolerance. The zero tolerance disables the timestamp check.
+func Verify(header, timestamp string, body []byte, now time.Time, tolerance time.Duration, secrets ...string) error {
+	if header == "" {
+		return ErrNoSignature
+	}
+
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidTimestamp
+	}
+	if tolerance > 0 {
+		diff := now.Sub(time.Unix(ts, 0))
+		if diff > tolerance || diff < -tolerance {
+			return ErrTimestampExpired
+		}
+	}
+
+	for _, secret := range secrets {
+		expected := []byte(Sign(secret, ts, body))
+		for _, s := range strings.Split(header, ",") {
+			parts := strings.SplitN(strings.TrimSpace(s), "=", 2)
+			if len(parts) != 2 || parts[0] != scheme {
+				continue
+			}
+			if hmac.Equal(expected, []byte(parts[1])) {
+				return nil
+			}
+		}
+	}
+
+	return ErrSignatureMismatch
+}
+
+// VerifyRequest verifies the incoming webhook request with the DefaultTolerance and returns its body.
+// The request body is restored, so it may be read by the next handlers.
+func VerifyRequest(r *http.Request, secrets ...string) ([]byte, error) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	r.Body.Close()
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	err = Verify(r.Header.Get(SignatureHeader), r.Header.Get(TimestampHeader), body, time.Now(), DefaultTolerance, secrets...)
+	if err != nil {
+		return nil, err
+	}
+
+	return body, nil
+}
@@ -0,0 +1,57 @@
+package signature
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyAcceptsSignatureOfAnySecret(t *testing.T) {
+	now := time.Now()
+	body := []byte(`{"id":"1"}`)
+	header := Header(now.Unix(), body, "new", "old")
+	ts := strconv.FormatInt(now.Unix(), 10)
+
+	assert.Nil(t, Verify(header, ts, body, now, DefaultTolerance, "new"))
+	assert.Nil(t, Verify(header, ts, body, now, DefaultTolerance, "old"))
+	assert.Equal(t, ErrSignatureMismatch, Verify(header, ts, body, now, DefaultTolerance, "other"))
+}
+
+func TestVerifyReturnErrorWithModifiedBody(t *testing.T) {
+	now := time.Now()
+	header := Header(now.Unix(), []byte(`{"id":"1"}`), "secret")
+
+	err := Verify(header, strconv.FormatInt(now.Unix(), 10), []byte(`{"id":"2"}`), now, DefaultTolerance, "secret")
+	assert.Equal(t, ErrSignatureMismatch, err)
+}
+
+func TestVerifyReturnErrorWithExpiredTimestamp(t *testing.T) {
+	now := time.Now()
+	ts := now.Add(-time.Hour).Unix()
+	body := []byte(`{}`)
+	header := Header(ts, body, "secret")
+
+	assert.Equal(t, ErrTimestampExpired, Verify(header, strconv.FormatInt(ts, 10), body, now, DefaultTolerance, "secret"))
+	assert.Nil(t, Verify(header, strconv.FormatInt(ts, 10), body, now, 0, "secret"))
+	assert.Equal(t, ErrInvalidTimestamp, Verify(header, "", body, now, DefaultTolerance, "secret"))
+	assert.Equal(t, ErrNoSignature, Verify("", strconv.FormatInt(ts, 10), body, now, DefaultTolerance, "secret"))
+}
+
+func TestVerifyRequestRestoresBody(t *testing.T) {
+	body := []byte(`{"action":"user.logout"}`)
+	r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	SetHeaders(r.Header, time.Now(), body, "secret")
+
+	b, err := VerifyRequest(r, "secret")
+	assert.Nil(t, err)
+	assert.Equal(t, body, b)
+
+	rest, _ := ioutil.ReadAll(r.Body)
+	assert.Equal(t, body, rest)
+}
@@ -1,39 +1,70 @@
 package webhooks
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
-	"fmt"
-	"io"
-	"net/http"
-	"sync"
 	"time"
 
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/entity"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/repository"
 	"github.com/ProtocolONE/auth1.protocol.one/pkg/appcore/log"
 	"github.com/google/uuid"
+	"github.com/pkg/errors"
 	"go.uber.org/zap"
 )
 
-const (
-	UserLogoutAction = "user.logout"
-)
-
+// Hook is the body of the webhook request.
 type Hook struct {
-	ID        string            `json:"id"`
-	Action    string            `json:"action"`
-	UserID    string            `json:"user_id"`
-	CreatedAt string            `json:"created_at"`
-	Event     map[string]string `json:"event"`
+	ID        string `json:"id"`
+	Version   int    `json:"version"`
+	Action    string `json:"action"`
+	AppID     string `json:"app_id"`
+	UserID    string `json:"user_id"`
+	CreatedAt string `json:"created_at"`
+	Event     Event  `json:"event"`
+}
+
+// UnmarshalJSON decodes the event into the payload type of the action. The event of the unknown action
+// is left nil, so the consumers built with the older version of the package may skip it.
+func (h *Hook) UnmarshalJSON(data []byte) error {
+	type hook Hook
+	raw := struct {
+		*hook
+		Event json.RawMessage `json:"event"`
+	}{hook: (*hook)(h)}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	h.Event = nil
+	if create, ok := events[h.Action]; ok {
+		e := create()
+		if len(raw.Event) > 0 {
+			if err := json.Unmarshal(raw.Event, e); err != nil {
+				return errors.Wrapf(err, "unable to decode %s event", h.Action)
+			}
+		}
+		h.Event = e
+	}
+
+	return nil
 }
 
-type WebHooks struct{}
+// WebHooks puts the events to the delivery queue, they are sent by the Dispatcher.
+type WebHooks struct {
+	deliveries repository.WebhookDeliveryRepository
+}
 
-func NewWebhooks() *WebHooks {
-	return &WebHooks{}
+func NewWebhooks(deliveries repository.WebhookDeliveryRepository) *WebHooks {
+	return &WebHooks{deliveries: deliveries}
 }
 
-func (wh *WebHooks) UserLogout(ctx context.Context, userId string, endpoints []string) error {
+// Publish enqueues the event of the user for each endpoint of the application.
+func (wh *WebHooks) Publish(ctx context.Context, appId string, userId string, endpoints []string, event Event) error {
+	if len(endpoints) == 0 {
+		return nil
+	}
+
 	uid, err := uuid.NewUUID()
 	if err != nil {
 		return err
@@ -41,57 +72,48 @@ func (wh *WebHooks) UserLogout(ctx context.Context, userId string, endpoints []s
 
 	hook := Hook{
 		ID:        uid.String(),
-		Action:    UserLogoutAction,
+		Version:   SchemaVersion,
+		Action:    event.Action(),
+		AppID:     appId,
 		CreatedAt: time.Now().Format(time.RFC3339),
 		UserID:    userId,
-		Event:     map[string]string{},
+		Event:     event,
 	}
 
-	log.Info(ctx, fmt.Sprintf("Webhook %s started", hook.ID))
-	trigger(ctx, endpoints, hook)
-	log.Info(ctx, fmt.Sprintf("Webhook %s finished", hook.ID))
-
-	return nil
+	return wh.enqueue(ctx, entity.AppID(appId), hook, endpoints)
 }
 
-func trigger(ctx context.Context, endpoints []string, hook Hook) {
-	jsonHook, err := json.Marshal(hook)
+func (wh *WebHooks) enqueue(ctx context.Context, appID entity.AppID, hook Hook, endpoints []string) error {
+	payload, err := json.Marshal(hook)
 	if err != nil {
-		log.Error(ctx, err.Error())
-		return
+		return err
 	}
 
-	buf := &bytes.Buffer{}
-	buf.Write(jsonHook)
-
-	log.Debug(ctx, "webhook", zap.ByteString("body", jsonHook))
-
-	wg := sync.WaitGroup{}
-	wg.Add(len(endpoints))
-
-	// todo: do queue and retry on failure
+	now := time.Now()
 	for _, url := range endpoints {
-		go func() {
-			defer wg.Done()
-			err := post(url, buf)
-			if err != nil {
-				log.Error(ctx, err.Error(), zap.String("hook", hook.ID), zap.String("url", url))
-			}
-		}()
-	}
-	wg.Wait()
-}
-
-func post(url string, hook io.Reader) error {
-	c := http.Client{
-		Timeout: time.Second * 30,
-	}
-
-	resp, err := c.Post(url, "application/json", hook)
-	if err != nil {
-		return err
+		d := &entity.WebhookDelivery{
+			AppID:         appID,
+			URL:           url,
+			Event:         hook.Action,
+			Payload:       payload,
+			Status:        entity.WebhookDeliveryPending,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+			UpdatedAt:     now,
+		}
+		if err := wh.deliveries.Create(ctx, d); err != nil {
+			return errors.Wrapf(err, "unable to enqueue webhook %s", hook.ID)
+		}
+
+		// INFO: The payload isn't logged, it carries the personal data of the user
+		log.Info(ctx, "Webhook enqueued",
+			zap.String("hook", hook.ID),
+			zap.String("action", hook.Action),
+			zap.String("app", string(appID)),
+			zap.String("delivery", string(d.ID)),
+			zap.String("url", url),
+		)
 	}
-	defer resp.Body.Close()
 
 	return nil
 }
@@ -0,0 +1,49 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/ProtocolONE/auth1.protocol.one/internal/domain/repository"
+	"github.com/ProtocolONE/auth1.protocol.one/internal/repository/webhook_delivery/memory"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPublishEnqueuesVersionedHookForEachEndpoint(t *testing.T) {
+	deliveries := memory.New()
+	event := RolesChangedEvent{Roles: []string{"admin"}, PreviousRoles: []string{"user"}}
+
+	err := NewWebhooks(deliveries).Publish(context.Background(), "app_id", "user_id", []string{"http://a", "http://b"}, event)
+	assert.Nil(t, err)
+
+	list, _, err := deliveries.Find(context.Background(), repository.WebhookDeliveryQuery{})
+	assert.Nil(t, err)
+	if assert.Len(t, list, 2) {
+		assert.Equal(t, RolesChangedAction, list[0].Event)
+
+		hook := Hook{}
+		assert.Nil(t, json.Unmarshal(list[0].Payload, &hook))
+		assert.Equal(t, SchemaVersion, hook.Version)
+		assert.Equal(t, "app_id", hook.AppID)
+		assert.Equal(t, &event, hook.Event)
+	}
+}
+
+func TestPublishSkipsApplicationWithoutEndpoints(t *testing.T) {
+	deliveries := memory.New()
+
+	err := NewWebhooks(deliveries).Publish(context.Background(), "app_id", "user_id", nil, UserLoginEvent{})
+	assert.Nil(t, err)
+
+	list, _, _ := deliveries.Find(context.Background(), repository.WebhookDeliveryQuery{})
+	assert.Empty(t, list)
+}
+
+func TestHookLeavesUnknownEventEmpty(t *testing.T) {
+	hook := Hook{}
+	err := json.Unmarshal([]byte(`{"id":"1","version":2,"action":"user.renamed","event":{"name":"test"}}`), &hook)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, hook.Version)
+	assert.Nil(t, hook.Event)
+}